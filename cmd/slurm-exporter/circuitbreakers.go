@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/metrics"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+)
+
+// newCircuitBreakers creates the per-endpoint circuit breakers of one SLURM
+// client and the collector of their slurm_exporter_circuit_breaker_state
+func newCircuitBreakers(cfg config.CircuitBreakerConfig, logger *logrus.Logger) (*resilience.CircuitBreakerManager, prometheus.Collector) {
+	perfMetrics := metrics.NewPerformanceMetrics("slurm")
+	return resilience.NewCircuitBreakerManager(cfg, perfMetrics, logger), perfMetrics.CircuitBreakerState
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/health"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+	"github.com/jontk/slurm-exporter/internal/slurm"
+	"github.com/jontk/slurm-exporter/internal/tracing"
+)
+
+// clusterSet holds everything created for a multi-cluster exporter
+type clusterSet struct {
+	gatherer     *collector.ClusterGatherer
+	registries   map[string]config.ReloadableRegistry
+	healthChecks map[string]health.CheckFunc
+
+	// collectors are the collector registries of the clusters set up
+	collectors []*collector.Registry
+}
+
+// collectorForMetric returns the name of the collector exporting a metric
+// family in any cluster
+func (s *clusterSet) collectorForMetric(metric string) string {
+	for _, registry := range s.collectors {
+		if name := registry.CollectorForMetric(metric); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// setupClusters creates a SLURM client, collector registry and Prometheus
+// registry for every configured cluster. A cluster that cannot be set up is
+// reported down; it is only fatal when no cluster could be set up.
+func setupClusters(ctx context.Context, cfg *config.Config, tracer *tracing.CollectionTracer, logger *logrus.Entry) (*clusterSet, error) {
+	set := &clusterSet{
+		gatherer:     collector.NewClusterGatherer(),
+		registries:   make(map[string]config.ReloadableRegistry),
+		healthChecks: make(map[string]health.CheckFunc),
+	}
+
+	for i := range cfg.Clusters {
+		cluster := &cfg.Clusters[i]
+		clusterLogger := logger.WithField("cluster", cluster.Name)
+
+		breakers, breakerMetrics := newCircuitBreakers(cfg.Observability.CircuitBreaker, logger.Logger)
+		registry, promRegistry, client, err := setupCluster(&cluster.SLURM, &cluster.Collectors, breakers, breakerMetrics, cfg.Observability.Caching, tracer)
+		if err != nil {
+			clusterLogger.WithError(err).Error("Failed to set up cluster, reporting it down")
+			set.gatherer.AddCluster(cluster.Name, nil, nil, cluster.ScrapeTimeout)
+			continue
+		}
+
+		set.gatherer.AddCluster(cluster.Name, registry, promRegistry, cluster.ScrapeTimeout)
+		set.registries[cluster.Name] = registry
+		set.collectors = append(set.collectors, registry)
+		set.healthChecks["cluster_"+cluster.Name] = health.NewClusterHealthCheck(
+			cluster.Name,
+			func() bool { return set.gatherer.ClusterUp(cluster.Name) },
+			client.GetLastError,
+		)
+		if cfg.Observability.CircuitBreaker.Enabled {
+			set.healthChecks["circuit_breakers_"+cluster.Name] = health.NewCircuitBreakerHealthCheck(breakers.StatusMaps)
+		}
+
+		if err := startCollection(ctx, registry, &cluster.Collectors, cfg.Observability.AdaptiveCollection, client, clusterLogger); err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", cluster.Name, err)
+		}
+		clusterLogger.WithField("base_url", cluster.SLURM.BaseURL).Info("Cluster configured")
+	}
+
+	if len(set.registries) == 0 {
+		return nil, errors.New("no cluster could be set up")
+	}
+	return set, nil
+}
+
+// setupCluster creates the client and registries of one cluster, with the
+// analysis clients of the enabled collectors
+func setupCluster(slurmCfg *config.SLURMConfig, collectors *config.CollectorsConfig, breakers *resilience.CircuitBreakerManager, breakerMetrics prometheus.Collector, caching config.CachingConfig, tracer *tracing.CollectionTracer) (*collector.Registry, *prometheus.Registry, *slurm.Client, error) {
+	promRegistry := prometheus.NewRegistry()
+
+	registry, err := collector.NewRegistry(collectors, promRegistry)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create collector registry: %w", err)
+	}
+	registry.SetTracer(tracer)
+
+	if err := promRegistry.Register(breakerMetrics); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to register circuit breaker metrics: %w", err)
+	}
+
+	slurmWrapper, err := slurm.NewClient(slurmCfg, slurm.WithTracer(tracer), slurm.WithCircuitBreakers(breakers), slurm.WithCache(caching))
+	if err != nil {
+		// Don't wrap the error as it may contain sensitive config information
+		return nil, nil, nil, errors.New("failed to create SLURM client (check configuration for details)")
+	}
+	slurmClient := slurmWrapper.GetSlurmClient()
+	if err := promRegistry.Register(slurmWrapper.FetchMetrics()); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to register fetch metrics: %w", err)
+	}
+
+	registry.SetAnalysisClients(slurm.NewAnalysisClients(slurmClient, slurmCfg, collectors, slurm.WithTracer(tracer), slurm.WithCircuitBreakers(breakers)))
+
+	if err := registry.CreateCollectorsFromConfig(collectors, slurmClient); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create collectors: %w", err)
+	}
+	return registry, promRegistry, slurmWrapper, nil
+}
+
+// startCollection starts the performance monitoring of a registry and, if
+// configured, its background collection with adaptive intervals
+func startCollection(ctx context.Context, registry *collector.Registry, collectors *config.CollectorsConfig, adaptive config.AdaptiveCollectionConfig, client *slurm.Client, logger *logrus.Entry) error {
+	registry.StartPerformanceMonitoring(ctx, performanceMonitoringInterval)
+
+	if !collectors.Global.BackgroundCollection {
+		if adaptive.Enabled {
+			logger.Warn("Adaptive collection intervals require background collection, ignoring them")
+		}
+		return nil
+	}
+	if adaptive.Enabled {
+		if err := registry.EnableAdaptiveIntervals(adaptive, client.GetSlurmClient()); err != nil {
+			return fmt.Errorf("failed to enable adaptive collection intervals: %w", err)
+		}
+	}
+	if err := registry.StartBackgroundCollection(ctx); err != nil {
+		return fmt.Errorf("failed to start background collection: %w", err)
+	}
+	return nil
+}
@@ -17,9 +17,12 @@ import (
 
 	"github.com/jontk/slurm-exporter/internal/collector"
 	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/filtering"
+	"github.com/jontk/slurm-exporter/internal/health"
 	"github.com/jontk/slurm-exporter/internal/logging"
 	"github.com/jontk/slurm-exporter/internal/server"
 	"github.com/jontk/slurm-exporter/internal/slurm"
+	"github.com/jontk/slurm-exporter/internal/tracing"
 	"github.com/jontk/slurm-exporter/pkg/version"
 )
 
@@ -100,44 +103,99 @@ func main() {
 		"log_format":   cfg.Logging.Format,
 	}).Info("Starting SLURM Prometheus Exporter")
 
-	// Create Prometheus registry for collectors
-	promRegistry := prometheus.NewRegistry()
-
-	// Create collector registry
-	registry, err := collector.NewRegistry(&cfg.Collectors, promRegistry)
-	if err != nil {
-		logger.WithComponent("main").WithError(err).Fatal("Failed to create collector registry")
-	}
-
-	// Create SLURM client using our wrapper
-	slurmWrapper, err := slurm.NewClient(&cfg.SLURM)
+	// Create the tracer before anything that records spans
+	tracer, err := tracing.NewCollectionTracer(cfg.Observability.Tracing, logger.Logger)
 	if err != nil {
-		// Don't log the error directly as it may contain sensitive config information
-		logger.WithComponent("main").Fatal("Failed to create SLURM client (check configuration for details)")
+		logger.WithComponent("main").WithError(err).Fatal("Failed to create tracer")
 	}
 
-	// Get the underlying client for collectors
-	slurmClient := slurmWrapper.GetSlurmClient()
+	var (
+		promRegistry  *prometheus.Registry
+		srv           *server.Server
+		reloadHandler config.ReloadHandler
+		collectorOf   func(metric string) string
+	)
+	if len(cfg.Clusters) > 0 {
+		// Every cluster has its own client and registries, served together
+		// with a cluster label
+		clusters, err := setupClusters(ctx, cfg, tracer, logger.WithComponent("clusters"))
+		if err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to set up clusters")
+		}
+		logger.WithComponent("main").WithField("clusters", len(cfg.Clusters)).Info("Multi-cluster mode enabled")
 
-	// Create and register collectors based on configuration
-	if err := registry.CreateCollectorsFromConfig(&cfg.Collectors, slurmClient); err != nil {
-		logger.WithComponent("main").WithError(err).Fatal("Failed to create collectors")
-	}
+		// The exporter's own metrics are served next to the clusters'
+		promRegistry = prometheus.NewRegistry()
+		srv, err = server.New(cfg, logger.Logger, clusters.gatherer, promRegistry)
+		if err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to create server")
+		}
+		srv.AddGatherer(clusters.gatherer)
+		for name, check := range clusters.healthChecks {
+			srv.RegisterHealthCheck(name, check)
+		}
+		reloadHandler = config.CreateClusterReloadHandler(clusters.registries, logger.WithComponent("config-watcher"))
+		collectorOf = clusters.collectorForMetric
+	} else {
+		// Every slurmrestd endpoint gets a circuit breaker
+		breakers, breakerMetrics := newCircuitBreakers(cfg.Observability.CircuitBreaker, logger.Logger)
+		var (
+			registry     *collector.Registry
+			slurmWrapper *slurm.Client
+		)
+		registry, promRegistry, slurmWrapper, err = setupCluster(&cfg.SLURM, &cfg.Collectors, breakers, breakerMetrics, cfg.Observability.Caching, tracer)
+		if err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to set up collectors")
+		}
 
-	// Start performance monitoring with configured reporting interval
-	registry.StartPerformanceMonitoring(ctx, performanceMonitoringInterval)
-	logger.WithComponent("main").Info("Performance monitoring started")
+		if err := startCollection(ctx, registry, &cfg.Collectors, cfg.Observability.AdaptiveCollection, slurmWrapper, logger.WithComponent("main")); err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to start collection")
+		}
+		logger.WithComponent("main").Info("Performance monitoring started")
 
-	// Create and start the server
-	srv, err := server.New(cfg, logger.Logger, registry, promRegistry)
-	if err != nil {
-		logger.WithComponent("main").WithError(err).Fatal("Failed to create server")
+		// Create the server
+		srv, err = server.New(cfg, logger.Logger, registry, promRegistry)
+		if err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to create server")
+		}
+		srv.SetCollectorRegistry(registry)
+		if cfg.Observability.CircuitBreaker.Enabled {
+			srv.RegisterHealthCheck("circuit_breakers", health.NewCircuitBreakerHealthCheck(breakers.StatusMaps))
+		}
+		reloadHandler = config.CreateReloadHandler(registry, logger.WithComponent("config-watcher"))
+		collectorOf = registry.CollectorForMetric
+	}
+	srv.SetTracer(tracer)
+
+	// Smart filtering drops or samples low-value series of the collectors
+	var smartFilter *filtering.SmartFilter
+	if cfg.Observability.SmartFiltering.Enabled {
+		smartFilter, err = filtering.NewSmartFilter(cfg.Observability.SmartFiltering, logger.Logger)
+		if err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to create smart filter")
+		}
+		if err := smartFilter.RegisterMetrics(promRegistry); err != nil {
+			logger.WithComponent("main").WithError(err).Fatal("Failed to register smart filter metrics")
+		}
+		srv.SetSmartFilter(smartFilter, collectorOf)
 	}
 
 	// Setup graceful shutdown handling
 	shutdown := NewShutdownManager(logger.Logger, gracefulShutdownTimeout)
 
-	// Register shutdown hooks for proper cleanup
+	// Register shutdown hooks for proper cleanup. Hooks run in reverse order,
+	// so pending spans are flushed after the server has stopped.
+	shutdown.AddShutdownHook("tracer", func(ctx context.Context) error {
+		logger.WithComponent("shutdown").Info("Flushing traces")
+		return tracer.Shutdown(ctx)
+	})
+
+	if smartFilter != nil {
+		shutdown.AddShutdownHook("smart-filter", func(ctx context.Context) error {
+			return smartFilter.Close()
+		})
+	}
+
 	shutdown.AddShutdownHook("server", func(ctx context.Context) error {
 		logger.WithComponent("shutdown").Info("Shutting down HTTP server")
 		return srv.Shutdown(ctx)
@@ -149,7 +207,7 @@ func main() {
 	})
 
 	// Setup config watcher for hot-reload
-	configWatcher, err := config.NewWatcher(*configFile, config.CreateReloadHandler(registry, logger.WithComponent("config-watcher")), logger.WithComponent("config-watcher"))
+	configWatcher, err := config.NewWatcher(*configFile, reloadHandler, logger.WithComponent("config-watcher"))
 	if err != nil {
 		logger.WithComponent("main").WithError(err).Error("Failed to create config watcher, hot-reload disabled")
 		// Continue without hot-reload
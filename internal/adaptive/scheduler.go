@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"slices"
 	"sync"
 	"time"
 
@@ -155,6 +156,11 @@ func validateConfig(cfg config.AdaptiveCollectionConfig) error {
 	if cfg.ScoreWindow <= 0 {
 		return fmt.Errorf("score_window must be positive")
 	}
+	for _, collector := range cfg.StableCollectors {
+		if slices.Contains(cfg.ActiveCollectors, collector) {
+			return fmt.Errorf("collector %q cannot be both active and stable", collector)
+		}
+	}
 	return nil
 }
 
@@ -416,27 +422,26 @@ func (s *CollectorScheduler) adaptIntervals(activityScore float64) {
 	// Calculate target interval using inverse relationship
 	// High activity (score → 1.0) = shorter intervals (→ min_interval)
 	// Low activity (score → 0.0) = longer intervals (→ max_interval)
+	targetDuration := scaleInterval(s.config.MinInterval, s.config.MaxInterval, activityScore)
 
-	minInterval := s.config.MinInterval.Seconds()
-	maxInterval := s.config.MaxInterval.Seconds()
+	// Stable collectors slow down while the cluster is quiet but are not
+	// sped up beyond the base interval during bursts
+	stableDuration := scaleInterval(s.config.BaseInterval, s.config.MaxInterval, activityScore)
 
-	// Use exponential scaling for smoother adaptation
-	targetInterval := maxInterval - (maxInterval-minInterval)*math.Pow(activityScore, 0.5)
-	targetDuration := time.Duration(targetInterval * float64(time.Second))
-
-	// Apply the same interval to all collectors
-	// In a more sophisticated implementation, different collectors could have different adaptations
-	for collector := range s.collectorIntervals {
-		oldInterval := s.collectorIntervals[collector]
-		s.collectorIntervals[collector] = targetDuration
+	for collector, oldInterval := range s.collectorIntervals {
+		interval := targetDuration
+		if slices.Contains(s.config.StableCollectors, collector) {
+			interval = stableDuration
+		}
+		s.collectorIntervals[collector] = interval
 
 		// Record metrics
-		s.metrics.adaptedInterval.WithLabelValues(collector).Set(targetDuration.Seconds())
+		s.metrics.adaptedInterval.WithLabelValues(collector).Set(interval.Seconds())
 
 		// Record adjustment direction
-		if targetDuration < oldInterval {
+		if interval < oldInterval {
 			s.metrics.intervalAdjustments.WithLabelValues(collector, "decrease").Inc()
-		} else if targetDuration > oldInterval {
+		} else if interval > oldInterval {
 			s.metrics.intervalAdjustments.WithLabelValues(collector, "increase").Inc()
 		}
 	}
@@ -447,6 +452,21 @@ func (s *CollectorScheduler) adaptIntervals(activityScore float64) {
 	s.metrics.adaptationEvents.WithLabelValues("interval_update").Inc()
 }
 
+// scaleInterval maps an activity score to an interval between shortest, at
+// full activity, and longest, using exponential scaling for smoother
+// adaptation
+func scaleInterval(shortest, longest time.Duration, activityScore float64) time.Duration {
+	span := longest.Seconds() - shortest.Seconds()
+	return time.Duration((longest.Seconds() - span*math.Pow(activityScore, 0.5)) * float64(time.Second))
+}
+
+// Adapts reports whether the interval of a collector is adapted, i.e.
+// whether it is listed as an active or stable collector
+func (s *CollectorScheduler) Adapts(collector string) bool {
+	return s.enabled && (slices.Contains(s.config.ActiveCollectors, collector) ||
+		slices.Contains(s.config.StableCollectors, collector))
+}
+
 // RegisterCollector registers a collector with the scheduler
 func (s *CollectorScheduler) RegisterCollector(name string) {
 	if !s.enabled {
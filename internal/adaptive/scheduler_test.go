@@ -88,6 +88,18 @@ func TestNewCollectorScheduler_InvalidConfig(t *testing.T) {
 				ScoreWindow:  300 * time.Second,
 			},
 		},
+		{
+			name: "active_and_stable",
+			cfg: config.AdaptiveCollectionConfig{
+				Enabled:          true,
+				MinInterval:      30 * time.Second,
+				MaxInterval:      120 * time.Second,
+				BaseInterval:     60 * time.Second,
+				ScoreWindow:      300 * time.Second,
+				ActiveCollectors: []string{"jobs", "qos"},
+				StableCollectors: []string{"qos"},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -297,6 +309,38 @@ func TestCollectorScheduler_IntervalAdaptation(t *testing.T) {
 	assert.True(t, lowActivityInterval <= cfg.MaxInterval)
 }
 
+func TestCollectorScheduler_StableCollectors(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg := config.AdaptiveCollectionConfig{
+		Enabled:          true,
+		MinInterval:      10 * time.Second,
+		MaxInterval:      120 * time.Second,
+		BaseInterval:     30 * time.Second,
+		ScoreWindow:      300 * time.Second,
+		ActiveCollectors: []string{"jobs"},
+		StableCollectors: []string{"qos"},
+	}
+
+	scheduler, err := NewCollectorScheduler(cfg, 30*time.Second, logger)
+	require.NoError(t, err)
+
+	assert.True(t, scheduler.Adapts("jobs"))
+	assert.True(t, scheduler.Adapts("qos"))
+	assert.False(t, scheduler.Adapts("users"))
+
+	scheduler.RegisterCollector("jobs")
+	scheduler.RegisterCollector("qos")
+	scheduler.UpdateActivity(context.Background(), 1000, 100, map[string]interface{}{"jobs": 1000})
+
+	// Stable collectors are not sped up beyond the base interval
+	assert.Less(t, scheduler.GetCollectionInterval("jobs"), scheduler.GetCollectionInterval("qos"))
+	assert.GreaterOrEqual(t, scheduler.GetCollectionInterval("qos"), cfg.BaseInterval)
+	assert.LessOrEqual(t, scheduler.GetCollectionInterval("qos"), cfg.MaxInterval)
+}
+
 func TestCollectorScheduler_RecordCollection(t *testing.T) {
 	t.Parallel()
 	logger := logrus.New()
@@ -28,6 +28,30 @@ type AccountCostTrackingSLURMClient interface {
 	GetAccountCostPolicies(ctx context.Context, account string) ([]*AccountCostPolicy, error)
 }
 
+// AccountCostLister is optionally implemented by an
+// AccountCostTrackingSLURMClient to list the accounts to track, instead of
+// the sample accounts
+type AccountCostLister interface {
+	ListCostAccounts(ctx context.Context) ([]string, error)
+}
+
+// AccountCostAllocator is optionally implemented by an
+// AccountCostTrackingSLURMClient to break the cost of an account down by
+// user and partition
+type AccountCostAllocator interface {
+	GetAccountCostAllocations(ctx context.Context, account string) ([]*AccountCostAllocation, error)
+}
+
+// AccountCostAllocation is the cost one user of an account accrued in one
+// partition
+type AccountCostAllocation struct {
+	AccountName   string
+	UserName      string
+	PartitionName string
+	AccruedCost   float64 // in the current budget period
+	RunningCost   float64 // per hour, of the jobs running now
+}
+
 type AccountCostMetrics struct {
 	AccountName          string
 	TotalCost            float64
@@ -238,6 +262,15 @@ type AccountCostTrackingCollector struct {
 	client AccountCostTrackingSLURMClient
 	mutex  sync.RWMutex
 
+	// Alerts, optimizations and policies already counted, so that counters
+	// only grow when something new is reported
+	countedAlerts        map[string]bool
+	resolvedAlerts       map[string]bool
+	escalatedAlerts      map[string]bool
+	countedOptimizations map[string]bool
+	countedPolicies      map[string]bool
+	policyViolations     map[string]int
+
 	// Cost metrics
 	totalCost            *prometheus.GaugeVec
 	costToDate           *prometheus.GaugeVec
@@ -337,11 +370,21 @@ type AccountCostTrackingCollector struct {
 	costPoliciesActive   *prometheus.GaugeVec
 	costPolicyViolations *prometheus.CounterVec
 	costPolicyLimit      *prometheus.GaugeVec
+
+	// Allocation metrics
+	accruedCost     *prometheus.GaugeVec
+	runningCostRate *prometheus.GaugeVec
 }
 
 func NewAccountCostTrackingCollector(client AccountCostTrackingSLURMClient) *AccountCostTrackingCollector {
 	return &AccountCostTrackingCollector{
-		client: client,
+		client:               client,
+		countedAlerts:        make(map[string]bool),
+		resolvedAlerts:       make(map[string]bool),
+		escalatedAlerts:      make(map[string]bool),
+		countedOptimizations: make(map[string]bool),
+		countedPolicies:      make(map[string]bool),
+		policyViolations:     make(map[string]int),
 
 		// Cost metrics
 		totalCost: prometheus.NewGaugeVec(
@@ -447,7 +490,7 @@ func NewAccountCostTrackingCollector(client AccountCostTrackingSLURMClient) *Acc
 		budgetUtilization: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "slurm_account_budget_utilization",
-				Help: "Budget utilization percentage for account",
+				Help: "Budget utilization for account (0-1)",
 			},
 			[]string{"account", "period"},
 		),
@@ -910,6 +953,22 @@ func NewAccountCostTrackingCollector(client AccountCostTrackingSLURMClient) *Acc
 			},
 			[]string{"account", "policy_type"},
 		),
+
+		// Allocation metrics
+		accruedCost: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_account_accrued_cost",
+				Help: "Cost accrued in the current budget period by user and partition",
+			},
+			[]string{"account", "user", "partition"},
+		),
+		runningCostRate: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_account_running_cost_per_hour",
+				Help: "Hourly cost of the running jobs by user and partition",
+			},
+			[]string{"account", "user", "partition"},
+		),
 	}
 }
 
@@ -992,14 +1051,45 @@ func (c *AccountCostTrackingCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.costPoliciesActive.Describe(ch)
 	c.costPolicyViolations.Describe(ch)
 	c.costPolicyLimit.Describe(ch)
+	c.accruedCost.Describe(ch)
+	c.runningCostRate.Describe(ch)
 }
 
 func (c *AccountCostTrackingCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.CollectWithContext(context.Background(), ch)
+}
+
+// CollectWithContext collects the account cost metrics within ctx
+func (c *AccountCostTrackingCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	ctx := context.Background()
-	accounts := []string{"account1", "account2", "account3"}
+	// Reset gauges so that accounts, statuses and periods that went away are
+	// dropped
+	for _, gauge := range []*prometheus.GaugeVec{
+		c.totalCost, c.costToDate, c.estimatedMonthlyCost, c.costPerCPUHour, c.costPerGPUHour,
+		c.costPerMemoryGBHour, c.costPerStorageGBHour, c.averageDailyCost, c.peakDailyCost, c.costEfficiency,
+		c.costPerJob, c.costPerUser, c.totalBudget, c.remainingBudget, c.budgetUtilization, c.burnRate,
+		c.projectedOverrun, c.daysRemaining, c.budgetStatus, c.budgetAlertLevel, c.computeCost, c.storageCost,
+		c.networkCost, c.licenseCost, c.supportCost, c.overheadCost, c.cpuCost, c.gpuCost, c.memoryCost,
+		c.localStorageCost, c.sharedStorageCost, c.backupCost, c.weeklyForecast, c.monthlyForecast,
+		c.quarterlyForecast, c.annualForecast, c.forecastConfidence, c.growthRate, c.forecastAccuracy,
+		c.trendDirection, c.costAlertsActive, c.costAlertsSeverity, c.optimizationsActive, c.estimatedSavings,
+		c.actualSavings, c.optimizationROI, c.optimizationsPriority, c.peerAccountsAvgCost, c.industryBenchmark,
+		c.costRanking, c.costPercentile, c.efficiencyRanking, c.currentUtilization, c.projectedUtilization,
+		c.dailyBurnRate, c.optimalBurnRate, c.burnRateVariance, c.timeToDepletion, c.costVolatility,
+		c.trendStrength, c.predictiveAccuracy, c.trendConfidence, c.costElasticity, c.usageCorrelation,
+		c.seasonalityIndex, c.costPredictability, c.resourceUtilizationImpact, c.userBehaviorImpact,
+		c.workloadPatternImpact, c.analyticsScore, c.costPoliciesActive, c.costPolicyLimit, c.accruedCost,
+		c.runningCostRate,
+	} {
+		gauge.Reset()
+	}
+
+	accounts, err := c.getAccounts(ctx)
+	if err != nil {
+		return err
+	}
 
 	for _, account := range accounts {
 		c.collectCostMetrics(ctx, account, ch)
@@ -1013,6 +1103,7 @@ func (c *AccountCostTrackingCollector) Collect(ch chan<- prometheus.Metric) {
 		c.collectTrendMetrics(ctx, account, ch)
 		c.collectAnalyticsMetrics(ctx, account, ch)
 		c.collectPolicyMetrics(ctx, account, ch)
+		c.collectAllocationMetrics(ctx, account)
 	}
 
 	c.totalCost.Collect(ch)
@@ -1093,6 +1184,19 @@ func (c *AccountCostTrackingCollector) Collect(ch chan<- prometheus.Metric) {
 	c.costPoliciesActive.Collect(ch)
 	c.costPolicyViolations.Collect(ch)
 	c.costPolicyLimit.Collect(ch)
+	c.accruedCost.Collect(ch)
+	c.runningCostRate.Collect(ch)
+
+	return ctx.Err()
+}
+
+// getAccounts asks the client which accounts to track, falling back to the
+// sample accounts for clients that cannot list them
+func (c *AccountCostTrackingCollector) getAccounts(ctx context.Context) ([]string, error) {
+	if lister, ok := c.client.(AccountCostLister); ok {
+		return lister.ListCostAccounts(ctx)
+	}
+	return []string{"account1", "account2", "account3"}, nil
 }
 
 func (c *AccountCostTrackingCollector) collectCostMetrics(ctx context.Context, account string, ch chan<- prometheus.Metric) {
@@ -1222,10 +1326,14 @@ func (c *AccountCostTrackingCollector) collectAlertMetrics(ctx context.Context,
 	escalatedCounts := make(map[string]int)
 
 	for _, alert := range alerts {
-		if alertCounts[alert.AlertType] == nil {
-			alertCounts[alert.AlertType] = make(map[string]int)
+		// Alerts are counted once, when first reported
+		if !c.countedAlerts[alert.AlertID] {
+			c.countedAlerts[alert.AlertID] = true
+			if alertCounts[alert.AlertType] == nil {
+				alertCounts[alert.AlertType] = make(map[string]int)
+			}
+			alertCounts[alert.AlertType][alert.Severity]++
 		}
-		alertCounts[alert.AlertType][alert.Severity]++
 
 		if alert.Status == "active" {
 			if activeCounts[alert.AlertType] == nil {
@@ -1234,13 +1342,17 @@ func (c *AccountCostTrackingCollector) collectAlertMetrics(ctx context.Context,
 			activeCounts[alert.AlertType][alert.Severity]++
 		}
 
-		severityCounts[alert.Severity]++
+		if alert.Status == "active" {
+			severityCounts[alert.Severity]++
+		}
 
-		if alert.Status == "resolved" {
+		if alert.Status == "resolved" && !c.resolvedAlerts[alert.AlertID] {
+			c.resolvedAlerts[alert.AlertID] = true
 			resolvedCounts[alert.AlertType]++
 		}
 
-		if alert.EscalationLevel > 0 {
+		if alert.EscalationLevel > 0 && !c.escalatedAlerts[alert.AlertID] {
+			c.escalatedAlerts[alert.AlertID] = true
 			escalatedCounts[alert.AlertType]++
 		}
 	}
@@ -1283,10 +1395,14 @@ func (c *AccountCostTrackingCollector) collectOptimizationMetrics(ctx context.Co
 	priorityCounts := make(map[string]int)
 
 	for _, opt := range optimizations {
-		if optimizationCounts[opt.OptimizationType] == nil {
-			optimizationCounts[opt.OptimizationType] = make(map[string]int)
+		// Optimizations are counted once in each status they reach
+		if key := opt.OptimizationID + "/" + opt.Status; !c.countedOptimizations[key] {
+			c.countedOptimizations[key] = true
+			if optimizationCounts[opt.OptimizationType] == nil {
+				optimizationCounts[opt.OptimizationType] = make(map[string]int)
+			}
+			optimizationCounts[opt.OptimizationType][opt.Status]++
 		}
-		optimizationCounts[opt.OptimizationType][opt.Status]++
 
 		if opt.Status == "active" {
 			activeCounts[opt.OptimizationType]++
@@ -1390,13 +1506,20 @@ func (c *AccountCostTrackingCollector) collectPolicyMetrics(ctx context.Context,
 	violationCounts := make(map[string]int)
 
 	for _, policy := range policies {
-		policyCounts[policy.PolicyType]++
+		if !c.countedPolicies[policy.PolicyID] {
+			c.countedPolicies[policy.PolicyID] = true
+			policyCounts[policy.PolicyType]++
+		}
 
 		if policy.Active {
 			activeCounts[policy.PolicyType]++
 		}
 
-		violationCounts[policy.PolicyType] += policy.Violations
+		// Policies report their violations so far; only new ones are added
+		if last := c.policyViolations[policy.PolicyID]; policy.Violations > last {
+			violationCounts[policy.PolicyType] += policy.Violations - last
+		}
+		c.policyViolations[policy.PolicyID] = policy.Violations
 
 		c.costPolicyLimit.WithLabelValues(account, policy.PolicyType).Set(policy.CostLimit)
 	}
@@ -1413,3 +1536,23 @@ func (c *AccountCostTrackingCollector) collectPolicyMetrics(ctx context.Context,
 		c.costPolicyViolations.WithLabelValues(account, policyType).Add(float64(count))
 	}
 }
+
+// collectAllocationMetrics breaks the cost of an account down by user and
+// partition, for clients that can allocate it
+func (c *AccountCostTrackingCollector) collectAllocationMetrics(ctx context.Context, account string) {
+	allocator, ok := c.client.(AccountCostAllocator)
+	if !ok {
+		return
+	}
+
+	allocations, err := allocator.GetAccountCostAllocations(ctx, account)
+	if err != nil {
+		log.Printf("Error collecting cost allocations for account %s: %v", account, err)
+		return
+	}
+
+	for _, allocation := range allocations {
+		c.accruedCost.WithLabelValues(account, allocation.UserName, allocation.PartitionName).Set(allocation.AccruedCost)
+		c.runningCostRate.WithLabelValues(account, allocation.UserName, allocation.PartitionName).Set(allocation.RunningCost)
+	}
+}
@@ -5,6 +5,7 @@ package collector
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -73,6 +74,12 @@ type AccountQuotas struct {
 	PartitionQuotas   map[string]*PartitionQuota `json:"partition_quotas"`
 	AllowedPartitions []string                   `json:"allowed_partitions"`
 
+	// TRES-minute budgets by TRES name (cpu, gres/gpu, billing...):
+	// GrpTRESMins against past and running usage, GrpTRESRunMins against
+	// the minutes running jobs still have left
+	TRESMinutes    map[string]*ResourceQuota `json:"tres_minutes"`
+	TRESRunMinutes map[string]*ResourceQuota `json:"tres_run_minutes"`
+
 	// Time-based Quotas
 	QuotaPeriod    string        `json:"quota_period"`
 	QuotaResetDate time.Time     `json:"quota_reset_date"`
@@ -99,6 +106,11 @@ type ResourceQuota struct {
 	IsUnlimited      bool       `json:"is_unlimited"`
 	IsSoft           bool       `json:"is_soft"`
 	ExpiresAt        *time.Time `json:"expires_at"`
+
+	// Burn-down of consumable quotas: usage per day over the recent past
+	// and when the quota runs out at that rate
+	BurnRate            float64    `json:"burn_rate"`
+	ProjectedExhaustion *time.Time `json:"projected_exhaustion"`
 }
 
 // LimitQuota represents a numeric limit quota
@@ -194,6 +206,10 @@ type AccountQuotaCollector struct {
 	client AccountQuotaSLURMClient
 	mutex  sync.RWMutex
 
+	// Newest violation counted per account, so that violations reported
+	// again on the next collection are not counted twice
+	lastViolation map[string]time.Time
+
 	// Quota Limit Metrics
 	accountQuotaLimit       *prometheus.GaugeVec
 	accountQuotaUsed        *prometheus.GaugeVec
@@ -260,7 +276,8 @@ type AccountQuotaCollector struct {
 // NewAccountQuotaCollector creates a new account quota collector
 func NewAccountQuotaCollector(client AccountQuotaSLURMClient) *AccountQuotaCollector {
 	return &AccountQuotaCollector{
-		client: client,
+		client:        client,
+		lastViolation: make(map[string]time.Time),
 
 		// Quota Limit Metrics
 		accountQuotaLimit: prometheus.NewGaugeVec(
@@ -502,7 +519,7 @@ func NewAccountQuotaCollector(client AccountQuotaSLURMClient) *AccountQuotaColle
 		accountQuotaGrowthRate: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "slurm_account_quota_growth_rate",
-				Help: "Account quota usage growth rate",
+				Help: "Account quota usage growth rate, in quota units per day",
 			},
 			[]string{"account", "resource_type"},
 		),
@@ -640,15 +657,34 @@ func (c *AccountQuotaCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface
 func (c *AccountQuotaCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.CollectWithContext(context.Background(), ch)
+}
+
+// CollectWithContext collects the account quota metrics within ctx
+func (c *AccountQuotaCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	ctx := context.Background()
+	// Reset gauges so that accounts and quotas that went away are dropped
+	for _, gauge := range []*prometheus.GaugeVec{
+		c.accountQuotaLimit, c.accountQuotaUsed, c.accountQuotaReserved, c.accountQuotaAvailable,
+		c.accountQuotaUtilization, c.accountQuotaThreshold, c.accountCPUQuotaMinutes, c.accountMemoryQuotaGB,
+		c.accountGPUQuotaHours, c.accountStorageQuotaGB, c.accountJobQuotaLimit, c.accountJobQuotaCurrent,
+		c.accountQoSQuotaLimit, c.accountQoSQuotaUsed, c.accountQoSPriority, c.accountQoSJobLimit,
+		c.accountPartitionQuotaLimit, c.accountPartitionQuotaUsed, c.accountPartitionPriority, c.accountPartitionMaxJobs,
+		c.accountResourceUsageTotal, c.accountResourceUsageRate, c.accountResourceEfficiency, c.accountResourceWaste,
+		c.accountActiveUsers, c.accountQuotaViolationSeverity, c.accountQuotaEnforcementStatus, c.accountQuotaGracePeriod,
+		c.accountQuotaGrowthRate, c.accountQuotaTrendDirection, c.accountQuotaProjectedUsage, c.accountQuotaDepletionDays,
+		c.accountQuotaAlertLevel, c.accountQuotaAlertCount, c.accountQuotaRecommendationScore, c.accountQuotaOptimizationPotential,
+		c.accountQuotaLastModified, c.accountQuotaVersion, c.accountQuotaActive, c.accountQuotaResetDays,
+	} {
+		gauge.Reset()
+	}
 
 	// Get all account quotas
 	allQuotas, err := c.client.GetAllAccountQuotas(ctx)
 	if err != nil {
-		return
+		return err
 	}
 
 	for _, quota := range allQuotas {
@@ -704,6 +740,7 @@ func (c *AccountQuotaCollector) Collect(ch chan<- prometheus.Metric) {
 	c.accountQuotaVersion.Collect(ch)
 	c.accountQuotaActive.Collect(ch)
 	c.accountQuotaResetDays.Collect(ch)
+	return ctx.Err()
 }
 
 func (c *AccountQuotaCollector) collectAccountQuota(ctx context.Context, quota *AccountQuotas) {
@@ -730,6 +767,8 @@ func (c *AccountQuotaCollector) collectAccountQuota(ctx context.Context, quota *
 		c.accountMemoryQuotaGB.WithLabelValues(quota.AccountName, "available").Set(quota.MemoryGB.Available)
 	}
 	c.publishSimpleResourceQuotas(quota)
+	c.publishTRESMinuteQuotas(quota.AccountName, "tres_minutes", quota.TRESMinutes)
+	c.publishTRESMinuteQuotas(quota.AccountName, "tres_run_minutes", quota.TRESRunMinutes)
 	for qosName, qosQuota := range quota.QoSLimits {
 		c.accountQoSPriority.WithLabelValues(quota.AccountName, qosName).Set(float64(qosQuota.Priority))
 		if qosQuota.CPULimit != nil {
@@ -752,7 +791,29 @@ func (c *AccountQuotaCollector) collectAccountQuota(ctx context.Context, quota *
 	c.accountQuotaVersion.WithLabelValues(quota.AccountName).Set(float64(quota.QuotaVersion))
 	c.accountQuotaLastModified.WithLabelValues(quota.AccountName).Set(float64(quota.ModifiedAt.Unix()))
 	c.accountQuotaGracePeriod.WithLabelValues(quota.AccountName).Set(quota.GracePeriod.Hours())
-	c.accountQuotaResetDays.WithLabelValues(quota.AccountName).Set(time.Until(quota.QuotaResetDate).Hours() / 24)
+	if !quota.QuotaResetDate.IsZero() {
+		c.accountQuotaResetDays.WithLabelValues(quota.AccountName).Set(time.Until(quota.QuotaResetDate).Hours() / 24)
+	}
+}
+
+// publishTRESMinuteQuotas publishes TRES-minute budgets with the TRES as the
+// resource type, along with how fast they burn down
+func (c *AccountQuotaCollector) publishTRESMinuteQuotas(account, quotaType string, quotas map[string]*ResourceQuota) {
+	for tres, quota := range quotas {
+		c.accountQuotaLimit.WithLabelValues(account, tres, quotaType).Set(quota.Limit)
+		c.accountQuotaUsed.WithLabelValues(account, tres, quotaType).Set(quota.Used)
+		c.accountQuotaReserved.WithLabelValues(account, tres, quotaType).Set(quota.Reserved)
+		c.accountQuotaAvailable.WithLabelValues(account, tres, quotaType).Set(quota.Available)
+		c.accountQuotaUtilization.WithLabelValues(account, tres, quotaType).Set(quota.UtilizationRate)
+		if quotaType != "tres_minutes" {
+			continue
+		}
+		c.accountQuotaThreshold.WithLabelValues(account, tres).Set(quota.ThresholdPercent)
+		c.accountQuotaGrowthRate.WithLabelValues(account, tres).Set(quota.BurnRate)
+		if quota.ProjectedExhaustion != nil {
+			c.accountQuotaDepletionDays.WithLabelValues(account, tres).Set(time.Until(*quota.ProjectedExhaustion).Hours() / 24)
+		}
+	}
 }
 
 // publishSimpleResourceQuotas publishes GPU, Storage, and Job quotas
@@ -830,14 +891,27 @@ func (c *AccountQuotaCollector) collectQuotaViolations(ctx context.Context, acco
 		return
 	}
 
-	// Count violations by type
+	// Count violations by type, each once
 	violationCounts := make(map[string]map[string]int)
+	severity := make(map[string]float64)
+	last := c.lastViolation[accountName]
+	newest := last
 	for _, violation := range violations {
+		if violation.ResolvedAt == nil {
+			severity[violation.ResourceType] = max(severity[violation.ResourceType], quotaViolationSeverity(violation.Severity))
+		}
+		if !violation.Timestamp.After(last) {
+			continue
+		}
+		if violation.Timestamp.After(newest) {
+			newest = violation.Timestamp
+		}
 		if _, ok := violationCounts[violation.ResourceType]; !ok {
 			violationCounts[violation.ResourceType] = make(map[string]int)
 		}
 		violationCounts[violation.ResourceType][violation.ViolationType]++
 	}
+	c.lastViolation[accountName] = newest
 
 	// Set violation metrics
 	for resourceType, typeCounts := range violationCounts {
@@ -846,24 +920,48 @@ func (c *AccountQuotaCollector) collectQuotaViolations(ctx context.Context, acco
 		}
 	}
 
+	for resourceType, level := range severity {
+		c.accountQuotaViolationSeverity.WithLabelValues(accountName, resourceType).Set(level)
+	}
+
 	// Get enforcement status
-	_, err = c.client.GetQuotaEnforcementStatus(ctx, accountName)
-	if err == nil {
-		// Mock enforcement status values
-		c.accountQuotaEnforcementStatus.WithLabelValues(accountName, "cpu").Set(2.0) // hard enforcement
-		c.accountQuotaViolationSeverity.WithLabelValues(accountName, "cpu").Set(3.0) // severity level
+	status, err := c.client.GetQuotaEnforcementStatus(ctx, accountName)
+	if err == nil && status != nil {
+		var mode float64
+		switch status.EnforcementMode {
+		case "soft":
+			mode = 1
+		case "hard":
+			mode = 2
+		}
+		c.accountQuotaEnforcementStatus.WithLabelValues(accountName, "all").Set(mode)
 	}
 }
 
-func (c *AccountQuotaCollector) collectQuotaTrends(ctx context.Context, accountName string) {
-	_, err := c.client.GetAccountQuotaTrends(ctx, accountName, "30d")
-	if err != nil {
-		return
+// quotaViolationSeverity maps a violation severity onto the 0-10 scale
+func quotaViolationSeverity(severity string) float64 {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 10
+	case "error":
+		return 6
+	case "warning":
+		return 3
+	default:
+		return 1
 	}
+}
 
-	// Mock trend data
-	c.accountQuotaProjectedUsage.WithLabelValues(accountName, "cpu", "7d").Set(1500.0)
-	c.accountQuotaProjectedUsage.WithLabelValues(accountName, "cpu", "30d").Set(6000.0)
+func (c *AccountQuotaCollector) collectQuotaTrends(ctx context.Context, accountName string) {
+	for _, period := range []string{"7d", "30d"} {
+		trends, err := c.client.GetAccountQuotaTrends(ctx, accountName, period)
+		if err != nil || trends == nil {
+			return
+		}
+		for resourceType, projected := range trends.Predictions {
+			c.accountQuotaProjectedUsage.WithLabelValues(accountName, resourceType, period).Set(projected)
+		}
+	}
 }
 
 func (c *AccountQuotaCollector) collectQuotaAlerts(ctx context.Context, accountName string) {
@@ -889,11 +987,12 @@ func (c *AccountQuotaCollector) collectQuotaAlerts(ctx context.Context, accountN
 	c.accountQuotaAlertLevel.WithLabelValues(accountName, "overall").Set(float64(maxAlertLevel))
 
 	// Get recommendations
-	_, err = c.client.GetAccountQuotaRecommendations(ctx, accountName)
-	if err == nil {
-		// Mock recommendation scores
-		c.accountQuotaRecommendationScore.WithLabelValues(accountName, "increase_quota").Set(0.8)
-		c.accountQuotaOptimizationPotential.WithLabelValues(accountName, "cpu").Set(25.0)
+	recommendations, err := c.client.GetAccountQuotaRecommendations(ctx, accountName)
+	if err == nil && recommendations != nil {
+		for _, recommendation := range recommendations.Recommendations {
+			c.accountQuotaRecommendationScore.WithLabelValues(accountName, recommendation.Type).Set(recommendation.Confidence)
+		}
+		c.accountQuotaOptimizationPotential.WithLabelValues(accountName, "all").Set(100 * (1 - recommendations.OptimizationScore))
 	}
 }
 
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	slurm "github.com/jontk/slurm-client"
+
+	"github.com/jontk/slurm-exporter/internal/adaptive"
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// activityCollector is the collector after whose collections cluster
+// activity is measured; the job list it fetched is shared with the
+// measurement
+const activityCollector = "jobs"
+
+// EnableAdaptiveIntervals lets cluster activity adapt the background
+// collection intervals of the active and stable collectors in cfg. Activity
+// is measured from the job and node lists after every collection of the
+// jobs collector. It must be called before StartBackgroundCollection.
+func (r *Registry) EnableAdaptiveIntervals(cfg config.AdaptiveCollectionConfig, client slurm.SlurmClient) error {
+	scheduler, err := adaptive.NewCollectorScheduler(cfg, r.config.Global.DefaultInterval, r.logger.Logger)
+	if err != nil {
+		return err
+	}
+	if err := scheduler.RegisterMetrics(r.promRegistry); err != nil {
+		return fmt.Errorf("failed to register adaptive scheduler metrics: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[activityCollector]; !ok {
+		r.logger.Warn("Jobs collector disabled, adaptive collection intervals stay at base_interval")
+	}
+	r.adaptive = scheduler
+	r.activityClient = client
+	return nil
+}
+
+// startAdaptiveSchedules hands the adapted collectors of a new scheduler to
+// the adaptive scheduler, starting them at its base interval
+func (r *Registry) startAdaptiveSchedules(scheduler *Scheduler) {
+	if r.adaptive == nil {
+		return
+	}
+	for name := range scheduler.GetScheduleStats() {
+		if r.adaptive.Adapts(name) {
+			r.adaptive.RegisterCollector(name)
+			scheduler.adaptInterval(name, r.adaptive.GetCollectionInterval(name))
+		}
+	}
+}
+
+// updateActivity measures cluster activity from the job and node lists and
+// applies the adapted intervals to the schedules
+func (r *Registry) updateActivity(ctx context.Context, scheduler *Scheduler) {
+	jobs, err := r.activityClient.Jobs().List(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Debug("Failed to list jobs for cluster activity")
+		return
+	}
+	nodes, err := r.activityClient.Nodes().List(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Debug("Failed to list nodes for cluster activity")
+		return
+	}
+
+	// Job and node counts by state, compared between updates for the change rate
+	counts := make(map[string]int)
+	for _, job := range jobs.Jobs {
+		counts["jobs_"+strings.ToLower(getJobState(job))]++
+	}
+	for _, node := range nodes.Nodes {
+		state := "unknown"
+		if len(node.State) > 0 {
+			state = strings.ToLower(string(node.State[0]))
+		}
+		counts["nodes_"+state]++
+	}
+	changeData := make(map[string]interface{}, len(counts))
+	for key, count := range counts {
+		changeData[key] = count
+	}
+
+	r.adaptive.UpdateActivity(ctx, len(jobs.Jobs), len(nodes.Nodes), changeData)
+	for name := range scheduler.GetScheduleStats() {
+		if r.adaptive.Adapts(name) {
+			scheduler.adaptInterval(name, r.adaptive.GetCollectionInterval(name))
+		}
+	}
+}
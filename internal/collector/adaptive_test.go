@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func TestRegistry_AdaptiveIntervals(t *testing.T) {
+	t.Parallel()
+	cfg := &config.CollectorsConfig{
+		Global: config.GlobalCollectorConfig{
+			DefaultInterval: time.Hour,
+			DefaultTimeout:  time.Second,
+			MaxConcurrency:  2,
+		},
+		Jobs:  config.JobsConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
+		QoS:   config.CollectorConfig{Enabled: true},
+		Users: config.CollectorConfig{Enabled: true},
+	}
+	promRegistry := prometheus.NewRegistry()
+	registry, err := NewRegistry(cfg, promRegistry)
+	require.NoError(t, err)
+	for _, name := range []string{"jobs", "qos", "users"} {
+		require.NoError(t, registry.Register(name, &mockCollector{name: name, enabled: true}))
+	}
+
+	// A busy cluster: many jobs per node
+	mockClient := new(mocks.MockSlurmClient)
+	mockJobManager := new(mocks.MockJobManager)
+	mockNodeManager := new(mocks.MockNodeManager)
+	mockClient.On("Jobs").Return(mockJobManager)
+	mockClient.On("Nodes").Return(mockNodeManager)
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(&slurm.JobList{Jobs: make([]slurm.Job, 1000)}, nil)
+	mockNodeManager.On("List", mock.Anything, mock.Anything).Return(&slurm.NodeList{Nodes: make([]slurm.Node, 100)}, nil)
+
+	require.NoError(t, registry.EnableAdaptiveIntervals(config.AdaptiveCollectionConfig{
+		Enabled:          true,
+		MinInterval:      time.Second,
+		MaxInterval:      10 * time.Minute,
+		BaseInterval:     time.Minute,
+		ScoreWindow:      time.Minute,
+		ActiveCollectors: []string{"jobs"},
+		StableCollectors: []string{"qos"},
+	}, mockClient))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, registry.StartBackgroundCollection(ctx))
+
+	interval := func(name string) time.Duration {
+		return registry.scheduler.GetScheduleStats()[name].Interval
+	}
+	require.Eventually(t, func() bool { return interval("jobs") != time.Minute }, 5*time.Second, 10*time.Millisecond)
+
+	// Active collectors follow activity more closely than stable ones,
+	// which never go below the base interval
+	assert.Equal(t, registry.adaptive.GetCollectionInterval("jobs"), interval("jobs"))
+	assert.Equal(t, registry.adaptive.GetCollectionInterval("qos"), interval("qos"))
+	assert.Less(t, interval("jobs"), interval("qos"))
+	assert.GreaterOrEqual(t, interval("qos"), time.Minute)
+	assert.Equal(t, time.Hour, interval("users"), "unlisted collectors keep their interval")
+
+	byName := familiesByName(mustGather(t, promRegistry))
+	intervals := byName["slurm_exporter_scheduler_interval_seconds"]
+	require.NotNil(t, intervals)
+	exported := make(map[string]float64)
+	for _, metric := range intervals.Metric {
+		exported[labelValue(metric, "collector")] = metric.GetGauge().GetValue()
+	}
+	assert.Equal(t, interval("jobs").Seconds(), exported["jobs"])
+	assert.Equal(t, interval("qos").Seconds(), exported["qos"])
+	assert.NotContains(t, exported, "users")
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// contextCollector is implemented by the analysis collectors, which expose
+// prometheus.Collector directly and offer a context-aware collection method
+type contextCollector interface {
+	prometheus.Collector
+	CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error
+}
+
+// analysisCollectorAdapter adapts an analysis collector to the Collector
+// interface so it can be managed by the registry
+type analysisCollectorAdapter struct {
+	name      string
+	collector contextCollector
+	timeout   time.Duration
+
+	mu      sync.RWMutex
+	enabled bool
+}
+
+// newAnalysisCollectorAdapter wraps collector under the given name, bounding
+// each collection by timeout when it is positive
+func newAnalysisCollectorAdapter(name string, collector contextCollector, timeout time.Duration) *analysisCollectorAdapter {
+	return &analysisCollectorAdapter{
+		name:      name,
+		collector: collector,
+		timeout:   timeout,
+		enabled:   true,
+	}
+}
+
+// Name returns the collector name
+func (a *analysisCollectorAdapter) Name() string {
+	return a.name
+}
+
+// Describe sends the wrapped collector's descriptors to the channel
+func (a *analysisCollectorAdapter) Describe(ch chan<- *prometheus.Desc) {
+	a.collector.Describe(ch)
+}
+
+// Collect runs the wrapped collector within the configured timeout
+func (a *analysisCollectorAdapter) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	return a.collector.CollectWithContext(ctx, ch)
+}
+
+// IsEnabled returns whether the collector is enabled
+func (a *analysisCollectorAdapter) IsEnabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.enabled
+}
+
+// SetEnabled enables or disables the collector
+func (a *analysisCollectorAdapter) SetEnabled(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = enabled
+}
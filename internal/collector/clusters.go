@@ -0,0 +1,283 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// ClusterLabel is the label added to every series in multi-cluster mode
+const ClusterLabel = "cluster"
+
+// exportedClusterLabel holds a cluster label a series already had
+var exportedClusterLabel = "exported_" + ClusterLabel
+
+// clusterTarget is one cluster served by a ClusterGatherer
+type clusterTarget struct {
+	name     string
+	registry *Registry
+	gatherer prometheus.Gatherer
+	timeout  time.Duration
+
+	// gathering is set while a Gather of this cluster is running, so a
+	// cluster that outlived its timeout is skipped instead of piling up
+	gathering atomic.Bool
+
+	// up mirrors the cluster_up gauge for health checks
+	up atomic.Bool
+}
+
+// clusterResult is the outcome of gathering one cluster
+type clusterResult struct {
+	families []*dto.MetricFamily
+	failed   []string
+	err      error
+}
+
+// ClusterGatherer serves several clusters from one exporter. Each cluster
+// has its own collector registry and Prometheus registry; they are gathered
+// concurrently, each bounded by its own timeout, and every series is given
+// a cluster label.
+type ClusterGatherer struct {
+	clusters []*clusterTarget
+	logger   *logrus.Entry
+
+	metrics        *prometheus.Registry
+	up             *prometheus.GaugeVec
+	scrapeDuration *prometheus.GaugeVec
+	scrapeTimeouts *prometheus.CounterVec
+}
+
+// NewClusterGatherer creates an empty multi-cluster gatherer
+func NewClusterGatherer() *ClusterGatherer {
+	g := &ClusterGatherer{
+		logger:  logrus.WithField("component", "cluster_gatherer"),
+		metrics: prometheus.NewRegistry(),
+		up: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "cluster_up",
+				Help:      "Whether the last scrape of the cluster completed within its timeout (1) or not (0)",
+			},
+			[]string{ClusterLabel},
+		),
+		scrapeDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "cluster_scrape_duration_seconds",
+				Help:      "Duration of the last completed scrape of the cluster",
+			},
+			[]string{ClusterLabel},
+		),
+		scrapeTimeouts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "cluster_scrape_timeouts_total",
+				Help:      "Scrapes that returned without the cluster because it exceeded its timeout or was still busy",
+			},
+			[]string{ClusterLabel},
+		),
+	}
+	g.metrics.MustRegister(g.up, g.scrapeDuration, g.scrapeTimeouts)
+	return g
+}
+
+// AddCluster adds a cluster. A nil gatherer marks a cluster that could not
+// be set up; it is reported down on every scrape. A cluster with a registry
+// is gathered through it, so gatherer must be the registry's Prometheus
+// registry.
+func (g *ClusterGatherer) AddCluster(name string, registry *Registry, gatherer prometheus.Gatherer, timeout time.Duration) {
+	g.clusters = append(g.clusters, &clusterTarget{
+		name:     name,
+		registry: registry,
+		gatherer: gatherer,
+		timeout:  timeout,
+	})
+	g.up.WithLabelValues(name).Set(0)
+	g.scrapeTimeouts.WithLabelValues(name)
+}
+
+// setUp records whether the last scrape of a cluster succeeded
+func (g *ClusterGatherer) setUp(cluster *clusterTarget, up bool) {
+	cluster.up.Store(up)
+	value := 0.0
+	if up {
+		value = 1
+	}
+	g.up.WithLabelValues(cluster.name).Set(value)
+}
+
+// Gather implements prometheus.Gatherer
+func (g *ClusterGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, _, err := g.GatherContext(context.Background())
+	return families, err
+}
+
+// GatherContext gathers every cluster with its collectors running on ctx,
+// and returns the collectors that failed as cluster/collector
+func (g *ClusterGatherer) GatherContext(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+	results := make([]clusterResult, len(g.clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range g.clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = g.gatherCluster(ctx, cluster)
+		}()
+	}
+	wg.Wait()
+
+	// prometheus.Gatherers merges families with the same name across
+	// clusters and checks them for consistency
+	var failed []string
+	gatherers := make(prometheus.Gatherers, 0, len(results)+1)
+	for i, result := range results {
+		for _, name := range result.failed {
+			failed = append(failed, g.clusters[i].name+"/"+name)
+		}
+		gatherers = append(gatherers, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return result.families, result.err
+		}))
+	}
+	gatherers = append(gatherers, g.metrics)
+	families, err := gatherers.Gather()
+	return families, failed, err
+}
+
+// gatherCluster gathers one cluster, giving up after its timeout. The
+// collectors of a cluster that is given up on are cancelled.
+func (g *ClusterGatherer) gatherCluster(ctx context.Context, cluster *clusterTarget) clusterResult {
+	logger := g.logger.WithField(ClusterLabel, cluster.name)
+	if cluster.gatherer == nil {
+		return clusterResult{}
+	}
+	if !cluster.gathering.CompareAndSwap(false, true) {
+		logger.Warn("Previous scrape of cluster still running, skipping it")
+		g.setUp(cluster, false)
+		g.scrapeTimeouts.WithLabelValues(cluster.name).Inc()
+		return clusterResult{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan clusterResult, 1)
+	go func() {
+		defer cluster.gathering.Store(false)
+		if cluster.registry != nil {
+			families, failed, err := cluster.registry.GatherContext(ctx)
+			done <- clusterResult{families: families, failed: failed, err: err}
+			return
+		}
+		families, err := cluster.gatherer.Gather()
+		done <- clusterResult{families: families, err: err}
+	}()
+
+	timer := time.NewTimer(cluster.timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		g.setUp(cluster, true)
+		g.scrapeDuration.WithLabelValues(cluster.name).Set(time.Since(start).Seconds())
+		addClusterLabel(result.families, cluster.name)
+		return result
+	case <-timer.C:
+		logger.WithField("timeout", cluster.timeout).Warn("Cluster scrape timed out, serving the other clusters without it")
+		g.setUp(cluster, false)
+		g.scrapeTimeouts.WithLabelValues(cluster.name).Inc()
+		return clusterResult{}
+	}
+}
+
+// addClusterLabel adds the cluster label to every metric. A cluster label
+// the metric already has, such as the SLURM cluster of an association, is
+// kept as exported_cluster, the same way Prometheus handles label clashes.
+func addClusterLabel(families []*dto.MetricFamily, cluster string) {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == ClusterLabel {
+					label.Name = &exportedClusterLabel
+				}
+			}
+			name, value := ClusterLabel, cluster
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+		}
+	}
+}
+
+// GetStats returns the collector states of all clusters, keyed by
+// cluster/collector
+func (g *ClusterGatherer) GetStats() map[string]CollectorState {
+	stats := make(map[string]CollectorState)
+	for _, cluster := range g.clusters {
+		if cluster.registry == nil {
+			continue
+		}
+		for name, state := range cluster.registry.GetStats() {
+			stats[cluster.name+"/"+name] = state
+		}
+	}
+	return stats
+}
+
+// CollectAll runs every collector of every cluster concurrently
+func (g *ClusterGatherer) CollectAll(ctx context.Context) error {
+	errs := make([]error, len(g.clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range g.clusters {
+		if cluster.registry == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = cluster.registry.CollectAll(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// GetPerformanceStats returns the performance statistics of all clusters,
+// keyed by cluster/collector
+func (g *ClusterGatherer) GetPerformanceStats() map[string]*CollectorPerformanceStats {
+	stats := make(map[string]*CollectorPerformanceStats)
+	for _, cluster := range g.clusters {
+		if cluster.registry == nil {
+			continue
+		}
+		for name, perf := range cluster.registry.GetPerformanceStats() {
+			stats[cluster.name+"/"+name] = perf
+		}
+	}
+	return stats
+}
+
+// ClusterUp reports whether the last scrape of a cluster completed in time
+func (g *ClusterGatherer) ClusterUp(name string) bool {
+	for _, cluster := range g.clusters {
+		if cluster.name == name {
+			return cluster.up.Load()
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// newClusterTestRegistry returns a registry with one node gauge and one
+// series that already carries SLURM's own cluster name
+func newClusterTestRegistry(t *testing.T, nodes float64) *prometheus.Registry {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "slurm_nodes_total", Help: "Nodes"})
+	gauge.Set(nodes)
+	info := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "slurm_cluster_info", Help: "Info"}, []string{ClusterLabel})
+	info.WithLabelValues("slurmctld-name").Set(1)
+	require.NoError(t, reg.Register(gauge))
+	require.NoError(t, reg.Register(info))
+	return reg
+}
+
+// familiesByName indexes gathered families by name
+func familiesByName(families []*dto.MetricFamily) map[string]*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+func TestClusterGatherer_AddsClusterLabel(t *testing.T) {
+	t.Parallel()
+	g := NewClusterGatherer()
+	g.AddCluster("alpha", nil, newClusterTestRegistry(t, 10), time.Second)
+	g.AddCluster("beta", nil, newClusterTestRegistry(t, 20), time.Second)
+
+	families, err := g.Gather()
+	require.NoError(t, err)
+	byName := familiesByName(families)
+
+	nodes := byName["slurm_nodes_total"]
+	require.NotNil(t, nodes)
+	require.Len(t, nodes.Metric, 2)
+	values := make(map[string]float64)
+	for _, m := range nodes.Metric {
+		values[labelValue(m, ClusterLabel)] = m.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"alpha": 10, "beta": 20}, values)
+
+	// A cluster label the series already had is kept as exported_cluster
+	info := byName["slurm_cluster_info"]
+	require.NotNil(t, info)
+	require.Len(t, info.Metric, 2)
+	for _, m := range info.Metric {
+		assert.Equal(t, "slurmctld-name", labelValue(m, "exported_cluster"))
+		assert.NotEmpty(t, labelValue(m, ClusterLabel))
+	}
+
+	assert.True(t, g.ClusterUp("alpha"))
+	assert.True(t, g.ClusterUp("beta"))
+}
+
+func TestClusterGatherer_SlowClusterTimesOut(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	defer close(release)
+	slow := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		<-release
+		return nil, nil
+	})
+
+	g := NewClusterGatherer()
+	g.AddCluster("fast", nil, newClusterTestRegistry(t, 5), time.Second)
+	g.AddCluster("slow", nil, slow, 50*time.Millisecond)
+	g.AddCluster("broken", nil, nil, time.Second)
+
+	start := time.Now()
+	families, err := g.Gather()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the slow cluster must not stall the scrape")
+
+	byName := familiesByName(families)
+	require.NotNil(t, byName["slurm_nodes_total"])
+	assert.Equal(t, "fast", labelValue(byName["slurm_nodes_total"].Metric[0], ClusterLabel))
+
+	up := make(map[string]float64)
+	for _, m := range byName["slurm_exporter_cluster_up"].Metric {
+		up[labelValue(m, ClusterLabel)] = m.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"fast": 1, "slow": 0, "broken": 0}, up)
+
+	// The slow cluster is still busy, so the next scrape skips it at once
+	_, err = g.Gather()
+	require.NoError(t, err)
+	assert.False(t, g.ClusterUp("slow"))
+	for _, m := range familiesByName(mustGather(t, g))["slurm_exporter_cluster_scrape_timeouts_total"].Metric {
+		if labelValue(m, ClusterLabel) == "slow" {
+			assert.GreaterOrEqual(t, m.GetCounter().GetValue(), 2.0)
+		}
+	}
+}
+
+// mustGather gathers and fails the test on error
+func mustGather(t *testing.T, g prometheus.Gatherer) []*dto.MetricFamily {
+	t.Helper()
+	families, err := g.Gather()
+	require.NoError(t, err)
+	return families
+}
+
+func TestClusterGatherer_GatherContext(t *testing.T) {
+	t.Parallel()
+	promRegistry := prometheus.NewRegistry()
+	registry, err := NewRegistry(&config.CollectorsConfig{}, promRegistry)
+	require.NoError(t, err)
+
+	// The collector fails unless it runs on the scrape's context
+	type scrapeKey struct{}
+	require.NoError(t, registry.Register("jobs", &mockRegistryCollector{
+		name:    "jobs",
+		enabled: true,
+		collectFunc: func(ctx context.Context, _ chan<- prometheus.Metric) error {
+			if ctx.Value(scrapeKey{}) == nil {
+				return errors.New("not the scrape's context")
+			}
+			return nil
+		},
+	}))
+
+	g := NewClusterGatherer()
+	g.AddCluster("alpha", registry, promRegistry, time.Second)
+
+	_, failed, err := g.GatherContext(context.WithValue(context.Background(), scrapeKey{}, "scrape"))
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+
+	_, failed, err = g.GatherContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha/jobs"}, failed)
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/tracing"
 )
 
 // ConcurrentCollector manages concurrent collection from multiple collectors
@@ -42,6 +44,14 @@ type CollectionResult struct {
 	MetricCount   int
 	Error         error
 	Success       bool
+
+	// metrics are the collected metrics, kept as the collector's snapshot
+	// by background collection
+	metrics []prometheus.Metric
+
+	// exemplar links the collection duration to the slowest traced
+	// slurmrestd call, or is nil when none was traced
+	exemplar prometheus.Labels
 }
 
 // NewConcurrentCollector creates a new concurrent collector
@@ -145,6 +155,13 @@ func (cc *ConcurrentCollector) collectFromCollector(
 		return
 	}
 
+	// Trace the collection; background collections start their own trace
+	if tracer := cc.registry.tracer; tracer != nil {
+		var finish func()
+		ctx, finish = tracer.TraceCollection(ctx, name)
+		defer finish()
+	}
+
 	// Track active collections
 	atomic.AddInt64(&cc.activeCollections, 1)
 	defer atomic.AddInt64(&cc.activeCollections, -1)
@@ -164,9 +181,10 @@ func (cc *ConcurrentCollector) collectFromCollector(
 	var collectionErr error
 	done := make(chan struct{})
 
+	collectCtx, slowest := tracing.TrackSlowestCall(ctx)
 	go func() {
 		defer close(done)
-		collectionErr = collector.Collect(ctx, metricChan)
+		collectionErr = collector.Collect(collectCtx, metricChan)
 		close(metricChan)
 	}()
 
@@ -184,6 +202,13 @@ func (cc *ConcurrentCollector) collectFromCollector(
 	result.MetricCount = len(metrics)
 	result.Error = collectionErr
 	result.Success = collectionErr == nil
+	result.metrics = metrics
+	result.exemplar = slowest()
+
+	if tracer := cc.registry.tracer; tracer != nil {
+		tracer.AddSpanAttribute(ctx, "metric.count", result.MetricCount)
+		tracer.RecordError(ctx, collectionErr)
+	}
 
 	// Log result
 	if result.Success {
@@ -392,6 +417,11 @@ func (co *CollectionOrchestrator) Stop() {
 
 // CollectNow triggers an immediate collection for a specific collector
 func (co *CollectionOrchestrator) CollectNow(name string) (*CollectionResult, error) {
+	return co.collectNow(name, 30*time.Second)
+}
+
+// collectNow collects from a collector, giving up after the timeout
+func (co *CollectionOrchestrator) collectNow(name string, timeout time.Duration) (*CollectionResult, error) {
 	collector, exists := co.registry.Get(name)
 	if !exists {
 		return nil, fmt.Errorf("collector %s not found", name)
@@ -404,7 +434,7 @@ func (co *CollectionOrchestrator) CollectNow(name string) (*CollectionResult, er
 	resultChan := make(chan *CollectionResult, 1)
 	co.collector.wg.Add(1)
 
-	ctx, cancel := context.WithTimeout(co.ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(co.ctx, timeout)
 	defer cancel()
 
 	go co.collector.collectFromCollector(ctx, name, collector, resultChan)
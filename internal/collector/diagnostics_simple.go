@@ -239,6 +239,12 @@ func (c *DiagnosticsCollector) Collect(ctx context.Context, ch chan<- prometheus
 		return nil
 	}
 
+	// The counters restart from zero when slurmctld resets its statistics
+	var statsStart time.Time
+	if diag.ReqTimeStart > 0 {
+		statsStart = time.Unix(diag.ReqTimeStart, 0)
+	}
+
 	// Export thread and agent metrics
 	ch <- prometheus.MustNewConstMetric(
 		c.serverThreadCount,
@@ -277,40 +283,15 @@ func (c *DiagnosticsCollector) Collect(ctx context.Context, ch chan<- prometheus
 	)
 
 	// Export job metrics
-	ch <- prometheus.MustNewConstMetric(
-		c.jobsSubmitted,
-		prometheus.CounterValue,
-		float64(diag.JobsSubmitted),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.jobsSubmitted, float64(diag.JobsSubmitted), statsStart, clusterName)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.jobsStarted,
-		prometheus.CounterValue,
-		float64(diag.JobsStarted),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.jobsStarted, float64(diag.JobsStarted), statsStart, clusterName)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.jobsCompleted,
-		prometheus.CounterValue,
-		float64(diag.JobsCompleted),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.jobsCompleted, float64(diag.JobsCompleted), statsStart, clusterName)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.jobsCanceled,
-		prometheus.CounterValue,
-		float64(diag.JobsCanceled),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.jobsCanceled, float64(diag.JobsCanceled), statsStart, clusterName)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.jobsFailed,
-		prometheus.CounterValue,
-		float64(diag.JobsFailed),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.jobsFailed, float64(diag.JobsFailed), statsStart, clusterName)
 
 	// Export schedule cycle metrics
 	ch <- prometheus.MustNewConstMetric(
@@ -334,12 +315,7 @@ func (c *DiagnosticsCollector) Collect(ctx context.Context, ch chan<- prometheus
 		clusterName,
 	)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.scheduleCycleCounter,
-		prometheus.CounterValue,
-		float64(diag.ScheduleCycleCounter),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.scheduleCycleCounter, float64(diag.ScheduleCycleCounter), statsStart, clusterName)
 
 	// Export backfill cycle metrics (fields now prefixed with BF)
 	ch <- prometheus.MustNewConstMetric(
@@ -364,12 +340,7 @@ func (c *DiagnosticsCollector) Collect(ctx context.Context, ch chan<- prometheus
 	)
 
 	// Note: BFCycleCounter doesn't exist, using BFCycle as substitute
-	ch <- prometheus.MustNewConstMetric(
-		c.backfillCycleCounter,
-		prometheus.CounterValue,
-		float64(diag.BFCycle),
-		clusterName,
-	)
+	ch <- newStatsCounter(c.backfillCycleCounter, float64(diag.BFCycle), statsStart, clusterName)
 
 	// Gittos metrics not available in current Diagnostics struct
 	// ch <- prometheus.MustNewConstMetric(
@@ -414,3 +385,12 @@ func (c *DiagnosticsCollector) SetEnabled(enabled bool) {
 	// For now, we don't track enabled state in these simple collectors
 	// The enabled state is managed by the registry
 }
+
+// newStatsCounter creates a counter of the slurmctld statistics, created
+// when they were last reset if that is known
+func newStatsCounter(desc *prometheus.Desc, value float64, statsStart time.Time, labelValues ...string) prometheus.Metric {
+	if statsStart.IsZero() {
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labelValues...)
+	}
+	return prometheus.MustNewConstMetricWithCreatedTimestamp(desc, prometheus.CounterValue, value, statsStart, labelValues...)
+}
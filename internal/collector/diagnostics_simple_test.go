@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func TestDiagnosticsCollector_CreatedTimestamps(t *testing.T) {
+	t.Parallel()
+	statsStart := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Info").Return(nil)
+	client.On("GetDiagnostics", mock.Anything).
+		Return(&slurm.Diagnostics{ReqTimeStart: statsStart.Unix(), JobsSubmitted: 42, BFCycle: 7}, nil).Once()
+	client.On("GetDiagnostics", mock.Anything).
+		Return(&slurm.Diagnostics{JobsSubmitted: 42}, nil).Once()
+	collector := NewDiagnosticsCollector(client, testutil.GetTestLogger().WithField("component", "test"), time.Second)
+
+	collect := func() map[*prometheus.Desc]*dto.Metric {
+		ch := make(chan prometheus.Metric, 100)
+		require.NoError(t, collector.Collect(context.Background(), ch))
+		close(ch)
+
+		byDesc := make(map[*prometheus.Desc]*dto.Metric)
+		for metric := range ch {
+			m := &dto.Metric{}
+			require.NoError(t, metric.Write(m))
+			byDesc[metric.Desc()] = m
+		}
+		return byDesc
+	}
+
+	byDesc := collect()
+
+	// Counters are created when slurmctld last reset its statistics
+	submitted := byDesc[collector.jobsSubmitted]
+	require.NotNil(t, submitted)
+	assert.Equal(t, 42.0, submitted.GetCounter().GetValue())
+	assert.Equal(t, statsStart, submitted.GetCounter().GetCreatedTimestamp().AsTime())
+	assert.Equal(t, statsStart, byDesc[collector.backfillCycleCounter].GetCounter().GetCreatedTimestamp().AsTime())
+
+	// Gauges have no created timestamp
+	assert.NotNil(t, byDesc[collector.serverThreadCount].GetGauge())
+
+	// Without a reset time the counters are sent without one
+	byDesc = collect()
+	assert.Nil(t, byDesc[collector.jobsSubmitted].GetCounter().GetCreatedTimestamp())
+}
@@ -8,9 +8,9 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
-
-	// Commented out as only used in commented-out field
-	// "sync"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	slurm "github.com/jontk/slurm-client"
@@ -26,6 +26,8 @@ type FairShareCollector struct {
 
 	// Fair-share data storage
 	userFairShares map[string]*UserFairShare
+	accountShares  []slurm.Share
+	clusterName    string
 	// TODO: Unused field - preserved for future account hierarchy tracking
 	// accountHierarchy  *AccountFairShareHierarchy
 	priorityFactors map[string]*JobPriorityFactors
@@ -41,9 +43,9 @@ type FairShareCollector struct {
 	// User behavior analysis
 	behaviorAnalyzer *UserBehaviorAnalyzer
 
-	// TODO: Unused fields - preserved for future collection tracking and thread safety
+	// TODO: Unused field - preserved for future collection tracking
 	// lastCollection    time.Time
-	// mu                sync.RWMutex
+	mu sync.Mutex
 }
 
 // FairShareConfig configures the fair-share collector
@@ -61,9 +63,8 @@ type FairShareConfig struct {
 
 	// Analysis parameters
 	ViolationThreshold float64 // Threshold for fair-share violations
-	DecayPeriod        time.Duration
 	ResetCycle         time.Duration
-	PriorityWeights    PriorityWeights
+	PriorityWeights    PriorityWeights // PriorityWeight* of slurm.conf; zero when unknown
 
 	// Queue analysis
 	EnableQueueAnalysis   bool
@@ -122,9 +123,8 @@ type UserFairShare struct {
 	FairSharePriority float64 // Priority contribution from fair-share
 
 	// Decay and reset tracking
-	LastDecay     time.Time
-	LastReset     time.Time
-	DecayHalfLife time.Duration
+	LastDecay time.Time
+	LastReset time.Time
 
 	// Account association
 	AccountPath   string // Full account hierarchy path
@@ -794,43 +794,39 @@ type FairShareMetrics struct {
 	FairShareDataQuality        *prometheus.GaugeVec
 }
 
+// DefaultFairShareConfig returns the default fair-share collector configuration
+func DefaultFairShareConfig() *FairShareConfig {
+	return &FairShareConfig{
+		CollectionInterval:       30 * time.Second,
+		FairShareRetention:       24 * time.Hour,
+		PriorityRetention:        6 * time.Hour,
+		EnableUserFairShare:      true,
+		EnableAccountHierarchy:   true,
+		EnablePriorityAnalysis:   true,
+		EnableViolationDetection: true,
+		EnableTrendAnalysis:      true,
+		ViolationThreshold:       0.2,
+		ResetCycle:               7 * 24 * time.Hour,
+		EnableQueueAnalysis:      true,
+		QueuePositionTracking:    true,
+		WaitTimePrediction:       true,
+		EnableBehaviorAnalysis:   true,
+		BehaviorPatternWindow:    7 * 24 * time.Hour,
+		OptimizationSuggestions:  true,
+		MaxUsersPerCollection:    1000,
+		MaxAccountsPerCollection: 100,
+		EnableParallelProcessing: true,
+		MaxConcurrentAnalyses:    5,
+		GenerateReports:          true,
+		ReportInterval:           24 * time.Hour,
+		ReportRetention:          30 * 24 * time.Hour,
+	}
+}
+
 // NewFairShareCollector creates a new fair-share monitoring collector
 func NewFairShareCollector(client slurm.SlurmClient, logger *slog.Logger, config *FairShareConfig) (*FairShareCollector, error) {
 	if config == nil {
-		config = &FairShareConfig{
-			CollectionInterval:       30 * time.Second,
-			FairShareRetention:       24 * time.Hour,
-			PriorityRetention:        6 * time.Hour,
-			EnableUserFairShare:      true,
-			EnableAccountHierarchy:   true,
-			EnablePriorityAnalysis:   true,
-			EnableViolationDetection: true,
-			EnableTrendAnalysis:      true,
-			ViolationThreshold:       0.2,
-			DecayPeriod:              24 * time.Hour,
-			ResetCycle:               7 * 24 * time.Hour,
-			PriorityWeights: PriorityWeights{
-				AgeWeight:       0.2,
-				FairShareWeight: 0.5,
-				QoSWeight:       0.2,
-				PartitionWeight: 0.05,
-				AssocWeight:     0.03,
-				JobSizeWeight:   0.02,
-			},
-			EnableQueueAnalysis:      true,
-			QueuePositionTracking:    true,
-			WaitTimePrediction:       true,
-			EnableBehaviorAnalysis:   true,
-			BehaviorPatternWindow:    7 * 24 * time.Hour,
-			OptimizationSuggestions:  true,
-			MaxUsersPerCollection:    1000,
-			MaxAccountsPerCollection: 100,
-			EnableParallelProcessing: true,
-			MaxConcurrentAnalyses:    5,
-			GenerateReports:          true,
-			ReportInterval:           24 * time.Hour,
-			ReportRetention:          30 * 24 * time.Hour,
-		}
+		config = DefaultFairShareConfig()
 	}
 
 	violationDetector := &FairShareViolationDetector{
@@ -1229,9 +1225,17 @@ func (f *FairShareCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface
 func (f *FairShareCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
+	_ = f.CollectWithContext(context.Background(), ch)
+}
 
-	if err := f.collectFairShareMetrics(ctx); err != nil {
+// CollectWithContext refreshes the fair-share data from SLURM and sends the
+// resulting metrics to ch, returning any collection error to the caller.
+func (f *FairShareCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.collectFairShareMetrics(ctx)
+	if err != nil {
 		f.logger.Error("Failed to collect fair-share metrics", "error", err)
 		f.metrics.FairShareCollectionErrors.WithLabelValues("collect", "collection_error").Inc()
 	}
@@ -1277,6 +1281,8 @@ func (f *FairShareCollector) Collect(ch chan<- prometheus.Metric) {
 	f.metrics.FairShareCollectionDuration.Collect(ch)
 	f.metrics.FairShareCollectionErrors.Collect(ch)
 	f.metrics.FairShareDataQuality.Collect(ch)
+
+	return err
 }
 
 // collectFairShareMetrics collects all fair-share and priority metrics
@@ -1287,23 +1293,31 @@ func (f *FairShareCollector) collectFairShareMetrics(ctx context.Context) error
 	}()
 
 	// Collect user fair-share data
-	if err := f.collectUserFairShares(ctx); err != nil {
-		return fmt.Errorf("user fair-share collection failed: %w", err)
+	if f.config.EnableUserFairShare {
+		if err := f.collectUserFairShares(ctx); err != nil {
+			return fmt.Errorf("user fair-share collection failed: %w", err)
+		}
 	}
 
 	// Collect account hierarchy
-	if err := f.collectAccountHierarchy(ctx); err != nil {
-		return fmt.Errorf("account hierarchy collection failed: %w", err)
+	if f.config.EnableAccountHierarchy {
+		if err := f.collectAccountHierarchy(ctx); err != nil {
+			return fmt.Errorf("account hierarchy collection failed: %w", err)
+		}
 	}
 
 	// Collect job priority factors
-	if err := f.collectJobPriorityFactors(ctx); err != nil {
-		return fmt.Errorf("job priority collection failed: %w", err)
+	if f.config.EnablePriorityAnalysis {
+		if err := f.collectJobPriorityFactors(ctx); err != nil {
+			return fmt.Errorf("job priority collection failed: %w", err)
+		}
 	}
 
 	// Analyze violations
-	if err := f.analyzeViolations(ctx); err != nil {
-		return fmt.Errorf("violation analysis failed: %w", err)
+	if f.config.EnableViolationDetection {
+		if err := f.analyzeViolations(ctx); err != nil {
+			return fmt.Errorf("violation analysis failed: %w", err)
+		}
 	}
 
 	// Analyze policy effectiveness
@@ -1312,124 +1326,248 @@ func (f *FairShareCollector) collectFairShareMetrics(ctx context.Context) error
 	}
 
 	// Analyze trends
-	if err := f.analyzeTrends(ctx); err != nil {
-		return fmt.Errorf("trend analysis failed: %w", err)
+	if f.config.EnableTrendAnalysis {
+		if err := f.analyzeTrends(ctx); err != nil {
+			return fmt.Errorf("trend analysis failed: %w", err)
+		}
 	}
 
 	// Analyze queues
-	if err := f.analyzeQueues(ctx); err != nil {
-		return fmt.Errorf("queue analysis failed: %w", err)
+	if f.config.EnableQueueAnalysis {
+		if err := f.analyzeQueues(ctx); err != nil {
+			return fmt.Errorf("queue analysis failed: %w", err)
+		}
 	}
 
 	// Analyze user behavior
-	if err := f.analyzeUserBehavior(ctx); err != nil {
-		return fmt.Errorf("user behavior analysis failed: %w", err)
+	if f.config.EnableBehaviorAnalysis {
+		if err := f.analyzeUserBehavior(ctx); err != nil {
+			return fmt.Errorf("user behavior analysis failed: %w", err)
+		}
 	}
 
 	return nil
 }
 
-// collectUserFairShares collects user fair-share factors
+// collectUserFairShares collects user fair-share factors from the slurmrestd
+// shares endpoint (the same data reported by sshare)
 func (f *FairShareCollector) collectUserFairShares(ctx context.Context) error {
 	startTime := time.Now()
 	defer func() {
 		f.metrics.FairShareCollectionDuration.WithLabelValues("collect_user_fairshares").Observe(time.Since(startTime).Seconds())
 	}()
 
-	// This is a simplified implementation since GetUserFairShare() doesn't exist yet
-	// We'll simulate fair-share data based on job usage patterns
-
-	jobManager := f.slurmClient.Jobs()
-	// Using nil for options as the exact structure is not clear
-	jobList, err := jobManager.List(ctx, nil)
+	shares, err := f.slurmClient.GetShares(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to list jobs: %w", err)
+		return fmt.Errorf("failed to get shares: %w", err)
 	}
 
-	// TODO: Job field names (UserName, Account, etc.) are not available in the current slurm-client version
-	// Skipping user usage aggregation for now
-	userUsage := make(map[string]*UserUsageData)
-	_ = jobList // Suppress unused variable warning
+	// Association rows without a user describe accounts; keep them for the
+	// hierarchy and to resolve each user's account path
+	f.accountShares = f.accountShares[:0]
+	var userShares []slurm.Share
+	if shares != nil {
+		for _, share := range shares.Shares {
+			if f.clusterName == "" && share.Cluster != "" {
+				f.clusterName = share.Cluster
+			}
+			if share.User == "" {
+				f.accountShares = append(f.accountShares, share)
+			} else {
+				userShares = append(userShares, share)
+			}
+		}
+	}
+	parents := accountParents(f.accountShares)
+
+	// Series for users that disappeared from the shares output must not linger
+	f.resetUserFairShareMetrics()
+
+	now := time.Now()
+	current := make(map[string]*UserFairShare, len(userShares))
+	for _, share := range userShares {
+		if f.config.MaxUsersPerCollection > 0 && len(current) >= f.config.MaxUsersPerCollection {
+			f.logger.Warn("Fair-share user limit reached, skipping remaining users",
+				"limit", f.config.MaxUsersPerCollection, "total", len(userShares))
+			break
+		}
 
-	// Calculate fair-share factors for each user
-	for userKey, usage := range userUsage {
-		fairShare := f.calculateUserFairShare(usage)
-		f.userFairShares[userKey] = fairShare
+		key := userFairShareKey(share.User, share.Account, share.Partition)
+		fairShare := f.calculateUserFairShare(share, f.userFairShares[key], parents, now)
+		current[key] = fairShare
 
 		// Update metrics
 		f.updateUserFairShareMetrics(fairShare)
 	}
+	f.userFairShares = current
 
-	f.metrics.FairShareDataQuality.WithLabelValues("user_fairshare", "calculated").Set(0.8) // Simulated quality
+	f.metrics.FairShareDataQuality.WithLabelValues("user_fairshare", "slurmrestd").Set(1)
 	return nil
 }
 
-// UserUsageData aggregates usage data for a user
-type UserUsageData struct {
-	UserName      string
-	Account       string
-	TotalCPUUsage float64
-	JobCount      int
+// userFairShareKey builds the map key identifying a user association
+func userFairShareKey(user, account, partition string) string {
+	return user + "/" + account + "/" + partition
 }
 
-// calculateUserFairShare calculates fair-share factor for a user (simplified)
-func (f *FairShareCollector) calculateUserFairShare(usage *UserUsageData) *UserFairShare {
-	// Simplified calculation - in reality this would use SLURM's fair-share algorithm
-	now := time.Now()
+// accountParents maps each account to its parent account. Older API versions
+// do not report the parent, in which case accounts hang directly off root.
+func accountParents(accountShares []slurm.Share) map[string]string {
+	parents := make(map[string]string, len(accountShares))
+	for _, share := range accountShares {
+		if share.Account == "" || share.Account == "root" {
+			continue
+		}
+		parent := share.ParentAccount
+		if parent == "" {
+			parent = "root"
+		}
+		parents[share.Account] = parent
+	}
+	return parents
+}
 
-	// Simulate fair-share calculation
-	baseShares := int64(100) // Base shares for user
-	normalizedShares := 0.1  // 10% of total shares
+// accountPath returns the path from root to account (e.g. "root.physics.hep")
+// and the account's depth below root. Cycles in malformed data are cut short.
+func accountPath(account string, parents map[string]string) (string, int) {
+	if account == "" || account == "root" {
+		return "root", 0
+	}
+
+	path := []string{account}
+	seen := map[string]bool{account: true}
+	for current := account; ; {
+		parent, ok := parents[current]
+		if !ok {
+			parent = "root"
+		}
+		if parent == "root" || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		path = append(path, parent)
+		current = parent
+	}
+	path = append(path, "root")
+
+	// Reverse into root-first order
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
 
-	// Calculate effective usage (simplified)
-	effectiveUsage := usage.TotalCPUUsage / 168.0 // Weekly average
-	targetUsage := normalizedShares * 1000.0      // Target based on shares
-	usageRatio := effectiveUsage / targetUsage
+	joined := path[0]
+	for _, p := range path[1:] {
+		joined += "." + p
+	}
+	return joined, len(path) - 1
+}
 
-	// Calculate fair-share factor (inverse relationship with usage ratio)
-	fairShareFactor := math.Max(0.1, math.Min(2.0, 1.0/math.Max(0.1, usageRatio)))
+// calculateUserFairShare builds the fair-share state for a user association
+// from the shares data, using the previous state to derive trends and how
+// long a violation has been ongoing
+func (f *FairShareCollector) calculateUserFairShare(share slurm.Share, previous *UserFairShare, parents map[string]string, now time.Time) *UserFairShare {
+	// Effective usage relative to the normalised shares is what SLURM's
+	// fair-share algorithm compares; 1.0 means the user consumed exactly
+	// their share
+	usageRatio := 0.0
+	if share.NormalizedShares > 0 {
+		usageRatio = share.EffectiveUsage / share.NormalizedShares
+	}
 
-	// Calculate priority contribution
-	fairSharePriority := fairShareFactor * f.config.PriorityWeights.FairShareWeight * 10000
+	fairShareFactor := share.FairshareUsage
+	// slurmctld adds the factor times PriorityWeightFairshare to the job
+	// priority
+	fairSharePriority := fairShareFactor * f.config.PriorityWeights.FairShareWeight
 
-	// Determine trend (simplified)
+	// Trend from the change since the previous collection, extrapolated
+	// linearly over the next 24 hours
 	trendDirection := "stable"
-	if usageRatio > 1.2 {
-		trendDirection = "degrading"
-	} else if usageRatio < 0.8 {
-		trendDirection = "improving"
+	trendStrength := 0.0
+	predictedFactor := fairShareFactor
+	if previous != nil {
+		delta := fairShareFactor - previous.FairShareFactor
+		switch {
+		case delta > fairShareTrendEpsilon:
+			trendDirection = "improving"
+		case delta < -fairShareTrendEpsilon:
+			trendDirection = "degrading"
+		}
+		trendStrength = math.Min(1.0, math.Abs(delta)/math.Max(previous.FairShareFactor, fairShareTrendEpsilon))
+
+		if elapsed := now.Sub(previous.Timestamp); elapsed > 0 {
+			predictedFactor = fairShareFactor + delta*float64(24*time.Hour)/float64(elapsed)
+			predictedFactor = math.Max(0, math.Min(1, predictedFactor))
+		}
+	}
+
+	isViolating := share.NormalizedShares > 0 && usageRatio > 1.0+f.config.ViolationThreshold
+	var violationDuration time.Duration
+	var violationHistory []*FairShareViolation
+	if previous != nil {
+		violationHistory = previous.ViolationHistory
+		if isViolating && previous.IsViolating {
+			violationDuration = previous.ViolationDuration + now.Sub(previous.Timestamp)
+		}
+	}
+
+	path, level := accountPath(share.Account, parents)
+	parent := parents[share.Account]
+	if parent == "" && share.Account != "root" {
+		parent = "root"
+	}
+
+	updateCount := int64(1)
+	if previous != nil {
+		updateCount = previous.UpdateCount + 1
 	}
 
 	return &UserFairShare{
-		UserName:          usage.UserName,
-		Account:           usage.Account,
+		UserName:          share.User,
+		Account:           share.Account,
+		Partition:         share.Partition,
 		Timestamp:         now,
 		FairShareFactor:   fairShareFactor,
-		RawShares:         baseShares,
-		NormalizedShares:  normalizedShares,
-		EffectiveUsage:    effectiveUsage,
-		CPUUsage:          usage.TotalCPUUsage,
-		TargetUsage:       targetUsage,
+		RawShares:         int64(share.RawShares),
+		NormalizedShares:  share.NormalizedShares,
+		EffectiveUsage:    share.EffectiveUsage,
+		TargetUsage:       share.NormalizedShares,
 		UsageRatio:        usageRatio,
 		FairSharePriority: fairSharePriority,
-		LastDecay:         now.Add(-24 * time.Hour),
-		LastReset:         now.Add(-7 * 24 * time.Hour),
-		DecayHalfLife:     f.config.DecayPeriod,
-		AccountPath:       fmt.Sprintf("root.%s", usage.Account),
-		ParentAccount:     "root",
-		Level:             1,
-		DataQuality:       0.85,
+		AccountPath:       path,
+		ParentAccount:     parent,
+		Level:             level + 1,
+		DataQuality:       1.0,
 		LastUpdated:       now,
-		UpdateCount:       1,
+		UpdateCount:       updateCount,
 		TrendDirection:    trendDirection,
-		TrendStrength:     0.7,
-		PredictedFactor:   fairShareFactor * 0.95, // Slightly lower prediction
-		IsViolating:       usageRatio > (1.0 + f.config.ViolationThreshold),
-		ViolationSeverity: math.Max(0, (usageRatio-1.0)/2.0),
-		ViolationDuration: 0,
+		TrendStrength:     trendStrength,
+		PredictedFactor:   predictedFactor,
+		IsViolating:       isViolating,
+		ViolationSeverity: math.Max(0, math.Min(1, (usageRatio-1.0)/2.0)),
+		ViolationDuration: violationDuration,
+		ViolationHistory:  violationHistory,
 	}
 }
 
+// fairShareTrendEpsilon is the smallest factor change treated as a trend
+const fairShareTrendEpsilon = 0.001
+
+// resetUserFairShareMetrics clears the per-user series before a refresh
+func (f *FairShareCollector) resetUserFairShareMetrics() {
+	f.metrics.UserFairShareFactor.Reset()
+	f.metrics.UserRawShares.Reset()
+	f.metrics.UserNormalizedShares.Reset()
+	f.metrics.UserEffectiveUsage.Reset()
+	f.metrics.UserUsageRatio.Reset()
+	f.metrics.UserFairSharePriority.Reset()
+	f.metrics.FairShareTrendDirection.Reset()
+	f.metrics.FairShareTrendStrength.Reset()
+	f.metrics.FairSharePrediction.Reset()
+	f.metrics.FairShareViolations.Reset()
+	f.metrics.ViolationSeverity.Reset()
+	f.metrics.ViolationDuration.Reset()
+}
+
 // updateUserFairShareMetrics updates Prometheus metrics for user fair-share
 func (f *FairShareCollector) updateUserFairShareMetrics(fairShare *UserFairShare) {
 	labels := []string{fairShare.UserName, fairShare.Account, fairShare.Partition}
@@ -1438,12 +1576,13 @@ func (f *FairShareCollector) updateUserFairShareMetrics(fairShare *UserFairShare
 	f.metrics.UserRawShares.WithLabelValues(labels...).Set(float64(fairShare.RawShares))
 	f.metrics.UserNormalizedShares.WithLabelValues(labels...).Set(fairShare.NormalizedShares)
 	f.metrics.UserUsageRatio.WithLabelValues(labels...).Set(fairShare.UsageRatio)
-	f.metrics.UserFairSharePriority.WithLabelValues(labels...).Set(fairShare.FairSharePriority)
+	// Without PriorityWeightFairshare the contribution is unknown
+	if f.config.PriorityWeights.FairShareWeight > 0 {
+		f.metrics.UserFairSharePriority.WithLabelValues(labels...).Set(fairShare.FairSharePriority)
+	}
 
-	// Usage by resource type
-	f.metrics.UserEffectiveUsage.WithLabelValues(append(labels, "cpu")...).Set(fairShare.CPUUsage)
-	f.metrics.UserEffectiveUsage.WithLabelValues(append(labels, "memory")...).Set(fairShare.MemoryUsage)
-	f.metrics.UserEffectiveUsage.WithLabelValues(append(labels, "gpu")...).Set(fairShare.GPUUsage)
+	// SLURM decays usage in billing TRES units, not per resource type
+	f.metrics.UserEffectiveUsage.WithLabelValues(append(labels, "billing")...).Set(fairShare.EffectiveUsage)
 
 	// Trend metrics
 	trendValue := 0.0
@@ -1461,20 +1600,67 @@ func (f *FairShareCollector) updateUserFairShareMetrics(fairShare *UserFairShare
 
 	// Violation metrics
 	if fairShare.IsViolating {
-		f.metrics.FairShareViolations.WithLabelValues(fairShare.UserName, fairShare.Account, "overuse", "moderate").Set(1)
+		severity := f.violationDetector.severityLevel(fairShare.UsageRatio - 1.0)
+		f.metrics.FairShareViolations.WithLabelValues(fairShare.UserName, fairShare.Account, "overuse", severity).Set(1)
 		f.metrics.ViolationSeverity.WithLabelValues(fairShare.UserName, fairShare.Account, "overuse").Set(fairShare.ViolationSeverity)
 		f.metrics.ViolationDuration.WithLabelValues(fairShare.UserName, fairShare.Account, "overuse").Set(fairShare.ViolationDuration.Seconds())
 	}
 }
 
-// Placeholder implementations for remaining methods
+// collectAccountHierarchy exports the account level of the fair-share tree
 //
 //nolint:unparam
 func (f *FairShareCollector) collectAccountHierarchy(ctx context.Context) error {
 	_ = ctx
-	// Simplified account hierarchy collection
-	f.metrics.AccountHierarchyDepth.WithLabelValues("root").Set(3)
-	f.metrics.AccountUserCount.WithLabelValues("root", "", "total").Set(float64(len(f.userFairShares)))
+
+	f.metrics.AccountFairShareFactor.Reset()
+	f.metrics.AccountTotalUsage.Reset()
+	f.metrics.AccountTargetUsage.Reset()
+	f.metrics.AccountUserCount.Reset()
+	f.metrics.AccountHierarchyDepth.Reset()
+
+	parents := accountParents(f.accountShares)
+
+	usersPerAccount := make(map[string]map[string]bool)
+	for _, fairShare := range f.userFairShares {
+		if usersPerAccount[fairShare.Account] == nil {
+			usersPerAccount[fairShare.Account] = make(map[string]bool)
+		}
+		usersPerAccount[fairShare.Account][fairShare.UserName] = true
+	}
+
+	// Accounts are emitted in name order so the limit drops a stable set
+	accounts := make([]slurm.Share, len(f.accountShares))
+	copy(accounts, f.accountShares)
+	sort.SliceStable(accounts, func(i, j int) bool { return accounts[i].Account < accounts[j].Account })
+
+	maxDepth := 0
+	emitted := 0
+	for _, share := range accounts {
+		if share.Account == "" {
+			continue
+		}
+		_, depth := accountPath(share.Account, parents)
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		if f.config.MaxAccountsPerCollection > 0 && emitted >= f.config.MaxAccountsPerCollection {
+			continue
+		}
+		emitted++
+
+		parent := parents[share.Account]
+
+		// SLURM only reports a level fair-share (LevelFS) for account
+		// associations; it compares the account with its siblings
+		f.metrics.AccountFairShareFactor.WithLabelValues(share.Account, parent, strconv.Itoa(depth)).Set(share.FairshareLevel)
+		f.metrics.AccountTotalUsage.WithLabelValues(share.Account, parent, "billing").Set(share.EffectiveUsage)
+		f.metrics.AccountTargetUsage.WithLabelValues(share.Account, parent, "billing").Set(share.NormalizedShares)
+		f.metrics.AccountUserCount.WithLabelValues(share.Account, parent, "total").Set(float64(len(usersPerAccount[share.Account])))
+	}
+
+	f.metrics.AccountHierarchyDepth.WithLabelValues("root").Set(float64(maxDepth))
 	return nil
 }
 
@@ -1568,11 +1754,16 @@ func (f *FairShareCollector) analyzePolicyEffectiveness(ctx context.Context) err
 func (f *FairShareCollector) updatePolicyMetrics() {
 	metrics := f.policyAnalyzer.policyMetrics
 
-	f.metrics.PolicyOverallScore.WithLabelValues("default").Set(metrics.OverallScore)
-	f.metrics.PolicyBalanceScore.WithLabelValues("default").Set(metrics.BalanceScore)
-	f.metrics.PolicyFairnessScore.WithLabelValues("default").Set(metrics.FairnessScore)
-	f.metrics.PolicyEfficiencyScore.WithLabelValues("default").Set(metrics.EfficiencyScore)
-	f.metrics.PolicyGiniCoefficient.WithLabelValues("default").Set(metrics.GiniCoefficient)
+	cluster := f.clusterName
+	if cluster == "" {
+		cluster = "default"
+	}
+
+	f.metrics.PolicyOverallScore.WithLabelValues(cluster).Set(metrics.OverallScore)
+	f.metrics.PolicyBalanceScore.WithLabelValues(cluster).Set(metrics.BalanceScore)
+	f.metrics.PolicyFairnessScore.WithLabelValues(cluster).Set(metrics.FairnessScore)
+	f.metrics.PolicyEfficiencyScore.WithLabelValues(cluster).Set(metrics.EfficiencyScore)
+	f.metrics.PolicyGiniCoefficient.WithLabelValues(cluster).Set(metrics.GiniCoefficient)
 }
 
 func (f *FairShareCollector) analyzeTrends(ctx context.Context) error {
@@ -1621,26 +1812,54 @@ func (f *FairShareCollector) analyzeUserBehavior(ctx context.Context) error {
 }
 
 // Helper methods for analysis engines
+
+// analyzeViolations tracks one open violation per user association, dropping
+// violations once the association is back within its share
 func (v *FairShareViolationDetector) analyzeViolations(userFairShares map[string]*UserFairShare) {
-	for _, fairShare := range userFairShares {
-		if fairShare.IsViolating {
-			violationID := fmt.Sprintf("%s_%s_%d", fairShare.UserName, fairShare.Account, time.Now().Unix())
-			violation := &FairShareViolation{
-				ViolationID:   violationID,
+	now := time.Now()
+	for key := range v.violations {
+		if fairShare, ok := userFairShares[key]; !ok || !fairShare.IsViolating {
+			delete(v.violations, key)
+		}
+	}
+
+	for key, fairShare := range userFairShares {
+		if !fairShare.IsViolating {
+			continue
+		}
+
+		violation, ok := v.violations[key]
+		if !ok {
+			violation = &FairShareViolation{
+				ViolationID:   fmt.Sprintf("%s_%d", key, now.Unix()),
 				UserName:      fairShare.UserName,
 				Account:       fairShare.Account,
 				ViolationType: "overuse",
-				Severity:      "moderate",
-				StartTime:     time.Now().Add(-fairShare.ViolationDuration),
-				Duration:      fairShare.ViolationDuration,
-				CurrentFactor: fairShare.FairShareFactor,
-				TargetFactor:  1.0,
-				Deviation:     math.Abs(fairShare.FairShareFactor - 1.0),
-				ClusterImpact: fairShare.ViolationSeverity * 0.1,
-				IsResolved:    false,
+				StartTime:     now.Add(-fairShare.ViolationDuration),
 			}
-			v.violations[violationID] = violation
+			v.violations[key] = violation
 		}
+
+		violation.Severity = v.severityLevel(fairShare.UsageRatio - 1.0)
+		violation.Duration = fairShare.ViolationDuration
+		violation.CurrentFactor = fairShare.FairShareFactor
+		violation.TargetFactor = 1.0
+		violation.Deviation = fairShare.UsageRatio - 1.0
+		violation.ClusterImpact = fairShare.ViolationSeverity * fairShare.NormalizedShares
+	}
+}
+
+// severityLevel maps how far usage exceeds the share onto a severity name
+func (v *FairShareViolationDetector) severityLevel(excess float64) string {
+	switch {
+	case excess >= v.thresholds.CriticalThreshold:
+		return "critical"
+	case excess >= v.thresholds.MajorThreshold:
+		return "major"
+	case excess >= v.thresholds.ModerateThreshold:
+		return "moderate"
+	default:
+		return "minor"
 	}
 }
 
@@ -1652,9 +1871,11 @@ func (p *FairSharePolicyAnalyzer) analyzePolicyEffectiveness(userFairShares map[
 	}
 
 	var fairShareSum, usageRatioSum, violationCount float64
+	usageRatios := make([]float64, 0, totalUsers)
 	for _, fairShare := range userFairShares {
 		fairShareSum += fairShare.FairShareFactor
 		usageRatioSum += fairShare.UsageRatio
+		usageRatios = append(usageRatios, fairShare.UsageRatio)
 		if fairShare.IsViolating {
 			violationCount++
 		}
@@ -1671,5 +1892,28 @@ func (p *FairSharePolicyAnalyzer) analyzePolicyEffectiveness(userFairShares map[
 	p.policyMetrics.FairnessScore = math.Max(0, 1.0-math.Abs(averageFairShare-1.0))
 	p.policyMetrics.EfficiencyScore = math.Min(averageUsageRatio, 1.0)
 	p.policyMetrics.ViolationRate = violationRate
-	p.policyMetrics.GiniCoefficient = 0.3 // Simplified calculation
+	p.policyMetrics.GiniCoefficient = giniCoefficient(usageRatios)
+}
+
+// giniCoefficient measures inequality across values (0 = perfectly equal,
+// approaching 1 = one value holds everything)
+func giniCoefficient(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, n)
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var sum, weighted float64
+	for i, v := range sorted {
+		sum += v
+		weighted += float64(i+1) * v
+	}
+	if sum == 0 {
+		return 0
+	}
+	return (2*weighted)/(float64(n)*sum) - float64(n+1)/float64(n)
 }
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func newTestFairShareCollector(t *testing.T, client slurm.SlurmClient) *FairShareCollector {
+	t.Helper()
+	cfg := DefaultFairShareConfig()
+	cfg.EnablePriorityAnalysis = false
+	cfg.EnableQueueAnalysis = false
+	cfg.EnableBehaviorAnalysis = false
+
+	collector, err := NewFairShareCollector(client, slog.Default(), cfg)
+	require.NoError(t, err)
+	return collector
+}
+
+func testSharesList() *slurm.SharesList {
+	return &slurm.SharesList{
+		Shares: []slurm.Share{
+			{Cluster: "hpc", Account: "root", NormalizedShares: 1, EffectiveUsage: 1, FairshareLevel: 1},
+			{Cluster: "hpc", Account: "physics", ParentAccount: "root", NormalizedShares: 0.6, EffectiveUsage: 0.5, FairshareLevel: 1.2},
+			{Cluster: "hpc", Account: "hep", ParentAccount: "physics", NormalizedShares: 0.3, EffectiveUsage: 0.4, FairshareLevel: 0.75},
+			{Cluster: "hpc", Account: "hep", User: "alice", RawShares: 1, NormalizedShares: 0.1, EffectiveUsage: 0.25, FairshareUsage: 0.2},
+			{Cluster: "hpc", Account: "physics", User: "bob", RawShares: 2, NormalizedShares: 0.2, EffectiveUsage: 0.1, FairshareUsage: 0.8},
+		},
+	}
+}
+
+func TestFairShareCollector_CollectFromShares(t *testing.T) {
+	t.Parallel()
+	mockClient := new(mocks.MockSlurmClient)
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(testSharesList(), nil)
+
+	collector := newTestFairShareCollector(t, mockClient)
+
+	ch := make(chan prometheus.Metric, 1000)
+	err := collector.CollectWithContext(context.Background(), ch)
+	close(ch)
+	require.NoError(t, err)
+
+	m := collector.metrics
+	assert.InDelta(t, 0.2, testutil.ToFloat64(m.UserFairShareFactor.WithLabelValues("alice", "hep", "")), 1e-9)
+	assert.InDelta(t, 2.5, testutil.ToFloat64(m.UserUsageRatio.WithLabelValues("alice", "hep", "")), 1e-9)
+	assert.InDelta(t, 0.5, testutil.ToFloat64(m.UserUsageRatio.WithLabelValues("bob", "physics", "")), 1e-9)
+
+	// alice uses 2.5x her share, bob stays under his
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.FairShareViolations.WithLabelValues("alice", "hep", "overuse", "critical")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.FairShareViolations))
+
+	// Account hierarchy uses LevelFS and the parent chain
+	assert.InDelta(t, 0.75, testutil.ToFloat64(m.AccountFairShareFactor.WithLabelValues("hep", "physics", "2")), 1e-9)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.AccountHierarchyDepth.WithLabelValues("root")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.AccountUserCount.WithLabelValues("physics", "root", "total")))
+
+	// One of two users is violating; the cluster label comes from the shares
+	assert.InDelta(t, 0.5, testutil.ToFloat64(m.PolicyOverallScore.WithLabelValues("hpc")), 1e-9)
+	mockClient.AssertExpectations(t)
+}
+
+func TestFairShareCollector_FairSharePriority(t *testing.T) {
+	t.Parallel()
+	mockClient := new(mocks.MockSlurmClient)
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(testSharesList(), nil)
+
+	// Without PriorityWeightFairshare there is no contribution to report
+	collector := newTestFairShareCollector(t, mockClient)
+	ch := make(chan prometheus.Metric, 1000)
+	require.NoError(t, collector.CollectWithContext(context.Background(), ch))
+	close(ch)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.metrics.UserFairSharePriority))
+
+	collector = newTestFairShareCollector(t, mockClient)
+	collector.config.PriorityWeights.FairShareWeight = 10000
+	ch = make(chan prometheus.Metric, 1000)
+	require.NoError(t, collector.CollectWithContext(context.Background(), ch))
+	close(ch)
+	assert.InDelta(t, 2000, testutil.ToFloat64(collector.metrics.UserFairSharePriority.WithLabelValues("alice", "hep", "")), 1e-9)
+	assert.InDelta(t, 8000, testutil.ToFloat64(collector.metrics.UserFairSharePriority.WithLabelValues("bob", "physics", "")), 1e-9)
+}
+
+func TestFairShareCollector_DropsDepartedUsers(t *testing.T) {
+	t.Parallel()
+	mockClient := new(mocks.MockSlurmClient)
+	first := testSharesList()
+	second := &slurm.SharesList{Shares: first.Shares[:4]}
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(first, nil).Once()
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(second, nil).Once()
+
+	collector := newTestFairShareCollector(t, mockClient)
+
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric, 1000)
+		require.NoError(t, collector.CollectWithContext(context.Background(), ch))
+		close(ch)
+	}
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.metrics.UserFairShareFactor))
+	assert.Len(t, collector.violationDetector.violations, 1)
+	mockClient.AssertExpectations(t)
+}
+
+func TestFairShareCollector_CollectError(t *testing.T) {
+	t.Parallel()
+	mockClient := new(mocks.MockSlurmClient)
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(nil, errors.New("slurmrestd unavailable"))
+
+	collector := newTestFairShareCollector(t, mockClient)
+
+	ch := make(chan prometheus.Metric, 1000)
+	err := collector.CollectWithContext(context.Background(), ch)
+	close(ch)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.metrics.FairShareCollectionErrors.WithLabelValues("collect", "collection_error")))
+}
+
+func TestGiniCoefficient(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 0.0, giniCoefficient(nil))
+	assert.InDelta(t, 0.0, giniCoefficient([]float64{1, 1, 1, 1}), 1e-9)
+	assert.InDelta(t, 0.75, giniCoefficient([]float64{0, 0, 0, 4}), 1e-9)
+}
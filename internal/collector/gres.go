@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"strconv"
+	"strings"
+)
+
+// gresKey identifies a generic resource by type and optional model (e.g. gpu:a100)
+type gresKey struct {
+	gresType string
+	model    string
+}
+
+// gresCounts maps a GRES type/model to a count
+type gresCounts map[gresKey]float64
+
+// add accumulates the counts from other into g
+func (g gresCounts) add(other gresCounts, factor float64) {
+	for key, count := range other {
+		g[key] += count * factor
+	}
+}
+
+// parseGRES parses a node GRES string as reported by slurmctld, for example
+// "gpu:a100:4(S:0-1),gpu:v100:2" or "gpu:(null):2(IDX:0,1),mps:0".
+func parseGRES(gres string) gresCounts {
+	counts := make(gresCounts)
+	for _, entry := range splitGRESList(gres) {
+		key, count, ok := parseGRESEntry(entry)
+		if !ok {
+			continue
+		}
+		counts[key] += count
+	}
+	return counts
+}
+
+// parseGRESEntry parses a single "type[:model][:count]" entry, ignoring any
+// trailing "(S:...)" or "(IDX:...)" annotations.
+func parseGRESEntry(entry string) (gresKey, float64, bool) {
+	// Untyped GRES in use are reported with a "(null)" model placeholder
+	entry = strings.ReplaceAll(entry, "(null)", "")
+	if idx := strings.Index(entry, "("); idx >= 0 {
+		entry = entry[:idx]
+	}
+
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return gresKey{}, 0, false
+	}
+
+	parts := strings.Split(entry, ":")
+	key := gresKey{gresType: parts[0]}
+	count := 1.0
+
+	switch len(parts) {
+	case 1:
+		// Bare type, e.g. "gpu"
+	case 2:
+		if n, ok := parseGRESCount(parts[1]); ok {
+			count = n
+		} else {
+			key.model = parts[1]
+		}
+	default:
+		key.model = parts[1]
+		n, ok := parseGRESCount(parts[2])
+		if !ok {
+			return gresKey{}, 0, false
+		}
+		count = n
+	}
+
+	return key, count, true
+}
+
+// parseGRESCount parses a GRES count with an optional K/M/G/T/P suffix
+func parseGRESCount(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	multiplier := 1.0
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1024
+	case "M":
+		multiplier = 1024 * 1024
+	case "G":
+		multiplier = 1024 * 1024 * 1024
+	case "T":
+		multiplier = 1024 * 1024 * 1024 * 1024
+	case "P":
+		multiplier = 1024 * 1024 * 1024 * 1024 * 1024
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n * multiplier, true
+}
+
+// splitGRESList splits a comma separated GRES list while keeping commas that
+// appear inside parenthesised annotations such as "(IDX:0,2)".
+func splitGRESList(s string) []string {
+	var entries []string
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				entries = append(entries, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		entries = append(entries, s[start:])
+	}
+	return entries
+}
+
+// ParseTRES parses a TRES string such as "cpu=4,mem=16G,node=1,gres/gpu:a100=2"
+// into a map keyed by TRES name. Values take an optional K/M/G/T/P suffix;
+// memory values are normalised to megabytes, which is the unit SLURM uses
+// for the mem TRES.
+func ParseTRES(tres string) map[string]float64 {
+	result := make(map[string]float64)
+	for _, entry := range strings.Split(tres, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || name == "" {
+			continue
+		}
+
+		if name == "mem" {
+			if mb, ok := parseMemoryMB(value); ok {
+				result[name] = mb
+			}
+			continue
+		}
+
+		if n, ok := parseGRESCount(value); ok {
+			result[name] = n
+		}
+	}
+	return result
+}
+
+// parseMemoryMB parses a memory value with an optional K/M/G/T/P suffix into megabytes
+func parseMemoryMB(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	multiplier := 1.0
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1.0 / 1024
+	case "M":
+		multiplier = 1
+	case "G":
+		multiplier = 1024
+	case "T":
+		multiplier = 1024 * 1024
+	case "P":
+		multiplier = 1024 * 1024 * 1024
+	default:
+		s += "M"
+	}
+	s = s[:len(s)-1]
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n * multiplier, true
+}
+
+// gresFromTRES extracts GRES counts from a TRES string. SLURM reports both the
+// untyped total ("gres/gpu=4") and the per-model breakdown ("gres/gpu:a100=4");
+// the per-model entries are preferred so totals are not double counted.
+func gresFromTRES(tres string) gresCounts {
+	typed := make(gresCounts)
+	untyped := make(gresCounts)
+
+	for name, value := range ParseTRES(tres) {
+		gres, ok := strings.CutPrefix(name, "gres/")
+		if !ok {
+			continue
+		}
+		gresType, model, hasModel := strings.Cut(gres, ":")
+		if hasModel {
+			typed[gresKey{gresType: gresType, model: model}] += value
+		} else {
+			untyped[gresKey{gresType: gresType}] += value
+		}
+	}
+
+	hasTyped := make(map[string]bool)
+	for key := range typed {
+		hasTyped[key.gresType] = true
+	}
+	for key, value := range untyped {
+		if !hasTyped[key.gresType] {
+			typed[key] = value
+		}
+	}
+	return typed
+}
+
+// gresFromTRESPer parses a "tres_per_node"/"tres_per_job" style string such as
+// "gres/gpu:a100:2,gres/mps:100" (or the legacy "gres:gpu:2" form).
+func gresFromTRESPer(tres string) gresCounts {
+	counts := make(gresCounts)
+	for _, entry := range splitGRESList(tres) {
+		entry = strings.TrimSpace(entry)
+		gres, ok := strings.CutPrefix(entry, "gres/")
+		if !ok {
+			gres, ok = strings.CutPrefix(entry, "gres:")
+		}
+		if !ok {
+			continue
+		}
+		// tres_per_* may also use "gpu=2" instead of "gpu:2"
+		gres = strings.ReplaceAll(gres, "=", ":")
+		key, count, ok := parseGRESEntry(gres)
+		if !ok {
+			continue
+		}
+		counts[key] += count
+	}
+	return counts
+}
+
+// jobGRES returns the generic resources held by a running job, or requested by
+// a pending one when no allocation has been made yet.
+func jobGRES(tresAlloc, tresPerNode, tresPerJob *string, nodeCount *uint32) gresCounts {
+	if tresAlloc != nil && *tresAlloc != "" {
+		if counts := gresFromTRES(*tresAlloc); len(counts) > 0 {
+			return counts
+		}
+	}
+
+	counts := make(gresCounts)
+	if tresPerJob != nil && *tresPerJob != "" {
+		counts.add(gresFromTRESPer(*tresPerJob), 1)
+	}
+	if tresPerNode != nil && *tresPerNode != "" {
+		nodes := 1.0
+		if nodeCount != nil && *nodeCount > 0 {
+			nodes = float64(*nodeCount)
+		}
+		counts.add(gresFromTRESPer(*tresPerNode), nodes)
+	}
+	return counts
+}
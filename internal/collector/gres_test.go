@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGRES(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected gresCounts
+	}{
+		{
+			name:     "empty",
+			input:    "",
+			expected: gresCounts{},
+		},
+		{
+			name:  "typed with socket annotation",
+			input: "gpu:a100:4(S:0-1),gpu:v100:2",
+			expected: gresCounts{
+				{gresType: "gpu", model: "a100"}: 4,
+				{gresType: "gpu", model: "v100"}: 2,
+			},
+		},
+		{
+			name:  "untyped count",
+			input: "gpu:8",
+			expected: gresCounts{
+				{gresType: "gpu"}: 8,
+			},
+		},
+		{
+			name:  "used with null model and index list",
+			input: "gpu:(null):2(IDX:0,3),mps:0",
+			expected: gresCounts{
+				{gresType: "gpu"}: 2,
+				{gresType: "mps"}: 0,
+			},
+		},
+		{
+			name:  "used with model and index range",
+			input: "gpu:a100:3(IDX:0-2)",
+			expected: gresCounts{
+				{gresType: "gpu", model: "a100"}: 3,
+			},
+		},
+		{
+			name:  "count with suffix",
+			input: "mps:1K",
+			expected: gresCounts{
+				{gresType: "mps"}: 1024,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, parseGRES(tt.input))
+		})
+	}
+}
+
+func TestParseTRES(t *testing.T) {
+	t.Parallel()
+
+	tres := ParseTRES("cpu=16,mem=64G,node=2,billing=20,gres/gpu=4,gres/gpu:a100=4")
+
+	assert.Equal(t, 16.0, tres["cpu"])
+	assert.Equal(t, 64.0*1024, tres["mem"])
+	assert.Equal(t, 2.0, tres["node"])
+	assert.Equal(t, 20.0, tres["billing"])
+	assert.Equal(t, 4.0, tres["gres/gpu"])
+	assert.Equal(t, 4.0, tres["gres/gpu:a100"])
+
+	// Counts and memory both take every suffix SLURM prints
+	tres = ParseTRES("cpu=2K,mem=1P,fs/disk=3M,billing=bad")
+	assert.Equal(t, 2.0*1024, tres["cpu"])
+	assert.Equal(t, 1024.0*1024*1024, tres["mem"])
+	assert.Equal(t, 3.0*1024*1024, tres["fs/disk"])
+	assert.NotContains(t, tres, "billing")
+}
+
+func TestGRESFromTRES(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prefers typed entries", func(t *testing.T) {
+		t.Parallel()
+		counts := gresFromTRES("cpu=8,gres/gpu=3,gres/gpu:a100=2,gres/gpu:v100=1")
+		assert.Equal(t, gresCounts{
+			{gresType: "gpu", model: "a100"}: 2,
+			{gresType: "gpu", model: "v100"}: 1,
+		}, counts)
+	})
+
+	t.Run("falls back to untyped", func(t *testing.T) {
+		t.Parallel()
+		counts := gresFromTRES("cpu=8,mem=1000M,gres/gpu=2")
+		assert.Equal(t, gresCounts{{gresType: "gpu"}: 2}, counts)
+	})
+}
+
+func TestJobGRES(t *testing.T) {
+	t.Parallel()
+
+	strPtr := func(s string) *string { return &s }
+	uint32Ptr := func(i uint32) *uint32 { return &i }
+
+	t.Run("running job uses allocation", func(t *testing.T) {
+		t.Parallel()
+		counts := jobGRES(strPtr("cpu=4,gres/gpu=2,gres/gpu:a100=2"), strPtr("gres/gpu:a100:1"), nil, uint32Ptr(2))
+		assert.Equal(t, gresCounts{{gresType: "gpu", model: "a100"}: 2}, counts)
+	})
+
+	t.Run("pending job multiplies per-node request", func(t *testing.T) {
+		t.Parallel()
+		counts := jobGRES(nil, strPtr("gres/gpu:a100:4"), nil, uint32Ptr(3))
+		assert.Equal(t, gresCounts{{gresType: "gpu", model: "a100"}: 12}, counts)
+	})
+
+	t.Run("pending job with legacy per-job request", func(t *testing.T) {
+		t.Parallel()
+		counts := jobGRES(strPtr(""), nil, strPtr("gres:gpu:2"), nil)
+		assert.Equal(t, gresCounts{{gresType: "gpu"}: 2}, counts)
+	})
+}
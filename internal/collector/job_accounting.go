@@ -0,0 +1,350 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	accountingCollectorSubsystem = "accounting"
+)
+
+// AccountingJob is a finished job as recorded by slurmdbd
+type AccountingJob struct {
+	JobID      string
+	User       string
+	Account    string
+	Partition  string
+	QoS        string
+	State      string
+	ExitCode   int
+	Signal     int
+	SubmitTime time.Time
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// JobAccountingReader lists jobs recorded by slurmdbd between start and end.
+// slurmrestd's slurmctld job list forgets finished jobs after MinJobAge, so
+// historical data has to come from the accounting database instead.
+type JobAccountingReader interface {
+	ListAccountingJobs(ctx context.Context, start, end time.Time) ([]AccountingJob, error)
+}
+
+// AccountingCollectorOptions configures the job accounting collector
+type AccountingCollectorOptions struct {
+	// Interval is the minimum time between slurmdbd polls; scrapes in between
+	// report the counters accumulated so far
+	Interval time.Duration
+
+	// Timeout bounds each slurmdbd poll
+	Timeout time.Duration
+
+	// Lookback is the window queried when no high-water mark exists yet
+	Lookback time.Duration
+
+	// Overlap is re-queried before the high-water mark to pick up records
+	// slurmdbd commits late; jobs seen in it are not counted twice
+	Overlap time.Duration
+
+	// StateFile persists the high-water mark across restarts when set
+	StateFile string
+}
+
+// accountingState is the persisted high-water mark together with the jobs
+// already counted inside the overlap window
+type accountingState struct {
+	HighWaterMark time.Time            `json:"high_water_mark"`
+	Counted       map[string]time.Time `json:"counted"`
+}
+
+// AccountingCollector exports counters and histograms for finished jobs read
+// from slurmdbd over a sliding window
+type AccountingCollector struct {
+	reader JobAccountingReader
+	opts   AccountingCollectorOptions
+	logger *logrus.Entry
+	now    func() time.Time
+
+	mu       sync.Mutex
+	enabled  bool
+	state    accountingState
+	lastPoll time.Time
+
+	jobsTotal     *prometheus.CounterVec
+	waitSeconds   *prometheus.HistogramVec
+	runSeconds    *prometheus.HistogramVec
+	highWaterMark prometheus.Gauge
+	pollErrors    prometheus.Counter
+}
+
+// NewAccountingCollector creates a historical job accounting collector and
+// restores its high-water mark from opts.StateFile if present
+func NewAccountingCollector(reader JobAccountingReader, opts AccountingCollectorOptions, logger *logrus.Entry) *AccountingCollector {
+	// Wait and run times range from seconds to days
+	buckets := prometheus.ExponentialBuckets(60, 2, 14)
+
+	c := &AccountingCollector{
+		reader:  reader,
+		opts:    opts,
+		logger:  logger.WithField("collector", "accounting"),
+		now:     time.Now,
+		enabled: true,
+		state:   accountingState{Counted: make(map[string]time.Time)},
+
+		jobsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: accountingCollectorSubsystem,
+				Name:      "jobs_total",
+				Help:      "Finished jobs recorded by slurmdbd",
+			},
+			[]string{"user", "account", "partition", "state", "exit_code"},
+		),
+		waitSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:                       namespace,
+				Subsystem:                       accountingCollectorSubsystem,
+				Name:                            "job_wait_seconds",
+				Help:                            "Time finished jobs spent queued before starting",
+				Buckets:                         buckets,
+				NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+				NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+				NativeHistogramMinResetDuration: nativeHistogramMinResetDuration,
+			},
+			[]string{"partition"},
+		),
+		runSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:                       namespace,
+				Subsystem:                       accountingCollectorSubsystem,
+				Name:                            "job_runtime_seconds",
+				Help:                            "Run time of finished jobs",
+				Buckets:                         buckets,
+				NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+				NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+				NativeHistogramMinResetDuration: nativeHistogramMinResetDuration,
+			},
+			[]string{"partition", "state"},
+		),
+		highWaterMark: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: accountingCollectorSubsystem,
+				Name:      "high_water_mark_timestamp_seconds",
+				Help:      "End of the last slurmdbd window that was fully processed",
+			},
+		),
+		pollErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: accountingCollectorSubsystem,
+				Name:      "poll_errors_total",
+				Help:      "Failed slurmdbd job accounting polls",
+			},
+		),
+	}
+
+	c.loadState()
+	return c
+}
+
+// Name returns the collector name
+func (c *AccountingCollector) Name() string {
+	return "accounting"
+}
+
+// Describe sends metric descriptions to the channel
+func (c *AccountingCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.jobsTotal.Describe(ch)
+	c.waitSeconds.Describe(ch)
+	c.runSeconds.Describe(ch)
+	c.highWaterMark.Describe(ch)
+	c.pollErrors.Describe(ch)
+}
+
+// Collect polls slurmdbd when the interval has elapsed and reports the
+// accumulated counters
+func (c *AccountingCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	var err error
+	if c.lastPoll.IsZero() || c.now().Sub(c.lastPoll) >= c.opts.Interval {
+		if err = c.poll(ctx); err != nil {
+			c.pollErrors.Inc()
+			c.logger.WithError(err).Error("Failed to poll job accounting")
+		}
+	}
+	c.mu.Unlock()
+
+	c.jobsTotal.Collect(ch)
+	c.waitSeconds.Collect(ch)
+	c.runSeconds.Collect(ch)
+	c.highWaterMark.Collect(ch)
+	c.pollErrors.Collect(ch)
+	return err
+}
+
+// poll queries the window since the high-water mark and counts newly
+// finished jobs. The caller must hold c.mu.
+func (c *AccountingCollector) poll(ctx context.Context) error {
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	end := c.now()
+	start := end.Add(-c.opts.Lookback)
+	if !c.state.HighWaterMark.IsZero() {
+		start = c.state.HighWaterMark.Add(-c.opts.Overlap)
+	}
+
+	jobs, err := c.reader.ListAccountingJobs(ctx, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to list accounting jobs: %w", err)
+	}
+	c.lastPoll = end
+
+	var counted int
+	for _, job := range jobs {
+		// Jobs still running or queued are reported by the jobs collector
+		if job.EndTime.IsZero() || job.EndTime.Before(start) || job.EndTime.After(end) || !isAccountingFinalState(job.State) {
+			continue
+		}
+		if _, seen := c.state.Counted[job.JobID]; seen {
+			continue
+		}
+		c.state.Counted[job.JobID] = job.EndTime
+		counted++
+
+		exitCode := fmt.Sprintf("%d:%d", job.ExitCode, job.Signal)
+		c.jobsTotal.WithLabelValues(job.User, job.Account, job.Partition, job.State, exitCode).Inc()
+
+		if !job.StartTime.IsZero() {
+			if !job.SubmitTime.IsZero() && !job.StartTime.Before(job.SubmitTime) {
+				c.waitSeconds.WithLabelValues(job.Partition).Observe(job.StartTime.Sub(job.SubmitTime).Seconds())
+			}
+			if !job.EndTime.Before(job.StartTime) {
+				c.runSeconds.WithLabelValues(job.Partition, job.State).Observe(job.EndTime.Sub(job.StartTime).Seconds())
+			}
+		}
+	}
+
+	// Jobs that ended before the next overlap window can no longer be returned
+	c.state.HighWaterMark = end
+	cutoff := end.Add(-c.opts.Overlap)
+	for id, endTime := range c.state.Counted {
+		if endTime.Before(cutoff) {
+			delete(c.state.Counted, id)
+		}
+	}
+	c.highWaterMark.Set(float64(end.Unix()))
+
+	c.logger.WithFields(logrus.Fields{
+		"window_start": start,
+		"window_end":   end,
+		"returned":     len(jobs),
+		"counted":      counted,
+	}).Debug("Job accounting polled")
+
+	if err := c.saveState(); err != nil {
+		c.logger.WithError(err).Warn("Failed to persist job accounting high-water mark")
+	}
+	return nil
+}
+
+// loadState restores the high-water mark from the state file
+func (c *AccountingCollector) loadState() {
+	if c.opts.StateFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.opts.StateFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.WithError(err).Warn("Failed to read job accounting state, starting from lookback window")
+		}
+		return
+	}
+
+	var state accountingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		c.logger.WithError(err).Warn("Ignoring corrupt job accounting state, starting from lookback window")
+		return
+	}
+	if state.Counted == nil {
+		state.Counted = make(map[string]time.Time)
+	}
+
+	c.state = state
+	if !state.HighWaterMark.IsZero() {
+		c.highWaterMark.Set(float64(state.HighWaterMark.Unix()))
+	}
+}
+
+// saveState atomically writes the high-water mark to the state file. The
+// caller must hold c.mu.
+func (c *AccountingCollector) saveState() error {
+	if c.opts.StateFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.opts.StateFile), filepath.Base(c.opts.StateFile)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.opts.StateFile); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+// IsEnabled returns whether this collector is enabled
+func (c *AccountingCollector) IsEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+// SetEnabled enables or disables the collector
+func (c *AccountingCollector) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = enabled
+}
+
+// isAccountingFinalState reports whether a slurmdbd job state is terminal
+func isAccountingFinalState(state string) bool {
+	switch state {
+	case JobStateCompleted, "FAILED", "CANCELLED", "TIMEOUT", "NODE_FAIL", "PREEMPTED",
+		"BOOT_FAIL", "DEADLINE", "OUT_OF_MEMORY":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAccountingReader struct {
+	jobs    []AccountingJob
+	err     error
+	windows [][2]time.Time
+}
+
+func (f *fakeAccountingReader) ListAccountingJobs(_ context.Context, start, end time.Time) ([]AccountingJob, error) {
+	f.windows = append(f.windows, [2]time.Time{start, end})
+	return f.jobs, f.err
+}
+
+var accountingTestNow = time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+
+func accountingTestJobs() []AccountingJob {
+	return []AccountingJob{
+		{
+			JobID: "101", User: "alice", Account: "physics", Partition: "compute", State: "COMPLETED",
+			SubmitTime: accountingTestNow.Add(-50 * time.Minute),
+			StartTime:  accountingTestNow.Add(-40 * time.Minute),
+			EndTime:    accountingTestNow.Add(-5 * time.Minute),
+		},
+		{
+			JobID: "102", User: "bob", Account: "chem", Partition: "compute", State: "FAILED", ExitCode: 1,
+			SubmitTime: accountingTestNow.Add(-30 * time.Minute),
+			StartTime:  accountingTestNow.Add(-20 * time.Minute),
+			EndTime:    accountingTestNow.Add(-10 * time.Minute),
+		},
+		{
+			// Still running: left to the jobs collector
+			JobID: "103", User: "bob", Account: "chem", Partition: "compute", State: "RUNNING",
+			SubmitTime: accountingTestNow.Add(-30 * time.Minute),
+			StartTime:  accountingTestNow.Add(-20 * time.Minute),
+		},
+	}
+}
+
+func newTestAccountingCollector(reader JobAccountingReader, stateFile string) *AccountingCollector {
+	c := NewAccountingCollector(reader, AccountingCollectorOptions{
+		Interval:  5 * time.Minute,
+		Lookback:  time.Hour,
+		Overlap:   15 * time.Minute,
+		StateFile: stateFile,
+	}, logrus.NewEntry(logrus.New()))
+	c.now = func() time.Time { return accountingTestNow }
+	return c
+}
+
+func collectAccounting(t *testing.T, c *AccountingCollector) error {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 1000)
+	err := c.Collect(context.Background(), ch)
+	close(ch)
+	return err
+}
+
+func TestAccountingCollector_CountsFinishedJobs(t *testing.T) {
+	t.Parallel()
+	reader := &fakeAccountingReader{jobs: accountingTestJobs()}
+	c := newTestAccountingCollector(reader, "")
+
+	require.NoError(t, collectAccounting(t, c))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.jobsTotal.WithLabelValues("alice", "physics", "compute", "COMPLETED", "0:0")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.jobsTotal.WithLabelValues("bob", "chem", "compute", "FAILED", "1:0")))
+	assert.Equal(t, 2, testutil.CollectAndCount(c.jobsTotal))
+	assert.Equal(t, 1, testutil.CollectAndCount(c.waitSeconds))
+	assert.Equal(t, float64(accountingTestNow.Unix()), testutil.ToFloat64(c.highWaterMark))
+
+	// The first poll covers the lookback window
+	require.Len(t, reader.windows, 1)
+	assert.Equal(t, accountingTestNow.Add(-time.Hour), reader.windows[0][0])
+}
+
+func TestAccountingCollector_OverlapIsNotCountedTwice(t *testing.T) {
+	t.Parallel()
+	reader := &fakeAccountingReader{jobs: accountingTestJobs()}
+	c := newTestAccountingCollector(reader, "")
+
+	require.NoError(t, collectAccounting(t, c))
+
+	// Scrapes within the interval do not poll again
+	require.NoError(t, collectAccounting(t, c))
+	assert.Len(t, reader.windows, 1)
+
+	c.now = func() time.Time { return accountingTestNow.Add(5 * time.Minute) }
+	require.NoError(t, collectAccounting(t, c))
+
+	require.Len(t, reader.windows, 2)
+	assert.Equal(t, accountingTestNow.Add(-15*time.Minute), reader.windows[1][0])
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.jobsTotal.WithLabelValues("alice", "physics", "compute", "COMPLETED", "0:0")))
+}
+
+func TestAccountingCollector_PersistsHighWaterMark(t *testing.T) {
+	t.Parallel()
+	stateFile := filepath.Join(t.TempDir(), "accounting.json")
+	reader := &fakeAccountingReader{jobs: accountingTestJobs()}
+	require.NoError(t, collectAccounting(t, newTestAccountingCollector(reader, stateFile)))
+
+	// A restarted collector resumes from the high-water mark and skips jobs
+	// already counted in the overlap window
+	restarted := newTestAccountingCollector(reader, stateFile)
+	restarted.now = func() time.Time { return accountingTestNow.Add(time.Minute) }
+	require.NoError(t, collectAccounting(t, restarted))
+
+	require.Len(t, reader.windows, 2)
+	assert.Equal(t, accountingTestNow.Add(-15*time.Minute), reader.windows[1][0])
+	assert.Equal(t, 0, testutil.CollectAndCount(restarted.jobsTotal))
+}
+
+func TestAccountingCollector_PollError(t *testing.T) {
+	t.Parallel()
+	reader := &fakeAccountingReader{err: errors.New("slurmdbd unavailable")}
+	c := newTestAccountingCollector(reader, "")
+
+	assert.Error(t, collectAccounting(t, c))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.pollErrors))
+
+	// A failed poll is retried on the next scrape
+	assert.Error(t, collectAccounting(t, c))
+	assert.Len(t, reader.windows, 2)
+}
@@ -68,6 +68,34 @@ type JobPrioritySLURMClient interface {
 	ValidatePriorityPrediction(ctx context.Context, jobID string) (*PriorityPredictionValidation, error)
 }
 
+// PrioritySLURMClient serves both the job priority and the priority factors
+// collectors
+type PrioritySLURMClient interface {
+	JobPrioritySLURMClient
+	PriorityFactorsSLURMClient
+}
+
+// PriorityTargetLister is optionally implemented by a JobPrioritySLURMClient
+// or PriorityFactorsSLURMClient to supply the jobs, users, accounts,
+// partitions and QoS the collectors should analyse. Without it the collectors
+// use sample targets.
+type PriorityTargetLister interface {
+	ListPriorityTargets(ctx context.Context) (*PriorityTargets, error)
+}
+
+// PriorityTargets lists the subjects of a priority analysis pass
+type PriorityTargets struct {
+	JobIDs     []string `json:"job_ids"`
+	Users      []string `json:"users"`
+	Accounts   []string `json:"accounts"`
+	Partitions []string `json:"partitions"`
+	QoS        []string `json:"qos"`
+
+	// ValidatedJobIDs are jobs whose predicted start can be checked against
+	// the actual start
+	ValidatedJobIDs []string `json:"validated_job_ids"`
+}
+
 // DetailedJobPriorityFactors represents detailed priority breakdown
 type DetailedJobPriorityFactors struct {
 	JobID         string `json:"job_id"`
@@ -258,7 +286,7 @@ func NewJobPriorityCollector(client JobPrioritySLURMClient) *JobPriorityCollecto
 
 		jobPriorityRank: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_job_priority_rank",
+				Name: "slurm_job_priority_queue_rank",
 				Help: "Queue rank based on priority",
 			},
 			[]string{"job_id", "user", "account", "partition", "rank_type"},
@@ -323,7 +351,7 @@ func NewJobPriorityCollector(client JobPrioritySLURMClient) *JobPriorityCollecto
 		// Priority Prediction Metrics
 		jobEstimatedWaitTime: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_job_estimated_wait_time_seconds",
+				Name: "slurm_job_priority_estimated_wait_seconds",
 				Help: "Estimated wait time for job scheduling in seconds",
 			},
 			[]string{"job_id", "user", "account", "partition", "method"},
@@ -533,34 +561,33 @@ func (c *JobPriorityCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface
 func (c *JobPriorityCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
+	_ = c.CollectWithContext(context.Background(), ch)
+}
 
+// CollectWithContext runs a job priority analysis pass and sends the metrics to ch
+func (c *JobPriorityCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	// Reset metrics
 	c.resetMetrics()
 
 	// Collect system priority statistics
 	c.collectSystemPriorityStats(ctx)
 
-	// Get sample job IDs for priority analysis
-	sampleJobIDs := c.getSampleJobIDs()
-	for _, jobID := range sampleJobIDs {
+	targets := c.getTargets(ctx)
+
+	for _, jobID := range targets.JobIDs {
 		c.collectJobPriorityMetrics(ctx, jobID)
 	}
 
-	// Get sample partitions for queue analysis
-	samplePartitions := c.getSamplePartitions()
-	for _, partition := range samplePartitions {
+	for _, partition := range targets.Partitions {
 		c.collectQueueAnalysis(ctx, partition)
 	}
 
-	// Get sample users for priority pattern analysis
-	sampleUsers := c.getSampleUsers()
-	for _, user := range sampleUsers {
+	for _, user := range targets.Users {
 		c.collectUserPriorityPatterns(ctx, user)
 	}
 
 	// Collect prediction validation metrics
-	c.collectPredictionValidation(ctx)
+	c.collectPredictionValidation(ctx, targets.ValidatedJobIDs)
 
 	// Collect all metrics
 	c.jobPriorityScore.Collect(ch)
@@ -593,6 +620,8 @@ func (c *JobPriorityCollector) Collect(ch chan<- prometheus.Metric) {
 	c.predictionError.Collect(ch)
 	c.predictionConfidence.Collect(ch)
 	c.predictionModel.Collect(ch)
+
+	return ctx.Err()
 }
 
 func (c *JobPriorityCollector) resetMetrics() {
@@ -730,9 +759,8 @@ func (c *JobPriorityCollector) collectUserPriorityPatterns(ctx context.Context,
 	c.userSubmissionPattern.WithLabelValues(pattern.UserName, pattern.AccountName, "resource_efficiency").Set(pattern.ResourceEfficiency)
 }
 
-func (c *JobPriorityCollector) collectPredictionValidation(ctx context.Context) {
-	sampleJobIDs := c.getSampleJobIDs()
-	for _, jobID := range sampleJobIDs {
+func (c *JobPriorityCollector) collectPredictionValidation(ctx context.Context, jobIDs []string) {
+	for _, jobID := range jobIDs {
 		validation, err := c.client.ValidatePriorityPrediction(ctx, jobID)
 		if err != nil {
 			continue
@@ -748,6 +776,26 @@ func (c *JobPriorityCollector) collectPredictionValidation(ctx context.Context)
 	}
 }
 
+// getTargets asks the client which jobs, partitions and users to analyse,
+// falling back to the sample targets for clients that cannot list them
+func (c *JobPriorityCollector) getTargets(ctx context.Context) *PriorityTargets {
+	if lister, ok := c.client.(PriorityTargetLister); ok {
+		targets, err := lister.ListPriorityTargets(ctx)
+		if err != nil {
+			log.Printf("Error listing job priority targets: %v", err)
+			return &PriorityTargets{}
+		}
+		return targets
+	}
+
+	return &PriorityTargets{
+		JobIDs:          c.getSampleJobIDs(),
+		Partitions:      c.getSamplePartitions(),
+		Users:           c.getSampleUsers(),
+		ValidatedJobIDs: c.getSampleJobIDs(),
+	}
+}
+
 // Simplified mock data generators for testing purposes
 func (c *JobPriorityCollector) getSampleJobIDs() []string {
 	return []string{"12345", "12346", "12347"}
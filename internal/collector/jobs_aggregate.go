@@ -0,0 +1,346 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+const (
+	jobsAggregateSubsystem = "jobs"
+)
+
+// defaultJobsAggregateGroupBy is used when aggregate mode is enabled without
+// an explicit label set
+var defaultJobsAggregateGroupBy = []string{"account", "partition", "qos", "state"}
+
+// jobWaitBuckets covers queue waits from a minute to several days
+var jobWaitBuckets = prometheus.ExponentialBuckets(60, 2, 14)
+
+// jobAggregate accumulates the jobs sharing one aggregation label set
+type jobAggregate struct {
+	labelValues []string
+	count       float64
+	cpus        float64
+	memoryBytes float64
+	gpus        float64
+}
+
+// jobAggregateWait accumulates the queue waits of the jobs sharing one
+// aggregation label set, without the state label
+type jobAggregateWait struct {
+	labelValues []string
+	wait        prometheus.Histogram
+}
+
+// SetMode switches the collector between per-job series and aggregate mode.
+// In aggregate mode jobs are grouped by the configured labels and per-job
+// series are only kept for the top-N longest-pending and largest jobs.
+func (c *JobsSimpleCollector) SetMode(mode string, aggregate config.JobsAggregateConfig) {
+	groupBy := aggregate.GroupBy
+	if len(groupBy) == 0 {
+		groupBy = defaultJobsAggregateGroupBy
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	enabled := mode == config.JobsModeAggregate
+	changed := enabled != c.aggregate || !slices.Equal(groupBy, c.aggregateGroupBy)
+	c.aggregate = enabled
+	c.aggregateTopN = aggregate.TopN
+	if !changed {
+		return
+	}
+	if !slices.Equal(groupBy, c.aggregateGroupBy) {
+		c.aggregateGroupBy = append([]string(nil), groupBy...)
+		// Rebuild the aggregate descriptors with the new variable labels
+		c.initializeAggregateMetrics(c.constLabels())
+	}
+	// Waits observed under another mode or label set start over
+	c.jobWaits = nil
+	c.startedJobs = nil
+	c.waitsSeeded = false
+}
+
+// initializeAggregateMetrics creates the aggregate-mode descriptors
+func (c *JobsSimpleCollector) initializeAggregateMetrics(constLabels prometheus.Labels) {
+	groupBy := c.aggregateGroupBy
+	if len(groupBy) == 0 {
+		groupBy = defaultJobsAggregateGroupBy
+	}
+
+	c.jobsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsAggregateSubsystem, "count"),
+		"Number of jobs in each aggregation group",
+		groupBy,
+		constLabels,
+	)
+
+	c.jobsCPUs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsAggregateSubsystem, "cpus"),
+		"Total CPUs allocated to, or requested by, the jobs in each aggregation group",
+		groupBy,
+		constLabels,
+	)
+
+	c.jobsMemory = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsAggregateSubsystem, "memory_bytes"),
+		"Total memory allocated to, or requested by, the jobs in each aggregation group over all of their nodes in bytes",
+		groupBy,
+		constLabels,
+	)
+
+	c.jobsGPUs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsAggregateSubsystem, "gpus"),
+		"Total GPUs allocated to, or requested by, the jobs in each aggregation group",
+		groupBy,
+		constLabels,
+	)
+
+	c.jobsWait = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsAggregateSubsystem, "wait_seconds"),
+		"Queue wait, start minus submit time, of the jobs started in each aggregation group",
+		jobWaitGroupBy(groupBy),
+		constLabels,
+	)
+}
+
+// jobWaitGroupBy returns the aggregation labels of the wait histogram. Jobs
+// are observed once as they start, so the state label is left out.
+func jobWaitGroupBy(groupBy []string) []string {
+	return slices.DeleteFunc(slices.Clone(groupBy), func(label string) bool { return label == "state" })
+}
+
+// collectAggregated emits grouped job metrics plus per-job series for the
+// top-N jobs
+func (c *JobsSimpleCollector) collectAggregated(ch chan<- prometheus.Metric, jobs []slurm.Job, now time.Time) {
+	groups := make(map[string]*jobAggregate)
+	var order []string
+	waitGroupBy := jobWaitGroupBy(c.aggregateGroupBy)
+
+	c.waitsMu.Lock()
+	defer c.waitsMu.Unlock()
+	if c.jobWaits == nil {
+		c.jobWaits = make(map[string]*jobAggregateWait)
+	}
+	started := make(map[string]struct{}, len(c.startedJobs))
+
+	for _, job := range jobs {
+		jobCtx := extractJobContext(job)
+		values := make([]string, len(c.aggregateGroupBy))
+		for i, label := range c.aggregateGroupBy {
+			values[i] = jobAggregateLabelValue(job, jobCtx, label)
+		}
+
+		key := strings.Join(values, "\xff")
+		group, ok := groups[key]
+		if !ok {
+			group = &jobAggregate{labelValues: values}
+			groups[key] = group
+			order = append(order, key)
+		}
+
+		group.count++
+		if job.CPUs != nil {
+			group.cpus += float64(c.sanitizeCPUCount(int(*job.CPUs), jobCtx.jobID))
+		}
+		group.memoryBytes += c.jobTotalMemory(job, jobCtx.jobState)
+		for key, count := range jobGRES(job.TRESAllocStr, job.TRESPerNode, job.TRESPerJob, job.NodeCount) {
+			if key.gresType == "gpu" {
+				group.gpus += count
+			}
+		}
+
+		wait, ok := jobStartWait(job, jobCtx.jobState)
+		if !ok {
+			continue
+		}
+		started[jobCtx.jobID] = struct{}{}
+		if _, seen := c.startedJobs[jobCtx.jobID]; seen || !c.waitsSeeded {
+			continue
+		}
+		waitValues := make([]string, len(waitGroupBy))
+		for i, label := range waitGroupBy {
+			waitValues[i] = jobAggregateLabelValue(job, jobCtx, label)
+		}
+		waitKey := strings.Join(waitValues, "\xff")
+		waitGroup, ok := c.jobWaits[waitKey]
+		if !ok {
+			waitGroup = &jobAggregateWait{labelValues: waitValues, wait: newHistogramAccumulator(jobWaitBuckets)}
+			c.jobWaits[waitKey] = waitGroup
+		}
+		waitGroup.wait.Observe(wait)
+	}
+
+	// Jobs that left the list will not come back
+	c.startedJobs = started
+	c.waitsSeeded = true
+
+	for _, key := range order {
+		c.emitJobAggregate(ch, groups[key])
+	}
+	c.emitJobWaits(ch, waitGroupBy)
+
+	for _, i := range topJobs(jobs, c.aggregateTopN) {
+		c.collectJobMetrics(ch, jobs[i], extractJobContext(jobs[i]), now)
+	}
+}
+
+// emitJobAggregate sends the metrics of one aggregation group
+func (c *JobsSimpleCollector) emitJobAggregate(ch chan<- prometheus.Metric, group *jobAggregate) {
+	labels := make(map[string]string, len(c.aggregateGroupBy))
+	for i, label := range c.aggregateGroupBy {
+		labels[label] = group.labelValues[i]
+	}
+
+	gauges := []struct {
+		name  string
+		desc  *prometheus.Desc
+		value float64
+		isRes bool
+	}{
+		{"slurm_jobs_count", c.jobsCount, group.count, false},
+		{"slurm_jobs_cpus", c.jobsCPUs, group.cpus, true},
+		{"slurm_jobs_memory_bytes", c.jobsMemory, group.memoryBytes, true},
+		{"slurm_jobs_gpus", c.jobsGPUs, group.gpus, true},
+	}
+	for _, g := range gauges {
+		if c.shouldCollectMetric(g.name, MetricTypeGauge, false, g.isRes) &&
+			c.shouldCollectWithCardinality(g.name, labels) {
+			ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, g.value, group.labelValues...)
+		}
+	}
+}
+
+// emitJobWaits sends the wait histograms of the groups seen since the mode
+// was last set. The caller must hold c.waitsMu.
+func (c *JobsSimpleCollector) emitJobWaits(ch chan<- prometheus.Metric, groupBy []string) {
+	if !c.shouldCollectMetric("slurm_jobs_wait_seconds", MetricTypeHistogram, true, false) {
+		return
+	}
+	for _, group := range c.jobWaits {
+		labels := make(map[string]string, len(groupBy))
+		for i, label := range groupBy {
+			labels[label] = group.labelValues[i]
+		}
+		if !c.shouldCollectWithCardinality("slurm_jobs_wait_seconds", labels) {
+			continue
+		}
+		if metric, err := newConstHistogram(c.jobsWait, group.wait, group.labelValues...); err == nil {
+			ch <- metric
+		}
+	}
+}
+
+// jobAggregateLabelValue returns the value of an aggregation label for a job
+func jobAggregateLabelValue(job slurm.Job, ctx jobContext, label string) string {
+	switch label {
+	case "user":
+		return ctx.userName
+	case "account":
+		if job.Account != nil && *job.Account != "" {
+			return *job.Account
+		}
+	case "partition":
+		return ctx.partition
+	case "qos":
+		if job.QoS != nil && *job.QoS != "" {
+			return *job.QoS
+		}
+	case "state":
+		return ctx.jobState
+	}
+	return "unknown"
+}
+
+// jobTotalMemory returns the memory of a job over all of its nodes in bytes.
+// It is the mem TRES the job was allocated, or requested while it is
+// pending, which also covers jobs sized with --mem-per-cpu. Jobs reporting
+// neither fall back to their memory per node times their node count.
+func (c *JobsSimpleCollector) jobTotalMemory(job slurm.Job, state string) float64 {
+	tres := []*string{job.TRESAllocStr, job.TRESReqStr}
+	if strings.EqualFold(state, "PENDING") {
+		tres[0], tres[1] = tres[1], tres[0]
+	}
+	for _, s := range tres {
+		if s == nil {
+			continue
+		}
+		if mb, ok := ParseTRES(*s)["mem"]; ok {
+			return mb * 1024 * 1024
+		}
+	}
+
+	if job.MemoryPerNode == nil {
+		return 0
+	}
+	nodes := 1.0
+	if job.NodeCount != nil && *job.NodeCount > 0 {
+		nodes = float64(*job.NodeCount)
+	}
+	return float64(c.sanitizeMemory(int(*job.MemoryPerNode*1024*1024))) * nodes
+}
+
+// jobStartWait returns how long a started job waited in the queue. The start
+// time of a pending job is the scheduler's forecast, and a job cancelled
+// while pending ends the moment it "starts", so neither has waited yet.
+func jobStartWait(job slurm.Job, state string) (float64, bool) {
+	if strings.EqualFold(state, "PENDING") || job.SubmitTime.IsZero() || job.StartTime.IsZero() {
+		return 0, false
+	}
+	if strings.EqualFold(state, "CANCELLED") && !job.EndTime.After(job.StartTime) {
+		return 0, false
+	}
+
+	wait := job.StartTime.Sub(job.SubmitTime)
+	if wait < 0 {
+		return 0, false
+	}
+	return wait.Seconds(), true
+}
+
+// topJobs returns the indexes of the n longest-pending jobs followed by the n
+// largest jobs by CPU count, without duplicates
+func topJobs(jobs []slurm.Job, n int) []int {
+	if n <= 0 {
+		return nil
+	}
+
+	var pending, sized []int
+	for i, job := range jobs {
+		if strings.EqualFold(getJobState(job), "PENDING") && !job.SubmitTime.IsZero() {
+			pending = append(pending, i)
+		}
+		if job.CPUs != nil && *job.CPUs > 0 {
+			sized = append(sized, i)
+		}
+	}
+
+	sort.SliceStable(pending, func(a, b int) bool {
+		return jobs[pending[a]].SubmitTime.Before(jobs[pending[b]].SubmitTime)
+	})
+	sort.SliceStable(sized, func(a, b int) bool {
+		return *jobs[sized[a]].CPUs > *jobs[sized[b]].CPUs
+	})
+
+	selected := make([]int, 0, 2*n)
+	seen := make(map[int]bool, 2*n)
+	for _, candidates := range [][]int{pending, sized} {
+		for _, i := range candidates[:min(n, len(candidates))] {
+			if !seen[i] {
+				seen[i] = true
+				selected = append(selected, i)
+			}
+		}
+	}
+	return selected
+}
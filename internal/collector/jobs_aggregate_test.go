@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/testutil"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func aggregateTestJob(id int32, account string, state api.JobState, cpus uint32, submitted time.Time) slurm.Job {
+	partition := "compute"
+	qos := "normal"
+	gpus := "gres/gpu=2"
+	return slurm.Job{
+		JobID:      &id,
+		Account:    &account,
+		Partition:  &partition,
+		QoS:        &qos,
+		JobState:   []api.JobState{state},
+		CPUs:       &cpus,
+		TRESPerJob: &gpus,
+		SubmitTime: submitted,
+	}
+}
+
+// collectByDesc runs one collection and groups the results by descriptor
+func collectByDesc(t *testing.T, collector *JobsSimpleCollector) map[*prometheus.Desc][]*dto.Metric {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	require.NoError(t, collector.Collect(context.Background(), ch))
+	close(ch)
+
+	byDesc := make(map[*prometheus.Desc][]*dto.Metric)
+	for metric := range ch {
+		m := &dto.Metric{}
+		require.NoError(t, metric.Write(m))
+		byDesc[metric.Desc()] = append(byDesc[metric.Desc()], m)
+	}
+	return byDesc
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func newAggregateTestCollector(jobs []slurm.Job, topN int) *JobsSimpleCollector {
+	mockClient := new(mocks.MockSlurmClient)
+	mockJobManager := new(mocks.MockJobManager)
+	mockClient.On("Jobs").Return(mockJobManager)
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(&slurm.JobList{Jobs: jobs}, nil)
+
+	collector := NewJobsSimpleCollector(mockClient, testutil.GetTestLogger())
+	collector.SetMode(config.JobsModeAggregate, config.JobsAggregateConfig{
+		GroupBy: []string{"account", "state"},
+		TopN:    topN,
+	})
+	return collector
+}
+
+func TestJobsSimpleCollector_AggregateMode(t *testing.T) {
+	t.Parallel()
+	submitted := time.Now().Add(-2 * time.Hour)
+	chem := aggregateTestJob(3, "chem", api.JobStatePending, 16, submitted)
+	memory, nodes := uint64(4096), uint32(2)
+	chem.MemoryPerNode = &memory
+	chem.NodeCount = &nodes
+	collector := newAggregateTestCollector([]slurm.Job{
+		aggregateTestJob(1, "physics", api.JobStatePending, 4, submitted),
+		aggregateTestJob(2, "physics", api.JobStatePending, 8, submitted),
+		chem,
+	}, 0)
+
+	byDesc := collectByDesc(t, collector)
+
+	// Per-job series are dropped entirely when top_n is 0
+	assert.Empty(t, byDesc[collector.jobInfo])
+	assert.Empty(t, byDesc[collector.jobCPUs])
+
+	require.Len(t, byDesc[collector.jobsCount], 2)
+	values := make(map[string]float64)
+	for _, m := range byDesc[collector.jobsCPUs] {
+		values[labelValue(m, "account")] = m.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"physics": 12, "chem": 16}, values)
+
+	for _, m := range byDesc[collector.jobsGPUs] {
+		if labelValue(m, "account") == "physics" {
+			assert.Equal(t, 4.0, m.GetGauge().GetValue())
+		}
+	}
+
+	// Memory per node counts once for each node of the job
+	for _, m := range byDesc[collector.jobsMemory] {
+		if labelValue(m, "account") == "chem" {
+			assert.Equal(t, 2.0*4096*1024*1024, m.GetGauge().GetValue())
+		}
+	}
+
+	// Pending jobs have not waited out their queue time yet
+	assert.Empty(t, byDesc[collector.jobsWait])
+}
+
+func TestJobsSimpleCollector_JobTotalMemory(t *testing.T) {
+	t.Parallel()
+	collector := NewJobsSimpleCollector(new(mocks.MockSlurmClient), testutil.GetTestLogger())
+	str := func(s string) *string { return &s }
+	memory, nodes := uint64(4096), uint32(2)
+
+	tests := []struct {
+		name  string
+		job   slurm.Job
+		state string
+		want  float64
+	}{
+		{
+			name:  "running job uses its allocation",
+			job:   slurm.Job{TRESAllocStr: str("cpu=8,mem=16G,node=2"), TRESReqStr: str("cpu=8,mem=8G")},
+			state: "RUNNING",
+			want:  16 * 1024 * 1024 * 1024,
+		},
+		{
+			name:  "pending job uses its request",
+			job:   slurm.Job{TRESAllocStr: str("cpu=8,mem=16G"), TRESReqStr: str("cpu=8,mem=8G")},
+			state: "PENDING",
+			want:  8 * 1024 * 1024 * 1024,
+		},
+		{
+			name:  "memory per CPU is covered by the TRES",
+			job:   slurm.Job{TRESReqStr: str("cpu=4,mem=2048")},
+			state: "RUNNING",
+			want:  2048 * 1024 * 1024,
+		},
+		{
+			name:  "memory per node without TRES",
+			job:   slurm.Job{MemoryPerNode: &memory, NodeCount: &nodes},
+			state: "RUNNING",
+			want:  2 * 4096 * 1024 * 1024,
+		},
+		{
+			name:  "no memory",
+			job:   slurm.Job{TRESAllocStr: str("cpu=1")},
+			state: "RUNNING",
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, collector.jobTotalMemory(tt.job, tt.state))
+		})
+	}
+}
+
+func TestJobsSimpleCollector_AggregateWaits(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	submitted := now.Add(-2 * time.Hour)
+	running := func(id int32) slurm.Job {
+		job := aggregateTestJob(id, "physics", api.JobStateRunning, 4, submitted)
+		job.StartTime = submitted.Add(time.Hour)
+		return job
+	}
+	// The start time of a pending job is the scheduler's forecast
+	pending := aggregateTestJob(2, "physics", api.JobStatePending, 4, submitted)
+	pending.StartTime = now.Add(time.Hour)
+	cancelled := aggregateTestJob(3, "physics", api.JobStateCancelled, 4, submitted)
+	cancelled.StartTime = now
+	cancelled.EndTime = now
+
+	first := &slurm.JobList{Jobs: []slurm.Job{running(1), pending}}
+	second := &slurm.JobList{Jobs: []slurm.Job{running(1), running(2), cancelled}}
+	mockClient := new(mocks.MockSlurmClient)
+	mockJobManager := new(mocks.MockJobManager)
+	mockClient.On("Jobs").Return(mockJobManager)
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(first, nil).Once()
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(second, nil)
+
+	collector := NewJobsSimpleCollector(mockClient, testutil.GetTestLogger())
+	collector.SetMode(config.JobsModeAggregate, config.JobsAggregateConfig{GroupBy: []string{"account", "state"}})
+
+	// Job 1 had started before the first pass and is only remembered
+	assert.Empty(t, collectByDesc(t, collector)[collector.jobsWait])
+
+	// Job 2 is observed once as it starts, however often it is seen running
+	collectByDesc(t, collector)
+	waits := collectByDesc(t, collector)[collector.jobsWait]
+	require.Len(t, waits, 1)
+	m := waits[0]
+	assert.Equal(t, "physics", labelValue(m, "account"))
+	assert.Empty(t, labelValue(m, "state"))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 3600, m.GetHistogram().GetSampleSum(), 1e-6)
+
+	// Native buckets are sent next to the classic ones, and the histogram
+	// counts from its creation
+	assert.NotNil(t, m.GetHistogram().Schema)
+	assert.Len(t, m.GetHistogram().GetBucket(), len(jobWaitBuckets))
+	assert.NotNil(t, m.GetHistogram().GetCreatedTimestamp())
+}
+
+func TestJobsSimpleCollector_AggregateTopN(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	collector := newAggregateTestCollector([]slurm.Job{
+		aggregateTestJob(1, "physics", api.JobStatePending, 4, now.Add(-time.Hour)),
+		aggregateTestJob(2, "physics", api.JobStatePending, 8, now.Add(-3*time.Hour)),
+		aggregateTestJob(3, "chem", api.JobStateRunning, 64, now.Add(-time.Minute)),
+	}, 1)
+
+	byDesc := collectByDesc(t, collector)
+
+	// Job 2 has been pending longest and job 3 is largest
+	var kept []string
+	for _, m := range byDesc[collector.jobInfo] {
+		kept = append(kept, labelValue(m, "job_id"))
+	}
+	assert.ElementsMatch(t, []string{"2", "3"}, kept)
+	assert.Len(t, byDesc[collector.jobsCount], 2)
+}
+
+func TestJobsSimpleCollector_SetModeKeepsDescriptors(t *testing.T) {
+	t.Parallel()
+	collector := newAggregateTestCollector(nil, 0)
+	count := collector.jobsCount
+
+	// Reloads reapply the mode; unchanged labels keep the descriptors
+	collector.SetMode(config.JobsModeAggregate, config.JobsAggregateConfig{GroupBy: []string{"account", "state"}, TopN: 5})
+	assert.Same(t, count, collector.jobsCount)
+	assert.Equal(t, 5, collector.aggregateTopN)
+
+	collector.SetMode(config.JobsModeAggregate, config.JobsAggregateConfig{GroupBy: []string{"partition"}})
+	assert.NotSame(t, count, collector.jobsCount)
+}
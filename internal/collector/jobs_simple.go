@@ -6,7 +6,9 @@ package collector
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strings"
+	"sync"
 	"time"
 
 	slurm "github.com/jontk/slurm-client"
@@ -44,6 +46,10 @@ type JobsSimpleCollector struct {
 	// Custom labels
 	customLabels map[string]string
 
+	// mu guards the descriptors and the mode, which reloads replace while
+	// collections run
+	mu sync.RWMutex
+
 	// Job state metrics
 	jobStates *prometheus.Desc
 
@@ -56,19 +62,55 @@ type JobsSimpleCollector struct {
 	jobCPUs   *prometheus.Desc
 	jobMemory *prometheus.Desc
 	jobNodes  *prometheus.Desc
+	jobGRES   *prometheus.Desc
 
 	// Job info metric
 	jobInfo *prometheus.Desc
+
+	// Failure counters. Finished jobs stay in the slurmctld job list until
+	// MinJobAge expires, so each one is counted once and remembered until it
+	// drops out of the list. The jobs that already failed on the first pass
+	// may have been counted before a restart and are only remembered. The
+	// counts are kept apart from the descriptor so that rebuilding it does
+	// not reset them.
+	jobFailures     *prometheus.Desc
+	failureCounts   map[string]*jobFailureCount
+	countedFailures map[string]struct{}
+	failuresSeeded  bool
+	failuresMu      sync.Mutex
+
+	// Aggregate mode replaces per-job series with grouped totals
+	aggregate        bool
+	aggregateGroupBy []string
+	aggregateTopN    int
+
+	// Queue waits of the started jobs by aggregation group. Each job is
+	// observed once, when it is first seen started, and remembered until it
+	// drops out of the list; the jobs already started on the first pass are
+	// only remembered.
+	jobWaits    map[string]*jobAggregateWait
+	startedJobs map[string]struct{}
+	waitsSeeded bool
+	waitsMu     sync.Mutex
+
+	// Aggregate metrics
+	jobsCount  *prometheus.Desc
+	jobsCPUs   *prometheus.Desc
+	jobsMemory *prometheus.Desc
+	jobsGPUs   *prometheus.Desc
+	jobsWait   *prometheus.Desc
 }
 
 // NewJobsSimpleCollector creates a new Jobs collector
 func NewJobsSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry) *JobsSimpleCollector {
 	c := &JobsSimpleCollector{
-		client:       client,
-		logger:       logger.WithField("collector", "jobs"),
-		enabled:      true,
-		metricFilter: NewMetricFilter(DefaultMetricFilterConfig()),
-		customLabels: make(map[string]string),
+		client:          client,
+		logger:          logger.WithField("collector", "jobs"),
+		enabled:         true,
+		metricFilter:    NewMetricFilter(DefaultMetricFilterConfig()),
+		customLabels:    make(map[string]string),
+		failureCounts:   make(map[string]*jobFailureCount),
+		countedFailures: make(map[string]struct{}),
 	}
 
 	// Initialize metrics
@@ -77,13 +119,18 @@ func NewJobsSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry) *Job
 	return c
 }
 
-// initializeMetrics creates metric descriptors with custom labels as constant labels
-func (c *JobsSimpleCollector) initializeMetrics() {
-	// Convert custom labels to prometheus.Labels for constant labels
+// constLabels converts the custom labels to the constant labels of the descriptors
+func (c *JobsSimpleCollector) constLabels() prometheus.Labels {
 	constLabels := prometheus.Labels{}
 	for k, v := range c.customLabels {
 		constLabels[k] = v
 	}
+	return constLabels
+}
+
+// initializeMetrics creates metric descriptors with custom labels as constant labels
+func (c *JobsSimpleCollector) initializeMetrics() {
+	constLabels := c.constLabels()
 
 	c.jobStates = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, jobsCollectorSubsystem, "state"),
@@ -122,7 +169,7 @@ func (c *JobsSimpleCollector) initializeMetrics() {
 
 	c.jobMemory = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, jobsCollectorSubsystem, "memory_bytes"),
-		"Memory allocated to the job in bytes",
+		"Memory allocated to each node of the job in bytes",
 		[]string{"job_id", "job_name", "user", "partition"},
 		constLabels,
 	)
@@ -134,12 +181,28 @@ func (c *JobsSimpleCollector) initializeMetrics() {
 		constLabels,
 	)
 
+	c.jobGRES = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsCollectorSubsystem, "gres"),
+		"Number of generic resources (e.g. GPUs) allocated to the job, or requested while pending, by type and model",
+		[]string{"job_id", "job_name", "user", "partition", "gres_type", "gres_model"},
+		constLabels,
+	)
+
 	c.jobInfo = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, jobsCollectorSubsystem, "info"),
 		"Job information",
 		[]string{"job_id", "job_name", "user", "account", "partition", "qos", "state"},
 		constLabels,
 	)
+
+	c.jobFailures = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, jobsCollectorSubsystem, "failures_total"),
+		"Jobs that ended in FAILED, TIMEOUT, OUT_OF_MEMORY, NODE_FAIL or PREEMPTED, by exit code and signal",
+		[]string{"partition", "account", "state", "reason", "exit_code", "signal"},
+		constLabels,
+	)
+
+	c.initializeAggregateMetrics(constLabels)
 }
 
 // Name returns the collector name
@@ -177,6 +240,11 @@ func (c *JobsSimpleCollector) UpdateFilterConfig(config config.FilterConfig) {
 
 // SetCustomLabels sets custom labels for this collector
 func (c *JobsSimpleCollector) SetCustomLabels(labels map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if maps.Equal(c.customLabels, labels) {
+		return
+	}
 	c.customLabels = make(map[string]string)
 	for k, v := range labels {
 		c.customLabels[k] = v
@@ -229,6 +297,8 @@ func (c *JobsSimpleCollector) shouldCollectWithCardinality(metricName string, la
 
 // Describe implements prometheus.Collector
 func (c *JobsSimpleCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	ch <- c.jobStates
 	ch <- c.jobQueueTime
 	ch <- c.jobRunTime
@@ -236,7 +306,14 @@ func (c *JobsSimpleCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.jobCPUs
 	ch <- c.jobMemory
 	ch <- c.jobNodes
+	ch <- c.jobGRES
 	ch <- c.jobInfo
+	ch <- c.jobFailures
+	ch <- c.jobsCount
+	ch <- c.jobsCPUs
+	ch <- c.jobsMemory
+	ch <- c.jobsGPUs
+	ch <- c.jobsWait
 }
 
 // Collect implements the Collector interface
@@ -332,14 +409,95 @@ func (c *JobsSimpleCollector) collect(ctx context.Context, ch chan<- prometheus.
 
 	now := time.Now()
 
-	for _, job := range jobList.Jobs {
-		jobCtx := extractJobContext(job)
-		c.collectJobMetrics(ch, job, jobCtx, now)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.aggregate {
+		c.collectAggregated(ch, jobList.Jobs, now)
+	} else {
+		for _, job := range jobList.Jobs {
+			jobCtx := extractJobContext(job)
+			c.collectJobMetrics(ch, job, jobCtx, now)
+		}
 	}
 
+	c.collectJobFailures(ch, jobList.Jobs)
+
 	return nil
 }
 
+// collectJobFailures counts newly failed jobs and emits the failure counters
+func (c *JobsSimpleCollector) collectJobFailures(ch chan<- prometheus.Metric, jobs []slurm.Job) {
+	if !c.shouldCollectMetric("slurm_job_failures_total", MetricTypeCounter, false, false) {
+		return
+	}
+
+	c.failuresMu.Lock()
+	defer c.failuresMu.Unlock()
+
+	counted := make(map[string]struct{}, len(c.countedFailures))
+	for _, job := range jobs {
+		state := getJobState(job)
+		if !isJobFailureState(state) {
+			continue
+		}
+		jobID := getJobID(job)
+		if _, ok := c.countedFailures[jobID]; ok || !c.failuresSeeded {
+			counted[jobID] = struct{}{}
+			continue
+		}
+
+		partition := "unknown"
+		if job.Partition != nil && *job.Partition != "" {
+			partition = *job.Partition
+		}
+		account := "unknown"
+		if job.Account != nil && *job.Account != "" {
+			account = *job.Account
+		}
+		reason := "none"
+		if job.StateReason != nil && *job.StateReason != "" {
+			reason = *job.StateReason
+		}
+		exitCode, signal := jobExitCode(job)
+
+		labels := map[string]string{
+			"partition": partition,
+			"account":   account,
+			"state":     state,
+			"reason":    reason,
+			"exit_code": exitCode,
+			"signal":    signal,
+		}
+		if !c.shouldCollectWithCardinality("slurm_job_failures_total", labels) {
+			continue
+		}
+		labelValues := []string{partition, account, state, reason, exitCode, signal}
+		key := strings.Join(labelValues, "\xff")
+		count, ok := c.failureCounts[key]
+		if !ok {
+			count = &jobFailureCount{labelValues: labelValues}
+			c.failureCounts[key] = count
+		}
+		count.value++
+		counted[jobID] = struct{}{}
+	}
+
+	// Jobs that left the list will not come back, and jobs held back by the
+	// cardinality limits are retried on the next pass
+	c.countedFailures = counted
+	c.failuresSeeded = true
+	for _, count := range c.failureCounts {
+		ch <- prometheus.MustNewConstMetric(c.jobFailures, prometheus.CounterValue, count.value, count.labelValues...)
+	}
+}
+
+// jobFailureCount is the failure counter of one label set
+type jobFailureCount struct {
+	labelValues []string
+	value       float64
+}
+
 // collectJobMetrics collects all metrics for a single job
 func (c *JobsSimpleCollector) collectJobMetrics(ch chan<- prometheus.Metric, job slurm.Job, ctx jobContext, now time.Time) {
 	c.collectJobState(ch, ctx)
@@ -479,6 +637,25 @@ func (c *JobsSimpleCollector) collectResourceMetrics(ch chan<- prometheus.Metric
 			ctx.jobID, ctx.jobName, ctx.userName, ctx.partition,
 		)
 	}
+
+	// Generic resources (GPUs etc.) from the allocation or the per-node/per-job request
+	if !c.shouldCollectMetric("slurm_job_gres", MetricTypeGauge, false, true) {
+		return
+	}
+	for key, count := range jobGRES(job.TRESAllocStr, job.TRESPerNode, job.TRESPerJob, job.NodeCount) {
+		gresLabels := ctx.createJobLabels()
+		gresLabels["gres_type"] = key.gresType
+		gresLabels["gres_model"] = key.model
+		if !c.shouldCollectWithCardinality("slurm_job_gres", gresLabels) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.jobGRES,
+			prometheus.GaugeValue,
+			count,
+			ctx.jobID, ctx.jobName, ctx.userName, ctx.partition, key.gresType, key.model,
+		)
+	}
 }
 
 // sanitizeCPUCount validates and sanitizes CPU count
@@ -555,6 +732,44 @@ func isJobActive(state string) bool {
 	}
 }
 
+// isJobFailureState reports whether a job ended in a state counted as a failure
+func isJobFailureState(state string) bool {
+	switch strings.ToUpper(state) {
+	case "FAILED", "TIMEOUT", "OUT_OF_MEMORY", "NODE_FAIL", "PREEMPTED":
+		return true
+	}
+	return false
+}
+
+// jobExitCode returns the job's return code and signal name, falling back to
+// the derived exit code (highest of all steps) when the job's own is unset
+func jobExitCode(job slurm.Job) (string, string) {
+	exitCode := job.ExitCode
+	if exitCode == nil || exitCode.ReturnCode == nil {
+		if job.DerivedExitCode != nil {
+			exitCode = job.DerivedExitCode
+		}
+	}
+	if exitCode == nil {
+		return "unknown", "none"
+	}
+
+	returnCode := "unknown"
+	if exitCode.ReturnCode != nil {
+		returnCode = fmt.Sprintf("%d", *exitCode.ReturnCode)
+	}
+	signal := "none"
+	if exitCode.Signal != nil {
+		switch {
+		case exitCode.Signal.Name != nil && *exitCode.Signal.Name != "":
+			signal = *exitCode.Signal.Name
+		case exitCode.Signal.ID != nil && *exitCode.Signal.ID != 0:
+			signal = fmt.Sprintf("%d", *exitCode.Signal.ID)
+		}
+	}
+	return returnCode, signal
+}
+
 // Helper functions
 func getJobID(job slurm.Job) string {
 	if job.JobID != nil {
@@ -7,6 +7,8 @@ import (
 	"context"
 	"testing"
 
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -24,7 +26,7 @@ func TestJobsSimpleCollector_Describe(t *testing.T) {
 
 	collector := NewJobsSimpleCollector(mockClient, logger)
 
-	ch := make(chan *prometheus.Desc, 10)
+	ch := make(chan *prometheus.Desc, 20)
 	collector.Describe(ch)
 	close(ch)
 
@@ -226,3 +228,62 @@ func TestJobsSimpleCollector_EmptyJobList(t *testing.T) {
 
 	assert.Equal(t, 0, count, "should not emit metrics when job list is empty")
 }
+
+func failedTestJob(id int32, state api.JobState, returnCode uint32, signal string) slurm.Job {
+	partition := "compute"
+	account := "physics"
+	reason := "NonZeroExitCode"
+	job := slurm.Job{
+		JobID:       &id,
+		Partition:   &partition,
+		Account:     &account,
+		StateReason: &reason,
+		JobState:    []api.JobState{state},
+		ExitCode:    &api.ExitCode{ReturnCode: &returnCode},
+	}
+	if signal != "" {
+		job.ExitCode.Signal = &api.ExitCodeSignal{Name: &signal}
+	}
+	return job
+}
+
+func TestJobsSimpleCollector_FailureCounters(t *testing.T) {
+	t.Parallel()
+	logger := testutil.GetTestLogger()
+	mockClient := new(mocks.MockSlurmClient)
+	mockJobManager := new(mocks.MockJobManager)
+	mockClient.On("Jobs").Return(mockJobManager)
+
+	first := &slurm.JobList{Jobs: []slurm.Job{
+		failedTestJob(1, api.JobStateFailed, 1, ""),
+		failedTestJob(2, api.JobStateCompleted, 0, ""),
+	}}
+	second := &slurm.JobList{Jobs: []slurm.Job{
+		failedTestJob(1, api.JobStateFailed, 1, ""),
+		failedTestJob(3, api.JobStateOutOfMemory, 0, "SIGKILL"),
+	}}
+	third := &slurm.JobList{Jobs: []slurm.Job{
+		failedTestJob(3, api.JobStateOutOfMemory, 0, "SIGKILL"),
+		failedTestJob(4, api.JobStateFailed, 1, ""),
+	}}
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(first, nil).Once()
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(second, nil).Once()
+	mockJobManager.On("List", mock.Anything, mock.Anything).Return(third, nil).Once()
+
+	// Job 1 had already failed on the first pass and is not counted
+	collector := NewJobsSimpleCollector(mockClient, logger)
+	assert.Empty(t, collectByDesc(t, collector)[collector.jobFailures])
+	collectByDesc(t, collector)
+
+	// Reloads rebuild the descriptors without resetting the counts
+	collector.SetCustomLabels(map[string]string{"cluster": "main"})
+	failures := collectByDesc(t, collector)[collector.jobFailures]
+
+	// Job 3 stays in the list across both scrapes but is counted once
+	counts := make(map[string]float64)
+	for _, m := range failures {
+		counts[labelValue(m, "state")+"/"+labelValue(m, "signal")] = m.GetCounter().GetValue()
+		assert.Equal(t, "main", labelValue(m, "cluster"))
+	}
+	assert.Equal(t, map[string]float64{"FAILED/none": 1, "OUT_OF_MEMORY/SIGKILL": 1}, counts)
+}
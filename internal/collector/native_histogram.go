@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// nativeHistogramBucketFactor bounds the growth from one native
+	// histogram bucket to the next, giving a resolution of about 10%
+	nativeHistogramBucketFactor = 1.1
+
+	// nativeHistogramMaxBuckets caps the native buckets of long-lived
+	// histograms; past it the resolution is reduced
+	nativeHistogramMaxBuckets = 160
+
+	// nativeHistogramMinResetDuration is how long a long-lived histogram
+	// keeps its resolution before it may be reset to restore it
+	nativeHistogramMinResetDuration = time.Hour
+)
+
+// newHistogramAccumulator returns a long-lived histogram that accumulates
+// observations with both the classic buckets and native buckets, to be sent
+// as a constant metric with newConstHistogram
+func newHistogramAccumulator(buckets []float64) prometheus.Histogram {
+	return prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                            "accumulator",
+		Help:                            "Accumulates a constant histogram",
+		Buckets:                         buckets,
+		NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+		NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+		NativeHistogramMinResetDuration: nativeHistogramMinResetDuration,
+	})
+}
+
+// constHistogram is a histogram snapshot sent as a constant metric
+type constHistogram struct {
+	desc      *prometheus.Desc
+	labels    []*dto.LabelPair
+	histogram *dto.Histogram
+}
+
+// newConstHistogram snapshots an accumulator as a metric of desc. The
+// snapshot keeps the accumulator's creation as its created timestamp.
+func newConstHistogram(desc *prometheus.Desc, accumulator prometheus.Histogram, labelValues ...string) (prometheus.Metric, error) {
+	var m dto.Metric
+	if err := accumulator.Write(&m); err != nil {
+		return nil, err
+	}
+
+	return &constHistogram{
+		desc:      desc,
+		labels:    prometheus.MakeLabelPairs(desc, labelValues),
+		histogram: m.Histogram,
+	}, nil
+}
+
+// Desc implements prometheus.Metric
+func (h *constHistogram) Desc() *prometheus.Desc {
+	return h.desc
+}
+
+// Write implements prometheus.Metric
+func (h *constHistogram) Write(out *dto.Metric) error {
+	out.Label = h.labels
+	out.Histogram = h.histogram
+	return nil
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// nodeReasonRule is a compiled reason category rule
+type nodeReasonRule struct {
+	category string
+	pattern  *regexp.Regexp
+}
+
+// compileNodeReasonCategories compiles reason category rules in order
+func compileNodeReasonCategories(categories []config.NodeReasonCategory) ([]nodeReasonRule, error) {
+	rules := make([]nodeReasonRule, 0, len(categories))
+	for _, category := range categories {
+		pattern, err := regexp.Compile(category.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for node reason category %q: %w", category.Category, err)
+		}
+		rules = append(rules, nodeReasonRule{category: category.Category, pattern: pattern})
+	}
+	return rules, nil
+}
+
+// categorizeNodeReason returns the category of the first rule matching reason,
+// "none" when no reason is set and "other" when no rule matches
+func categorizeNodeReason(rules []nodeReasonRule, reason string) string {
+	if reason == "" {
+		return "none"
+	}
+	for _, rule := range rules {
+		if rule.pattern.MatchString(reason) {
+			return rule.category
+		}
+	}
+	return "other"
+}
+
+// isNodeUnschedulable reports whether any of the node's state flags keeps the
+// scheduler from placing jobs on it
+func isNodeUnschedulable(states []api.NodeState) bool {
+	for _, state := range states {
+		switch state {
+		case api.NodeStateDown, api.NodeStateDrain, api.NodeStateFail,
+			api.NodeStateNotResponding, api.NodeStateMaintenance, api.NodeStateError:
+			return true
+		}
+	}
+	return false
+}
+
+// SetReasonCategories replaces the rules used to categorise node reasons
+func (c *NodesSimpleCollector) SetReasonCategories(categories []config.NodeReasonCategory) error {
+	rules, err := compileNodeReasonCategories(categories)
+	if err != nil {
+		return err
+	}
+	c.reasonMu.Lock()
+	defer c.reasonMu.Unlock()
+	c.reasonRules = rules
+	return nil
+}
+
+// collectNodeReasons emits the reason, when it was set and by whom, and how
+// long unschedulable nodes have been out of service
+func (c *NodesSimpleCollector) collectNodeReasons(ch chan<- prometheus.Metric, nodes []slurm.Node, now time.Time) {
+	c.reasonMu.Lock()
+	defer c.reasonMu.Unlock()
+
+	seen := make(map[string]time.Time, len(c.unschedulableSince))
+	for _, node := range nodes {
+		if node.Name == nil {
+			continue
+		}
+		nodeName := *node.Name
+
+		reason := ""
+		if node.Reason != nil {
+			reason = *node.Reason
+		}
+		category := categorizeNodeReason(c.reasonRules, reason)
+
+		if reason != "" {
+			setBy := ""
+			if node.ReasonSetByUser != nil {
+				setBy = *node.ReasonSetByUser
+			}
+			ch <- prometheus.MustNewConstMetric(c.nodeReasonInfo, prometheus.GaugeValue, 1, nodeName, category, reason, setBy)
+			if !node.ReasonChangedAt.IsZero() {
+				ch <- prometheus.MustNewConstMetric(c.nodeReasonChangedAt, prometheus.GaugeValue, float64(node.ReasonChangedAt.Unix()), nodeName, category)
+			}
+		}
+
+		if !isNodeUnschedulable(node.State) {
+			continue
+		}
+
+		// slurmrestd has no state change time, so the reason timestamp is
+		// used, falling back to when the exporter first saw the node out
+		since, tracked := c.unschedulableSince[nodeName]
+		if !tracked {
+			since = now
+		}
+		seen[nodeName] = since
+		if !node.ReasonChangedAt.IsZero() {
+			since = node.ReasonChangedAt
+		}
+
+		duration := now.Sub(since).Seconds()
+		if duration < 0 {
+			duration = 0
+		}
+		ch <- prometheus.MustNewConstMetric(c.nodeUnschedulable, prometheus.GaugeValue, duration, nodeName, category)
+	}
+
+	// Nodes back in service start a fresh interval next time
+	c.unschedulableSince = seen
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/testutil"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func TestCategorizeNodeReason(t *testing.T) {
+	t.Parallel()
+	rules, err := compileNodeReasonCategories(config.DefaultNodeReasonCategories())
+	require.NoError(t, err)
+
+	tests := []struct {
+		reason   string
+		expected string
+	}{
+		{"", "none"},
+		{"Not responding", "not_responding"},
+		{"NHC: check_hw_eth failed", "health_check"},
+		{"GPU Xid 79, ticket 1234", "hardware"},
+		{"IB link flapping", "network"},
+		{"Kernel upgrade", "maintenance"},
+		{"Kill task failed", "scheduler"},
+		{"waiting on vendor", "other"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, categorizeNodeReason(rules, tt.reason), tt.reason)
+	}
+
+	_, err = compileNodeReasonCategories([]config.NodeReasonCategory{{Category: "bad", Pattern: "("}})
+	assert.Error(t, err)
+}
+
+func TestNodesSimpleCollector_ReasonMetrics(t *testing.T) {
+	t.Parallel()
+	collector := NewNodesSimpleCollector(new(mocks.MockSlurmClient), testutil.GetTestLogger())
+	require.NoError(t, collector.SetReasonCategories([]config.NodeReasonCategory{
+		{Category: "disk", Pattern: `(?i)disk`},
+	}))
+
+	now := time.Now()
+	drained, down, idle := "node01", "node02", "node03"
+	reason, setBy := "disk failure on /scratch", "alice"
+	nodes := []slurm.Node{
+		{
+			Name:            &drained,
+			State:           []api.NodeState{api.NodeStateIdle, api.NodeStateDrain},
+			Reason:          &reason,
+			ReasonSetByUser: &setBy,
+			ReasonChangedAt: now.Add(-3 * time.Hour),
+		},
+		// Down without a reason timestamp: measured from when it was first seen
+		{Name: &down, State: []api.NodeState{api.NodeStateDown}},
+		{Name: &idle, State: []api.NodeState{api.NodeStateIdle}},
+	}
+
+	collect := func(at time.Time) map[*prometheus.Desc][]*dto.Metric {
+		ch := make(chan prometheus.Metric, 20)
+		collector.collectNodeReasons(ch, nodes, at)
+		close(ch)
+		byDesc := make(map[*prometheus.Desc][]*dto.Metric)
+		for metric := range ch {
+			m := &dto.Metric{}
+			require.NoError(t, metric.Write(m))
+			byDesc[metric.Desc()] = append(byDesc[metric.Desc()], m)
+		}
+		return byDesc
+	}
+
+	collect(now)
+	byDesc := collect(now.Add(10 * time.Minute))
+
+	require.Len(t, byDesc[collector.nodeReasonInfo], 1)
+	info := byDesc[collector.nodeReasonInfo][0]
+	assert.Equal(t, "disk", labelValue(info, "category"))
+	assert.Equal(t, "alice", labelValue(info, "set_by_user"))
+	assert.Equal(t, float64(now.Add(-3*time.Hour).Unix()), byDesc[collector.nodeReasonChangedAt][0].GetGauge().GetValue())
+
+	unschedulable := make(map[string]float64)
+	for _, m := range byDesc[collector.nodeUnschedulable] {
+		unschedulable[labelValue(m, "node")] = m.GetGauge().GetValue()
+	}
+	assert.Len(t, unschedulable, 2)
+	assert.InDelta(t, (3*time.Hour + 10*time.Minute).Seconds(), unschedulable["node01"], 1)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), unschedulable["node02"], 1)
+}
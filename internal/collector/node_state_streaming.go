@@ -5,10 +5,13 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jontk/slurm-client/api"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -493,6 +496,18 @@ type NodeStateStreamingCollector struct {
 	client NodeStateStreamingSLURMClient
 	mutex  sync.RWMutex
 
+	// totals holds the last cumulative totals reported by the client, so
+	// counters only advance by the difference
+	totals map[string]float64
+
+	// streaming is set once the state change stream is consumed
+	streamMu  sync.Mutex
+	streaming bool
+
+	// lastStateChange is when each node last changed state in a partition
+	eventsMu        sync.Mutex
+	lastStateChange map[string]time.Time
+
 	// Node state event metrics
 	nodeEventsTotal     *prometheus.CounterVec
 	nodeEventRate       *prometheus.GaugeVec
@@ -583,7 +598,9 @@ type NodeStateStreamingCollector struct {
 
 func NewNodeStateStreamingCollector(client NodeStateStreamingSLURMClient) *NodeStateStreamingCollector {
 	return &NodeStateStreamingCollector{
-		client: client,
+		client:          client,
+		totals:          make(map[string]float64),
+		lastStateChange: make(map[string]time.Time),
 
 		// Node state event metrics
 		nodeEventsTotal: prometheus.NewCounterVec(
@@ -1155,12 +1172,19 @@ func (c *NodeStateStreamingCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.performanceDegradation.Describe(ch)
 }
 
+// Collect implements the prometheus.Collector interface
 func (c *NodeStateStreamingCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.CollectWithContext(context.Background(), ch)
+}
+
+// CollectWithContext starts consuming the state change stream if it is not
+// consumed yet, and sends the metrics to ch
+func (c *NodeStateStreamingCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.ensureStreaming()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	ctx := context.Background()
-
 	c.collectStreamingConfiguration(ctx, ch)
 	c.collectActiveStreams(ctx, ch)
 	c.collectStreamingMetrics(ctx, ch)
@@ -1237,6 +1261,86 @@ func (c *NodeStateStreamingCollector) Collect(ch chan<- prometheus.Metric) {
 	c.securityIncidents.Collect(ch)
 	c.complianceViolations.Collect(ch)
 	c.performanceDegradation.Collect(ch)
+
+	return ctx.Err()
+}
+
+// Start consumes the node state change stream until ctx ends, updating the
+// state change and transition metrics from its events
+func (c *NodeStateStreamingCollector) Start(ctx context.Context) error {
+	events, err := c.client.StreamNodeStateChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for event := range events {
+			c.processEvent(event)
+		}
+	}()
+	return nil
+}
+
+// ensureStreaming starts consuming the stream for the collector's lifetime,
+// retrying on the next collection if it cannot be opened
+func (c *NodeStateStreamingCollector) ensureStreaming() {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	if c.streaming {
+		return
+	}
+	if err := c.Start(context.Background()); err != nil {
+		log.Printf("Error streaming node state changes: %v", err)
+		return
+	}
+	c.streaming = true
+}
+
+// processEvent updates the metrics from one node state change
+func (c *NodeStateStreamingCollector) processEvent(event NodeStateChangeEvent) {
+	c.nodeEventsTotal.WithLabelValues(event.EventType, event.CurrentState, event.PartitionName, event.NodeName).Inc()
+	if event.PreviousState == "" || event.PreviousState == event.CurrentState {
+		return
+	}
+
+	c.nodeStateChanges.WithLabelValues(event.NodeName, event.PreviousState, event.CurrentState, event.PartitionName).Inc()
+	c.nodeStateTransitions.WithLabelValues(event.PreviousState+"->"+event.CurrentState, event.PartitionName).Inc()
+
+	// The time in the previous state is known from the node's previous change
+	key := event.NodeName + "\xff" + event.PartitionName
+	c.eventsMu.Lock()
+	last, seen := c.lastStateChange[key]
+	c.lastStateChange[key] = event.StateChangeTime
+	c.eventsMu.Unlock()
+	if seen && event.StateChangeTime.After(last) {
+		c.nodeStateDuration.WithLabelValues(event.PreviousState, event.PartitionName).Observe(event.StateChangeTime.Sub(last).Seconds())
+	}
+
+	if event.ActualDowntime > 0 {
+		c.nodeDowntime.WithLabelValues(event.NodeName, event.PreviousState, event.PartitionName).Observe(event.ActualDowntime.Seconds())
+		if !isNodeUnschedulable([]api.NodeState{api.NodeState(event.CurrentState)}) {
+			c.nodeRecoveryTime.WithLabelValues(event.NodeName, event.PreviousState, event.PartitionName).Observe(event.ActualDowntime.Seconds())
+		}
+	}
+	if event.MaintenanceMode {
+		c.nodeMaintenanceEvents.WithLabelValues(event.NodeName, event.CurrentState, event.PartitionName).Inc()
+	}
+}
+
+// addTotal advances a counter to a cumulative total reported by the client,
+// so that the total is not added again on every collection
+func (c *NodeStateStreamingCollector) addTotal(vec *prometheus.CounterVec, total float64, labelValues ...string) {
+	key := fmt.Sprintf("%p\xff%s", vec, strings.Join(labelValues, "\xff"))
+	previous := c.totals[key]
+	c.totals[key] = total
+	if total < previous {
+		// The client restarted its totals
+		previous = 0
+	}
+	if delta := total - previous; delta > 0 {
+		vec.WithLabelValues(labelValues...).Add(delta)
+	}
 }
 
 func (c *NodeStateStreamingCollector) collectStreamingConfiguration(ctx context.Context, ch chan<- prometheus.Metric) {
@@ -1333,17 +1437,17 @@ func (c *NodeStateStreamingCollector) collectStreamingMetrics(ctx context.Contex
 	c.queueDepth.WithLabelValues("main").Set(float64(metrics.QueueDepth))
 	c.processingEfficiency.WithLabelValues().Set(metrics.ProcessingEfficiency)
 
-	c.nodeEventsProcessed.WithLabelValues("all", "total").Add(float64(metrics.TotalEventsProcessed))
-	c.nodeEventsDropped.WithLabelValues("system", "all").Add(float64(metrics.TotalEventsDropped))
-	c.nodeEventsFailed.WithLabelValues("processing", "all").Add(float64(metrics.TotalEventsFailed))
+	c.addTotal(c.nodeEventsProcessed, float64(metrics.TotalEventsProcessed), "all", "total")
+	c.addTotal(c.nodeEventsDropped, float64(metrics.TotalEventsDropped), "system", "all")
+	c.addTotal(c.nodeEventsFailed, float64(metrics.TotalEventsFailed), "processing", "all")
 
 	// Node-specific metrics
 	c.nodeCoverage.WithLabelValues("all").Set(metrics.NodeCoverage)
 	c.stateChangeAccuracy.WithLabelValues("all").Set(metrics.StateChangeAccuracy)
 	c.predictiveAccuracy.WithLabelValues("failure").Set(metrics.PredictionAccuracy)
 	c.maintenanceCompliance.WithLabelValues("all").Set(metrics.MaintenanceCompliance)
-	c.securityIncidents.WithLabelValues("all", "all").Add(float64(metrics.SecurityIncidents))
-	c.complianceViolations.WithLabelValues("all", "all").Add(float64(metrics.ComplianceViolations))
+	c.addTotal(c.securityIncidents, float64(metrics.SecurityIncidents), "all", "all")
+	c.addTotal(c.complianceViolations, float64(metrics.ComplianceViolations), "all", "all")
 }
 
 func (c *NodeStateStreamingCollector) collectStreamingHealth(ctx context.Context, ch chan<- prometheus.Metric) {
@@ -1356,7 +1460,7 @@ func (c *NodeStateStreamingCollector) collectStreamingHealth(ctx context.Context
 
 	c.streamingHealthScore.WithLabelValues().Set(health.HealthScore)
 	c.serviceAvailability.WithLabelValues().Set(health.ServiceAvailability)
-	c.streamingUptime.WithLabelValues().Add(health.StreamingUptime.Seconds())
+	c.addTotal(c.streamingUptime, health.StreamingUptime.Seconds())
 	c.criticalIssues.WithLabelValues().Set(float64(len(health.CriticalIssues)))
 	c.warningIssues.WithLabelValues().Set(float64(len(health.WarningIssues)))
 	c.healthCheckDuration.WithLabelValues("overall").Observe(health.HealthCheckDuration.Seconds())
@@ -1381,13 +1485,13 @@ func (c *NodeStateStreamingCollector) collectEventSubscriptions(ctx context.Cont
 		}
 		subscriptionCounts[sub.SubscriptionType][sub.SubscriptionStatus]++
 
-		c.subscriptionDeliveries.WithLabelValues(sub.SubscriptionID, sub.DeliveryMethod).Add(float64(sub.DeliveryCount))
-		c.subscriptionFailures.WithLabelValues(sub.SubscriptionID, "delivery_failure").Add(float64(sub.FailedDeliveries))
+		c.addTotal(c.subscriptionDeliveries, float64(sub.DeliveryCount), sub.SubscriptionID, sub.DeliveryMethod)
+		c.addTotal(c.subscriptionFailures, float64(sub.FailedDeliveries), sub.SubscriptionID, "delivery_failure")
 	}
 
 	for subType, statusMap := range subscriptionCounts {
 		for status, count := range statusMap {
-			c.eventSubscriptionsTotal.WithLabelValues(subType, status).Add(float64(count))
+			c.addTotal(c.eventSubscriptionsTotal, float64(count), subType, status)
 		}
 	}
 }
@@ -1406,7 +1510,7 @@ func (c *NodeStateStreamingCollector) collectEventFilters(ctx context.Context, c
 			filterCounts[filter.FilterType]++
 		}
 
-		c.filterMatchCount.WithLabelValues(filter.FilterID, filter.FilterType).Add(float64(filter.MatchCount))
+		c.addTotal(c.filterMatchCount, float64(filter.MatchCount), filter.FilterID, filter.FilterType)
 
 		var efficiency float64
 		if filter.MatchCount > 0 {
@@ -1428,17 +1532,17 @@ func (c *NodeStateStreamingCollector) collectProcessingStats(ctx context.Context
 		return
 	}
 
-	c.nodeEventsProcessed.WithLabelValues("all", "received").Add(float64(stats.TotalEventsReceived))
-	c.nodeEventsProcessed.WithLabelValues("all", "processed").Add(float64(stats.TotalEventsProcessed))
-	c.nodeEventsProcessed.WithLabelValues("all", "filtered").Add(float64(stats.TotalEventsFiltered))
-	c.nodeEventsDropped.WithLabelValues("processing", "all").Add(float64(stats.TotalEventsDropped))
-
-	c.validationErrors.WithLabelValues("validation").Add(float64(stats.ValidationErrors))
-	c.enrichmentErrors.WithLabelValues("enrichment").Add(float64(stats.EnrichmentErrors))
-	c.deliveryErrors.WithLabelValues("http", "delivery").Add(float64(stats.DeliveryErrors))
-	c.transformationErrors.WithLabelValues("transformation").Add(float64(stats.TransformationErrors))
-	c.networkErrors.WithLabelValues("network").Add(float64(stats.NetworkErrors))
-	c.authenticationErrors.WithLabelValues("token").Add(float64(stats.AuthenticationErrors))
+	c.addTotal(c.nodeEventsProcessed, float64(stats.TotalEventsReceived), "all", "received")
+	c.addTotal(c.nodeEventsProcessed, float64(stats.TotalEventsProcessed), "all", "processed")
+	c.addTotal(c.nodeEventsProcessed, float64(stats.TotalEventsFiltered), "all", "filtered")
+	c.addTotal(c.nodeEventsDropped, float64(stats.TotalEventsDropped), "processing", "all")
+
+	c.addTotal(c.validationErrors, float64(stats.ValidationErrors), "validation")
+	c.addTotal(c.enrichmentErrors, float64(stats.EnrichmentErrors), "enrichment")
+	c.addTotal(c.deliveryErrors, float64(stats.DeliveryErrors), "http", "delivery")
+	c.addTotal(c.transformationErrors, float64(stats.TransformationErrors), "transformation")
+	c.addTotal(c.networkErrors, float64(stats.NetworkErrors), "network")
+	c.addTotal(c.authenticationErrors, float64(stats.AuthenticationErrors), "token")
 
 	for queueType, depth := range stats.ProcessingQueues {
 		c.queueDepth.WithLabelValues(queueType).Set(float64(depth))
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeStateStreamingCollector_ProcessEvent(t *testing.T) {
+	t.Parallel()
+	c := NewNodeStateStreamingCollector(nil)
+	at := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	for _, partition := range []string{"compute", "gpu"} {
+		c.processEvent(NodeStateChangeEvent{
+			NodeName:        "node01",
+			PartitionName:   partition,
+			PreviousState:   "IDLE",
+			CurrentState:    "DRAIN",
+			StateChangeTime: at,
+			EventType:       "state_change",
+		})
+	}
+	c.processEvent(NodeStateChangeEvent{
+		NodeName:        "node01",
+		PartitionName:   "compute",
+		PreviousState:   "DRAIN",
+		CurrentState:    "IDLE",
+		StateChangeTime: at.Add(time.Hour),
+		EventType:       "state_change",
+		ActualDowntime:  time.Hour,
+	})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.nodeStateTransitions.WithLabelValues("IDLE->DRAIN", "compute")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.nodeStateTransitions.WithLabelValues("IDLE->DRAIN", "gpu")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.nodeStateTransitions.WithLabelValues("DRAIN->IDLE", "compute")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.nodeStateChanges.WithLabelValues("node01", "IDLE", "DRAIN", "gpu")))
+	assert.Equal(t, 1, testutil.CollectAndCount(c.nodeStateDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(c.nodeRecoveryTime))
+}
+
+func TestNodeStateStreamingCollector_AddTotal(t *testing.T) {
+	t.Parallel()
+	c := NewNodeStateStreamingCollector(nil)
+
+	c.addTotal(c.nodeEventsDropped, 5, "system", "all")
+	c.addTotal(c.nodeEventsDropped, 5, "system", "all")
+	c.addTotal(c.nodeEventsDropped, 8, "system", "all")
+	assert.Equal(t, 8.0, testutil.ToFloat64(c.nodeEventsDropped.WithLabelValues("system", "all")))
+
+	// A total that went backwards was restarted
+	c.addTotal(c.nodeEventsDropped, 2, "system", "all")
+	assert.Equal(t, 10.0, testutil.ToFloat64(c.nodeEventsDropped.WithLabelValues("system", "all")))
+}
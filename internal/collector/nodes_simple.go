@@ -7,10 +7,14 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	slurm "github.com/jontk/slurm-client"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
 )
 
 const (
@@ -41,18 +45,40 @@ type NodesSimpleCollector struct {
 	nodeMemoryTotal     *prometheus.Desc
 	nodeMemoryAllocated *prometheus.Desc
 
+	// Node generic resource (GRES) metrics
+	nodeGRESConfigured *prometheus.Desc
+	nodeGRESAllocated  *prometheus.Desc
+	nodeGRESIdle       *prometheus.Desc
+
 	// Node info
 	nodeInfo *prometheus.Desc
+
+	// Partition membership, joinable on node
+	nodePartitionMembership *prometheus.Desc
+
+	// Drain/down reason metrics
+	nodeReasonInfo      *prometheus.Desc
+	nodeReasonChangedAt *prometheus.Desc
+	nodeUnschedulable   *prometheus.Desc
+
+	// Reason categorisation and the time each unschedulable node was first
+	// seen, for nodes without a reason timestamp
+	reasonMu           sync.Mutex
+	reasonRules        []nodeReasonRule
+	unschedulableSince map[string]time.Time
 }
 
 // NewNodesSimpleCollector creates a new Nodes collector
 func NewNodesSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry) *NodesSimpleCollector {
 	c := &NodesSimpleCollector{
-		client:       client,
-		logger:       logger.WithField("collector", "nodes"),
-		enabled:      true,
-		customLabels: make(map[string]string),
+		client:             client,
+		logger:             logger.WithField("collector", "nodes"),
+		enabled:            true,
+		customLabels:       make(map[string]string),
+		unschedulableSince: make(map[string]time.Time),
 	}
+	// The default rules are known to compile
+	c.reasonRules, _ = compileNodeReasonCategories(config.DefaultNodeReasonCategories())
 
 	// Initialize metrics
 	c.initializeMetrics()
@@ -70,42 +96,91 @@ func (c *NodesSimpleCollector) initializeMetrics() {
 	c.nodeState = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "state"),
 		"Current state of the node (1=up, 0=down)",
-		[]string{"node", "state", "partition"},
+		[]string{"node", "state"},
 		constLabels,
 	)
 
 	c.nodeCPUsTotal = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "cpus_total"),
 		"Total number of CPUs on the node",
-		[]string{"node", "partition"},
+		[]string{"node"},
 		constLabels,
 	)
 
 	c.nodeCPUsAllocated = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "cpus_allocated"),
 		"Number of allocated CPUs on the node",
-		[]string{"node", "partition"},
+		[]string{"node"},
 		constLabels,
 	)
 
 	c.nodeMemoryTotal = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "memory_total_bytes"),
 		"Total memory on the node in bytes",
-		[]string{"node", "partition"},
+		[]string{"node"},
 		constLabels,
 	)
 
 	c.nodeMemoryAllocated = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "memory_allocated_bytes"),
 		"Allocated memory on the node in bytes",
-		[]string{"node", "partition"},
+		[]string{"node"},
+		constLabels,
+	)
+
+	c.nodeGRESConfigured = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "gres_configured"),
+		"Number of generic resources (e.g. GPUs) configured on the node by type and model",
+		[]string{"node", "gres_type", "gres_model"},
+		constLabels,
+	)
+
+	c.nodeGRESAllocated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "gres_allocated"),
+		"Number of generic resources (e.g. GPUs) allocated to jobs on the node by type and model",
+		[]string{"node", "gres_type", "gres_model"},
+		constLabels,
+	)
+
+	c.nodeGRESIdle = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "gres_idle"),
+		"Number of unallocated generic resources on the node by type and model (0 when the node is down or drained)",
+		[]string{"node", "gres_type", "gres_model"},
 		constLabels,
 	)
 
 	c.nodeInfo = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "info"),
 		"Node information with all labels",
-		[]string{"node", "partition", "state", "reason", "arch", "os"},
+		[]string{"node", "state", "reason_category", "arch", "os"},
+		constLabels,
+	)
+
+	c.nodePartitionMembership = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "partition_membership"),
+		"Partitions the node belongs to (always 1); join on node to break node metrics down by partition",
+		[]string{"node", "partition"},
+		constLabels,
+	)
+
+	c.nodeReasonInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "reason_info"),
+		"Free-text reason set on a node, with its category and the user who set it; only present while a reason is set",
+		[]string{"node", "category", "reason", "set_by_user"},
+		constLabels,
+	)
+
+	c.nodeReasonChangedAt = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "reason_changed_timestamp_seconds"),
+		"Unix timestamp when the node's reason was last set",
+		[]string{"node", "category"},
+		constLabels,
+	)
+
+	c.nodeUnschedulable = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nodesCollectorSubsystem, "unschedulable_seconds"),
+		"Time the node has been down, drained, failed or not responding, measured from its reason timestamp",
+		[]string{"node", "category"},
 		constLabels,
 	)
 }
@@ -142,7 +217,14 @@ func (c *NodesSimpleCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.nodeCPUsAllocated
 	ch <- c.nodeMemoryTotal
 	ch <- c.nodeMemoryAllocated
+	ch <- c.nodeGRESConfigured
+	ch <- c.nodeGRESAllocated
+	ch <- c.nodeGRESIdle
 	ch <- c.nodeInfo
+	ch <- c.nodePartitionMembership
+	ch <- c.nodeReasonInfo
+	ch <- c.nodeReasonChangedAt
+	ch <- c.nodeUnschedulable
 }
 
 // Collect implements the Collector interface
@@ -171,12 +253,6 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 	c.logger.WithField("count", len(nodeList.Nodes)).Info("Collected node entries")
 
 	for _, node := range nodeList.Nodes {
-		// Get first partition if available
-		partition := ""
-		if len(node.Partitions) > 0 {
-			partition = node.Partitions[0]
-		}
-
 		// Extract node properties safely
 		nodeName := "unknown"
 		if node.Name != nil {
@@ -195,6 +271,17 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			nodeMemory = *node.RealMemory
 		}
 
+		// Partition membership is exported separately so per-node series
+		// are not duplicated for nodes in overlapping partitions
+		for _, partition := range node.Partitions {
+			ch <- prometheus.MustNewConstMetric(
+				c.nodePartitionMembership,
+				prometheus.GaugeValue,
+				1,
+				nodeName, partition,
+			)
+		}
+
 		// Node state metric
 		stateValue := 0.0
 		if isNodeUp(nodeStateStr) {
@@ -205,7 +292,7 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeState,
 			prometheus.GaugeValue,
 			stateValue,
-			nodeName, nodeStateStr, partition,
+			nodeName, nodeStateStr,
 		)
 
 		// CPU metrics
@@ -213,7 +300,7 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeCPUsTotal,
 			prometheus.GaugeValue,
 			float64(nodeCPUs),
-			nodeName, partition,
+			nodeName,
 		)
 
 		// Get actual allocated CPUs from node data (API provides this)
@@ -225,7 +312,7 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeCPUsAllocated,
 			prometheus.GaugeValue,
 			float64(allocCPUs),
-			nodeName, partition,
+			nodeName,
 		)
 
 		// Memory metrics (convert MB to bytes if Memory exists)
@@ -242,7 +329,7 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeMemoryTotal,
 			prometheus.GaugeValue,
 			memoryTotalBytes,
-			nodeName, partition,
+			nodeName,
 		)
 
 		// Get actual allocated memory from node data (API provides this in MB)
@@ -257,14 +344,21 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeMemoryAllocated,
 			prometheus.GaugeValue,
 			memoryAllocBytes,
-			nodeName, partition,
+			nodeName,
 		)
 
-		// Node info
+		// Generic resource metrics
+		c.collectNodeGRES(ch, node, nodeName, nodeStateStr)
+
+		// Node info carries the reason category only; the free text is in
+		// node_reason_info so it does not churn node_info series
 		reason := ""
-		if node.Reason != nil && *node.Reason != "" {
+		if node.Reason != nil {
 			reason = *node.Reason
 		}
+		c.reasonMu.Lock()
+		reasonCategory := categorizeNodeReason(c.reasonRules, reason)
+		c.reasonMu.Unlock()
 
 		// Get actual architecture and OS from node data
 		arch := "x86_64" // default
@@ -280,13 +374,56 @@ func (c *NodesSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 			c.nodeInfo,
 			prometheus.GaugeValue,
 			1,
-			nodeName, partition, nodeStateStr, reason, arch, os,
+			nodeName, nodeStateStr, reasonCategory, arch, os,
 		)
 	}
 
+	c.collectNodeReasons(ch, nodeList.Nodes, time.Now())
+
 	return nil
 }
 
+// collectNodeGRES emits configured, allocated and idle GRES counts for a node
+func (c *NodesSimpleCollector) collectNodeGRES(ch chan<- prometheus.Metric, node slurm.Node, nodeName, nodeState string) {
+	if node.GRES == nil || *node.GRES == "" {
+		return
+	}
+
+	configured := parseGRES(*node.GRES)
+	allocated := make(gresCounts)
+	if node.GRESUsed != nil {
+		allocated = parseGRES(*node.GRESUsed)
+	}
+	up := isNodeUp(nodeState)
+
+	for key, total := range configured {
+		used := allocated[key]
+		idle := 0.0
+		if up && total > used {
+			idle = total - used
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeGRESConfigured,
+			prometheus.GaugeValue,
+			total,
+			nodeName, key.gresType, key.model,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeGRESAllocated,
+			prometheus.GaugeValue,
+			used,
+			nodeName, key.gresType, key.model,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeGRESIdle,
+			prometheus.GaugeValue,
+			idle,
+			nodeName, key.gresType, key.model,
+		)
+	}
+}
+
 // isNodeUp returns true if the node is in an up state
 func isNodeUp(state string) bool {
 	state = strings.ToUpper(state)
@@ -7,6 +7,8 @@ import (
 	"context"
 	"testing"
 
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
@@ -384,3 +386,114 @@ func TestNodesSimpleCollector_ResourceMetrics(t *testing.T) {
 	assert.True(t, metricTypes["memory_total"], "should have memory_total metrics")
 	assert.True(t, metricTypes["memory_allocated"], "should have memory_allocated metrics")
 }
+
+func TestNodesSimpleCollector_GRESMetrics(t *testing.T) {
+	t.Parallel()
+	logger := testutil.GetTestLogger()
+	mockClient := new(mocks.MockSlurmClient)
+	mockNodeManager := new(mocks.MockNodeManager)
+
+	strPtr := func(s string) *string { return &s }
+	nodeList := &slurm.NodeList{
+		Nodes: []slurm.Node{
+			{
+				Name:       strPtr("gpu01"),
+				State:      []api.NodeState{api.NodeStateMixed},
+				Partitions: []string{"gpu"},
+				GRES:       strPtr("gpu:a100:4(S:0-1)"),
+				GRESUsed:   strPtr("gpu:a100:3(IDX:0-2)"),
+			},
+			{
+				Name:       strPtr("gpu02"),
+				State:      []api.NodeState{api.NodeStateDrain},
+				Partitions: []string{"gpu"},
+				GRES:       strPtr("gpu:a100:4(S:0-1)"),
+				GRESUsed:   strPtr("gpu:a100:0(IDX:N/A)"),
+			},
+		},
+	}
+
+	mockClient.On("Nodes").Return(mockNodeManager)
+	mockNodeManager.On("List", mock.Anything, mock.Anything).Return(nodeList, nil)
+
+	collector := NewNodesSimpleCollector(mockClient, logger)
+
+	ch := make(chan prometheus.Metric, 100)
+	err := collector.Collect(context.Background(), ch)
+	close(ch)
+	assert.NoError(t, err)
+
+	values := make(map[string]float64)
+	for metric := range ch {
+		desc := metric.Desc().String()
+		var name string
+		switch {
+		case contains(desc, "node_gres_configured"):
+			name = "configured"
+		case contains(desc, "node_gres_allocated"):
+			name = "allocated"
+		case contains(desc, "node_gres_idle"):
+			name = "idle"
+		default:
+			continue
+		}
+
+		pb := &dto.Metric{}
+		assert.NoError(t, metric.Write(pb))
+		labels := make(map[string]string)
+		for _, label := range pb.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		assert.Equal(t, "gpu", labels["gres_type"])
+		assert.Equal(t, "a100", labels["gres_model"])
+		values[labels["node"]+"/"+name] = pb.GetGauge().GetValue()
+	}
+
+	assert.Equal(t, 4.0, values["gpu01/configured"])
+	assert.Equal(t, 3.0, values["gpu01/allocated"])
+	assert.Equal(t, 1.0, values["gpu01/idle"])
+	assert.Equal(t, 4.0, values["gpu02/configured"])
+	assert.Equal(t, 0.0, values["gpu02/allocated"])
+	assert.Equal(t, 0.0, values["gpu02/idle"], "drained node should not report idle GRES")
+}
+
+func TestNodesSimpleCollector_PartitionMembership(t *testing.T) {
+	t.Parallel()
+	logger := testutil.GetTestLogger()
+	mockClient := new(mocks.MockSlurmClient)
+	mockNodeManager := new(mocks.MockNodeManager)
+
+	name := "node01"
+	cpus := int32(64)
+	nodeList := &slurm.NodeList{Nodes: []slurm.Node{{
+		Name:       &name,
+		CPUs:       &cpus,
+		State:      []api.NodeState{api.NodeStateIdle},
+		Partitions: []string{"batch", "debug", "long"},
+	}}}
+	mockClient.On("Nodes").Return(mockNodeManager)
+	mockNodeManager.On("List", mock.Anything, mock.Anything).Return(nodeList, nil)
+
+	collector := NewNodesSimpleCollector(mockClient, logger)
+	ch := make(chan prometheus.Metric, 100)
+	assert.NoError(t, collector.Collect(context.Background(), ch))
+	close(ch)
+
+	var cpuSeries int
+	var partitions []string
+	for metric := range ch {
+		pb := &dto.Metric{}
+		assert.NoError(t, metric.Write(pb))
+		switch metric.Desc() {
+		case collector.nodeCPUsTotal:
+			cpuSeries++
+			assert.Equal(t, "", labelValue(pb, "partition"))
+		case collector.nodePartitionMembership:
+			partitions = append(partitions, labelValue(pb, "partition"))
+		}
+	}
+
+	// Capacity is reported once per node, membership once per partition
+	assert.Equal(t, 1, cpuSeries)
+	assert.ElementsMatch(t, []string{"batch", "debug", "long"}, partitions)
+}
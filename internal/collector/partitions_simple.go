@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	slurm "github.com/jontk/slurm-client"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,10 +38,21 @@ type PartitionsSimpleCollector struct {
 	partitionCPUsAllocated *prometheus.Desc
 	partitionCPUsIdle      *prometheus.Desc
 
+	// Partition generic resource (GRES) metrics
+	partitionGRESConfigured *prometheus.Desc
+	partitionGRESAllocated  *prometheus.Desc
+	partitionGRESIdle       *prometheus.Desc
+
 	// Partition job metrics
 	partitionJobsPending *prometheus.Desc
 	partitionJobsRunning *prometheus.Desc
 
+	// Pending job breakdown by state reason and QoS
+	partitionPendingJobs      *prometheus.Desc
+	partitionPendingCPUs      *prometheus.Desc
+	partitionPendingGPUs      *prometheus.Desc
+	partitionPendingOldestAge *prometheus.Desc
+
 	// Partition info
 	partitionInfo *prometheus.Desc
 }
@@ -110,6 +122,27 @@ func NewPartitionsSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry
 		nil,
 	)
 
+	c.partitionGRESConfigured = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "gres_configured"),
+		"Number of generic resources (e.g. GPUs) configured in the partition by type and model",
+		[]string{"partition", "gres_type", "gres_model"},
+		nil,
+	)
+
+	c.partitionGRESAllocated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "gres_allocated"),
+		"Number of generic resources (e.g. GPUs) allocated in the partition by type and model",
+		[]string{"partition", "gres_type", "gres_model"},
+		nil,
+	)
+
+	c.partitionGRESIdle = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "gres_idle"),
+		"Number of unallocated generic resources on schedulable nodes in the partition by type and model",
+		[]string{"partition", "gres_type", "gres_model"},
+		nil,
+	)
+
 	c.partitionJobsPending = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "jobs_pending"),
 		"Number of pending jobs in the partition",
@@ -124,6 +157,34 @@ func NewPartitionsSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry
 		nil,
 	)
 
+	c.partitionPendingJobs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "pending_jobs_by_reason"),
+		"Number of pending jobs in the partition by QoS and state reason",
+		[]string{"partition", "qos", "reason"},
+		nil,
+	)
+
+	c.partitionPendingCPUs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "pending_cpus_requested"),
+		"Number of CPUs requested by pending jobs in the partition by QoS and state reason",
+		[]string{"partition", "qos", "reason"},
+		nil,
+	)
+
+	c.partitionPendingGPUs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "pending_gpus_requested"),
+		"Number of GPUs requested by pending jobs in the partition by QoS and state reason",
+		[]string{"partition", "qos", "reason"},
+		nil,
+	)
+
+	c.partitionPendingOldestAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "pending_oldest_age_seconds"),
+		"Time since submission of the oldest pending job in the partition by QoS and state reason",
+		[]string{"partition", "qos", "reason"},
+		nil,
+	)
+
 	c.partitionInfo = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, partitionsCollectorSubsystem, "info"),
 		"Partition information with all labels",
@@ -159,8 +220,15 @@ func (c *PartitionsSimpleCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.partitionCPUsTotal
 	ch <- c.partitionCPUsAllocated
 	ch <- c.partitionCPUsIdle
+	ch <- c.partitionGRESConfigured
+	ch <- c.partitionGRESAllocated
+	ch <- c.partitionGRESIdle
 	ch <- c.partitionJobsPending
 	ch <- c.partitionJobsRunning
+	ch <- c.partitionPendingJobs
+	ch <- c.partitionPendingCPUs
+	ch <- c.partitionPendingGPUs
+	ch <- c.partitionPendingOldestAge
 	ch <- c.partitionInfo
 }
 
@@ -174,11 +242,77 @@ func (c *PartitionsSimpleCollector) Collect(ctx context.Context, ch chan<- prome
 
 // partitionStats holds aggregated statistics for a partition
 type partitionStats struct {
+	// Capacity summed over member nodes; zero when the node list is unavailable
+	memberNodes int
+	memberCPUs  int
+
 	idleNodes     int
 	downNodes     int
 	allocatedCPUs int
 	pendingJobs   int
 	runningJobs   int
+
+	// Generic resources aggregated from member nodes
+	gresConfigured gresCounts
+	gresAllocated  gresCounts
+	gresIdle       gresCounts
+
+	// Pending jobs broken down by QoS and state reason
+	pending map[pendingJobKey]*pendingJobStats
+}
+
+// pendingJobKey groups pending jobs within a partition
+type pendingJobKey struct {
+	qos    string
+	reason string
+}
+
+// pendingJobStats holds the totals of one pending-job group
+type pendingJobStats struct {
+	jobs         int
+	cpus         float64
+	gpus         float64
+	oldestSubmit time.Time
+}
+
+// newPartitionStats creates an empty partitionStats
+func newPartitionStats() *partitionStats {
+	return &partitionStats{
+		gresConfigured: make(gresCounts),
+		gresAllocated:  make(gresCounts),
+		gresIdle:       make(gresCounts),
+		pending:        make(map[pendingJobKey]*pendingJobStats),
+	}
+}
+
+// addPendingJob adds a pending job to the state reason and QoS breakdown
+func (s *partitionStats) addPendingJob(job slurm.Job) {
+	key := pendingJobKey{qos: "unknown", reason: "None"}
+	if job.QoS != nil && *job.QoS != "" {
+		key.qos = *job.QoS
+	}
+	if job.StateReason != nil && *job.StateReason != "" {
+		key.reason = *job.StateReason
+	}
+
+	group := s.pending[key]
+	if group == nil {
+		group = &pendingJobStats{}
+		s.pending[key] = group
+	}
+	group.jobs++
+	if job.CPUs != nil {
+		group.cpus += float64(*job.CPUs)
+	}
+	// Pending jobs have no allocation yet, so GPUs come from the request
+	for gresKey, count := range jobGRES(job.TRESReqStr, job.TRESPerNode, job.TRESPerJob, job.NodeCount) {
+		if gresKey.gresType == "gpu" {
+			group.gpus += count
+		}
+	}
+	if !job.SubmitTime.IsZero() && (group.oldestSubmit.IsZero() || job.SubmitTime.Before(group.oldestSubmit)) {
+		group.oldestSubmit = job.SubmitTime
+	}
 }
 
 // publishPartitionMetrics publishes all metrics for a single partition
@@ -202,8 +336,12 @@ func (c *PartitionsSimpleCollector) publishPartitionMetrics(ch chan<- prometheus
 	ch <- prometheus.MustNewConstMetric(c.partitionState, prometheus.GaugeValue, stateValue, name, stateStr)
 
 	// Extract node total from nested Nodes.Total
+	// Prefer capacity summed from node membership, so it is consistent with
+	// the allocated and idle counts derived from the same nodes
 	nodesTot := int32(0)
-	if partition.Nodes != nil && partition.Nodes.Total != nil {
+	if stats != nil && stats.memberNodes > 0 {
+		nodesTot = int32(stats.memberNodes)
+	} else if partition.Nodes != nil && partition.Nodes.Total != nil {
 		nodesTot = *partition.Nodes.Total
 	}
 	ch <- prometheus.MustNewConstMetric(c.partitionNodesTotal, prometheus.GaugeValue, float64(nodesTot), name)
@@ -228,7 +366,9 @@ func (c *PartitionsSimpleCollector) publishPartitionMetrics(ch chan<- prometheus
 
 	// Extract CPU total from nested CPUs.Total
 	cpusTot := int32(0)
-	if partition.CPUs != nil && partition.CPUs.Total != nil {
+	if stats != nil && stats.memberCPUs > 0 {
+		cpusTot = int32(stats.memberCPUs)
+	} else if partition.CPUs != nil && partition.CPUs.Total != nil {
 		cpusTot = *partition.CPUs.Total
 	}
 	ch <- prometheus.MustNewConstMetric(c.partitionCPUsTotal, prometheus.GaugeValue, float64(cpusTot), name)
@@ -245,6 +385,14 @@ func (c *PartitionsSimpleCollector) publishPartitionMetrics(ch chan<- prometheus
 	}
 	ch <- prometheus.MustNewConstMetric(c.partitionCPUsIdle, prometheus.GaugeValue, float64(idleCPUs), name)
 
+	if stats != nil {
+		for key, total := range stats.gresConfigured {
+			ch <- prometheus.MustNewConstMetric(c.partitionGRESConfigured, prometheus.GaugeValue, total, name, key.gresType, key.model)
+			ch <- prometheus.MustNewConstMetric(c.partitionGRESAllocated, prometheus.GaugeValue, stats.gresAllocated[key], name, key.gresType, key.model)
+			ch <- prometheus.MustNewConstMetric(c.partitionGRESIdle, prometheus.GaugeValue, stats.gresIdle[key], name, key.gresType, key.model)
+		}
+	}
+
 	pendingJobs := 0
 	runningJobs := 0
 	if stats != nil {
@@ -254,6 +402,22 @@ func (c *PartitionsSimpleCollector) publishPartitionMetrics(ch chan<- prometheus
 	ch <- prometheus.MustNewConstMetric(c.partitionJobsPending, prometheus.GaugeValue, float64(pendingJobs), name)
 	ch <- prometheus.MustNewConstMetric(c.partitionJobsRunning, prometheus.GaugeValue, float64(runningJobs), name)
 
+	if stats != nil {
+		now := time.Now()
+		for key, group := range stats.pending {
+			ch <- prometheus.MustNewConstMetric(c.partitionPendingJobs, prometheus.GaugeValue, float64(group.jobs), name, key.qos, key.reason)
+			ch <- prometheus.MustNewConstMetric(c.partitionPendingCPUs, prometheus.GaugeValue, group.cpus, name, key.qos, key.reason)
+			ch <- prometheus.MustNewConstMetric(c.partitionPendingGPUs, prometheus.GaugeValue, group.gpus, name, key.qos, key.reason)
+			if !group.oldestSubmit.IsZero() {
+				age := now.Sub(group.oldestSubmit).Seconds()
+				if age < 0 {
+					age = 0
+				}
+				ch <- prometheus.MustNewConstMetric(c.partitionPendingOldestAge, prometheus.GaugeValue, age, name, key.qos, key.reason)
+			}
+		}
+	}
+
 	// Extract time limits from partition data
 	maxTime := formatTimeLimit(partition.Maximums)
 	defaultTime := formatDefaultTime(partition.Defaults)
@@ -330,9 +494,13 @@ func buildPartitionStats(nodeList *slurm.NodeList, jobList *slurm.JobList) map[s
 			// Each node can belong to multiple partitions
 			for _, partitionName := range node.Partitions {
 				if statsMap[partitionName] == nil {
-					statsMap[partitionName] = &partitionStats{}
+					statsMap[partitionName] = newPartitionStats()
 				}
 				stats := statsMap[partitionName]
+				stats.memberNodes++
+				if node.CPUs != nil {
+					stats.memberCPUs += int(*node.CPUs)
+				}
 
 				// Count nodes by state
 				if len(node.State) > 0 {
@@ -349,6 +517,9 @@ func buildPartitionStats(nodeList *slurm.NodeList, jobList *slurm.JobList) map[s
 				if node.AllocCPUs != nil {
 					stats.allocatedCPUs += int(*node.AllocCPUs)
 				}
+
+				// Sum generic resources for this partition
+				addNodeGRES(stats, node)
 			}
 		}
 	}
@@ -362,7 +533,7 @@ func buildPartitionStats(nodeList *slurm.NodeList, jobList *slurm.JobList) map[s
 			partitionName := *job.Partition
 
 			if statsMap[partitionName] == nil {
-				statsMap[partitionName] = &partitionStats{}
+				statsMap[partitionName] = newPartitionStats()
 			}
 			stats := statsMap[partitionName]
 
@@ -372,6 +543,7 @@ func buildPartitionStats(nodeList *slurm.NodeList, jobList *slurm.JobList) map[s
 				switch jobState {
 				case "PENDING":
 					stats.pendingJobs++
+					stats.addPendingJob(job)
 				case "RUNNING":
 					stats.runningJobs++
 				}
@@ -382,6 +554,29 @@ func buildPartitionStats(nodeList *slurm.NodeList, jobList *slurm.JobList) map[s
 	return statsMap
 }
 
+// addNodeGRES adds a node's configured, allocated and idle GRES to the partition stats
+func addNodeGRES(stats *partitionStats, node slurm.Node) {
+	if node.GRES == nil || *node.GRES == "" {
+		return
+	}
+
+	configured := parseGRES(*node.GRES)
+	allocated := make(gresCounts)
+	if node.GRESUsed != nil {
+		allocated = parseGRES(*node.GRESUsed)
+	}
+
+	nodeUp := len(node.State) > 0 && isNodeUp(string(node.State[0]))
+	for key, total := range configured {
+		used := allocated[key]
+		stats.gresConfigured[key] += total
+		stats.gresAllocated[key] += used
+		if nodeUp && total > used {
+			stats.gresIdle[key] += total - used
+		}
+	}
+}
+
 // formatTimeLimit formats the maximum time limit from partition maximums
 func formatTimeLimit(maximums *slurm.PartitionMaximums) string {
 	if maximums == nil || maximums.Time == nil {
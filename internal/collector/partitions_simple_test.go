@@ -6,7 +6,10 @@ package collector
 import (
 	"context"
 	"testing"
+	"time"
 
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -357,3 +360,63 @@ func TestPartitionsSimpleCollector_ActivePartitions(t *testing.T) {
 
 	assert.True(t, count > 0, "should have metrics for active partitions")
 }
+
+func pendingTestJob(partition, qos, reason string, cpus uint32, tresReq string, submitted time.Time) slurm.Job {
+	return slurm.Job{
+		Partition:   &partition,
+		QoS:         &qos,
+		StateReason: &reason,
+		JobState:    []api.JobState{api.JobStatePending},
+		CPUs:        &cpus,
+		TRESReqStr:  &tresReq,
+		SubmitTime:  submitted,
+	}
+}
+
+func TestBuildPartitionStats_PendingReasons(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	jobList := &slurm.JobList{Jobs: []slurm.Job{
+		pendingTestJob("gpu", "normal", "Resources", 8, "cpu=8,mem=16G,node=1,gres/gpu=2", now.Add(-30*time.Minute)),
+		pendingTestJob("gpu", "normal", "Resources", 4, "cpu=4,mem=8G,node=1,gres/gpu=1", now.Add(-2*time.Hour)),
+		pendingTestJob("gpu", "normal", "QOSMaxCpuPerUserLimit", 16, "cpu=16,node=1", now.Add(-time.Hour)),
+		pendingTestJob("gpu", "high", "Priority", 2, "cpu=2,node=1", now.Add(-time.Minute)),
+	}}
+
+	stats := buildPartitionStats(nil, jobList)["gpu"]
+	if !assert.NotNil(t, stats) {
+		return
+	}
+	assert.Equal(t, 4, stats.pendingJobs)
+	assert.Len(t, stats.pending, 3)
+
+	resources := stats.pending[pendingJobKey{qos: "normal", reason: "Resources"}]
+	if assert.NotNil(t, resources) {
+		assert.Equal(t, 2, resources.jobs)
+		assert.Equal(t, 12.0, resources.cpus)
+		assert.Equal(t, 3.0, resources.gpus)
+		assert.Equal(t, now.Add(-2*time.Hour), resources.oldestSubmit)
+	}
+
+	limited := stats.pending[pendingJobKey{qos: "normal", reason: "QOSMaxCpuPerUserLimit"}]
+	if assert.NotNil(t, limited) {
+		assert.Equal(t, 1, limited.jobs)
+		assert.Equal(t, 0.0, limited.gpus)
+	}
+}
+
+func TestBuildPartitionStats_OverlappingMembership(t *testing.T) {
+	t.Parallel()
+	names := []string{"node01", "node02"}
+	cpus := int32(32)
+	nodeList := &slurm.NodeList{Nodes: []slurm.Node{
+		{Name: &names[0], CPUs: &cpus, State: []api.NodeState{api.NodeStateIdle}, Partitions: []string{"batch", "debug"}},
+		{Name: &names[1], CPUs: &cpus, State: []api.NodeState{api.NodeStateIdle}, Partitions: []string{"batch"}},
+	}}
+
+	stats := buildPartitionStats(nodeList, nil)
+	assert.Equal(t, 2, stats["batch"].memberNodes)
+	assert.Equal(t, 64, stats["batch"].memberCPUs)
+	assert.Equal(t, 1, stats["debug"].memberNodes)
+	assert.Equal(t, 32, stats["debug"].memberCPUs)
+}
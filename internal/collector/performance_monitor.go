@@ -205,6 +205,12 @@ func (pm *PerformanceMonitor) RegisterMetrics(reg prometheus.Registerer) error {
 
 // RecordCollection records a collection attempt
 func (pm *PerformanceMonitor) RecordCollection(collector string, duration time.Duration, metricsCount int, err error) {
+	pm.RecordCollectionWithExemplar(collector, duration, metricsCount, err, nil)
+}
+
+// RecordCollectionWithExemplar records a collection attempt, attaching
+// exemplar labels to the duration observation when they are non-nil
+func (pm *PerformanceMonitor) RecordCollectionWithExemplar(collector string, duration time.Duration, metricsCount int, err error, exemplar prometheus.Labels) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -237,7 +243,12 @@ func (pm *PerformanceMonitor) RecordCollection(collector string, duration time.D
 		pm.recordSuccess(collector, stats, metricsCount)
 	}
 
-	pm.collectionDuration.WithLabelValues(collector, status).Observe(duration.Seconds())
+	observer := pm.collectionDuration.WithLabelValues(collector, status)
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(duration.Seconds(), exemplar)
+	} else {
+		observer.Observe(duration.Seconds())
+	}
 
 	// Check SLAs
 	pm.checkSLAViolations(collector, stats, duration, err)
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/jontk/slurm-exporter/internal/testutil"
@@ -75,6 +76,35 @@ func TestPerformanceMonitor_RecordCollection_Success(t *testing.T) {
 	assert.Equal(t, 42, stats.LastMetricCount)
 }
 
+func TestPerformanceMonitor_RecordCollectionWithExemplar(t *testing.T) {
+	t.Parallel()
+	logger := testutil.GetTestLogger()
+
+	pm := NewPerformanceMonitor("test", "collector", SLAConfig{}, logger.WithField("component", "test"))
+	exemplar := prometheus.Labels{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"}
+	pm.RecordCollectionWithExemplar("jobs", 2*time.Second, 10, nil, exemplar)
+	pm.RecordCollection("nodes", time.Second, 10, nil)
+
+	exemplars := make(map[string]map[string]string)
+	metric := &dto.Metric{}
+	for _, collector := range []string{"jobs", "nodes"} {
+		observer, err := pm.collectionDuration.GetMetricWithLabelValues(collector, "success")
+		assert.NoError(t, err)
+		assert.NoError(t, observer.(prometheus.Metric).Write(metric))
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			if e := bucket.GetExemplar(); e != nil {
+				exemplars[collector] = make(map[string]string)
+				for _, label := range e.GetLabel() {
+					exemplars[collector][label.GetName()] = label.GetValue()
+				}
+			}
+		}
+	}
+
+	// Only the traced collection links to its slowest call
+	assert.Equal(t, map[string]map[string]string{"jobs": exemplar}, exemplars)
+}
+
 func TestPerformanceMonitor_RecordCollection_Error(t *testing.T) {
 	t.Parallel()
 	logger := testutil.GetTestLogger()
@@ -76,6 +76,10 @@ type PriorityFactorsCollector struct {
 	temporalFactorCycles         *prometheus.GaugeVec
 	temporalFactorSeasonality    *prometheus.GaugeVec
 	temporalFactorPredictability *prometheus.GaugeVec
+
+	// Pending Job Factor Summary
+	factorMeanContribution *prometheus.GaugeVec
+	factorDominantJobs     *prometheus.GaugeVec
 }
 
 // PriorityFactorsSLURMClient interface for priority factor operations
@@ -92,6 +96,27 @@ type PriorityFactorsSLURMClient interface {
 	ValidateFactorConfiguration(ctx context.Context) (*FactorConfigurationValidation, error)
 }
 
+// PriorityFactorSummarizer is optionally implemented by a
+// PriorityFactorsSLURMClient to summarise the factors of every pending job,
+// not only the jobs analysed individually, by partition and QoS
+type PriorityFactorSummarizer interface {
+	GetPriorityFactorSummary(ctx context.Context) ([]*PriorityFactorGroup, error)
+}
+
+// PriorityFactorGroup summarises the priority factors of the pending jobs of
+// one partition and QoS
+type PriorityFactorGroup struct {
+	PartitionName string `json:"partition_name"`
+	QoSName       string `json:"qos_name"`
+	PendingJobs   int    `json:"pending_jobs"`
+
+	// MeanContributions is the average contribution of each factor
+	MeanContributions map[string]float64 `json:"mean_contributions"`
+
+	// DominantJobs counts the jobs by the factor contributing most
+	DominantJobs map[string]int `json:"dominant_jobs"`
+}
+
 // PriorityFactorBreakdown represents detailed factor analysis
 type PriorityFactorBreakdown struct {
 	JobID         string `json:"job_id"`
@@ -117,6 +142,7 @@ type PriorityFactorBreakdown struct {
 	SizeWeight      float64 `json:"size_weight"`
 	AssocWeight     float64 `json:"assoc_weight"`
 	NiceWeight      float64 `json:"nice_weight"`
+	TRESWeight      float64 `json:"tres_weight"`
 
 	// Factor Contributions
 	AgeContribution       float64 `json:"age_contribution"`
@@ -126,6 +152,10 @@ type PriorityFactorBreakdown struct {
 	SizeContribution      float64 `json:"size_contribution"`
 	AssocContribution     float64 `json:"assoc_contribution"`
 	NiceContribution      float64 `json:"nice_contribution"`
+	TRESContribution      float64 `json:"tres_contribution"`
+	// UnexplainedContribution is the part of the priority the weighted
+	// factors and nice do not account for
+	UnexplainedContribution float64 `json:"unexplained_contribution"`
 
 	// Normalized Values (0-1 scale)
 	AgeNormalized       float64 `json:"age_normalized"`
@@ -135,15 +165,18 @@ type PriorityFactorBreakdown struct {
 	SizeNormalized      float64 `json:"size_normalized"`
 	AssocNormalized     float64 `json:"assoc_normalized"`
 	NiceNormalized      float64 `json:"nice_normalized"`
+	TRESNormalized      float64 `json:"tres_normalized"`
 
 	// Effective Contributions
-	AgeEffective       float64 `json:"age_effective"`
-	FairShareEffective float64 `json:"fair_share_effective"`
-	QoSEffective       float64 `json:"qos_effective"`
-	PartitionEffective float64 `json:"partition_effective"`
-	SizeEffective      float64 `json:"size_effective"`
-	AssocEffective     float64 `json:"assoc_effective"`
-	NiceEffective      float64 `json:"nice_effective"`
+	AgeEffective         float64 `json:"age_effective"`
+	FairShareEffective   float64 `json:"fair_share_effective"`
+	QoSEffective         float64 `json:"qos_effective"`
+	PartitionEffective   float64 `json:"partition_effective"`
+	SizeEffective        float64 `json:"size_effective"`
+	AssocEffective       float64 `json:"assoc_effective"`
+	NiceEffective        float64 `json:"nice_effective"`
+	TRESEffective        float64 `json:"tres_effective"`
+	UnexplainedEffective float64 `json:"unexplained_effective"`
 
 	// Analysis Metadata
 	TotalPriority      float64   `json:"total_priority"`
@@ -738,6 +771,23 @@ func NewPriorityFactorsCollector(client PriorityFactorsSLURMClient) *PriorityFac
 			},
 			[]string{"factor", "timeframe"},
 		),
+
+		// Pending Job Factor Summary
+		factorMeanContribution: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_priority_factor_mean_contribution",
+				Help: "Average priority contributed by each factor to pending jobs",
+			},
+			[]string{"partition", "qos", "factor"},
+		),
+
+		factorDominantJobs: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_priority_factor_dominant_jobs",
+				Help: "Pending jobs whose priority comes mostly from each factor",
+			},
+			[]string{"partition", "qos", "factor"},
+		),
 	}
 }
 
@@ -785,48 +835,48 @@ func (c *PriorityFactorsCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.temporalFactorCycles.Describe(ch)
 	c.temporalFactorSeasonality.Describe(ch)
 	c.temporalFactorPredictability.Describe(ch)
+	c.factorMeanContribution.Describe(ch)
+	c.factorDominantJobs.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface
 func (c *PriorityFactorsCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
+	_ = c.CollectWithContext(context.Background(), ch)
+}
 
+// CollectWithContext runs a priority factor analysis pass and sends the metrics to ch
+func (c *PriorityFactorsCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	// Reset metrics
 	c.resetMetrics()
 
 	// Collect system factor weights
 	c.collectSystemFactorWeights(ctx)
 
-	// Get sample job IDs for factor breakdown
-	sampleJobIDs := c.getSampleJobIDs()
-	for _, jobID := range sampleJobIDs {
+	targets := c.getTargets(ctx)
+
+	for _, jobID := range targets.JobIDs {
 		c.collectJobFactorBreakdown(ctx, jobID)
 	}
 
-	// Get sample users for factor profiles
-	sampleUsers := c.getSampleUsers()
-	for _, user := range sampleUsers {
+	for _, user := range targets.Users {
 		c.collectUserFactorProfile(ctx, user)
 	}
 
-	// Get sample accounts for factor summaries
-	sampleAccounts := c.getSampleAccounts()
-	for _, account := range sampleAccounts {
+	for _, account := range targets.Accounts {
 		c.collectAccountFactorSummary(ctx, account)
 	}
 
-	// Get sample partitions for factor analysis
-	samplePartitions := c.getSamplePartitions()
-	for _, partition := range samplePartitions {
+	for _, partition := range targets.Partitions {
 		c.collectPartitionFactorAnalysis(ctx, partition)
 	}
 
-	// Get sample QoS for factor analysis
-	sampleQoS := c.getSampleQoS()
-	for _, qos := range sampleQoS {
+	for _, qos := range targets.QoS {
 		c.collectQoSFactorAnalysis(ctx, qos)
 	}
 
+	// Collect the factor summary of all pending jobs
+	c.collectFactorSummary(ctx)
+
 	// Collect factor trend analysis
 	c.collectFactorTrendAnalysis(ctx)
 
@@ -879,6 +929,10 @@ func (c *PriorityFactorsCollector) Collect(ch chan<- prometheus.Metric) {
 	c.temporalFactorCycles.Collect(ch)
 	c.temporalFactorSeasonality.Collect(ch)
 	c.temporalFactorPredictability.Collect(ch)
+	c.factorMeanContribution.Collect(ch)
+	c.factorDominantJobs.Collect(ch)
+
+	return ctx.Err()
 }
 
 func (c *PriorityFactorsCollector) resetMetrics() {
@@ -922,6 +976,8 @@ func (c *PriorityFactorsCollector) resetMetrics() {
 	c.temporalFactorCycles.Reset()
 	c.temporalFactorSeasonality.Reset()
 	c.temporalFactorPredictability.Reset()
+	c.factorMeanContribution.Reset()
+	c.factorDominantJobs.Reset()
 }
 
 func (c *PriorityFactorsCollector) collectSystemFactorWeights(ctx context.Context) {
@@ -983,6 +1039,8 @@ func (c *PriorityFactorsCollector) collectJobFactorBreakdown(ctx context.Context
 	c.priorityFactorContribution.WithLabelValues(append(labels, "size")...).Set(breakdown.SizeContribution)
 	c.priorityFactorContribution.WithLabelValues(append(labels, "assoc")...).Set(breakdown.AssocContribution)
 	c.priorityFactorContribution.WithLabelValues(append(labels, "nice")...).Set(breakdown.NiceContribution)
+	c.priorityFactorContribution.WithLabelValues(append(labels, "tres")...).Set(breakdown.TRESContribution)
+	c.priorityFactorContribution.WithLabelValues(append(labels, "unexplained")...).Set(breakdown.UnexplainedContribution)
 
 	// Normalized values
 	c.priorityFactorNormalized.WithLabelValues(append(labels, "age")...).Set(breakdown.AgeNormalized)
@@ -992,6 +1050,7 @@ func (c *PriorityFactorsCollector) collectJobFactorBreakdown(ctx context.Context
 	c.priorityFactorNormalized.WithLabelValues(append(labels, "size")...).Set(breakdown.SizeNormalized)
 	c.priorityFactorNormalized.WithLabelValues(append(labels, "assoc")...).Set(breakdown.AssocNormalized)
 	c.priorityFactorNormalized.WithLabelValues(append(labels, "nice")...).Set(breakdown.NiceNormalized)
+	c.priorityFactorNormalized.WithLabelValues(append(labels, "tres")...).Set(breakdown.TRESNormalized)
 
 	// Effective contributions
 	c.priorityFactorEffective.WithLabelValues(append(labels, "age")...).Set(breakdown.AgeEffective)
@@ -1001,6 +1060,8 @@ func (c *PriorityFactorsCollector) collectJobFactorBreakdown(ctx context.Context
 	c.priorityFactorEffective.WithLabelValues(append(labels, "size")...).Set(breakdown.SizeEffective)
 	c.priorityFactorEffective.WithLabelValues(append(labels, "assoc")...).Set(breakdown.AssocEffective)
 	c.priorityFactorEffective.WithLabelValues(append(labels, "nice")...).Set(breakdown.NiceEffective)
+	c.priorityFactorEffective.WithLabelValues(append(labels, "tres")...).Set(breakdown.TRESEffective)
+	c.priorityFactorEffective.WithLabelValues(append(labels, "unexplained")...).Set(breakdown.UnexplainedEffective)
 
 	// Weights
 	weightLabels := []string{"job", breakdown.PartitionName, breakdown.QoSName}
@@ -1011,6 +1072,7 @@ func (c *PriorityFactorsCollector) collectJobFactorBreakdown(ctx context.Context
 	c.priorityFactorWeight.WithLabelValues(append([]string{"size"}, weightLabels...)...).Set(breakdown.SizeWeight)
 	c.priorityFactorWeight.WithLabelValues(append([]string{"assoc"}, weightLabels...)...).Set(breakdown.AssocWeight)
 	c.priorityFactorWeight.WithLabelValues(append([]string{"nice"}, weightLabels...)...).Set(breakdown.NiceWeight)
+	c.priorityFactorWeight.WithLabelValues(append([]string{"tres"}, weightLabels...)...).Set(breakdown.TRESWeight)
 }
 
 func (c *PriorityFactorsCollector) collectUserFactorProfile(ctx context.Context, userName string) {
@@ -1092,7 +1154,7 @@ func (c *PriorityFactorsCollector) collectQoSFactorAnalysis(ctx context.Context,
 }
 
 func (c *PriorityFactorsCollector) collectFactorTrendAnalysis(ctx context.Context) {
-	factors := []string{"age", "fairshare", "qos", "partition", "size", "assoc", "nice"}
+	factors := []string{"age", "fairshare", "qos", "partition", "size", "assoc", "tres", "nice", "unexplained"}
 	period := "24h"
 
 	for _, factor := range factors {
@@ -1116,7 +1178,7 @@ func (c *PriorityFactorsCollector) collectFactorTrendAnalysis(ctx context.Contex
 }
 
 func (c *PriorityFactorsCollector) collectFactorImpactAnalysis(ctx context.Context) {
-	factors := []string{"age", "fairshare", "qos", "partition", "size", "assoc", "nice"}
+	factors := []string{"age", "fairshare", "qos", "partition", "size", "assoc", "tres", "nice", "unexplained"}
 
 	for _, factor := range factors {
 		impact, err := c.client.GetFactorImpactAnalysis(ctx, factor)
@@ -1156,6 +1218,52 @@ func (c *PriorityFactorsCollector) collectOptimizationMetrics(ctx context.Contex
 	}
 }
 
+// collectFactorSummary exports which factors the priority of pending jobs
+// comes from, by partition and QoS, for clients that can summarise them
+func (c *PriorityFactorsCollector) collectFactorSummary(ctx context.Context) {
+	summarizer, ok := c.client.(PriorityFactorSummarizer)
+	if !ok {
+		return
+	}
+
+	groups, err := summarizer.GetPriorityFactorSummary(ctx)
+	if err != nil {
+		log.Printf("Error collecting priority factor summary: %v", err)
+		return
+	}
+
+	for _, group := range groups {
+		for factor, contribution := range group.MeanContributions {
+			c.factorMeanContribution.WithLabelValues(group.PartitionName, group.QoSName, factor).Set(contribution)
+		}
+		for factor, jobs := range group.DominantJobs {
+			c.factorDominantJobs.WithLabelValues(group.PartitionName, group.QoSName, factor).Set(float64(jobs))
+		}
+	}
+}
+
+// getTargets asks the client which jobs, users, accounts, partitions and QoS
+// to analyse, falling back to the sample targets for clients that cannot list
+// them
+func (c *PriorityFactorsCollector) getTargets(ctx context.Context) *PriorityTargets {
+	if lister, ok := c.client.(PriorityTargetLister); ok {
+		targets, err := lister.ListPriorityTargets(ctx)
+		if err != nil {
+			log.Printf("Error listing priority factor targets: %v", err)
+			return &PriorityTargets{}
+		}
+		return targets
+	}
+
+	return &PriorityTargets{
+		JobIDs:     c.getSampleJobIDs(),
+		Users:      c.getSampleUsers(),
+		Accounts:   c.getSampleAccounts(),
+		Partitions: c.getSamplePartitions(),
+		QoS:        c.getSampleQoS(),
+	}
+}
+
 // Simplified mock data generators for testing purposes
 func (c *PriorityFactorsCollector) getSampleJobIDs() []string {
 	return []string{"12345", "12346", "12347"}
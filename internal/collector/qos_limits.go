@@ -27,6 +27,43 @@ type QoSLimitsSLURMClient interface {
 	GetSystemQoSOverview(ctx context.Context) (*SystemQoSOverview, error)
 }
 
+// QoSTargetLister is optionally implemented by a QoSLimitsSLURMClient to
+// supply the QoS the collector should report on. Without it the collector
+// uses sample QoS names.
+type QoSTargetLister interface {
+	ListQoSNames(ctx context.Context) ([]string, error)
+}
+
+// QoSTRESUsageReporter is optionally implemented by a QoSLimitsSLURMClient
+// to report how much of each TRES the jobs of every QoS use, next to the QoS
+// limits on that TRES
+type QoSTRESUsageReporter interface {
+	GetQoSTRESUsage(ctx context.Context) ([]*QoSTRESUsage, error)
+}
+
+// QoSTRESUsage is the use of one TRES by the jobs of a QoS, or by the jobs of
+// one user within it when UserName is set. Job counts are reported as the
+// "jobs" TRES and memory in megabytes, the unit of the mem TRES.
+type QoSTRESUsage struct {
+	QoSName  string  `json:"qos_name"`
+	UserName string  `json:"user_name"`
+	TRES     string  `json:"tres"`
+	Running  float64 `json:"running"` // allocated to running jobs
+	Pending  float64 `json:"pending"` // requested by pending jobs
+
+	// Limits are the QoS limits on the TRES
+	Limits []QoSTRESLimit `json:"limits"`
+}
+
+// QoSTRESLimit is one QoS limit on a TRES and the usage it is enforced
+// against. For per-user limits reported for the whole QoS, Used is the usage
+// of the user closest to the limit.
+type QoSTRESLimit struct {
+	Name  string  `json:"name"` // GrpTRES, MaxTRESPU, GrpJobs, MaxJobsPU, GrpSubmitJobs or MaxSubmitPU
+	Value float64 `json:"value"`
+	Used  float64 `json:"used"`
+}
+
 // QoSResourceLimits represents Quality of Service resource limits
 type QoSResourceLimits struct {
 	QoSName              string
@@ -411,6 +448,13 @@ type QoSLimitsCollector struct {
 	qosWalltimeConsumed    *prometheus.CounterVec
 	qosEfficiencyScore     *prometheus.GaugeVec
 
+	// QoS TRES usage against limits
+	qosTRESUsage          *prometheus.GaugeVec
+	qosTRESLimit          *prometheus.GaugeVec
+	qosTRESUsageRatio     *prometheus.GaugeVec
+	qosUserTRESUsage      *prometheus.GaugeVec
+	qosUserTRESUsageRatio *prometheus.GaugeVec
+
 	// QoS violation metrics
 	qosViolations              *prometheus.CounterVec
 	qosViolationSeverity       *prometheus.GaugeVec
@@ -508,7 +552,7 @@ func NewQoSLimitsCollector(client QoSLimitsSLURMClient) *QoSLimitsCollector {
 		),
 		qosMaxCPUsPerUser: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_max_cpus_per_user",
+				Name: "slurm_qos_limits_max_cpus_per_user",
 				Help: "Maximum CPUs per user for QoS",
 			},
 			[]string{"qos"},
@@ -522,14 +566,14 @@ func NewQoSLimitsCollector(client QoSLimitsSLURMClient) *QoSLimitsCollector {
 		),
 		qosMaxJobsPerUser: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_max_jobs_per_user",
+				Name: "slurm_qos_limits_max_jobs_per_user",
 				Help: "Maximum jobs per user for QoS",
 			},
 			[]string{"qos"},
 		),
 		qosMinCPUs: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_min_cpus",
+				Name: "slurm_qos_limits_min_cpus",
 				Help: "Minimum CPUs for QoS",
 			},
 			[]string{"qos"},
@@ -543,7 +587,7 @@ func NewQoSLimitsCollector(client QoSLimitsSLURMClient) *QoSLimitsCollector {
 		),
 		qosMinNodes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_min_nodes",
+				Name: "slurm_qos_limits_min_nodes",
 				Help: "Minimum nodes for QoS",
 			},
 			[]string{"qos"},
@@ -552,14 +596,14 @@ func NewQoSLimitsCollector(client QoSLimitsSLURMClient) *QoSLimitsCollector {
 		// QoS priority and configuration metrics
 		qosPriority: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_priority",
+				Name: "slurm_qos_limits_priority",
 				Help: "Priority value for QoS",
 			},
 			[]string{"qos"},
 		),
 		qosUsageFactor: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_qos_usage_factor",
+				Name: "slurm_qos_limits_usage_factor",
 				Help: "Usage factor for QoS",
 			},
 			[]string{"qos"},
@@ -658,6 +702,43 @@ func NewQoSLimitsCollector(client QoSLimitsSLURMClient) *QoSLimitsCollector {
 			[]string{"qos"},
 		),
 
+		// QoS TRES usage against limits
+		qosTRESUsage: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_qos_tres_usage",
+				Help: "TRES allocated to running jobs or requested by pending jobs of the QoS, memory in bytes",
+			},
+			[]string{"qos", "tres", "state"},
+		),
+		qosTRESLimit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_qos_tres_limit",
+				Help: "QoS limit on a TRES, memory in bytes",
+			},
+			[]string{"qos", "tres", "limit"},
+		),
+		qosTRESUsageRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_qos_tres_usage_ratio",
+				Help: "TRES usage of the QoS as a ratio of its limit; for per-user limits, of the user closest to it",
+			},
+			[]string{"qos", "tres", "limit"},
+		),
+		qosUserTRESUsage: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_qos_user_tres_usage",
+				Help: "TRES allocated to running jobs or requested by pending jobs of a user within the QoS, memory in bytes",
+			},
+			[]string{"qos", "user", "tres", "state"},
+		),
+		qosUserTRESUsageRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "slurm_qos_user_tres_usage_ratio",
+				Help: "TRES usage of a user within the QoS as a ratio of the QoS per-user limit",
+			},
+			[]string{"qos", "user", "tres", "limit"},
+		),
+
 		// QoS violation metrics
 		qosViolations: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -978,6 +1059,11 @@ func (c *QoSLimitsCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.qosUsersActive.Describe(ch)
 	c.qosWalltimeConsumed.Describe(ch)
 	c.qosEfficiencyScore.Describe(ch)
+	c.qosTRESUsage.Describe(ch)
+	c.qosTRESLimit.Describe(ch)
+	c.qosTRESUsageRatio.Describe(ch)
+	c.qosUserTRESUsage.Describe(ch)
+	c.qosUserTRESUsageRatio.Describe(ch)
 	c.qosViolations.Describe(ch)
 	c.qosViolationSeverity.Describe(ch)
 	c.qosActiveViolations.Describe(ch)
@@ -1021,6 +1107,11 @@ func (c *QoSLimitsCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect fetches the stats and delivers them as Prometheus metrics
 func (c *QoSLimitsCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.CollectWithContext(context.Background(), ch)
+}
+
+// CollectWithContext fetches the QoS limits and usage and sends the metrics to ch
+func (c *QoSLimitsCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -1048,6 +1139,11 @@ func (c *QoSLimitsCollector) Collect(ch chan<- prometheus.Metric) {
 	c.qosJobsPending.Reset()
 	c.qosUsersActive.Reset()
 	c.qosEfficiencyScore.Reset()
+	c.qosTRESUsage.Reset()
+	c.qosTRESLimit.Reset()
+	c.qosTRESUsageRatio.Reset()
+	c.qosUserTRESUsage.Reset()
+	c.qosUserTRESUsageRatio.Reset()
 	c.qosViolationSeverity.Reset()
 	c.qosActiveViolations.Reset()
 	c.qosAutoResolutionRate.Reset()
@@ -1077,15 +1173,18 @@ func (c *QoSLimitsCollector) Collect(ch chan<- prometheus.Metric) {
 	c.systemQoSViolationRate.Reset()
 	c.systemQoSComplianceScore.Reset()
 
+	qosNames := c.getQoSNames(ctx)
+
 	// Collect various metrics
-	c.collectQoSLimitsMetrics()
-	c.collectQoSUsageMetrics()
-	c.collectQoSViolationMetrics()
-	c.collectQoSEnforcementMetrics()
-	c.collectQoSPerformanceMetrics()
-	c.collectQoSHierarchyMetrics()
-	c.collectQoSEffectivenessMetrics()
-	c.collectSystemQoSMetrics()
+	c.collectQoSLimitsMetrics(ctx, qosNames)
+	c.collectQoSUsageMetrics(ctx, qosNames)
+	c.collectQoSTRESUsageMetrics(ctx)
+	c.collectQoSViolationMetrics(ctx)
+	c.collectQoSEnforcementMetrics(ctx, qosNames)
+	c.collectQoSPerformanceMetrics(ctx, qosNames)
+	c.collectQoSHierarchyMetrics(ctx)
+	c.collectQoSEffectivenessMetrics(ctx, qosNames)
+	c.collectSystemQoSMetrics(ctx)
 
 	// Collect all metrics
 	c.qosLimitCPUs.Collect(ch)
@@ -1114,6 +1213,11 @@ func (c *QoSLimitsCollector) Collect(ch chan<- prometheus.Metric) {
 	c.qosUsersActive.Collect(ch)
 	c.qosWalltimeConsumed.Collect(ch)
 	c.qosEfficiencyScore.Collect(ch)
+	c.qosTRESUsage.Collect(ch)
+	c.qosTRESLimit.Collect(ch)
+	c.qosTRESUsageRatio.Collect(ch)
+	c.qosUserTRESUsage.Collect(ch)
+	c.qosUserTRESUsageRatio.Collect(ch)
 	c.qosViolations.Collect(ch)
 	c.qosViolationSeverity.Collect(ch)
 	c.qosActiveViolations.Collect(ch)
@@ -1153,16 +1257,14 @@ func (c *QoSLimitsCollector) Collect(ch chan<- prometheus.Metric) {
 	c.collectionDuration.Collect(ch)
 	c.collectionErrors.Collect(ch)
 	c.lastCollectionTime.Collect(ch)
+
+	return ctx.Err()
 }
 
-func (c *QoSLimitsCollector) collectQoSLimitsMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSLimitsMetrics(ctx context.Context, qosNames []string) {
 	start := time.Now()
 
-	// Sample QoS names for collection
-	sampleQoS := []string{"normal", "high", "low", "preempt", "debug"}
-
-	for _, qosName := range sampleQoS {
+	for _, qosName := range qosNames {
 		limits, err := c.client.GetQoSLimits(ctx, qosName)
 		if err != nil {
 			c.collectionErrors.WithLabelValues("limits", "qos_limits_error").Inc()
@@ -1217,14 +1319,10 @@ func (c *QoSLimitsCollector) collectQoSLimitsMetrics() {
 	c.lastCollectionTime.WithLabelValues("limits").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSUsageMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSUsageMetrics(ctx context.Context, qosNames []string) {
 	start := time.Now()
 
-	// Sample QoS names for usage collection
-	sampleQoS := []string{"normal", "high", "low", "preempt", "debug"}
-
-	for _, qosName := range sampleQoS {
+	for _, qosName := range qosNames {
 		usage, err := c.client.GetQoSUsage(ctx, qosName)
 		if err != nil {
 			c.collectionErrors.WithLabelValues("usage", "qos_usage_error").Inc()
@@ -1268,8 +1366,61 @@ func (c *QoSLimitsCollector) collectQoSUsageMetrics() {
 	c.lastCollectionTime.WithLabelValues("usage").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSViolationMetrics() {
-	ctx := context.Background()
+// collectQoSTRESUsageMetrics exports how close each QoS, and each user
+// within it, is to the QoS limits, for clients that can report TRES usage
+func (c *QoSLimitsCollector) collectQoSTRESUsageMetrics(ctx context.Context) {
+	reporter, ok := c.client.(QoSTRESUsageReporter)
+	if !ok {
+		return
+	}
+	start := time.Now()
+
+	usages, err := reporter.GetQoSTRESUsage(ctx)
+	if err != nil {
+		c.collectionErrors.WithLabelValues("tres_usage", "qos_tres_usage_error").Inc()
+		return
+	}
+
+	for _, usage := range usages {
+		if usage.UserName == "" {
+			c.qosTRESUsage.WithLabelValues(usage.QoSName, usage.TRES, "running").Set(tresMetricValue(usage.TRES, usage.Running))
+			c.qosTRESUsage.WithLabelValues(usage.QoSName, usage.TRES, "pending").Set(tresMetricValue(usage.TRES, usage.Pending))
+		} else {
+			c.qosUserTRESUsage.WithLabelValues(usage.QoSName, usage.UserName, usage.TRES, "running").Set(tresMetricValue(usage.TRES, usage.Running))
+			c.qosUserTRESUsage.WithLabelValues(usage.QoSName, usage.UserName, usage.TRES, "pending").Set(tresMetricValue(usage.TRES, usage.Pending))
+		}
+
+		for _, limit := range usage.Limits {
+			if usage.UserName == "" {
+				c.qosTRESLimit.WithLabelValues(usage.QoSName, usage.TRES, limit.Name).Set(tresMetricValue(usage.TRES, limit.Value))
+			}
+			// A zero limit allows nothing; there is no ratio to report
+			if limit.Value <= 0 {
+				continue
+			}
+			if usage.UserName == "" {
+				c.qosTRESUsageRatio.WithLabelValues(usage.QoSName, usage.TRES, limit.Name).Set(limit.Used / limit.Value)
+			} else {
+				c.qosUserTRESUsageRatio.WithLabelValues(usage.QoSName, usage.UserName, usage.TRES, limit.Name).Set(limit.Used / limit.Value)
+			}
+		}
+	}
+
+	duration := time.Since(start).Seconds()
+	c.collectionDuration.WithLabelValues("tres_usage").Observe(duration)
+	c.lastCollectionTime.WithLabelValues("tres_usage").Set(float64(time.Now().Unix()))
+}
+
+// tresMetricValue converts a TRES count to the unit it is exported in:
+// memory from megabytes to bytes, everything else as counted
+func tresMetricValue(tres string, value float64) float64 {
+	if tres == "mem" {
+		return value * 1024 * 1024
+	}
+	return value
+}
+
+func (c *QoSLimitsCollector) collectQoSViolationMetrics(ctx context.Context) {
 	start := time.Now()
 
 	// Get violations for all QoS
@@ -1323,14 +1474,10 @@ func (c *QoSLimitsCollector) collectQoSViolationMetrics() {
 	c.lastCollectionTime.WithLabelValues("violations").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSEnforcementMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSEnforcementMetrics(ctx context.Context, qosNames []string) {
 	start := time.Now()
 
-	// Sample QoS names for enforcement collection
-	sampleQoS := []string{"normal", "high", "low", "preempt", "debug"}
-
-	for _, qosName := range sampleQoS {
+	for _, qosName := range qosNames {
 		enforcement, err := c.client.GetQoSEnforcement(ctx, qosName)
 		if err != nil {
 			c.collectionErrors.WithLabelValues("enforcement", "qos_enforcement_error").Inc()
@@ -1370,14 +1517,10 @@ func (c *QoSLimitsCollector) collectQoSEnforcementMetrics() {
 	c.lastCollectionTime.WithLabelValues("enforcement").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSPerformanceMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSPerformanceMetrics(ctx context.Context, qosNames []string) {
 	start := time.Now()
 
-	// Sample QoS names for performance collection
-	sampleQoS := []string{"normal", "high", "low", "preempt", "debug"}
-
-	for _, qosName := range sampleQoS {
+	for _, qosName := range qosNames {
 		stats, err := c.client.GetQoSStatistics(ctx, qosName, "24h")
 		if err != nil {
 			c.collectionErrors.WithLabelValues("performance", "qos_statistics_error").Inc()
@@ -1403,8 +1546,7 @@ func (c *QoSLimitsCollector) collectQoSPerformanceMetrics() {
 	c.lastCollectionTime.WithLabelValues("performance").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSHierarchyMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSHierarchyMetrics(ctx context.Context) {
 	start := time.Now()
 
 	hierarchy, err := c.client.GetQoSHierarchy(ctx)
@@ -1439,14 +1581,10 @@ func (c *QoSLimitsCollector) collectQoSHierarchyMetrics() {
 	c.lastCollectionTime.WithLabelValues("hierarchy").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectQoSEffectivenessMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectQoSEffectivenessMetrics(ctx context.Context, qosNames []string) {
 	start := time.Now()
 
-	// Sample QoS names for effectiveness analysis
-	sampleQoS := []string{"normal", "high", "low", "preempt", "debug"}
-
-	for _, qosName := range sampleQoS {
+	for _, qosName := range qosNames {
 		effectiveness, err := c.client.GetQoSEffectiveness(ctx, qosName)
 		if err != nil {
 			c.collectionErrors.WithLabelValues("effectiveness", "qos_effectiveness_error").Inc()
@@ -1468,8 +1606,7 @@ func (c *QoSLimitsCollector) collectQoSEffectivenessMetrics() {
 	c.lastCollectionTime.WithLabelValues("effectiveness").Set(float64(time.Now().Unix()))
 }
 
-func (c *QoSLimitsCollector) collectSystemQoSMetrics() {
-	ctx := context.Background()
+func (c *QoSLimitsCollector) collectSystemQoSMetrics(ctx context.Context) {
 	start := time.Now()
 
 	overview, err := c.client.GetSystemQoSOverview(ctx)
@@ -1495,3 +1632,18 @@ func (c *QoSLimitsCollector) collectSystemQoSMetrics() {
 	c.collectionDuration.WithLabelValues("system").Observe(duration)
 	c.lastCollectionTime.WithLabelValues("system").Set(float64(time.Now().Unix()))
 }
+
+// getQoSNames asks the client which QoS to report on, falling back to the
+// sample QoS names for clients that cannot list them
+func (c *QoSLimitsCollector) getQoSNames(ctx context.Context) []string {
+	if lister, ok := c.client.(QoSTargetLister); ok {
+		names, err := lister.ListQoSNames(ctx)
+		if err != nil {
+			c.collectionErrors.WithLabelValues("targets", "qos_list_error").Inc()
+			return nil
+		}
+		return names
+	}
+
+	return []string{"normal", "high", "low", "preempt", "debug"}
+}
@@ -115,6 +115,20 @@ type QueueAnalysisSLURMClient interface {
 	GetSystemLoadImpact(ctx context.Context) (*SystemLoadImpact, error)
 }
 
+// QueueAnalysisTargetLister is optionally implemented by a
+// QueueAnalysisSLURMClient to supply the partitions, jobs and users the
+// collector should analyse. Without it only the cluster-wide analyses run.
+type QueueAnalysisTargetLister interface {
+	ListQueueTargets(ctx context.Context) (*QueueAnalysisTargets, error)
+}
+
+// QueueAnalysisTargets lists the subjects of a queue analysis pass
+type QueueAnalysisTargets struct {
+	Partitions []string `json:"partitions"`
+	JobIDs     []string `json:"job_ids"`
+	Users      []string `json:"users"`
+}
+
 // QueuePositionAnalysis represents queue position analysis for a job
 type QueuePositionAnalysis struct {
 	JobID         string `json:"job_id"`
@@ -537,7 +551,7 @@ func NewQueueAnalysisCollector(client QueueAnalysisSLURMClient) *QueueAnalysisCo
 
 		queueDepth: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_queue_depth",
+				Name: "slurm_queue_analysis_depth",
 				Help: "Current depth of job queue",
 			},
 			[]string{"partition", "queue_class", "metric"},
@@ -661,7 +675,7 @@ func NewQueueAnalysisCollector(client QueueAnalysisSLURMClient) *QueueAnalysisCo
 
 		queueProcessingRate: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_queue_processing_rate",
+				Name: "slurm_queue_analysis_processing_rate",
 				Help: "Queue processing rate",
 			},
 			[]string{"partition", "rate_type"},
@@ -685,7 +699,7 @@ func NewQueueAnalysisCollector(client QueueAnalysisSLURMClient) *QueueAnalysisCo
 
 		queueEfficiencyScore: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Name: "slurm_queue_efficiency_score",
+				Name: "slurm_queue_analysis_efficiency_score",
 				Help: "Overall queue efficiency score",
 			},
 			[]string{"partition", "efficiency_type"},
@@ -1080,27 +1094,26 @@ func (c *QueueAnalysisCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface
 func (c *QueueAnalysisCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
+	_ = c.CollectWithContext(context.Background(), ch)
+}
 
+// CollectWithContext runs a queue analysis pass and sends the metrics to ch
+func (c *QueueAnalysisCollector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	// Reset metrics
 	c.resetMetrics()
 
-	// Get sample partitions for analysis
-	samplePartitions := c.getSamplePartitions()
-	for _, partition := range samplePartitions {
+	targets := c.getTargets(ctx)
+
+	for _, partition := range targets.Partitions {
 		c.collectPartitionQueueMetrics(ctx, partition)
 	}
 
-	// Get sample job IDs for position analysis
-	sampleJobIDs := c.getSampleJobIDs()
-	for _, jobID := range sampleJobIDs {
+	for _, jobID := range targets.JobIDs {
 		c.collectJobQueuePosition(ctx, jobID)
 		c.collectWaitTimePrediction(ctx, jobID)
 	}
 
-	// Get sample users for experience analysis
-	sampleUsers := c.getSampleUsers()
-	for _, user := range sampleUsers {
+	for _, user := range targets.Users {
 		c.collectUserQueueExperience(ctx, user)
 	}
 
@@ -1114,7 +1127,7 @@ func (c *QueueAnalysisCollector) Collect(ch chan<- prometheus.Metric) {
 	c.collectPriorityQueueAnalysis(ctx)
 
 	// Collect backfill analysis
-	c.collectBackfillAnalysis(ctx)
+	c.collectBackfillAnalysis(ctx, targets.Partitions)
 
 	// Collect queue state transitions
 	c.collectQueueStateTransitions(ctx)
@@ -1185,6 +1198,8 @@ func (c *QueueAnalysisCollector) Collect(ch chan<- prometheus.Metric) {
 	c.capacityUtilization.Collect(ch)
 	c.resourceFragmentation.Collect(ch)
 	c.schedulingOverhead.Collect(ch)
+
+	return ctx.Err()
 }
 
 // resetQueueMetrics resets queue-related metrics
@@ -1495,8 +1510,7 @@ func (c *QueueAnalysisCollector) collectPriorityQueueAnalysis(ctx context.Contex
 	}
 }
 
-func (c *QueueAnalysisCollector) collectBackfillAnalysis(ctx context.Context) {
-	partitions := c.getSamplePartitions()
+func (c *QueueAnalysisCollector) collectBackfillAnalysis(ctx context.Context, partitions []string) {
 	for _, partition := range partitions {
 		analysis, err := c.client.GetBackfillAnalysis(ctx, partition)
 		if err != nil {
@@ -1594,15 +1608,17 @@ func (c *QueueAnalysisCollector) collectSystemLoadImpact(ctx context.Context) {
 	}
 }
 
-// Simplified mock data generators for testing purposes
-func (c *QueueAnalysisCollector) getSamplePartitions() []string {
-	return []string{"cpu", "gpu", "bigmem"}
-}
-
-func (c *QueueAnalysisCollector) getSampleJobIDs() []string {
-	return []string{"12345", "12346", "12347"}
-}
-
-func (c *QueueAnalysisCollector) getSampleUsers() []string {
-	return []string{"user1", "user2", "user3"}
+// getTargets asks the client which partitions, jobs and users to analyse.
+// Clients that cannot list them get no per-target analysis.
+func (c *QueueAnalysisCollector) getTargets(ctx context.Context) *QueueAnalysisTargets {
+	lister, ok := c.client.(QueueAnalysisTargetLister)
+	if !ok {
+		return &QueueAnalysisTargets{}
+	}
+	targets, err := lister.ListQueueTargets(ctx)
+	if err != nil {
+		log.Printf("Error listing queue analysis targets: %v", err)
+		return &QueueAnalysisTargets{}
+	}
+	return targets
 }
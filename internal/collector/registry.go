@@ -6,15 +6,22 @@ package collector
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 
 	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-exporter/internal/adaptive"
 	"github.com/jontk/slurm-exporter/internal/config"
 	"github.com/jontk/slurm-exporter/internal/metrics"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+	"github.com/jontk/slurm-exporter/internal/tracing"
 )
 
 // Registry manages multiple collectors
@@ -23,6 +30,9 @@ type Registry struct {
 	collectors map[string]Collector
 	mu         sync.RWMutex
 
+	// Names of the collectors exporting each metric family
+	metricOwners map[string]string
+
 	// Prometheus registry
 	promRegistry *prometheus.Registry
 
@@ -38,6 +48,26 @@ type Registry struct {
 	// Performance monitoring
 	performanceMonitor *PerformanceMonitor
 
+	// Sources of the collectors fed by the exporter's own clients
+	analysisClients AnalysisClients
+
+	// Tracer for collection spans
+	tracer *tracing.CollectionTracer
+
+	// Background collection, serving scrapes from snapshots once started
+	snapshots *snapshotStore
+	scheduler *Scheduler
+
+	// Adaptive background collection intervals and the client used to
+	// measure cluster activity for them
+	adaptive       *adaptive.CollectorScheduler
+	activityClient slurm.SlurmClient
+
+	// gatherSlot admits one GatherContext at a time, whose context and
+	// failures are kept in gathering for the collector adapters
+	gatherSlot chan struct{}
+	gathering  atomic.Pointer[gatherScope]
+
 	// Logger
 	logger *logrus.Entry
 }
@@ -71,13 +101,22 @@ func NewRegistry(cfg *config.CollectorsConfig, promRegistry *prometheus.Registry
 		return nil, fmt.Errorf("failed to register performance metrics: %w", err)
 	}
 
+	// Snapshot ages are only reported once background collection starts
+	snapshots := newSnapshotStore("slurm", "exporter")
+	if err := promRegistry.Register(snapshots); err != nil {
+		return nil, fmt.Errorf("failed to register snapshot metrics: %w", err)
+	}
+
 	registry := &Registry{
 		collectors:         make(map[string]Collector),
+		metricOwners:       make(map[string]string),
 		promRegistry:       promRegistry,
 		metrics:            collectorMetrics,
 		config:             cfg,
 		cardinalityManager: cardinalityManager,
 		performanceMonitor: performanceMonitor,
+		snapshots:          snapshots,
+		gatherSlot:         make(chan struct{}, 1),
 		logger:             logger,
 	}
 
@@ -100,13 +139,20 @@ func (r *Registry) Register(name string, collector Collector) error {
 
 	// Register collector with Prometheus
 	if err := r.promRegistry.Register(&collectorAdapter{
+		name:               name,
 		collector:          collector,
 		performanceMonitor: r.performanceMonitor,
+		tracer:             r.tracer,
+		snapshots:          r.snapshots,
+		gathering:          &r.gathering,
 	}); err != nil {
 		return fmt.Errorf("failed to register collector %s with prometheus: %w", name, err)
 	}
 
 	r.collectors[name] = collector
+	for _, metric := range describedMetrics(collector) {
+		r.metricOwners[metric] = name
+	}
 	r.logger.WithField("collector", name).Info("Collector registered")
 
 	// Initialize collector state
@@ -136,6 +182,11 @@ func (r *Registry) Unregister(name string) error {
 	}
 
 	delete(r.collectors, name)
+	for metric, owner := range r.metricOwners {
+		if owner == name {
+			delete(r.metricOwners, metric)
+		}
+	}
 	r.logger.WithField("collector", name).Info("Collector unregistered")
 
 	// Remove metrics
@@ -148,6 +199,14 @@ func (r *Registry) Unregister(name string) error {
 	return nil
 }
 
+// CollectorForMetric returns the name of the collector exporting a metric
+// family, or an empty string for metrics not exported by a collector
+func (r *Registry) CollectorForMetric(metric string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.metricOwners[metric]
+}
+
 // Get returns a collector by name
 func (r *Registry) Get(name string) (Collector, bool) {
 	r.mu.RLock()
@@ -294,10 +353,55 @@ func (r *Registry) Collect(ch chan<- prometheus.Metric) {
 	// Individual collectors will handle their own collection
 }
 
+// GatherContext gathers the Prometheus registry with every collector
+// running on ctx, and returns the collectors that failed in this gather.
+// Gathers run one at a time, so a gather that outlives its caller holds
+// back the next one instead of running alongside it; waiting for it ends
+// with ctx. The registry must not be gathered otherwise at the same time.
+func (r *Registry) GatherContext(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+	select {
+	case r.gatherSlot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	defer func() { <-r.gatherSlot }()
+
+	scope := &gatherScope{ctx: ctx}
+	r.gathering.Store(scope)
+	defer r.gathering.Store(nil)
+
+	families, err := r.promRegistry.Gather()
+	return families, scope.failures(), err
+}
+
+// gatherScope is the context and collector failures of a GatherContext
+type gatherScope struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	failed []string
+}
+
+func (s *gatherScope) fail(collector string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = append(s.failed, collector)
+}
+
+func (s *gatherScope) failures() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
 // collectorAdapter adapts our Collector interface to prometheus.Collector
 type collectorAdapter struct {
+	name               string
 	collector          Collector
 	performanceMonitor *PerformanceMonitor
+	tracer             *tracing.CollectionTracer
+	snapshots          *snapshotStore
+	gathering          *atomic.Pointer[gatherScope]
 }
 
 // Describe implements prometheus.Collector
@@ -307,44 +411,85 @@ func (ca *collectorAdapter) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements prometheus.Collector
 func (ca *collectorAdapter) Collect(ch chan<- prometheus.Metric) {
+	if ca.snapshots != nil && ca.snapshots.enabled.Load() {
+		// The scheduler collects in the background; serve its last result
+		if snapshot, ok := ca.snapshots.load(ca.name); ok {
+			for _, metric := range snapshot.metrics {
+				ch <- metric
+			}
+		}
+		return
+	}
+
+	// Collect on the context of a GatherContext in progress, which joins
+	// the trace of the scrape that triggered this collection
 	ctx := context.Background()
+	var scope *gatherScope
+	if ca.gathering != nil {
+		scope = ca.gathering.Load()
+	}
+	if scope != nil {
+		ctx = scope.ctx
+	}
+	if ca.tracer != nil {
+		var finish func()
+		ctx, finish = ca.tracer.TraceCollection(ctx, ca.collector.Name())
+		defer finish()
+	}
 	startTime := time.Now()
 
-	// Create a wrapper channel to count metrics
+	// Buffer the metrics so that a collection rejected by an open circuit
+	// breaker can be replaced by the last good one
 	metricsChan := make(chan prometheus.Metric, 1000)
-	var metricsCount int
+	var collected []prometheus.Metric
 	done := make(chan struct{})
 
-	// Start a goroutine to forward metrics and count them
 	go func() {
 		defer close(done)
 		for metric := range metricsChan {
-			select {
-			case ch <- metric:
-				metricsCount++
-			case <-ctx.Done():
-				// Context cancelled, stop sending metrics
-				return
-			}
+			collected = append(collected, metric)
 		}
 	}()
 
 	// Collect metrics
-	err := ca.collector.Collect(ctx, metricsChan)
+	collectCtx, rejected := resilience.TrackRejections(ctx)
+	collectCtx, slowest := tracing.TrackSlowestCall(collectCtx)
+	err := ca.collector.Collect(collectCtx, metricsChan)
 	close(metricsChan)
 
-	// Wait for the forwarding goroutine to finish
+	// Wait for the buffering goroutine to finish
 	<-done
 
 	duration := time.Since(startTime)
+	metricsCount := len(collected)
 
 	// Record performance metrics
 	if ca.performanceMonitor != nil {
-		ca.performanceMonitor.RecordCollection(ca.collector.Name(), duration, metricsCount, err)
+		ca.performanceMonitor.RecordCollectionWithExemplar(ca.collector.Name(), duration, metricsCount, err, slowest())
+	}
+
+	if ca.tracer != nil {
+		ca.tracer.AddSpanAttribute(ctx, "metric.count", metricsCount)
+		ca.tracer.RecordError(ctx, err)
 	}
 
 	if err != nil {
 		logrus.WithError(err).WithField("collector", ca.collector.Name()).Error("Collection failed")
+		if scope != nil {
+			scope.fail(ca.name)
+		}
+	}
+
+	if ca.snapshots != nil {
+		if err == nil {
+			ca.snapshots.store(ca.name, collected, nil, startTime)
+		} else if snapshot, ok := ca.snapshots.load(ca.name); ok && rejected() {
+			logrus.WithField("collector", ca.collector.Name()).Warn("SLURM circuit breaker open, serving last good metrics")
+			collected = snapshot.metrics
+		}
+	}
+	for _, metric := range collected {
+		ch <- metric
 	}
 }
 
@@ -421,6 +566,13 @@ type RegistryOptions struct {
 
 // ReconfigureCollectors updates collector configurations without restart
 func (r *Registry) ReconfigureCollectors(cfg *config.CollectorsConfig) error {
+	err := r.reconfigureCollectors(cfg)
+	r.updateSchedules(cfg)
+	return err
+}
+
+// reconfigureCollectors applies the configuration to the collectors
+func (r *Registry) reconfigureCollectors(cfg *config.CollectorsConfig) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -442,10 +594,18 @@ func (r *Registry) ReconfigureCollectors(cfg *config.CollectorsConfig) error {
 			enabled = cfg.Jobs.Enabled
 			filterConfig = cfg.Jobs.Filters
 			customLabels = cfg.Jobs.Labels
+			if jobs, ok := collector.(*JobsSimpleCollector); ok {
+				jobs.SetMode(cfg.Jobs.Mode, cfg.Jobs.Aggregate)
+			}
 		case "nodes":
 			enabled = cfg.Nodes.Enabled
 			filterConfig = cfg.Nodes.Filters
 			customLabels = cfg.Nodes.Labels
+			if nodes, ok := collector.(*NodesSimpleCollector); ok {
+				if err := nodes.SetReasonCategories(cfg.Nodes.ReasonCategories); err != nil {
+					errors = append(errors, fmt.Errorf("nodes: %w", err))
+				}
+			}
 		case "partitions":
 			enabled = cfg.Partitions.Enabled
 			filterConfig = cfg.Partitions.Filters
@@ -482,6 +642,38 @@ func (r *Registry) ReconfigureCollectors(cfg *config.CollectorsConfig) error {
 			enabled = cfg.System.Enabled
 			filterConfig = cfg.System.Filters
 			customLabels = cfg.System.Labels
+		case "fairshare":
+			enabled = cfg.FairShare.Enabled
+			filterConfig = cfg.FairShare.Filters
+			customLabels = cfg.FairShare.Labels
+		case "accounting":
+			enabled = cfg.Accounting.Enabled
+			filterConfig = cfg.Accounting.Filters
+			customLabels = cfg.Accounting.Labels
+		case "node_events":
+			enabled = cfg.NodeEvents.Enabled
+			filterConfig = cfg.NodeEvents.Filters
+			customLabels = cfg.NodeEvents.Labels
+		case "job_priority", "priority_factors":
+			enabled = cfg.Priority.Enabled
+			filterConfig = cfg.Priority.Filters
+			customLabels = cfg.Priority.Labels
+		case "queue_analysis":
+			enabled = cfg.QueueAnalysis.Enabled
+			filterConfig = cfg.QueueAnalysis.Filters
+			customLabels = cfg.QueueAnalysis.Labels
+		case "qos_limits":
+			enabled = cfg.QoSLimits.Enabled
+			filterConfig = cfg.QoSLimits.Filters
+			customLabels = cfg.QoSLimits.Labels
+		case "account_quota":
+			enabled = cfg.AccountQuota.Enabled
+			filterConfig = cfg.AccountQuota.Filters
+			customLabels = cfg.AccountQuota.Labels
+		case "account_cost":
+			enabled = cfg.AccountCost.Enabled
+			filterConfig = cfg.AccountCost.Filters
+			customLabels = cfg.AccountCost.Labels
 		default:
 			r.logger.WithField("collector", name).Warn("Unknown collector in registry")
 			continue
@@ -520,6 +712,81 @@ func (r *Registry) ReconfigureCollectors(cfg *config.CollectorsConfig) error {
 	return nil
 }
 
+// StartBackgroundCollection collects every enabled collector on its own
+// interval until ctx is done. From then on scrapes are served from the
+// last collected snapshot instead of querying SLURM.
+func (r *Registry) StartBackgroundCollection(ctx context.Context) error {
+	r.mu.Lock()
+	if r.scheduler != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("background collection already started")
+	}
+	r.mu.Unlock()
+
+	scheduler, err := NewScheduler(r, r.config)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduler: %w", err)
+	}
+	if err := scheduler.InitializeSchedules(); err != nil {
+		return fmt.Errorf("failed to initialize schedules: %w", err)
+	}
+	r.startAdaptiveSchedules(scheduler)
+
+	r.mu.Lock()
+	r.scheduler = scheduler
+	r.mu.Unlock()
+
+	r.snapshots.enable(time.Now())
+	if err := scheduler.Start(); err != nil {
+		return fmt.Errorf("failed to start scheduler: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		scheduler.Stop()
+	}()
+
+	r.logger.Info("Background collection started")
+	return nil
+}
+
+// updateSchedules applies a new configuration to the background schedules
+func (r *Registry) updateSchedules(cfg *config.CollectorsConfig) {
+	r.mu.RLock()
+	scheduler := r.scheduler
+	collectors := make(map[string]Collector, len(r.collectors))
+	for name, collector := range r.collectors {
+		collectors[name] = collector
+	}
+	r.mu.RUnlock()
+
+	if scheduler == nil {
+		return
+	}
+
+	configs := cfg.ByName()
+	stats := scheduler.GetScheduleStats()
+	for name, collector := range collectors {
+		collectorCfg := config.CollectorConfig{Enabled: true}
+		if c, ok := configs[name]; ok {
+			collectorCfg = *c
+		}
+		interval, timeout := scheduleTiming(collectorCfg, cfg.Global)
+		if current, ok := stats[name]; ok && (current.Interval != interval || current.Timeout != timeout) {
+			if err := scheduler.UpdateSchedule(name, interval, timeout); err != nil {
+				r.logger.WithError(err).WithField("collector", name).Warn("Failed to update collection schedule")
+			}
+		}
+
+		if collector.IsEnabled() {
+			_ = scheduler.EnableSchedule(name)
+		} else {
+			_ = scheduler.DisableSchedule(name)
+			r.snapshots.remove(name)
+		}
+	}
+}
+
 // CollectorFactory is a function that creates a collector
 type CollectorFactory func(config *config.CollectorConfig) (Collector, error)
 
@@ -623,8 +890,18 @@ func (r *Registry) registerConfigurableCollectors(cfg *config.CollectorsConfig,
 		filterConfig config.FilterConfig
 		labels       map[string]string
 	}{
-		{cfg.Jobs.Enabled, "jobs", func() Collector { return NewJobsSimpleCollector(client, r.logger) }, cfg.Jobs.Filters, cfg.Jobs.Labels},
-		{cfg.Nodes.Enabled, "nodes", func() Collector { return NewNodesSimpleCollector(client, r.logger) }, config.FilterConfig{}, cfg.Nodes.Labels},
+		{cfg.Jobs.Enabled, "jobs", func() Collector {
+			jobs := NewJobsSimpleCollector(client, r.logger)
+			jobs.SetMode(cfg.Jobs.Mode, cfg.Jobs.Aggregate)
+			return jobs
+		}, cfg.Jobs.Filters, cfg.Jobs.Labels},
+		{cfg.Nodes.Enabled, "nodes", func() Collector {
+			nodes := NewNodesSimpleCollector(client, r.logger)
+			if err := nodes.SetReasonCategories(cfg.Nodes.ReasonCategories); err != nil {
+				r.logger.WithError(err).Warn("Invalid node reason categories, using defaults")
+			}
+			return nodes
+		}, config.FilterConfig{}, cfg.Nodes.Labels},
 		// Temporarily disabled during API migration:
 		// {cfg.Performance.Enabled, "performance", func() Collector { return NewPerformanceSimpleCollector(client, r.logger) }, config.FilterConfig{}, cfg.Performance.Labels},
 		{cfg.System.Enabled, "system", func() Collector { return NewSystemSimpleCollector(client, r.logger) }, config.FilterConfig{}, cfg.System.Labels},
@@ -642,6 +919,182 @@ func (r *Registry) registerConfigurableCollectors(cfg *config.CollectorsConfig,
 	return nil
 }
 
+// registerAnalysisCollectors registers the analysis collectors, which expose
+// prometheus.Collector directly and are wrapped in an analysisCollectorAdapter
+func (r *Registry) registerAnalysisCollectors(cfg *config.CollectorsConfig, client slurm.SlurmClient) error {
+	if cfg.FairShare.Enabled {
+		fairShareConfig := DefaultFairShareConfig()
+		fairShareConfig.CollectionInterval = cfg.FairShare.Interval
+		// Job priority, queue and behaviour analysis need data slurmrestd
+		// does not expose yet, so only the shares-backed analysis runs
+		fairShareConfig.EnablePriorityAnalysis = false
+		fairShareConfig.EnableQueueAnalysis = false
+		fairShareConfig.EnableBehaviorAnalysis = false
+		// The fair-share priority contribution uses the slurm.conf weights
+		// configured for the priority collectors
+		weights := cfg.Priority.Weights
+		fairShareConfig.PriorityWeights = PriorityWeights{
+			AgeWeight:       weights.Age,
+			FairShareWeight: weights.FairShare,
+			QoSWeight:       weights.QoS,
+			PartitionWeight: weights.Partition,
+			AssocWeight:     weights.Assoc,
+			JobSizeWeight:   weights.JobSize,
+		}
+
+		fairShare, err := NewFairShareCollector(client, slog.Default().With("collector", "fairshare"), fairShareConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create fairshare collector: %w", err)
+		}
+
+		timeout := cfg.FairShare.Timeout
+		if timeout <= 0 {
+			timeout = cfg.CollectionTimeout
+		}
+		if err := r.registerCollector("fairshare", newAnalysisCollectorAdapter("fairshare", fairShare, timeout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTracer sets the tracer used for collection spans. It must be called
+// before CreateCollectorsFromConfig.
+func (r *Registry) SetTracer(tracer *tracing.CollectionTracer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tracer = tracer
+}
+
+// AnalysisClients are the sources of the collectors that are fed by the
+// exporter's own clients rather than by slurm.SlurmClient directly. A
+// collector whose client is nil is skipped even when it is enabled.
+type AnalysisClients struct {
+	// Accounting reads finished jobs from slurmdbd for the accounting
+	// collector
+	Accounting JobAccountingReader
+
+	// NodeEvents streams node state changes for the node events collector
+	NodeEvents NodeStateStreamingSLURMClient
+
+	// Priority derives job priorities and their factors for the priority
+	// collectors
+	Priority PrioritySLURMClient
+
+	// QueueAnalysis analyses queue positions and wait times for the queue
+	// analysis collector
+	QueueAnalysis QueueAnalysisSLURMClient
+
+	// QoSLimits reports QoS limits and their usage for the QoS limits
+	// collector
+	QoSLimits QoSLimitsSLURMClient
+
+	// AccountQuota burns account budgets down for the account quota
+	// collector
+	AccountQuota AccountQuotaSLURMClient
+
+	// AccountCost prices account usage for the account cost tracking
+	// collector
+	AccountCost AccountCostTrackingSLURMClient
+}
+
+// SetAnalysisClients sets the sources of the client-fed collectors. It must
+// be called before CreateCollectorsFromConfig.
+func (r *Registry) SetAnalysisClients(clients AnalysisClients) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.analysisClients = clients
+}
+
+// registerAccountingCollector registers the historical job accounting collector
+func (r *Registry) registerAccountingCollector(cfg *config.CollectorsConfig, reader JobAccountingReader) error {
+	if !cfg.Accounting.Enabled {
+		return nil
+	}
+	if reader == nil {
+		r.logger.Warn("Accounting collector enabled but no slurmdbd reader is available, skipping")
+		return nil
+	}
+
+	timeout := cfg.Accounting.Timeout
+	if timeout <= 0 {
+		timeout = cfg.CollectionTimeout
+	}
+	opts := AccountingCollectorOptions{
+		Interval:  cfg.Accounting.Interval,
+		Timeout:   timeout,
+		Lookback:  cfg.Accounting.Lookback,
+		Overlap:   cfg.Accounting.Overlap,
+		StateFile: cfg.Accounting.StateFile,
+	}
+	return r.registerCollector("accounting", NewAccountingCollector(reader, opts, r.logger))
+}
+
+// clientCollector is a collector fed by one of the analysis clients
+type clientCollector struct {
+	name     string
+	settings config.CollectorConfig
+	// available reports whether the client of the collector has been set
+	available bool
+	create    func() contextCollector
+}
+
+// registerClientCollectors registers the enabled collectors fed by the
+// analysis clients, each but accounting wrapped in an
+// analysisCollectorAdapter
+func (r *Registry) registerClientCollectors(cfg *config.CollectorsConfig) error {
+	r.mu.RLock()
+	clients := r.analysisClients
+	r.mu.RUnlock()
+
+	if err := r.registerAccountingCollector(cfg, clients.Accounting); err != nil {
+		return err
+	}
+
+	collectors := []clientCollector{
+		{"node_events", cfg.NodeEvents, clients.NodeEvents != nil, func() contextCollector {
+			return NewNodeStateStreamingCollector(clients.NodeEvents)
+		}},
+		{"job_priority", cfg.Priority.CollectorConfig, clients.Priority != nil, func() contextCollector {
+			return NewJobPriorityCollector(clients.Priority)
+		}},
+		{"priority_factors", cfg.Priority.CollectorConfig, clients.Priority != nil, func() contextCollector {
+			return NewPriorityFactorsCollector(clients.Priority)
+		}},
+		{"queue_analysis", cfg.QueueAnalysis, clients.QueueAnalysis != nil, func() contextCollector {
+			return NewQueueAnalysisCollector(clients.QueueAnalysis)
+		}},
+		{"qos_limits", cfg.QoSLimits, clients.QoSLimits != nil, func() contextCollector {
+			return NewQoSLimitsCollector(clients.QoSLimits)
+		}},
+		{"account_quota", cfg.AccountQuota.CollectorConfig, clients.AccountQuota != nil, func() contextCollector {
+			return NewAccountQuotaCollector(clients.AccountQuota)
+		}},
+		{"account_cost", cfg.AccountCost.CollectorConfig, clients.AccountCost != nil, func() contextCollector {
+			return NewAccountCostTrackingCollector(clients.AccountCost)
+		}},
+	}
+
+	for _, c := range collectors {
+		if !c.settings.Enabled {
+			continue
+		}
+		if !c.available {
+			r.logger.WithField("collector", c.name).Warn("Collector enabled but its client is not available, skipping")
+			continue
+		}
+
+		timeout := c.settings.Timeout
+		if timeout <= 0 {
+			timeout = cfg.CollectionTimeout
+		}
+		if err := r.registerCollector(c.name, newAnalysisCollectorAdapter(c.name, c.create(), timeout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateCollectorsFromConfig creates and registers collectors based on configuration
 func (r *Registry) CreateCollectorsFromConfig(cfg *config.CollectorsConfig, client interface{}) error {
 	r.logger.Info("Creating collectors from configuration")
@@ -667,6 +1120,37 @@ func (r *Registry) CreateCollectorsFromConfig(cfg *config.CollectorsConfig, clie
 		return err
 	}
 
+	// Register analysis collectors built on prometheus.Collector
+	if err := r.registerAnalysisCollectors(cfg, slurmClient); err != nil {
+		return err
+	}
+
+	// Register the collectors fed by the analysis clients
+	if err := r.registerClientCollectors(cfg); err != nil {
+		return err
+	}
+
 	r.logger.WithField("count", len(r.collectors)).Info("Collectors created and registered")
 	return nil
 }
+
+// descNamePattern extracts the metric name from the string form of a
+// prometheus.Desc, which does not expose it otherwise
+var descNamePattern = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// describedMetrics returns the names of the metrics a collector describes
+func describedMetrics(collector Collector) []string {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		defer close(descs)
+		collector.Describe(descs)
+	}()
+
+	var names []string
+	for desc := range descs {
+		if match := descNamePattern.FindStringSubmatch(desc.String()); match != nil {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/jontk/slurm-exporter/internal/config"
 	"github.com/jontk/slurm-exporter/internal/metrics"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
 )
 
 // mockRegistryCollector implements the Collector interface for testing
@@ -537,11 +539,11 @@ func TestRegistryReconfiguration(t *testing.T) {
 			DefaultTimeout:  10 * time.Second,
 			MaxConcurrency:  5,
 		},
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled:  true,
 			Interval: 30 * time.Second,
 			Timeout:  10 * time.Second,
-		},
+		}},
 	}
 
 	promRegistry := prometheus.NewRegistry()
@@ -565,3 +567,211 @@ func TestRegistryReconfiguration(t *testing.T) {
 		_ = err
 	})
 }
+
+func TestRegistryCreatesFairShareCollector(t *testing.T) {
+	t.Parallel()
+	cfg := &config.CollectorsConfig{
+		Global: config.GlobalCollectorConfig{
+			DefaultInterval: 30 * time.Second,
+			DefaultTimeout:  10 * time.Second,
+			MaxConcurrency:  5,
+		},
+		FairShare: config.CollectorConfig{
+			Enabled:  true,
+			Interval: 120 * time.Second,
+			Timeout:  10 * time.Second,
+		},
+	}
+
+	mockClient := new(mocks.MockSlurmClient)
+	mockClient.On("GetShares", mock.Anything, mock.Anything).Return(testSharesList(), nil)
+
+	promRegistry := prometheus.NewRegistry()
+	registry, err := NewRegistry(cfg, promRegistry)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	if err := registry.CreateCollectorsFromConfig(cfg, mockClient); err != nil {
+		t.Fatalf("Failed to create collectors: %v", err)
+	}
+
+	if _, exists := registry.Get("fairshare"); !exists {
+		t.Fatal("Expected fairshare collector to be registered")
+	}
+
+	families, err := promRegistry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	found := false
+	for _, family := range families {
+		if family.GetName() == "slurm_user_fairshare_factor" {
+			found = true
+			if len(family.GetMetric()) != 2 {
+				t.Errorf("Expected 2 user fair-share series, got %d", len(family.GetMetric()))
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected slurm_user_fairshare_factor to be exported")
+	}
+
+	// Reconfiguration must recognise the collector
+	cfg.FairShare.Enabled = false
+	if err := registry.ReconfigureCollectors(cfg); err != nil {
+		t.Fatalf("Failed to reconfigure collectors: %v", err)
+	}
+	collector, _ := registry.Get("fairshare")
+	if collector.IsEnabled() {
+		t.Error("Expected fairshare collector to be disabled after reconfiguration")
+	}
+}
+
+func TestRegistryGatherContext(t *testing.T) {
+	t.Parallel()
+	registry, err := NewRegistry(&config.CollectorsConfig{}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	release := make(chan struct{})
+	blocking := &mockRegistryCollector{
+		name:    "blocking",
+		enabled: true,
+		collectFunc: func(ctx context.Context, ch chan<- prometheus.Metric) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-release:
+				return nil
+			}
+		},
+	}
+	if err := registry.Register("blocking", blocking); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+	if err := registry.Register("ok", &mockRegistryCollector{name: "ok", enabled: true}); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+
+	// Collectors run on the gather's context and only its failures count
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, failed, err := registry.GatherContext(ctx)
+	if err != nil {
+		t.Fatalf("GatherContext() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0] != "blocking" {
+		t.Errorf("failed = %v, want [blocking]", failed)
+	}
+
+	// A gather in progress holds back the next one
+	done := make(chan []string)
+	go func() {
+		_, failed, _ := registry.GatherContext(context.Background())
+		done <- failed
+	}()
+	time.Sleep(20 * time.Millisecond)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	if _, _, err := registry.GatherContext(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("concurrent GatherContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if failed := <-done; len(failed) != 0 {
+		t.Errorf("failed = %v, want none", failed)
+	}
+}
+
+func TestRegistryCollectorForMetric(t *testing.T) {
+	cfg := &config.CollectorsConfig{}
+	registry, err := NewRegistry(cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	collector := &mockRegistryCollector{
+		name:    "nodes",
+		enabled: true,
+		descs: []*prometheus.Desc{
+			prometheus.NewDesc("slurm_node_cpus", "CPUs", []string{"node"}, nil),
+			prometheus.NewDesc("slurm_node_state", "State", []string{"node", "state"}, prometheus.Labels{"cluster": "a"}),
+		},
+	}
+	if err := registry.Register("nodes", collector); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+
+	for metric, want := range map[string]string{
+		"slurm_node_cpus":             "nodes",
+		"slurm_node_state":            "nodes",
+		"slurm_exporter_collector_up": "",
+	} {
+		if got := registry.CollectorForMetric(metric); got != want {
+			t.Errorf("CollectorForMetric(%q) = %q, want %q", metric, got, want)
+		}
+	}
+
+	if err := registry.Unregister("nodes"); err != nil {
+		t.Fatalf("Failed to unregister collector: %v", err)
+	}
+	if got := registry.CollectorForMetric("slurm_node_cpus"); got != "" {
+		t.Errorf("CollectorForMetric() after unregister = %q, want none", got)
+	}
+}
+
+// fakeNodeEventSource satisfies NodeStateStreamingSLURMClient for
+// registration only
+type fakeNodeEventSource struct {
+	NodeStateStreamingSLURMClient
+}
+
+// fakeQoSLimitsClient satisfies QoSLimitsSLURMClient for registration only
+type fakeQoSLimitsClient struct {
+	QoSLimitsSLURMClient
+}
+
+func TestRegistrySetAnalysisClients(t *testing.T) {
+	t.Parallel()
+	cfg := &config.CollectorsConfig{
+		NodeEvents:        config.CollectorConfig{Enabled: true, Timeout: 5 * time.Second},
+		Accounting:        config.AccountingConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
+		Priority:          config.PriorityConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
+		QoSLimits:         config.CollectorConfig{Enabled: true},
+		AccountCost:       config.AccountCostConfig{CollectorConfig: config.CollectorConfig{Enabled: false}},
+		CollectionTimeout: 10 * time.Second,
+	}
+	registry, err := NewRegistry(cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	registry.SetAnalysisClients(AnalysisClients{NodeEvents: fakeNodeEventSource{}, QoSLimits: fakeQoSLimitsClient{}})
+	if err := registry.CreateCollectorsFromConfig(cfg, new(mocks.MockSlurmClient)); err != nil {
+		t.Fatalf("Failed to create collectors: %v", err)
+	}
+
+	collector, exists := registry.Get("node_events")
+	if !exists {
+		t.Fatal("Expected node_events collector to be registered")
+	}
+	if adapter, ok := collector.(*analysisCollectorAdapter); !ok || adapter.timeout != 5*time.Second {
+		t.Errorf("Expected node_events to be an analysis adapter with its own timeout, got %#v", collector)
+	}
+	collector, exists = registry.Get("qos_limits")
+	if !exists {
+		t.Fatal("Expected qos_limits collector to be registered")
+	}
+	if adapter, ok := collector.(*analysisCollectorAdapter); !ok || adapter.timeout != 10*time.Second {
+		t.Errorf("Expected qos_limits to be an analysis adapter with the collection timeout, got %#v", collector)
+	}
+	// Accounting and priority are enabled without a client, account cost
+	// has neither
+	for _, name := range []string{"accounting", "job_priority", "priority_factors", "account_cost"} {
+		if _, exists := registry.Get(name); exists {
+			t.Errorf("Expected %s collector not to be registered", name)
+		}
+	}
+}
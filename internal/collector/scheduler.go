@@ -129,33 +129,53 @@ func NewScheduler(registry *Registry, config *config.CollectorsConfig) (*Schedul
 	return scheduler, nil
 }
 
-// InitializeSchedules creates schedules from configuration
+// scheduleTiming returns the interval and timeout of a collector, falling
+// back to the global defaults
+func scheduleTiming(cfg config.CollectorConfig, global config.GlobalCollectorConfig) (time.Duration, time.Duration) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = global.DefaultInterval
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = global.DefaultTimeout
+	}
+	return interval, timeout
+}
+
+// InitializeSchedules creates schedules from configuration. Collectors that
+// are not registered get a disabled schedule; registered collectors without
+// a configuration section use the global defaults.
 func (s *Scheduler) InitializeSchedules() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Helper function to create schedule
-	//nolint:unparam
-	createSchedule := func(name string, cfg config.CollectorConfig) error {
-		interval := cfg.Interval
-		if interval <= 0 {
-			interval = s.config.Global.DefaultInterval
+	scheduleConfigs := make(map[string]config.CollectorConfig)
+	for name, cfg := range s.config.ByName() {
+		scheduleConfigs[name] = *cfg
+	}
+	for _, name := range s.registry.List() {
+		if _, ok := scheduleConfigs[name]; !ok {
+			scheduleConfigs[name] = config.CollectorConfig{Enabled: true}
 		}
+	}
+
+	for name, cfg := range scheduleConfigs {
+		interval, timeout := scheduleTiming(cfg, s.config.Global)
 
-		timeout := cfg.Timeout
-		if timeout <= 0 {
-			timeout = s.config.Global.DefaultTimeout
+		enabled := cfg.Enabled
+		if collector, registered := s.registry.Get(name); !registered || !collector.IsEnabled() {
+			enabled = false
 		}
 
-		schedule := &Schedule{
+		s.schedules[name] = &Schedule{
 			CollectorName: name,
 			Interval:      interval,
 			Timeout:       timeout,
-			enabled:       cfg.Enabled,
+			enabled:       enabled,
 		}
 
-		s.schedules[name] = schedule
-
 		// Set interval in orchestrator
 		s.orchestrator.SetCollectorInterval(name, interval)
 
@@ -163,27 +183,8 @@ func (s *Scheduler) InitializeSchedules() error {
 			"collector": name,
 			"interval":  interval,
 			"timeout":   timeout,
-			"enabled":   cfg.Enabled,
-		}).Info("Initialized collection schedule")
-
-		return nil
-	}
-
-	// Create schedules for each collector type
-	scheduleConfigs := map[string]config.CollectorConfig{
-		"cluster":     s.config.Cluster,
-		"nodes":       s.config.Nodes,
-		"jobs":        s.config.Jobs,
-		"users":       s.config.Users,
-		"partitions":  s.config.Partitions,
-		"performance": s.config.Performance,
-		"system":      s.config.System,
-	}
-
-	for name, cfg := range scheduleConfigs {
-		if err := createSchedule(name, cfg); err != nil {
-			return fmt.Errorf("failed to create schedule for %s: %w", name, err)
-		}
+			"enabled":   enabled,
+		}).Debug("Initialized collection schedule")
 	}
 
 	return nil
@@ -252,17 +253,17 @@ func (s *Scheduler) Stop() {
 	}
 }
 
-// startSchedule starts a collection schedule
+// startSchedule starts a collection schedule with an immediate first run,
+// so that snapshots are available soon after startup
 func (s *Scheduler) startSchedule(name string, schedule *Schedule) {
 	schedule.mu.Lock()
 	defer schedule.mu.Unlock()
 
-	// Calculate next run time
-	now := time.Now()
-	schedule.NextRun = now.Add(schedule.Interval)
+	// The first run is due now
+	schedule.NextRun = time.Now()
 
 	// Create timer
-	schedule.timer = time.AfterFunc(schedule.Interval, func() {
+	schedule.timer = time.AfterFunc(0, func() {
 		s.runScheduledCollection(name, schedule)
 	})
 
@@ -300,7 +301,20 @@ func (s *Scheduler) runScheduledCollection(name string, schedule *Schedule) {
 	}
 
 	// Run collection
-	result, err := s.orchestrator.CollectNow(name)
+	result, err := s.orchestrator.collectNow(name, schedule.Timeout)
+
+	// Keep the metrics for scrapes and record the collection
+	if result != nil {
+		s.registry.snapshots.store(name, result.metrics, err, result.EndTime)
+		s.registry.performanceMonitor.RecordCollectionWithExemplar(name, result.Duration, result.MetricCount, err, result.exemplar)
+	}
+
+	// Adapt the intervals to the activity seen, including this schedule's
+	if name == activityCollector && err == nil && s.registry.adaptive != nil {
+		ctx, cancel := context.WithTimeout(s.ctx, schedule.Timeout)
+		s.registry.updateActivity(ctx, s)
+		cancel()
+	}
 
 	// Update schedule stats
 	schedule.mu.Lock()
@@ -422,6 +436,39 @@ func (s *Scheduler) UpdateSchedule(name string, interval, timeout time.Duration)
 	return nil
 }
 
+// adaptInterval changes the interval of a collector's schedule from its next
+// run on. A shorter interval brings forward a pending run that is now due
+// earlier; a longer one takes effect after it.
+func (s *Scheduler) adaptInterval(name string, interval time.Duration) {
+	s.mu.RLock()
+	schedule, exists := s.schedules[name]
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	schedule.mu.Lock()
+	defer schedule.mu.Unlock()
+	if schedule.Interval == interval {
+		return
+	}
+	schedule.Interval = interval
+	s.orchestrator.SetCollectorInterval(name, interval)
+
+	nextRun := schedule.LastRun.Add(interval)
+	if schedule.enabled && schedule.timer != nil && nextRun.Before(schedule.NextRun) && schedule.timer.Stop() {
+		schedule.NextRun = nextRun
+		schedule.timer = time.AfterFunc(max(time.Until(nextRun), 0), func() {
+			s.runScheduledCollection(name, schedule)
+		})
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"collector": name,
+		"interval":  interval,
+	}).Debug("Adapted collection interval")
+}
+
 // EnableSchedule enables a collector's schedule
 func (s *Scheduler) EnableSchedule(name string) error {
 	s.mu.RLock()
@@ -59,15 +59,15 @@ func TestScheduler(t *testing.T) {
 			Interval: 50 * time.Millisecond,
 			Timeout:  25 * time.Millisecond,
 		},
-		Nodes: config.CollectorConfig{
+		Nodes: config.NodesConfig{CollectorConfig: config.CollectorConfig{
 			Enabled:  true,
 			Interval: 75 * time.Millisecond,
 			Timeout:  25 * time.Millisecond,
-		},
-		Jobs: config.CollectorConfig{
+		}},
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled:  false, // Disabled
 			Interval: 100 * time.Millisecond,
-		},
+		}},
 	}
 
 	// Create registry
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectorSnapshot is the outcome of the last successful background
+// collection of a collector
+type collectorSnapshot struct {
+	metrics     []prometheus.Metric
+	collectedAt time.Time
+}
+
+// snapshotStore holds the metrics collected in the background. While it is
+// enabled, scrapes are served from it instead of calling the collectors.
+type snapshotStore struct {
+	enabled atomic.Bool
+	started time.Time
+
+	mu        sync.RWMutex
+	snapshots map[string]*collectorSnapshot
+
+	ageDesc *prometheus.Desc
+}
+
+// newSnapshotStore creates an empty, disabled snapshot store
+func newSnapshotStore(namespace, subsystem string) *snapshotStore {
+	return &snapshotStore{
+		snapshots: make(map[string]*collectorSnapshot),
+		ageDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "collector_snapshot_age_seconds"),
+			"Seconds since the collector last completed a background collection, or since collection started if it never did",
+			[]string{"collector"},
+			nil,
+		),
+	}
+}
+
+// enable starts serving scrapes from snapshots
+func (s *snapshotStore) enable(now time.Time) {
+	s.mu.Lock()
+	s.started = now
+	s.mu.Unlock()
+	s.enabled.Store(true)
+}
+
+// store replaces the snapshot of a collector. A failed collection keeps the
+// previous snapshot, whose age then shows how stale it is; only when there
+// is none yet are the partial metrics of the failed collection kept.
+func (s *snapshotStore) store(name string, metrics []prometheus.Metric, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		if _, ok := s.snapshots[name]; !ok {
+			s.snapshots[name] = &collectorSnapshot{metrics: metrics, collectedAt: s.started}
+		}
+		return
+	}
+	s.snapshots[name] = &collectorSnapshot{metrics: metrics, collectedAt: now}
+}
+
+// remove drops the snapshot of a collector, e.g. once it is disabled
+func (s *snapshotStore) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snapshots, name)
+}
+
+// load returns the snapshot of a collector
+func (s *snapshotStore) load(name string) (*collectorSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[name]
+	return snapshot, ok
+}
+
+// Describe implements prometheus.Collector
+func (s *snapshotStore) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.ageDesc
+}
+
+// Collect implements prometheus.Collector and reports the snapshot ages
+func (s *snapshotStore) Collect(ch chan<- prometheus.Metric) {
+	if !s.enabled.Load() {
+		return
+	}
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for name, snapshot := range s.snapshots {
+		ch <- prometheus.MustNewConstMetric(s.ageDesc, prometheus.GaugeValue, now.Sub(snapshot.collectedAt).Seconds(), name)
+	}
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+)
+
+func TestRegistry_BackgroundCollection(t *testing.T) {
+	t.Parallel()
+	cfg := &config.CollectorsConfig{
+		Global: config.GlobalCollectorConfig{
+			DefaultInterval: time.Hour,
+			DefaultTimeout:  time.Second,
+			MaxConcurrency:  2,
+		},
+		Cluster: config.CollectorConfig{Enabled: true},
+	}
+	promRegistry := prometheus.NewRegistry()
+	registry, err := NewRegistry(cfg, promRegistry)
+	require.NoError(t, err)
+
+	desc := prometheus.NewDesc("slurm_test_collections", "Collections so far", nil, nil)
+	var collections atomic.Int32
+	require.NoError(t, registry.Register("cluster", &mockCollector{
+		name:    "cluster",
+		enabled: true,
+		collectFunc: func(ctx context.Context, ch chan<- prometheus.Metric) error {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(collections.Add(1)))
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, registry.StartBackgroundCollection(ctx))
+	require.Error(t, registry.StartBackgroundCollection(ctx), "starting twice must fail")
+
+	// The first collection runs right away
+	require.Eventually(t, func() bool { return collections.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	for range 3 {
+		byName := familiesByName(mustGather(t, promRegistry))
+
+		collected := byName["slurm_test_collections"]
+		require.NotNil(t, collected)
+		assert.Equal(t, 1.0, collected.Metric[0].GetGauge().GetValue())
+
+		age := byName["slurm_exporter_collector_snapshot_age_seconds"]
+		require.NotNil(t, age)
+		require.Len(t, age.Metric, 1)
+		assert.Equal(t, "cluster", labelValue(age.Metric[0], "collector"))
+	}
+	assert.Equal(t, int32(1), collections.Load(), "scrapes must not trigger collections")
+}
+
+func TestSnapshotStore_KeepsLastGoodSnapshot(t *testing.T) {
+	t.Parallel()
+	desc := prometheus.NewDesc("slurm_test_value", "Value", nil, nil)
+	metric := func(v float64) []prometheus.Metric {
+		return []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)}
+	}
+
+	started := time.Now()
+	s := newSnapshotStore("slurm", "exporter")
+	s.enable(started)
+
+	// Without an earlier snapshot a failure is served, aged from the start
+	s.store("nodes", metric(1), errors.New("timeout"), started.Add(time.Minute))
+	snapshot, ok := s.load("nodes")
+	require.True(t, ok)
+	assert.Len(t, snapshot.metrics, 1)
+	assert.Equal(t, started, snapshot.collectedAt)
+
+	good := started.Add(2 * time.Minute)
+	s.store("nodes", metric(2), nil, good)
+	s.store("nodes", nil, errors.New("timeout"), started.Add(3*time.Minute))
+	snapshot, ok = s.load("nodes")
+	require.True(t, ok)
+	assert.Len(t, snapshot.metrics, 1)
+	assert.Equal(t, good, snapshot.collectedAt)
+
+	s.remove("nodes")
+	_, ok = s.load("nodes")
+	assert.False(t, ok)
+}
+
+func TestRegistry_ServesLastGoodMetricsWhileCircuitOpen(t *testing.T) {
+	t.Parallel()
+	cfg := &config.CollectorsConfig{
+		Global:  config.GlobalCollectorConfig{DefaultTimeout: time.Second},
+		Cluster: config.CollectorConfig{Enabled: true},
+	}
+	promRegistry := prometheus.NewRegistry()
+	registry, err := NewRegistry(cfg, promRegistry)
+	require.NoError(t, err)
+
+	desc := prometheus.NewDesc("slurm_test_collections", "Collections so far", nil, nil)
+	var collections atomic.Int32
+	var failure atomic.Value
+	require.NoError(t, registry.Register("cluster", &mockCollector{
+		name:    "cluster",
+		enabled: true,
+		collectFunc: func(ctx context.Context, ch chan<- prometheus.Metric) error {
+			n := collections.Add(1)
+			if failure.Load() == "rejected" {
+				resilience.MarkRejected(ctx)
+				return resilience.ErrCircuitOpen
+			}
+			if failure.Load() == "timeout" {
+				return errors.New("timeout")
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(n))
+			return nil
+		},
+	}))
+
+	gathered := func() *float64 {
+		collected := familiesByName(mustGather(t, promRegistry))["slurm_test_collections"]
+		if collected == nil {
+			return nil
+		}
+		v := collected.Metric[0].GetGauge().GetValue()
+		return &v
+	}
+
+	require.NotNil(t, gathered())
+
+	// A rejected collection serves the last good metrics
+	failure.Store("rejected")
+	value := gathered()
+	require.NotNil(t, value)
+	assert.Equal(t, 1.0, *value)
+
+	// Other failures do not
+	failure.Store("timeout")
+	assert.Nil(t, gathered())
+	assert.Equal(t, int32(3), collections.Load())
+}
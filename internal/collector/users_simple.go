@@ -28,6 +28,7 @@ type UsersSimpleCollector struct {
 	userJobsPending  *prometheus.Desc
 	userCPUsUsed     *prometheus.Desc
 	userMemoryUsed   *prometheus.Desc
+	userGRESUsed     *prometheus.Desc
 	userAssociations *prometheus.Desc
 }
 
@@ -75,6 +76,13 @@ func NewUsersSimpleCollector(client slurm.SlurmClient, logger *logrus.Entry) *Us
 		nil,
 	)
 
+	c.userGRESUsed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, usersCollectorSubsystem, "gres_used"),
+		"Number of generic resources (e.g. GPUs) allocated to the user's running jobs by type and model",
+		[]string{"user", "account", "partition", "gres_type", "gres_model"},
+		nil,
+	)
+
 	c.userAssociations = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, usersCollectorSubsystem, "associations_total"),
 		"Number of associations for the user",
@@ -107,6 +115,7 @@ func (c *UsersSimpleCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.userJobsPending
 	ch <- c.userCPUsUsed
 	ch <- c.userMemoryUsed
+	ch <- c.userGRESUsed
 	ch <- c.userAssociations
 }
 
@@ -209,6 +218,17 @@ func (c *UsersSimpleCollector) collect(ctx context.Context, ch chan<- prometheus
 					user.Name, key.account, key.partition,
 				)
 			}
+
+			for key, gres := range stats.gresUsed {
+				for gresKey, count := range gres {
+					ch <- prometheus.MustNewConstMetric(
+						c.userGRESUsed,
+						prometheus.GaugeValue,
+						count,
+						user.Name, key.account, key.partition, gresKey.gresType, gresKey.model,
+					)
+				}
+			}
 		}
 	}
 
@@ -227,6 +247,7 @@ type userJobStats struct {
 	pendingJobs map[userJobKey]int
 	cpusUsed    map[userJobKey]int
 	memoryUsed  map[userJobKey]int64
+	gresUsed    map[userJobKey]gresCounts
 }
 
 // collectJobStatsByUser collects job statistics grouped by user
@@ -256,6 +277,7 @@ func (c *UsersSimpleCollector) collectJobStatsByUser(ctx context.Context, jobsMa
 				pendingJobs: make(map[userJobKey]int),
 				cpusUsed:    make(map[userJobKey]int),
 				memoryUsed:  make(map[userJobKey]int64),
+				gresUsed:    make(map[userJobKey]gresCounts),
 			}
 		}
 
@@ -285,6 +307,13 @@ func (c *UsersSimpleCollector) collectJobStatsByUser(ctx context.Context, jobsMa
 			// CPU/memory tracking would require parsing TRES, skip for now
 			// TODO: Add TRES parsing when needed
 
+			if gres := jobGRES(job.TRESAllocStr, job.TRESPerNode, job.TRESPerJob, job.NodeCount); len(gres) > 0 {
+				if stats[userName].gresUsed[key] == nil {
+					stats[userName].gresUsed[key] = make(gresCounts)
+				}
+				stats[userName].gresUsed[key].add(gres, 1)
+			}
+
 		case "PENDING":
 			stats[userName].pendingJobs[key]++
 		}
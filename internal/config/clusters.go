@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clusterNamePattern restricts cluster names to safe label values
+var clusterNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ClusterConfig describes one cluster scraped by a multi-cluster exporter.
+//
+// An entry takes any key of the top-level slurm section (base_url, auth, tls,
+// timeout, ...) plus scrape_timeout and a collectors section. Settings it
+// leaves out are inherited from the top-level slurm and collectors sections.
+type ClusterConfig struct {
+	Name string `yaml:"name"`
+
+	// ScrapeTimeout bounds how long a scrape waits for this cluster before
+	// reporting it down. Defaults to collectors.global.default_timeout.
+	ScrapeTimeout time.Duration `yaml:"scrape_timeout"`
+
+	// SLURM and Collectors are the effective settings, resolved on load
+	SLURM      SLURMConfig      `yaml:"-"`
+	Collectors CollectorsConfig `yaml:"-"`
+
+	// overrides holds the entry as written, applied on top of the top-level
+	// settings by resolveClusters
+	overrides *yaml.Node
+}
+
+// UnmarshalYAML keeps the entry so it can be resolved once the top-level
+// settings, including environment overrides, are known
+func (c *ClusterConfig) UnmarshalYAML(node *yaml.Node) error {
+	var entry struct {
+		Name          string        `yaml:"name"`
+		ScrapeTimeout time.Duration `yaml:"scrape_timeout"`
+	}
+	if err := node.Decode(&entry); err != nil {
+		return err
+	}
+	c.Name = entry.Name
+	c.ScrapeTimeout = entry.ScrapeTimeout
+	c.overrides = node
+	return nil
+}
+
+// resolveClusters computes the effective settings of every cluster entry
+func (c *Config) resolveClusters() error {
+	for i := range c.Clusters {
+		cluster := &c.Clusters[i]
+
+		slurm, collectors, err := c.resolveOverrides(cluster.overrides)
+		if err != nil {
+			return fmt.Errorf("clusters[%d] (%s): %w", i, cluster.Name, err)
+		}
+
+		cluster.SLURM = slurm
+		cluster.Collectors = collectors
+		if cluster.ScrapeTimeout == 0 {
+			cluster.ScrapeTimeout = collectors.Global.DefaultTimeout
+		}
+	}
+	return nil
+}
+
+// resolveOverrides applies an entry that takes slurm keys and a collectors
+// section on top of copies of the top-level slurm and collectors sections
+func (c *Config) resolveOverrides(overrides *yaml.Node) (SLURMConfig, CollectorsConfig, error) {
+	slurm, err := deepCopy(c.SLURM)
+	if err != nil {
+		return slurm, CollectorsConfig{}, err
+	}
+	collectors, err := deepCopy(c.Collectors)
+	if err != nil {
+		return slurm, collectors, err
+	}
+	if overrides == nil {
+		return slurm, collectors, nil
+	}
+
+	// Unknown keys such as name and collectors are ignored here
+	if err := overrides.Decode(&slurm); err != nil {
+		return slurm, collectors, err
+	}
+	var section struct {
+		Collectors yaml.Node `yaml:"collectors"`
+	}
+	if err := overrides.Decode(&section); err != nil {
+		return slurm, collectors, err
+	}
+	if !section.Collectors.IsZero() {
+		if err := section.Collectors.Decode(&collectors); err != nil {
+			return slurm, collectors, fmt.Errorf("collectors: %w", err)
+		}
+	}
+	return slurm, collectors, nil
+}
+
+// deepCopy copies a configuration section through YAML so that maps and
+// slices are not shared between clusters
+func deepCopy[T any](in T) (T, error) {
+	var out T
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return out, fmt.Errorf("failed to copy configuration: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("failed to copy configuration: %w", err)
+	}
+	return out, nil
+}
+
+// validateClusters validates the cluster list
+func (c *Config) validateClusters() error {
+	seen := make(map[string]bool, len(c.Clusters))
+	stateFiles := make(map[string]string, len(c.Clusters))
+	for i := range c.Clusters {
+		cluster := &c.Clusters[i]
+		if cluster.Name == "" {
+			return fmt.Errorf("clusters[%d].name cannot be empty", i)
+		}
+		if !clusterNamePattern.MatchString(cluster.Name) {
+			return fmt.Errorf("clusters[%d].name '%s' may only contain letters, digits, '_', '.' and '-'", i, cluster.Name)
+		}
+		if seen[cluster.Name] {
+			return fmt.Errorf("clusters[%d].name '%s' is used more than once", i, cluster.Name)
+		}
+		seen[cluster.Name] = true
+
+		if cluster.ScrapeTimeout <= 0 {
+			return fmt.Errorf("cluster %s: scrape_timeout must be positive, got '%v'", cluster.Name, cluster.ScrapeTimeout)
+		}
+
+		if err := cluster.SLURM.Validate(); err != nil {
+			return fmt.Errorf("cluster %s: %w", cluster.Name, err)
+		}
+		if err := cluster.Collectors.Validate(); err != nil {
+			return fmt.Errorf("cluster %s collectors: %w", cluster.Name, err)
+		}
+
+		// Each cluster keeps its own accounting high-water mark
+		if accounting := cluster.Collectors.Accounting; accounting.Enabled && accounting.StateFile != "" {
+			if other, ok := stateFiles[accounting.StateFile]; ok {
+				return fmt.Errorf("clusters %s and %s share collectors.accounting.state_file '%s', set one per cluster", other, cluster.Name, accounting.StateFile)
+			}
+			stateFiles[accounting.StateFile] = cluster.Name
+		}
+	}
+	return nil
+}
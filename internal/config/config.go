@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,8 +21,9 @@ import (
 
 // Authentication type constants
 const (
-	AuthTypeJWT  = "jwt"
-	AuthTypeNone = "none"
+	AuthTypeJWT    = "jwt"
+	AuthTypeJWTKey = "jwt_key"
+	AuthTypeNone   = "none"
 )
 
 // Config represents the application configuration.
@@ -32,6 +35,13 @@ type Config struct {
 	Metrics       MetricsConfig       `yaml:"metrics"`
 	Observability ObservabilityConfig `yaml:"observability"`
 	Validation    ValidationConfig    `yaml:"validation"`
+
+	// Clusters switches the exporter to multi-cluster mode, one entry per
+	// slurmrestd endpoint
+	Clusters []ClusterConfig `yaml:"clusters"`
+
+	// Probe serves slurmrestd targets chosen by Prometheus on request
+	Probe ProbeConfig `yaml:"probe"`
 }
 
 // ServerConfig holds HTTP server configuration.
@@ -85,6 +95,10 @@ type SLURMConfig struct {
 	RetryDelay    time.Duration   `yaml:"retry_delay"`
 	TLS           SLURMTLSConfig  `yaml:"tls"`
 	RateLimit     RateLimitConfig `yaml:"rate_limit"`
+
+	// SnapshotMaxAge is how long a fetched endpoint is shared with other
+	// collectors before it is fetched again; 0 only shares concurrent fetches
+	SnapshotMaxAge time.Duration `yaml:"snapshot_max_age"`
 }
 
 // SLURMTLSConfig holds TLS configuration for SLURM connections.
@@ -103,23 +117,25 @@ type RateLimitConfig struct {
 
 // AuthConfig holds authentication configuration.
 type AuthConfig struct {
-	Type         string            `yaml:"type"`          // jwt, basic, apikey, none
-	Token        string            `yaml:"token"`         // For JWT
-	TokenFile    string            `yaml:"token_file"`    // For JWT from file
-	Username     string            `yaml:"username"`      // For basic auth
-	Password     string            `yaml:"password"`      // For basic auth
-	PasswordFile string            `yaml:"password_file"` // For basic auth from file
-	APIKey       string            `yaml:"api_key"`       // For API key auth
-	APIKeyFile   string            `yaml:"api_key_file"`  // For API key from file
-	Headers      map[string]string `yaml:"headers"`       // Custom headers
+	Type          string            `yaml:"type"`           // jwt, jwt_key, basic, apikey, none
+	Token         string            `yaml:"token"`          // For JWT
+	TokenFile     string            `yaml:"token_file"`     // For JWT from file, re-read when it changes
+	KeyFile       string            `yaml:"key_file"`       // For jwt_key: the cluster's jwt_hs256.key
+	TokenLifetime time.Duration     `yaml:"token_lifetime"` // For jwt_key: lifetime of minted tokens
+	Username      string            `yaml:"username"`       // For basic auth, and the token user for JWT
+	Password      string            `yaml:"password"`       // For basic auth
+	PasswordFile  string            `yaml:"password_file"`  // For basic auth from file
+	APIKey        string            `yaml:"api_key"`        // For API key auth
+	APIKeyFile    string            `yaml:"api_key_file"`   // For API key from file
+	Headers       map[string]string `yaml:"headers"`        // Custom headers
 }
 
 // CollectorsConfig holds configuration for metric collectors.
 type CollectorsConfig struct {
 	Global            GlobalCollectorConfig `yaml:"global"`
 	Cluster           CollectorConfig       `yaml:"cluster"`
-	Nodes             CollectorConfig       `yaml:"nodes"`
-	Jobs              CollectorConfig       `yaml:"jobs"`
+	Nodes             NodesConfig           `yaml:"nodes"`
+	Jobs              JobsConfig            `yaml:"jobs"`
 	Users             CollectorConfig       `yaml:"users"`
 	Accounts          CollectorConfig       `yaml:"accounts"`
 	Associations      CollectorConfig       `yaml:"associations"`
@@ -130,6 +146,14 @@ type CollectorsConfig struct {
 	Reservations      CollectorConfig       `yaml:"reservations"`
 	Licenses          CollectorConfig       `yaml:"licenses"`
 	Shares            CollectorConfig       `yaml:"shares"`
+	FairShare         CollectorConfig       `yaml:"fairshare"`
+	Accounting        AccountingConfig      `yaml:"accounting"`
+	NodeEvents        CollectorConfig       `yaml:"node_events"`
+	Priority          PriorityConfig        `yaml:"priority"`
+	QueueAnalysis     CollectorConfig       `yaml:"queue_analysis"`
+	QoSLimits         CollectorConfig       `yaml:"qos_limits"`
+	AccountQuota      AccountQuotaConfig    `yaml:"account_quota"`
+	AccountCost       AccountCostConfig     `yaml:"account_cost"`
 	Diagnostics       CollectorConfig       `yaml:"diagnostics"`
 	TRES              CollectorConfig       `yaml:"tres"`
 	WCKeys            CollectorConfig       `yaml:"wckeys"`
@@ -138,6 +162,40 @@ type CollectorsConfig struct {
 	CollectionTimeout time.Duration         `yaml:"collection_timeout"`
 }
 
+// ByName returns the settings of every collector keyed by its registry name
+func (c *CollectorsConfig) ByName() map[string]*CollectorConfig {
+	return map[string]*CollectorConfig{
+		"cluster":      &c.Cluster,
+		"nodes":        &c.Nodes.CollectorConfig,
+		"jobs":         &c.Jobs.CollectorConfig,
+		"users":        &c.Users,
+		"accounts":     &c.Accounts,
+		"associations": &c.Associations,
+		"partitions":   &c.Partitions,
+		"performance":  &c.Performance,
+		"system":       &c.System,
+		"qos":          &c.QoS,
+		"reservations": &c.Reservations,
+		"licenses":     &c.Licenses,
+		"shares":       &c.Shares,
+		"fairshare":    &c.FairShare,
+		"accounting":   &c.Accounting.CollectorConfig,
+		"node_events":  &c.NodeEvents,
+		"diagnostics":  &c.Diagnostics,
+		"tres":         &c.TRES,
+		"wckeys":       &c.WCKeys,
+		"clusters":     &c.Clusters,
+
+		// Both priority collectors share the priority settings
+		"job_priority":     &c.Priority.CollectorConfig,
+		"priority_factors": &c.Priority.CollectorConfig,
+		"queue_analysis":   &c.QueueAnalysis,
+		"qos_limits":       &c.QoSLimits,
+		"account_quota":    &c.AccountQuota.CollectorConfig,
+		"account_cost":     &c.AccountCost.CollectorConfig,
+	}
+}
+
 // GlobalCollectorConfig holds global collector settings.
 type GlobalCollectorConfig struct {
 	DefaultInterval     time.Duration         `yaml:"default_interval"`
@@ -147,6 +205,10 @@ type GlobalCollectorConfig struct {
 	ErrorThreshold      int                   `yaml:"error_threshold"`
 	RecoveryDelay       time.Duration         `yaml:"recovery_delay"`
 	GracefulDegradation bool                  `yaml:"graceful_degradation"`
+
+	// BackgroundCollection runs each collector on its own interval and
+	// serves scrapes from the last snapshot instead of collecting per scrape
+	BackgroundCollection bool `yaml:"background_collection"`
 }
 
 // CollectorConfig holds configuration for individual collectors.
@@ -160,6 +222,108 @@ type CollectorConfig struct {
 	ErrorHandling  ErrorHandlingConfig `yaml:"error_handling"`
 }
 
+// AccountingConfig holds configuration for the historical job accounting
+// collector, which polls the slurmdbd jobs endpoint.
+type AccountingConfig struct {
+	CollectorConfig `yaml:",inline"`
+	Lookback        time.Duration `yaml:"lookback"`   // Window queried on first start, before any high-water mark exists
+	Overlap         time.Duration `yaml:"overlap"`    // Re-queried margin before the high-water mark for late slurmdbd records
+	StateFile       string        `yaml:"state_file"` // Where the high-water mark is persisted across restarts
+}
+
+// PriorityConfig holds configuration for the job priority and priority
+// factors collectors. slurmrestd reports each job's total priority but not
+// the multifactor weights, so they are configured here to match slurm.conf.
+type PriorityConfig struct {
+	CollectorConfig `yaml:",inline"`
+	Weights         PriorityWeightsConfig `yaml:"weights"`
+	MaxAge          time.Duration         `yaml:"max_age"` // PriorityMaxAge: age at which the age factor reaches 1.0
+}
+
+// PriorityWeightsConfig mirrors the PriorityWeight* settings of slurm.conf.
+type PriorityWeightsConfig struct {
+	Age       float64            `yaml:"age"`       // PriorityWeightAge
+	FairShare float64            `yaml:"fairshare"` // PriorityWeightFairshare
+	JobSize   float64            `yaml:"job_size"`  // PriorityWeightJobSize
+	Partition float64            `yaml:"partition"` // PriorityWeightPartition
+	QoS       float64            `yaml:"qos"`       // PriorityWeightQOS
+	Assoc     float64            `yaml:"assoc"`     // PriorityWeightAssoc
+	TRES      map[string]float64 `yaml:"tres"`      // PriorityWeightTRES by TRES, e.g. cpu, mem or gres/gpu
+}
+
+// AccountQuotaConfig holds configuration for the account quota collector,
+// which burns association GrpTRESMins budgets down against their usage.
+type AccountQuotaConfig struct {
+	CollectorConfig  `yaml:",inline"`
+	UsageResetPeriod string        `yaml:"usage_reset_period"` // PriorityUsageResetPeriod: none, daily, weekly, monthly, quarterly or yearly
+	BurnRateWindow   time.Duration `yaml:"burn_rate_window"`   // Recent usage the burn rate is averaged over
+}
+
+// UsageResetPeriods are the PriorityUsageResetPeriod values budgets can be
+// reset on
+var UsageResetPeriods = []string{"none", "daily", "weekly", "monthly", "quarterly", "yearly"}
+
+// AccountCostConfig holds configuration for the account cost tracking
+// collector, which prices the TRES accounts use from a price table.
+type AccountCostConfig struct {
+	CollectorConfig `yaml:",inline"`
+	Prices          map[string]float64            `yaml:"prices"`           // Price per TRES-hour by TRES, e.g. billing, cpu or gres/gpu:a100; mem is per GB-hour
+	PartitionPrices map[string]map[string]float64 `yaml:"partition_prices"` // Prices by partition, overriding the default prices TRES by TRES
+	Budgets         map[string]float64            `yaml:"budgets"`          // Budget by account for each budget period
+	BudgetPeriod    string                        `yaml:"budget_period"`    // daily, weekly, monthly, quarterly or yearly
+	BurnRateWindow  time.Duration                 `yaml:"burn_rate_window"` // Recent cost the burn rate is averaged over
+}
+
+// BudgetPeriods are the periods cost budgets can be set for
+var BudgetPeriods = []string{"daily", "weekly", "monthly", "quarterly", "yearly"}
+
+// NodesConfig holds configuration for the nodes collector.
+type NodesConfig struct {
+	CollectorConfig  `yaml:",inline"`
+	ReasonCategories []NodeReasonCategory `yaml:"reason_categories"` // First matching rule names the category of a node's drain/down reason
+}
+
+// NodeReasonCategory maps node reasons matching a regular expression to a
+// normalised category.
+type NodeReasonCategory struct {
+	Category string `yaml:"category"`
+	Pattern  string `yaml:"pattern"`
+}
+
+// DefaultNodeReasonCategories returns the reason categories used when none are configured.
+func DefaultNodeReasonCategories() []NodeReasonCategory {
+	return []NodeReasonCategory{
+		{Category: "not_responding", Pattern: `(?i)not responding|no_respond`},
+		{Category: "health_check", Pattern: `(?i)health.?check|\bnhc\b`},
+		{Category: "hardware", Pattern: `(?i)hardware|\bhw\b|\becc\b|dimm|memory error|disk|\bpsu\b|\bfan\b|\bxid\b|\bgpu\b|nvlink`},
+		{Category: "network", Pattern: `(?i)network|infiniband|\bib\b|\bopa\b|ethernet|link down`},
+		{Category: "maintenance", Pattern: `(?i)maint|upgrade|firmware|reboot|update|patch`},
+		{Category: "scheduler", Pattern: `(?i)kill task failed|prolog|epilog|batch job complete failure`},
+	}
+}
+
+// JobsConfig holds configuration for the jobs collector.
+type JobsConfig struct {
+	CollectorConfig `yaml:",inline"`
+	Mode            string              `yaml:"mode"`      // per_job (default) or aggregate
+	Aggregate       JobsAggregateConfig `yaml:"aggregate"` // Grouping used when mode is aggregate
+}
+
+// JobsAggregateConfig controls how jobs are grouped in aggregate mode.
+type JobsAggregateConfig struct {
+	GroupBy []string `yaml:"group_by"` // Subset of user, account, partition, qos, state
+	TopN    int      `yaml:"top_n"`    // Per-job series kept for the N longest-pending and N largest jobs; 0 disables
+}
+
+// Jobs collector modes
+const (
+	JobsModePerJob    = "per_job"
+	JobsModeAggregate = "aggregate"
+)
+
+// JobsAggregateLabels lists the labels jobs can be grouped by in aggregate mode.
+var JobsAggregateLabels = []string{"user", "account", "partition", "qos", "state"}
+
 // FilterConfig holds filtering configuration for collectors.
 type FilterConfig struct {
 	// Entity filters
@@ -331,15 +495,17 @@ func Default() *Config {
 				RequestsPerSecond: 10.0,
 				BurstSize:         20,
 			},
+			SnapshotMaxAge: 5 * time.Second,
 		},
 		Collectors: CollectorsConfig{
 			Global: GlobalCollectorConfig{
-				DefaultInterval:     30 * time.Second,
-				DefaultTimeout:      10 * time.Second,
-				MaxConcurrency:      5,
-				ErrorThreshold:      5,
-				RecoveryDelay:       60 * time.Second,
-				GracefulDegradation: true,
+				DefaultInterval:      30 * time.Second,
+				DefaultTimeout:       10 * time.Second,
+				MaxConcurrency:       5,
+				ErrorThreshold:       5,
+				RecoveryDelay:        60 * time.Second,
+				GracefulDegradation:  true,
+				BackgroundCollection: true,
 				BatchProcessing: BatchProcessingConfig{
 					Enabled:           true,
 					MaxBatchSize:      100,
@@ -393,9 +559,50 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Nodes: CollectorConfig{
+			Nodes: NodesConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  true,
+					Interval: 30 * time.Second,
+					Timeout:  10 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				ReasonCategories: DefaultNodeReasonCategories(),
+			},
+			Jobs: JobsConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  true,
+					Interval: 15 * time.Second,
+					Timeout:  10 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				Mode: JobsModePerJob,
+				Aggregate: JobsAggregateConfig{
+					GroupBy: []string{"account", "partition", "qos", "state"},
+				},
+			},
+			Users: CollectorConfig{
 				Enabled:  true,
-				Interval: 30 * time.Second,
+				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -409,9 +616,9 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Jobs: CollectorConfig{
+			Partitions: CollectorConfig{
 				Enabled:  true,
-				Interval: 15 * time.Second,
+				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -425,9 +632,9 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Users: CollectorConfig{
-				Enabled:  true,
-				Interval: 60 * time.Second,
+			Performance: CollectorConfig{
+				Enabled:  false, // Disabled - not registered in collector registry (API migration)
+				Interval: 30 * time.Second,
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -441,7 +648,7 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Partitions: CollectorConfig{
+			System: CollectorConfig{
 				Enabled:  true,
 				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
@@ -457,9 +664,9 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Performance: CollectorConfig{
-				Enabled:  false, // Disabled - not registered in collector registry (API migration)
-				Interval: 30 * time.Second,
+			QoS: CollectorConfig{
+				Enabled:  true,
+				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -473,7 +680,7 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			System: CollectorConfig{
+			Reservations: CollectorConfig{
 				Enabled:  true,
 				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
@@ -489,7 +696,7 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			QoS: CollectorConfig{
+			Licenses: CollectorConfig{
 				Enabled:  true,
 				Interval: 60 * time.Second,
 				Timeout:  10 * time.Second,
@@ -505,9 +712,9 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Reservations: CollectorConfig{
+			Shares: CollectorConfig{
 				Enabled:  true,
-				Interval: 60 * time.Second,
+				Interval: 120 * time.Second, // Less frequent as fairshare changes slowly
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -521,9 +728,9 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Licenses: CollectorConfig{
-				Enabled:  true,
-				Interval: 60 * time.Second,
+			FairShare: CollectorConfig{
+				Enabled:  false,             // Disabled by default; builds on the shares endpoint
+				Interval: 120 * time.Second, // Fair-share factors decay slowly
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -537,9 +744,120 @@ func Default() *Config {
 					MaxRetryDelay: 60 * time.Second,
 				},
 			},
-			Shares: CollectorConfig{
-				Enabled:  true,
-				Interval: 120 * time.Second, // Less frequent as fairshare changes slowly
+			Accounting: AccountingConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  false,             // Disabled by default; requires slurmdbd
+					Interval: 300 * time.Second, // Finished jobs only need to be counted once
+					Timeout:  30 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				Lookback: time.Hour,
+				Overlap:  10 * time.Minute,
+			},
+			Priority: PriorityConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  false,            // Disabled by default; needs the slurm.conf priority weights
+					Interval: 60 * time.Second, // Slurm recalculates priorities every PriorityCalcPeriod (5m)
+					Timeout:  30 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				MaxAge: 7 * 24 * time.Hour,
+			},
+			QueueAnalysis: CollectorConfig{
+				Enabled:  false,            // Disabled by default; lists every job each interval
+				Interval: 60 * time.Second, // How often queue positions and wait times are analysed
+				Timeout:  30 * time.Second,
+				Filters: FilterConfig{
+					Metrics: MetricFilterConfig{
+						EnableAll: true,
+					},
+				},
+				ErrorHandling: ErrorHandlingConfig{
+					MaxRetries:    3,
+					RetryDelay:    5 * time.Second,
+					BackoffFactor: 2.0,
+					MaxRetryDelay: 60 * time.Second,
+				},
+			},
+			QoSLimits: CollectorConfig{
+				Enabled:  false,            // Disabled by default; lists every job each interval
+				Interval: 60 * time.Second, // How often usage is compared with the QoS limits
+				Timeout:  30 * time.Second,
+				Filters: FilterConfig{
+					Metrics: MetricFilterConfig{
+						EnableAll: true,
+					},
+				},
+				ErrorHandling: ErrorHandlingConfig{
+					MaxRetries:    3,
+					RetryDelay:    5 * time.Second,
+					BackoffFactor: 2.0,
+					MaxRetryDelay: 60 * time.Second,
+				},
+			},
+			AccountQuota: AccountQuotaConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  false,             // Disabled by default; lists associations with their usage
+					Interval: 300 * time.Second, // Association usage is rolled up hourly
+					Timeout:  60 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				UsageResetPeriod: "none",
+				BurnRateWindow:   7 * 24 * time.Hour,
+			},
+			AccountCost: AccountCostConfig{
+				CollectorConfig: CollectorConfig{
+					Enabled:  false,             // Disabled by default; needs a price table
+					Interval: 300 * time.Second, // Association usage is rolled up hourly
+					Timeout:  60 * time.Second,
+					Filters: FilterConfig{
+						Metrics: MetricFilterConfig{
+							EnableAll: true,
+						},
+					},
+					ErrorHandling: ErrorHandlingConfig{
+						MaxRetries:    3,
+						RetryDelay:    5 * time.Second,
+						BackoffFactor: 2.0,
+						MaxRetryDelay: 60 * time.Second,
+					},
+				},
+				BudgetPeriod:   "monthly",
+				BurnRateWindow: 7 * 24 * time.Hour,
+			},
+			NodeEvents: CollectorConfig{
+				Enabled:  false,            // Disabled by default; polls the nodes endpoint on its own
+				Interval: 30 * time.Second, // How often node snapshots are diffed
 				Timeout:  10 * time.Second,
 				Filters: FilterConfig{
 					Metrics: MetricFilterConfig{
@@ -655,18 +973,20 @@ func Default() *Config {
 			Tracing: TracingConfig{
 				Enabled:    false, // Disabled by default for performance
 				SampleRate: 0.01,  // 1% sampling when enabled
-				Endpoint:   "localhost:4317",
+				Endpoint:   "localhost:4318",
 				Insecure:   true,
 			},
 			AdaptiveCollection: AdaptiveCollectionConfig{
-				Enabled:      true,
-				MinInterval:  30 * time.Second,
-				MaxInterval:  5 * time.Minute,
-				BaseInterval: 1 * time.Minute,
-				ScoreWindow:  10 * time.Minute,
+				Enabled:          false, // Overrides collector intervals, so opt-in
+				MinInterval:      30 * time.Second,
+				MaxInterval:      5 * time.Minute,
+				BaseInterval:     1 * time.Minute,
+				ScoreWindow:      10 * time.Minute,
+				ActiveCollectors: []string{"jobs", "nodes"},
+				StableCollectors: []string{"qos", "tres", "licenses", "clusters"},
 			},
 			SmartFiltering: SmartFilteringConfig{
-				Enabled:        true,
+				Enabled:        false, // Drops series, so opt-in
 				NoiseThreshold: 0.8,
 				CacheSize:      10000,
 				LearningWindow: 100,
@@ -726,6 +1046,12 @@ func Default() *Config {
 				MaxConcurrency: 4,
 			},
 		},
+		Probe: ProbeConfig{
+			Enabled:    false,
+			Path:       "/probe",
+			MaxTargets: 100,
+			ClientTTL:  10 * time.Minute,
+		},
 	}
 }
 
@@ -770,6 +1096,14 @@ func Load(filename string) (*Config, error) {
 		return cfg, fmt.Errorf("failed to apply environment overrides: %w", err)
 	}
 
+	// Cluster entries inherit the top-level settings after overrides
+	if err := cfg.resolveClusters(); err != nil {
+		return cfg, fmt.Errorf("failed to resolve clusters: %w", err)
+	}
+	if err := cfg.resolveProbeModules(); err != nil {
+		return cfg, fmt.Errorf("failed to resolve probe modules: %w", err)
+	}
+
 	// Validate configuration with enhanced validation
 	if err := cfg.ValidateEnhanced(); err != nil {
 		return cfg, fmt.Errorf("configuration validation failed: %w", err)
@@ -804,6 +1138,17 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("observability configuration: %w", err)
 	}
 
+	if err := c.validateClusters(); err != nil {
+		return fmt.Errorf("clusters configuration: %w", err)
+	}
+
+	if err := c.Probe.Validate(); err != nil {
+		return fmt.Errorf("probe configuration: %w", err)
+	}
+	if c.Probe.Enabled && c.Probe.Path == c.Server.MetricsPath {
+		return fmt.Errorf("probe configuration: probe.path '%s' conflicts with server.metrics_path", c.Probe.Path)
+	}
+
 	return nil
 }
 
@@ -903,6 +1248,10 @@ func (s *SLURMConfig) Validate() error {
 		return fmt.Errorf("slurm.retry_delay must be positive, got '%v' (example: '5s', '10s')", s.RetryDelay)
 	}
 
+	if s.SnapshotMaxAge < 0 {
+		return fmt.Errorf("slurm.snapshot_max_age cannot be negative, got '%v' (use 0 to only share concurrent fetches)", s.SnapshotMaxAge)
+	}
+
 	if err := s.Auth.Validate(); err != nil {
 		return fmt.Errorf("auth configuration: %w", err)
 	}
@@ -937,6 +1286,20 @@ func (a *AuthConfig) validateJWTAuth() error {
 	return validateFileExists(a.TokenFile, "slurm.auth.token_file")
 }
 
+// validateJWTKeyAuth validates self-signed JWT authentication configuration
+func (a *AuthConfig) validateJWTKeyAuth() error {
+	if a.Username == "" {
+		return fmt.Errorf("slurm.auth.username must be specified when using jwt_key auth (tokens are minted for this SLURM user)")
+	}
+	if a.KeyFile == "" {
+		return fmt.Errorf("slurm.auth.key_file must be specified when using jwt_key auth (example: '/etc/slurm/jwt_hs256.key')")
+	}
+	if a.TokenLifetime < 0 {
+		return fmt.Errorf("slurm.auth.token_lifetime cannot be negative, got '%v' (use 0 for the default of 5m)", a.TokenLifetime)
+	}
+	return validateFileExists(a.KeyFile, "slurm.auth.key_file")
+}
+
 // validateBasicAuth validates basic authentication configuration
 func (a *AuthConfig) validateBasicAuth() error {
 	if a.Username == "" {
@@ -965,6 +1328,10 @@ func (a *AuthConfig) Validate() error {
 		if err := a.validateJWTAuth(); err != nil {
 			return err
 		}
+	case AuthTypeJWTKey:
+		if err := a.validateJWTKeyAuth(); err != nil {
+			return err
+		}
 	case "basic":
 		if err := a.validateBasicAuth(); err != nil {
 			return err
@@ -974,7 +1341,7 @@ func (a *AuthConfig) Validate() error {
 			return err
 		}
 	default:
-		return fmt.Errorf("unsupported auth type: '%s' (supported types: 'none', 'jwt', 'basic', 'apikey')", a.Type)
+		return fmt.Errorf("unsupported auth type: '%s' (supported types: 'none', 'jwt', 'jwt_key', 'basic', 'apikey')", a.Type)
 	}
 
 	return nil
@@ -1008,14 +1375,22 @@ func (c *CollectorsConfig) Validate() error {
 		collector CollectorConfig
 	}{
 		{"cluster", c.Cluster},
-		{"nodes", c.Nodes},
-		{"jobs", c.Jobs},
+		{"nodes", c.Nodes.CollectorConfig},
+		{"jobs", c.Jobs.CollectorConfig},
 		{"users", c.Users},
 		{"partitions", c.Partitions},
 		{"performance", c.Performance},
 		{"system", c.System},
 		{"qos", c.QoS},
 		{"reservations", c.Reservations},
+		{"fairshare", c.FairShare},
+		{"accounting", c.Accounting.CollectorConfig},
+		{"node_events", c.NodeEvents},
+		{"priority", c.Priority.CollectorConfig},
+		{"queue_analysis", c.QueueAnalysis},
+		{"qos_limits", c.QoSLimits},
+		{"account_quota", c.AccountQuota.CollectorConfig},
+		{"account_cost", c.AccountCost.CollectorConfig},
 	}
 
 	for _, col := range collectors {
@@ -1024,6 +1399,77 @@ func (c *CollectorsConfig) Validate() error {
 		}
 	}
 
+	for i, rule := range c.Nodes.ReasonCategories {
+		if rule.Category == "" {
+			return fmt.Errorf("collectors.nodes.reason_categories[%d].category cannot be empty", i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("collectors.nodes.reason_categories[%d].pattern is not a valid regular expression: %w", i, err)
+		}
+	}
+
+	if err := c.Jobs.validateMode(); err != nil {
+		return fmt.Errorf("collectors.jobs: %w", err)
+	}
+
+	if c.Accounting.Enabled {
+		if c.Accounting.Lookback <= 0 {
+			return fmt.Errorf("collectors.accounting.lookback must be positive when enabled, got '%v' (example: '1h')", c.Accounting.Lookback)
+		}
+		if c.Accounting.Overlap < 0 {
+			return fmt.Errorf("collectors.accounting.overlap cannot be negative, got '%v' (example: '10m')", c.Accounting.Overlap)
+		}
+	}
+
+	if c.AccountQuota.Enabled {
+		if c.AccountQuota.BurnRateWindow <= 0 {
+			return fmt.Errorf("collectors.account_quota.burn_rate_window must be positive when enabled, got '%v' (example: '168h')", c.AccountQuota.BurnRateWindow)
+		}
+		if c.AccountQuota.UsageResetPeriod != "" && !slices.Contains(UsageResetPeriods, strings.ToLower(c.AccountQuota.UsageResetPeriod)) {
+			return fmt.Errorf("collectors.account_quota.usage_reset_period must be one of %s, got '%s'", strings.Join(UsageResetPeriods, ", "), c.AccountQuota.UsageResetPeriod)
+		}
+	}
+
+	if c.AccountCost.Enabled {
+		if err := c.AccountCost.validate(); err != nil {
+			return fmt.Errorf("collectors.account_cost: %w", err)
+		}
+	}
+
+	if c.Priority.Enabled {
+		if c.Priority.MaxAge <= 0 {
+			return fmt.Errorf("collectors.priority.max_age must be positive when enabled, got '%v' (example: '168h')", c.Priority.MaxAge)
+		}
+		weights := c.Priority.Weights
+		var total float64
+		for _, weight := range []struct {
+			name  string
+			value float64
+		}{
+			{"age", weights.Age},
+			{"fairshare", weights.FairShare},
+			{"job_size", weights.JobSize},
+			{"partition", weights.Partition},
+			{"qos", weights.QoS},
+			{"assoc", weights.Assoc},
+		} {
+			if weight.value < 0 {
+				return fmt.Errorf("collectors.priority.weights.%s cannot be negative, got %v", weight.name, weight.value)
+			}
+			total += weight.value
+		}
+		for tres, weight := range weights.TRES {
+			if weight < 0 {
+				return fmt.Errorf("collectors.priority.weights.tres.%s cannot be negative, got %v", tres, weight)
+			}
+			total += weight
+		}
+		// Without weights every factor contribution reads as zero
+		if total == 0 {
+			return fmt.Errorf("collectors.priority.weights must set at least one PriorityWeight* value of slurm.conf when enabled (example: 'age: 1000')")
+		}
+	}
+
 	// Validate degradation config
 	if err := c.Degradation.Validate(); err != nil {
 		return fmt.Errorf("collectors.degradation: %w", err)
@@ -1032,6 +1478,68 @@ func (c *CollectorsConfig) Validate() error {
 	return nil
 }
 
+// validateMode validates the jobs collector mode and aggregation settings.
+func (c *JobsConfig) validateMode() error {
+	switch c.Mode {
+	case "", JobsModePerJob:
+		return nil
+	case JobsModeAggregate:
+	default:
+		return fmt.Errorf("invalid mode '%s', must be one of: %s, %s", c.Mode, JobsModePerJob, JobsModeAggregate)
+	}
+
+	if len(c.Aggregate.GroupBy) == 0 {
+		return fmt.Errorf("aggregate.group_by must list at least one label when mode is aggregate (valid: %s)", strings.Join(JobsAggregateLabels, ", "))
+	}
+	seen := make(map[string]bool, len(c.Aggregate.GroupBy))
+	for _, label := range c.Aggregate.GroupBy {
+		if !slices.Contains(JobsAggregateLabels, label) {
+			return fmt.Errorf("invalid aggregate.group_by label '%s', must be one of: %s", label, strings.Join(JobsAggregateLabels, ", "))
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate aggregate.group_by label '%s'", label)
+		}
+		seen[label] = true
+	}
+
+	if c.Aggregate.TopN < 0 {
+		return fmt.Errorf("aggregate.top_n cannot be negative, got %d (use 0 to drop all per-job series)", c.Aggregate.TopN)
+	}
+	return nil
+}
+
+// validate checks the price table, budgets and budget period of the
+// account cost tracking collector
+func (c *AccountCostConfig) validate() error {
+	if len(c.Prices) == 0 && len(c.PartitionPrices) == 0 {
+		return fmt.Errorf("prices must price at least one TRES when enabled (example: 'cpu: 0.02')")
+	}
+	for tres, price := range c.Prices {
+		if price < 0 {
+			return fmt.Errorf("prices.%s cannot be negative, got %v", tres, price)
+		}
+	}
+	for partition, prices := range c.PartitionPrices {
+		for tres, price := range prices {
+			if price < 0 {
+				return fmt.Errorf("partition_prices.%s.%s cannot be negative, got %v", partition, tres, price)
+			}
+		}
+	}
+	for account, budget := range c.Budgets {
+		if budget < 0 {
+			return fmt.Errorf("budgets.%s cannot be negative, got %v", account, budget)
+		}
+	}
+	if c.BudgetPeriod != "" && !slices.Contains(BudgetPeriods, strings.ToLower(c.BudgetPeriod)) {
+		return fmt.Errorf("budget_period must be one of %s, got '%s'", strings.Join(BudgetPeriods, ", "), c.BudgetPeriod)
+	}
+	if c.BurnRateWindow <= 0 {
+		return fmt.Errorf("burn_rate_window must be positive when enabled, got '%v' (example: '168h')", c.BurnRateWindow)
+	}
+	return nil
+}
+
 // Validate validates the collector configuration.
 func (c *CollectorConfig) Validate() error {
 	if c.Enabled {
@@ -1359,6 +1867,9 @@ func (c *Config) applySLURMEnvOverrides(prefix string) error {
 	if err := envDuration(prefix+"RETRY_DELAY", func(v time.Duration) error { c.SLURM.RetryDelay = v; return nil }); err != nil {
 		return err
 	}
+	if err := envDuration(prefix+"SNAPSHOT_MAX_AGE", func(v time.Duration) error { c.SLURM.SnapshotMaxAge = v; return nil }); err != nil {
+		return err
+	}
 
 	// Integer overrides
 	if err := envInt(prefix+"RETRY_ATTEMPTS", func(v int) error { c.SLURM.RetryAttempts = v; return nil }); err != nil {
@@ -1369,6 +1880,10 @@ func (c *Config) applySLURMEnvOverrides(prefix string) error {
 	envString(prefix+"AUTH_TYPE", func(v string) { c.SLURM.Auth.Type = v })
 	envString(prefix+"AUTH_TOKEN", func(v string) { c.SLURM.Auth.Token = v })
 	envString(prefix+"AUTH_TOKEN_FILE", func(v string) { c.SLURM.Auth.TokenFile = v })
+	envString(prefix+"AUTH_KEY_FILE", func(v string) { c.SLURM.Auth.KeyFile = v })
+	if err := envDuration(prefix+"AUTH_TOKEN_LIFETIME", func(v time.Duration) error { c.SLURM.Auth.TokenLifetime = v; return nil }); err != nil {
+		return err
+	}
 	envString(prefix+"AUTH_USERNAME", func(v string) { c.SLURM.Auth.Username = v })
 	envString(prefix+"AUTH_PASSWORD", func(v string) { c.SLURM.Auth.Password = v })
 	envString(prefix+"AUTH_PASSWORD_FILE", func(v string) { c.SLURM.Auth.PasswordFile = v })
@@ -1421,17 +1936,33 @@ func (c *Config) applyCollectorsEnvOverrides(prefix string) error {
 		c.Collectors.Global.MaxConcurrency = concurrency
 	}
 
+	if val := os.Getenv(prefix + "GLOBAL_BACKGROUND_COLLECTION"); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid global background collection: %w", err)
+		}
+		c.Collectors.Global.BackgroundCollection = enabled
+	}
+
 	// Individual collector overrides
 	collectors := map[string]*CollectorConfig{
-		"CLUSTER":      &c.Collectors.Cluster,
-		"NODES":        &c.Collectors.Nodes,
-		"JOBS":         &c.Collectors.Jobs,
-		"USERS":        &c.Collectors.Users,
-		"PARTITIONS":   &c.Collectors.Partitions,
-		"PERFORMANCE":  &c.Collectors.Performance,
-		"SYSTEM":       &c.Collectors.System,
-		"QOS":          &c.Collectors.QoS,
-		"RESERVATIONS": &c.Collectors.Reservations,
+		"CLUSTER":        &c.Collectors.Cluster,
+		"NODES":          &c.Collectors.Nodes.CollectorConfig,
+		"JOBS":           &c.Collectors.Jobs.CollectorConfig,
+		"USERS":          &c.Collectors.Users,
+		"PARTITIONS":     &c.Collectors.Partitions,
+		"PERFORMANCE":    &c.Collectors.Performance,
+		"SYSTEM":         &c.Collectors.System,
+		"QOS":            &c.Collectors.QoS,
+		"RESERVATIONS":   &c.Collectors.Reservations,
+		"FAIRSHARE":      &c.Collectors.FairShare,
+		"ACCOUNTING":     &c.Collectors.Accounting.CollectorConfig,
+		"NODE_EVENTS":    &c.Collectors.NodeEvents,
+		"PRIORITY":       &c.Collectors.Priority.CollectorConfig,
+		"QUEUE_ANALYSIS": &c.Collectors.QueueAnalysis,
+		"QOS_LIMITS":     &c.Collectors.QoSLimits,
+		"ACCOUNT_QUOTA":  &c.Collectors.AccountQuota.CollectorConfig,
+		"ACCOUNT_COST":   &c.Collectors.AccountCost.CollectorConfig,
 	}
 
 	for name, collector := range collectors {
@@ -1440,6 +1971,9 @@ func (c *Config) applyCollectorsEnvOverrides(prefix string) error {
 		}
 	}
 
+	envString(prefix+"ACCOUNTING_STATE_FILE", func(v string) { c.Collectors.Accounting.StateFile = v })
+	envString(prefix+"JOBS_MODE", func(v string) { c.Collectors.Jobs.Mode = v })
+
 	return nil
 }
 
@@ -1763,6 +2297,9 @@ type TracingConfig struct {
 	SampleRate float64 `yaml:"sample_rate"`
 	Endpoint   string  `yaml:"endpoint"`
 	Insecure   bool    `yaml:"insecure"`
+
+	// CollectorSampleRates overrides SampleRate for individual collectors
+	CollectorSampleRates map[string]float64 `yaml:"collector_sample_rates"`
 }
 
 // AdaptiveCollectionConfig holds adaptive collection configuration
@@ -1772,6 +2309,14 @@ type AdaptiveCollectionConfig struct {
 	MaxInterval  time.Duration `yaml:"max_interval"`
 	BaseInterval time.Duration `yaml:"base_interval"`
 	ScoreWindow  time.Duration `yaml:"score_window"`
+
+	// ActiveCollectors follow cluster activity, down to min_interval
+	// during bursts
+	ActiveCollectors []string `yaml:"active_collectors"`
+
+	// StableCollectors are stretched towards max_interval while the cluster
+	// is quiet but never collected more often than base_interval
+	StableCollectors []string `yaml:"stable_collectors"`
 }
 
 // SmartFilteringConfig holds smart filtering configuration
@@ -1782,6 +2327,17 @@ type SmartFilteringConfig struct {
 	LearningWindow int     `yaml:"learning_window"`
 	VarianceLimit  float64 `yaml:"variance_limit"`
 	CorrelationMin float64 `yaml:"correlation_min"`
+
+	// DryRun learns and reports filter decisions without dropping any series
+	DryRun bool `yaml:"dry_run"`
+
+	// SampleEvery keeps one in this many scrapes of series marked for
+	// reduction; 0 drops them like filtered series
+	SampleEvery int `yaml:"sample_every"`
+
+	// Collectors limits filtering to the series of these collectors; all
+	// collectors when empty
+	Collectors []string `yaml:"collectors"`
 }
 
 // CircuitBreakerConfig holds circuit breaker configuration
@@ -1914,7 +2470,13 @@ func (t *TracingConfig) Validate() error {
 		}
 
 		if t.Endpoint == "" {
-			return fmt.Errorf("endpoint must be specified when tracing is enabled (example: 'localhost:4317')")
+			return fmt.Errorf("endpoint must be specified when tracing is enabled (example: 'localhost:4318')")
+		}
+
+		for collector, rate := range t.CollectorSampleRates {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("collector_sample_rates.%s must be between 0 and 1, got %.4f", collector, rate)
+			}
 		}
 	}
 
@@ -1947,6 +2509,12 @@ func (a *AdaptiveCollectionConfig) Validate() error {
 		if a.ScoreWindow <= 0 {
 			return fmt.Errorf("score_window must be positive, got '%v' (example: '10m')", a.ScoreWindow)
 		}
+
+		for _, collector := range a.StableCollectors {
+			if slices.Contains(a.ActiveCollectors, collector) {
+				return fmt.Errorf("collector %q cannot be both active and stable", collector)
+			}
+		}
 	}
 
 	return nil
@@ -1974,6 +2542,10 @@ func (s *SmartFilteringConfig) Validate() error {
 		if s.CorrelationMin < 0 || s.CorrelationMin > 1 {
 			return fmt.Errorf("correlation_min must be between 0 and 1, got %.2f", s.CorrelationMin)
 		}
+
+		if s.SampleEvery < 0 {
+			return fmt.Errorf("sample_every must be non-negative, got %d", s.SampleEvery)
+		}
 	}
 
 	return nil
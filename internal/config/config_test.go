@@ -5,6 +5,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -171,8 +172,8 @@ func TestCollectorsConfig(t *testing.T) {
 	// Test individual collector settings (excluding Performance which is disabled by default)
 	collectors := []CollectorConfig{
 		cfg.Collectors.Cluster,
-		cfg.Collectors.Nodes,
-		cfg.Collectors.Jobs,
+		cfg.Collectors.Nodes.CollectorConfig,
+		cfg.Collectors.Jobs.CollectorConfig,
 		cfg.Collectors.Users,
 		cfg.Collectors.Partitions,
 		cfg.Collectors.System,
@@ -520,6 +521,22 @@ func TestValidateAuthConfig(t *testing.T) {
 			},
 			valid: false,
 		},
+		{
+			name: "jwt_key without username",
+			config: AuthConfig{
+				Type:    "jwt_key",
+				KeyFile: "/etc/slurm/jwt_hs256.key",
+			},
+			valid: false,
+		},
+		{
+			name: "jwt_key without key file",
+			config: AuthConfig{
+				Type:     "jwt_key",
+				Username: "slurm",
+			},
+			valid: false,
+		},
 		{
 			name: "basic auth with credentials",
 			config: AuthConfig{
@@ -693,6 +710,116 @@ func TestValidateCollectorConfig(t *testing.T) {
 	}
 }
 
+func TestValidateJobsMode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		config JobsConfig
+		valid  bool
+	}{
+		{
+			name:   "default per-job mode",
+			config: JobsConfig{},
+			valid:  true,
+		},
+		{
+			name: "aggregate mode",
+			config: JobsConfig{
+				Mode:      JobsModeAggregate,
+				Aggregate: JobsAggregateConfig{GroupBy: []string{"account", "state"}, TopN: 10},
+			},
+			valid: true,
+		},
+		{
+			name:   "unknown mode",
+			config: JobsConfig{Mode: "summary"},
+			valid:  false,
+		},
+		{
+			name: "aggregate mode with unknown label",
+			config: JobsConfig{
+				Mode:      JobsModeAggregate,
+				Aggregate: JobsAggregateConfig{GroupBy: []string{"account", "job_name"}},
+			},
+			valid: false,
+		},
+		{
+			name: "aggregate mode without labels",
+			config: JobsConfig{
+				Mode: JobsModeAggregate,
+			},
+			valid: false,
+		},
+		{
+			name: "aggregate mode with negative top_n",
+			config: JobsConfig{
+				Mode:      JobsModeAggregate,
+				Aggregate: JobsAggregateConfig{GroupBy: []string{"partition"}, TopN: -1},
+			},
+			valid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.config.validateMode()
+			if tt.valid && err != nil {
+				t.Errorf("Expected config to be valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected config to be invalid, got no error")
+			}
+		})
+	}
+}
+
+func TestValidatePriorityWeights(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		weights PriorityWeightsConfig
+		valid   bool
+	}{
+		{
+			name:    "all weights zero",
+			weights: PriorityWeightsConfig{},
+			valid:   false,
+		},
+		{
+			name:    "age weight",
+			weights: PriorityWeightsConfig{Age: 1000},
+			valid:   true,
+		},
+		{
+			name:    "TRES weights only",
+			weights: PriorityWeightsConfig{TRES: map[string]float64{"gres/gpu": 1000}},
+			valid:   true,
+		},
+		{
+			name:    "negative TRES weight",
+			weights: PriorityWeightsConfig{Age: 1000, TRES: map[string]float64{"cpu": -1}},
+			valid:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := Default().Collectors
+			cfg.Priority.Enabled = true
+			cfg.Priority.Weights = tt.weights
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected config to be valid, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected config to be invalid, got no error")
+			}
+		})
+	}
+}
+
 func TestDefault(t *testing.T) {
 	t.Parallel()
 	cfg := Default()
@@ -1088,3 +1215,214 @@ func TestSLURMConfigURLValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadClusters(t *testing.T) {
+	t.Parallel()
+	yamlContent := `
+slurm:
+  base_url: "https://slurm-a:6820"
+  timeout: 20s
+  auth:
+    type: "jwt"
+    token: "shared-token"
+    headers:
+      X-Site: "hq"
+
+collectors:
+  jobs:
+    enabled: true
+    interval: "30s"
+
+clusters:
+  - name: alpha
+  - name: beta
+    base_url: "https://slurm-b:6820"
+    scrape_timeout: 5s
+    auth:
+      type: "jwt"
+      token: "beta-token"
+      headers:
+        X-Cluster: "beta"
+    collectors:
+      jobs:
+        enabled: false
+`
+
+	tmpFile, err := os.CreateTemp("", "clusters-config-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.WriteString(yamlContent); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	cfg, err := Load(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Expected no error loading clusters config, got: %v", err)
+	}
+	if len(cfg.Clusters) != 2 {
+		t.Fatalf("Expected 2 clusters, got: %d", len(cfg.Clusters))
+	}
+
+	// A bare entry inherits the top-level settings
+	alpha := cfg.Clusters[0]
+	if alpha.SLURM.BaseURL != "https://slurm-a:6820" || alpha.SLURM.Auth.Token != "shared-token" {
+		t.Errorf("Expected alpha to inherit the slurm section, got: %s %s", alpha.SLURM.BaseURL, alpha.SLURM.Auth.Token)
+	}
+	if !alpha.Collectors.Jobs.Enabled || alpha.Collectors.Jobs.Interval != 30*time.Second {
+		t.Errorf("Expected alpha to inherit the collectors section, got: %+v", alpha.Collectors.Jobs.CollectorConfig)
+	}
+	if alpha.ScrapeTimeout != cfg.Collectors.Global.DefaultTimeout {
+		t.Errorf("Expected alpha scrape timeout to default to %v, got: %v", cfg.Collectors.Global.DefaultTimeout, alpha.ScrapeTimeout)
+	}
+
+	// Overrides replace only what they set
+	beta := cfg.Clusters[1]
+	if beta.SLURM.BaseURL != "https://slurm-b:6820" || beta.SLURM.Auth.Token != "beta-token" {
+		t.Errorf("Expected beta overrides, got: %s %s", beta.SLURM.BaseURL, beta.SLURM.Auth.Token)
+	}
+	if beta.SLURM.Timeout != 20*time.Second {
+		t.Errorf("Expected beta to inherit the slurm timeout, got: %v", beta.SLURM.Timeout)
+	}
+	if beta.Collectors.Jobs.Enabled || beta.Collectors.Jobs.Interval != 30*time.Second {
+		t.Errorf("Expected beta to disable jobs and keep its interval, got: %+v", beta.Collectors.Jobs.CollectorConfig)
+	}
+	if beta.ScrapeTimeout != 5*time.Second {
+		t.Errorf("Expected beta scrape timeout to be 5s, got: %v", beta.ScrapeTimeout)
+	}
+
+	// Maps are copied, not shared between clusters
+	if _, ok := alpha.SLURM.Auth.Headers["X-Cluster"]; ok {
+		t.Error("Expected beta headers not to leak into alpha")
+	}
+	if _, ok := cfg.SLURM.Auth.Headers["X-Cluster"]; ok {
+		t.Error("Expected beta headers not to leak into the top-level slurm section")
+	}
+	if beta.SLURM.Auth.Headers["X-Site"] != "hq" {
+		t.Errorf("Expected beta to merge inherited headers, got: %v", beta.SLURM.Auth.Headers)
+	}
+}
+
+func TestValidateClusters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		clusters []ClusterConfig
+		errMsg   string
+	}{
+		{"empty name", []ClusterConfig{{Name: ""}}, "name cannot be empty"},
+		{"invalid name", []ClusterConfig{{Name: "a b"}}, "may only contain"},
+		{"duplicate name", []ClusterConfig{{Name: "a"}, {Name: "a"}}, "used more than once"},
+		{"no scrape timeout", []ClusterConfig{{Name: "a"}}, "scrape_timeout must be positive"},
+		{"shared state file", []ClusterConfig{{Name: "a"}, {Name: "b"}}, "share collectors.accounting.state_file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := Default()
+			cfg.Clusters = tt.clusters
+			for i := range cfg.Clusters {
+				cfg.Clusters[i].SLURM = cfg.SLURM
+				cfg.Clusters[i].Collectors = cfg.Collectors
+				if tt.name != "no scrape timeout" {
+					cfg.Clusters[i].ScrapeTimeout = time.Second
+				}
+				if tt.name == "shared state file" {
+					cfg.Clusters[i].Collectors.Accounting.Enabled = true
+					cfg.Clusters[i].Collectors.Accounting.StateFile = "/var/lib/slurm-exporter/accounting.json"
+				}
+			}
+
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestLoadProbeModules(t *testing.T) {
+	t.Parallel()
+	yamlContent := `
+slurm:
+  auth:
+    type: "jwt"
+    token: "shared-token"
+
+probe:
+  enabled: true
+  allowed_targets:
+    - 'https://slurm-[a-z]+\.example\.com:6820'
+  modules:
+    minimal:
+      scrape_timeout: 15s
+      auth:
+        type: "none"
+      collectors:
+        jobs:
+          enabled: false
+`
+
+	tmpFile, err := os.CreateTemp("", "probe-config-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.WriteString(yamlContent); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	cfg, err := Load(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Expected no error loading probe config, got: %v", err)
+	}
+
+	if cfg.Probe.Path != "/probe" || cfg.Probe.MaxTargets != 100 {
+		t.Errorf("Expected probe defaults, got path %s and max_targets %d", cfg.Probe.Path, cfg.Probe.MaxTargets)
+	}
+
+	// The default module is added and inherits everything
+	def, ok := cfg.Probe.Modules[DefaultProbeModule]
+	if !ok {
+		t.Fatal("Expected a default probe module")
+	}
+	if def.SLURM.Auth.Token != "shared-token" || !def.Collectors.Jobs.Enabled {
+		t.Errorf("Expected the default module to inherit the top-level settings, got: %+v", def.SLURM.Auth)
+	}
+
+	minimal := cfg.Probe.Modules["minimal"]
+	if minimal.SLURM.Auth.Type != "none" || minimal.Collectors.Jobs.Enabled {
+		t.Errorf("Expected minimal module overrides, got auth %s and jobs %v", minimal.SLURM.Auth.Type, minimal.Collectors.Jobs.Enabled)
+	}
+	if minimal.ScrapeTimeout != 15*time.Second {
+		t.Errorf("Expected minimal scrape timeout to be 15s, got: %v", minimal.ScrapeTimeout)
+	}
+
+	patterns, err := cfg.Probe.AllowedTargetPatterns()
+	if err != nil {
+		t.Fatalf("AllowedTargetPatterns() error = %v", err)
+	}
+	if !patterns[0].MatchString("https://slurm-a.example.com:6820") || patterns[0].MatchString("https://slurm-a.example.com:6820.evil.net") {
+		t.Error("Expected allowed targets to match whole URLs only")
+	}
+
+	// Without allowed targets, credentials would go to any URL
+	allowed := cfg.Probe.AllowedTargets
+	cfg.Probe.AllowedTargets = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "probe.allowed_targets is required") {
+		t.Errorf("Expected missing allowed_targets to be rejected, got: %v", err)
+	}
+	cfg.Probe.AllowedTargets = allowed
+
+	// A probe path that hides the metrics endpoint is rejected
+	cfg.Probe.Path = cfg.Server.MetricsPath
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "conflicts with server.metrics_path") {
+		t.Errorf("Expected metrics path conflict, got: %v", err)
+	}
+}
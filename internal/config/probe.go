@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProbeModule is the module used when a probe names none
+const DefaultProbeModule = "default"
+
+// ProbeConfig configures the probe endpoint, which scrapes the slurmrestd
+// given in its target parameter the way the blackbox and SNMP exporters do.
+type ProbeConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+
+	// AllowedTargets are regular expressions a target URL must match in
+	// full. Module credentials are sent to the target, so at least one is
+	// required.
+	AllowedTargets []string `yaml:"allowed_targets"`
+
+	// MaxTargets bounds the number of targets with a cached client
+	MaxTargets int `yaml:"max_targets"`
+
+	// ClientTTL is how long the client of a target is kept after its last probe
+	ClientTTL time.Duration `yaml:"client_ttl"`
+
+	// Modules are the named auth and collector profiles a probe can select
+	Modules map[string]ProbeModule `yaml:"modules"`
+}
+
+// ProbeModule is a named profile for probes.
+//
+// A module takes any key of the top-level slurm section except base_url,
+// which comes from the probe target, plus scrape_timeout and a collectors
+// section. Settings it leaves out are inherited from the top-level sections.
+type ProbeModule struct {
+	// ScrapeTimeout bounds a probe. Defaults to
+	// collectors.global.default_timeout; a shorter timeout sent by
+	// Prometheus takes precedence.
+	ScrapeTimeout time.Duration `yaml:"scrape_timeout"`
+
+	// SLURM and Collectors are the effective settings, resolved on load
+	SLURM      SLURMConfig      `yaml:"-"`
+	Collectors CollectorsConfig `yaml:"-"`
+
+	overrides *yaml.Node
+}
+
+// UnmarshalYAML keeps the module so it can be resolved once the top-level
+// settings, including environment overrides, are known
+func (m *ProbeModule) UnmarshalYAML(node *yaml.Node) error {
+	var entry struct {
+		ScrapeTimeout time.Duration `yaml:"scrape_timeout"`
+	}
+	if err := node.Decode(&entry); err != nil {
+		return err
+	}
+	m.ScrapeTimeout = entry.ScrapeTimeout
+	m.overrides = node
+	return nil
+}
+
+// resolveProbeModules computes the effective settings of every probe module,
+// adding a default module that inherits everything when none is configured
+func (c *Config) resolveProbeModules() error {
+	if !c.Probe.Enabled {
+		return nil
+	}
+	if c.Probe.Modules == nil {
+		c.Probe.Modules = make(map[string]ProbeModule)
+	}
+	if _, ok := c.Probe.Modules[DefaultProbeModule]; !ok {
+		c.Probe.Modules[DefaultProbeModule] = ProbeModule{}
+	}
+
+	for name, module := range c.Probe.Modules {
+		slurm, collectors, err := c.resolveOverrides(module.overrides)
+		if err != nil {
+			return fmt.Errorf("probe module %s: %w", name, err)
+		}
+		module.SLURM = slurm
+		module.Collectors = collectors
+		if module.ScrapeTimeout == 0 {
+			module.ScrapeTimeout = collectors.Global.DefaultTimeout
+		}
+		c.Probe.Modules[name] = module
+	}
+	return nil
+}
+
+// AllowedTargetPatterns compiles the allowed target expressions, anchored so
+// that they match the whole target
+func (p *ProbeConfig) AllowedTargetPatterns() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(p.AllowedTargets))
+	for _, expr := range p.AllowedTargets {
+		pattern, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed_targets expression '%s': %w", expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Validate validates the probe configuration.
+func (p *ProbeConfig) Validate() error {
+	if !p.Enabled {
+		return nil
+	}
+	if !strings.HasPrefix(p.Path, "/") {
+		return fmt.Errorf("probe.path must start with '/', got '%s'", p.Path)
+	}
+	if p.MaxTargets <= 0 {
+		return fmt.Errorf("probe.max_targets must be positive, got %d", p.MaxTargets)
+	}
+	if p.ClientTTL <= 0 {
+		return fmt.Errorf("probe.client_ttl must be positive, got '%v'", p.ClientTTL)
+	}
+	if len(p.AllowedTargets) == 0 {
+		return fmt.Errorf("probe.allowed_targets is required: module credentials are sent to every probed target")
+	}
+	if _, err := p.AllowedTargetPatterns(); err != nil {
+		return fmt.Errorf("probe: %w", err)
+	}
+
+	for name, module := range p.Modules {
+		if module.ScrapeTimeout <= 0 {
+			return fmt.Errorf("probe module %s: scrape_timeout must be positive, got '%v'", name, module.ScrapeTimeout)
+		}
+		if err := module.SLURM.Validate(); err != nil {
+			return fmt.Errorf("probe module %s: %w", name, err)
+		}
+		if err := module.Collectors.Validate(); err != nil {
+			return fmt.Errorf("probe module %s collectors: %w", name, err)
+		}
+	}
+	return nil
+}
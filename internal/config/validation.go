@@ -139,7 +139,7 @@ func (c *Config) validateSecurity() ValidationErrors {
 	}
 
 	// SLURM authentication validation
-	if c.SLURM.Auth.Type == "jwt" && c.SLURM.Auth.Token == "" {
+	if c.SLURM.Auth.Type == "jwt" && c.SLURM.Auth.Token == "" && c.SLURM.Auth.TokenFile == "" {
 		errors = append(errors, ValidationError{
 			Field:   "slurm.auth.token",
 			Message: "JWT token is required when auth type is 'jwt'",
@@ -248,8 +248,8 @@ func (c *Config) validateBusinessLogic() ValidationErrors {
 
 	// Validate custom labels are reasonable
 	for collectorName, collector := range map[string]CollectorConfig{
-		"jobs":         c.Collectors.Jobs,
-		"nodes":        c.Collectors.Nodes,
+		"jobs":         c.Collectors.Jobs.CollectorConfig,
+		"nodes":        c.Collectors.Nodes.CollectorConfig,
 		"partitions":   c.Collectors.Partitions,
 		"cluster":      c.Collectors.Cluster,
 		"users":        c.Collectors.Users,
@@ -278,7 +278,7 @@ func (c *Config) validateCrossField() ValidationErrors {
 	// Server timeout should be longer than collection timeout
 	maxCollectionTimeout := c.Collectors.Global.DefaultTimeout
 	for _, collector := range []CollectorConfig{
-		c.Collectors.Jobs, c.Collectors.Nodes, c.Collectors.Partitions,
+		c.Collectors.Jobs.CollectorConfig, c.Collectors.Nodes.CollectorConfig, c.Collectors.Partitions,
 		c.Collectors.Cluster, c.Collectors.Users, c.Collectors.QoS,
 		c.Collectors.Reservations, c.Collectors.Accounts, c.Collectors.Associations,
 		c.Collectors.Performance, c.Collectors.System,
@@ -440,8 +440,8 @@ func (c *Config) validateMetricFilters() ValidationErrors {
 	var errors ValidationErrors
 
 	collectors := map[string]CollectorConfig{
-		"jobs":         c.Collectors.Jobs,
-		"nodes":        c.Collectors.Nodes,
+		"jobs":         c.Collectors.Jobs.CollectorConfig,
+		"nodes":        c.Collectors.Nodes.CollectorConfig,
 		"partitions":   c.Collectors.Partitions,
 		"cluster":      c.Collectors.Cluster,
 		"users":        c.Collectors.Users,
@@ -118,7 +118,7 @@ func getValidTestConfig() *Config {
 			Global: GlobalCollectorConfig{
 				DefaultInterval: 15 * time.Second,
 			},
-			Jobs: CollectorConfig{
+			Jobs: JobsConfig{CollectorConfig: CollectorConfig{
 				Enabled: true,
 				Timeout: 15 * time.Second,
 				Filters: FilterConfig{
@@ -126,7 +126,7 @@ func getValidTestConfig() *Config {
 						EnableAll: true,
 					},
 				},
-			},
+			}},
 		},
 		Metrics: MetricsConfig{
 			Cardinality: CardinalityConfig{
@@ -209,3 +209,36 @@ func CreateReloadHandler(registry ReloadableRegistry, logger *logrus.Entry) Relo
 		return nil
 	}
 }
+
+// CreateClusterReloadHandler creates a reload handler for a multi-cluster
+// exporter. Each cluster registry is reconfigured with its own resolved
+// collector settings; adding or removing clusters requires a restart.
+func CreateClusterReloadHandler(registries map[string]ReloadableRegistry, logger *logrus.Entry) ReloadHandler {
+	return func(newConfig *Config) error {
+		logger.Info("Applying new configuration")
+
+		seen := make(map[string]bool, len(newConfig.Clusters))
+		for i := range newConfig.Clusters {
+			cluster := &newConfig.Clusters[i]
+			seen[cluster.Name] = true
+
+			registry, ok := registries[cluster.Name]
+			if !ok {
+				logger.WithField("cluster", cluster.Name).Warn("New cluster in configuration, restart the exporter to scrape it")
+				continue
+			}
+			if err := registry.ReconfigureCollectors(&cluster.Collectors); err != nil {
+				return fmt.Errorf("failed to reconfigure collectors of cluster %s: %w", cluster.Name, err)
+			}
+		}
+
+		for name := range registries {
+			if !seen[name] {
+				logger.WithField("cluster", name).Warn("Cluster removed from configuration, restart the exporter to stop scraping it")
+			}
+		}
+
+		logger.Info("Configuration reload completed")
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package filtering
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Gatherer wraps gatherer so that the series of the SLURM collectors pass
+// through the filter on every scrape. collectorOf names the collector
+// exporting a metric family; families it does not know, such as the
+// exporter's own and Go runtime metrics, are never filtered.
+func (sf *SmartFilter) Gatherer(gatherer prometheus.Gatherer, collectorOf func(metric string) string) prometheus.Gatherer {
+	if !sf.enabled {
+		return gatherer
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+
+		result := make([]*dto.MetricFamily, 0, len(families))
+		byCollector := make(map[string][]*dto.MetricFamily)
+		for _, family := range families {
+			collector := collectorOf(family.GetName())
+			if !sf.filters(collector) {
+				result = append(result, family)
+				continue
+			}
+			byCollector[collector] = append(byCollector[collector], family)
+		}
+		if len(byCollector) == 0 {
+			return families, err
+		}
+
+		for collector, collectorFamilies := range byCollector {
+			filtered, filterErr := sf.ProcessMetrics(context.Background(), collector, collectorFamilies)
+			if filterErr != nil {
+				sf.logger.WithError(filterErr).WithField("collector", collector).Warn("Smart filtering failed, keeping all metrics")
+				filtered = collectorFamilies
+			}
+			result = append(result, filtered...)
+		}
+
+		// Gatherers return families sorted by name
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].GetName() < result[j].GetName()
+		})
+		return result, err
+	})
+}
+
+// filters reports whether the series of a collector are filtered
+func (sf *SmartFilter) filters(collector string) bool {
+	if collector == "" {
+		return false
+	}
+	return len(sf.config.Collectors) == 0 || slices.Contains(sf.config.Collectors, collector)
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGathererTestFilter returns a filter past its learning phase and a
+// registry with a noisy and a constant node metric and an exporter metric
+func newGathererTestFilter(t *testing.T, cfg config.SmartFilteringConfig) (*SmartFilter, prometheus.Gatherer) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg.Enabled = true
+	cfg.NoiseThreshold = 0.8
+	cfg.CacheSize = 1000
+	cfg.LearningWindow = 50
+	cfg.VarianceLimit = 1.0
+	filter, err := NewSmartFilter(cfg, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = filter.Close() })
+	filter.learningPhase = false
+
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"slurm_node_noisy", "slurm_node_constant", "slurm_exporter_up"} {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: name})
+		gauge.Set(1)
+		registry.MustRegister(gauge)
+	}
+	filter.cacheDecision("slurm_node_noisy", ActionFilter)
+	filter.cacheDecision("slurm_node_constant", ActionReduce)
+	return filter, registry
+}
+
+func nodeCollector(metric string) string {
+	if metric == "slurm_exporter_up" {
+		return ""
+	}
+	return "nodes"
+}
+
+func familyNames(families []*dto.MetricFamily) []string {
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	return names
+}
+
+func TestSmartFilter_Gatherer(t *testing.T) {
+	t.Parallel()
+	filter, registry := newGathererTestFilter(t, config.SmartFilteringConfig{SampleEvery: 2})
+	gatherer := filter.Gatherer(registry, nodeCollector)
+
+	// Reduced series are kept every second scrape, exporter metrics always
+	var scrapes [][]string
+	for range 3 {
+		families, err := gatherer.Gather()
+		require.NoError(t, err)
+		scrapes = append(scrapes, familyNames(families))
+	}
+	assert.Equal(t, []string{"slurm_exporter_up", "slurm_node_constant"}, scrapes[0])
+	assert.Equal(t, []string{"slurm_exporter_up"}, scrapes[1])
+	assert.Equal(t, scrapes[0], scrapes[2])
+
+	stats := filter.GetStats()
+	assert.Equal(t, int64(6), stats["total_samples"])
+	assert.Equal(t, int64(4), stats["filtered_samples"])
+}
+
+func TestSmartFilter_GathererDryRun(t *testing.T) {
+	t.Parallel()
+	filter, registry := newGathererTestFilter(t, config.SmartFilteringConfig{DryRun: true})
+
+	families, err := filter.Gatherer(registry, nodeCollector).Gather()
+	require.NoError(t, err)
+	assert.Len(t, families, 3, "dry run must not drop series")
+	assert.Equal(t, int64(2), filter.GetStats()["filtered_samples"], "dry run must report what it would drop")
+}
+
+func TestSmartFilter_GathererCollectors(t *testing.T) {
+	t.Parallel()
+	filter, registry := newGathererTestFilter(t, config.SmartFilteringConfig{Collectors: []string{"jobs"}})
+
+	families, err := filter.Gatherer(registry, nodeCollector).Gather()
+	require.NoError(t, err)
+	assert.Len(t, families, 3, "only the series of listed collectors are filtered")
+}
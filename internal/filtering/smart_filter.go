@@ -57,6 +57,12 @@ func (a FilterAction) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, so that actions appear by
+// name in JSON
+func (a FilterAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
 // SmartFilter implements intelligent metric filtering with pattern learning
 type SmartFilter struct {
 	config  config.SmartFilteringConfig
@@ -79,6 +85,9 @@ type SmartFilter struct {
 	filterCache map[string]FilterAction
 	cacheExpiry time.Time
 
+	// Scrapes seen of series marked for reduction, for sampling
+	reduceCounts map[string]int
+
 	// Background processing
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -178,6 +187,7 @@ func NewSmartFilter(cfg config.SmartFilteringConfig, logger *logrus.Logger) (*Sm
 		patterns:       make(map[string]*MetricPattern),
 		patternHistory: make(map[string][]float64),
 		filterCache:    make(map[string]FilterAction),
+		reduceCounts:   make(map[string]int),
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -190,6 +200,7 @@ func NewSmartFilter(cfg config.SmartFilteringConfig, logger *logrus.Logger) (*Sm
 	filter.metrics.learningPhase.Set(1)
 
 	logger.WithFields(logrus.Fields{
+		"dry_run":         cfg.DryRun,
 		"noise_threshold": cfg.NoiseThreshold,
 		"cache_size":      cfg.CacheSize,
 		"learning_window": cfg.LearningWindow,
@@ -246,7 +257,8 @@ func (sf *SmartFilter) RegisterMetrics(registry prometheus.Registerer) error {
 	return nil
 }
 
-// ProcessMetrics processes a set of metrics through the smart filter
+// ProcessMetrics processes a set of metrics through the smart filter. In dry
+// run mode the decisions are learned and reported but all metrics are kept.
 func (sf *SmartFilter) ProcessMetrics(ctx context.Context, collector string, metrics []*dto.MetricFamily) ([]*dto.MetricFamily, error) {
 	if !sf.enabled {
 		return metrics, nil
@@ -284,8 +296,12 @@ func (sf *SmartFilter) ProcessMetrics(ctx context.Context, collector string, met
 		"filtered":      filteredCount,
 		"kept":          totalMetrics - filteredCount,
 		"filter_rate":   float64(filteredCount) / float64(totalMetrics) * 100,
+		"dry_run":       sf.config.DryRun,
 	}).Debug("Processed metrics through smart filter")
 
+	if sf.config.DryRun {
+		return metrics, nil
+	}
 	return filteredFamilies, nil
 }
 
@@ -331,7 +347,7 @@ func (sf *SmartFilter) shouldKeepMetric(ctx context.Context, collector, metricNa
 	// Check cache first
 	if action, found := sf.getCachedDecision(key); found {
 		sf.recordDecision(action, "cache_hit")
-		return action == ActionKeep
+		return sf.keep(key, action)
 	}
 
 	// Get or create pattern for this metric
@@ -341,17 +357,42 @@ func (sf *SmartFilter) shouldKeepMetric(ctx context.Context, collector, metricNa
 	value := sf.extractValue(metric)
 	sf.updatePattern(pattern, value)
 
-	// Make filtering decision
+	// Make filtering decision, kept on the pattern for inspection
+	sf.mu.Lock()
 	action := sf.makeFilterDecision(pattern, collector)
+	pattern.FilterRecommend = action
+	noiseScore := pattern.NoiseScore
+	sf.mu.Unlock()
 
 	// Cache the decision
 	sf.cacheDecision(key, action)
 
 	// Record metrics
-	sf.metrics.noiseScore.WithLabelValues(collector).Observe(pattern.NoiseScore)
+	sf.metrics.noiseScore.WithLabelValues(collector).Observe(noiseScore)
 	sf.recordDecision(action, "computed")
 
-	return action == ActionKeep
+	return sf.keep(key, action)
+}
+
+// keep applies a filter decision to one scrape of a series. Series marked
+// for reduction are kept once every SampleEvery scrapes, or never when
+// SampleEvery is 0.
+func (sf *SmartFilter) keep(key string, action FilterAction) bool {
+	switch action {
+	case ActionFilter:
+		return false
+	case ActionReduce:
+		if sf.config.SampleEvery <= 0 {
+			return false
+		}
+		sf.mu.Lock()
+		defer sf.mu.Unlock()
+		count := sf.reduceCounts[key]
+		sf.reduceCounts[key] = (count + 1) % sf.config.SampleEvery
+		return count == 0
+	default:
+		return true
+	}
 }
 
 // createMetricKey creates a unique key for a metric
@@ -693,6 +734,7 @@ func (sf *SmartFilter) cleanupOldPatterns(now time.Time) {
 	for key, pattern := range sf.patterns {
 		if now.Sub(pattern.LastUpdated) > maxAge {
 			delete(sf.patterns, key)
+			delete(sf.reduceCounts, key)
 		}
 	}
 }
@@ -710,6 +752,7 @@ func (sf *SmartFilter) GetStats() map[string]interface{} {
 
 	stats := map[string]interface{}{
 		"enabled":          true,
+		"dry_run":          sf.config.DryRun,
 		"learning_phase":   sf.learningPhase,
 		"total_patterns":   len(sf.patterns),
 		"cache_size":       len(sf.filterCache),
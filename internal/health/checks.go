@@ -380,3 +380,31 @@ func NewCircuitBreakerHealthCheck(getStatuses func() map[string]interface{}) Che
 		return check
 	}
 }
+
+// NewClusterHealthCheck creates a health check for one cluster of a
+// multi-cluster exporter. A cluster that is down degrades the exporter
+// rather than failing it, since the other clusters are still served.
+func NewClusterHealthCheck(name string, up func() bool, lastError func() error) CheckFunc {
+	return func(ctx context.Context) Check {
+		check := Check{
+			Status: StatusHealthy,
+			Metadata: map[string]string{
+				"cluster": name,
+			},
+		}
+
+		if err := lastError(); err != nil {
+			check.Metadata["last_error"] = err.Error()
+		}
+
+		if !up() {
+			check.Status = StatusDegraded
+			check.Message = fmt.Sprintf("Cluster %s did not complete its last scrape", name)
+			check.Error = check.Metadata["last_error"]
+			return check
+		}
+
+		check.Message = fmt.Sprintf("Cluster %s is responding normally", name)
+		return check
+	}
+}
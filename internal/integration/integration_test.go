@@ -35,14 +35,14 @@ func TestFullCollectorIntegration(t *testing.T) {
 
 	// Configure collectors
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
-		Nodes: config.CollectorConfig{
+		}},
+		Nodes: config.NodesConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
+		}},
 		Partitions: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
@@ -114,10 +114,10 @@ func TestHTTPMetricsEndpoint(t *testing.T) {
 
 	// Configure collectors
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
+		}},
 	}
 
 	// Create prometheus registry
@@ -163,7 +163,7 @@ func TestCollectorFiltering(t *testing.T) {
 
 	// Configure collectors with filtering
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
 			Filters: config.FilterConfig{
@@ -173,7 +173,7 @@ func TestCollectorFiltering(t *testing.T) {
 					ExcludeMetrics: []string{},
 				},
 			},
-		},
+		}},
 	}
 
 	// Create prometheus registry
@@ -210,10 +210,10 @@ func TestCollectorCustomLabels(t *testing.T) {
 
 	// Configure collectors with custom labels
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
+		}},
 	}
 
 	// Create prometheus registry
@@ -257,10 +257,10 @@ func TestCollectorTimeout(t *testing.T) {
 
 	// Configure collectors with short timeout
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 100 * time.Millisecond, // Very short timeout
-		},
+		}},
 	}
 
 	// Create prometheus registry
@@ -300,10 +300,10 @@ func TestPerformanceMonitoringIntegration(t *testing.T) {
 
 	// Configure collectors
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
+		}},
 	}
 
 	// Create Prometheus registry
@@ -353,10 +353,10 @@ func TestCardinalityLimiting(t *testing.T) {
 
 	// Configure collectors with cardinality limits
 	cfg := &config.CollectorsConfig{
-		Jobs: config.CollectorConfig{
+		Jobs: config.JobsConfig{CollectorConfig: config.CollectorConfig{
 			Enabled: true,
 			Timeout: 30 * time.Second,
-		},
+		}},
 	}
 
 	// Create Prometheus registry
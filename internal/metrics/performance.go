@@ -212,7 +212,7 @@ func NewPerformanceMetrics(namespace string) *PerformanceMetrics {
 				Namespace: namespace,
 				Subsystem: "exporter",
 				Name:      "circuit_breaker_state",
-				Help:      "Circuit breaker state (0=closed, 1=half-open, 2=open)",
+				Help:      "Circuit breaker state (0=closed, 1=open, 2=half-open)",
 			},
 			[]string{"name"},
 		),
@@ -121,13 +121,9 @@ func (c *IntelligentCache) Get(key string) (interface{}, bool) {
 		return nil, false
 	}
 
-	// Check if entry has expired
+	// Expired entries are kept until cleanup, so that the value set after
+	// this miss is compared with them and their change history goes on
 	if time.Now().After(entry.ExpiresAt) {
-		c.mu.Lock()
-		delete(c.entries, key)
-		c.totalSize -= int64(entry.Size)
-		c.mu.Unlock()
-
 		c.recordMiss()
 		c.logger.WithField("key", key).Debug("Cache entry expired")
 		return nil, false
@@ -149,7 +145,6 @@ func (c *IntelligentCache) Set(key string, value interface{}) {
 	defer c.mu.Unlock()
 
 	now := time.Now()
-	ttl := c.calculateTTL(key, value)
 
 	// Calculate estimated size
 	size := c.estimateSize(value)
@@ -163,7 +158,8 @@ func (c *IntelligentCache) Set(key string, value interface{}) {
 	var changeHistory []ChangeRecord
 	stabilityScore := 0.5 // Default stability
 
-	if existing, exists := c.entries[key]; exists && c.config.ChangeTracking {
+	existing, exists := c.entries[key]
+	if exists && c.config.ChangeTracking {
 		changeHistory = existing.ChangeHistory
 
 		// Record change
@@ -188,10 +184,12 @@ func (c *IntelligentCache) Set(key string, value interface{}) {
 
 		// Update stability score
 		stabilityScore = c.calculateStabilityScore(changeHistory)
-
+	}
+	if exists {
 		// Remove old entry size from total
 		c.totalSize -= int64(existing.Size)
 	}
+	ttl := c.calculateTTL(existing, changeHistory)
 
 	// Create new entry
 	entry := &IntelligentCacheEntry{
@@ -223,9 +221,11 @@ func (c *IntelligentCache) Set(key string, value interface{}) {
 	}).Debug("Cache entry stored")
 }
 
-// calculateTTL determines the optimal TTL for a cache entry using adaptive logic
-func (c *IntelligentCache) calculateTTL(key string, value interface{}) time.Duration {
-	_ = value
+// calculateTTL determines the optimal TTL for a cache entry using adaptive
+// logic. The TTL of the entry being replaced is extended while its value
+// stays the same and reduced while it keeps changing, so it settles on how
+// often the value actually changes.
+func (c *IntelligentCache) calculateTTL(existing *IntelligentCacheEntry, changeHistory []ChangeRecord) time.Duration {
 	// Start with base TTL
 	ttl := c.config.BaseTTL
 
@@ -235,8 +235,9 @@ func (c *IntelligentCache) calculateTTL(key string, value interface{}) time.Dura
 	}
 
 	// Check if we have an existing entry with change history
-	if existing, exists := c.entries[key]; exists && len(existing.ChangeHistory) > 0 {
-		stabilityScore := c.calculateStabilityScore(existing.ChangeHistory)
+	if existing != nil && len(changeHistory) > 0 {
+		ttl = existing.TTL
+		stabilityScore := c.calculateStabilityScore(changeHistory)
 
 		// Calculate adaptive TTL based on stability
 		if stabilityScore < c.config.AdaptiveTTL.ChangeThreshold {
@@ -276,11 +277,17 @@ func (c *IntelligentCache) calculateStabilityScore(history []ChangeRecord) float
 	// Stability is inverse of change rate
 	stability := 1.0 - avgChange
 
-	// Consider change frequency
+	// Consider change frequency; records of unchanged values are not changes
 	if len(history) > 1 {
 		timespan := history[len(history)-1].Timestamp.Sub(history[0].Timestamp)
+		changes := 0
+		for _, record := range history {
+			if record.ChangeScore > 0 {
+				changes++
+			}
+		}
 		if timespan > 0 {
-			frequency := float64(len(history)) / timespan.Hours()
+			frequency := float64(changes) / timespan.Hours()
 			// Higher frequency reduces stability
 			stability *= math.Max(0.1, 1.0-frequency*0.1)
 		}
@@ -379,7 +386,8 @@ func (c *IntelligentCache) startBackgroundCleanup() {
 	}()
 }
 
-// cleanup removes expired entries and updates metrics
+// cleanup removes expired entries and updates metrics. Entries are removed
+// once their change history would have aged out of the stability window.
 func (c *IntelligentCache) cleanup() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -388,7 +396,7 @@ func (c *IntelligentCache) cleanup() {
 	expired := 0
 
 	for key, entry := range c.entries {
-		if now.After(entry.ExpiresAt) {
+		if now.After(entry.ExpiresAt.Add(c.config.AdaptiveTTL.StabilityWindow)) {
 			c.totalSize -= int64(entry.Size)
 			delete(c.entries, key)
 			expired++
@@ -538,6 +546,18 @@ func (c *IntelligentCache) GetStats() map[string]interface{} {
 	}
 }
 
+// TTL returns the adaptive TTL of a cached entry
+func (c *IntelligentCache) TTL(key string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return 0, false
+	}
+	return entry.TTL, true
+}
+
 // GetTopEntries returns the most frequently accessed cache entries
 func (c *IntelligentCache) GetTopEntries(limit int) []IntelligentCacheEntry {
 	c.mu.RLock()
@@ -148,6 +148,66 @@ func TestIntelligentCache_AdaptiveTTL(t *testing.T) {
 	assert.Greater(t, len(entry.ChangeHistory), 0)
 }
 
+func TestIntelligentCache_TTLFollowsChanges(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg := config.CachingConfig{
+		Intelligent:     true,
+		BaseTTL:         1 * time.Minute,
+		MaxEntries:      100,
+		CleanupInterval: 5 * time.Minute,
+		ChangeTracking:  true,
+		AdaptiveTTL: config.AdaptiveTTLConfig{
+			Enabled:           true,
+			MinTTL:            30 * time.Second,
+			MaxTTL:            4 * time.Minute,
+			StabilityWindow:   10 * time.Minute,
+			VarianceThreshold: 0.1,
+			ChangeThreshold:   0.05,
+			ExtensionFactor:   2.0,
+			ReductionFactor:   0.5,
+		},
+	}
+
+	cache := NewIntelligentCache(cfg, logger)
+	defer cache.Close()
+
+	ttl := func() time.Duration {
+		ttl, ok := cache.TTL("qos")
+		require.True(t, ok)
+		return ttl
+	}
+
+	// Unchanged values extend the TTL up to max_ttl
+	cache.Set("qos", "normal")
+	assert.Equal(t, time.Minute, ttl())
+	cache.Set("qos", "normal")
+	assert.Equal(t, 2*time.Minute, ttl())
+	cache.Set("qos", "normal")
+	cache.Set("qos", "normal")
+	assert.Equal(t, 4*time.Minute, ttl())
+
+	// A change stops the extension, values that keep changing reduce it
+	// down to min_ttl
+	cache.Set("qos", "high")
+	assert.Equal(t, 4*time.Minute, ttl())
+	for i := range 10 {
+		cache.Set("qos", fmt.Sprintf("value_%d", i))
+	}
+	assert.Equal(t, 30*time.Second, ttl())
+
+	// Expired entries are misses but keep their history for the next value
+	cache.mu.Lock()
+	cache.entries["qos"].ExpiresAt = time.Now().Add(-time.Second)
+	cache.mu.Unlock()
+	_, found := cache.Get("qos")
+	assert.False(t, found)
+	cache.Set("qos", "normal")
+	assert.Greater(t, len(cache.entries["qos"].ChangeHistory), 1)
+}
+
 func TestIntelligentCache_StabilityScore(t *testing.T) {
 	t.Parallel()
 	logger := logrus.New()
@@ -404,3 +404,17 @@ func (cbm *CircuitBreakerManager) HealthCheck() error {
 
 	return nil
 }
+
+// StatusMaps returns the state of every circuit breaker in the form taken by
+// health.NewCircuitBreakerHealthCheck
+func (cbm *CircuitBreakerManager) StatusMaps() map[string]interface{} {
+	statuses := cbm.GetStatuses()
+	result := make(map[string]interface{}, len(statuses))
+	for name, status := range statuses {
+		result[name] = map[string]interface{}{
+			"state":    status.State.String(),
+			"failures": status.Failures,
+		}
+	}
+	return result
+}
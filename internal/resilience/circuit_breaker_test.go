@@ -397,6 +397,11 @@ func TestCircuitBreakerManager_HealthCheck(t *testing.T) {
 
 	// cb2 should still be healthy
 	assert.Equal(t, StateClosed, cb2.GetState())
+
+	// Status maps feed the health check endpoint
+	statuses := manager.StatusMaps()
+	assert.Equal(t, map[string]interface{}{"state": "open", "failures": 1}, statuses["cb1"])
+	assert.Equal(t, map[string]interface{}{"state": "closed", "failures": 0}, statuses["cb2"])
 }
 
 func TestState_String(t *testing.T) {
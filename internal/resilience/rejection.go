@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// rejectionKey is the context key of the rejection tracker
+type rejectionKey struct{}
+
+// rejectionTracker records whether a circuit breaker rejected a call. It
+// also marks the tracker of the enclosing context, if any.
+type rejectionTracker struct {
+	rejected atomic.Bool
+	parent   *rejectionTracker
+}
+
+// TrackRejections returns a context that records whether a circuit breaker
+// rejected a call made with it, and a function reporting whether one did.
+// Errors of rejected calls do not always survive the layers between the
+// breaker and the caller, so this is how callers tell them apart.
+func TrackRejections(ctx context.Context) (context.Context, func() bool) {
+	parent, _ := ctx.Value(rejectionKey{}).(*rejectionTracker)
+	tracker := &rejectionTracker{parent: parent}
+	return context.WithValue(ctx, rejectionKey{}, tracker), tracker.rejected.Load
+}
+
+// MarkRejected records on ctx that a circuit breaker rejected a call
+func MarkRejected(ctx context.Context) {
+	tracker, _ := ctx.Value(rejectionKey{}).(*rejectionTracker)
+	for ; tracker != nil; tracker = tracker.parent {
+		tracker.rejected.Store(true)
+	}
+}
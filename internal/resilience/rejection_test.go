@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package resilience
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackRejections(t *testing.T) {
+	t.Parallel()
+	// Marking a context without a tracker is a no-op
+	MarkRejected(context.Background())
+
+	outer, outerRejected := TrackRejections(context.Background())
+	inner, innerRejected := TrackRejections(outer)
+	_, siblingRejected := TrackRejections(outer)
+	assert.False(t, outerRejected())
+
+	MarkRejected(inner)
+	assert.True(t, innerRejected())
+	assert.True(t, outerRejected(), "a rejection must reach the enclosing tracker")
+	assert.False(t, siblingRejected())
+}
@@ -0,0 +1,335 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/slurm"
+	"github.com/jontk/slurm-exporter/internal/tracing"
+)
+
+// probeTimeoutOffset is subtracted from the timeout Prometheus sends so the
+// response arrives before Prometheus gives up
+const probeTimeoutOffset = 500 * time.Millisecond
+
+// probeTarget is the cached client and collectors of one probed slurmrestd.
+// Collectors are kept between probes so their counters keep counting; each
+// probe still serves them from their own registry, separate from /metrics.
+type probeTarget struct {
+	// ready is closed once the target has been built or failed to build
+	ready chan struct{}
+	err   error
+
+	client   *slurm.Client
+	gatherer contextGatherer
+
+	lastUsed time.Time
+}
+
+// contextGatherer gathers the metrics of a target with its collectors
+// running on ctx and reports the collectors that failed in that gather
+type contextGatherer interface {
+	GatherContext(ctx context.Context) ([]*dto.MetricFamily, []string, error)
+}
+
+// close releases the client of the target
+func (t *probeTarget) close() {
+	if t.client != nil {
+		_ = t.client.Close()
+	}
+}
+
+// prober serves the probe endpoint and caches a target per module and URL
+type prober struct {
+	config  *config.ProbeConfig
+	allowed []*regexp.Regexp
+	server  *Server
+	logger  *logrus.Entry
+
+	// build creates a target, replaced in tests
+	build func(target string, module *config.ProbeModule, tracer *tracing.CollectionTracer) (*probeTarget, error)
+
+	mu      sync.Mutex
+	targets map[string]*probeTarget
+}
+
+// newProber creates the probe handler state
+func newProber(cfg *config.ProbeConfig, s *Server) (*prober, error) {
+	allowed, err := cfg.AllowedTargetPatterns()
+	if err != nil {
+		return nil, err
+	}
+	return &prober{
+		config:  cfg,
+		allowed: allowed,
+		server:  s,
+		logger:  s.logger.WithField("component", "probe"),
+		build:   buildProbeTarget,
+		targets: make(map[string]*probeTarget),
+	}, nil
+}
+
+// buildProbeTarget creates the SLURM client and collectors for a target
+func buildProbeTarget(target string, module *config.ProbeModule, tracer *tracing.CollectionTracer) (*probeTarget, error) {
+	slurmCfg := module.SLURM
+	slurmCfg.BaseURL = target
+
+	collectors := module.Collectors
+	// Targets come and go, so there is no state file to resume from
+	collectors.Accounting.StateFile = ""
+	// Node events come from snapshots diffed across polls, which a target
+	// that is only probed now and then never has
+	collectors.NodeEvents.Enabled = false
+
+	slurmWrapper, err := slurm.NewClient(&slurmCfg, slurm.WithTracer(tracer))
+	if err != nil {
+		// Don't wrap the error as it may contain sensitive config information
+		return nil, errors.New("failed to create SLURM client (check module configuration for details)")
+	}
+	slurmClient := slurmWrapper.GetSlurmClient()
+
+	promRegistry := prometheus.NewRegistry()
+	promRegistry.MustRegister(slurmWrapper.FetchMetrics())
+	registry, err := collector.NewRegistry(&collectors, promRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collector registry: %w", err)
+	}
+	registry.SetTracer(tracer)
+
+	registry.SetAnalysisClients(slurm.NewAnalysisClients(slurmClient, &slurmCfg, &collectors, slurm.WithTracer(tracer)))
+
+	if err := registry.CreateCollectorsFromConfig(&collectors, slurmClient); err != nil {
+		return nil, fmt.Errorf("failed to create collectors: %w", err)
+	}
+
+	return &probeTarget{
+		client:   slurmWrapper,
+		gatherer: registry,
+	}, nil
+}
+
+// ServeHTTP handles /probe?target=<url>&module=<name>
+func (p *prober) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	moduleName := params.Get("module")
+	if moduleName == "" {
+		moduleName = config.DefaultProbeModule
+	}
+	module, ok := p.config.Modules[moduleName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+		return
+	}
+	if !p.targetAllowed(target) {
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		return
+	}
+
+	logger := p.logger.WithFields(logrus.Fields{"target": target, "module": moduleName})
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout(r, module.ScrapeTimeout))
+	defer cancel()
+
+	if tracer := p.server.tracer; tracer != nil {
+		var finish func()
+		ctx, finish = tracer.TraceScrape(ctx)
+		defer finish()
+	}
+
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Whether the probe of the target succeeded",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "How long the probe took to complete in seconds",
+	})
+	probeRegistry := prometheus.NewRegistry()
+	probeRegistry.MustRegister(probeSuccess, probeDuration)
+
+	start := time.Now()
+	families, err := p.probe(ctx, target, moduleName, &module)
+	probeDuration.Set(time.Since(start).Seconds())
+	if err != nil {
+		logger.WithError(err).Warn("Probe failed")
+	} else {
+		probeSuccess.Set(1)
+	}
+
+	gatherers := prometheus.Gatherers{
+		probeRegistry,
+		prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil }),
+	}
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorLog:                            p.logger,
+		ErrorHandling:                       promhttp.ContinueOnError,
+		EnableOpenMetrics:                   true,
+		EnableOpenMetricsTextCreatedSamples: true,
+	}).ServeHTTP(w, r)
+}
+
+// probe gathers the collectors of a target on ctx, giving up when ctx ends.
+// A collector that ignores ctx is not waited for; the target's next probe
+// waits for its gather to finish instead of starting another.
+func (p *prober) probe(ctx context.Context, target, moduleName string, module *config.ProbeModule) ([]*dto.MetricFamily, error) {
+	t, err := p.getTarget(ctx, target, moduleName, module)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		families []*dto.MetricFamily
+		failed   []string
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		families, failed, err := t.gatherer.GatherContext(ctx)
+		done <- result{families: families, failed: failed, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return res.families, res.err
+		}
+		if len(res.failed) > 0 {
+			return res.families, fmt.Errorf("collectors failed: %v", res.failed)
+		}
+		return res.families, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("probe timed out: %w", ctx.Err())
+	}
+}
+
+// getTarget returns the cached target or builds it. Concurrent probes of a
+// new target wait for a single build; failed builds are not cached.
+func (p *prober) getTarget(ctx context.Context, target, moduleName string, module *config.ProbeModule) (*probeTarget, error) {
+	key := moduleName + "\x00" + target
+	now := time.Now()
+
+	p.mu.Lock()
+	p.expireLocked(now)
+	t, ok := p.targets[key]
+	if !ok {
+		p.makeRoomLocked()
+		t = &probeTarget{ready: make(chan struct{})}
+		p.targets[key] = t
+		go p.buildTarget(key, t, target, module)
+	}
+	t.lastUsed = now
+	p.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return t, t.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for client: %w", ctx.Err())
+	}
+}
+
+// buildTarget builds a target and drops it again if that fails
+func (p *prober) buildTarget(key string, t *probeTarget, target string, module *config.ProbeModule) {
+	built, err := p.build(target, module, p.server.tracer)
+	if err != nil {
+		t.err = err
+		p.mu.Lock()
+		if p.targets[key] == t {
+			delete(p.targets, key)
+		}
+		p.mu.Unlock()
+	} else {
+		t.client, t.gatherer = built.client, built.gatherer
+	}
+	close(t.ready)
+}
+
+// expireLocked drops targets that have not been probed within the client TTL
+func (p *prober) expireLocked(now time.Time) {
+	for key, t := range p.targets {
+		if now.Sub(t.lastUsed) > p.config.ClientTTL {
+			p.dropLocked(key, t)
+		}
+	}
+}
+
+// makeRoomLocked drops the least recently probed targets until a new one fits
+func (p *prober) makeRoomLocked() {
+	for len(p.targets) >= p.config.MaxTargets {
+		var oldestKey string
+		var oldest *probeTarget
+		for key, t := range p.targets {
+			if oldest == nil || t.lastUsed.Before(oldest.lastUsed) {
+				oldestKey, oldest = key, t
+			}
+		}
+		p.dropLocked(oldestKey, oldest)
+	}
+}
+
+// dropLocked removes a target and closes it once it is built
+func (p *prober) dropLocked(key string, t *probeTarget) {
+	delete(p.targets, key)
+	go func() {
+		<-t.ready
+		t.close()
+	}()
+}
+
+// targetAllowed reports whether a target matches the allowed expressions.
+// No target is allowed without any.
+func (p *prober) targetAllowed(target string) bool {
+	for _, pattern := range p.allowed {
+		if pattern.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTimeout returns the module timeout, shortened to the scrape timeout
+// Prometheus announces in its request headers
+func probeTimeout(r *http.Request, moduleTimeout time.Duration) time.Duration {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return moduleTimeout
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return moduleTimeout
+	}
+	timeout := time.Duration(seconds*float64(time.Second)) - probeTimeoutOffset
+	if timeout <= 0 || timeout > moduleTimeout {
+		return moduleTimeout
+	}
+	return timeout
+}
+
+// Close drops all cached targets
+func (p *prober) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, t := range p.targets {
+		p.dropLocked(key, t)
+	}
+}
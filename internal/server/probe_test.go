@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/tracing"
+)
+
+// gatherFunc serves a function as the collectors of a target
+type gatherFunc func(ctx context.Context) ([]*dto.MetricFamily, []string, error)
+
+func (f gatherFunc) GatherContext(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+	return f(ctx)
+}
+
+// fakeProbeBuilder builds targets that export their URL, counting builds
+type fakeProbeBuilder struct {
+	mu         sync.Mutex
+	builds     map[string]int
+	fail       map[string]bool
+	slow       map[string]bool
+	collectors map[string][]string
+}
+
+func (f *fakeProbeBuilder) build(target string, module *config.ProbeModule, _ *tracing.CollectionTracer) (*probeTarget, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.builds[target]++
+	if f.fail[target] {
+		return nil, errors.New("connection refused")
+	}
+	if f.slow[target] {
+		// Ignores ctx, as a collector stuck in a call without one would
+		return &probeTarget{gatherer: gatherFunc(func(context.Context) ([]*dto.MetricFamily, []string, error) {
+			time.Sleep(time.Second)
+			return nil, nil, nil
+		})}, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	info := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "slurm_probe_test_info", Help: "Test"}, []string{"target"})
+	info.WithLabelValues(target).Set(1)
+	reg.MustRegister(info)
+	failed := f.collectors[target]
+	return &probeTarget{gatherer: gatherFunc(func(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, nil, errors.New("gathered without the probe deadline")
+		}
+		families, err := reg.Gather()
+		return families, failed, err
+	})}, nil
+}
+
+func (f *fakeProbeBuilder) count(target string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.builds[target]
+}
+
+func newProbeTestServer(t *testing.T, maxTargets int) (*Server, *fakeProbeBuilder) {
+	t.Helper()
+	cfg := createTestConfig()
+	cfg.Probe = config.ProbeConfig{
+		Enabled:        true,
+		Path:           "/probe",
+		AllowedTargets: []string{`https://slurm-[a-z]+\.example\.com:6820`},
+		MaxTargets:     maxTargets,
+		ClientTTL:      time.Minute,
+		Modules: map[string]config.ProbeModule{
+			config.DefaultProbeModule: {ScrapeTimeout: 5 * time.Second},
+			"quick":                   {ScrapeTimeout: 100 * time.Millisecond},
+		},
+	}
+
+	s, err := New(cfg, createTestLogger(), &mockRegistry{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	builder := &fakeProbeBuilder{builds: map[string]int{}, fail: map[string]bool{}, slow: map[string]bool{}, collectors: map[string][]string{}}
+	s.prober.build = builder.build
+	return s, builder
+}
+
+func probe(t *testing.T, s *Server, target, module string) (int, string) {
+	t.Helper()
+	query := url.Values{}
+	if target != "" {
+		query.Set("target", target)
+	}
+	if module != "" {
+		query.Set("module", module)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/probe?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Body)
+	return w.Code, string(body)
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	t.Parallel()
+	s, builder := newProbeTestServer(t, 10)
+	targetA := "https://slurm-a.example.com:6820"
+
+	t.Run("Rejected", func(t *testing.T) {
+		if code, _ := probe(t, s, "", ""); code != http.StatusBadRequest {
+			t.Errorf("missing target: status = %d, want %d", code, http.StatusBadRequest)
+		}
+		if code, _ := probe(t, s, targetA, "unknown"); code != http.StatusBadRequest {
+			t.Errorf("unknown module: status = %d, want %d", code, http.StatusBadRequest)
+		}
+		if code, _ := probe(t, s, "http://169.254.169.254/", ""); code != http.StatusForbidden {
+			t.Errorf("disallowed target: status = %d, want %d", code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("CachesTarget", func(t *testing.T) {
+		for range 3 {
+			code, body := probe(t, s, targetA, "")
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+			if !strings.Contains(body, "probe_success 1") {
+				t.Errorf("body missing probe_success 1:\n%s", body)
+			}
+			if !strings.Contains(body, `slurm_probe_test_info{target="`+targetA+`"} 1`) {
+				t.Errorf("body missing target metrics:\n%s", body)
+			}
+		}
+		if got := builder.count(targetA); got != 1 {
+			t.Errorf("builds = %d, want 1", got)
+		}
+
+		// Another module gets its own client
+		if _, body := probe(t, s, targetA, "quick"); !strings.Contains(body, "probe_success 1") {
+			t.Errorf("quick module probe failed:\n%s", body)
+		}
+		if got := builder.count(targetA); got != 2 {
+			t.Errorf("builds = %d, want 2", got)
+		}
+	})
+
+	t.Run("FailedBuildIsRetried", func(t *testing.T) {
+		target := "https://slurm-down.example.com:6820"
+		builder.mu.Lock()
+		builder.fail[target] = true
+		builder.mu.Unlock()
+
+		for range 2 {
+			code, body := probe(t, s, target, "")
+			if code != http.StatusOK || !strings.Contains(body, "probe_success 0") {
+				t.Errorf("status = %d, body:\n%s", code, body)
+			}
+		}
+		if got := builder.count(target); got != 2 {
+			t.Errorf("builds = %d, want 2", got)
+		}
+	})
+
+	t.Run("FailedCollectors", func(t *testing.T) {
+		target := "https://slurm-partial.example.com:6820"
+		builder.mu.Lock()
+		builder.collectors[target] = []string{"jobs"}
+		builder.mu.Unlock()
+
+		_, body := probe(t, s, target, "")
+		if !strings.Contains(body, "probe_success 0") {
+			t.Errorf("body missing probe_success 0:\n%s", body)
+		}
+		if !strings.Contains(body, `slurm_probe_test_info{target="`+target+`"} 1`) {
+			t.Errorf("body missing the metrics that were collected:\n%s", body)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		target := "https://slurm-slow.example.com:6820"
+		builder.mu.Lock()
+		builder.slow[target] = true
+		builder.mu.Unlock()
+
+		start := time.Now()
+		_, body := probe(t, s, target, "quick")
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("probe took %v, want it bounded by the module timeout", elapsed)
+		}
+		if !strings.Contains(body, "probe_success 0") {
+			t.Errorf("body missing probe_success 0:\n%s", body)
+		}
+	})
+}
+
+func TestProbeEndpoint_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+	s, builder := newProbeTestServer(t, 2)
+	targets := []string{
+		"https://slurm-a.example.com:6820",
+		"https://slurm-b.example.com:6820",
+		"https://slurm-c.example.com:6820",
+	}
+
+	probe(t, s, targets[0], "")
+	probe(t, s, targets[1], "")
+	probe(t, s, targets[0], "")
+	// The cache is full, so c replaces b, the least recently probed
+	probe(t, s, targets[2], "")
+	probe(t, s, targets[0], "")
+	probe(t, s, targets[1], "")
+
+	want := map[string]int{targets[0]: 1, targets[1]: 2, targets[2]: 1}
+	for target, builds := range want {
+		if got := builder.count(target); got != builds {
+			t.Errorf("builds of %s = %d, want %d", target, got, builds)
+		}
+	}
+}
+
+func TestProbeTargetAllowed_DeniesWithoutPatterns(t *testing.T) {
+	t.Parallel()
+	p := &prober{}
+	if p.targetAllowed("https://slurm-a.example.com:6820") {
+		t.Error("target allowed without any allowed_targets")
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 10 * time.Second},
+		{"5", 4500 * time.Millisecond},
+		{"30", 10 * time.Second},
+		{"0.2", 10 * time.Second},
+		{"invalid", 10 * time.Second},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", tt.header)
+		}
+		if got := probeTimeout(req, 10*time.Second); got != tt.want {
+			t.Errorf("probeTimeout(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -9,16 +9,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 
 	"github.com/jontk/slurm-exporter/internal/collector"
 	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/filtering"
 	"github.com/jontk/slurm-exporter/internal/health"
+	"github.com/jontk/slurm-exporter/internal/tracing"
 )
 
 // startTime tracks when the server package was loaded
@@ -121,6 +127,12 @@ type Server struct {
 	promRegistry   *prometheus.Registry
 	httpMetrics    *HTTPMetrics
 	healthChecker  *health.HealthChecker
+	tracer         *tracing.CollectionTracer
+	gatherers      prometheus.Gatherers
+	collectors     contextGatherer
+	prober         *prober
+	smartFilter    *filtering.SmartFilter
+	collectorOf    func(metric string) string
 	isShuttingDown bool
 }
 
@@ -149,6 +161,15 @@ func New(cfg *config.Config, logger *logrus.Logger, registry RegistryInterface,
 		healthChecker: healthChecker,
 	}
 
+	// Setup the probe endpoint before its route is added
+	if cfg.Probe.Enabled {
+		prober, err := newProber(&cfg.Probe, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create probe handler: %w", err)
+		}
+		s.prober = prober
+	}
+
 	// Setup health checks
 	s.setupHealthChecks()
 
@@ -330,6 +351,11 @@ func (s *Server) setupRoutes() http.Handler {
 	// Metrics endpoint
 	mux.Handle(s.config.Server.MetricsPath, s.createMetricsHandler())
 
+	// Multi-target probe endpoint
+	if s.prober != nil {
+		mux.Handle(s.config.Probe.Path, s.prober)
+	}
+
 	// Root endpoint with basic info
 	mux.HandleFunc("/", s.handleRoot)
 
@@ -337,6 +363,7 @@ func (s *Server) setupRoutes() http.Handler {
 	mux.HandleFunc("/debug/health", s.handleDebugHealth)
 	mux.HandleFunc("/debug/collectors", s.handleDebugCollectors)
 	mux.HandleFunc("/debug/performance", s.handleDebugPerformance)
+	mux.HandleFunc("/debug/patterns", s.handleDebugPatterns)
 
 	// Apply middleware to all routes
 	return s.CombinedMiddleware(mux)
@@ -383,7 +410,11 @@ func (s *Server) Start(ctx context.Context) error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
 	s.isShuttingDown = true
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	if s.prober != nil {
+		s.prober.Close()
+	}
+	return err
 }
 
 // IsShuttingDown returns whether the server is in shutdown mode
@@ -562,22 +593,19 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(content))
 }
 
+// runtimeGatherer gathers the Go runtime and process metrics. They describe
+// the whole process, so one registry is shared by every server.
+var runtimeGatherer = sync.OnceValue(func() prometheus.Gatherer {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+})
+
 // createMetricsHandler creates the Prometheus metrics handler
 func (s *Server) createMetricsHandler() http.Handler {
-	// Create a custom gatherer that collects from our registry
-	gatherer := prometheus.Gatherers{
-		s.promRegistry,
-		prometheus.DefaultGatherer, // Include Go runtime metrics
-	}
-
-	// Create promhttp handler with custom configuration
-	handler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
-		ErrorLog:      s.logger,
-		ErrorHandling: promhttp.ContinueOnError,
-		Timeout:       30 * time.Second,
-	})
-
-	// Wrap with collection triggering
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if request context is already cancelled
 		select {
@@ -588,13 +616,92 @@ func (s *Server) createMetricsHandler() http.Handler {
 		default:
 		}
 
-		// Prometheus will trigger collection via registered collector adapters
+		// The collectors run on the context of this scrape, so their spans
+		// are children of its span and they end with its request
+		ctx := r.Context()
+		if s.tracer != nil {
+			var finish func()
+			ctx, finish = s.tracer.TraceScrape(ctx)
+			defer finish()
+			r = r.WithContext(ctx)
+		}
 
-		// Serve the metrics
-		handler.ServeHTTP(w, r)
+		// Gather our registry and any additional gatherers, such as the
+		// per-cluster registries
+		gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			gatherers := make(prometheus.Gatherers, 0, len(s.gatherers)+2)
+			for _, g := range s.gatherers {
+				if cg, ok := g.(contextGatherer); ok {
+					g = gatherOn(ctx, cg)
+				}
+				gatherers = append(gatherers, g)
+			}
+			if s.collectors != nil {
+				gatherers = append(gatherers, gatherOn(ctx, s.collectors))
+			} else {
+				gatherers = append(gatherers, s.promRegistry)
+			}
+			gatherers = append(gatherers, runtimeGatherer())
+			if s.smartFilter != nil {
+				return s.smartFilter.Gatherer(gatherers, s.collectorOf).Gather()
+			}
+			return gatherers.Gather()
+		})
+
+		// Serve the metrics. OpenMetrics is negotiated with the scraper; it
+		// carries the created timestamps of counters and the exemplars of
+		// histograms.
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+			ErrorLog:                            s.logger,
+			ErrorHandling:                       promhttp.ContinueOnError,
+			Timeout:                             30 * time.Second,
+			EnableOpenMetrics:                   true,
+			EnableOpenMetricsTextCreatedSamples: true,
+		}).ServeHTTP(w, r)
+	})
+}
+
+// gatherOn gathers g with its collectors running on ctx
+func gatherOn(ctx context.Context, g contextGatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, _, err := g.GatherContext(ctx)
+		return families, err
 	})
 }
 
+// SetTracer sets the tracer used to create a span for each scrape
+func (s *Server) SetTracer(tracer *tracing.CollectionTracer) {
+	s.tracer = tracer
+}
+
+// AddGatherer adds a gatherer served on the metrics endpoint next to the
+// Prometheus registry. A gatherer with a GatherContext method runs its
+// collectors on the context of each scrape. It must be called before Start.
+func (s *Server) AddGatherer(gatherer prometheus.Gatherer) {
+	s.gatherers = append(s.gatherers, gatherer)
+}
+
+// SetCollectorRegistry gathers the Prometheus registry through registry,
+// which runs its collectors on the context of each scrape. registry must
+// have been created with the Prometheus registry passed to New. It must be
+// called before Start.
+func (s *Server) SetCollectorRegistry(registry *collector.Registry) {
+	s.collectors = registry
+}
+
+// SetSmartFilter passes the collector metrics served on the metrics endpoint
+// through filter. collectorOf names the collector exporting a metric family.
+// It must be called before Start.
+func (s *Server) SetSmartFilter(filter *filtering.SmartFilter, collectorOf func(metric string) string) {
+	s.smartFilter = filter
+	s.collectorOf = collectorOf
+}
+
+// RegisterHealthCheck adds a check to the health endpoints
+func (s *Server) RegisterHealthCheck(name string, check health.CheckFunc) {
+	s.healthChecker.RegisterCheck(name, check)
+}
+
 // GetPrometheusRegistry returns the Prometheus registry
 func (s *Server) GetPrometheusRegistry() *prometheus.Registry {
 	return s.promRegistry
@@ -651,6 +758,42 @@ func (s *Server) handleDebugHealth(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleDebugPatterns shows the series patterns learned by the smart filter
+// and its decision for each. The action query parameter (keep, filter,
+// reduce or unknown) limits the patterns shown.
+func (s *Server) handleDebugPatterns(w http.ResponseWriter, r *http.Request) {
+	logger := s.logger.WithField("component", "debug_patterns_handler")
+	logger.Debug("Debug patterns requested")
+
+	if s.smartFilter == nil || !s.smartFilter.IsEnabled() {
+		http.Error(w, "Smart filtering not enabled", http.StatusNotFound)
+		return
+	}
+
+	action := r.URL.Query().Get("action")
+	patterns := make([]filtering.MetricPattern, 0)
+	for _, pattern := range s.smartFilter.GetPatterns() {
+		if action == "" || pattern.FilterRecommend.String() == action {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].NoiseScore > patterns[j].NoiseScore
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":     s.smartFilter.GetStats(),
+		"patterns":  patterns,
+		"timestamp": time.Now(),
+	}); err != nil {
+		logger.WithError(err).Error("Failed to encode debug patterns response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 // handleDebugCollectors provides detailed collector information for debugging
 func (s *Server) handleDebugCollectors(w http.ResponseWriter, r *http.Request) {
 	logger := s.logger.WithField("component", "debug_collectors_handler")
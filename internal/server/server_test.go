@@ -10,14 +10,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 
 	"github.com/jontk/slurm-exporter/internal/collector"
 	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/filtering"
 )
 
 // mockRegistry implements RegistryInterface for testing
@@ -45,6 +48,18 @@ func (m *mockRegistry) CollectAll(ctx context.Context) error {
 	return nil
 }
 
+// contextGathererFunc serves a function as a gatherer that takes a context
+type contextGathererFunc func(ctx context.Context) ([]*dto.MetricFamily, []string, error)
+
+func (f contextGathererFunc) Gather() ([]*dto.MetricFamily, error) {
+	families, _, err := f(context.Background())
+	return families, err
+}
+
+func (f contextGathererFunc) GatherContext(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+	return f(ctx)
+}
+
 func (m *mockRegistry) GetPerformanceStats() map[string]*collector.CollectorPerformanceStats {
 	return map[string]*collector.CollectorPerformanceStats{
 		"test_collector": {
@@ -371,6 +386,98 @@ func TestMetricsEndpoint(t *testing.T) {
 		}
 	})
 
+	t.Run("OpenMetrics", func(t *testing.T) {
+		t.Parallel()
+		server, err := New(createTestConfig(), createTestLogger(), &mockRegistry{}, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+
+		//nolint:promlinter // Test metric name is intentionally simple
+		counter := prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "test_events_total",
+			Help: "A test counter",
+		})
+		counter.Inc()
+		if err := server.RegisterCollector(counter); err != nil {
+			t.Fatalf("Failed to register test metric: %v", err)
+		}
+		handler := server.createMetricsHandler()
+
+		scrape := func(accept string) (string, string) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			body, err := io.ReadAll(w.Result().Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %v", err)
+			}
+			return w.Result().Header.Get("Content-Type"), string(body)
+		}
+
+		// Scrapers asking for OpenMetrics get it, with created timestamps
+		contentType, body := scrape("application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+		if !strings.HasPrefix(contentType, "application/openmetrics-text") {
+			t.Errorf("Expected OpenMetrics content type, got %q", contentType)
+		}
+		if !strings.Contains(body, "test_events_created ") {
+			t.Error("Expected OpenMetrics output to contain test_events_created")
+		}
+		if !strings.HasSuffix(body, "# EOF\n") {
+			t.Error("Expected OpenMetrics output to end with # EOF")
+		}
+
+		// Other scrapers still get the text format
+		contentType, body = scrape("")
+		if !strings.HasPrefix(contentType, "text/plain") {
+			t.Errorf("Expected text content type, got %q", contentType)
+		}
+		if strings.Contains(body, "test_events_created") {
+			t.Error("Expected text output not to contain created samples")
+		}
+	})
+
+	t.Run("GathersOnRequestContext", func(t *testing.T) {
+		t.Parallel()
+		server, err := New(createTestConfig(), createTestLogger(), &mockRegistry{}, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+
+		// Each scrape's collectors run on its own request's context
+		type scrapeKey struct{}
+		var (
+			mu   sync.Mutex
+			seen []any
+		)
+		server.AddGatherer(contextGathererFunc(func(ctx context.Context) ([]*dto.MetricFamily, []string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, ctx.Value(scrapeKey{}))
+			return nil, nil, nil
+		}))
+		handler := server.createMetricsHandler()
+
+		var wg sync.WaitGroup
+		for _, scrape := range []string{"a", "b"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx := context.WithValue(context.Background(), scrapeKey{}, scrape)
+				req := httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(ctx)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+		}
+		wg.Wait()
+
+		if len(seen) != 2 || seen[0] == seen[1] {
+			t.Errorf("Expected each scrape to gather on its own context, got %v", seen)
+		}
+	})
+
 	t.Run("WithCollectionError", func(t *testing.T) {
 		t.Parallel()
 		cfg := createTestConfig()
@@ -711,3 +818,64 @@ func TestSetupRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestDebugPatternsEndpoint(t *testing.T) {
+	t.Parallel()
+	server, err := New(createTestConfig(), createTestLogger(), &mockRegistry{}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	get := func(path string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		body, _ := io.ReadAll(w.Body)
+		return w.Code, string(body)
+	}
+
+	if code, _ := get("/debug/patterns"); code != http.StatusNotFound {
+		t.Errorf("without smart filtering: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	filter, err := filtering.NewSmartFilter(config.SmartFilteringConfig{
+		Enabled:        true,
+		NoiseThreshold: 0.8,
+		CacheSize:      100,
+		LearningWindow: 10,
+		VarianceLimit:  0.8,
+		CorrelationMin: 0.2,
+	}, createTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create smart filter: %v", err)
+	}
+	defer func() { _ = filter.Close() }()
+
+	//nolint:promlinter // Test metric name is intentionally simple
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "A test gauge"})
+	if err := server.RegisterCollector(gauge); err != nil {
+		t.Fatalf("Failed to register test metric: %v", err)
+	}
+	server.SetSmartFilter(filter, func(metric string) string {
+		if metric == "test_gauge" {
+			return "test"
+		}
+		return ""
+	})
+
+	// The filter learns from scrapes and keeps everything while learning
+	if code, body := get("/metrics"); code != http.StatusOK || !strings.Contains(body, "test_gauge 0") {
+		t.Fatalf("metrics: status = %d, body:\n%s", code, body)
+	}
+
+	code, body := get("/debug/patterns?action=keep")
+	if code != http.StatusOK {
+		t.Fatalf("patterns: status = %d, want %d", code, http.StatusOK)
+	}
+	if !strings.Contains(body, `"name":"test_gauge"`) || !strings.Contains(body, `"filter_recommend":"keep"`) || !strings.Contains(body, `"learning_phase":true`) {
+		t.Errorf("patterns missing test_gauge decision:\n%s", body)
+	}
+	if _, body := get("/debug/patterns?action=filter"); strings.Contains(body, "test_gauge") {
+		t.Errorf("patterns with action=filter include kept series:\n%s", body)
+	}
+}
@@ -0,0 +1,1403 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+const (
+	// costWarningRatio is the share of a budget at which an account is
+	// warned; quotaWarningRatio raises the alert level further
+	costWarningRatio = 0.8
+
+	// costTrendTolerance is the change in burn rate between two windows
+	// below which costs count as stable
+	costTrendTolerance = 0.1
+
+	// costTrendDays is how many days of costs trends are read from
+	costTrendDays = 30
+
+	// maxCostAlerts bounds the resolved cost alerts kept for reporting
+	maxCostAlerts = 1000
+
+	// costAlertRetention is how long resolved cost alerts are kept
+	costAlertRetention = 90 * 24 * time.Hour
+
+	// daysPerYear converts daily burn rates to monthly, quarterly and
+	// annual forecasts
+	daysPerYear = 365.25
+)
+
+// Cost alert types
+const (
+	costAlertThreshold        = "budget_threshold"
+	costAlertExceeded         = "budget_exceeded"
+	costAlertProjectedOverrun = "projected_overrun"
+)
+
+// AccountCostOptions controls how the account cost adapter prices usage
+type AccountCostOptions struct {
+	// SnapshotTTL is how long a fetched snapshot is reused, so that one
+	// collection pass issues a single set of API calls
+	SnapshotTTL time.Duration
+
+	// Prices is the price of a TRES-hour by TRES name, such as billing,
+	// cpu, gres/gpu or gres/gpu:a100. Memory is priced per GB-hour.
+	Prices map[string]float64
+
+	// PartitionPrices overrides Prices in a partition, TRES by TRES
+	PartitionPrices map[string]map[string]float64
+
+	// Budgets is the budget of each account for one budget period
+	Budgets map[string]float64
+
+	// BudgetPeriod is the period budgets are set for: daily, weekly,
+	// monthly, quarterly or yearly
+	BudgetPeriod string
+
+	// BurnRateWindow is the recent cost the burn rate is averaged over
+	BurnRateWindow time.Duration
+}
+
+// DefaultAccountCostOptions returns the default account cost options, with
+// an empty price table
+func DefaultAccountCostOptions() *AccountCostOptions {
+	return &AccountCostOptions{
+		SnapshotTTL:    15 * time.Second,
+		BudgetPeriod:   "monthly",
+		BurnRateWindow: 7 * 24 * time.Hour,
+	}
+}
+
+// AccountCostOptionsFromConfig returns the account cost options for the
+// account cost tracking collector configuration
+func AccountCostOptionsFromConfig(cfg *config.AccountCostConfig) *AccountCostOptions {
+	opts := DefaultAccountCostOptions()
+	if cfg == nil {
+		return opts
+	}
+	opts.Prices = cfg.Prices
+	opts.PartitionPrices = cfg.PartitionPrices
+	opts.Budgets = cfg.Budgets
+	if cfg.BudgetPeriod != "" {
+		opts.BudgetPeriod = strings.ToLower(cfg.BudgetPeriod)
+	}
+	if cfg.BurnRateWindow > 0 {
+		opts.BurnRateWindow = cfg.BurnRateWindow
+	}
+	return opts
+}
+
+// AccountCostClient implements collector.AccountCostTrackingSLURMClient by
+// pricing what accounts use from a price table, without an external billing
+// system. Historical cost comes from the hourly usage records slurmdbd rolls
+// up for the user associations, priced at the prices of the association's
+// partition; running jobs add what they accrued since the last rollup, from
+// their allocated TRES. Accounts include the cost of their sub-accounts, as
+// with GrpTRESMins. Costs are projected over the budget period and month at
+// the burn rate averaged over the burn rate window, and alerted on when
+// they near, exceed or are projected to exceed the account's budget.
+// Figures no price table can provide, such as efficiency, industry
+// benchmarks and savings, are reported as zero.
+type AccountCostClient struct {
+	client slurm.SlurmClient
+	opts   AccountCostOptions
+	prices priceTable
+	now    func() time.Time
+
+	mu         sync.Mutex
+	snapshot   *accountCostSnapshot
+	alerts     map[string]*collector.AccountCostAlert // active alerts by ID
+	resolved   []*collector.AccountCostAlert
+	exceeded   map[string]int       // account -> budgets exceeded
+	lastExceed map[string]time.Time // account -> when a budget was last exceeded
+	policies   map[string]time.Time // account -> when its budget was first seen
+}
+
+// accountCostSnapshot is one consistent view of what the accounts cost
+type accountCostSnapshot struct {
+	fetchedAt   time.Time
+	periodStart time.Time
+	periodEnd   time.Time
+	rolledUp    time.Time // end of the newest hour of usage records
+	accounts    map[string]*accountCost
+	parents     map[string]string
+}
+
+// accountCost is what an account and its sub-accounts cost
+type accountCost struct {
+	hourly      map[int64]*costUsage // by hour
+	byUser      map[string]float64   // by user, in the budget period
+	allocations map[costKey]*collector.AccountCostAllocation
+}
+
+// costKey identifies the usage of one user of an account in one partition
+type costKey struct {
+	user      string
+	partition string
+}
+
+// costUsage is the TRES-minutes used over some time and what they cost
+type costUsage struct {
+	cost    float64
+	byTRES  tresCounts // cost by TRES
+	minutes tresCounts
+}
+
+// priceTable prices TRES-hours, with partition prices overriding the
+// default prices TRES by TRES
+type priceTable struct {
+	prices     map[string]float64
+	partitions map[string]map[string]float64
+}
+
+// NewAccountCostClient creates an account cost adapter over a SLURM client
+func NewAccountCostClient(client slurm.SlurmClient, opts *AccountCostOptions) *AccountCostClient {
+	defaults := DefaultAccountCostOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	c := &AccountCostClient{
+		client:     client,
+		opts:       *opts,
+		prices:     newPriceTable(opts.Prices, opts.PartitionPrices),
+		now:        time.Now,
+		alerts:     make(map[string]*collector.AccountCostAlert),
+		exceeded:   make(map[string]int),
+		lastExceed: make(map[string]time.Time),
+		policies:   make(map[string]time.Time),
+	}
+	if c.opts.SnapshotTTL <= 0 {
+		c.opts.SnapshotTTL = defaults.SnapshotTTL
+	}
+	if c.opts.BudgetPeriod == "" {
+		c.opts.BudgetPeriod = defaults.BudgetPeriod
+	}
+	if c.opts.BurnRateWindow <= 0 {
+		c.opts.BurnRateWindow = defaults.BurnRateWindow
+	}
+	return c
+}
+
+// ListCostAccounts lists the accounts costs are tracked for
+func (c *AccountCostClient) ListCostAccounts(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.accountNames(), nil
+}
+
+// GetAccountCostMetrics reports what an account cost. TotalCost covers all
+// the usage records slurmrestd returns and CostToDate the budget period.
+// The cost per CPU-hour is the compute cost, GPUs and memory included, per
+// CPU-hour used in the budget period; the costs per GPU- and memory
+// GB-hour are what those cost on their own, across partitions and GPU
+// types.
+func (c *AccountCostClient) GetAccountCostMetrics(ctx context.Context, account string) (*collector.AccountCostMetrics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	period := a.since(snap.periodStart)
+	burn, _, _ := a.burnRate(now, c.opts.BurnRateWindow)
+	monthStart, monthEnd := usageResetWindow(now, "monthly")
+
+	var users int
+	for _, cost := range a.byUser {
+		if cost > 0 {
+			users++
+		}
+	}
+	var peak float64
+	for _, day := range a.days(snap.periodStart, now) {
+		peak = math.Max(peak, day.cost)
+	}
+	breakdown := costBreakdown(account, period.byTRES)
+
+	return &collector.AccountCostMetrics{
+		AccountName:          account,
+		TotalCost:            a.since(time.Time{}).cost,
+		CostToDate:           period.cost,
+		EstimatedMonthlyCost: a.since(monthStart).cost + burn*monthEnd.Sub(now).Hours()/24,
+		CostPerCPUHour:       ratio(breakdown.ComputeCost, period.minutes["cpu"]/60),
+		CostPerGPUHour:       ratio(breakdown.GPUCost, gpuMinutes(period.minutes)/60),
+		CostPerMemoryGBHour:  ratio(breakdown.MemoryCost, period.minutes["mem"]/60/1024),
+		AverageDailyCost:     period.cost / math.Max(now.Sub(snap.periodStart).Hours()/24, 1.0/24),
+		PeakDailyCost:        peak,
+		CostPerUser:          ratio(period.cost, float64(users)),
+		LastUpdated:          now,
+	}, nil
+}
+
+// GetAccountBudgetInfo reports an account's budget for the budget period,
+// how fast it is burnt and by how much it is projected to be overrun
+func (c *AccountCostClient) GetAccountBudgetInfo(ctx context.Context, account string) (*collector.AccountBudgetInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	status := c.budgetStatus(snap, account, a)
+	return &collector.AccountBudgetInfo{
+		AccountName:       account,
+		TotalBudget:       status.budget,
+		RemainingBudget:   math.Max(0, status.budget-status.cost),
+		BudgetPeriod:      c.opts.BudgetPeriod,
+		BudgetStartDate:   snap.periodStart,
+		BudgetEndDate:     snap.periodEnd,
+		BudgetUtilization: status.utilization(),
+		DaysRemaining:     int(math.Ceil(status.daysLeft)),
+		BurnRate:          status.burn,
+		ProjectedOverrun:  status.overrun(),
+		BudgetStatus:      status.health(),
+		LastBudgetReset:   snap.periodStart,
+		BudgetAlertLevel:  status.alertLevel(),
+	}, nil
+}
+
+// GetAccountCostHistory reports the daily cost of an account between
+// startTime and endTime, with the TRES-hours it used and its cost by TRES
+func (c *AccountCostClient) GetAccountCostHistory(ctx context.Context, account string, startTime, endTime time.Time) ([]*collector.AccountCostHistoryEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	var cumulative float64
+	days := a.days(startTime, endTime)
+	history := make([]*collector.AccountCostHistoryEntry, 0, len(days))
+	for _, day := range days {
+		cumulative += day.cost
+		breakdown := make(map[string]float64, len(day.byTRES))
+		for tres, cost := range day.byTRES {
+			breakdown[tres] = cost
+		}
+		history = append(history, &collector.AccountCostHistoryEntry{
+			Date:           day.date,
+			DailyCost:      day.cost,
+			CumulativeCost: cumulative,
+			CPUHours:       day.minutes["cpu"] / 60,
+			GPUHours:       gpuMinutes(day.minutes) / 60,
+			MemoryGBHours:  day.minutes["mem"] / 60 / 1024,
+			CostBreakdown:  breakdown,
+		})
+	}
+	return history, nil
+}
+
+// GetAccountCostBreakdown breaks the cost of an account in the budget period
+// down by kind of TRES: cpu, GPUs and memory are compute, as are billing and
+// nodes, fs/* TRES are local and bb/* shared storage, license/* TRES are
+// licenses and energy is overhead
+func (c *AccountCostClient) GetAccountCostBreakdown(ctx context.Context, account string) (*collector.AccountCostBreakdown, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+	return costBreakdown(account, a.since(snap.periodStart).byTRES), nil
+}
+
+// GetAccountCostForecasts projects the cost of an account over the coming
+// week, month, quarter and year at its burn rate. The optimistic and
+// pessimistic monthly forecasts are a standard deviation of the daily cost
+// below and above it. Accuracy is how well the burn rate of the window
+// before predicted that of the burn rate window, and confidence the share
+// of the window covered by cost data.
+func (c *AccountCostClient) GetAccountCostForecasts(ctx context.Context, account string) (*collector.AccountCostForecasts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	burn, previous, coverage := a.burnRate(now, c.opts.BurnRateWindow)
+	_, variance := meanVariance(dailyCosts(a.days(now.Add(-c.opts.BurnRateWindow), now)))
+	monthDays := daysPerYear / 12
+
+	forecasts := &collector.AccountCostForecasts{
+		AccountName:            account,
+		WeeklyForecast:         burn * 7,
+		MonthlyForecast:        burn * monthDays,
+		QuarterlyForecast:      burn * daysPerYear / 4,
+		AnnualForecast:         burn * daysPerYear,
+		TrendDirection:         costTrendDirection(burn, previous),
+		ConfidenceLevel:        coverage,
+		SeasonalFactors:        weekdayFactors(a.days(now.AddDate(0, 0, -costTrendDays), now)),
+		GrowthRate:             ratio(burn-previous, previous),
+		ProjectedBudgetOverrun: c.budgetStatus(snap, account, a).overrun(),
+		OptimisticForecast:     math.Max(0, burn-math.Sqrt(variance)) * monthDays,
+		PessimisticForecast:    (burn + math.Sqrt(variance)) * monthDays,
+		ForecastAccuracy:       forecastAccuracy(burn, previous, coverage),
+		LastForecastUpdate:     now,
+	}
+	return forecasts, nil
+}
+
+// GetAccountCostAlerts reports the active budget alerts of an account and
+// those resolved within the retention period. Alerts are raised once per
+// budget period when the cost reaches 80% of the budget, when it exceeds
+// the budget and when it is projected to exceed it by the end of the
+// period; they resolve when the condition clears, including when a new
+// period starts.
+func (c *AccountCostClient) GetAccountCostAlerts(ctx context.Context, account string) ([]*collector.AccountCostAlert, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := snap.account(account); err != nil {
+		return nil, err
+	}
+
+	var alerts []*collector.AccountCostAlert
+	for _, id := range sortedCostAlertIDs(c.alerts) {
+		if alert := c.alerts[id]; alert.AccountName == account {
+			copied := *alert
+			alerts = append(alerts, &copied)
+		}
+	}
+	for _, alert := range c.resolved {
+		if alert.AccountName == account {
+			copied := *alert
+			alerts = append(alerts, &copied)
+		}
+	}
+	return alerts, nil
+}
+
+// GetAccountCostOptimizations reports no optimizations: a price table says
+// what usage cost, not how much of it was wasted
+func (c *AccountCostClient) GetAccountCostOptimizations(ctx context.Context, account string) ([]*collector.AccountCostOptimization, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := snap.account(account); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// GetAccountCostComparisons compares the cost of an account in the budget
+// period with its peers, the accounts with the same parent. The ranking is
+// 1 for the most expensive peer and the percentile the share of the other
+// peers that cost less, in percent.
+func (c *AccountCostClient) GetAccountCostComparisons(ctx context.Context, account string) (*collector.AccountCostComparisons, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := a.since(snap.periodStart).cost
+	peers := snap.peers(account)
+	var total float64
+	var cheaper int
+	ranking := 1
+	for _, peer := range peers {
+		peerCost := snap.accounts[peer].since(snap.periodStart).cost
+		total += peerCost
+		switch {
+		case peerCost > cost:
+			ranking++
+		case peerCost < cost:
+			cheaper++
+		}
+	}
+	average := ratio(total, float64(len(peers)))
+
+	return &collector.AccountCostComparisons{
+		AccountName:         account,
+		PeerAccountsAvgCost: average,
+		CostRanking:         ranking,
+		TotalAccounts:       len(peers) + 1,
+		CostPercentile:      100 * ratio(float64(cheaper), float64(len(peers))),
+		CostVariance:        ratio(cost-average, average),
+		ComparisonPeriod:    c.opts.BudgetPeriod,
+		LastComparison:      snap.fetchedAt,
+	}, nil
+}
+
+// GetAccountBudgetUtilization reports how much of an account's budget is
+// used and projected to be used by the end of the budget period. The
+// optimal burn rate spreads the rest of the budget evenly over the rest of
+// the period and the recommended adjustment is the projected overrun. The
+// utilization history is the share of the budget used by the end of each
+// day of the period.
+func (c *AccountCostClient) GetAccountBudgetUtilization(ctx context.Context, account string) (*collector.AccountBudgetUtilization, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	status := c.budgetStatus(snap, account, a)
+	utilization := &collector.AccountBudgetUtilization{
+		AccountName:           account,
+		CurrentUtilization:    status.utilization(),
+		ProjectedUtilization:  ratio(status.projected(), status.budget),
+		UtilizationTrend:      trend(dailyCosts(a.days(now.Add(-c.opts.BurnRateWindow), now))),
+		DailyBurnRate:         status.burn,
+		OptimalBurnRate:       status.optimalBurn(),
+		BudgetHealth:          status.health(),
+		RecommendedAdjustment: status.overrun(),
+		LastUtilizationUpdate: now,
+	}
+	utilization.BurnRateVariance = ratio(status.burn-utilization.OptimalBurnRate, utilization.OptimalBurnRate)
+	if at := exhaustion(now, status.budget, status.cost, status.burn); status.budget > 0 && at != nil {
+		utilization.TimeToDepletion = int(math.Ceil(at.Sub(now).Hours() / 24))
+	}
+	if status.budget > 0 {
+		var cumulative float64
+		for _, day := range a.days(snap.periodStart, now) {
+			cumulative += day.cost
+			utilization.UtilizationHistory = append(utilization.UtilizationHistory, cumulative/status.budget)
+		}
+	}
+	return utilization, nil
+}
+
+// GetAccountCostTrends reads trends from the daily cost of an account over
+// the last week and month. Volatility is the coefficient of variation of
+// the daily cost, strength how linear its trend is and anomalies the days
+// more than two standard deviations above the mean.
+func (c *AccountCostClient) GetAccountCostTrends(ctx context.Context, account string) (*collector.AccountCostTrends, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	month := dailyCosts(a.days(now.AddDate(0, 0, -costTrendDays), now))
+	week := dailyCosts(a.days(now.AddDate(0, 0, -7), now))
+	burn, previous, coverage := a.burnRate(now, c.opts.BurnRateWindow)
+
+	trends := &collector.AccountCostTrends{
+		AccountName:        account,
+		ShortTermTrend:     trend(week),
+		LongTermTrend:      trend(month),
+		CostVolatility:     variation(month),
+		TrendStrength:      math.Abs(correlation(dayIndexes(len(month)), month)),
+		PredictiveAccuracy: forecastAccuracy(burn, previous, coverage),
+		TrendConfidence:    sampleConfidence(len(month)),
+		LastTrendAnalysis:  now,
+	}
+	mean, variance := meanVariance(month)
+	for _, cost := range month {
+		if cost > mean+2*math.Sqrt(variance) {
+			trends.AnomalyCount++
+		}
+	}
+	return trends, nil
+}
+
+// GetAccountCostAnalytics reports how predictable the cost of an account is
+// and what drives it: the TRES it spent most on in the budget period. Usage
+// correlation is between the daily CPU- and GPU-hours and cost, which
+// partition prices and GPU types set apart.
+func (c *AccountCostClient) GetAccountCostAnalytics(ctx context.Context, account string) (*collector.AccountCostAnalytics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	days := a.days(now.AddDate(0, 0, -costTrendDays), now)
+	costs := dailyCosts(days)
+	hours := make([]float64, len(days))
+	for i, day := range days {
+		hours[i] = (day.minutes["cpu"] + gpuMinutes(day.minutes)) / 60
+	}
+
+	period := a.since(snap.periodStart)
+	drivers := sortedTRES(period.byTRES)
+	sort.SliceStable(drivers, func(i, j int) bool { return period.byTRES[drivers[i]] > period.byTRES[drivers[j]] })
+
+	return &collector.AccountCostAnalytics{
+		AccountName:         account,
+		UsageCorrelation:    correlation(hours, costs),
+		SeasonalityIndex:    variation(mapValues(weekdayFactors(days))),
+		CostPredictability:  math.Max(0, 1-variation(costs)),
+		CostDrivers:         drivers[:min(len(drivers), 3)],
+		LastAnalyticsUpdate: now,
+	}, nil
+}
+
+// GetAccountCostReports reports the cost of an account in the current
+// budget period
+func (c *AccountCostClient) GetAccountCostReports(ctx context.Context, account string) ([]*collector.AccountCostReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	period := a.since(snap.periodStart)
+	status := c.budgetStatus(snap, account, a)
+	report := &collector.AccountCostReport{
+		ReportID:      fmt.Sprintf("%s/%s/%d", account, c.opts.BudgetPeriod, snap.periodStart.Unix()),
+		AccountName:   account,
+		ReportType:    "budget_period",
+		GeneratedTime: now,
+		ReportPeriod:  c.opts.BudgetPeriod,
+		TotalCost:     period.cost,
+		CostBreakdown: make(map[string]float64),
+		BudgetStatus:  status.health(),
+		KeyMetrics: map[string]float64{
+			"budget":         status.budget,
+			"burn_rate":      status.burn,
+			"projected_cost": status.projected(),
+			"utilization":    status.utilization(),
+		},
+		Trends: map[string]string{
+			"short_term": trend(dailyCosts(a.days(now.AddDate(0, 0, -7), now))),
+			"long_term":  trend(dailyCosts(a.days(now.AddDate(0, 0, -costTrendDays), now))),
+		},
+	}
+	for tres, cost := range period.byTRES {
+		report.CostBreakdown[tres] = cost
+	}
+	if overrun := status.overrun(); overrun > 0 {
+		report.Recommendations = append(report.Recommendations,
+			fmt.Sprintf("projected to exceed the %s budget by %.2f; raise it or slow down to %.2f a day", c.opts.BudgetPeriod, overrun, status.optimalBurn()))
+	}
+	for _, id := range sortedCostAlertIDs(c.alerts) {
+		if alert := c.alerts[id]; alert.AccountName == account {
+			report.Alerts = append(report.Alerts, alert.Message)
+		}
+	}
+	return []*collector.AccountCostReport{report}, nil
+}
+
+// GetAccountCostPolicies reports the budget of an account as a cost policy.
+// The exporter only alerts on budgets, so violations are the budget periods
+// in which the budget was exceeded.
+func (c *AccountCostClient) GetAccountCostPolicies(ctx context.Context, account string) ([]*collector.AccountCostPolicy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := snap.account(account); err != nil {
+		return nil, err
+	}
+
+	budget, ok := c.opts.Budgets[account]
+	if !ok {
+		return nil, nil
+	}
+	return []*collector.AccountCostPolicy{{
+		PolicyID:        account + "/budget",
+		AccountName:     account,
+		PolicyType:      "budget",
+		PolicyName:      c.opts.BudgetPeriod + " budget",
+		Description:     fmt.Sprintf("%s budget of %.2f", c.opts.BudgetPeriod, budget),
+		CostLimit:       budget,
+		TimeWindow:      c.opts.BudgetPeriod,
+		EnforcementMode: "alert",
+		Violations:      c.exceeded[account],
+		LastViolation:   c.lastExceed[account],
+		CreatedTime:     c.policies[account],
+		ModifiedTime:    c.policies[account],
+		Active:          true,
+	}}, nil
+}
+
+// GetAccountCostAllocations breaks the cost of the account's own user
+// associations down by user and partition; sub-accounts report their own
+func (c *AccountCostClient) GetAccountCostAllocations(ctx context.Context, account string) ([]*collector.AccountCostAllocation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, err := snap.account(account)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := make([]*collector.AccountCostAllocation, 0, len(a.allocations))
+	for _, allocation := range a.allocations {
+		copied := *allocation
+		allocations = append(allocations, &copied)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		if allocations[i].UserName != allocations[j].UserName {
+			return allocations[i].UserName < allocations[j].UserName
+		}
+		return allocations[i].PartitionName < allocations[j].PartitionName
+	})
+	return allocations, nil
+}
+
+// refresh returns the current snapshot, fetching a new one when it is older
+// than the snapshot TTL. The caller must hold c.mu.
+func (c *AccountCostClient) refresh(ctx context.Context) (*accountCostSnapshot, error) {
+	now := c.now()
+	if c.snapshot != nil && now.Sub(c.snapshot.fetchedAt) < c.opts.SnapshotTTL {
+		return c.snapshot, nil
+	}
+
+	manager := c.client.Associations()
+	if manager == nil {
+		return nil, fmt.Errorf("associations endpoint not available")
+	}
+	assocList, err := manager.List(ctx, &slurm.ListAssociationsOptions{WithUsage: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list associations: %w", err)
+	}
+
+	snap := &accountCostSnapshot{
+		fetchedAt: now,
+		accounts:  make(map[string]*accountCost),
+		parents:   make(map[string]string),
+	}
+	snap.periodStart, snap.periodEnd = usageResetWindow(now, c.opts.BudgetPeriod)
+	if assocList != nil {
+		snap.addAssociations(assocList.Associations, c.prices)
+	}
+
+	if jobList, err := c.client.Jobs().List(ctx, nil); err != nil {
+		logrus.WithError(err).Debug("Account costs continuing without running jobs")
+	} else if jobList != nil {
+		for i := range jobList.Jobs {
+			snap.addJob(&jobList.Jobs[i], c.prices)
+		}
+	}
+
+	c.observe(snap)
+	c.snapshot = snap
+	return snap, nil
+}
+
+// observe raises and resolves the budget alerts of a snapshot. The caller
+// must hold c.mu.
+func (c *AccountCostClient) observe(snap *accountCostSnapshot) {
+	now := snap.fetchedAt
+
+	raised := make(map[string]*collector.AccountCostAlert)
+	for _, account := range snap.accountNames() {
+		if _, ok := c.opts.Budgets[account]; !ok {
+			continue
+		}
+		if _, ok := c.policies[account]; !ok {
+			c.policies[account] = now
+		}
+		status := c.budgetStatus(snap, account, snap.accounts[account])
+		if status.budget <= 0 {
+			continue
+		}
+		alert := func(kind, severity string, current, threshold float64, message string) {
+			id := fmt.Sprintf("%s/%s/%d", account, kind, snap.periodStart.Unix())
+			raised[id] = &collector.AccountCostAlert{
+				AlertID:        id,
+				AccountName:    account,
+				AlertType:      kind,
+				Severity:       severity,
+				Message:        message,
+				CurrentValue:   current,
+				ThresholdValue: threshold,
+				AlertTime:      now,
+				Status:         collector.StatusActive,
+				AutoResolution: true,
+			}
+		}
+		if status.cost >= status.budget {
+			alert(costAlertExceeded, "critical", status.cost, status.budget,
+				fmt.Sprintf("%s budget of %.2f exceeded", c.opts.BudgetPeriod, status.budget))
+			continue
+		}
+		if status.cost >= costWarningRatio*status.budget {
+			alert(costAlertThreshold, collector.StatusWarning, status.cost, costWarningRatio*status.budget,
+				fmt.Sprintf("%.0f%% of the %s budget used", 100*status.utilization(), c.opts.BudgetPeriod))
+		}
+		if overrun := status.overrun(); overrun > 0 {
+			alert(costAlertProjectedOverrun, collector.StatusWarning, status.projected(), status.budget,
+				fmt.Sprintf("%s budget projected to be exceeded by %.2f", c.opts.BudgetPeriod, overrun))
+		}
+	}
+
+	for _, id := range sortedCostAlertIDs(raised) {
+		alert := raised[id]
+		active, ok := c.alerts[id]
+		if !ok {
+			// Exceeding a budget escalates the alerts already raised for it
+			if alert.AlertType == costAlertExceeded {
+				for _, other := range c.alerts {
+					if other.AccountName == alert.AccountName {
+						alert.EscalationLevel = 1
+					}
+				}
+				c.exceeded[alert.AccountName]++
+				c.lastExceed[alert.AccountName] = now
+			}
+			c.alerts[id] = alert
+			continue
+		}
+		active.Message = alert.Message
+		active.CurrentValue = alert.CurrentValue
+		active.ThresholdValue = alert.ThresholdValue
+	}
+	for _, id := range sortedCostAlertIDs(c.alerts) {
+		if _, ok := raised[id]; ok {
+			continue
+		}
+		alert := c.alerts[id]
+		delete(c.alerts, id)
+		alert.Status = collector.StatusResolved
+		alert.ResolvedTime = now
+		c.resolved = append(c.resolved, alert)
+	}
+
+	for len(c.resolved) > 0 && (len(c.resolved) > maxCostAlerts || now.Sub(c.resolved[0].ResolvedTime) > costAlertRetention) {
+		c.resolved = c.resolved[1:]
+	}
+}
+
+// budgetStatus compares what an account cost in the budget period with its
+// budget. The caller must hold c.mu.
+func (c *AccountCostClient) budgetStatus(snap *accountCostSnapshot, account string, a *accountCost) budgetStatus {
+	burn, _, _ := a.burnRate(snap.fetchedAt, c.opts.BurnRateWindow)
+	return budgetStatus{
+		budget:   c.opts.Budgets[account],
+		cost:     a.since(snap.periodStart).cost,
+		burn:     burn,
+		daysLeft: math.Max(0, snap.periodEnd.Sub(snap.fetchedAt).Hours()/24),
+	}
+}
+
+// budgetStatus is what an account cost in the budget period against its
+// budget, with its burn rate per day
+type budgetStatus struct {
+	budget   float64
+	cost     float64
+	burn     float64
+	daysLeft float64
+}
+
+func (s budgetStatus) utilization() float64 {
+	return ratio(s.cost, s.budget)
+}
+
+// projected is the cost projected by the end of the budget period
+func (s budgetStatus) projected() float64 {
+	return s.cost + s.burn*s.daysLeft
+}
+
+func (s budgetStatus) overrun() float64 {
+	if s.budget <= 0 {
+		return 0
+	}
+	return math.Max(0, s.projected()-s.budget)
+}
+
+// optimalBurn spreads what is left of the budget over the rest of the
+// budget period
+func (s budgetStatus) optimalBurn() float64 {
+	return ratio(math.Max(0, s.budget-s.cost), s.daysLeft)
+}
+
+func (s budgetStatus) health() string {
+	switch {
+	case s.budget <= 0:
+		return collector.StatusHealthy
+	case s.cost >= s.budget:
+		return "critical"
+	case s.cost >= costWarningRatio*s.budget || s.overrun() > 0:
+		return collector.StatusWarning
+	default:
+		return collector.StatusHealthy
+	}
+}
+
+func (s budgetStatus) alertLevel() string {
+	switch {
+	case s.budget <= 0:
+		return collector.StateNone
+	case s.cost >= s.budget:
+		return "high"
+	case s.cost >= quotaWarningRatio*s.budget:
+		return "medium"
+	case s.cost >= costWarningRatio*s.budget || s.overrun() > 0:
+		return "low"
+	default:
+		return collector.StateNone
+	}
+}
+
+// addAssociations prices the usage records of the user associations, at
+// the prices of their partition, and charges them to the accounts. Accounts
+// whose users have no records are charged their own records.
+func (s *accountCostSnapshot) addAssociations(assocs []slurm.Association, prices priceTable) {
+	for i := range assocs {
+		assoc := &assocs[i]
+		account := stringValue(assoc.Account)
+		if account == "" || assoc.User != "" || stringValue(assoc.Partition) != "" || isDeletedAssociation(assoc) {
+			continue
+		}
+		s.accounts[account] = newAccountCost()
+		if parent := stringValue(assoc.ParentAccount); parent != "" && parent != account {
+			s.parents[account] = parent
+		}
+	}
+
+	charged := make(map[string]bool)
+	for i := range assocs {
+		assoc := &assocs[i]
+		account := stringValue(assoc.Account)
+		if s.accounts[account] == nil || assoc.User == "" || isDeletedAssociation(assoc) {
+			continue
+		}
+		if s.addRecords(account, assoc.User, stringValue(assoc.Partition), assoc.Accounting, prices) {
+			charged[account] = true
+		}
+	}
+	for i := range assocs {
+		assoc := &assocs[i]
+		account := stringValue(assoc.Account)
+		if s.accounts[account] == nil || assoc.User != "" || stringValue(assoc.Partition) != "" || charged[account] {
+			continue
+		}
+		s.addRecords(account, "", "", assoc.Accounting, prices)
+	}
+}
+
+// addRecords charges the hourly usage records of an association to its
+// account and reports whether there were any
+func (s *accountCostSnapshot) addRecords(account, user, partition string, records []api.Accounting, prices priceTable) bool {
+	hourly := make(map[int64]tresCounts)
+	for _, record := range records {
+		if record.TRES == nil || record.Allocated == nil || record.Allocated.Seconds == nil || record.Start == nil {
+			continue
+		}
+		hour := time.Unix(*record.Start, 0).Truncate(time.Hour)
+		if hourly[hour.Unix()] == nil {
+			hourly[hour.Unix()] = make(tresCounts)
+		}
+		hourly[hour.Unix()][tresName(*record.TRES)] += float64(*record.Allocated.Seconds) / 60
+		if end := hour.Add(time.Hour); end.After(s.rolledUp) {
+			s.rolledUp = end
+		}
+	}
+	for hour, minutes := range hourly {
+		s.charge(account, user, partition, hour, minutes, prices)
+	}
+	return len(hourly) > 0
+}
+
+// addJob charges a running job for the time since the newest usage
+// records, which slurmdbd has yet to roll up, and adds its hourly cost to
+// its user and partition
+func (s *accountCostSnapshot) addJob(job *slurm.Job, prices priceTable) {
+	qj := newQueueJob(job)
+	if qj.state != string(api.JobStateRunning) || !qj.hasStarted(s.fetchedAt) || s.accounts[qj.account] == nil {
+		return
+	}
+	tres := jobTRES(qj.cpus, job.TRESAllocStr, job.TRESReqStr)
+	delete(tres, jobsTRES)
+
+	perHour := make(tresCounts)
+	for name, count := range tres {
+		perHour[name] = count * 60
+	}
+	rate := sumCosts(prices.cost(qj.partition, perHour))
+	s.accounts[qj.account].allocation(qj.account, qj.user, qj.partition).RunningCost += rate
+
+	from := qj.start
+	if from.Before(s.rolledUp) {
+		from = s.rolledUp
+	}
+	for from.Before(s.fetchedAt) {
+		hour := from.Truncate(time.Hour)
+		to := hour.Add(time.Hour)
+		if to.After(s.fetchedAt) {
+			to = s.fetchedAt
+		}
+		minutes := make(tresCounts)
+		for name, count := range tres {
+			minutes[name] = count * to.Sub(from).Minutes()
+		}
+		s.charge(qj.account, qj.user, qj.partition, hour.Unix(), minutes, prices)
+		from = to
+	}
+}
+
+// charge prices TRES-minutes used in an hour and adds them to an account
+// and the accounts above it
+func (s *accountCostSnapshot) charge(account, user, partition string, hour int64, minutes tresCounts, prices priceTable) {
+	costs := prices.cost(partition, minutes)
+	total := sumCosts(costs)
+	inPeriod := !time.Unix(hour, 0).Before(s.periodStart)
+	for _, name := range s.lineage(account) {
+		a := s.accounts[name]
+		usage := a.hourly[hour]
+		if usage == nil {
+			usage = newCostUsage()
+			a.hourly[hour] = usage
+		}
+		usage.cost += total
+		usage.byTRES.add(costs)
+		usage.minutes.add(minutes)
+		if inPeriod && user != "" {
+			a.byUser[user] += total
+		}
+	}
+	if inPeriod && user != "" {
+		s.accounts[account].allocation(account, user, partition).AccruedCost += total
+	}
+}
+
+func (s *accountCostSnapshot) account(name string) (*accountCost, error) {
+	a, ok := s.accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("account %q not found", name)
+	}
+	return a, nil
+}
+
+func (s *accountCostSnapshot) accountNames() []string {
+	names := make([]string, 0, len(s.accounts))
+	for name := range s.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lineage returns an account and the accounts above it
+func (s *accountCostSnapshot) lineage(account string) []string {
+	var names []string
+	visited := make(map[string]bool)
+	for account != "" && !visited[account] {
+		visited[account] = true
+		if _, ok := s.accounts[account]; ok {
+			names = append(names, account)
+		}
+		account = s.parents[account]
+	}
+	return names
+}
+
+// peers returns the other accounts with the same parent
+func (s *accountCostSnapshot) peers(account string) []string {
+	var peers []string
+	for _, name := range s.accountNames() {
+		if name != account && s.parents[name] == s.parents[account] {
+			peers = append(peers, name)
+		}
+	}
+	return peers
+}
+
+func newAccountCost() *accountCost {
+	return &accountCost{
+		hourly:      make(map[int64]*costUsage),
+		byUser:      make(map[string]float64),
+		allocations: make(map[costKey]*collector.AccountCostAllocation),
+	}
+}
+
+// allocation returns the cost of a user of the account in a partition,
+// creating it
+func (a *accountCost) allocation(account, user, partition string) *collector.AccountCostAllocation {
+	key := costKey{user: user, partition: partition}
+	allocation := a.allocations[key]
+	if allocation == nil {
+		allocation = &collector.AccountCostAllocation{AccountName: account, UserName: user, PartitionName: partition}
+		a.allocations[key] = allocation
+	}
+	return allocation
+}
+
+// since adds up the cost from a time on
+func (a *accountCost) since(from time.Time) *costUsage {
+	total := newCostUsage()
+	for hour, usage := range a.hourly {
+		if !time.Unix(hour, 0).Before(from.Truncate(time.Hour)) {
+			total.add(usage)
+		}
+	}
+	return total
+}
+
+// days adds the cost up by day from the day of from to the day of to, in
+// the time zone of to, leaving out the days before the first cost
+func (a *accountCost) days(from, to time.Time) []*costDay {
+	first := int64(math.MaxInt64)
+	for hour := range a.hourly {
+		first = min(first, hour)
+	}
+	if first == math.MaxInt64 {
+		return nil
+	}
+	loc := to.Location()
+	if start := time.Unix(first, 0).In(loc); start.After(from) {
+		from = start
+	}
+
+	var days []*costDay
+	index := make(map[string]*costDay)
+	for day := midnight(from.In(loc)); day.Before(to); day = day.AddDate(0, 0, 1) {
+		entry := &costDay{date: day, costUsage: newCostUsage()}
+		days = append(days, entry)
+		index[day.Format(time.DateOnly)] = entry
+	}
+	for hour, usage := range a.hourly {
+		at := time.Unix(hour, 0).In(loc)
+		if at.After(to) {
+			continue
+		}
+		if entry := index[at.Format(time.DateOnly)]; entry != nil {
+			entry.add(usage)
+		}
+	}
+	return days
+}
+
+// burnRate averages the daily cost over the burn rate window, or over the
+// part of it since the first cost for new accounts, along with the window
+// before it and the share of the window covered
+func (a *accountCost) burnRate(now time.Time, window time.Duration) (float64, float64, float64) {
+	from := now.Add(-window)
+	first := now
+	for hour := range a.hourly {
+		if at := time.Unix(hour, 0); at.Before(first) {
+			first = at
+		}
+	}
+	if first.After(from) {
+		from = first
+	}
+	span := math.Max(now.Sub(from).Hours(), 1)
+
+	var burn, previous float64
+	for hour, usage := range a.hourly {
+		at := time.Unix(hour, 0)
+		switch {
+		case at.Before(now.Add(-2 * window)):
+		case at.Before(now.Add(-window)):
+			previous += usage.cost / (window.Hours() / 24)
+		case !at.Before(from.Truncate(time.Hour)):
+			burn += usage.cost / (span / 24)
+		}
+	}
+	return burn, previous, math.Min(1, span/window.Hours())
+}
+
+// costDay is what an account used and cost on one day
+type costDay struct {
+	date time.Time
+	*costUsage
+}
+
+func newCostUsage() *costUsage {
+	return &costUsage{byTRES: make(tresCounts), minutes: make(tresCounts)}
+}
+
+func (u *costUsage) add(other *costUsage) {
+	u.cost += other.cost
+	u.byTRES.add(other.byTRES)
+	u.minutes.add(other.minutes)
+}
+
+// newPriceTable returns a price table with TRES names in lower case
+func newPriceTable(prices map[string]float64, partitions map[string]map[string]float64) priceTable {
+	table := priceTable{prices: lowerKeys(prices), partitions: make(map[string]map[string]float64, len(partitions))}
+	for partition, partitionPrices := range partitions {
+		table.partitions[partition] = lowerKeys(partitionPrices)
+	}
+	return table
+}
+
+// price returns the price of an hour of a TRES in a partition
+func (p priceTable) price(partition, tres string) (float64, bool) {
+	tres = strings.ToLower(tres)
+	if price, ok := p.partitions[partition][tres]; ok {
+		return price, true
+	}
+	price, ok := p.prices[tres]
+	return price, ok
+}
+
+// cost prices TRES-minutes used in a partition, by TRES, with memory priced
+// per GB. GPUs of a type priced on their own, such as gres/gpu:a100, are
+// not priced again as gres/gpu.
+func (p priceTable) cost(partition string, minutes tresCounts) tresCounts {
+	costs := make(tresCounts)
+	typed := make(tresCounts)
+	for tres, used := range minutes {
+		base, _, ok := strings.Cut(tres, ":")
+		if !ok {
+			continue
+		}
+		if price, ok := p.price(partition, tres); ok {
+			costs[tres] = used / 60 * tresUnit(tres) * price
+			typed[base] += used
+		}
+	}
+	for tres, used := range minutes {
+		if tres == jobsTRES || strings.Contains(tres, ":") {
+			continue
+		}
+		if price, ok := p.price(partition, tres); ok {
+			costs[tres] = math.Max(0, used-typed[tres]) / 60 * tresUnit(tres) * price
+		}
+	}
+	return costs
+}
+
+// tresUnit converts a TRES count to the unit it is priced in
+func tresUnit(tres string) float64 {
+	if tres == "mem" {
+		return 1.0 / 1024
+	}
+	return 1
+}
+
+// costBreakdown sorts costs by TRES into kinds of cost
+func costBreakdown(account string, costs tresCounts) *collector.AccountCostBreakdown {
+	breakdown := &collector.AccountCostBreakdown{AccountName: account}
+	for tres, cost := range costs {
+		switch {
+		case tres == "cpu":
+			breakdown.CPUCost += cost
+		case tres == "mem":
+			breakdown.MemoryCost += cost
+		case tres == "gres/gpu" || strings.HasPrefix(tres, "gres/gpu:"):
+			breakdown.GPUCost += cost
+		case strings.HasPrefix(tres, "fs/"):
+			breakdown.LocalStorageCost += cost
+			continue
+		case strings.HasPrefix(tres, "bb/"):
+			breakdown.SharedStorageCost += cost
+			continue
+		case strings.HasPrefix(tres, "license/"):
+			breakdown.LicenseCost += cost
+			continue
+		case tres == "energy":
+			breakdown.OverheadCost += cost
+			continue
+		}
+		breakdown.ComputeCost += cost
+	}
+	breakdown.StorageCost = breakdown.LocalStorageCost + breakdown.SharedStorageCost
+	return breakdown
+}
+
+// gpuMinutes returns the GPU-minutes in TRES-minutes, counting typed GPUs
+// when the untyped gres/gpu TRES is not tracked
+func gpuMinutes(minutes tresCounts) float64 {
+	if used, ok := minutes["gres/gpu"]; ok {
+		return used
+	}
+	var used float64
+	for tres, count := range minutes {
+		if strings.HasPrefix(tres, "gres/gpu:") {
+			used += count
+		}
+	}
+	return used
+}
+
+// costTrendDirection compares the burn rate with that of the window before
+func costTrendDirection(burn, previous float64) string {
+	switch {
+	case burn > previous*(1+costTrendTolerance):
+		return "up"
+	case burn < previous*(1-costTrendTolerance):
+		return "down"
+	default:
+		return "stable"
+	}
+}
+
+// forecastAccuracy is how close the burn rate of the window before came to
+// the burn rate, when the whole window has cost data
+func forecastAccuracy(burn, previous, coverage float64) float64 {
+	if coverage < 1 || burn <= 0 || previous <= 0 {
+		return 0
+	}
+	return math.Max(0, 1-math.Abs(burn-previous)/burn)
+}
+
+// weekdayFactors compares the mean cost of each weekday with the mean daily
+// cost
+func weekdayFactors(days []*costDay) map[string]float64 {
+	byWeekday := make(map[string][]float64)
+	costs := make([]float64, 0, len(days))
+	for _, day := range days {
+		weekday := strings.ToLower(day.date.Weekday().String())
+		byWeekday[weekday] = append(byWeekday[weekday], day.cost)
+		costs = append(costs, day.cost)
+	}
+	mean, _ := meanVariance(costs)
+	if mean == 0 {
+		return nil
+	}
+	factors := make(map[string]float64, len(byWeekday))
+	fillMeans(factors, byWeekday)
+	for weekday, factor := range factors {
+		factors[weekday] = factor / mean
+	}
+	return factors
+}
+
+func dailyCosts(days []*costDay) []float64 {
+	costs := make([]float64, len(days))
+	for i, day := range days {
+		costs[i] = day.cost
+	}
+	return costs
+}
+
+func dayIndexes(n int) []float64 {
+	indexes := make([]float64, n)
+	for i := range indexes {
+		indexes[i] = float64(i)
+	}
+	return indexes
+}
+
+func mapValues(values map[string]float64) []float64 {
+	list := make([]float64, 0, len(values))
+	for _, key := range sortedTRES(values) {
+		list = append(list, values[key])
+	}
+	return list
+}
+
+func sumCosts(costs tresCounts) float64 {
+	var total float64
+	for _, cost := range costs {
+		total += cost
+	}
+	return total
+}
+
+func lowerKeys(values map[string]float64) map[string]float64 {
+	lowered := make(map[string]float64, len(values))
+	for key, value := range values {
+		lowered[strings.ToLower(key)] = value
+	}
+	return lowered
+}
+
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func sortedCostAlertIDs(alerts map[string]*collector.AccountCostAlert) []string {
+	ids := make([]string, 0, len(alerts))
+	for id := range alerts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Ensure AccountCostClient satisfies the collector interfaces
+var (
+	_ collector.AccountCostTrackingSLURMClient = (*AccountCostClient)(nil)
+	_ collector.AccountCostLister              = (*AccountCostClient)(nil)
+	_ collector.AccountCostAllocator           = (*AccountCostClient)(nil)
+)
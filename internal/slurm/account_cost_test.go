@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+// costTestOptions prices a CPU-hour at 0.06, a GPU-hour at 1.20, a memory
+// GB-hour at 0.006 and an A100 hour in the gpu partition at 3.00, with
+// research budgeted 50 a month
+func costTestOptions(budget float64) *AccountCostOptions {
+	opts := DefaultAccountCostOptions()
+	opts.Prices = map[string]float64{"cpu": 0.06, "gres/gpu": 1.2, "mem": 0.006}
+	opts.PartitionPrices = map[string]map[string]float64{"gpu": {"gres/gpu:A100": 3}}
+	opts.Budgets = map[string]float64{"research": budget}
+	return opts
+}
+
+// costTestAssociations returns research with its ml sub-account and a
+// physics peer. In June alice used 6000 CPU-minutes, bob 1200 CPU-minutes
+// and 600 A100-minutes in the gpu partition and carol 3000 CPU-minutes in
+// ml; in May alice used 60000 CPU-minutes.
+func costTestAssociations(time.Time) []slurm.Association {
+	june := time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC)
+	alice := quotaTestUser("alice", "research",
+		quotaTestRecord("cpu", "", 60000, time.Date(2024, 5, 22, 10, 0, 0, 0, time.UTC)),
+		quotaTestRecord("cpu", "", 6000, june))
+	bob := quotaTestUser("bob", "research",
+		quotaTestRecord("cpu", "", 1200, june),
+		quotaTestRecord("gres", "gpu", 600, june),
+		quotaTestRecord("gres", "gpu:a100", 600, june))
+	gpu := "gpu"
+	bob.Partition = &gpu
+	carol := quotaTestUser("carol", "ml", quotaTestRecord("cpu", "", 3000, time.Date(2024, 6, 1, 5, 0, 0, 0, time.UTC)))
+
+	return []slurm.Association{
+		quotaTestAccount("research", "root"),
+		quotaTestAccount("ml", "research"),
+		quotaTestAccount("physics", "root"),
+		alice, bob, carol,
+	}
+}
+
+// costTestRunningJob is alice running 16 CPUs and 64 GB in compute since
+// 11:30 on the test day, costing 1.344 an hour
+func costTestRunningJob(time.Time) []slurm.Job {
+	return []slurm.Job{qosTestJob(1, "alice", "normal", "RUNNING", "cpu=16,mem=64G,node=1", "")}
+}
+
+// newAccountCostTestClient returns a client whose associations and jobs are
+// produced at the current test time, which advances by setTime
+func newAccountCostTestClient(
+	t *testing.T,
+	opts *AccountCostOptions,
+	assocs func(now time.Time) []slurm.Association,
+	jobs func(now time.Time) []slurm.Job,
+) (*AccountCostClient, func(time.Time)) {
+	t.Helper()
+	clock, setTime := newTestClock()
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(mockJobList(jobs, clock))
+	client.On("Associations").Return(qosTestAssociations{list: func() []slurm.Association { return assocs(clock()) }})
+
+	c := NewAccountCostClient(client, opts)
+	c.now = clock
+	return c, setTime
+}
+
+func TestPriceTable_Cost(t *testing.T) {
+	t.Parallel()
+	prices := newPriceTable(costTestOptions(0).Prices, costTestOptions(0).PartitionPrices)
+
+	// Typed GPUs priced on their own are not priced again as gres/gpu
+	costs := prices.cost("gpu", tresCounts{"cpu": 120, "gres/gpu": 180, "gres/gpu:a100": 120, jobsTRES: 1})
+	assert.InDelta(t, 0.12, costs["cpu"], 1e-9)
+	assert.InDelta(t, 6, costs["gres/gpu:a100"], 1e-9)
+	assert.InDelta(t, 1.2, costs["gres/gpu"], 1e-9)
+	assert.NotContains(t, costs, jobsTRES)
+
+	// Outside the gpu partition A100s are priced as any GPU
+	costs = prices.cost("compute", tresCounts{"gres/gpu": 60, "gres/gpu:a100": 60, "mem": 60 * 1024, "node": 60})
+	assert.InDelta(t, 1.2, costs["gres/gpu"], 1e-9)
+	assert.NotContains(t, costs, "gres/gpu:a100")
+	assert.InDelta(t, 0.006, costs["mem"], 1e-9)
+	assert.NotContains(t, costs, "node")
+}
+
+func TestAccountCostClient_AccruedCost(t *testing.T) {
+	t.Parallel()
+	c, _ := newAccountCostTestClient(t, costTestOptions(50), costTestAssociations, costTestRunningJob)
+	ctx := context.Background()
+
+	accounts, err := c.ListCostAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ml", "physics", "research"}, accounts)
+
+	// June: alice 6.00 plus 0.672 running, bob 1.20 + 30.00, carol 3.00
+	metrics, err := c.GetAccountCostMetrics(ctx, "research")
+	require.NoError(t, err)
+	assert.InDelta(t, 40.872, metrics.CostToDate, 1e-6)
+	assert.InDelta(t, 100.872, metrics.TotalCost, 1e-6)
+	assert.InDelta(t, 37.2, metrics.PeakDailyCost, 1e-6)
+	assert.InDelta(t, 40.872/3, metrics.CostPerUser, 1e-6)
+	assert.InDelta(t, 3, metrics.CostPerGPUHour, 1e-6)
+	assert.InDelta(t, 40.872/178, metrics.CostPerCPUHour, 1e-6)
+	assert.InDelta(t, 0.006, metrics.CostPerMemoryGBHour, 1e-6)
+	burn := 40.872 / 7
+	assert.InDelta(t, 40.872+burn*27.5, metrics.EstimatedMonthlyCost, 1e-6)
+
+	breakdown, err := c.GetAccountCostBreakdown(ctx, "research")
+	require.NoError(t, err)
+	assert.InDelta(t, 40.872, breakdown.ComputeCost, 1e-6)
+	assert.InDelta(t, 30, breakdown.GPUCost, 1e-6)
+	assert.InDelta(t, 0.192, breakdown.MemoryCost, 1e-6)
+
+	ml, err := c.GetAccountCostMetrics(ctx, "ml")
+	require.NoError(t, err)
+	assert.InDelta(t, 3, ml.CostToDate, 1e-6)
+
+	budget, err := c.GetAccountBudgetInfo(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, "monthly", budget.BudgetPeriod)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), budget.BudgetStartDate)
+	assert.Equal(t, 28, budget.DaysRemaining)
+	assert.InDelta(t, 40.872/50, budget.BudgetUtilization, 1e-6)
+	assert.InDelta(t, burn, budget.BurnRate, 1e-6)
+	assert.InDelta(t, 40.872+burn*27.5-50, budget.ProjectedOverrun, 1e-6)
+	assert.Equal(t, collector.StatusWarning, budget.BudgetStatus)
+	assert.Equal(t, "low", budget.BudgetAlertLevel)
+
+	forecasts, err := c.GetAccountCostForecasts(ctx, "research")
+	require.NoError(t, err)
+	assert.InDelta(t, burn*7, forecasts.WeeklyForecast, 1e-6)
+	assert.Equal(t, "down", forecasts.TrendDirection)
+	assert.InDelta(t, 1, forecasts.ConfidenceLevel, 1e-9)
+
+	history, err := c.GetAccountCostHistory(ctx, "research", budget.BudgetStartDate, queueTestNow)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.InDelta(t, 3, history[0].DailyCost, 1e-6)
+	assert.InDelta(t, 120, history[1].CPUHours, 1e-6)
+	assert.InDelta(t, 10, history[1].GPUHours, 1e-6)
+	assert.InDelta(t, 40.872, history[2].CumulativeCost, 1e-6)
+
+	comparisons, err := c.GetAccountCostComparisons(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 1, comparisons.CostRanking)
+	assert.Equal(t, 2, comparisons.TotalAccounts)
+	assert.InDelta(t, 100, comparisons.CostPercentile, 1e-9)
+
+	allocations, err := c.GetAccountCostAllocations(ctx, "research")
+	require.NoError(t, err)
+	require.Len(t, allocations, 3)
+	assert.Equal(t, collector.AccountCostAllocation{AccountName: "research", UserName: "alice", AccruedCost: 6}, *allocations[0])
+	assert.Equal(t, "compute", allocations[1].PartitionName)
+	assert.InDelta(t, 0.672, allocations[1].AccruedCost, 1e-6)
+	assert.InDelta(t, 1.344, allocations[1].RunningCost, 1e-6)
+	assert.Equal(t, "gpu", allocations[2].PartitionName)
+	assert.InDelta(t, 31.2, allocations[2].AccruedCost, 1e-6)
+
+	_, err = c.GetAccountCostMetrics(ctx, "unknown")
+	assert.Error(t, err)
+}
+
+func TestAccountCostClient_BudgetAlerts(t *testing.T) {
+	t.Parallel()
+	c, setTime := newAccountCostTestClient(t, costTestOptions(45), costTestAssociations, costTestRunningJob)
+	ctx := context.Background()
+
+	// 91% of the budget used and projected to be exceeded
+	alerts, err := c.GetAccountCostAlerts(ctx, "research")
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, costAlertThreshold, alerts[0].AlertType)
+	assert.Equal(t, collector.StatusWarning, alerts[0].Severity)
+	assert.Equal(t, costAlertProjectedOverrun, alerts[1].AlertType)
+
+	budget, err := c.GetAccountBudgetInfo(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, "medium", budget.BudgetAlertLevel)
+
+	// Four more hours of alice's job exceed the budget, escalating the
+	// alerts raised before
+	setTime(queueTestNow.Add(4 * time.Hour))
+	alerts, err = c.GetAccountCostAlerts(ctx, "research")
+	require.NoError(t, err)
+	require.Len(t, alerts, 3)
+	exceeded := alerts[0]
+	assert.Equal(t, costAlertExceeded, exceeded.AlertType)
+	assert.Equal(t, collector.StatusActive, exceeded.Status)
+	assert.Equal(t, "critical", exceeded.Severity)
+	assert.Equal(t, 1, exceeded.EscalationLevel)
+	for _, alert := range alerts[1:] {
+		assert.Equal(t, collector.StatusResolved, alert.Status)
+	}
+
+	policies, err := c.GetAccountCostPolicies(ctx, "research")
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, 45.0, policies[0].CostLimit)
+	assert.Equal(t, 1, policies[0].Violations)
+
+	// A new budget period starts over
+	setTime(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	budget, err = c.GetAccountBudgetInfo(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), budget.LastBudgetReset)
+	assert.InDelta(t, 45-12*1.344, budget.RemainingBudget, 1e-6)
+	alerts, err = c.GetAccountCostAlerts(ctx, "research")
+	require.NoError(t, err)
+	for _, alert := range alerts {
+		if alert.AlertType == costAlertExceeded {
+			assert.Equal(t, collector.StatusResolved, alert.Status)
+		}
+	}
+
+	policies, err = c.GetAccountCostPolicies(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 1, policies[0].Violations)
+
+	policies, err = c.GetAccountCostPolicies(ctx, "physics")
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+}
@@ -0,0 +1,1577 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+const (
+	// maxQuotaViolations bounds the resolved quota violations kept for
+	// reporting
+	maxQuotaViolations = 1000
+
+	// quotaViolationRetention is how long resolved quota violations are kept
+	quotaViolationRetention = 30 * 24 * time.Hour
+
+	// quotaWarningRatio is the share of a budget at which an account is
+	// alerted on
+	quotaWarningRatio = 0.9
+
+	// quotaProjectionHorizon is how far usage is projected ahead when budgets
+	// are never reset
+	quotaProjectionHorizon = 30 * 24 * time.Hour
+
+	// quotaTrendTolerance is the change in burn rate between two windows
+	// below which usage counts as stable
+	quotaTrendTolerance = 0.1
+)
+
+// Association limits, named the way sacctmgr shows them
+const (
+	limitGrpTRESMins    = "GrpTRESMins"
+	limitGrpTRESRunMins = "GrpTRESRunMins"
+	limitMaxJobs        = "MaxJobs"
+	limitMaxSubmitJobs  = "MaxSubmitJobs"
+)
+
+// AccountQuotaOptions controls how the account quota adapter burns budgets
+// down
+type AccountQuotaOptions struct {
+	// SnapshotTTL is how long a fetched snapshot is reused, so that one
+	// collection pass issues a single set of API calls
+	SnapshotTTL time.Duration
+
+	// BurnRateWindow is the recent usage the burn rate is averaged over
+	BurnRateWindow time.Duration
+
+	// UsageResetPeriod mirrors PriorityUsageResetPeriod: none, daily,
+	// weekly, monthly, quarterly or yearly. Usage before the last reset does
+	// not count against GrpTRESMins.
+	UsageResetPeriod string
+}
+
+// DefaultAccountQuotaOptions returns the default account quota options
+func DefaultAccountQuotaOptions() *AccountQuotaOptions {
+	return &AccountQuotaOptions{
+		SnapshotTTL:      15 * time.Second,
+		BurnRateWindow:   7 * 24 * time.Hour,
+		UsageResetPeriod: "none",
+	}
+}
+
+// AccountQuotaOptionsFromConfig returns the account quota options for the
+// account quota collector configuration
+func AccountQuotaOptionsFromConfig(cfg *config.AccountQuotaConfig) *AccountQuotaOptions {
+	opts := DefaultAccountQuotaOptions()
+	if cfg == nil {
+		return opts
+	}
+	if cfg.BurnRateWindow > 0 {
+		opts.BurnRateWindow = cfg.BurnRateWindow
+	}
+	if cfg.UsageResetPeriod != "" {
+		opts.UsageResetPeriod = strings.ToLower(cfg.UsageResetPeriod)
+	}
+	return opts
+}
+
+// AccountQuotaClient implements collector.AccountQuotaSLURMClient by burning
+// the GrpTRESMins budgets of the account associations down against their
+// usage. Consumption is the TRES-minutes in the hourly usage records
+// slurmdbd rolls up for the account's user associations and sub-accounts
+// since the last usage reset, or, when slurmrestd returns no records, the
+// raw fair-share usage of the account, which is billing-weighted and only
+// matches the budget with PriorityDecayHalfLife=0. The burn rate averages
+// the usage over the burn rate window and projects when the budget runs
+// out. Running jobs reserve the TRES-minutes they have left, which is also
+// what GrpTRESRunMins limits. Quota violations are the pending jobs held by
+// an association limit, i.e. with a state reason starting with Assoc.
+// Figures slurmrestd has no data for, such as storage quotas and
+// efficiency, are reported as zero.
+type AccountQuotaClient struct {
+	client slurm.SlurmClient
+	opts   AccountQuotaOptions
+	now    func() time.Time
+
+	mu         sync.Mutex
+	snapshot   *accountQuotaSnapshot
+	versions   map[string]accountQuotaVersion // account -> limits version
+	samples    map[string][]consumptionSample // account -> consumption over the burn rate window
+	known      map[string]bool                // jobs already seen
+	finished   map[string]bool                // finished jobs already counted
+	activity   map[string]*accountActivity    // account -> jobs since last reported
+	violations map[string]*collector.QuotaViolation
+	resolved   []*collector.QuotaViolation
+}
+
+// accountQuotaVersion identifies the limits of an account, how often they
+// changed and when they were first seen
+type accountQuotaVersion struct {
+	version string
+	number  int
+	since   time.Time
+}
+
+// consumptionSample is the TRES-minutes an account had consumed at a time
+type consumptionSample struct {
+	at       time.Time
+	consumed tresCounts
+}
+
+// accountActivity counts the jobs of an account submitted and finished since
+// its usage was last reported
+type accountActivity struct {
+	submitted int
+	completed int
+	failed    int
+}
+
+// accountQuotaSnapshot is one consistent view of the account budgets
+type accountQuotaSnapshot struct {
+	fetchedAt time.Time
+	lastReset time.Time // zero when usage is never reset
+	nextReset time.Time
+	budgets   map[string]*accountBudget
+	parents   map[string]string
+	jobs      []*tresJob
+	records   bool // usage comes from the association usage records
+}
+
+// accountBudget is an account association with its limits and what the
+// account and its sub-accounts use
+type accountBudget struct {
+	assoc   *slurm.Association
+	limits  tresLimits
+	maxWall time.Duration
+	users   map[string]*slurm.Association
+
+	accountUsage
+	running    tresCounts
+	pending    tresCounts
+	runMinutes tresCounts // TRES-minutes running jobs have left
+	jobs       []*tresJob
+
+	burn     tresCounts // TRES-minutes per day
+	previous tresCounts // TRES-minutes per day in the window before
+	coverage float64    // share of the burn rate window with usage data
+}
+
+// accountUsage is the TRES-minutes an account consumed
+type accountUsage struct {
+	consumed tresCounts            // since the last reset
+	byUser   map[string]tresCounts // by user, since the last reset
+	hourly   map[int64]tresCounts  // by hour, including before the last reset
+}
+
+// NewAccountQuotaClient creates an account quota adapter over a SLURM client
+func NewAccountQuotaClient(client slurm.SlurmClient, opts *AccountQuotaOptions) *AccountQuotaClient {
+	defaults := DefaultAccountQuotaOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	c := &AccountQuotaClient{
+		client:     client,
+		opts:       *opts,
+		now:        time.Now,
+		versions:   make(map[string]accountQuotaVersion),
+		samples:    make(map[string][]consumptionSample),
+		known:      make(map[string]bool),
+		finished:   make(map[string]bool),
+		activity:   make(map[string]*accountActivity),
+		violations: make(map[string]*collector.QuotaViolation),
+	}
+	if c.opts.SnapshotTTL <= 0 {
+		c.opts.SnapshotTTL = defaults.SnapshotTTL
+	}
+	if c.opts.BurnRateWindow <= 0 {
+		c.opts.BurnRateWindow = defaults.BurnRateWindow
+	}
+	if c.opts.UsageResetPeriod == "" {
+		c.opts.UsageResetPeriod = defaults.UsageResetPeriod
+	}
+	return c
+}
+
+// GetAccountQuotas reports the quotas of an account
+func (c *AccountQuotaClient) GetAccountQuotas(ctx context.Context, accountName string) (*collector.AccountQuotas, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	return c.quotas(snap, accountName, budget), nil
+}
+
+// GetAllAccountQuotas reports the quotas of every account
+func (c *AccountQuotaClient) GetAllAccountQuotas(ctx context.Context) ([]*collector.AccountQuotas, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quotas := make([]*collector.AccountQuotas, 0, len(snap.budgets))
+	for _, name := range snap.accountNames() {
+		quotas = append(quotas, c.quotas(snap, name, snap.budgets[name]))
+	}
+	return quotas, nil
+}
+
+// quotas reports the budgets and limits of an account. TRESMinutes holds
+// the GrpTRESMins budgets and TRESRunMinutes the GrpTRESRunMins limits by
+// TRES; CPUMinutes and GPUHours repeat the cpu and gres/gpu budgets, and the
+// core, node, memory and GPU card quotas are the GrpTRES limits.
+func (c *AccountQuotaClient) quotas(snap *accountQuotaSnapshot, name string, b *accountBudget) *collector.AccountQuotas {
+	version := c.versions[name]
+	quotas := &collector.AccountQuotas{
+		AccountName:      name,
+		ParentAccount:    snap.parents[name],
+		Description:      stringValue(b.assoc.Comment),
+		TRESMinutes:      make(map[string]*collector.ResourceQuota),
+		TRESRunMinutes:   make(map[string]*collector.ResourceQuota),
+		AllowedQoS:       b.assoc.QoS,
+		QuotaPeriod:      c.opts.UsageResetPeriod,
+		QuotaResetDate:   snap.nextReset,
+		ModifiedAt:       version.since,
+		QuotaVersion:     version.number,
+		EnforcementLevel: b.enforcement(),
+		Active:           true,
+	}
+	if b.assoc.Default != nil {
+		quotas.DefaultQoS = stringValue(b.assoc.Default.QoS)
+	}
+
+	for tres, limit := range b.limits[limitGrpTRESMins] {
+		quotas.TRESMinutes[tres] = snap.budgetQuota(b, tres, limit)
+	}
+	for tres, limit := range b.limits[limitGrpTRESRunMins] {
+		quotas.TRESRunMinutes[tres] = concurrentQuota(limit, b.runMinutes[tres])
+	}
+	quotas.CPUMinutes = quotas.TRESMinutes["cpu"]
+	if gpu := quotas.TRESMinutes["gres/gpu"]; gpu != nil {
+		quotas.GPUHours = scaleQuota(gpu, 1.0/60)
+	}
+
+	grp := b.limits[limitGrpTRES]
+	if limit, ok := grp["cpu"]; ok {
+		quotas.CPUCores = concurrentQuota(limit, b.running["cpu"])
+	}
+	if limit, ok := grp["node"]; ok {
+		quotas.CPUNodes = concurrentQuota(limit, b.running["node"])
+	}
+	if limit, ok := grp["mem"]; ok {
+		quotas.MemoryGB = scaleQuota(concurrentQuota(limit, b.running["mem"]), 1.0/1024)
+	}
+	if limit, ok := grp["gres/gpu"]; ok {
+		quotas.GPUCards = concurrentQuota(limit, b.running["gres/gpu"])
+	}
+
+	held := c.heldBy(name)
+	if limit, ok := b.limits[limitGrpJobs][jobsTRES]; ok {
+		quotas.MaxJobs = &collector.LimitQuota{
+			Limit:      int(limit),
+			Current:    int(b.running[jobsTRES]),
+			Violations: held["AssocGrpJobsLimit"],
+		}
+	}
+	if limit, ok := b.limits[limitGrpSubmitJobs][jobsTRES]; ok {
+		quotas.MaxSubmitJobs = &collector.LimitQuota{
+			Limit:      int(limit),
+			Current:    int(b.running[jobsTRES] + b.pending[jobsTRES]),
+			Violations: held["AssocGrpSubmitJobsLimit"],
+		}
+	}
+	if b.maxWall > 0 {
+		quotas.MaxJobDuration = &collector.DurationQuota{
+			Limit:      b.maxWall,
+			Violations: held["AssocMaxWallDurationPerJobLimit"],
+		}
+		var runtimes []float64
+		for _, job := range b.jobs {
+			if job.state == string(api.JobStateRunning) {
+				runtimes = append(runtimes, job.runtime(snap.fetchedAt).Seconds())
+			}
+		}
+		sort.Float64s(runtimes)
+		mean, _ := meanVariance(runtimes)
+		quotas.MaxJobDuration.Average = seconds(mean)
+		quotas.MaxJobDuration.Maximum = seconds(percentile(runtimes, 1))
+	}
+	return quotas
+}
+
+// GetAccountQuotaUsage reports what an account and its sub-accounts used
+// since the last usage reset. CPU usage is in CPU-minutes, memory in
+// GB-minutes and GPU usage in GPU-hours, with the peak, average and
+// standard deviation of the hourly usage. Submitted, completed and failed
+// jobs count the jobs seen since the last call.
+func (c *AccountQuotaClient) GetAccountQuotaUsage(ctx context.Context, accountName string) (*collector.AccountQuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &collector.AccountQuotaUsage{
+		AccountName:     accountName,
+		Period:          c.opts.UsageResetPeriod,
+		CPUUsage:        snap.usageStats(b, "cpu", 1),
+		MemoryUsage:     snap.usageStats(b, "mem", 1.0/1024),
+		GPUUsage:        snap.usageStats(b, "gres/gpu", 1.0/60),
+		JobsRunning:     int(b.running[jobsTRES]),
+		JobsPending:     int(b.pending[jobsTRES]),
+		TotalUsers:      len(b.users),
+		UserQuotaShares: make(map[string]float64),
+		UsageTrend:      b.trend("cpu"),
+		GrowthRate:      b.burn["cpu"],
+		LastUpdated:     snap.fetchedAt,
+	}
+	if usage.Period == "none" {
+		usage.Period = "total"
+	}
+
+	active := make(map[string]bool)
+	for _, job := range b.jobs {
+		if job.user != "" && (job.state == string(api.JobStateRunning) || job.state == string(api.JobStatePending)) {
+			active[job.user] = true
+		}
+	}
+	usage.ActiveUsers = len(active)
+	tres := b.budgetTRES()
+	for user, consumed := range b.byUser {
+		usage.UserQuotaShares[user] = ratio(consumed[tres], b.consumed[tres])
+	}
+	if limit, ok := b.limits[limitGrpTRESMins]["cpu"]; ok {
+		usage.ProjectedDepletion = exhaustion(snap.fetchedAt, limit, b.consumed["cpu"], b.burn["cpu"])
+	}
+
+	if activity := c.activity[accountName]; activity != nil {
+		usage.JobsSubmitted = activity.submitted
+		usage.JobsCompleted = activity.completed
+		usage.JobsFailed = activity.failed
+		delete(c.activity, accountName)
+	}
+	return usage, nil
+}
+
+// GetAccountQuotaHistory reports, for every hour of the period since the
+// last reset, the TRES-minutes the account had consumed by the end of it
+// and their share of its GrpTRESMins budgets. Without usage records, the
+// consumption samples taken by the exporter are reported instead.
+func (c *AccountQuotaClient) GetAccountQuotaHistory(ctx context.Context, accountName string, period string) (*collector.AccountQuotaHistory, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	window, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	from := snap.fetchedAt.Add(-window)
+	if from.Before(snap.lastReset) {
+		from = snap.lastReset
+	}
+
+	history := &collector.AccountQuotaHistory{AccountName: accountName, Period: period}
+	point := func(at time.Time, consumed tresCounts) {
+		dp := collector.AccountQuotaDataPoint{
+			Timestamp:   at,
+			Quotas:      make(map[string]float64),
+			Usage:       make(map[string]float64),
+			Utilization: make(map[string]float64),
+		}
+		for tres, used := range consumed {
+			dp.Usage[tres] = used
+		}
+		for tres, limit := range b.limits[limitGrpTRESMins] {
+			dp.Quotas[tres] = limit
+			dp.Utilization[tres] = ratio(consumed[tres], limit)
+		}
+		history.DataPoints = append(history.DataPoints, dp)
+	}
+
+	if !snap.records {
+		for _, sample := range c.samples[accountName] {
+			if !sample.at.Before(from) {
+				point(sample.at, sample.consumed)
+			}
+		}
+		return history, nil
+	}
+
+	// Work back from the current consumption to what was consumed before
+	// the period, then add the hours up again
+	hours := b.hoursSince(from)
+	consumed := make(tresCounts)
+	consumed.add(b.consumed)
+	for _, hour := range hours {
+		consumed.sub(b.hourly[hour])
+	}
+	for _, hour := range hours {
+		consumed.add(b.hourly[hour])
+		cumulative := make(tresCounts)
+		cumulative.add(consumed)
+		point(time.Unix(hour, 0).Add(time.Hour), cumulative)
+	}
+	return history, nil
+}
+
+// GetAccountResourceLimits reports the GrpTRES limits of an account with
+// what its running jobs use. Memory is in GB; storage is not tracked by
+// Slurm and is zero.
+func (c *AccountQuotaClient) GetAccountResourceLimits(ctx context.Context, accountName string) (*collector.AccountResourceLimits, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	grp := b.limits[limitGrpTRES]
+	return &collector.AccountResourceLimits{
+		AccountName:  accountName,
+		CPULimits:    collector.ResourceLimitSet{HardLimit: grp["cpu"], CurrentUsage: b.running["cpu"]},
+		MemoryLimits: collector.ResourceLimitSet{HardLimit: grp["mem"] / 1024, CurrentUsage: b.running["mem"] / 1024},
+		GPULimits:    collector.ResourceLimitSet{HardLimit: grp["gres/gpu"], CurrentUsage: b.running["gres/gpu"]},
+	}, nil
+}
+
+// GetEffectiveResourceLimits reports the tightest CPU, memory (in GB) and
+// running job limits on a user's jobs in an account: the user association's
+// own limits and the group limits of the account and its parents, which
+// the user's jobs share with everyone else's
+func (c *AccountQuotaClient) GetEffectiveResourceLimits(ctx context.Context, accountName string, userName string) (*collector.EffectiveResourceLimits, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	user := b.users[userName]
+	if user == nil {
+		return nil, fmt.Errorf("user %s has no association with account %s", userName, accountName)
+	}
+
+	limits := &collector.EffectiveResourceLimits{AccountName: accountName, UserName: userName}
+	tighten := func(source string, current *float64, value float64, ok bool) {
+		if ok && (*current == 0 || value < *current) {
+			*current = value
+			if !contains(limits.Sources, source) {
+				limits.Sources = append(limits.Sources, source)
+			}
+		}
+	}
+	apply := func(source string, assoc tresLimits, perUser bool) {
+		cpu, ok := assoc[limitGrpTRES]["cpu"]
+		tighten(source, &limits.CPULimit, cpu, ok)
+		mem, ok := assoc[limitGrpTRES]["mem"]
+		tighten(source, &limits.MemoryLimit, mem/1024, ok)
+		jobs := float64(limits.JobLimit)
+		grpJobs, ok := assoc[limitGrpJobs][jobsTRES]
+		tighten(source, &jobs, grpJobs, ok)
+		if perUser {
+			maxJobs, ok := assoc[limitMaxJobs][jobsTRES]
+			tighten(source, &jobs, maxJobs, ok)
+		}
+		limits.JobLimit = int(jobs)
+	}
+
+	userLimits, _ := associationLimits(user)
+	apply("user:"+userName, userLimits, true)
+	for _, account := range snap.lineage(accountName) {
+		// MaxJobs set on an account association applies to each of its users
+		apply("account:"+account, snap.budgets[account].limits, true)
+	}
+	return limits, nil
+}
+
+// GetAccountQuotaViolations reports the pending jobs of an account held by
+// association limits, and those released within the period. A violation
+// starts when the exporter first sees the job held and resolves when the
+// job no longer is.
+func (c *AccountQuotaClient) GetAccountQuotaViolations(ctx context.Context, accountName string, period string) ([]*collector.QuotaViolation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	window, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	now := snap.fetchedAt
+
+	var violations []*collector.QuotaViolation
+	for _, id := range sortedQuotaViolationIDs(c.violations) {
+		if violation := c.violations[id]; violation.Account == accountName {
+			copied := *violation
+			copied.Duration = now.Sub(violation.Timestamp)
+			violations = append(violations, &copied)
+		}
+	}
+	for _, resolved := range c.resolved {
+		if resolved.Account == accountName && now.Sub(*resolved.ResolvedAt) <= window {
+			copied := *resolved
+			violations = append(violations, &copied)
+		}
+	}
+	return violations, nil
+}
+
+// GetQuotaEnforcementStatus reports whether an account has limits slurmctld
+// enforces, and which limits currently hold its jobs. Slurm has no grace
+// periods.
+func (c *AccountQuotaClient) GetQuotaEnforcementStatus(ctx context.Context, accountName string) (*collector.QuotaEnforcementStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	status := &collector.QuotaEnforcementStatus{AccountName: accountName, EnforcementMode: b.enforcement()}
+	held := c.heldBy(accountName)
+	for reason := range held {
+		status.BlockedOperations = append(status.BlockedOperations, reason)
+	}
+	sort.Strings(status.BlockedOperations)
+	return status, nil
+}
+
+// GetAccountQuotaUtilization reports the GrpTRESMins budget of an account
+// that is furthest used up
+func (c *AccountQuotaClient) GetAccountQuotaUtilization(ctx context.Context, accountName string) (*collector.QuotaUtilization, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	utilization := &collector.QuotaUtilization{AccountName: accountName, TrendDirection: "stable"}
+	budgets := b.limits[limitGrpTRESMins]
+	for _, tres := range sortedTRES(budgets) {
+		limit := budgets[tres]
+		used := b.consumed[tres]
+		if utilization.ResourceType != "" && ratio(used, limit) <= utilization.Utilization {
+			continue
+		}
+		utilization.ResourceType = tres
+		utilization.CurrentUsage = used
+		utilization.QuotaLimit = limit
+		utilization.Utilization = ratio(used, limit)
+		utilization.TrendDirection = b.trend(tres)
+		utilization.ProjectedFull = exhaustion(snap.fetchedAt, limit, used, b.burn[tres])
+	}
+	return utilization, nil
+}
+
+// GetAccountQuotaTrends reports the daily TRES-minutes an account consumed
+// over the period, and what it will have consumed by the end of the same
+// period from now at the current burn rate
+func (c *AccountQuotaClient) GetAccountQuotaTrends(ctx context.Context, accountName string, period string) (*collector.QuotaTrends, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+	window, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	trends := &collector.QuotaTrends{
+		AccountName: accountName,
+		Period:      period,
+		TrendData:   make(map[string][]float64),
+		Predictions: make(map[string]float64),
+		Seasonality: make(map[string]bool),
+	}
+	days := int(math.Ceil(window.Hours() / 24))
+	from := snap.fetchedAt.Add(-time.Duration(days) * 24 * time.Hour)
+	for _, hour := range b.hoursSince(from) {
+		day := int(time.Unix(hour, 0).Sub(from).Hours() / 24)
+		for tres, minutes := range b.hourly[hour] {
+			if trends.TrendData[tres] == nil {
+				trends.TrendData[tres] = make([]float64, days)
+			}
+			trends.TrendData[tres][day] += minutes
+		}
+	}
+	for tres, rate := range b.burn {
+		trends.Predictions[tres] = b.consumed[tres] + rate*window.Hours()/24
+	}
+	return trends, nil
+}
+
+// GetAccountQuotaAlerts reports the GrpTRESMins budgets of an account that
+// are used up, nearly used up, or projected to run out before the next
+// usage reset
+func (c *AccountQuotaClient) GetAccountQuotaAlerts(ctx context.Context, accountName string) ([]*collector.QuotaAlert, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*collector.QuotaAlert
+	alert := func(kind string, level int, tres string, used, threshold float64, message string) {
+		alerts = append(alerts, &collector.QuotaAlert{
+			AlertID:      accountName + "/" + tres + "/" + kind,
+			AccountName:  accountName,
+			Type:         kind,
+			Level:        level,
+			Message:      message,
+			ResourceType: tres,
+			CurrentUsage: used,
+			Threshold:    threshold,
+			Timestamp:    snap.fetchedAt,
+		})
+	}
+	budgets := b.limits[limitGrpTRESMins]
+	for _, tres := range sortedTRES(budgets) {
+		limit := budgets[tres]
+		used := b.consumed[tres]
+		switch {
+		case used >= limit:
+			alert("limit_reached", 3, tres, used, limit,
+				fmt.Sprintf("%s GrpTRESMins budget of %.0f used up", tres, limit))
+			continue
+		case used >= quotaWarningRatio*limit:
+			alert("usage_threshold", 2, tres, used, quotaWarningRatio*limit,
+				fmt.Sprintf("%.0f%% of the %s GrpTRESMins budget used", 100*ratio(used, limit), tres))
+		}
+		if at := exhaustion(snap.fetchedAt, limit, used, b.burn[tres]); at != nil && at.Before(snap.horizon()) {
+			alert("projected_exhaustion", 1, tres, used, limit,
+				fmt.Sprintf("%s GrpTRESMins budget projected to run out %s", tres, at.Format(time.RFC3339)))
+		}
+	}
+	return alerts, nil
+}
+
+// GetAccountQuotaRecommendations recommends resizing the GrpTRESMins budgets
+// of an account to what it is projected to use by the next usage reset: an
+// increase for budgets that will run out, a decrease for budgets that will
+// be left more than half unused. Confidence is the share of the burn rate
+// window covered by usage data, and OptimizationScore how closely the
+// budgets fit the projected usage.
+func (c *AccountQuotaClient) GetAccountQuotaRecommendations(ctx context.Context, accountName string) (*collector.QuotaRecommendations, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snap.budget(accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := &collector.QuotaRecommendations{AccountName: accountName, OptimizationScore: 1}
+	budgets := b.limits[limitGrpTRESMins]
+	var fits []float64
+	for _, tres := range sortedTRES(budgets) {
+		limit := budgets[tres]
+		rate, ok := b.burn[tres]
+		if !ok || limit <= 0 {
+			continue
+		}
+		projected := b.consumed[tres] + rate*snap.horizon().Sub(snap.fetchedAt).Hours()/24
+		fits = append(fits, math.Min(projected, limit)/math.Max(projected, limit))
+
+		recommendation := collector.QuotaRecommendation{
+			Account:      accountName,
+			CurrentValue: limit,
+			Confidence:   b.coverage,
+		}
+		switch {
+		case projected > limit:
+			recommendation.Type = "increase_quota/" + tres
+			recommendation.Priority = "high"
+			recommendation.RecommendedValue = math.Ceil(projected)
+			recommendation.Reason = fmt.Sprintf("projected to use %.0f %s TRES-minutes, over the budget", projected, tres)
+		case !snap.nextReset.IsZero() && projected < limit/2:
+			// Unused budget is only lost when usage is reset
+			recommendation.Type = "decrease_quota/" + tres
+			recommendation.Priority = "low"
+			recommendation.RecommendedValue = math.Ceil(projected / quotaWarningRatio)
+			recommendation.Reason = fmt.Sprintf("projected to use %.0f %s TRES-minutes, under half the budget", projected, tres)
+			recommendation.PotentialSavings = limit - recommendation.RecommendedValue
+			recommendations.PotentialSavings += recommendation.PotentialSavings
+		default:
+			continue
+		}
+		recommendation.ImplementationSteps = []string{
+			fmt.Sprintf("sacctmgr modify account %s set GrpTRESMins=%s=%.0f", accountName, tres, recommendation.RecommendedValue),
+		}
+		recommendations.Recommendations = append(recommendations.Recommendations, recommendation)
+	}
+	if len(fits) > 0 {
+		recommendations.OptimizationScore, _ = meanVariance(fits)
+	}
+	return recommendations, nil
+}
+
+// refresh returns the current snapshot, fetching a new one once the TTL has
+// expired. The caller must hold c.mu.
+func (c *AccountQuotaClient) refresh(ctx context.Context) (*accountQuotaSnapshot, error) {
+	now := c.now()
+	if c.snapshot != nil && now.Sub(c.snapshot.fetchedAt) < c.opts.SnapshotTTL {
+		return c.snapshot, nil
+	}
+
+	manager := c.client.Associations()
+	if manager == nil {
+		return nil, fmt.Errorf("associations endpoint not available")
+	}
+	assocList, err := manager.List(ctx, &slurm.ListAssociationsOptions{WithUsage: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list associations: %w", err)
+	}
+
+	snap := &accountQuotaSnapshot{
+		fetchedAt: now,
+		budgets:   make(map[string]*accountBudget),
+		parents:   make(map[string]string),
+	}
+	snap.lastReset, snap.nextReset = usageResetWindow(now, c.opts.UsageResetPeriod)
+	if assocList != nil {
+		snap.addAssociations(assocList.Associations)
+	}
+
+	// Fair-share usage stands in for the usage records when slurmrestd
+	// returns none
+	if !snap.records {
+		if shares, err := c.client.GetShares(ctx, nil); err != nil {
+			logrus.WithError(err).Debug("Account quotas continuing without fair-share usage")
+		} else if shares != nil {
+			snap.addShares(shares.Shares)
+		}
+	}
+
+	if jobList, err := c.client.Jobs().List(ctx, nil); err != nil {
+		logrus.WithError(err).Debug("Account quotas continuing without jobs")
+	} else if jobList != nil {
+		for i := range jobList.Jobs {
+			snap.addJob(&jobList.Jobs[i])
+		}
+	}
+
+	c.observe(snap)
+	c.snapshot = snap
+	return snap, nil
+}
+
+// observe tracks limit changes, consumption, submitted and finished jobs and
+// held jobs across snapshots, and derives the burn rates. The caller must
+// hold c.mu.
+func (c *AccountQuotaClient) observe(snap *accountQuotaSnapshot) {
+	now := snap.fetchedAt
+	baseline := c.snapshot == nil
+
+	for name, b := range snap.budgets {
+		if version := b.version(); c.versions[name].version != version {
+			c.versions[name] = accountQuotaVersion{version: version, number: c.versions[name].number + 1, since: now}
+		}
+		c.sample(name, now, b.consumed)
+		if snap.records {
+			b.burnFromRecords(now, c.opts.BurnRateWindow)
+		} else {
+			b.burnFromSamples(c.samples[name], c.opts.BurnRateWindow)
+		}
+	}
+	for name := range c.versions {
+		if _, ok := snap.budgets[name]; !ok {
+			delete(c.versions, name)
+			delete(c.samples, name)
+		}
+	}
+
+	// Jobs that were submitted or finished before the first snapshot are not
+	// counted
+	seen := make(map[string]bool)
+	held := make(map[string]*tresJob)
+	for _, job := range snap.jobs {
+		seen[job.id] = true
+		if job.state == string(api.JobStatePending) && strings.HasPrefix(job.reason, "Assoc") {
+			held[job.id+"/"+job.reason] = job
+		}
+		if !c.known[job.id] {
+			c.known[job.id] = true
+			if !baseline {
+				c.activityOf(job.account).submitted++
+			}
+		}
+		if !isFinishedState(job.state) || c.finished[job.id] {
+			continue
+		}
+		c.finished[job.id] = true
+		if baseline {
+			continue
+		}
+		switch {
+		case job.state == string(api.JobStateCompleted):
+			c.activityOf(job.account).completed++
+		case isFailedState(job.state):
+			c.activityOf(job.account).failed++
+		}
+	}
+	for id := range c.known {
+		if !seen[id] {
+			delete(c.known, id)
+			delete(c.finished, id)
+		}
+	}
+
+	for id, job := range held {
+		if _, ok := c.violations[id]; ok {
+			continue
+		}
+		violation := &collector.QuotaViolation{
+			Account:            job.account,
+			UserName:           job.user,
+			JobID:              job.id,
+			Timestamp:          now,
+			Type:               quotaViolationResource(job.reason),
+			ViolationType:      job.reason,
+			Severity:           quotaViolationSeverity(job.reason),
+			Message:            fmt.Sprintf("job %s pending on %s", job.id, job.reason),
+			Impact:             fmt.Sprintf("job %s pending", job.id),
+			ResourceType:       quotaViolationResource(job.reason),
+			QuotaType:          quotaViolationScope(job.reason),
+			ResolutionRequired: strings.Contains(job.reason, "Minutes"),
+			FirstOccurrence:    now,
+			LastOccurrence:     now,
+		}
+		// The same user held by the same limit before
+		for _, resolved := range c.resolved {
+			if resolved.Account == violation.Account && resolved.UserName == violation.UserName &&
+				resolved.ViolationType == violation.ViolationType {
+				violation.RecurrenceCount++
+				if resolved.FirstOccurrence.Before(violation.FirstOccurrence) {
+					violation.FirstOccurrence = resolved.FirstOccurrence
+				}
+			}
+		}
+		c.violations[id] = violation
+	}
+	for id, violation := range c.violations {
+		if _, ok := held[id]; ok {
+			continue
+		}
+		delete(c.violations, id)
+		resolved := *violation
+		resolved.ResolvedAt = &now
+		resolved.Duration = now.Sub(violation.Timestamp)
+		// The limit freed up when the job started or moved on to another
+		// pending reason; anything else took the job out of the queue
+		job := snap.job(violation.JobID)
+		switch {
+		case job == nil:
+			resolved.ResolvedBy = "left_queue"
+		case job.state == string(api.JobStatePending):
+			resolved.ResolvedBy = "released"
+			resolved.AutoResolved = true
+		case job.hasStarted(now):
+			resolved.ResolvedBy = "started"
+			resolved.AutoResolved = true
+		default:
+			resolved.ResolvedBy = strings.ToLower(job.state)
+		}
+		c.resolved = append(c.resolved, &resolved)
+	}
+
+	for len(c.resolved) > 0 && (len(c.resolved) > maxQuotaViolations || now.Sub(*c.resolved[0].ResolvedAt) > quotaViolationRetention) {
+		c.resolved = c.resolved[1:]
+	}
+}
+
+// sample records what an account had consumed, starting over when the
+// consumption went down because usage was reset or decayed. The caller must
+// hold c.mu.
+func (c *AccountQuotaClient) sample(name string, now time.Time, consumed tresCounts) {
+	samples := c.samples[name]
+	if n := len(samples); n > 0 {
+		for tres, used := range samples[n-1].consumed {
+			if consumed[tres] < used {
+				samples = nil
+				break
+			}
+		}
+	}
+	copied := make(tresCounts)
+	copied.add(consumed)
+	samples = append(samples, consumptionSample{at: now, consumed: copied})
+
+	// Keep the newest sample at or before the start of the window
+	cutoff := now.Add(-c.opts.BurnRateWindow)
+	for len(samples) > 1 && !samples[1].at.After(cutoff) {
+		samples = samples[1:]
+	}
+	c.samples[name] = samples
+}
+
+// activityOf returns the job counts of an account, creating them. The
+// caller must hold c.mu.
+func (c *AccountQuotaClient) activityOf(account string) *accountActivity {
+	activity := c.activity[account]
+	if activity == nil {
+		activity = &accountActivity{}
+		c.activity[account] = activity
+	}
+	return activity
+}
+
+// heldBy counts the active violations of an account by state reason. The
+// caller must hold c.mu.
+func (c *AccountQuotaClient) heldBy(account string) map[string]int {
+	held := make(map[string]int)
+	for _, violation := range c.violations {
+		if violation.Account == account {
+			held[violation.ViolationType]++
+		}
+	}
+	return held
+}
+
+// addAssociations reads the account associations as budgets and adds up the
+// usage records of the user associations, or of the account association
+// itself for accounts without user records. Sub-account usage counts
+// against the parent's budget.
+func (s *accountQuotaSnapshot) addAssociations(assocs []slurm.Association) {
+	for i := range assocs {
+		assoc := &assocs[i]
+		account := stringValue(assoc.Account)
+		if account == "" || assoc.User != "" || stringValue(assoc.Partition) != "" || isDeletedAssociation(assoc) {
+			continue
+		}
+		b := &accountBudget{assoc: assoc, users: make(map[string]*slurm.Association)}
+		b.limits, b.maxWall = associationLimits(assoc)
+		b.reset()
+		s.budgets[account] = b
+		if parent := stringValue(assoc.ParentAccount); parent != "" && parent != account {
+			s.parents[account] = parent
+		}
+	}
+
+	direct := make(map[string]*accountUsage)
+	usageOf := func(account string) *accountUsage {
+		usage := direct[account]
+		if usage == nil {
+			usage = newAccountUsage()
+			direct[account] = usage
+		}
+		return usage
+	}
+	for i := range assocs {
+		assoc := &assocs[i]
+		account := stringValue(assoc.Account)
+		b := s.budgets[account]
+		if b == nil || assoc.User == "" || isDeletedAssociation(assoc) {
+			continue
+		}
+		// The association without a partition stands for the user
+		if b.users[assoc.User] == nil || stringValue(assoc.Partition) == "" {
+			b.users[assoc.User] = assoc
+		}
+		if usageOf(account).addRecords(assoc.User, assoc.Accounting, s.lastReset) {
+			s.records = true
+		}
+	}
+	for account, b := range s.budgets {
+		if usage := usageOf(account); len(usage.hourly) == 0 && usage.addRecords("", b.assoc.Accounting, s.lastReset) {
+			s.records = true
+		}
+	}
+
+	for account, usage := range direct {
+		for _, name := range s.lineage(account) {
+			s.budgets[name].accountUsage.add(usage)
+		}
+	}
+}
+
+// addShares reads what the accounts consumed from their raw fair-share
+// usage, in billing-weighted TRES-seconds. Budgets on CPU-minutes alone,
+// the common case, are burnt down by it as well.
+func (s *accountQuotaSnapshot) addShares(shares []slurm.Share) {
+	for _, share := range shares {
+		b := s.budgets[share.Account]
+		if b == nil || share.Partition != "" {
+			continue
+		}
+		minutes := float64(share.RawUsage) / 60
+		consumed := b.consumed
+		if share.User != "" {
+			consumed = b.byUser[share.User]
+			if consumed == nil {
+				consumed = make(tresCounts)
+				b.byUser[share.User] = consumed
+			}
+		}
+		consumed["billing"] = minutes
+		budgets := b.limits[limitGrpTRESMins]
+		if _, ok := budgets["billing"]; !ok {
+			if _, ok := budgets["cpu"]; ok {
+				consumed["cpu"] = minutes
+			}
+		}
+	}
+}
+
+// addJob counts a job against its account and the accounts above it
+func (s *accountQuotaSnapshot) addJob(job *slurm.Job) {
+	tj := &tresJob{queueJob: newQueueJob(job)}
+	if tj.id == "" {
+		return
+	}
+	s.jobs = append(s.jobs, tj)
+
+	running := tj.state == string(api.JobStateRunning)
+	switch {
+	case running:
+		tj.tres = jobTRES(tj.cpus, job.TRESAllocStr, job.TRESReqStr)
+	case tj.state == string(api.JobStatePending):
+		tj.tres = jobTRES(tj.cpus, job.TRESReqStr)
+	}
+
+	// Running jobs have the TRES-minutes left to their time limit reserved
+	var left float64
+	if running && tj.end.After(s.fetchedAt) {
+		left = tj.end.Sub(s.fetchedAt).Minutes()
+	}
+	for _, account := range s.lineage(tj.account) {
+		b := s.budgets[account]
+		b.jobs = append(b.jobs, tj)
+		switch {
+		case running:
+			b.running.add(tj.tres)
+			for tres, count := range tj.tres {
+				if tres != jobsTRES {
+					b.runMinutes[tres] += count * left
+				}
+			}
+		case tj.state == string(api.JobStatePending):
+			b.pending.add(tj.tres)
+		}
+	}
+}
+
+// budgetQuota reports a GrpTRESMins budget. Running jobs reserve the
+// TRES-minutes they have left; the projection runs to the next usage reset.
+func (s *accountQuotaSnapshot) budgetQuota(b *accountBudget, tres string, limit float64) *collector.ResourceQuota {
+	used := b.consumed[tres]
+	rate := b.burn[tres]
+	quota := &collector.ResourceQuota{
+		Limit:               limit,
+		Used:                used,
+		Reserved:            b.runMinutes[tres],
+		Available:           math.Max(0, limit-used-b.runMinutes[tres]),
+		UtilizationRate:     ratio(used, limit),
+		ProjectedUsage:      used + rate*s.horizon().Sub(s.fetchedAt).Hours()/24,
+		ThresholdPercent:    100 * quotaWarningRatio,
+		BurnRate:            rate,
+		ProjectedExhaustion: exhaustion(s.fetchedAt, limit, used, rate),
+	}
+	if !s.nextReset.IsZero() {
+		reset := s.nextReset
+		quota.ExpiresAt = &reset
+	}
+	return quota
+}
+
+// usageStats reports the consumption of a TRES since the last reset in the
+// given units, with the statistics of its hourly usage
+func (s *accountQuotaSnapshot) usageStats(b *accountBudget, tres string, scale float64) collector.ResourceUsageStats {
+	limit := b.limits[limitGrpTRESMins][tres]
+	used := b.consumed[tres]
+	stats := collector.ResourceUsageStats{
+		Total:           used * scale,
+		Used:            used * scale,
+		Reserved:        b.runMinutes[tres] * scale,
+		UtilizationRate: ratio(used, limit),
+	}
+	if limit > 0 {
+		stats.Available = math.Max(0, limit-used-b.runMinutes[tres]) * scale
+	}
+	var hourly []float64
+	for _, hour := range b.hoursSince(s.lastReset) {
+		hourly = append(hourly, b.hourly[hour][tres]*scale)
+	}
+	sort.Float64s(hourly)
+	mean, variance := meanVariance(hourly)
+	stats.PeakUsage = percentile(hourly, 1)
+	stats.AverageUsage = mean
+	stats.StandardDev = math.Sqrt(variance)
+	return stats
+}
+
+// horizon is how far usage is projected: the next usage reset, if any
+func (s *accountQuotaSnapshot) horizon() time.Time {
+	if s.nextReset.IsZero() {
+		return s.fetchedAt.Add(quotaProjectionHorizon)
+	}
+	return s.nextReset
+}
+
+func (s *accountQuotaSnapshot) budget(name string) (*accountBudget, error) {
+	b, ok := s.budgets[name]
+	if !ok {
+		return nil, fmt.Errorf("account %s not found", name)
+	}
+	return b, nil
+}
+
+func (s *accountQuotaSnapshot) accountNames() []string {
+	names := make([]string, 0, len(s.budgets))
+	for name := range s.budgets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lineage returns an account and the accounts above it that have budgets
+func (s *accountQuotaSnapshot) lineage(account string) []string {
+	var names []string
+	visited := make(map[string]bool)
+	for account != "" && !visited[account] {
+		visited[account] = true
+		if _, ok := s.budgets[account]; ok {
+			names = append(names, account)
+		}
+		account = s.parents[account]
+	}
+	return names
+}
+
+func (s *accountQuotaSnapshot) job(id string) *tresJob {
+	for _, job := range s.jobs {
+		if job.id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// reset clears what the account uses
+func (b *accountBudget) reset() {
+	b.accountUsage = *newAccountUsage()
+	b.running = make(tresCounts)
+	b.pending = make(tresCounts)
+	b.runMinutes = make(tresCounts)
+	b.burn = make(tresCounts)
+	b.previous = make(tresCounts)
+}
+
+// burnFromRecords averages the hourly usage over the burn rate window, or
+// over the part of it since the first record for new accounts, along with
+// the window before it
+func (b *accountBudget) burnFromRecords(now time.Time, window time.Duration) {
+	hours := b.hoursSince(now.Add(-2 * window))
+	if len(hours) == 0 {
+		return
+	}
+	from := now.Add(-window)
+	if first := time.Unix(hours[0], 0); first.After(from) {
+		from = first
+	}
+	span := math.Max(now.Sub(from).Hours(), 1)
+	b.coverage = math.Min(1, span/window.Hours())
+	for _, hour := range hours {
+		at := time.Unix(hour, 0)
+		for tres, minutes := range b.hourly[hour] {
+			if at.Before(now.Add(-window)) {
+				b.previous[tres] += minutes / (window.Hours() / 24)
+			} else if !at.Before(from) {
+				b.burn[tres] += minutes / (span / 24)
+			}
+		}
+	}
+}
+
+// burnFromSamples derives the burn rate from how the consumption grew
+// between the first and last samples in the burn rate window
+func (b *accountBudget) burnFromSamples(samples []consumptionSample, window time.Duration) {
+	if len(samples) < 2 {
+		return
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	days := last.at.Sub(first.at).Hours() / 24
+	if days <= 0 {
+		return
+	}
+	b.coverage = math.Min(1, last.at.Sub(first.at).Hours()/window.Hours())
+	for tres, used := range last.consumed {
+		b.burn[tres] = math.Max(0, used-first.consumed[tres]) / days
+	}
+}
+
+// trend compares the burn rate of a TRES with the window before
+func (b *accountBudget) trend(tres string) string {
+	current, previous := b.burn[tres], b.previous[tres]
+	switch {
+	case previous == 0 && current == 0:
+		return "stable"
+	case current > previous*(1+quotaTrendTolerance):
+		return "increasing"
+	case current < previous*(1-quotaTrendTolerance):
+		return "decreasing"
+	default:
+		return "stable"
+	}
+}
+
+// budgetTRES returns the TRES users' shares of the account usage are
+// reported in: billing when it is budgeted, CPUs otherwise
+func (b *accountBudget) budgetTRES() string {
+	if _, ok := b.limits[limitGrpTRESMins]["billing"]; ok {
+		return "billing"
+	}
+	return "cpu"
+}
+
+// enforcement reports whether slurmctld enforces limits on the account
+func (b *accountBudget) enforcement() string {
+	for _, counts := range b.limits {
+		if len(counts) > 0 {
+			return "hard"
+		}
+	}
+	if b.maxWall > 0 {
+		return "hard"
+	}
+	return "disabled"
+}
+
+// version identifies the limits in the quota version
+func (b *accountBudget) version() string {
+	h := fnv.New32a()
+	names := make([]string, 0, len(b.limits))
+	for name := range b.limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, tres := range sortedTRES(b.limits[name]) {
+			fmt.Fprint(h, name, tres, b.limits[name][tres])
+		}
+	}
+	fmt.Fprint(h, b.maxWall)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// hoursSince returns the hours with usage records from the given time on,
+// in order
+func (b *accountBudget) hoursSince(from time.Time) []int64 {
+	hours := make([]int64, 0, len(b.hourly))
+	for hour := range b.hourly {
+		if !time.Unix(hour, 0).Before(from) {
+			hours = append(hours, hour)
+		}
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i] < hours[j] })
+	return hours
+}
+
+func newAccountUsage() *accountUsage {
+	return &accountUsage{
+		consumed: make(tresCounts),
+		byUser:   make(map[string]tresCounts),
+		hourly:   make(map[int64]tresCounts),
+	}
+}
+
+// addRecords adds the usage records of an association, counting those since
+// the last reset as consumed, and reports whether there were any
+func (u *accountUsage) addRecords(user string, records []api.Accounting, lastReset time.Time) bool {
+	for _, record := range records {
+		if record.TRES == nil || record.Allocated == nil || record.Allocated.Seconds == nil || record.Start == nil {
+			continue
+		}
+		tres := tresName(*record.TRES)
+		minutes := float64(*record.Allocated.Seconds) / 60
+		hour := time.Unix(*record.Start, 0).Truncate(time.Hour).Unix()
+		if u.hourly[hour] == nil {
+			u.hourly[hour] = make(tresCounts)
+		}
+		u.hourly[hour][tres] += minutes
+		if time.Unix(*record.Start, 0).Before(lastReset) {
+			continue
+		}
+		u.consumed[tres] += minutes
+		if user != "" {
+			if u.byUser[user] == nil {
+				u.byUser[user] = make(tresCounts)
+			}
+			u.byUser[user][tres] += minutes
+		}
+	}
+	return len(records) > 0
+}
+
+// add adds the usage of a sub-account or user
+func (u *accountUsage) add(other *accountUsage) {
+	u.consumed.add(other.consumed)
+	for user, consumed := range other.byUser {
+		if u.byUser[user] == nil {
+			u.byUser[user] = make(tresCounts)
+		}
+		u.byUser[user].add(consumed)
+	}
+	for hour, minutes := range other.hourly {
+		if u.hourly[hour] == nil {
+			u.hourly[hour] = make(tresCounts)
+		}
+		u.hourly[hour].add(minutes)
+	}
+}
+
+func (t tresCounts) add(other tresCounts) {
+	for name, count := range other {
+		t[name] += count
+	}
+}
+
+func (t tresCounts) sub(other tresCounts) {
+	for name, count := range other {
+		t[name] -= count
+	}
+}
+
+// associationLimits reads the limits of an association and its maximum
+// wall time per job
+func associationLimits(assoc *slurm.Association) (tresLimits, time.Duration) {
+	limits := make(tresLimits)
+	var maxWall time.Duration
+	max := assoc.Max
+	if max == nil {
+		return limits, maxWall
+	}
+	if max.TRES != nil {
+		limits.setTRES(limitGrpTRES, max.TRES.Total)
+		if max.TRES.Group != nil {
+			limits.setTRES(limitGrpTRESMins, max.TRES.Group.Minutes)
+			limits.setTRES(limitGrpTRESRunMins, max.TRES.Group.Active)
+		}
+	}
+	if max.Jobs != nil {
+		limits.setJobs(limitMaxJobs, max.Jobs.Active)
+		limits.setJobs(limitMaxSubmitJobs, max.Jobs.Total)
+		if per := max.Jobs.Per; per != nil {
+			limits.setJobs(limitGrpJobs, per.Count)
+			limits.setJobs(limitGrpSubmitJobs, per.Submitted)
+			if per.WallClock != nil {
+				maxWall = time.Duration(*per.WallClock) * time.Minute
+			}
+		}
+	}
+	return limits, maxWall
+}
+
+func isDeletedAssociation(assoc *slurm.Association) bool {
+	for _, flag := range assoc.Flags {
+		if flag == api.AssociationDefaultFlagsDeleted {
+			return true
+		}
+	}
+	return false
+}
+
+// concurrentQuota reports a limit on what running jobs hold at once
+func concurrentQuota(limit, used float64) *collector.ResourceQuota {
+	return &collector.ResourceQuota{
+		Limit:            limit,
+		Used:             used,
+		Available:        math.Max(0, limit-used),
+		UtilizationRate:  ratio(used, limit),
+		ThresholdPercent: 100 * quotaWarningRatio,
+	}
+}
+
+// scaleQuota converts a quota to other units
+func scaleQuota(quota *collector.ResourceQuota, scale float64) *collector.ResourceQuota {
+	scaled := *quota
+	scaled.Limit *= scale
+	scaled.Used *= scale
+	scaled.Reserved *= scale
+	scaled.Available *= scale
+	scaled.ProjectedUsage *= scale
+	scaled.BurnRate *= scale
+	return &scaled
+}
+
+// exhaustion projects when a budget runs out at a burn rate per day: now
+// when it already has, never when nothing is burning
+func exhaustion(now time.Time, limit, used, rate float64) *time.Time {
+	switch {
+	case used >= limit:
+		return &now
+	case rate <= 0:
+		return nil
+	}
+	at := now.Add(time.Duration((limit - used) / rate * float64(24*time.Hour)))
+	return &at
+}
+
+// usageResetWindow returns when usage was last reset and when it will be
+// next for a PriorityUsageResetPeriod, both zero when it is never reset.
+// Weeks start on Sunday, as they do for slurmctld.
+func usageResetWindow(now time.Time, period string) (time.Time, time.Time) {
+	year, month, day := now.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(period) {
+	case "daily":
+		return midnight, midnight.AddDate(0, 0, 1)
+	case "weekly":
+		last := midnight.AddDate(0, 0, -int(now.Weekday()))
+		return last, last.AddDate(0, 0, 7)
+	case "monthly":
+		last := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return last, last.AddDate(0, 1, 0)
+	case "quarterly":
+		last := time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, now.Location())
+		return last, last.AddDate(0, 3, 0)
+	case "yearly":
+		last := time.Date(year, 1, 1, 0, 0, 0, 0, now.Location())
+		return last, last.AddDate(1, 0, 0)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
+
+// parsePeriod parses a period such as "24h", "7d" or "30d"
+func parsePeriod(period string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(period, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(period); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid period %q", period)
+}
+
+// quotaViolationResource returns the TRES an association state reason such
+// as AssocGrpCPUMinutesLimit is about
+func quotaViolationResource(reason string) string {
+	for _, resource := range []struct{ marker, name string }{
+		{"Wall", "wall"},
+		{"CPU", "cpu"},
+		{"Cpu", "cpu"},
+		{"GRES", "gres"},
+		{"Mem", "mem"},
+		{"Node", "node"},
+		{"Billing", "billing"},
+		{"Energy", "energy"},
+		{"Jobs", jobsTRES},
+	} {
+		if strings.Contains(reason, resource.marker) {
+			return resource.name
+		}
+	}
+	return "other"
+}
+
+// quotaViolationSeverity rates an association state reason: an exhausted
+// TRES-minute budget holds the account's jobs until it is raised or reset,
+// other limits only until running jobs finish
+func quotaViolationSeverity(reason string) string {
+	if strings.Contains(reason, "Minutes") && !strings.Contains(reason, "RunMinutes") {
+		return "critical"
+	}
+	return "warning"
+}
+
+// quotaViolationScope returns whether an association limit applies to the
+// whole account or to each user
+func quotaViolationScope(reason string) string {
+	if strings.HasPrefix(reason, "AssocGrp") {
+		return "account"
+	}
+	return "user"
+}
+
+func sortedQuotaViolationIDs(violations map[string]*collector.QuotaViolation) []string {
+	ids := make([]string, 0, len(violations))
+	for id := range violations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Ensure AccountQuotaClient satisfies the collector interfaces
+var (
+	_ collector.AccountQuotaSLURMClient = (*AccountQuotaClient)(nil)
+)
@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+// quotaTestRecord is an hourly usage record of TRES-minutes
+func quotaTestRecord(kind, name string, minutes int64, at time.Time) api.Accounting {
+	tres := qosTestTRES(kind, name, 0)
+	allocated := minutes * 60
+	start := at.Unix()
+	return api.Accounting{TRES: &tres, Allocated: &api.AccountingAllocated{Seconds: &allocated}, Start: &start}
+}
+
+func quotaTestAccount(account, parent string, budget ...api.TRES) slurm.Association {
+	assoc := slurm.Association{Account: &account}
+	if parent != "" {
+		assoc.ParentAccount = &parent
+	}
+	if len(budget) > 0 {
+		assoc.Max = &api.AssociationMax{TRES: &api.AssociationMaxTRES{Group: &api.AssociationMaxTRESGroup{Minutes: budget}}}
+	}
+	return assoc
+}
+
+func quotaTestUser(user, account string, records ...api.Accounting) slurm.Association {
+	return slurm.Association{User: user, Account: &account, Accounting: records}
+}
+
+// newAccountQuotaTestClient returns a client whose associations, fair-share
+// usage and jobs are produced at the current test time, which advances by
+// setTime
+func newAccountQuotaTestClient(
+	t *testing.T,
+	opts *AccountQuotaOptions,
+	assocs func(now time.Time) []slurm.Association,
+	shares func(now time.Time) []slurm.Share,
+	jobs func(now time.Time) []slurm.Job,
+) (*AccountQuotaClient, func(time.Time)) {
+	t.Helper()
+	clock, setTime := newTestClock()
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(mockJobList(jobs, clock))
+	client.On("Associations").Return(qosTestAssociations{list: func() []slurm.Association { return assocs(clock()) }})
+	client.On("GetShares", mock.Anything, mock.Anything).Return(
+		func(context.Context, *slurm.GetSharesOptions) *slurm.SharesList {
+			return &slurm.SharesList{Shares: shares(clock())}
+		}, nil)
+
+	c := NewAccountQuotaClient(client, opts)
+	c.now = clock
+	return c, setTime
+}
+
+// quotaTestAssociationsWithUsage returns research, budgeted 100000 CPU-minutes
+// and 6000 GPU-minutes, whose users burnt 6000 CPU-minutes and 300
+// GPU-minutes a day over the last week on top of 20000 CPU-minutes before,
+// 3000 of them in its ml sub-account
+func quotaTestAssociationsWithUsage(time.Time) []slurm.Association {
+	research := quotaTestAccount("research", "root", qosTestTRES("cpu", "", 100000), qosTestTRES("gres", "gpu", 6000))
+	research.Max.TRES.Total = []api.TRES{qosTestTRES("cpu", "", 64)}
+	grpJobs := uint32(10)
+	research.Max.Jobs = &api.AssociationMaxJobs{Per: &api.AssociationMaxJobsPer{Count: &grpJobs}}
+
+	alice := quotaTestUser("alice", "research", quotaTestRecord("cpu", "", 17000, queueTestNow.Add(-10*24*time.Hour)))
+	bob := quotaTestUser("bob", "research")
+	for day := 1; day <= 7; day++ {
+		at := queueTestNow.Add(-time.Duration(24*day-1) * time.Hour)
+		alice.Accounting = append(alice.Accounting,
+			quotaTestRecord("cpu", "", 4000, at), quotaTestRecord("gres", "gpu", 300, at))
+		bob.Accounting = append(bob.Accounting, quotaTestRecord("cpu", "", 2000, at))
+	}
+	carol := quotaTestUser("carol", "ml", quotaTestRecord("cpu", "", 3000, queueTestNow.Add(-10*24*time.Hour)))
+
+	return []slurm.Association{research, quotaTestAccount("ml", "research"), alice, bob, carol}
+}
+
+func quotaTestRunningJob(now time.Time) []slurm.Job {
+	job := qosTestJob(1, "alice", "normal", "RUNNING", "cpu=16,mem=64G,node=1,gres/gpu=2", "")
+	job.EndTime = now.Add(2 * time.Hour)
+	return []slurm.Job{job}
+}
+
+func TestAccountQuotaClient_BudgetBurnDown(t *testing.T) {
+	t.Parallel()
+	c, _ := newAccountQuotaTestClient(t, nil, quotaTestAssociationsWithUsage,
+		func(time.Time) []slurm.Share { return nil }, quotaTestRunningJob)
+	ctx := context.Background()
+
+	all, err := c.GetAllAccountQuotas(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "ml", all[0].AccountName)
+	assert.Equal(t, "research", all[0].ParentAccount)
+	assert.Empty(t, all[0].TRESMinutes)
+
+	quotas := all[1]
+	cpu := quotas.TRESMinutes["cpu"]
+	require.NotNil(t, cpu)
+	assert.Same(t, cpu, quotas.CPUMinutes)
+	assert.Equal(t, 100000.0, cpu.Limit)
+	assert.Equal(t, 62000.0, cpu.Used)
+	assert.Equal(t, 16.0*120, cpu.Reserved)
+	assert.Equal(t, 100000.0-62000-1920, cpu.Available)
+	assert.InDelta(t, 0.62, cpu.UtilizationRate, 1e-9)
+	assert.InDelta(t, 6000, cpu.BurnRate, 1e-6)
+	assert.InDelta(t, 62000+6000*30, cpu.ProjectedUsage, 1e-3)
+	require.NotNil(t, cpu.ProjectedExhaustion)
+	assert.WithinDuration(t, queueTestNow.Add(time.Duration(38000.0/6000*24*float64(time.Hour))), *cpu.ProjectedExhaustion, time.Second)
+
+	require.NotNil(t, quotas.GPUHours)
+	assert.InDelta(t, 100, quotas.GPUHours.Limit, 1e-9)
+	assert.InDelta(t, 35, quotas.GPUHours.Used, 1e-9)
+	assert.InDelta(t, 5, quotas.GPUHours.BurnRate, 1e-6)
+	assert.Equal(t, 64.0, quotas.CPUCores.Limit)
+	assert.Equal(t, 16.0, quotas.CPUCores.Used)
+	assert.Equal(t, 10, quotas.MaxJobs.Limit)
+	assert.Equal(t, 1, quotas.MaxJobs.Current)
+	assert.Equal(t, 1, quotas.QuotaVersion)
+	assert.True(t, quotas.QuotaResetDate.IsZero())
+
+	// Usage ran at under half the rate the week before
+	usage, err := c.GetAccountQuotaUsage(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, "total", usage.Period)
+	assert.Equal(t, 62000.0, usage.CPUUsage.Total)
+	assert.Equal(t, "increasing", usage.UsageTrend)
+	assert.InDelta(t, 6000, usage.GrowthRate, 1e-6)
+	assert.InDelta(t, 45000.0/62000, usage.UserQuotaShares["alice"], 1e-9)
+	assert.Equal(t, 1, usage.ActiveUsers)
+	assert.Equal(t, 2, usage.TotalUsers)
+
+	alerts, err := c.GetAccountQuotaAlerts(ctx, "research")
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "projected_exhaustion", alerts[0].Type)
+	assert.Equal(t, "cpu", alerts[0].ResourceType)
+
+	recommendations, err := c.GetAccountQuotaRecommendations(ctx, "research")
+	require.NoError(t, err)
+	require.NotEmpty(t, recommendations.Recommendations)
+	assert.Equal(t, "increase_quota/cpu", recommendations.Recommendations[0].Type)
+	assert.InDelta(t, 242000, recommendations.Recommendations[0].RecommendedValue, 1)
+	assert.Equal(t, 1.0, recommendations.Recommendations[0].Confidence)
+	assert.Less(t, recommendations.OptimizationScore, 1.0)
+
+	trends, err := c.GetAccountQuotaTrends(ctx, "research", "7d")
+	require.NoError(t, err)
+	require.Len(t, trends.TrendData["cpu"], 7)
+	var week float64
+	for _, minutes := range trends.TrendData["cpu"] {
+		week += minutes
+	}
+	assert.Equal(t, 42000.0, week)
+	assert.InDelta(t, 62000+6000*7, trends.Predictions["cpu"], 1e-3)
+
+	history, err := c.GetAccountQuotaHistory(ctx, "research", "24h")
+	require.NoError(t, err)
+	require.Len(t, history.DataPoints, 1)
+	assert.Equal(t, 62000.0, history.DataPoints[0].Usage["cpu"])
+	assert.InDelta(t, 0.62, history.DataPoints[0].Utilization["cpu"], 1e-9)
+
+	_, err = c.GetAccountQuotas(ctx, "missing")
+	assert.Error(t, err)
+}
+
+func TestAccountQuotaClient_SharesFallback(t *testing.T) {
+	t.Parallel()
+	opts := DefaultAccountQuotaOptions()
+	opts.UsageResetPeriod = "monthly"
+	c, setTime := newAccountQuotaTestClient(t, opts,
+		func(now time.Time) []slurm.Association {
+			budget := int64(50000)
+			if now.After(queueTestNow) {
+				budget = 60000
+			}
+			return []slurm.Association{
+				quotaTestAccount("research", "root", qosTestTRES("cpu", "", budget)),
+				quotaTestUser("alice", "research"),
+			}
+		},
+		func(now time.Time) []slurm.Share {
+			// 2000 billing-weighted CPU-minutes a day
+			used := int64(10000+2000*now.Sub(queueTestNow).Hours()/24) * 60
+			return []slurm.Share{{Account: "research", RawUsage: used}, {Account: "research", User: "alice", RawUsage: used}}
+		},
+		func(time.Time) []slurm.Job { return nil })
+	ctx := context.Background()
+
+	quotas, err := c.GetAccountQuotas(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 10000.0, quotas.CPUMinutes.Used)
+	assert.Zero(t, quotas.CPUMinutes.BurnRate)
+	assert.Nil(t, quotas.CPUMinutes.ProjectedExhaustion)
+
+	setTime(queueTestNow.Add(24 * time.Hour))
+	quotas, err = c.GetAccountQuotas(ctx, "research")
+	require.NoError(t, err)
+	cpu := quotas.CPUMinutes
+	assert.Equal(t, 12000.0, cpu.Used)
+	assert.InDelta(t, 2000, cpu.BurnRate, 1e-6)
+	require.NotNil(t, cpu.ProjectedExhaustion)
+	assert.WithinDuration(t, queueTestNow.Add(25*24*time.Hour), *cpu.ProjectedExhaustion, time.Second)
+	assert.Equal(t, "monthly", quotas.QuotaPeriod)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), quotas.QuotaResetDate)
+	assert.Equal(t, 2, quotas.QuotaVersion)
+
+	usage, err := c.GetAccountQuotaUsage(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, usage.UserQuotaShares["alice"])
+
+	// Weeks reset on Sunday
+	last, next := usageResetWindow(queueTestNow, "weekly")
+	assert.Equal(t, time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), last)
+	assert.Equal(t, time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC), next)
+}
+
+func TestAccountQuotaClient_Violations(t *testing.T) {
+	t.Parallel()
+	start := queueTestNow.Add(10 * time.Minute)
+	c, setTime := newAccountQuotaTestClient(t, nil, quotaTestAssociationsWithUsage,
+		func(time.Time) []slurm.Share { return nil },
+		func(now time.Time) []slurm.Job {
+			jobs := quotaTestRunningJob(now)
+			if now.Before(start) {
+				return append(jobs, qosTestJob(2, "bob", "normal", "PENDING", "cpu=32", "AssocGrpCPUMinutesLimit"))
+			}
+			// Job 1 finished and job 2 started when the budget was raised
+			jobs[0].JobState = []api.JobState{api.JobStateCompleted}
+			jobs[0].EndTime = start
+			started := qosTestJob(2, "bob", "normal", "RUNNING", "cpu=32", "")
+			started.StartTime = start
+			return append(jobs, started, qosTestJob(3, "bob", "normal", "PENDING", "cpu=4", "Priority"))
+		})
+	ctx := context.Background()
+
+	violations, err := c.GetAccountQuotaViolations(ctx, "research", "7d")
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "AssocGrpCPUMinutesLimit", violations[0].ViolationType)
+	assert.Equal(t, "cpu", violations[0].ResourceType)
+	assert.Equal(t, "critical", violations[0].Severity)
+	assert.Equal(t, "account", violations[0].QuotaType)
+	assert.Nil(t, violations[0].ResolvedAt)
+
+	status, err := c.GetQuotaEnforcementStatus(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, "hard", status.EnforcementMode)
+	assert.Equal(t, []string{"AssocGrpCPUMinutesLimit"}, status.BlockedOperations)
+
+	setTime(start.Add(5 * time.Minute))
+	violations, err = c.GetAccountQuotaViolations(ctx, "research", "7d")
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	require.NotNil(t, violations[0].ResolvedAt)
+	assert.Equal(t, "started", violations[0].ResolvedBy)
+	assert.True(t, violations[0].AutoResolved)
+	assert.Equal(t, 15*time.Minute, violations[0].Duration)
+
+	// Jobs seen since the first snapshot are counted once
+	usage, err := c.GetAccountQuotaUsage(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.JobsSubmitted)
+	assert.Equal(t, 1, usage.JobsCompleted)
+	assert.Equal(t, 1, usage.JobsRunning)
+	assert.Equal(t, 1, usage.JobsPending)
+	usage, err = c.GetAccountQuotaUsage(ctx, "research")
+	require.NoError(t, err)
+	assert.Zero(t, usage.JobsSubmitted)
+	assert.Zero(t, usage.JobsCompleted)
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	slurmauth "github.com/jontk/slurm-client/pkg/auth"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+	authpkg "github.com/jontk/slurm-exporter/internal/slurm/auth"
+)
+
+// AccountingClient reads finished jobs from the slurmdbd jobs endpoint
+// (/slurmdb/<version>/jobs). slurm-client does not wrap this endpoint, so the
+// request is made directly using the exporter's authentication settings.
+type AccountingClient struct {
+	baseURL    string
+	apiVersion string
+	auth       slurmauth.Provider
+	httpClient *http.Client
+}
+
+// NewAccountingClient creates a slurmdbd jobs reader. apiVersion is the
+// slurmrestd API version in use, e.g. the value of SlurmClient.Version().
+func NewAccountingClient(cfg *config.SLURMConfig, apiVersion string, options ...Option) (*AccountingClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("SLURM base URL is required")
+	}
+	if apiVersion == "" {
+		apiVersion = cfg.APIVersion
+	}
+	if apiVersion == "" {
+		return nil, fmt.Errorf("SLURM API version is required for slurmdbd queries")
+	}
+
+	authProvider, err := authpkg.ConfigureAuth(&cfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
+	}
+
+	httpClient, err := applyOptions(options).newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountingClient{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiVersion: apiVersion,
+		auth:       authProvider,
+		httpClient: httpClient,
+	}, nil
+}
+
+// slurmdbJobsResponse is the subset of the slurmdbd jobs response used here.
+// The layout is shared by API versions v0.0.40 through v0.0.44.
+type slurmdbJobsResponse struct {
+	Jobs []struct {
+		JobID     int64  `json:"job_id"`
+		User      string `json:"user"`
+		Account   string `json:"account"`
+		Partition string `json:"partition"`
+		QoS       string `json:"qos"`
+		State     struct {
+			Current []string `json:"current"`
+		} `json:"state"`
+		ExitCode struct {
+			ReturnCode slurmdbNumber `json:"return_code"`
+			Signal     struct {
+				ID slurmdbNumber `json:"id"`
+			} `json:"signal"`
+		} `json:"exit_code"`
+		Time struct {
+			Submission int64 `json:"submission"`
+			Start      int64 `json:"start"`
+			End        int64 `json:"end"`
+		} `json:"time"`
+	} `json:"jobs"`
+	Errors []struct {
+		Description string `json:"description"`
+		Error       string `json:"error"`
+	} `json:"errors"`
+}
+
+// slurmdbNumber decodes Slurm's {"set","infinite","number"} integer wrapper
+// as well as plain numbers
+type slurmdbNumber int64
+
+func (n *slurmdbNumber) UnmarshalJSON(data []byte) error {
+	var plain int64
+	if err := json.Unmarshal(data, &plain); err == nil {
+		*n = slurmdbNumber(plain)
+		return nil
+	}
+
+	var wrapped struct {
+		Set    bool  `json:"set"`
+		Number int64 `json:"number"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return err
+	}
+	if wrapped.Set {
+		*n = slurmdbNumber(wrapped.Number)
+	}
+	return nil
+}
+
+// ListAccountingJobs returns jobs recorded by slurmdbd between start and end
+func (a *AccountingClient) ListAccountingJobs(ctx context.Context, start, end time.Time) ([]collector.AccountingJob, error) {
+	query := url.Values{}
+	query.Set("start_time", strconv.FormatInt(start.Unix(), 10))
+	query.Set("end_time", strconv.FormatInt(end.Unix(), 10))
+	endpoint := fmt.Sprintf("%s/slurmdb/%s/jobs?%s", a.baseURL, a.apiVersion, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build slurmdbd request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := a.auth.Authenticate(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to authenticate slurmdbd request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("slurmdbd jobs request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("slurmdbd jobs request returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var decoded slurmdbJobsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode slurmdbd jobs response: %w", err)
+	}
+	if len(decoded.Errors) > 0 {
+		return nil, fmt.Errorf("slurmdbd jobs request failed: %s", decoded.Errors[0].Description)
+	}
+
+	jobs := make([]collector.AccountingJob, 0, len(decoded.Jobs))
+	for _, job := range decoded.Jobs {
+		accountingJob := collector.AccountingJob{
+			JobID:      strconv.FormatInt(job.JobID, 10),
+			User:       job.User,
+			Account:    job.Account,
+			Partition:  job.Partition,
+			QoS:        job.QoS,
+			ExitCode:   int(job.ExitCode.ReturnCode),
+			Signal:     int(job.ExitCode.Signal.ID),
+			SubmitTime: unixTime(job.Time.Submission),
+			StartTime:  unixTime(job.Time.Start),
+			EndTime:    unixTime(job.Time.End),
+		}
+		if len(job.State.Current) > 0 {
+			accountingJob.State = job.State.Current[0]
+		}
+		jobs = append(jobs, accountingJob)
+	}
+	return jobs, nil
+}
+
+// unixTime converts a Slurm timestamp, where 0 means unset
+func unixTime(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// Ensure AccountingClient satisfies the collector interface
+var _ collector.JobAccountingReader = (*AccountingClient)(nil)
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+const slurmdbJobsFixture = `{
+  "jobs": [
+    {
+      "job_id": 4242,
+      "user": "alice",
+      "account": "physics",
+      "partition": "compute",
+      "qos": "normal",
+      "state": {"current": ["FAILED"], "reason": "None"},
+      "exit_code": {
+        "status": ["ERROR"],
+        "return_code": {"set": true, "infinite": false, "number": 2},
+        "signal": {"id": {"set": false, "infinite": false, "number": 0}, "name": ""}
+      },
+      "time": {"submission": 1717408800, "start": 1717409400, "end": 1717412400}
+    }
+  ],
+  "errors": []
+}`
+
+func TestAccountingClient_ListAccountingJobs(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/slurmdb/v0.0.42/jobs" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("start_time"); got != "1717405200" {
+			t.Errorf("start_time = %s, want 1717405200", got)
+		}
+		if got := r.Header.Get("X-SLURM-USER-NAME"); got != "exporter" {
+			t.Errorf("X-SLURM-USER-NAME = %q, want exporter", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(slurmdbJobsFixture))
+	}))
+	defer server.Close()
+
+	client, err := NewAccountingClient(&config.SLURMConfig{
+		BaseURL: server.URL,
+		Timeout: time.Second,
+		Auth:    config.AuthConfig{Type: "jwt", Username: "exporter", Token: "token"},
+	}, "v0.0.42")
+	if err != nil {
+		t.Fatalf("NewAccountingClient() error = %v", err)
+	}
+
+	start := time.Unix(1717405200, 0)
+	jobs, err := client.ListAccountingJobs(context.Background(), start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("ListAccountingJobs() error = %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+
+	job := jobs[0]
+	if job.JobID != "4242" || job.User != "alice" || job.State != "FAILED" || job.ExitCode != 2 || job.Signal != 0 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if got := job.EndTime.Sub(job.StartTime); got != 50*time.Minute {
+		t.Errorf("runtime = %v, want 50m", got)
+	}
+}
+
+func TestAccountingClient_ErrorStatus(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "slurmdbd connection refused", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, err := NewAccountingClient(&config.SLURMConfig{
+		BaseURL: server.URL,
+		Timeout: time.Second,
+		Auth:    config.AuthConfig{Type: "none"},
+	}, "v0.0.42")
+	if err != nil {
+		t.Fatalf("NewAccountingClient() error = %v", err)
+	}
+
+	if _, err := client.ListAccountingJobs(context.Background(), time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Error("expected error for non-200 response")
+	}
+}
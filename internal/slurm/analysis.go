@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	slurm "github.com/jontk/slurm-client"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// NewAnalysisClients creates the clients of the enabled collectors that are
+// fed by the exporter rather than by client directly. cfg is the slurmrestd
+// configuration of client, used for the slurmdbd accounting reader, which
+// gets options; the other clients share client and its endpoints.
+func NewAnalysisClients(client slurm.SlurmClient, cfg *config.SLURMConfig, collectors *config.CollectorsConfig, options ...Option) collector.AnalysisClients {
+	var clients collector.AnalysisClients
+
+	// The accounting collector reads finished jobs from slurmdbd
+	if collectors.Accounting.Enabled {
+		accountingClient, err := NewAccountingClient(cfg, client.Version(), options...)
+		if err != nil {
+			logrus.WithError(err).WithField("base_url", cfg.BaseURL).Error("Failed to create slurmdbd accounting client, accounting collector disabled")
+		} else {
+			clients.Accounting = accountingClient
+		}
+	}
+
+	// Node state change events are derived by diffing node snapshots
+	if collectors.NodeEvents.Enabled {
+		clients.NodeEvents = NewNodeEventSource(client, &NodeEventSourceOptions{
+			PollInterval: collectors.NodeEvents.Interval,
+		})
+	}
+
+	// Priority factors are derived from the pending jobs' priorities
+	if collectors.Priority.Enabled {
+		clients.Priority = NewPriorityClient(client, PriorityOptionsFromConfig(&collectors.Priority))
+	}
+
+	// Queue positions and wait times are derived from the job list
+	if collectors.QueueAnalysis.Enabled {
+		clients.QueueAnalysis = NewQueueAnalysisClient(client, nil)
+	}
+
+	// QoS limit usage is derived from the running and pending jobs
+	if collectors.QoSLimits.Enabled {
+		clients.QoSLimits = NewQoSLimitsClient(client, nil)
+	}
+
+	// Account budgets are burnt down against the association usage
+	if collectors.AccountQuota.Enabled {
+		clients.AccountQuota = NewAccountQuotaClient(client, AccountQuotaOptionsFromConfig(&collectors.AccountQuota))
+	}
+
+	// Account costs price the association usage and running jobs
+	if collectors.AccountCost.Enabled {
+		clients.AccountCost = NewAccountCostClient(client, AccountCostOptionsFromConfig(&collectors.AccountCost))
+	}
+
+	return clients
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func TestNewAnalysisClients(t *testing.T) {
+	t.Parallel()
+	client := new(mocks.MockSlurmClient)
+	client.On("Version").Return("v0.0.41")
+
+	collectors := &config.CollectorsConfig{}
+	collectors.Accounting.Enabled = true
+	collectors.NodeEvents.Enabled = true
+	collectors.AccountCost.Enabled = true
+	collectors.AccountQuota.Enabled = true
+	collectors.QoSLimits.Enabled = true
+	collectors.Priority.Enabled = true
+	collectors.QueueAnalysis.Enabled = true
+
+	// Without a base URL there is no slurmdbd reader, which leaves the
+	// accounting collector without a client rather than failing
+	clients := NewAnalysisClients(client, &config.SLURMConfig{}, collectors)
+	assert.Nil(t, clients.Accounting)
+	assert.IsType(t, &NodeEventSource{}, clients.NodeEvents)
+	assert.IsType(t, &AccountCostClient{}, clients.AccountCost)
+	assert.IsType(t, &AccountQuotaClient{}, clients.AccountQuota)
+	assert.IsType(t, &QoSLimitsClient{}, clients.QoSLimits)
+	assert.IsType(t, &PriorityClient{}, clients.Priority)
+	assert.IsType(t, &QueueAnalysisClient{}, clients.QueueAnalysis)
+
+	clients = NewAnalysisClients(client, &config.SLURMConfig{
+		BaseURL: "http://slurm:6820",
+		Timeout: time.Second,
+		Auth:    config.AuthConfig{Type: "jwt", Username: "exporter", Token: "token"},
+	}, collectors)
+	assert.IsType(t, &AccountingClient{}, clients.Accounting)
+
+	collectors.Accounting.Enabled = false
+	assert.Nil(t, NewAnalysisClients(client, &config.SLURMConfig{}, collectors).Accounting)
+}
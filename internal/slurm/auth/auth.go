@@ -4,9 +4,7 @@
 package auth
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 
@@ -15,24 +13,6 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// userTokenAuth implements SLURM user token authentication with both username and token
-type userTokenAuth struct {
-	username string
-	token    string
-}
-
-// Authenticate adds both X-SLURM-USER-NAME and X-SLURM-USER-TOKEN headers
-func (u *userTokenAuth) Authenticate(_ context.Context, req *http.Request) error {
-	req.Header.Set("X-SLURM-USER-NAME", u.username)
-	req.Header.Set("X-SLURM-USER-TOKEN", u.token)
-	return nil
-}
-
-// Type returns the authentication type
-func (u *userTokenAuth) Type() string {
-	return "user-token"
-}
-
 // ConfigureAuth creates an auth provider based on the configuration
 func ConfigureAuth(cfg *config.AuthConfig) (slurmauth.Provider, error) {
 	switch cfg.Type {
@@ -40,21 +20,17 @@ func ConfigureAuth(cfg *config.AuthConfig) (slurmauth.Provider, error) {
 		logrus.Debug("Using no authentication")
 		return slurmauth.NewNoAuth(), nil
 
-	case "jwt":
-		token, err := GetJWTToken(cfg)
+	case config.AuthTypeJWT, config.AuthTypeJWTKey:
+		// With a username both X-SLURM-USER-NAME and X-SLURM-USER-TOKEN are set
+		provider, err := NewJWTAuth(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure JWT auth: %w", err)
 		}
-		// If username is provided, use user token auth which sets both X-SLURM-USER-NAME and X-SLURM-USER-TOKEN
-		if cfg.Username != "" {
-			logrus.WithField("username", cfg.Username).Debug("Using JWT authentication with username")
-			return &userTokenAuth{
-				username: cfg.Username,
-				token:    token,
-			}, nil
-		}
-		logrus.Debug("Using JWT authentication")
-		return slurmauth.NewTokenAuth(token), nil
+		logrus.WithFields(logrus.Fields{
+			"type":     cfg.Type,
+			"username": cfg.Username,
+		}).Debug("Using JWT authentication")
+		return provider, nil
 
 	case "basic":
 		username, password, err := getBasicCredentials(cfg)
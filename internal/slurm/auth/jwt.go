@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultJWTTokenLifetime is the lifetime of tokens minted with jwt_key
+	DefaultJWTTokenLifetime = 5 * time.Minute
+
+	// jwtRefreshMargin is how long before its exp claim a token is replaced
+	jwtRefreshMargin = 30 * time.Second
+)
+
+// JWTAuth authenticates with a SLURM JWT that is kept fresh.
+//
+// Tokens from token_file are re-read whenever the file changes and when the
+// current token is about to expire. With a signing key, short-lived HS256
+// tokens are minted locally the same way `scontrol token` does.
+type JWTAuth struct {
+	username  string
+	token     string
+	tokenFile string
+	key       []byte
+	lifetime  time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	current string
+	expiry  time.Time
+	modTime time.Time
+	warned  time.Time
+}
+
+// NewJWTAuth creates a JWT provider for the jwt and jwt_key auth types and
+// loads the first token so configuration errors surface at startup
+func NewJWTAuth(cfg *config.AuthConfig) (*JWTAuth, error) {
+	j := &JWTAuth{
+		username:  cfg.Username,
+		token:     cfg.Token,
+		tokenFile: cfg.TokenFile,
+		lifetime:  cfg.TokenLifetime,
+		now:       time.Now,
+	}
+
+	switch cfg.Type {
+	case config.AuthTypeJWTKey:
+		if cfg.Username == "" {
+			return nil, fmt.Errorf("jwt_key auth requires username")
+		}
+		if cfg.KeyFile == "" {
+			return nil, fmt.Errorf("jwt_key auth requires key_file to be specified")
+		}
+		key, err := readKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		j.key = key
+		if j.lifetime <= 0 {
+			j.lifetime = DefaultJWTTokenLifetime
+		}
+	default:
+		if cfg.Token == "" && cfg.TokenFile == "" {
+			return nil, fmt.Errorf("JWT auth requires token or token_file to be specified")
+		}
+	}
+
+	if _, err := j.Token(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Authenticate sets the SLURM token headers, refreshing the token if needed
+func (j *JWTAuth) Authenticate(_ context.Context, req *http.Request) error {
+	token, err := j.Token()
+	if err != nil {
+		return err
+	}
+	if j.username != "" {
+		req.Header.Set("X-SLURM-USER-NAME", j.username)
+	}
+	req.Header.Set("X-SLURM-USER-TOKEN", token)
+	return nil
+}
+
+// Type returns the authentication type
+func (j *JWTAuth) Type() string {
+	if j.key != nil {
+		return config.AuthTypeJWTKey
+	}
+	return config.AuthTypeJWT
+}
+
+// Token returns a valid token. When a refresh fails the previous token is
+// kept, since slurmrestd may still accept it.
+func (j *JWTAuth) Token() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	if j.current != "" && !j.needsRefresh(now) {
+		return j.current, nil
+	}
+
+	if err := j.refresh(now); err != nil {
+		if j.current == "" {
+			return "", err
+		}
+		logrus.WithError(err).Warn("Failed to refresh SLURM JWT, keeping the current token")
+	}
+	return j.current, nil
+}
+
+// needsRefresh reports whether the token is close to expiry or its file has
+// been replaced
+func (j *JWTAuth) needsRefresh(now time.Time) bool {
+	if j.key == nil && j.tokenFile == "" {
+		// A static token cannot be renewed
+		return false
+	}
+	if !j.expiry.IsZero() && now.Add(j.refreshMargin()).After(j.expiry) {
+		return true
+	}
+	if j.tokenFile != "" && j.key == nil {
+		info, err := os.Stat(j.tokenFile)
+		if err == nil && !info.ModTime().Equal(j.modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshMargin returns how long before expiry the token is replaced
+func (j *JWTAuth) refreshMargin() time.Duration {
+	if j.key != nil {
+		// Minted tokens are renewed once a fifth of their lifetime remains
+		return min(jwtRefreshMargin, j.lifetime/5)
+	}
+	return jwtRefreshMargin
+}
+
+// refresh loads or mints a new token
+func (j *JWTAuth) refresh(now time.Time) error {
+	var token string
+	switch {
+	case j.key != nil:
+		token = signJWT(j.key, j.username, now, j.lifetime)
+	case j.token != "":
+		token = j.token
+	default:
+		info, err := os.Stat(j.tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to stat JWT token file %s: %w", j.tokenFile, err)
+		}
+		token, err = readSecretFile(j.tokenFile, "JWT token")
+		if err != nil {
+			return err
+		}
+		if token != j.current && j.current != "" {
+			logrus.WithField("token_file", j.tokenFile).Info("Reloaded SLURM JWT from token file")
+		}
+		j.modTime = info.ModTime()
+	}
+
+	expiry := jwtExpiry(token)
+	if !expiry.IsZero() && !now.Before(expiry) && !j.warned.Equal(expiry) {
+		logrus.WithField("expired_at", expiry).Warn("SLURM JWT has expired and no newer token is available")
+		j.warned = expiry
+	}
+	j.current = token
+	j.expiry = expiry
+	return nil
+}
+
+// signJWT mints an HS256 token with the claims slurmrestd checks: the SLURM
+// user name (sun), issue time and expiry
+func signJWT(key []byte, username string, now time.Time, lifetime time.Duration) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(struct {
+		Exp int64  `json:"exp"`
+		Iat int64  `json:"iat"`
+		Sun string `json:"sun"`
+	}{
+		Exp: now.Add(lifetime).Unix(),
+		Iat: now.Unix(),
+		Sun: username,
+	})
+	payload := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// jwtExpiry returns the exp claim of a token, or the zero time when the
+// token is not a JWT or has no expiry. The signature is not verified.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// readKeyFile reads the JWT signing key. Unlike other secrets the key is
+// binary and used exactly as stored.
+func readKeyFile(filename string) ([]byte, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat JWT key file %s: %w", filename, err)
+	}
+	if info.Mode()&0077 != 0 {
+		logrus.Warnf("JWT key file %s has permissions %v, consider restricting to 600", filename, info.Mode().Perm())
+	}
+
+	key, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key from file %s: %w", filename, err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("JWT key file %s is empty", filename)
+	}
+	return key, nil
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// testJWT builds an unsigned token with the given expiry
+func testJWT(t *testing.T, exp time.Time) string {
+	t.Helper()
+	claims, err := json.Marshal(map[string]int64{"exp": exp.Unix()})
+	if err != nil {
+		t.Fatalf("Failed to marshal claims: %v", err)
+	}
+	return "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+func TestJWTAuth_TokenFileRotation(t *testing.T) {
+	t.Parallel()
+	tokenFile := filepath.Join(t.TempDir(), "jwt")
+	now := time.Now()
+
+	first := testJWT(t, now.Add(time.Hour))
+	if err := os.WriteFile(tokenFile, []byte(first+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	provider, err := NewJWTAuth(&config.AuthConfig{Type: "jwt", TokenFile: tokenFile, Username: "slurm"})
+	if err != nil {
+		t.Fatalf("NewJWTAuth() error = %v", err)
+	}
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://localhost", nil)
+	if err := provider.Authenticate(context.Background(), req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got := req.Header.Get("X-SLURM-USER-TOKEN"); got != first {
+		t.Errorf("X-SLURM-USER-TOKEN = %q, want %q", got, first)
+	}
+	if got := req.Header.Get("X-SLURM-USER-NAME"); got != "slurm" {
+		t.Errorf("X-SLURM-USER-NAME = %q, want slurm", got)
+	}
+
+	// A rotated file is picked up on the next request
+	second := testJWT(t, now.Add(2*time.Hour))
+	if err := os.WriteFile(tokenFile, []byte(second), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	if err := os.Chtimes(tokenFile, now, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to touch token file: %v", err)
+	}
+	if got, _ := provider.Token(); got != second {
+		t.Errorf("Token() after rotation = %q, want %q", got, second)
+	}
+
+	// A token about to expire is re-read even if the file looks unchanged
+	third := testJWT(t, now.Add(3*time.Hour))
+	if err := os.WriteFile(tokenFile, []byte(third), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	if err := os.Chtimes(tokenFile, now, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to touch token file: %v", err)
+	}
+	provider.now = func() time.Time { return now.Add(2*time.Hour - 10*time.Second) }
+	if got, _ := provider.Token(); got != third {
+		t.Errorf("Token() near expiry = %q, want %q", got, third)
+	}
+
+	// A missing file keeps the current token
+	if err := os.Remove(tokenFile); err != nil {
+		t.Fatalf("Failed to remove token file: %v", err)
+	}
+	provider.now = func() time.Time { return now.Add(3*time.Hour - 10*time.Second) }
+	if got, err := provider.Token(); err != nil || got != third {
+		t.Errorf("Token() without file = %q, %v, want %q", got, err, third)
+	}
+}
+
+func TestJWTAuth_SignedWithKey(t *testing.T) {
+	t.Parallel()
+	keyFile := filepath.Join(t.TempDir(), "jwt_hs256.key")
+	key := []byte("0123456789abcdef0123456789abcdef\n")
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	provider, err := NewJWTAuth(&config.AuthConfig{
+		Type:          "jwt_key",
+		KeyFile:       keyFile,
+		Username:      "slurm",
+		TokenLifetime: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTAuth() error = %v", err)
+	}
+
+	start := time.Now()
+	provider.now = func() time.Time { return start }
+	provider.mu.Lock()
+	provider.current = ""
+	provider.mu.Unlock()
+	token, err := provider.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	// The signature covers header and claims with the key as stored
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Token() = %q, want three parts", token)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("signature = %q, want %q", parts[2], want)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Exp int64  `json:"exp"`
+		Iat int64  `json:"iat"`
+		Sun string `json:"sun"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	if claims.Sun != "slurm" || claims.Iat != start.Unix() || claims.Exp != start.Add(time.Minute).Unix() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Tokens are reused until a fifth of the lifetime remains
+	provider.now = func() time.Time { return start.Add(30 * time.Second) }
+	if again, _ := provider.Token(); again != token {
+		t.Error("Token() minted a new token too early")
+	}
+	provider.now = func() time.Time { return start.Add(50 * time.Second) }
+	if renewed, _ := provider.Token(); renewed == token {
+		t.Error("Token() did not renew a token close to expiry")
+	}
+}
+
+func TestNewJWTAuth_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		config *config.AuthConfig
+		errMsg string
+	}{
+		{"jwt without token", &config.AuthConfig{Type: "jwt"}, "requires token or token_file"},
+		{"jwt_key without username", &config.AuthConfig{Type: "jwt_key", KeyFile: "/tmp/key"}, "requires username"},
+		{"jwt_key without key", &config.AuthConfig{Type: "jwt_key", Username: "slurm"}, "requires key_file"},
+		{"jwt_key missing key", &config.AuthConfig{Type: "jwt_key", Username: "slurm", KeyFile: "/non/existent/key"}, "failed to stat JWT key file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewJWTAuth(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("NewJWTAuth() error = %v, want containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
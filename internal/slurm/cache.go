@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/performance"
+)
+
+// Endpoints served by CachingClient, used as the endpoint label
+const (
+	endpointQoS          = "qos"
+	endpointAssociations = "associations"
+	endpointAccounts     = "accounts"
+	endpointClusters     = "clusters"
+	endpointWCKeys       = "wckeys"
+	endpointTRES         = "tres"
+)
+
+// cachedEndpoints are the slow, rarely-changing endpoints CachingClient caches
+var cachedEndpoints = []string{
+	endpointQoS, endpointAssociations, endpointAccounts,
+	endpointClusters, endpointWCKeys, endpointTRES,
+}
+
+// CachingClient wraps a SLURM client so that slow, rarely-changing
+// endpoints are served from an intelligent cache. Unfiltered QoS,
+// association, account, cluster and WCKey lists and the TRES list are
+// cached with a TTL that adapts to how often they actually change; only
+// one fetch per endpoint is made at a time. Cached results must be treated
+// as read-only.
+type CachingClient struct {
+	slurm.SlurmClient
+
+	cache *performance.IntelligentCache
+
+	// fetching holds one token per endpoint, taken while it is fetched
+	fetching map[string]chan struct{}
+
+	hits   *prometheus.CounterVec
+	misses *prometheus.CounterVec
+	ttl    *prometheus.GaugeVec
+}
+
+// NewCachingClient wraps client, caching its slow endpoints as configured
+func NewCachingClient(client slurm.SlurmClient, cfg config.CachingConfig) *CachingClient {
+	fetching := make(map[string]chan struct{}, len(cachedEndpoints))
+	for _, endpoint := range cachedEndpoints {
+		fetching[endpoint] = make(chan struct{}, 1)
+	}
+
+	return &CachingClient{
+		SlurmClient: client,
+		cache:       performance.NewIntelligentCache(cfg, logrus.StandardLogger()),
+		fetching:    fetching,
+		hits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_cache_hits_total",
+				Help:      "Requests for a SLURM endpoint answered from the cache",
+			},
+			[]string{"endpoint"},
+		),
+		misses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_cache_misses_total",
+				Help:      "Requests for a SLURM endpoint that were not in the cache and fetched it",
+			},
+			[]string{"endpoint"},
+		),
+		ttl: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_cache_ttl_seconds",
+				Help:      "Time the last fetch of a SLURM endpoint is cached for, adapted to how often it changes",
+			},
+			[]string{"endpoint"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *CachingClient) Describe(ch chan<- *prometheus.Desc) {
+	c.hits.Describe(ch)
+	c.misses.Describe(ch)
+	c.ttl.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *CachingClient) Collect(ch chan<- prometheus.Metric) {
+	c.hits.Collect(ch)
+	c.misses.Collect(ch)
+	c.ttl.Collect(ch)
+}
+
+// Close stops the cache's background cleanup
+func (c *CachingClient) Close() error {
+	c.cache.Close()
+	return c.SlurmClient.Close()
+}
+
+// fetch returns the cached result of an endpoint, fetching and caching it
+// when there is none. Requests arriving during a fetch wait for it and are
+// then answered from the cache. Failed fetches are not cached.
+func (c *CachingClient) fetch(ctx context.Context, endpoint string, fetchFunc func(context.Context) (any, error)) (any, error) {
+	if value, ok := c.cache.Get(endpoint); ok {
+		c.hits.WithLabelValues(endpoint).Inc()
+		return value, nil
+	}
+
+	select {
+	case c.fetching[endpoint] <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.fetching[endpoint] }()
+
+	// The fetch we waited for may have filled the cache
+	if value, ok := c.cache.Get(endpoint); ok {
+		c.hits.WithLabelValues(endpoint).Inc()
+		return value, nil
+	}
+	c.misses.WithLabelValues(endpoint).Inc()
+
+	value, err := fetchFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Set(endpoint, value)
+	if ttl, ok := c.cache.TTL(endpoint); ok {
+		c.ttl.WithLabelValues(endpoint).Set(ttl.Seconds())
+	}
+	return value, nil
+}
+
+// QoS returns the QoS manager, caching unfiltered QoS lists
+func (c *CachingClient) QoS() slurm.QoSManager {
+	qos := c.SlurmClient.QoS()
+	if qos == nil {
+		return nil
+	}
+	return &cachingQoS{QoSManager: qos, client: c}
+}
+
+// Associations returns the association manager, caching unfiltered
+// association lists
+func (c *CachingClient) Associations() slurm.AssociationManager {
+	associations := c.SlurmClient.Associations()
+	if associations == nil {
+		return nil
+	}
+	return &cachingAssociations{AssociationManager: associations, client: c}
+}
+
+// Accounts returns the account manager, caching unfiltered account lists
+func (c *CachingClient) Accounts() slurm.AccountManager {
+	accounts := c.SlurmClient.Accounts()
+	if accounts == nil {
+		return nil
+	}
+	return &cachingAccounts{AccountManager: accounts, client: c}
+}
+
+// Clusters returns the cluster manager, caching unfiltered cluster lists
+func (c *CachingClient) Clusters() slurm.ClusterManager {
+	clusters := c.SlurmClient.Clusters()
+	if clusters == nil {
+		return nil
+	}
+	return &cachingClusters{ClusterManager: clusters, client: c}
+}
+
+// WCKeys returns the WCKey manager, caching unfiltered WCKey lists
+func (c *CachingClient) WCKeys() slurm.WCKeyManager {
+	wckeys := c.SlurmClient.WCKeys()
+	if wckeys == nil {
+		return nil
+	}
+	return &cachingWCKeys{WCKeyManager: wckeys, client: c}
+}
+
+// GetTRES returns the cached TRES list
+func (c *CachingClient) GetTRES(ctx context.Context) (*slurm.TRESList, error) {
+	value, err := c.fetch(ctx, endpointTRES, func(ctx context.Context) (any, error) {
+		return c.SlurmClient.GetTRES(ctx)
+	})
+	list, _ := value.(*slurm.TRESList)
+	return list, err
+}
+
+type cachingQoS struct {
+	slurm.QoSManager
+	client *CachingClient
+}
+
+// List caches unfiltered QoS lists; filtered lists are fetched as asked
+func (m *cachingQoS) List(ctx context.Context, opts *slurm.ListQoSOptions) (*slurm.QoSList, error) {
+	if opts != nil {
+		return m.QoSManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointQoS, func(ctx context.Context) (any, error) {
+		return m.QoSManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.QoSList)
+	return list, err
+}
+
+type cachingAssociations struct {
+	slurm.AssociationManager
+	client *CachingClient
+}
+
+// List caches unfiltered association lists; filtered lists are fetched as asked
+func (m *cachingAssociations) List(ctx context.Context, opts *slurm.ListAssociationsOptions) (*slurm.AssociationList, error) {
+	if opts != nil {
+		return m.AssociationManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointAssociations, func(ctx context.Context) (any, error) {
+		return m.AssociationManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.AssociationList)
+	return list, err
+}
+
+type cachingAccounts struct {
+	slurm.AccountManager
+	client *CachingClient
+}
+
+// List caches unfiltered account lists; filtered lists are fetched as asked
+func (m *cachingAccounts) List(ctx context.Context, opts *slurm.ListAccountsOptions) (*slurm.AccountList, error) {
+	if opts != nil {
+		return m.AccountManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointAccounts, func(ctx context.Context) (any, error) {
+		return m.AccountManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.AccountList)
+	return list, err
+}
+
+type cachingClusters struct {
+	slurm.ClusterManager
+	client *CachingClient
+}
+
+// List caches unfiltered cluster lists; filtered lists are fetched as asked
+func (m *cachingClusters) List(ctx context.Context, opts *slurm.ListClustersOptions) (*slurm.ClusterList, error) {
+	if opts != nil {
+		return m.ClusterManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointClusters, func(ctx context.Context) (any, error) {
+		return m.ClusterManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.ClusterList)
+	return list, err
+}
+
+type cachingWCKeys struct {
+	slurm.WCKeyManager
+	client *CachingClient
+}
+
+// List caches unfiltered WCKey lists; filtered lists are fetched as asked
+func (m *cachingWCKeys) List(ctx context.Context, opts *slurm.WCKeyListOptions) (*slurm.WCKeyList, error) {
+	if opts != nil {
+		return m.WCKeyManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointWCKeys, func(ctx context.Context) (any, error) {
+		return m.WCKeyManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.WCKeyList)
+	return list, err
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+// countingQoS is a QoS manager that counts and optionally delays List calls
+type countingQoS struct {
+	slurm.QoSManager
+	calls   atomic.Int32
+	delay   time.Duration
+	failing atomic.Bool
+}
+
+func (m *countingQoS) List(ctx context.Context, opts *slurm.ListQoSOptions) (*slurm.QoSList, error) {
+	m.calls.Add(1)
+	time.Sleep(m.delay)
+	if m.failing.Load() {
+		return nil, errors.New("slurmdbd unavailable")
+	}
+	return &slurm.QoSList{QoS: []slurm.QoS{{}}}, nil
+}
+
+// fakeQoSClient serves the counting QoS manager
+type fakeQoSClient struct {
+	slurm.SlurmClient
+	qos *countingQoS
+}
+
+func (c *fakeQoSClient) QoS() slurm.QoSManager {
+	return c.qos
+}
+
+func testCachingConfig(baseTTL time.Duration) config.CachingConfig {
+	return config.CachingConfig{
+		Intelligent:     true,
+		BaseTTL:         baseTTL,
+		MaxEntries:      100,
+		CleanupInterval: time.Minute,
+		ChangeTracking:  true,
+		AdaptiveTTL: config.AdaptiveTTLConfig{
+			Enabled:           true,
+			MinTTL:            baseTTL / 2,
+			MaxTTL:            8 * baseTTL,
+			StabilityWindow:   time.Minute,
+			VarianceThreshold: 0.1,
+			ChangeThreshold:   0.05,
+			ExtensionFactor:   2.0,
+			ReductionFactor:   0.5,
+		},
+	}
+}
+
+func TestCachingClient_CachesSlowEndpoints(t *testing.T) {
+	t.Parallel()
+	qos := &countingQoS{delay: 50 * time.Millisecond}
+	client := NewCachingClient(&fakeQoSClient{qos: qos}, testCachingConfig(time.Minute))
+	defer client.cache.Close()
+	ctx := context.Background()
+
+	// Concurrent collectors wait for one fetch
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := client.QoS().List(ctx, nil)
+			if err != nil || list == nil || len(list.QoS) != 1 {
+				t.Errorf("List() = %v, %v", list, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := qos.calls.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+
+	// Filtered lists are not cached
+	if _, err := client.QoS().List(ctx, &slurm.ListQoSOptions{}); err != nil {
+		t.Fatalf("List(opts) error = %v", err)
+	}
+	if got := qos.calls.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+
+	if got := testutil.ToFloat64(client.hits.WithLabelValues(endpointQoS)); got != 4 {
+		t.Errorf("hits = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(client.misses.WithLabelValues(endpointQoS)); got != 1 {
+		t.Errorf("misses = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(client.ttl.WithLabelValues(endpointQoS)); got != time.Minute.Seconds() {
+		t.Errorf("ttl = %v, want %v", got, time.Minute.Seconds())
+	}
+}
+
+func TestCachingClient_AdaptsTTL(t *testing.T) {
+	t.Parallel()
+	qos := &countingQoS{}
+	baseTTL := 20 * time.Millisecond
+	client := NewCachingClient(&fakeQoSClient{qos: qos}, testCachingConfig(baseTTL))
+	defer client.cache.Close()
+	ctx := context.Background()
+
+	// An unchanged list is cached for longer after every refetch
+	ttls := make([]time.Duration, 0, 3)
+	for range 3 {
+		if _, err := client.QoS().List(ctx, nil); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		ttl, _ := client.cache.TTL(endpointQoS)
+		ttls = append(ttls, ttl)
+		time.Sleep(ttl + 5*time.Millisecond)
+	}
+	want := []time.Duration{baseTTL, 2 * baseTTL, 4 * baseTTL}
+	for i := range want {
+		if ttls[i] != want[i] {
+			t.Errorf("ttls = %v, want %v", ttls, want)
+			break
+		}
+	}
+
+	// Failed fetches are not cached
+	qos.failing.Store(true)
+	if _, err := client.QoS().List(ctx, nil); err == nil {
+		t.Fatal("List() error = nil, want the fetch error")
+	}
+	qos.failing.Store(false)
+	if _, err := client.QoS().List(ctx, nil); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := qos.calls.Load(); got != 5 {
+		t.Errorf("fetches = %d, want 5", got)
+	}
+}
@@ -10,16 +10,21 @@ import (
 	"time"
 
 	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 
 	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/resilience"
 	authpkg "github.com/jontk/slurm-exporter/internal/slurm/auth"
+	"github.com/jontk/slurm-exporter/internal/tracing"
 )
 
 // Client provides a wrapper around the SLURM client with additional functionality
 type Client struct {
 	client      slurm.SlurmClient
+	shared      *SnapshotClient
+	cache       *CachingClient
 	config      *config.SLURMConfig
 	rateLimiter *rate.Limiter
 	mu          sync.RWMutex
@@ -36,8 +41,50 @@ type ConnectionPool struct {
 	config  *config.SLURMConfig
 }
 
+// Option configures optional behaviour of the SLURM clients
+type Option func(*clientOptions)
+
+// clientOptions holds the optional settings applied by Option
+type clientOptions struct {
+	tracer   *tracing.CollectionTracer
+	breakers *resilience.CircuitBreakerManager
+	caching  *config.CachingConfig
+}
+
+// WithTracer records a span for every request sent to slurmrestd
+func WithTracer(tracer *tracing.CollectionTracer) Option {
+	return func(o *clientOptions) {
+		o.tracer = tracer
+	}
+}
+
+// WithCircuitBreakers sends every request through the circuit breaker of
+// its endpoint, taken from the given manager
+func WithCircuitBreakers(breakers *resilience.CircuitBreakerManager) Option {
+	return func(o *clientOptions) {
+		o.breakers = breakers
+	}
+}
+
+// WithCache serves slow, rarely-changing endpoints from an intelligent
+// cache with adaptive TTLs, if cfg enables it
+func WithCache(cfg config.CachingConfig) Option {
+	return func(o *clientOptions) {
+		o.caching = &cfg
+	}
+}
+
+// applyOptions collects the given options
+func applyOptions(options []Option) clientOptions {
+	var o clientOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
 // NewClient creates a new SLURM client wrapper
-func NewClient(cfg *config.SLURMConfig) (*Client, error) {
+func NewClient(cfg *config.SLURMConfig, options ...Option) (*Client, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid SLURM configuration: %w", err)
 	}
@@ -49,31 +96,26 @@ func NewClient(cfg *config.SLURMConfig) (*Client, error) {
 	opts := []slurm.ClientOption{
 		slurm.WithBaseURL(cfg.BaseURL),
 	}
+	o := applyOptions(options)
+	httpClient, err := o.newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, slurm.WithHTTPClient(httpClient))
 
-	// Configure authentication: use WithUserToken for JWT+username (sets both
-	// X-SLURM-USER-NAME and X-SLURM-USER-TOKEN headers), otherwise use the
-	// auth provider which goes through WithAuth.
-	if cfg.Auth.Type == "jwt" && cfg.Auth.Username != "" {
-		token, err := authpkg.GetJWTToken(&cfg.Auth)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get JWT token: %w", err)
-		}
-		logrus.WithField("username", cfg.Auth.Username).Info("Using JWT user token authentication")
-		opts = append(opts, slurm.WithUserToken(cfg.Auth.Username, token))
-	} else {
-		authProvider, err := authpkg.ConfigureAuth(&cfg.Auth)
-		if err != nil {
-			return nil, fmt.Errorf("failed to configure authentication: %w", err)
-		}
-		opts = append(opts, slurm.WithAuth(authProvider))
+	// Configure authentication. JWT providers renew their token per request,
+	// re-reading token_file or minting a new one from the signing key.
+	authProvider, err := authpkg.ConfigureAuth(&cfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
 	}
+	opts = append(opts, slurm.WithAuth(authProvider))
 
 	// Create the SLURM client
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
 
 	var client slurm.SlurmClient
-	var err error
 
 	// Use adapter pattern for better version compatibility
 	// Adapters provide more complete implementation of standalone operations
@@ -99,8 +141,18 @@ func NewClient(cfg *config.SLURMConfig) (*Client, error) {
 		return nil, fmt.Errorf("failed to create SLURM client: %w", err)
 	}
 
+	// Collectors share fetches of the cache, or of the client itself
+	var cache *CachingClient
+	fetched := client
+	if o.caching != nil && o.caching.Intelligent {
+		cache = NewCachingClient(client, *o.caching)
+		fetched = cache
+	}
+
 	wrapper := &Client{
 		client:      client,
+		shared:      NewSnapshotClient(fetched, cfg.SnapshotMaxAge),
+		cache:       cache,
 		config:      cfg,
 		rateLimiter: rateLimiter,
 		connected:   false,
@@ -193,18 +245,23 @@ func (c *Client) GetLastError() error {
 	return c.lastError
 }
 
-// executeWithRetry executes a function with retry logic
-func (c *Client) executeWithRetry(ctx context.Context, operation func() error) error {
+// executeWithRetry executes a function with retry logic. Calls rejected by
+// an open circuit breaker are not retried.
+func (c *Client) executeWithRetry(ctx context.Context, operation func(context.Context) error) error {
 	var lastErr error
+	attempts := 0
 
 	for attempt := 0; attempt <= c.config.RetryAttempts; attempt++ {
+		attempts++
+
 		// Wait for rate limiter
 		if err := c.rateLimiter.Wait(ctx); err != nil {
 			return fmt.Errorf("rate limiter error: %w", err)
 		}
 
 		// Execute the operation
-		if err := operation(); err != nil {
+		opCtx, rejected := resilience.TrackRejections(ctx)
+		if err := operation(opCtx); err != nil {
 			lastErr = err
 
 			// Don't retry on context cancellation
@@ -212,6 +269,11 @@ func (c *Client) executeWithRetry(ctx context.Context, operation func() error) e
 				return ctx.Err()
 			}
 
+			// Don't retry while the circuit breaker is open
+			if rejected() {
+				break
+			}
+
 			// Log the attempt
 			logrus.WithError(err).WithField("attempt", attempt+1).Debug("SLURM API request failed")
 
@@ -245,12 +307,12 @@ func (c *Client) executeWithRetry(ctx context.Context, operation func() error) e
 	c.retryCount++
 	c.mu.Unlock()
 
-	return fmt.Errorf("operation failed after %d attempts: %w", c.config.RetryAttempts+1, lastErr)
+	return fmt.Errorf("operation failed after %d attempts: %w", attempts, lastErr)
 }
 
 // executeListOperation is a generic helper for executing SLURM List operations with retry logic
 func (c *Client) executeListOperation(ctx context.Context, listFunc func(context.Context) error) error {
-	return c.executeWithRetry(ctx, func() error {
+	return c.executeWithRetry(ctx, func(ctx context.Context) error {
 		reqCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
 		defer cancel()
 		return listFunc(reqCtx)
@@ -309,7 +371,7 @@ func (c *Client) GetPartitions(ctx context.Context) (*slurm.PartitionList, error
 func (c *Client) GetInfo(ctx context.Context) (*slurm.ClusterInfo, error) {
 	var result *slurm.ClusterInfo
 
-	err := c.executeWithRetry(ctx, func() error {
+	err := c.executeWithRetry(ctx, func(ctx context.Context) error {
 		reqCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
 		defer cancel()
 
@@ -330,7 +392,7 @@ func (c *Client) GetInfo(ctx context.Context) (*slurm.ClusterInfo, error) {
 func (c *Client) GetStats(ctx context.Context) (*slurm.ClusterStats, error) {
 	var result *slurm.ClusterStats
 
-	err := c.executeWithRetry(ctx, func() error {
+	err := c.executeWithRetry(ctx, func(ctx context.Context) error {
 		reqCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
 		defer cancel()
 
@@ -354,6 +416,9 @@ func (c *Client) Close() error {
 
 	c.connected = false
 	// No explicit cleanup needed for the HTTP client in this case
+	if c.cache != nil {
+		c.cache.cache.Close()
+	}
 	return nil
 }
 
@@ -371,7 +436,34 @@ func (c *Client) ResetRetryCount() {
 	c.retryCount = 0
 }
 
-// GetSlurmClient returns the underlying SLURM client
+// GetSlurmClient returns the SLURM client for collectors, which shares the
+// endpoints they fetch
 func (c *Client) GetSlurmClient() slurm.SlurmClient {
-	return c.client
+	return c.shared
+}
+
+// FetchMetrics returns the fetch duration, payload size, sharing and cache
+// metrics of the endpoints fetched through GetSlurmClient
+func (c *Client) FetchMetrics() prometheus.Collector {
+	if c.cache == nil {
+		return c.shared
+	}
+	return fetchMetrics{c.shared, c.cache}
+}
+
+// fetchMetrics combines the metrics of the snapshot and caching clients
+type fetchMetrics []prometheus.Collector
+
+// Describe implements prometheus.Collector
+func (m fetchMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range m {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m fetchMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range m {
+		collector.Collect(ch)
+	}
 }
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+	"github.com/jontk/slurm-exporter/internal/tracing"
+)
+
+// newHTTPClient returns the HTTP client used for slurmrestd requests, which
+// measures response sizes and applies the circuit breaker, TLS and tracing
+// settings
+func (o clientOptions) newHTTPClient(cfg *config.SLURMConfig) (*http.Client, error) {
+	tracingEnabled := o.tracer != nil && o.tracer.IsEnabled()
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if cfg.TLS != (config.SLURMTLSConfig{}) {
+		tlsConfig, err := newTLSConfig(&cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.TLSClientConfig = tlsConfig
+		transport = base
+	}
+	if o.breakers != nil {
+		transport = &breakerTransport{next: transport, breakers: o.breakers}
+	}
+	transport = &payloadTransport{next: transport}
+	if tracingEnabled {
+		transport = tracing.NewTransport(transport, o.tracer)
+	}
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+// newTLSConfig builds the TLS settings for slurmrestd connections
+func newTLSConfig(cfg *config.SLURMTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//nolint:gosec // Explicitly requested through slurm.tls.insecure_skip_verify
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SLURM CA certificate %s: %w", cfg.CACertFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in SLURM CA certificate %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SLURM client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// breakerTransport sends every request through the circuit breaker of its
+// endpoint. While a breaker is open, requests fail at once instead of
+// waiting for an unresponsive slurmrestd or slurmctld to time out.
+type breakerTransport struct {
+	next     http.RoundTripper
+	breakers *resilience.CircuitBreakerManager
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointName(req.URL.Path)
+	var (
+		resp         *http.Response
+		roundTripErr error
+	)
+	err := t.breakers.GetOrCreate(endpoint).CallWithContext(req.Context(), func(ctx context.Context) error {
+		resp, roundTripErr = t.next.RoundTrip(req)
+		switch {
+		case errors.Is(roundTripErr, context.Canceled):
+			// The caller gave up; that says nothing about the endpoint
+			return nil
+		case roundTripErr != nil:
+			return roundTripErr
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return fmt.Errorf("slurmrestd returned %s", resp.Status)
+		}
+		return nil
+	})
+
+	if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrCircuitHalfOpen) {
+		resilience.MarkRejected(req.Context())
+		return nil, fmt.Errorf("%s: %w", endpoint, err)
+	}
+	// Server errors are counted above and left to the client to report
+	return resp, roundTripErr
+}
+
+// endpointName names the endpoint of a slurmrestd request path for circuit
+// breakers, e.g. "slurm/jobs" for /slurm/v0.0.44/jobs and "slurm/job" for
+// /slurm/v0.0.44/job/42, keeping the number of breakers bounded
+func endpointName(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 3 && strings.HasPrefix(segments[1], "v") {
+		return segments[0] + "/" + segments[2]
+	}
+	if len(segments) >= 2 {
+		return segments[0] + "/" + segments[1]
+	}
+	return segments[0]
+}
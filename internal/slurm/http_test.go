@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/jontk/slurm-exporter/internal/metrics"
+	"github.com/jontk/slurm-exporter/internal/resilience"
+)
+
+func TestBreakerTransport(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	breakers := resilience.NewCircuitBreakerManager(config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 2,
+		ResetTimeout:     time.Minute,
+		HalfOpenRequests: 1,
+	}, metrics.NewPerformanceMetrics("test"), logger)
+	client := &http.Client{Transport: &breakerTransport{next: http.DefaultTransport, breakers: breakers}}
+
+	get := func(path string) (*http.Response, bool, error) {
+		ctx, rejected := resilience.TrackRejections(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		return resp, rejected(), err
+	}
+
+	// Server errors reach the caller and count as failures
+	for range 2 {
+		resp, rejected, err := get("/slurm/v0.0.44/jobs")
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable || rejected {
+			t.Fatalf("get() = %v, %v, %v", resp, rejected, err)
+		}
+	}
+
+	// The open breaker rejects without calling slurmrestd
+	_, rejected, err := get("/slurm/v0.0.44/jobs?update_time=0")
+	if !errors.Is(err, resilience.ErrCircuitOpen) || !rejected {
+		t.Errorf("get() error = %v, rejected = %v, want an open circuit", err, rejected)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+
+	// Other endpoints have their own breaker
+	if _, rejected, err := get("/slurm/v0.0.44/nodes"); err != nil || rejected {
+		t.Errorf("nodes: error = %v, rejected = %v", err, rejected)
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"/slurm/v0.0.44/jobs":        "slurm/jobs",
+		"/slurm/v0.0.44/job/42":      "slurm/job",
+		"/slurmdb/v0.0.43/accounts/": "slurmdb/accounts",
+		"/openapi/v3":                "openapi/v3",
+		"/ping":                      "ping",
+	}
+	for path, want := range tests {
+		if got := endpointName(path); got != want {
+			t.Errorf("endpointName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
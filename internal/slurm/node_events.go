@@ -0,0 +1,722 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+)
+
+const (
+	// nodeEventType is the type of the events derived from node snapshots
+	nodeEventType = "state_change"
+
+	// nodeEventSourceName identifies the source in events and streams
+	nodeEventSourceName = "slurmrestd_poll"
+
+	// nodeStreamType is the type reported for every stream
+	nodeStreamType = "node_state"
+
+	// subscriptionCancelled asks ManageNodeEventSubscription to end a stream
+	subscriptionCancelled = "cancelled"
+)
+
+// NodeEventSourceOptions controls how the node event source polls slurmrestd
+type NodeEventSourceOptions struct {
+	// PollInterval is the time between two node snapshots
+	PollInterval time.Duration
+
+	// BufferSize is the number of events buffered per stream; events are
+	// dropped for a stream whose consumer falls this far behind
+	BufferSize int
+
+	// HistoryLength bounds the events kept per node for GetNodeEventHistory
+	HistoryLength int
+}
+
+// DefaultNodeEventSourceOptions returns the default node event source options
+func DefaultNodeEventSourceOptions() *NodeEventSourceOptions {
+	return &NodeEventSourceOptions{
+		PollInterval:  30 * time.Second,
+		BufferSize:    1000,
+		HistoryLength: 100,
+	}
+}
+
+// NodeEventSource implements collector.NodeStateStreamingSLURMClient on top
+// of the slurmrestd node list. slurmrestd has no push API, so the source polls
+// the nodes while at least one stream is open and emits an event for every
+// node whose state changed since the previous snapshot, one per partition of
+// the node.
+type NodeEventSource struct {
+	client slurm.SlurmClient
+	opts   NodeEventSourceOptions
+	now    func() time.Time
+
+	mu       sync.Mutex
+	nodes    map[string]*nodeEventState
+	history  map[string][]*collector.NodeEvent
+	streams  map[string]*nodeEventStream
+	stop     context.CancelFunc
+	started  time.Time
+	opened   int64
+	sequence int64
+	stats    nodeEventStats
+}
+
+// nodeEventState is what the last snapshot showed of a node
+type nodeEventState struct {
+	state string
+	since time.Time
+}
+
+// nodeEventStream is one consumer of the events
+type nodeEventStream struct {
+	id        string
+	started   time.Time
+	events    chan collector.NodeStateChangeEvent
+	delivered int64
+	dropped   int64
+	lastEvent time.Time
+}
+
+// nodeEventStats counts the polls and events of the source
+type nodeEventStats struct {
+	polls         int64
+	pollErrors    int64
+	lastPoll      time.Time
+	lastDuration  time.Duration
+	minDuration   time.Duration
+	maxDuration   time.Duration
+	totalDuration time.Duration
+	lastErr       error
+	events        int64
+	delivered     int64
+	dropped       int64
+}
+
+// NewNodeEventSource creates a node event source backed by client
+func NewNodeEventSource(client slurm.SlurmClient, opts *NodeEventSourceOptions) *NodeEventSource {
+	defaults := DefaultNodeEventSourceOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	s := &NodeEventSource{
+		client:  client,
+		opts:    *opts,
+		now:     time.Now,
+		nodes:   make(map[string]*nodeEventState),
+		history: make(map[string][]*collector.NodeEvent),
+		streams: make(map[string]*nodeEventStream),
+	}
+	if s.opts.PollInterval <= 0 {
+		s.opts.PollInterval = defaults.PollInterval
+	}
+	if s.opts.BufferSize <= 0 {
+		s.opts.BufferSize = defaults.BufferSize
+	}
+	if s.opts.HistoryLength <= 0 {
+		s.opts.HistoryLength = defaults.HistoryLength
+	}
+	return s
+}
+
+// Ensure NodeEventSource satisfies the collector interface
+var _ collector.NodeStateStreamingSLURMClient = (*NodeEventSource)(nil)
+
+// StreamNodeStateChanges opens a stream of node state changes. The first
+// poll only records the current states; later polls emit the changes. The
+// channel is closed once ctx ends, and polling stops with the last stream.
+func (s *NodeEventSource) StreamNodeStateChanges(ctx context.Context) (<-chan collector.NodeStateChangeEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opened++
+	stream := &nodeEventStream{
+		id:      fmt.Sprintf("stream-%d", s.opened),
+		started: s.now(),
+		events:  make(chan collector.NodeStateChangeEvent, s.opts.BufferSize),
+	}
+	s.streams[stream.id] = stream
+
+	if s.stop == nil {
+		pollCtx, cancel := context.WithCancel(context.Background())
+		s.stop = cancel
+		s.started = s.now()
+		go s.run(pollCtx, s.opts.PollInterval)
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.closeStream(stream.id)
+	}()
+
+	return stream.events, nil
+}
+
+// closeStream ends a stream and stops polling when it was the last one
+func (s *NodeEventSource) closeStream(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream, ok := s.streams[id]
+	if !ok {
+		return
+	}
+	delete(s.streams, id)
+	close(stream.events)
+
+	if len(s.streams) == 0 && s.stop != nil {
+		s.stop()
+		s.stop = nil
+	}
+}
+
+// run polls the nodes every interval until ctx ends
+func (s *NodeEventSource) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if events, err := s.poll(ctx); err == nil {
+			s.deliver(events)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the nodes and returns the state changes since the last poll
+func (s *NodeEventSource) poll(ctx context.Context) ([]collector.NodeStateChangeEvent, error) {
+	start := s.now()
+	list, err := s.client.Nodes().List(ctx, nil)
+	if err == nil && list == nil {
+		err = errors.New("empty node list")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	duration := s.now().Sub(start)
+	s.stats.polls++
+	s.stats.lastPoll = start
+	s.stats.lastDuration = duration
+	s.stats.totalDuration += duration
+	if s.stats.minDuration == 0 || duration < s.stats.minDuration {
+		s.stats.minDuration = duration
+	}
+	if duration > s.stats.maxDuration {
+		s.stats.maxDuration = duration
+	}
+	s.stats.lastErr = err
+	if err != nil {
+		s.stats.pollErrors++
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(list.Nodes))
+	var events []collector.NodeStateChangeEvent
+	for _, node := range list.Nodes {
+		if node.Name == nil {
+			continue
+		}
+		name := *node.Name
+		seen[name] = true
+		state := nodeEventStateName(node.State)
+
+		previous, known := s.nodes[name]
+		if !known {
+			// A node's first snapshot is its baseline
+			s.nodes[name] = &nodeEventState{state: state, since: stateSince(node, state, start)}
+			continue
+		}
+		if previous.state == state {
+			continue
+		}
+
+		events = append(events, s.newEvents(node, previous, state, start)...)
+		s.nodes[name] = &nodeEventState{state: state, since: stateSince(node, state, start)}
+	}
+
+	// Nodes removed from the cluster start from a new baseline if they return
+	for name := range s.nodes {
+		if !seen[name] {
+			delete(s.nodes, name)
+		}
+	}
+
+	s.stats.events += int64(len(events))
+	return events, nil
+}
+
+// newEvents builds the events for a node whose state changed, one for each
+// of its partitions
+func (s *NodeEventSource) newEvents(node slurm.Node, previous *nodeEventState, state string, at time.Time) []collector.NodeStateChangeEvent {
+	s.sequence++
+	name := *node.Name
+
+	event := collector.NodeStateChangeEvent{
+		NodeID:          name,
+		NodeName:        name,
+		PreviousState:   previous.state,
+		CurrentState:    state,
+		StateChangeTime: at,
+		EventType:       nodeEventType,
+		Features:        node.Features,
+		ActiveFeatures:  node.ActiveFeatures,
+		BootTime:        node.BootTime,
+		SlurmdStartTime: node.SlurmdStartTime,
+		MaintenanceMode: state == string(api.NodeStateMaintenance),
+		StreamingSource: nodeEventSourceName,
+		EventSequence:   s.sequence,
+		CorrelationID:   fmt.Sprintf("%s-%d", name, s.sequence),
+		TriggerType:     "poll",
+	}
+	if node.Reason != nil {
+		event.StateChangeReason = *node.Reason
+	}
+	if node.CPUs != nil {
+		event.TotalCPUs = int(*node.CPUs)
+	}
+	if node.AllocCPUs != nil {
+		event.AllocatedCPUs = int(*node.AllocCPUs)
+	}
+	event.FreeCPUs = event.TotalCPUs - event.AllocatedCPUs
+	if node.RealMemory != nil {
+		event.TotalMemory = *node.RealMemory * 1024 * 1024
+	}
+	if node.AllocMemory != nil {
+		event.AllocatedMemory = *node.AllocMemory * 1024 * 1024
+	}
+	event.FreeMemory = event.TotalMemory - event.AllocatedMemory
+	if node.Architecture != nil {
+		event.Architecture = *node.Architecture
+	}
+	if node.OperatingSystem != nil {
+		event.OS = *node.OperatingSystem
+	}
+	if node.Version != nil {
+		event.SlurmdVersion = *node.Version
+	}
+	if node.Address != nil {
+		event.NodeAddress = *node.Address
+	}
+	if node.Hostname != nil {
+		event.NodeHostname = *node.Hostname
+	}
+	if node.Comment != nil {
+		event.NodeComment = *node.Comment
+	}
+	if node.Weight != nil {
+		event.NodeWeight = int(*node.Weight)
+	}
+	if isOutOfService(previous.state) {
+		// At least this long; the node may have been out before the exporter saw it
+		event.ActualDowntime = at.Sub(previous.since)
+	}
+
+	partitions := node.Partitions
+	if len(partitions) == 0 {
+		partitions = []string{""}
+	}
+	events := make([]collector.NodeStateChangeEvent, 0, len(partitions))
+	for _, partition := range partitions {
+		e := event
+		e.EventID = fmt.Sprintf("%s-%s-%d", name, partition, s.sequence)
+		e.PartitionName = partition
+		events = append(events, e)
+	}
+
+	s.record(name, event)
+	return events
+}
+
+// record adds a state change to the history of a node
+func (s *NodeEventSource) record(name string, event collector.NodeStateChangeEvent) {
+	history := append(s.history[name], &collector.NodeEvent{
+		EventID:       event.CorrelationID,
+		NodeID:        name,
+		EventType:     event.EventType,
+		EventTime:     event.StateChangeTime,
+		EventSource:   nodeEventSourceName,
+		CorrelationID: event.CorrelationID,
+		EventData: map[string]interface{}{
+			"previous_state": event.PreviousState,
+			"current_state":  event.CurrentState,
+			"reason":         event.StateChangeReason,
+		},
+		DeliveryStatus: "detected",
+	})
+	if len(history) > s.opts.HistoryLength {
+		history = history[len(history)-s.opts.HistoryLength:]
+	}
+	s.history[name] = history
+}
+
+// deliver sends events to every stream, dropping them for full streams
+func (s *NodeEventSource) deliver(events []collector.NodeStateChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, stream := range s.streams {
+		for _, event := range events {
+			select {
+			case stream.events <- event:
+				stream.delivered++
+				stream.lastEvent = now
+				s.stats.delivered++
+			default:
+				stream.dropped++
+				s.stats.dropped++
+			}
+		}
+	}
+}
+
+// GetNodeStreamingConfiguration reports how the source polls
+func (s *NodeEventSource) GetNodeStreamingConfiguration(ctx context.Context) (*collector.NodeStreamingConfiguration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &collector.NodeStreamingConfiguration{
+		StreamingEnabled:     s.stop != nil,
+		EventBufferSize:      s.opts.BufferSize,
+		EventFlushInterval:   s.opts.PollInterval,
+		StreamingProtocol:    nodeEventSourceName,
+		DeduplicationEnabled: true,
+		HealthCheckInterval:  s.opts.PollInterval,
+		StateChangeDetection: true,
+	}, nil
+}
+
+// ConfigureNodeStreaming applies the buffer size and poll interval
+// (EventFlushInterval) of config. They take effect for streams opened and
+// polling started afterwards.
+func (s *NodeEventSource) ConfigureNodeStreaming(ctx context.Context, config *collector.NodeStreamingConfiguration) error {
+	if config == nil {
+		return errors.New("node streaming configuration is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if config.EventBufferSize > 0 {
+		s.opts.BufferSize = config.EventBufferSize
+	}
+	if config.EventFlushInterval > 0 {
+		s.opts.PollInterval = config.EventFlushInterval
+	}
+	return nil
+}
+
+// GetActiveNodeStreams reports the open streams
+func (s *NodeEventSource) GetActiveNodeStreams(ctx context.Context) ([]*collector.ActiveNodeStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := s.healthLocked()
+	streams := make([]*collector.ActiveNodeStream, 0, len(s.streams))
+	for _, stream := range s.sortedStreamsLocked() {
+		streamHealth := health
+		if stream.dropped > 0 && streamHealth == "healthy" {
+			streamHealth = "warning"
+		}
+		streams = append(streams, &collector.ActiveNodeStream{
+			StreamID:         stream.id,
+			StreamStartTime:  stream.started,
+			LastEventTime:    stream.lastEvent,
+			EventCount:       stream.delivered,
+			StreamStatus:     "active",
+			StreamType:       nodeStreamType,
+			ConsumerID:       stream.id,
+			BufferedEvents:   len(stream.events),
+			QueuedEvents:     len(stream.events),
+			ProcessedEvents:  stream.delivered,
+			DroppedEvents:    stream.dropped,
+			DeliveryAttempts: stream.delivered + stream.dropped,
+			StreamHealth:     streamHealth,
+			LastHeartbeat:    s.stats.lastPoll,
+		})
+	}
+	return streams, nil
+}
+
+// GetNodeEventHistory returns the state changes of a node within duration
+func (s *NodeEventSource) GetNodeEventHistory(ctx context.Context, nodeID string, duration time.Duration) ([]*collector.NodeEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := s.now().Add(-duration)
+	var events []*collector.NodeEvent
+	for _, event := range s.history[nodeID] {
+		if duration <= 0 || !event.EventTime.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetNodeStreamingMetrics reports the event and stream totals
+func (s *NodeEventSource) GetNodeStreamingMetrics(ctx context.Context) (*collector.NodeStreamingMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &collector.NodeStreamingMetrics{
+		TotalStreams:         s.opened,
+		ActiveStreams:        int64(len(s.streams)),
+		EventsPerSecond:      s.eventRateLocked(),
+		AverageEventLatency:  s.averageDurationLocked(),
+		MaxEventLatency:      s.stats.maxDuration,
+		MinEventLatency:      s.stats.minDuration,
+		TotalEventsProcessed: s.stats.delivered,
+		TotalEventsDropped:   s.stats.dropped,
+		ErrorRate:            s.errorRateLocked(),
+		SuccessRate:          1 - s.errorRateLocked(),
+		QueueDepth:           s.queueDepthLocked(),
+		StreamingHealth:      1 - s.errorRateLocked(),
+	}, nil
+}
+
+// GetNodeEventFilters returns no filters; every state change is emitted
+func (s *NodeEventSource) GetNodeEventFilters(ctx context.Context) ([]*collector.NodeEventFilter, error) {
+	return []*collector.NodeEventFilter{}, nil
+}
+
+// GetNodeStreamingHealthStatus reports whether the last poll succeeded
+func (s *NodeEventSource) GetNodeStreamingHealthStatus(ctx context.Context) (*collector.NodeStreamingHealthStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &collector.NodeStreamingHealthStatus{
+		OverallHealth:       s.healthLocked(),
+		ComponentHealth:     map[string]string{"slurmrestd": "healthy"},
+		LastHealthCheck:     s.stats.lastPoll,
+		HealthCheckDuration: s.stats.lastDuration,
+		HealthScore:         1 - s.errorRateLocked(),
+		ServiceAvailability: 1 - s.errorRateLocked(),
+		ConfigurationValid:  true,
+		MonitoringEnabled:   s.stop != nil,
+	}
+	if s.stats.lastErr != nil {
+		status.ComponentHealth["slurmrestd"] = "unhealthy"
+		status.CriticalIssues = append(status.CriticalIssues, fmt.Sprintf("node poll failed: %v", s.stats.lastErr))
+	}
+	if s.stats.dropped > 0 {
+		status.WarningIssues = append(status.WarningIssues, fmt.Sprintf("%d events dropped for slow streams", s.stats.dropped))
+	}
+	if s.stop != nil {
+		status.StreamingUptime = s.now().Sub(s.started)
+	}
+	return status, nil
+}
+
+// GetNodeEventSubscriptions reports the open streams as subscriptions
+func (s *NodeEventSource) GetNodeEventSubscriptions(ctx context.Context) ([]*collector.NodeEventSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptions := make([]*collector.NodeEventSubscription, 0, len(s.streams))
+	for _, stream := range s.sortedStreamsLocked() {
+		subscriptions = append(subscriptions, &collector.NodeEventSubscription{
+			SubscriptionID:     stream.id,
+			SubscriberName:     stream.id,
+			SubscriptionType:   nodeStreamType,
+			EventTypes:         []string{nodeEventType},
+			DeliveryMethod:     "channel",
+			SubscriptionStatus: "active",
+			CreatedTime:        stream.started,
+			LastDeliveryTime:   stream.lastEvent,
+			DeliveryCount:      stream.delivered,
+			FailedDeliveries:   stream.dropped,
+			DeliveryGuarantee:  "at_most_once",
+		})
+	}
+	return subscriptions, nil
+}
+
+// ManageNodeEventSubscription cancels the stream of a subscription whose
+// status is "cancelled". Streams are opened with StreamNodeStateChanges.
+func (s *NodeEventSource) ManageNodeEventSubscription(ctx context.Context, subscription *collector.NodeEventSubscription) error {
+	if subscription == nil {
+		return errors.New("node event subscription is nil")
+	}
+	if subscription.SubscriptionStatus != subscriptionCancelled {
+		return fmt.Errorf("unsupported subscription status %q, streams are opened with StreamNodeStateChanges", subscription.SubscriptionStatus)
+	}
+
+	s.mu.Lock()
+	_, ok := s.streams[subscription.SubscriptionID]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("node event subscription %q not found", subscription.SubscriptionID)
+	}
+	s.closeStream(subscription.SubscriptionID)
+	return nil
+}
+
+// GetNodeEventProcessingStats reports the polls and the events they produced
+func (s *NodeEventSource) GetNodeEventProcessingStats(ctx context.Context) (*collector.NodeEventProcessingStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queues := make(map[string]int64, len(s.streams))
+	for id, stream := range s.streams {
+		queues[id] = int64(len(stream.events))
+	}
+
+	return &collector.NodeEventProcessingStats{
+		ProcessingStartTime:   s.started,
+		TotalEventsReceived:   s.stats.events,
+		TotalEventsProcessed:  s.stats.delivered,
+		TotalEventsDropped:    s.stats.dropped,
+		TotalProcessingTime:   s.stats.totalDuration,
+		AverageProcessingTime: s.averageDurationLocked(),
+		MaxProcessingTime:     s.stats.maxDuration,
+		MinProcessingTime:     s.stats.minDuration,
+		ProcessingThroughput:  s.eventRateLocked(),
+		ErrorRate:             s.errorRateLocked(),
+		SuccessRate:           1 - s.errorRateLocked(),
+		NetworkErrors:         s.stats.pollErrors,
+		BackpressureEvents:    s.stats.dropped,
+		ProcessingQueues:      queues,
+		PerformanceCounters: map[string]int64{
+			"polls":       s.stats.polls,
+			"poll_errors": s.stats.pollErrors,
+		},
+	}, nil
+}
+
+// GetNodeStreamingPerformanceMetrics reports poll latency and event rates
+func (s *NodeEventSource) GetNodeStreamingPerformanceMetrics(ctx context.Context) (*collector.NodeStreamingPerformanceMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &collector.NodeStreamingPerformanceMetrics{
+		Throughput:        s.eventRateLocked(),
+		Latency:           s.averageDurationLocked(),
+		MaxLatency:        s.stats.maxDuration,
+		MessageRate:       s.eventRateLocked(),
+		ErrorRate:         s.errorRateLocked(),
+		SuccessRate:       1 - s.errorRateLocked(),
+		ActiveConnections: int64(len(s.streams)),
+		QueueDepth:        s.queueDepthLocked(),
+	}, nil
+}
+
+// healthLocked summarises the state of the polls
+func (s *NodeEventSource) healthLocked() string {
+	switch {
+	case s.stats.lastErr != nil:
+		return "critical"
+	case s.stats.dropped > 0:
+		return "warning"
+	default:
+		return "healthy"
+	}
+}
+
+// errorRateLocked is the fraction of polls that failed
+func (s *NodeEventSource) errorRateLocked() float64 {
+	if s.stats.polls == 0 {
+		return 0
+	}
+	return float64(s.stats.pollErrors) / float64(s.stats.polls)
+}
+
+// eventRateLocked is the number of events per second since polling started
+func (s *NodeEventSource) eventRateLocked() float64 {
+	if s.started.IsZero() {
+		return 0
+	}
+	elapsed := s.now().Sub(s.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.stats.events) / elapsed
+}
+
+// averageDurationLocked is the mean duration of a poll
+func (s *NodeEventSource) averageDurationLocked() time.Duration {
+	if s.stats.polls == 0 {
+		return 0
+	}
+	return s.stats.totalDuration / time.Duration(s.stats.polls)
+}
+
+// queueDepthLocked is the number of events buffered across streams
+func (s *NodeEventSource) queueDepthLocked() int64 {
+	var depth int64
+	for _, stream := range s.streams {
+		depth += int64(len(stream.events))
+	}
+	return depth
+}
+
+// sortedStreamsLocked returns the streams in the order they were opened
+func (s *NodeEventSource) sortedStreamsLocked() []*nodeEventStream {
+	streams := make([]*nodeEventStream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		if !streams[i].started.Equal(streams[j].started) {
+			return streams[i].started.Before(streams[j].started)
+		}
+		return streams[i].id < streams[j].id
+	})
+	return streams
+}
+
+// nodeEventStateName reduces the state and flags of a node to the state
+// transitions are reported between: DOWN, then the DRAIN, FAIL and
+// MAINTENANCE flags, then the base state
+func nodeEventStateName(states []api.NodeState) string {
+	if len(states) == 0 {
+		return string(api.NodeStateUnknown)
+	}
+	for _, state := range []api.NodeState{api.NodeStateDown, api.NodeStateDrain, api.NodeStateFail, api.NodeStateMaintenance} {
+		for _, s := range states {
+			if s == state {
+				return string(state)
+			}
+		}
+	}
+	return string(states[0])
+}
+
+// isOutOfService reports whether jobs cannot be scheduled on a node in state
+func isOutOfService(state string) bool {
+	switch api.NodeState(state) {
+	case api.NodeStateDown, api.NodeStateDrain, api.NodeStateFail, api.NodeStateMaintenance:
+		return true
+	}
+	return false
+}
+
+// stateSince returns when a node entered state: the reason timestamp for
+// nodes taken out of service, otherwise when the exporter saw the state
+func stateSince(node slurm.Node, state string, observed time.Time) time.Time {
+	if isOutOfService(state) && !node.ReasonChangedAt.IsZero() && node.ReasonChangedAt.Before(observed) {
+		return node.ReasonChangedAt
+	}
+	return observed
+}
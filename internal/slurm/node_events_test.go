@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+func nodeEventTestNode(name, reason string, partitions []string, states ...api.NodeState) slurm.Node {
+	node := slurm.Node{Name: &name, State: states, Partitions: partitions}
+	if reason != "" {
+		node.Reason = &reason
+	}
+	return node
+}
+
+func newNodeEventTestSource(t *testing.T, lists ...*slurm.NodeList) *NodeEventSource {
+	t.Helper()
+	nodeManager := new(mocks.MockNodeManager)
+	for _, list := range lists[:len(lists)-1] {
+		nodeManager.On("List", mock.Anything, mock.Anything).Return(list, nil).Once()
+	}
+	// The last snapshot stays current
+	nodeManager.On("List", mock.Anything, mock.Anything).Return(lists[len(lists)-1], nil)
+	client := new(mocks.MockSlurmClient)
+	client.On("Nodes").Return(nodeManager)
+
+	source := NewNodeEventSource(client, nil)
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	source.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return source
+}
+
+func TestNodeEventSource_Poll(t *testing.T) {
+	t.Parallel()
+	partitions := []string{"compute", "gpu"}
+	source := newNodeEventTestSource(t,
+		&slurm.NodeList{Nodes: []slurm.Node{
+			nodeEventTestNode("node01", "", partitions, api.NodeStateIdle),
+			nodeEventTestNode("node02", "", []string{"compute"}, api.NodeStateAllocated),
+		}},
+		&slurm.NodeList{Nodes: []slurm.Node{
+			nodeEventTestNode("node01", "bad dimm", partitions, api.NodeStateIdle, api.NodeStateDrain),
+			nodeEventTestNode("node02", "", []string{"compute"}, api.NodeStateAllocated),
+		}},
+		&slurm.NodeList{Nodes: []slurm.Node{
+			nodeEventTestNode("node01", "", partitions, api.NodeStateIdle),
+		}},
+	)
+	ctx := context.Background()
+
+	// The first snapshot is the baseline
+	events, err := source.poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = source.poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for i, partition := range partitions {
+		assert.Equal(t, "node01", events[i].NodeName)
+		assert.Equal(t, partition, events[i].PartitionName)
+		assert.Equal(t, "IDLE", events[i].PreviousState)
+		assert.Equal(t, "DRAIN", events[i].CurrentState)
+		assert.Equal(t, "bad dimm", events[i].StateChangeReason)
+		assert.Zero(t, events[i].ActualDowntime)
+	}
+
+	// Returning to service reports how long the node was out
+	events, err = source.poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "DRAIN", events[0].PreviousState)
+	assert.Equal(t, "IDLE", events[0].CurrentState)
+	assert.Positive(t, events[0].ActualDowntime)
+
+	history, err := source.GetNodeEventHistory(ctx, "node01", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "DRAIN", history[0].EventData["current_state"])
+}
+
+func TestNodeEventSource_Stream(t *testing.T) {
+	t.Parallel()
+	source := newNodeEventTestSource(t,
+		&slurm.NodeList{Nodes: []slurm.Node{nodeEventTestNode("node01", "", nil, api.NodeStateIdle)}},
+		&slurm.NodeList{Nodes: []slurm.Node{nodeEventTestNode("node01", "", nil, api.NodeStateDown)}},
+	)
+	source.now = time.Now
+	source.opts.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := source.StreamNodeStateChanges(ctx)
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "IDLE", event.PreviousState)
+		assert.Equal(t, "DOWN", event.CurrentState)
+		assert.Empty(t, event.PartitionName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no node state change event")
+	}
+
+	cancel()
+	for range events {
+	}
+	streams, err := source.GetActiveNodeStreams(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, streams)
+}
@@ -0,0 +1,1982 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/config"
+)
+
+const (
+	// priorityAlgorithm is reported as the priority algorithm version
+	priorityAlgorithm = "priority/multifactor"
+
+	// maxPrioritySamples bounds the per-snapshot factor summaries kept for trends
+	maxPrioritySamples = 288
+
+	// priorityTolerance is the share of a job's priority the configured
+	// weights may leave unexplained before the job counts as mismatched
+	priorityTolerance = 0.05
+)
+
+// Factors of the multifactor priority plugin. The weighted factors come
+// first; nice is subtracted as is and unexplained covers whatever part of
+// the priority the weighted factors leave unexplained.
+const (
+	factorAge = iota
+	factorFairShare
+	factorSize
+	factorPartition
+	factorQoS
+	factorAssoc
+	factorTRES
+	factorNice
+	factorUnexplained
+	numPriorityFactors
+
+	numWeightedFactors = factorNice
+)
+
+// priorityFactorNames are the factor label values used by the collectors
+var priorityFactorNames = [numPriorityFactors]string{"age", "fairshare", "size", "partition", "qos", "assoc", "tres", "nice", "unexplained"}
+
+// priorityWeightKeys are the configuration keys of the weighted factors
+var priorityWeightKeys = [numWeightedFactors]string{"age", "fairshare", "job_size", "partition", "qos", "assoc", "tres"}
+
+// PriorityWeights mirrors the PriorityWeight* settings of slurm.conf, which
+// slurmrestd does not report
+type PriorityWeights struct {
+	Age       float64
+	FairShare float64
+	JobSize   float64
+	Partition float64
+	QoS       float64
+	Assoc     float64
+
+	// TRES is PriorityWeightTRES by TRES name as it appears in TRES
+	// strings, e.g. cpu, mem or gres/gpu
+	TRES map[string]float64
+}
+
+// values returns the weight of each weighted factor; the tres factor weighs
+// the sum of the per-TRES weights
+func (w PriorityWeights) values() [numWeightedFactors]float64 {
+	return [numWeightedFactors]float64{w.Age, w.FairShare, w.JobSize, w.Partition, w.QoS, w.Assoc, w.tresTotal()}
+}
+
+func (w PriorityWeights) tresTotal() float64 {
+	var total float64
+	for _, weight := range w.TRES {
+		total += weight
+	}
+	return total
+}
+
+// version identifies the weights in the configuration_version label
+func (w PriorityWeights) version() string {
+	h := fnv.New32a()
+	fmt.Fprint(h, w.values(), w.TRES)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// PriorityOptions controls how the priority adapter derives factors
+type PriorityOptions struct {
+	// SnapshotTTL is how long a fetched snapshot is reused, so that one
+	// collection pass issues a single set of API calls
+	SnapshotTTL time.Duration
+
+	// Weights are the PriorityWeight* settings of the cluster
+	Weights PriorityWeights
+
+	// MaxAge is the PriorityMaxAge setting: the pending time at which the
+	// age factor reaches 1
+	MaxAge time.Duration
+
+	// MaxTrackedJobs bounds the pending jobs reported for per-job analysis
+	MaxTrackedJobs int
+
+	// MaxTrackedUsers bounds the users and accounts reported for analysis
+	MaxTrackedUsers int
+}
+
+// DefaultPriorityOptions returns the default priority options
+func DefaultPriorityOptions() *PriorityOptions {
+	return &PriorityOptions{
+		SnapshotTTL:     15 * time.Second,
+		MaxAge:          7 * 24 * time.Hour,
+		MaxTrackedJobs:  100,
+		MaxTrackedUsers: 50,
+	}
+}
+
+// PriorityOptionsFromConfig returns the priority options for the collector
+// configuration
+func PriorityOptionsFromConfig(cfg *config.PriorityConfig) *PriorityOptions {
+	opts := DefaultPriorityOptions()
+	if cfg.MaxAge > 0 {
+		opts.MaxAge = cfg.MaxAge
+	}
+	opts.Weights = PriorityWeights{
+		Age:       cfg.Weights.Age,
+		FairShare: cfg.Weights.FairShare,
+		JobSize:   cfg.Weights.JobSize,
+		Partition: cfg.Weights.Partition,
+		QoS:       cfg.Weights.QoS,
+		Assoc:     cfg.Weights.Assoc,
+	}
+	if len(cfg.Weights.TRES) > 0 {
+		opts.Weights.TRES = make(map[string]float64, len(cfg.Weights.TRES))
+		for tres, weight := range cfg.Weights.TRES {
+			opts.Weights.TRES[strings.ToLower(tres)] = weight
+		}
+	}
+	return opts
+}
+
+// PriorityClient implements the job priority and priority factor collector
+// interfaces on top of the priorities slurmrestd reports for pending jobs.
+// The normalized factors are derived the way the multifactor plugin computes
+// them: from the accrued age, the shares endpoint, the job size against the
+// cluster CPUs and the partition, QoS and association priorities.
+// The tres factor is the job's share of each weighted TRES of its partition.
+// Contributions apply the configured weights, and whatever part of a job's
+// priority they leave unexplained is reported as the unexplained factor.
+// Scheduling predictions come from the queue analysis adapter.
+type PriorityClient struct {
+	client slurm.SlurmClient
+	queue  *QueueAnalysisClient
+	opts   *PriorityOptions
+	now    func() time.Time
+
+	mu          sync.Mutex
+	snapshot    *prioritySnapshot
+	history     map[string][]priorityPoint
+	samples     []prioritySample
+	predictions map[string]priorityPrediction
+	validations map[string]*collector.PriorityPredictionValidation
+	outcomes    []queuePredictionOutcome
+}
+
+// priorityJob is a pending job with its priority broken into factors
+type priorityJob struct {
+	*queueJob
+	age          time.Duration
+	nice         float64
+	normalized   [numPriorityFactors]float64
+	contribution [numPriorityFactors]float64
+
+	queueRank     int
+	partitionRank int
+	userRank      int
+}
+
+// prioritySnapshot is one consistent view of the pending jobs
+type prioritySnapshot struct {
+	fetchedAt time.Time
+	duration  time.Duration
+	pending   []*priorityJob // highest priority first
+	byID      map[string]*priorityJob
+	started   map[string]time.Time // job -> start, for jobs that left the queue
+	inputs    *priorityInputs
+}
+
+// priorityInputs are the values the factors are normalized against. Each
+// source is optional; without it the corresponding factor reads as zero.
+type priorityInputs struct {
+	partitions   map[string]float64 // partition -> PriorityJobFactor
+	maxPartition float64
+	tres         map[string]tresCounts // partition -> configured TRES
+	qos          map[string]float64    // QoS -> priority
+	maxQoS       float64
+	assocs       map[string]float64 // association -> priority
+	maxAssoc     float64
+	shares       map[string]float64 // association -> fair-share factor
+	accountUsers map[string]map[string]bool
+	totalCPUs    float64
+}
+
+type priorityPoint struct {
+	at       time.Time
+	priority float64
+}
+
+// prioritySample summarises the pending jobs of one snapshot
+type prioritySample struct {
+	at        time.Time
+	means     [numPriorityFactors]float64 // mean contribution of each factor
+	explained float64                     // mean share explained by the weights
+	drift     float64                     // mean priority change per hour
+	changes   float64                     // priority changes per hour
+}
+
+type priorityPrediction struct {
+	at             time.Time
+	predictedStart time.Time
+	confidence     float64
+}
+
+// NewPriorityClient creates a priority adapter over a SLURM client
+func NewPriorityClient(client slurm.SlurmClient, opts *PriorityOptions) *PriorityClient {
+	if opts == nil {
+		opts = DefaultPriorityOptions()
+	}
+	queueOpts := DefaultQueueAnalysisOptions()
+	queueOpts.SnapshotTTL = opts.SnapshotTTL
+	queueOpts.MaxTrackedJobs = opts.MaxTrackedJobs
+	queueOpts.MaxTrackedUsers = opts.MaxTrackedUsers
+
+	return &PriorityClient{
+		client:      client,
+		queue:       NewQueueAnalysisClient(client, queueOpts),
+		opts:        opts,
+		now:         time.Now,
+		history:     make(map[string][]priorityPoint),
+		predictions: make(map[string]priorityPrediction),
+		validations: make(map[string]*collector.PriorityPredictionValidation),
+	}
+}
+
+// ListPriorityTargets reports the highest-priority pending jobs, the users
+// and accounts with the most pending jobs, the partitions and QoS, and the
+// predicted jobs that have since started
+func (p *PriorityClient) ListPriorityTargets(ctx context.Context) (*collector.PriorityTargets, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make(map[string]bool, len(snap.inputs.partitions))
+	for name := range snap.inputs.partitions {
+		partitions[name] = true
+	}
+	qos := make(map[string]bool, len(snap.inputs.qos))
+	for name := range snap.inputs.qos {
+		qos[name] = true
+	}
+
+	targets := &collector.PriorityTargets{}
+	userJobs := make(map[string]int)
+	accountJobs := make(map[string]int)
+	for _, job := range snap.pending {
+		if p.opts.MaxTrackedJobs <= 0 || len(targets.JobIDs) < p.opts.MaxTrackedJobs {
+			targets.JobIDs = append(targets.JobIDs, job.id)
+		}
+		if job.user != "" {
+			userJobs[job.user]++
+		}
+		if job.account != "" {
+			accountJobs[job.account]++
+		}
+		if job.partition != "" {
+			partitions[job.partition] = true
+		}
+		qos[job.qos] = true
+	}
+	targets.Users = busiest(userJobs, p.opts.MaxTrackedUsers)
+	targets.Accounts = busiest(accountJobs, p.opts.MaxTrackedUsers)
+	targets.Partitions = sortedKeys(partitions)
+	targets.QoS = sortedKeys(qos)
+
+	for id := range p.validations {
+		targets.ValidatedJobIDs = append(targets.ValidatedJobIDs, id)
+	}
+	sort.Strings(targets.ValidatedJobIDs)
+
+	return targets, nil
+}
+
+// CalculateJobPriority breaks the priority of a pending job into the
+// weighted factor contributions
+func (p *PriorityClient) CalculateJobPriority(ctx context.Context, jobID string) (*collector.DetailedJobPriorityFactors, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, err := snap.pendingJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := p.opts.Weights
+	return &collector.DetailedJobPriorityFactors{
+		JobID:           job.id,
+		UserName:        job.user,
+		AccountName:     job.account,
+		PartitionName:   job.partition,
+		QoSName:         job.qos,
+		TotalPriority:   roundUint(job.priority),
+		AgePriority:     roundUint(job.contribution[factorAge]),
+		FairShareFactor: job.normalized[factorFairShare],
+		QoSPriority:     roundUint(job.contribution[factorQoS]),
+		PartitionPrio:   roundUint(job.contribution[factorPartition]),
+		SizePriority:    roundUint(job.contribution[factorSize]),
+		AssocPriority:   roundUint(job.contribution[factorAssoc]),
+		NicePriority:    int32(job.nice),
+		AgeWeight:       w.Age,
+		FairShareWeight: w.FairShare,
+		QoSWeight:       w.QoS,
+		PartitionWeight: w.Partition,
+		SizeWeight:      w.JobSize,
+		QueueRank:       job.queueRank,
+		PartitionRank:   job.partitionRank,
+		UserRank:        job.userRank,
+		SubmittedAt:     job.submit,
+		LastCalculated:  snap.fetchedAt,
+		NextUpdate:      snap.fetchedAt.Add(p.opts.SnapshotTTL),
+	}, nil
+}
+
+// PredictJobScheduling combines the queue analysis wait-time prediction with
+// the job's priority trend. The first prediction made for a job is checked
+// against its start once it leaves the queue.
+func (p *PriorityClient) PredictJobScheduling(ctx context.Context, jobID string) (*collector.JobSchedulingPrediction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, err := snap.pendingJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := p.queue.PredictWaitTime(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	position, err := p.queue.GetQueuePositionAnalysis(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	prediction := &collector.JobSchedulingPrediction{
+		JobID:              job.id,
+		EstimatedWaitTime:  wait.PredictedWaitTime,
+		EstimatedStartTime: wait.EstimatedStartTime,
+		ConfidenceLevel:    wait.ConfidenceLevel,
+		PredictionMethod:   wait.PredictionMethod,
+		QueuePosition:      position.CurrentPosition,
+		QueueDepth:         position.TotalQueueDepth,
+		JobsAhead:          position.JobsAhead,
+		ProcessingRate:     position.AdvancementRate,
+		LastUpdated:        snap.fetchedAt,
+	}
+	prediction.PriorityTrend, prediction.PriorityVelocity, prediction.PriorityVolatility = p.priorityTrend(job.id)
+	if wait.SystemLoadFactor > 0 {
+		prediction.ResourceAvailability = 1 - wait.SystemLoadFactor
+	}
+	if metrics, err := p.queue.GetQueueMetrics(ctx, job.partition); err == nil {
+		prediction.PartitionUtilization = metrics.ResourceUtilization
+		prediction.StarvationRisk = metrics.StarvationRisk
+	}
+
+	if _, ok := p.predictions[job.id]; !ok {
+		p.predictions[job.id] = priorityPrediction{
+			at:             snap.fetchedAt,
+			predictedStart: wait.EstimatedStartTime,
+			confidence:     wait.ConfidenceLevel,
+		}
+	}
+	return prediction, nil
+}
+
+// GetQueueAnalysis summarises the pending jobs of a partition by priority
+// tier, relative to the highest pending priority in the cluster
+func (p *PriorityClient) GetQueueAnalysis(ctx context.Context, partition string) (*collector.QueueAnalysis, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := p.queue.GetQueueMetrics(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &collector.QueueAnalysis{
+		PartitionName:       partition,
+		TotalJobs:           metrics.TotalJobs,
+		PendingJobs:         metrics.PendingJobs,
+		RunningJobs:         metrics.RunningJobs,
+		ProcessingRate:      metrics.JobsPerHour,
+		EfficiencyScore:     metrics.ProcessingEfficiency,
+		StarvationRisk:      metrics.StarvationRisk,
+		StarvationThreshold: p.queue.opts.StarvationThreshold.Seconds(),
+		LastAnalyzed:        snap.fetchedAt,
+	}
+
+	var waits []float64
+	for _, job := range snap.pending {
+		if job.partition != partition {
+			continue
+		}
+		switch snap.tier(job) {
+		case "high":
+			analysis.HighPriorityJobs++
+		case "medium":
+			analysis.MediumPriorityJobs++
+		default:
+			analysis.LowPriorityJobs++
+		}
+		waited := math.Max(0, snap.fetchedAt.Sub(job.submit).Seconds())
+		waits = append(waits, waited)
+		analysis.OldestJobAge = math.Max(analysis.OldestJobAge, waited)
+	}
+	analysis.AverageWaitTime, _ = meanVariance(waits)
+
+	return analysis, nil
+}
+
+// GetSystemPriorityStats summarises the priorities of all pending jobs
+func (p *PriorityClient) GetSystemPriorityStats(ctx context.Context) (*collector.SystemPriorityStats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := p.opts.Weights
+	stats := &collector.SystemPriorityStats{
+		TotalJobs:             len(snap.pending),
+		AlgorithmVersion:      priorityAlgorithm,
+		CalculationTime:       snap.duration.Seconds(),
+		AgeWeightGlobal:       w.Age,
+		FairShareWeightGlobal: w.FairShare,
+		QoSWeightGlobal:       w.QoS,
+		PartitionWeightGlobal: w.Partition,
+		LastUpdated:           snap.fetchedAt,
+	}
+
+	if len(snap.pending) > 0 {
+		values := priorities(snap.pending)
+		sort.Float64s(values)
+		mean, variance := meanVariance(values)
+		stats.AveragePriority = mean
+		stats.PriorityStdDev = math.Sqrt(variance)
+		stats.PriorityRange = roundUint(values[len(values)-1] - values[0])
+		stats.PriorityMedian = percentile(values, 0.5)
+	}
+
+	if n := len(p.samples); n > 0 {
+		latest := p.samples[n-1]
+		stats.CalculationAccuracy = latest.explained
+		stats.RebalanceFrequency = latest.changes
+		stats.PriorityInflation = math.Max(0, latest.drift)
+		stats.PriorityDeflation = math.Max(0, -latest.drift)
+	}
+
+	var volatilities []float64
+	for _, history := range p.history {
+		if len(history) > 1 {
+			volatilities = append(volatilities, variation(historyValues(history)))
+		}
+	}
+	stats.PriorityVolatility, _ = meanVariance(volatilities)
+
+	return stats, nil
+}
+
+// GetUserPriorityPattern describes the priorities and submission timing of a
+// user's pending jobs. Efficiency figures are not derived.
+func (p *PriorityClient) GetUserPriorityPattern(ctx context.Context, userName string) (*collector.UserPriorityPattern, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := snap.jobsWhere(func(job *priorityJob) bool { return job.user == userName })
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("user %s has no pending jobs", userName)
+	}
+
+	pattern := &collector.UserPriorityPattern{
+		UserName:     userName,
+		AccountName:  mostCommon(jobs, func(job *priorityJob) string { return job.account }),
+		LastAnalyzed: snap.fetchedAt,
+	}
+
+	values := priorities(jobs)
+	mean, variance := meanVariance(values)
+	pattern.AveragePriority = mean
+	pattern.PriorityVariance = variance
+	pattern.PriorityConsistency = 1 - math.Min(1, variation(values))
+	pattern.BehaviorScore, _ = meanVariance(normalizedValues(jobs, factorFairShare))
+
+	var hours [24]int
+	submits := make([]time.Time, 0, len(jobs))
+	for _, job := range jobs {
+		switch snap.tier(job) {
+		case "high":
+			pattern.HighPriorityRatio++
+		case "low":
+			pattern.LowPriorityRatio++
+		}
+		hours[job.submit.Hour()]++
+		submits = append(submits, job.submit)
+	}
+	pattern.HighPriorityRatio /= float64(len(jobs))
+	pattern.LowPriorityRatio /= float64(len(jobs))
+
+	peak := 0
+	for _, count := range hours {
+		peak = max(peak, count)
+	}
+	for hour, count := range hours {
+		if count == peak {
+			pattern.PeakSubmissionHours = append(pattern.PeakSubmissionHours, hour)
+		}
+	}
+
+	// Jobs submitted within a minute of another one count as a batch
+	sort.Slice(submits, func(i, j int) bool { return submits[i].Before(submits[j]) })
+	batched := 0
+	for i := range submits {
+		if (i > 0 && submits[i].Sub(submits[i-1]) <= time.Minute) ||
+			(i < len(submits)-1 && submits[i+1].Sub(submits[i]) <= time.Minute) {
+			batched++
+		}
+	}
+	pattern.BatchingBehavior = float64(batched) / float64(len(submits))
+	if span := submits[len(submits)-1].Sub(submits[0]).Hours(); span > 0 {
+		pattern.SubmissionFrequency = float64(len(submits)-1) / span
+	}
+
+	switch {
+	case len(jobs) == 1:
+		pattern.SubmissionPattern = "single"
+	case pattern.BatchingBehavior >= 0.5:
+		pattern.SubmissionPattern = "batch"
+	default:
+		pattern.SubmissionPattern = "spread"
+	}
+
+	return pattern, nil
+}
+
+// ValidatePriorityPrediction reports how the scheduling prediction for a job
+// compared with its actual start. Each validation is reported once.
+func (p *PriorityClient) ValidatePriorityPrediction(ctx context.Context, jobID string) (*collector.PriorityPredictionValidation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	validation, ok := p.validations[jobID]
+	if !ok {
+		return nil, fmt.Errorf("no started prediction for job %s", jobID)
+	}
+	delete(p.validations, jobID)
+	return validation, nil
+}
+
+// GetPriorityFactorBreakdown reports the raw, normalized, weighted and
+// effective value of each factor of a pending job
+func (p *PriorityClient) GetPriorityFactorBreakdown(ctx context.Context, jobID string) (*collector.PriorityFactorBreakdown, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, err := snap.pendingJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := p.opts.Weights
+	inputs := snap.inputs
+	dominant, secondary := job.dominant()
+	explained, _ := explainedBy([]*priorityJob{job}, w.values())
+
+	return &collector.PriorityFactorBreakdown{
+		JobID:         job.id,
+		UserName:      job.user,
+		AccountName:   job.account,
+		PartitionName: job.partition,
+		QoSName:       job.qos,
+
+		AgeValue:       job.age.Seconds(),
+		FairShareValue: job.normalized[factorFairShare],
+		QoSValue:       inputs.qos[job.qos],
+		PartitionValue: inputs.partitions[job.partition],
+		SizeValue:      job.cpus,
+		AssocValue:     inputs.association(inputs.assocs, job.queueJob),
+		NiceValue:      job.nice,
+
+		AgeWeight:       w.Age,
+		FairShareWeight: w.FairShare,
+		QoSWeight:       w.QoS,
+		PartitionWeight: w.Partition,
+		SizeWeight:      w.JobSize,
+		AssocWeight:     w.Assoc,
+		TRESWeight:      w.tresTotal(),
+		// The nice value is subtracted from the priority unweighted
+		NiceWeight: 1,
+
+		AgeContribution:         job.contribution[factorAge],
+		FairShareContribution:   job.contribution[factorFairShare],
+		QoSContribution:         job.contribution[factorQoS],
+		PartitionContribution:   job.contribution[factorPartition],
+		SizeContribution:        job.contribution[factorSize],
+		AssocContribution:       job.contribution[factorAssoc],
+		NiceContribution:        job.contribution[factorNice],
+		TRESContribution:        job.contribution[factorTRES],
+		UnexplainedContribution: job.contribution[factorUnexplained],
+
+		AgeNormalized:       job.normalized[factorAge],
+		FairShareNormalized: job.normalized[factorFairShare],
+		QoSNormalized:       job.normalized[factorQoS],
+		PartitionNormalized: job.normalized[factorPartition],
+		SizeNormalized:      job.normalized[factorSize],
+		AssocNormalized:     job.normalized[factorAssoc],
+		TRESNormalized:      job.normalized[factorTRES],
+
+		AgeEffective:         job.effective(factorAge),
+		FairShareEffective:   job.effective(factorFairShare),
+		QoSEffective:         job.effective(factorQoS),
+		PartitionEffective:   job.effective(factorPartition),
+		SizeEffective:        job.effective(factorSize),
+		AssocEffective:       job.effective(factorAssoc),
+		NiceEffective:        job.effective(factorNice),
+		TRESEffective:        job.effective(factorTRES),
+		UnexplainedEffective: job.effective(factorUnexplained),
+
+		TotalPriority:      job.priority,
+		DominantFactor:     factorName(dominant),
+		SecondaryFactor:    factorName(secondary),
+		FactorBalance:      job.balance(),
+		ConfigurationScore: explained,
+		AnalyzedAt:         snap.fetchedAt,
+	}, nil
+}
+
+// GetSystemFactorWeights reports the configured weights, the range of each
+// factor's contribution across pending jobs and how well the weights
+// reproduce the priorities slurmrestd reports
+func (p *PriorityClient) GetSystemFactorWeights(ctx context.Context) (*collector.SystemFactorWeights, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := p.opts.Weights
+	current := w.values()
+	weights := &collector.SystemFactorWeights{
+		AgeWeightGlobal:       w.Age,
+		FairShareWeightGlobal: w.FairShare,
+		QoSWeightGlobal:       w.QoS,
+		PartitionWeightGlobal: w.Partition,
+		SizeWeightGlobal:      w.JobSize,
+		AssocWeightGlobal:     w.Assoc,
+		NiceWeightGlobal:      1,
+		AgeWeightRange:        snap.contributionRange(factorAge),
+		FairShareWeightRange:  snap.contributionRange(factorFairShare),
+		QoSWeightRange:        snap.contributionRange(factorQoS),
+		PartitionWeightRange:  snap.contributionRange(factorPartition),
+		SizeWeightRange:       snap.contributionRange(factorSize),
+		WeightBalance:         balance(current[:]),
+		ConfigurationVersion:  w.version(),
+		CalibrationStability:  p.explainedStability(),
+		LastCalibration:       snap.fetchedAt,
+		NextCalibration:       snap.fetchedAt.Add(p.opts.SnapshotTTL),
+		LastUpdated:           snap.fetchedAt,
+	}
+	weights.WeightEffectiveness, weights.CalibrationAccuracy = explainedBy(snap.pending, current)
+	if fitted, err := snap.fitWeights(current); err == nil {
+		weights.WeightOptimality = optimality(weights.WeightEffectiveness, snap.pending, fitted)
+	}
+	weights.OptimizationRecommended = len(snap.pending) > 0 && weights.CalibrationAccuracy < 1-2*priorityTolerance
+
+	return weights, nil
+}
+
+// GetUserFactorProfile compares the factor contributions of a user's
+// pending jobs with the rest of the queue
+func (p *PriorityClient) GetUserFactorProfile(ctx context.Context, userName string) (*collector.UserFactorProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := snap.jobsWhere(func(job *priorityJob) bool { return job.user == userName })
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("user %s has no pending jobs", userName)
+	}
+
+	profile := &collector.UserFactorProfile{
+		UserName:     userName,
+		AccountName:  mostCommon(jobs, func(job *priorityJob) string { return job.account }),
+		LastAnalyzed: snap.fetchedAt,
+	}
+	profile.AvgAgeContribution, profile.AgeVariance = meanVariance(contributions(jobs, factorAge))
+	profile.AvgFairShareContribution, profile.FairShareVariance = meanVariance(contributions(jobs, factorFairShare))
+	profile.AvgQoSContribution, profile.QoSVariance = meanVariance(contributions(jobs, factorQoS))
+	profile.AvgPartitionContribution, profile.PartitionVariance = meanVariance(contributions(jobs, factorPartition))
+	profile.AvgSizeContribution, profile.SizeVariance = meanVariance(contributions(jobs, factorSize))
+
+	profile.DominantFactorPattern = mostCommon(jobs, func(job *priorityJob) string {
+		dominant, _ := job.dominant()
+		return factorName(dominant)
+	})
+	var matching int
+	for _, job := range jobs {
+		if dominant, _ := job.dominant(); factorName(dominant) == profile.DominantFactorPattern {
+			matching++
+		}
+	}
+	profile.FactorStability = float64(matching) / float64(len(jobs))
+
+	userMean, _ := meanVariance(priorities(jobs))
+	clusterMean, clusterVariance := meanVariance(priorities(snap.pending))
+	if top := snap.pending[0].priority; top > 0 {
+		profile.FactorOptimizationScore = userMean / top
+	}
+	if stddev := math.Sqrt(clusterVariance); stddev > 0 {
+		profile.DeviationFromNorm = (userMean - clusterMean) / stddev
+	}
+	switch deviation := math.Abs(profile.DeviationFromNorm); {
+	case deviation >= 2:
+		profile.DeviationSignificance = "high"
+	case deviation >= 1:
+		profile.DeviationSignificance = "medium"
+	default:
+		profile.DeviationSignificance = "low"
+	}
+
+	// Explain the deviation by the factor that differs most from the queue
+	largest, factor := 0.0, -1
+	for f := range numPriorityFactors {
+		userFactor, _ := meanVariance(contributions(jobs, f))
+		clusterFactor, _ := meanVariance(contributions(snap.pending, f))
+		if diff := userFactor - clusterFactor; math.Abs(diff) > math.Abs(largest) {
+			largest, factor = diff, f
+		}
+	}
+	if factor >= 0 {
+		direction := "above"
+		if largest < 0 {
+			direction = "below"
+		}
+		profile.DeviationExplanation = fmt.Sprintf("%s contribution %.0f %s the queue average",
+			factorName(factor), math.Abs(largest), direction)
+	}
+
+	return profile, nil
+}
+
+// GetAccountFactorSummary summarises the factors of an account's pending
+// jobs. Recommended actions are not derived.
+func (p *PriorityClient) GetAccountFactorSummary(ctx context.Context, accountName string) (*collector.AccountFactorSummary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := snap.jobsWhere(func(job *priorityJob) bool { return job.account == accountName })
+	users := make(map[string]bool)
+	for user := range snap.inputs.accountUsers[accountName] {
+		users[user] = true
+	}
+	if len(jobs) == 0 && len(users) == 0 {
+		return nil, fmt.Errorf("account %s has no pending jobs or users", accountName)
+	}
+
+	summary := &collector.AccountFactorSummary{
+		AccountName:                 accountName,
+		AgeFactorDistribution:       make(map[string]float64),
+		FairShareFactorDistribution: make(map[string]float64),
+		QoSFactorDistribution:       make(map[string]float64),
+		LastSummarized:              snap.fetchedAt,
+	}
+
+	byUser := make(map[string][]*priorityJob)
+	var balances []float64
+	for _, job := range jobs {
+		users[job.user] = true
+		byUser[job.user] = append(byUser[job.user], job)
+		balances = append(balances, job.balance())
+	}
+	summary.TotalUsers = len(users)
+	summary.ActiveUsers = len(byUser)
+	if len(jobs) == 0 {
+		return summary, nil
+	}
+
+	summary.AvgFactorBalance, _ = meanVariance(balances)
+	summary.FactorVarianceScore = variation(priorities(jobs))
+	summary.FactorEfficiencyScore, _ = explainedBy(jobs, p.opts.Weights.values())
+	for user, userJobs := range byUser {
+		summary.AgeFactorDistribution[user], _ = meanVariance(normalizedValues(userJobs, factorAge))
+		summary.FairShareFactorDistribution[user], _ = meanVariance(normalizedValues(userJobs, factorFairShare))
+		summary.QoSFactorDistribution[user], _ = meanVariance(normalizedValues(userJobs, factorQoS))
+	}
+
+	// The share of the account's jobs below the median pending priority
+	all := priorities(snap.pending)
+	sort.Float64s(all)
+	median := percentile(all, 0.5)
+	var below int
+	for _, job := range jobs {
+		if job.priority < median {
+			below++
+		}
+	}
+	summary.OptimizationPotential = float64(below) / float64(len(jobs))
+
+	return summary, nil
+}
+
+// GetPartitionFactorAnalysis reports the partition factor of a partition and
+// how much of its pending jobs' priority comes from it. Optimisation scores
+// and recommendations are not derived.
+func (p *PriorityClient) GetPartitionFactorAnalysis(ctx context.Context, partition string) (*collector.PartitionFactorAnalysis, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobFactor, known := snap.inputs.partitions[partition]
+	jobs := snap.jobsWhere(func(job *priorityJob) bool { return job.partition == partition })
+	if !known && len(jobs) == 0 {
+		return nil, fmt.Errorf("partition %s not found", partition)
+	}
+
+	w := p.opts.Weights
+	analysis := &collector.PartitionFactorAnalysis{
+		PartitionName:             partition,
+		PartitionWeight:           w.Partition,
+		PartitionFactorMultiplier: ratio(jobFactor, snap.inputs.maxPartition),
+		LastAnalyzed:              snap.fetchedAt,
+	}
+	analysis.PartitionPriorityBonus = w.Partition * analysis.PartitionFactorMultiplier
+	if len(jobs) == 0 {
+		return analysis, nil
+	}
+
+	analysis.FactorUtilizationRate = ratio(sum(contributions(jobs, factorPartition)), sum(priorities(jobs)))
+	analysis.FactorEffectiveness, _ = explainedBy(jobs, w.values())
+	analysis.FactorBalance = meanBalance(jobs)
+
+	return analysis, nil
+}
+
+// GetQoSFactorAnalysis reports the priority of a QoS and how much of the
+// queue's priority comes from it. Performance and optimisation scores are
+// not derived.
+func (p *PriorityClient) GetQoSFactorAnalysis(ctx context.Context, qosName string) (*collector.QoSFactorAnalysis, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	qosPriority, known := snap.inputs.qos[qosName]
+	jobs := snap.jobsWhere(func(job *priorityJob) bool { return job.qos == qosName })
+	if !known && len(jobs) == 0 {
+		return nil, fmt.Errorf("QoS %s not found", qosName)
+	}
+
+	analysis := &collector.QoSFactorAnalysis{
+		QoSName:          qosName,
+		QoSPriorityValue: roundUint(qosPriority),
+		QoSWeight:        p.opts.Weights.QoS,
+		QoSMultiplier:    ratio(qosPriority, snap.inputs.maxQoS),
+		LastAnalyzed:     snap.fetchedAt,
+	}
+	if len(jobs) == 0 {
+		return analysis, nil
+	}
+
+	var effective []float64
+	for _, job := range jobs {
+		effective = append(effective, job.effective(factorQoS))
+	}
+	analysis.QoSFactorEffectiveness, _ = meanVariance(effective)
+	analysis.QoSUtilizationRate = float64(len(jobs)) / float64(len(snap.pending))
+	qosMean, _ := meanVariance(contributions(jobs, factorQoS))
+	queueMean, _ := meanVariance(priorities(snap.pending))
+	analysis.QoSFactorImpact = ratio(qosMean, queueMean)
+	analysis.QoSBalanceScore = meanBalance(jobs)
+
+	return analysis, nil
+}
+
+// GetFactorTrendAnalysis follows the mean contribution of a factor across
+// the snapshots taken within period. Seasonality and cycles are not derived.
+func (p *PriorityClient) GetFactorTrendAnalysis(ctx context.Context, factor string, period string) (*collector.FactorTrendAnalysis, error) {
+	f, ok := factorIndex(factor)
+	if !ok {
+		return nil, fmt.Errorf("unknown priority factor %q", factor)
+	}
+	window, err := time.ParseDuration(period)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trend period %q: %w", period, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	since := snap.fetchedAt.Add(-window)
+	var hours, values []float64
+	for _, sample := range p.samples {
+		if sample.at.Before(since) {
+			continue
+		}
+		hours = append(hours, sample.at.Sub(since).Hours())
+		values = append(values, sample.means[f])
+	}
+
+	analysis := &collector.FactorTrendAnalysis{
+		FactorName:           factor,
+		AnalysisPeriod:       period,
+		TrendDirection:       trend(values),
+		TrendVelocity:        slope(hours, values),
+		TrendVolatility:      variation(values),
+		PredictionConfidence: sampleConfidence(len(values)),
+		PredictionTimeframe:  period,
+		LastAnalyzed:         snap.fetchedAt,
+	}
+	analysis.FutureTrendPrediction = analysis.TrendDirection
+	if half := len(values) / 2; half >= 2 {
+		if span := hours[len(hours)-1] - hours[0]; span > 0 {
+			analysis.TrendAcceleration = (slope(hours[half:], values[half:]) - slope(hours[:half], values[:half])) / span
+		}
+	}
+
+	return analysis, nil
+}
+
+// GetFactorImpactAnalysis reports how much of the pending jobs' priority a
+// factor accounts for and how it moves with the total and the other factors
+func (p *PriorityClient) GetFactorImpactAnalysis(ctx context.Context, factor string) (*collector.FactorImpactAnalysis, error) {
+	f, ok := factorIndex(factor)
+	if !ok {
+		return nil, fmt.Errorf("unknown priority factor %q", factor)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(snap.pending) < 2 {
+		return nil, fmt.Errorf("not enough pending jobs to analyse the %s factor", factor)
+	}
+
+	values := contributions(snap.pending, f)
+	totals := priorities(snap.pending)
+	var effective []float64
+	for _, job := range snap.pending {
+		effective = append(effective, job.effective(f))
+	}
+
+	impact := &collector.FactorImpactAnalysis{
+		FactorName:         factor,
+		ImpactCorrelation:  correlation(values, totals),
+		FactorInteractions: make(map[string]float64),
+		LastAnalyzed:       snap.fetchedAt,
+	}
+	impact.ImpactScore, _ = meanVariance(effective)
+	switch {
+	case impact.ImpactScore > 0.5:
+		impact.ImpactSignificance = "high"
+	case impact.ImpactScore > 0.2:
+		impact.ImpactSignificance = "medium"
+	default:
+		impact.ImpactSignificance = "low"
+	}
+	_, valueVariance := meanVariance(values)
+	_, totalVariance := meanVariance(totals)
+	if totalVariance > 0 {
+		impact.ImpactSensitivity = math.Sqrt(valueVariance / totalVariance)
+	}
+
+	means := make([]float64, 0, len(p.samples))
+	for _, sample := range p.samples {
+		means = append(means, sample.means[f])
+	}
+	if n := len(p.samples); n > 1 {
+		if hours := p.samples[n-1].at.Sub(p.samples[n-2].at).Hours(); hours > 0 {
+			impact.ImpactChangeRate = (means[n-1] - means[n-2]) / hours
+		}
+	}
+	impact.ImpactVolatility = variation(means)
+	impact.ImpactStability = 1 - math.Min(1, impact.ImpactVolatility)
+
+	for other := range numPriorityFactors {
+		if other == f {
+			continue
+		}
+		c := correlation(values, contributions(snap.pending, other))
+		impact.FactorInteractions[factorName(other)] = c
+		switch {
+		case c > 0.5:
+			impact.FactorSynergies = append(impact.FactorSynergies, factorName(other))
+		case c < -0.5:
+			impact.FactorConflicts = append(impact.FactorConflicts, factorName(other))
+		}
+	}
+
+	return impact, nil
+}
+
+// OptimizeFactorWeights fits the weights that best reproduce the priorities
+// of the pending jobs. The cluster's PriorityWeight* settings are what the
+// fit recovers, so the recommendation is for the exporter configuration to
+// match them rather than a change to the cluster. Only the system scope is
+// supported.
+func (p *PriorityClient) OptimizeFactorWeights(ctx context.Context, scope string) (*collector.FactorOptimizationResult, error) {
+	if scope != "system" {
+		return nil, fmt.Errorf("unsupported optimization scope %q", scope)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := p.opts.Weights.values()
+	fitted, err := snap.fitWeights(current)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &collector.FactorOptimizationResult{
+		OptimizationScope:        scope,
+		CurrentConfiguration:     weightMap(current),
+		CurrentBalance:           balance(current[:]),
+		RecommendedConfiguration: weightMap(fitted),
+		RecommendedActions:       weightActions(current, fitted),
+		OptimizedAt:              snap.fetchedAt,
+	}
+	result.CurrentEffectiveness, _ = explainedBy(snap.pending, current)
+	result.ExpectedEffectiveness, _ = explainedBy(snap.pending, fitted)
+	result.ExpectedImprovement = result.ExpectedEffectiveness - result.CurrentEffectiveness
+
+	return result, nil
+}
+
+// ValidateFactorConfiguration checks the configured weights against the
+// priorities of the pending jobs and the factor inputs slurmrestd provides
+func (p *PriorityClient) ValidateFactorConfiguration(ctx context.Context) (*collector.FactorConfigurationValidation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(snap.pending) == 0 {
+		return nil, fmt.Errorf("no pending jobs to validate the priority weights against")
+	}
+
+	w := p.opts.Weights
+	current := w.values()
+	validation := &collector.FactorConfigurationValidation{
+		ValidationScope: "system",
+		StabilityScore:  p.explainedStability(),
+		ValidatedAt:     snap.fetchedAt,
+	}
+	validation.HealthScore, validation.ValidationScore = explainedBy(snap.pending, current)
+
+	if sum(current[:]) == 0 {
+		validation.ValidationIssues = append(validation.ValidationIssues,
+			"collectors.priority.weights are all zero, so factor contributions cannot be derived")
+	} else if validation.ValidationScore < 1-2*priorityTolerance {
+		validation.ValidationIssues = append(validation.ValidationIssues,
+			fmt.Sprintf("configured weights reproduce the priority of %.0f%% of pending jobs", validation.ValidationScore*100))
+	}
+
+	inputs := snap.inputs
+	if w.FairShare > 0 && len(inputs.shares) == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "fair-share weight is set but slurmrestd reported no shares")
+	}
+	if w.JobSize > 0 && inputs.totalCPUs == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "job size weight is set but the cluster CPU count is unavailable")
+	}
+	if w.Partition > 0 && inputs.maxPartition == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "partition weight is set but no partition reports a PriorityJobFactor")
+	}
+	if w.QoS > 0 && inputs.maxQoS == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "QoS weight is set but no QoS reports a priority")
+	}
+	if w.Assoc > 0 && inputs.maxAssoc == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "association weight is set but no association reports a priority")
+	}
+	if w.tresTotal() > 0 && len(inputs.tres) == 0 {
+		validation.ValidationWarnings = append(validation.ValidationWarnings, "TRES weights are set but no partition reports its configured TRES")
+	}
+
+	validation.IsValid = len(validation.ValidationIssues) == 0
+	switch {
+	case !validation.IsValid:
+		validation.ConfigurationHealth = "invalid"
+	case len(validation.ValidationWarnings) > 0:
+		validation.ConfigurationHealth = "degraded"
+	default:
+		validation.ConfigurationHealth = "healthy"
+	}
+
+	if fitted, err := snap.fitWeights(current); err == nil {
+		validation.OptimalityScore = optimality(validation.HealthScore, snap.pending, fitted)
+		if !validation.IsValid {
+			validation.ImmediateActions = weightActions(current, fitted)
+		}
+	}
+
+	return validation, nil
+}
+
+// GetPriorityFactorSummary reports the mean factor contributions and the
+// dominant factors of the pending jobs by partition and QoS
+func (p *PriorityClient) GetPriorityFactorSummary(ctx context.Context) ([]*collector.PriorityFactorGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap, err := p.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type groupKey struct{ partition, qos string }
+	groups := make(map[groupKey]*collector.PriorityFactorGroup)
+	var keys []groupKey
+	for _, job := range snap.pending {
+		key := groupKey{job.partition, job.qos}
+		group, ok := groups[key]
+		if !ok {
+			group = &collector.PriorityFactorGroup{
+				PartitionName:     job.partition,
+				QoSName:           job.qos,
+				MeanContributions: make(map[string]float64, numPriorityFactors),
+				DominantJobs:      make(map[string]int),
+			}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.PendingJobs++
+		for f, contribution := range job.contribution {
+			group.MeanContributions[factorName(f)] += contribution
+		}
+		if dominant, _ := job.dominant(); dominant >= 0 {
+			group.DominantJobs[factorName(dominant)]++
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].partition != keys[j].partition {
+			return keys[i].partition < keys[j].partition
+		}
+		return keys[i].qos < keys[j].qos
+	})
+	summary := make([]*collector.PriorityFactorGroup, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		for factor := range group.MeanContributions {
+			group.MeanContributions[factor] /= float64(group.PendingJobs)
+		}
+		summary = append(summary, group)
+	}
+	return summary, nil
+}
+
+// refresh returns the current snapshot, fetching a new one once the TTL has
+// expired. The caller must hold p.mu.
+func (p *PriorityClient) refresh(ctx context.Context) (*prioritySnapshot, error) {
+	now := p.now()
+	if p.snapshot != nil && now.Sub(p.snapshot.fetchedAt) < p.opts.SnapshotTTL {
+		return p.snapshot, nil
+	}
+
+	start := time.Now()
+	jobList, err := p.client.Jobs().List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	snap := &prioritySnapshot{
+		fetchedAt: now,
+		byID:      make(map[string]*priorityJob),
+		started:   make(map[string]time.Time),
+		inputs:    p.fetchInputs(ctx),
+	}
+	if jobList != nil {
+		for i := range jobList.Jobs {
+			job := newQueueJob(&jobList.Jobs[i])
+			switch {
+			case job.id == "":
+				continue
+			case job.state != string(api.JobStatePending):
+				if job.hasStarted(now) {
+					snap.started[job.id] = job.start
+				}
+				continue
+			}
+			pending := p.newPriorityJob(&jobList.Jobs[i], job, snap.inputs, now)
+			snap.pending = append(snap.pending, pending)
+			snap.byID[pending.id] = pending
+		}
+	}
+	snap.rank()
+	snap.duration = time.Since(start)
+
+	p.observe(snap)
+	p.snapshot = snap
+	return snap, nil
+}
+
+// fetchInputs gathers the values the factors are normalized against
+func (p *PriorityClient) fetchInputs(ctx context.Context) *priorityInputs {
+	inputs := &priorityInputs{
+		partitions:   make(map[string]float64),
+		tres:         make(map[string]tresCounts),
+		qos:          make(map[string]float64),
+		assocs:       make(map[string]float64),
+		shares:       make(map[string]float64),
+		accountUsers: make(map[string]map[string]bool),
+	}
+
+	if partitionList, err := p.client.Partitions().List(ctx, nil); err != nil {
+		logrus.WithError(err).Debug("Priority analysis continuing without partition factors")
+	} else if partitionList != nil {
+		for _, partition := range partitionList.Partitions {
+			if partition.Name == nil {
+				continue
+			}
+			var factor float64
+			if partition.Priority != nil && partition.Priority.JobFactor != nil {
+				factor = float64(*partition.Priority.JobFactor)
+			}
+			inputs.partitions[*partition.Name] = factor
+			inputs.maxPartition = math.Max(inputs.maxPartition, factor)
+			if partition.TRES != nil && partition.TRES.Configured != nil {
+				inputs.tres[*partition.Name] = collector.ParseTRES(*partition.TRES.Configured)
+			}
+		}
+	}
+
+	if manager := p.client.QoS(); manager != nil {
+		if qosList, err := manager.List(ctx, nil); err != nil {
+			logrus.WithError(err).Debug("Priority analysis continuing without QoS priorities")
+		} else if qosList != nil {
+			for _, qos := range qosList.QoS {
+				if qos.Name == nil {
+					continue
+				}
+				var priority float64
+				if qos.Priority != nil {
+					priority = float64(*qos.Priority)
+				}
+				inputs.qos[*qos.Name] = priority
+				inputs.maxQoS = math.Max(inputs.maxQoS, priority)
+			}
+		}
+	}
+
+	// Association priorities are only needed when they carry weight
+	if p.opts.Weights.Assoc > 0 {
+		if manager := p.client.Associations(); manager != nil {
+			if assocList, err := manager.List(ctx, nil); err != nil {
+				logrus.WithError(err).Debug("Priority analysis continuing without association priorities")
+			} else if assocList != nil {
+				for _, assoc := range assocList.Associations {
+					if assoc.Priority == nil {
+						continue
+					}
+					priority := float64(*assoc.Priority)
+					inputs.assocs[associationKey(assoc.User, stringValue(assoc.Account), stringValue(assoc.Partition))] = priority
+					inputs.maxAssoc = math.Max(inputs.maxAssoc, priority)
+				}
+			}
+		}
+	}
+
+	if shares, err := p.client.GetShares(ctx, nil); err != nil {
+		logrus.WithError(err).Debug("Priority analysis continuing without fair-share factors")
+	} else if shares != nil {
+		for _, share := range shares.Shares {
+			if share.User == "" {
+				continue
+			}
+			inputs.shares[associationKey(share.User, share.Account, share.Partition)] = share.FairshareUsage
+			if inputs.accountUsers[share.Account] == nil {
+				inputs.accountUsers[share.Account] = make(map[string]bool)
+			}
+			inputs.accountUsers[share.Account][share.User] = true
+		}
+	}
+
+	if stats, err := p.client.Info().Stats(ctx); err != nil {
+		logrus.WithError(err).Debug("Priority analysis continuing without cluster statistics")
+	} else if stats != nil {
+		inputs.totalCPUs = float64(stats.TotalCPUs)
+	}
+
+	return inputs
+}
+
+// newPriorityJob normalizes the factors of a pending job and weighs them
+func (p *PriorityClient) newPriorityJob(job *slurm.Job, queued *queueJob, inputs *priorityInputs, now time.Time) *priorityJob {
+	pj := &priorityJob{queueJob: queued}
+
+	// Age accrues from the accrue time, which older versions do not report
+	accrue := job.AccrueTime
+	if accrue.IsZero() {
+		accrue = job.EligibleTime
+	}
+	if accrue.IsZero() {
+		accrue = queued.submit
+	}
+	if !accrue.IsZero() && accrue.Before(now) {
+		pj.age = now.Sub(accrue)
+	}
+	if job.Nice != nil {
+		pj.nice = float64(*job.Nice)
+	}
+
+	pj.normalized[factorAge] = math.Min(1, ratio(pj.age.Seconds(), p.opts.MaxAge.Seconds()))
+	pj.normalized[factorFairShare] = inputs.association(inputs.shares, queued)
+	pj.normalized[factorSize] = math.Min(1, ratio(queued.cpus, inputs.totalCPUs))
+	pj.normalized[factorPartition] = ratio(inputs.partitions[queued.partition], inputs.maxPartition)
+	pj.normalized[factorQoS] = ratio(inputs.qos[queued.qos], inputs.maxQoS)
+	pj.normalized[factorAssoc] = ratio(inputs.association(inputs.assocs, queued), inputs.maxAssoc)
+	pj.normalized[factorTRES] = p.tresFactor(jobTRES(queued.cpus, job.TRESReqStr, job.TRESAllocStr), inputs.tres[queued.partition])
+
+	explained := -pj.nice
+	for f, weight := range p.opts.Weights.values() {
+		pj.contribution[f] = weight * pj.normalized[f]
+		explained += pj.contribution[f]
+	}
+	pj.contribution[factorNice] = -pj.nice
+	pj.contribution[factorUnexplained] = queued.priority - explained
+
+	return pj
+}
+
+// tresFactor averages the job's share of each weighted TRES of its
+// partition by the TRES weights, so that the tres weight, their sum, scales
+// it to the PriorityWeightTRES contribution
+func (p *PriorityClient) tresFactor(requested, configured tresCounts) float64 {
+	total := p.opts.Weights.tresTotal()
+	if total == 0 {
+		return 0
+	}
+	var factor float64
+	for tres, weight := range p.opts.Weights.TRES {
+		factor += weight * math.Min(1, ratio(requested[tres], configured[tres]))
+	}
+	return factor / total
+}
+
+// observe updates the histories kept between snapshots. The caller must hold p.mu.
+func (p *PriorityClient) observe(snap *prioritySnapshot) {
+	now := snap.fetchedAt
+
+	var drift []float64
+	var changed int
+	sample := prioritySample{at: now}
+	for _, job := range snap.pending {
+		history := p.history[job.id]
+		if n := len(history); n > 0 {
+			last := history[n-1]
+			if hours := now.Sub(last.at).Hours(); hours > 0 {
+				drift = append(drift, (job.priority-last.priority)/hours)
+			}
+			if job.priority != last.priority {
+				changed++
+			}
+		}
+		history = append(history, priorityPoint{at: now, priority: job.priority})
+		if len(history) > maxPositionChanges {
+			history = history[1:]
+		}
+		p.history[job.id] = history
+
+		for f, contribution := range job.contribution {
+			sample.means[f] += contribution
+		}
+	}
+	for id := range p.history {
+		if _, ok := snap.byID[id]; !ok {
+			delete(p.history, id)
+		}
+	}
+
+	if n := len(snap.pending); n > 0 {
+		for f := range sample.means {
+			sample.means[f] /= float64(n)
+		}
+		sample.explained, _ = explainedBy(snap.pending, p.opts.Weights.values())
+		sample.drift, _ = meanVariance(drift)
+		if last := len(p.samples) - 1; last >= 0 {
+			if hours := now.Sub(p.samples[last].at).Hours(); hours > 0 {
+				sample.changes = float64(changed) / hours
+			}
+		}
+		p.samples = append(p.samples, sample)
+		if len(p.samples) > maxPrioritySamples {
+			p.samples = p.samples[1:]
+		}
+	}
+
+	// Predictions are resolved once the job leaves the queue; jobs that
+	// left without starting are dropped
+	for id, prediction := range p.predictions {
+		if _, pending := snap.byID[id]; pending {
+			continue
+		}
+		delete(p.predictions, id)
+		if start, ok := snap.started[id]; ok {
+			p.resolvePrediction(id, prediction, start, now)
+		}
+	}
+}
+
+// resolvePrediction records how a scheduling prediction compared with the
+// job's actual start. The caller must hold p.mu.
+func (p *PriorityClient) resolvePrediction(jobID string, prediction priorityPrediction, start, now time.Time) {
+	predicted := math.Max(0, prediction.predictedStart.Sub(prediction.at).Seconds())
+	actual := math.Max(0, start.Sub(prediction.at).Seconds())
+	p.outcomes = append(p.outcomes, queuePredictionOutcome{predicted: predicted, actual: actual})
+	if len(p.outcomes) > maxPredictionOutcomes {
+		p.outcomes = p.outcomes[1:]
+	}
+
+	diff := predicted - actual
+	tolerance := predictionTolerance(actual)
+	validation := &collector.PriorityPredictionValidation{
+		JobID:              jobID,
+		PredictedWaitTime:  predicted,
+		ActualWaitTime:     actual,
+		PredictionError:    diff,
+		PredictionAccuracy: math.Max(0, 1-math.Abs(diff)/math.Max(actual, 300)),
+		ConfidenceLevel:    prediction.confidence,
+		AbsoluteError:      math.Abs(diff),
+		ModelVersion:       queueModelVersion,
+		ModelAccuracy:      outcomeAccuracy(p.outcomes),
+		ModelConfidence:    sampleConfidence(len(p.outcomes)),
+		ValidatedAt:        now,
+	}
+	if actual > 0 {
+		validation.RelativeError = validation.AbsoluteError / actual
+	}
+	switch {
+	case validation.AbsoluteError <= tolerance:
+		validation.ErrorCategory = "accurate"
+	case diff > 0:
+		validation.ErrorCategory = "overestimate"
+	default:
+		validation.ErrorCategory = "underestimate"
+	}
+	switch {
+	case validation.AbsoluteError <= tolerance:
+		validation.ErrorSeverity = "low"
+	case validation.AbsoluteError <= 4*tolerance:
+		validation.ErrorSeverity = "medium"
+	default:
+		validation.ErrorSeverity = "high"
+	}
+
+	// Validations are held until the collector reports them
+	if len(p.validations) < maxPredictionOutcomes {
+		p.validations[jobID] = validation
+	}
+}
+
+// priorityTrend describes how the priority of a pending job has moved while
+// it was observed: the direction, the change per hour and its variation
+func (p *PriorityClient) priorityTrend(jobID string) (string, float64, float64) {
+	history := p.history[jobID]
+	values := historyValues(history)
+	var velocity float64
+	if n := len(history); n > 1 {
+		if hours := history[n-1].at.Sub(history[0].at).Hours(); hours > 0 {
+			velocity = (values[n-1] - values[0]) / hours
+		}
+	}
+	return trend(values), velocity, variation(values)
+}
+
+// explainedStability is 1 when the share of priority the weights explain
+// holds steady across snapshots
+func (p *PriorityClient) explainedStability() float64 {
+	if len(p.samples) == 0 {
+		return 0
+	}
+	values := make([]float64, 0, len(p.samples))
+	for _, sample := range p.samples {
+		values = append(values, sample.explained)
+	}
+	return 1 - math.Min(1, variation(values))
+}
+
+// pendingJob looks up a pending job
+func (s *prioritySnapshot) pendingJob(jobID string) (*priorityJob, error) {
+	job, ok := s.byID[jobID]
+	if !ok {
+		return nil, fmt.Errorf("job %s is not pending", jobID)
+	}
+	return job, nil
+}
+
+// rank orders the pending jobs by priority and numbers them across the
+// queue, within their partition and among the user's jobs
+func (s *prioritySnapshot) rank() {
+	sort.SliceStable(s.pending, func(i, j int) bool { return queuedBefore(s.pending[i].queueJob, s.pending[j].queueJob) })
+	partitions := make(map[string]int)
+	users := make(map[string]int)
+	for i, job := range s.pending {
+		partitions[job.partition]++
+		users[job.user]++
+		job.queueRank = i + 1
+		job.partitionRank = partitions[job.partition]
+		job.userRank = users[job.user]
+	}
+}
+
+// tier classifies a job's priority against the highest pending priority
+func (s *prioritySnapshot) tier(job *priorityJob) string {
+	share := ratio(job.priority, s.pending[0].priority)
+	switch {
+	case share >= 2.0/3:
+		return "high"
+	case share >= 1.0/3:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+func (s *prioritySnapshot) jobsWhere(keep func(*priorityJob) bool) []*priorityJob {
+	var jobs []*priorityJob
+	for _, job := range s.pending {
+		if keep(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// contributionRange returns the smallest and largest contribution of a factor
+func (s *prioritySnapshot) contributionRange(f int) []float64 {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, job := range s.pending {
+		low = math.Min(low, job.contribution[f])
+		high = math.Max(high, job.contribution[f])
+	}
+	return []float64{low, high}
+}
+
+// fitWeights estimates by least squares the weights that best reproduce the
+// pending jobs' priorities, rounded to the integers slurm.conf takes.
+// Factors are added one at a time, configured ones first; a factor the jobs
+// cannot tell apart from those already fitted, such as a job size shared by
+// every job, keeps its current weight. The tres weight is the sum of the
+// per-TRES weights, which a single fitted value cannot recover, so it is
+// never fitted.
+func (s *prioritySnapshot) fitWeights(current [numWeightedFactors]float64) ([numWeightedFactors]float64, error) {
+	order := []int{factorAge, factorFairShare, factorPartition, factorQoS, factorAssoc, factorSize}
+	sort.SliceStable(order, func(i, j int) bool { return current[order[i]] > 0 && current[order[j]] == 0 })
+
+	var active []int
+	var solution []float64
+	for _, f := range order {
+		candidate := append(append([]int(nil), active...), f)
+		if len(candidate) > len(s.pending) {
+			break
+		}
+		if x, ok := s.leastSquares(candidate); ok {
+			active, solution = candidate, x
+		}
+	}
+
+	fitted := current
+	if len(active) == 0 {
+		return fitted, fmt.Errorf("not enough pending jobs to fit priority weights")
+	}
+	for i, f := range active {
+		fitted[f] = math.Max(0, math.Round(solution[i]))
+	}
+	return fitted, nil
+}
+
+// leastSquares solves the normal equations of
+// priority + nice = sum(weight * normalized) over the given factors, with
+// the tres contribution taken as given
+func (s *prioritySnapshot) leastSquares(factors []int) ([]float64, bool) {
+	a := make([][]float64, len(factors))
+	b := make([]float64, len(factors))
+	for i := range a {
+		a[i] = make([]float64, len(factors))
+	}
+	for _, job := range s.pending {
+		target := job.priority + job.nice - job.contribution[factorTRES]
+		for i, fi := range factors {
+			b[i] += job.normalized[fi] * target
+			for j, fj := range factors {
+				a[i][j] += job.normalized[fi] * job.normalized[fj]
+			}
+		}
+	}
+	return solve(a, b)
+}
+
+// association looks up a per-association value, falling back from the
+// partition-specific association to the user's account association
+func (in *priorityInputs) association(values map[string]float64, job *queueJob) float64 {
+	if value, ok := values[associationKey(job.user, job.account, job.partition)]; ok {
+		return value
+	}
+	return values[associationKey(job.user, job.account, "")]
+}
+
+func associationKey(user, account, partition string) string {
+	return user + "/" + account + "/" + partition
+}
+
+func (j *priorityJob) effective(f int) float64 {
+	return ratio(j.contribution[f], j.priority)
+}
+
+// dominant returns the factors with the largest and second largest positive
+// contribution, or -1 where there is none
+func (j *priorityJob) dominant() (int, int) {
+	first, second := -1, -1
+	for f, contribution := range j.contribution {
+		switch {
+		case contribution <= 0:
+		case first < 0 || contribution > j.contribution[first]:
+			first, second = f, first
+		case second < 0 || contribution > j.contribution[second]:
+			second = f
+		}
+	}
+	return first, second
+}
+
+func (j *priorityJob) balance() float64 {
+	return balance(j.contribution[:])
+}
+
+// explainedBy returns the mean share of the jobs' priority that weights
+// account for, and the share of jobs they reproduce within priorityTolerance
+func explainedBy(jobs []*priorityJob, weights [numWeightedFactors]float64) (float64, float64) {
+	if len(jobs) == 0 {
+		return 0, 0
+	}
+	var total float64
+	var calibrated int
+	for _, job := range jobs {
+		residual := job.priority + job.nice
+		for f, weight := range weights {
+			residual -= weight * job.normalized[f]
+		}
+		explained := 1.0
+		if job.priority > 0 {
+			explained = math.Max(0, 1-math.Abs(residual)/job.priority)
+		} else if math.Abs(residual) >= 1 {
+			explained = 0
+		}
+		total += explained
+		if explained >= 1-priorityTolerance {
+			calibrated++
+		}
+	}
+	n := float64(len(jobs))
+	return total / n, float64(calibrated) / n
+}
+
+// optimality compares how much of the priority the current weights explain
+// with the fitted weights
+func optimality(current float64, jobs []*priorityJob, fitted [numWeightedFactors]float64) float64 {
+	best, _ := explainedBy(jobs, fitted)
+	if best <= 0 {
+		return 0
+	}
+	return math.Min(1, current/best)
+}
+
+func weightMap(weights [numWeightedFactors]float64) map[string]float64 {
+	m := make(map[string]float64, len(weights))
+	for f, weight := range weights {
+		m[factorName(f)] = weight
+	}
+	return m
+}
+
+// weightActions lists the configuration changes that bring the weights to
+// the fitted values, ignoring differences within 10%
+func weightActions(current, fitted [numWeightedFactors]float64) []string {
+	var actions []string
+	for f := range numWeightedFactors {
+		if math.Abs(fitted[f]-current[f]) > math.Max(1, 0.1*current[f]) {
+			actions = append(actions, fmt.Sprintf("set collectors.priority.weights.%s to %.0f", priorityWeightKeys[f], fitted[f]))
+		}
+	}
+	return actions
+}
+
+// solve solves a x = b by Gaussian elimination with partial pivoting
+func solve(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-9 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		x[row] = b[row]
+		for k := row + 1; k < n; k++ {
+			x[row] -= a[row][k] * x[k]
+		}
+		x[row] /= a[row][row]
+	}
+	return x, true
+}
+
+func factorIndex(name string) (int, bool) {
+	for f, factor := range priorityFactorNames {
+		if factor == name {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+func factorName(f int) string {
+	if f < 0 {
+		return "none"
+	}
+	return priorityFactorNames[f]
+}
+
+func priorities(jobs []*priorityJob) []float64 {
+	values := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		values = append(values, job.priority)
+	}
+	return values
+}
+
+func contributions(jobs []*priorityJob, f int) []float64 {
+	values := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		values = append(values, job.contribution[f])
+	}
+	return values
+}
+
+func normalizedValues(jobs []*priorityJob, f int) []float64 {
+	values := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		values = append(values, job.normalized[f])
+	}
+	return values
+}
+
+func historyValues(history []priorityPoint) []float64 {
+	values := make([]float64, 0, len(history))
+	for _, point := range history {
+		values = append(values, point.priority)
+	}
+	return values
+}
+
+func meanBalance(jobs []*priorityJob) float64 {
+	balances := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		balances = append(balances, job.balance())
+	}
+	mean, _ := meanVariance(balances)
+	return mean
+}
+
+// mostCommon returns the most frequent key of the jobs, the first
+// alphabetically on a tie
+func mostCommon(jobs []*priorityJob, key func(*priorityJob) string) string {
+	counts := make(map[string]int)
+	for _, job := range jobs {
+		counts[key(job)]++
+	}
+	var best string
+	for value, count := range counts {
+		if count > counts[best] || (count == counts[best] && value < best) {
+			best = value
+		}
+	}
+	return best
+}
+
+// busiest returns the keys with the most jobs, at most limit of them
+func busiest(counts map[string]int, limit int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// balance is 0 when a single value makes up the whole positive total and
+// grows as the total spreads across values
+func balance(values []float64) float64 {
+	var total, top float64
+	for _, value := range values {
+		if value > 0 {
+			total += value
+			top = math.Max(top, value)
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 1 - top/total
+}
+
+// variation is the coefficient of variation of values
+func variation(values []float64) float64 {
+	mean, variance := meanVariance(values)
+	if mean == 0 {
+		return 0
+	}
+	return math.Sqrt(variance) / math.Abs(mean)
+}
+
+// correlation is the Pearson correlation of xs and ys, 0 when either is constant
+func correlation(xs, ys []float64) float64 {
+	xMean, xVariance := meanVariance(xs)
+	yMean, yVariance := meanVariance(ys)
+	if xVariance == 0 || yVariance == 0 || len(xs) != len(ys) {
+		return 0
+	}
+	var covariance float64
+	for i := range xs {
+		covariance += (xs[i] - xMean) * (ys[i] - yMean)
+	}
+	covariance /= float64(len(xs))
+	return covariance / math.Sqrt(xVariance*yVariance)
+}
+
+// slope is the least-squares slope of ys against xs
+func slope(xs, ys []float64) float64 {
+	xMean, xVariance := meanVariance(xs)
+	yMean, _ := meanVariance(ys)
+	if xVariance == 0 || len(xs) != len(ys) {
+		return 0
+	}
+	var covariance float64
+	for i := range xs {
+		covariance += (xs[i] - xMean) * (ys[i] - yMean)
+	}
+	return covariance / float64(len(xs)) / xVariance
+}
+
+func ratio(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+func roundUint(value float64) uint64 {
+	return uint64(math.Round(math.Max(0, value)))
+}
+
+// Ensure PriorityClient satisfies the collector interfaces
+var (
+	_ collector.PrioritySLURMClient      = (*PriorityClient)(nil)
+	_ collector.PriorityTargetLister     = (*PriorityClient)(nil)
+	_ collector.PriorityFactorSummarizer = (*PriorityClient)(nil)
+)
@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+// The weights the test cluster computes priorities with
+var priorityTestWeights = PriorityWeights{Age: 1000, FairShare: 2000, Partition: 500, QoS: 1000}
+
+var priorityTestShares = map[string]float64{"alice": 0.5, "bob": 0.25, "carol": 0.75}
+
+// priorityTestJob builds a pending job whose priority follows the test
+// weights, for a job that has accrued age for the given share of MaxAge
+func priorityTestJob(id int32, user, qos string, age float64, nice int32) slurm.Job {
+	maxAge := DefaultPriorityOptions().MaxAge
+	priority := 1000*age + 2000*priorityTestShares[user] + 500 - float64(nice)
+	if qos == "high" {
+		priority += 1000
+	}
+	job := queueTestJob(id, user, "PENDING", uint32(math.Round(priority)), queueTestNow.Add(-time.Duration(age*float64(maxAge))), time.Time{})
+	job.QoS = &qos
+	job.Nice = &nice
+	return job
+}
+
+func priorityTestJobs() []slurm.Job {
+	return []slurm.Job{
+		priorityTestJob(1, "alice", "normal", 0.5, 0),
+		priorityTestJob(2, "bob", "high", 0.25, 0),
+		priorityTestJob(3, "alice", "normal", 0.1, 100),
+		priorityTestJob(4, "carol", "high", 0.8, 0),
+		priorityTestJob(5, "bob", "normal", 0.6, 0),
+	}
+}
+
+// newPriorityTestClient returns a client whose job list is produced by jobs
+// at the current test time, which advances by setTime
+func newPriorityTestClient(t *testing.T, weights PriorityWeights, jobs func(now time.Time) []slurm.Job) (*PriorityClient, func(time.Time)) {
+	t.Helper()
+	clock, setTime := newTestClock()
+	jobManager := mockJobList(jobs, clock)
+
+	name := "compute"
+	jobFactor := int32(10)
+	total := int32(64)
+	configured := "cpu=64,mem=256G,node=4,gres/gpu=8"
+	partitionManager := new(mocks.MockPartitionManager)
+	partitionManager.On("List", mock.Anything, mock.Anything).Return(&slurm.PartitionList{
+		Partitions: []slurm.Partition{{
+			Name:     &name,
+			CPUs:     &api.PartitionCPUs{Total: &total},
+			Priority: &api.PartitionPriority{JobFactor: &jobFactor},
+			TRES:     &api.PartitionTRES{Configured: &configured},
+		}},
+	}, nil)
+
+	qosManager := new(mocks.MockQoSManager)
+	var qosList []slurm.QoS
+	for _, qos := range []struct {
+		name     string
+		priority uint32
+	}{{"normal", 0}, {"high", 100}} {
+		qosList = append(qosList, slurm.QoS{Name: &qos.name, Priority: &qos.priority})
+	}
+	qosManager.On("List", mock.Anything, mock.Anything).Return(&slurm.QoSList{QoS: qosList}, nil)
+
+	infoManager := new(mocks.MockInfoManager)
+	infoManager.On("Stats", mock.Anything).Return(&slurm.ClusterStats{TotalCPUs: 64, AllocatedCPUs: 4, IdleCPUs: 60}, nil)
+
+	shares := &slurm.SharesList{Shares: []slurm.Share{{Account: "research"}}}
+	for user, factor := range priorityTestShares {
+		shares.Shares = append(shares.Shares, slurm.Share{User: user, Account: "research", FairshareUsage: factor})
+	}
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(jobManager)
+	client.On("Partitions").Return(partitionManager)
+	client.On("QoS").Return(qosManager)
+	client.On("Info").Return(infoManager)
+	client.On("GetShares", mock.Anything, mock.Anything).Return(shares, nil)
+
+	opts := DefaultPriorityOptions()
+	opts.Weights = weights
+	p := NewPriorityClient(client, opts)
+	p.now = clock
+	p.queue.now = clock
+	return p, setTime
+}
+
+func TestPriorityClient_FactorBreakdown(t *testing.T) {
+	t.Parallel()
+	p, _ := newPriorityTestClient(t, priorityTestWeights, func(time.Time) []slurm.Job { return priorityTestJobs() })
+	ctx := context.Background()
+
+	breakdown, err := p.GetPriorityFactorBreakdown(ctx, "1")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, breakdown.AgeNormalized, 1e-9)
+	assert.InDelta(t, 500, breakdown.AgeContribution, 1e-6)
+	assert.InDelta(t, 1000, breakdown.FairShareContribution, 1e-9)
+	assert.InDelta(t, 500, breakdown.PartitionContribution, 1e-9)
+	assert.Zero(t, breakdown.QoSContribution)
+	assert.Zero(t, breakdown.TRESContribution)
+	assert.InDelta(t, 0, breakdown.UnexplainedContribution, 1e-6)
+	assert.InDelta(t, 0.5, breakdown.FairShareEffective, 1e-9)
+	assert.Equal(t, "fairshare", breakdown.DominantFactor)
+	assert.InDelta(t, 1, breakdown.ConfigurationScore, 1e-6)
+
+	breakdown, err = p.GetPriorityFactorBreakdown(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, -100.0, breakdown.NiceContribution)
+
+	// Ranks follow the priorities slurmrestd reports
+	priority, err := p.CalculateJobPriority(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1500), priority.TotalPriority)
+	assert.Equal(t, 5, priority.QueueRank)
+	assert.Equal(t, 5, priority.PartitionRank)
+	assert.Equal(t, 2, priority.UserRank)
+	assert.Equal(t, int32(100), priority.NicePriority)
+
+	_, err = p.CalculateJobPriority(ctx, "42")
+	assert.Error(t, err)
+}
+
+func TestPriorityClient_TRESFactor(t *testing.T) {
+	t.Parallel()
+	weights := priorityTestWeights
+	weights.TRES = map[string]float64{"cpu": 1600, "gres/gpu": 800}
+	p, _ := newPriorityTestClient(t, weights, func(time.Time) []slurm.Job {
+		jobs := priorityTestJobs()
+		gpus := "cpu=4,mem=16G,node=1,gres/gpu=2"
+		jobs[1].TRESReqStr = &gpus
+		return jobs
+	})
+	ctx := context.Background()
+
+	// 4 of the partition's 64 CPUs
+	breakdown, err := p.GetPriorityFactorBreakdown(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 2400.0, breakdown.TRESWeight)
+	assert.InDelta(t, 100, breakdown.TRESContribution, 1e-9)
+	assert.InDelta(t, 100.0/2400, breakdown.TRESNormalized, 1e-9)
+
+	// The test priorities carry no TRES term, which leaves it unexplained
+	assert.InDelta(t, -100, breakdown.UnexplainedContribution, 1e-6)
+
+	// Plus 2 of its 8 GPUs
+	breakdown, err = p.GetPriorityFactorBreakdown(ctx, "2")
+	require.NoError(t, err)
+	assert.InDelta(t, 300, breakdown.TRESContribution, 1e-9)
+}
+
+func TestPriorityClient_TargetsAndSummary(t *testing.T) {
+	t.Parallel()
+	p, _ := newPriorityTestClient(t, priorityTestWeights, func(time.Time) []slurm.Job { return priorityTestJobs() })
+	ctx := context.Background()
+
+	targets, err := p.ListPriorityTargets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "2", "1", "5", "3"}, targets.JobIDs)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, targets.Users)
+	assert.Equal(t, []string{"research"}, targets.Accounts)
+	assert.Equal(t, []string{"compute"}, targets.Partitions)
+	assert.Equal(t, []string{"high", "normal"}, targets.QoS)
+	assert.Empty(t, targets.ValidatedJobIDs)
+
+	summary, err := p.GetPriorityFactorSummary(ctx)
+	require.NoError(t, err)
+	require.Len(t, summary, 2)
+	assert.Equal(t, "high", summary[0].QoSName)
+	assert.Equal(t, 2, summary[0].PendingJobs)
+	assert.InDelta(t, 1000, summary[0].MeanContributions["qos"], 1e-9)
+	assert.Equal(t, "normal", summary[1].QoSName)
+	assert.Equal(t, 3, summary[1].PendingJobs)
+	assert.Zero(t, summary[1].MeanContributions["qos"])
+	assert.Equal(t, 2, summary[1].DominantJobs["fairshare"])
+	assert.Equal(t, 1, summary[1].DominantJobs["age"])
+}
+
+func TestPriorityClient_FitsWeights(t *testing.T) {
+	t.Parallel()
+	p, _ := newPriorityTestClient(t, PriorityWeights{}, func(time.Time) []slurm.Job { return priorityTestJobs() })
+	ctx := context.Background()
+
+	validation, err := p.ValidateFactorConfiguration(ctx)
+	require.NoError(t, err)
+	assert.False(t, validation.IsValid)
+	assert.Equal(t, "invalid", validation.ConfigurationHealth)
+
+	result, err := p.OptimizeFactorWeights(ctx, "system")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"age": 1000, "fairshare": 2000, "size": 0, "partition": 500, "qos": 1000, "assoc": 0, "tres": 0,
+	}, result.RecommendedConfiguration)
+	assert.InDelta(t, 1, result.ExpectedEffectiveness, 1e-6)
+	assert.Contains(t, result.RecommendedActions, "set collectors.priority.weights.fairshare to 2000")
+
+	_, err = p.OptimizeFactorWeights(ctx, "user")
+	assert.Error(t, err)
+
+	// The weights the priorities were computed with reproduce them
+	p, _ = newPriorityTestClient(t, priorityTestWeights, func(time.Time) []slurm.Job { return priorityTestJobs() })
+	validation, err = p.ValidateFactorConfiguration(ctx)
+	require.NoError(t, err)
+	assert.True(t, validation.IsValid)
+	assert.Equal(t, "healthy", validation.ConfigurationHealth)
+	assert.InDelta(t, 1, validation.ValidationScore, 1e-9)
+}
+
+func TestPriorityClient_PredictionValidation(t *testing.T) {
+	t.Parallel()
+	start := queueTestNow.Add(35 * time.Minute)
+	p, setTime := newPriorityTestClient(t, priorityTestWeights, func(now time.Time) []slurm.Job {
+		jobs := priorityTestJobs()
+		if now.Before(start) {
+			// The scheduler expects job 2 to start in half an hour
+			jobs[1].StartTime = queueTestNow.Add(30 * time.Minute)
+		} else {
+			jobs[1].JobState = []api.JobState{api.JobStateRunning}
+			jobs[1].StartTime = start
+		}
+		return jobs
+	})
+	ctx := context.Background()
+
+	prediction, err := p.PredictJobScheduling(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "scheduler_estimate", prediction.PredictionMethod)
+	assert.Equal(t, 30*time.Minute, prediction.EstimatedWaitTime)
+
+	setTime(start.Add(time.Minute))
+	targets, err := p.ListPriorityTargets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, targets.ValidatedJobIDs)
+	assert.NotContains(t, targets.JobIDs, "2")
+
+	validation, err := p.ValidatePriorityPrediction(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, 1800.0, validation.PredictedWaitTime)
+	assert.Equal(t, 2100.0, validation.ActualWaitTime)
+	assert.Equal(t, "accurate", validation.ErrorCategory)
+	assert.Equal(t, 1.0, validation.ModelAccuracy)
+
+	// A validation is reported once
+	_, err = p.ValidatePriorityPrediction(ctx, "2")
+	assert.Error(t, err)
+
+	// Pending jobs age between the two snapshots
+	trend, err := p.GetFactorTrendAnalysis(ctx, "age", "24h")
+	require.NoError(t, err)
+	assert.Equal(t, "increasing", trend.TrendDirection)
+	assert.Positive(t, trend.TrendVelocity)
+}
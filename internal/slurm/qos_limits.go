@@ -0,0 +1,1605 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+)
+
+const (
+	// jobsTRES is the TRES name the job count limits are reported under
+	jobsTRES = "jobs"
+
+	// maxQoSViolations bounds the resolved limit violations kept for reporting
+	maxQoSViolations = 1000
+
+	// qosViolationRetention is how long resolved violations are kept, and
+	// reported when the query does not name a shorter time range
+	qosViolationRetention = 24 * time.Hour
+
+	// qosWarningRatio is the share of a limit at which a QoS counts as close
+	// to it
+	qosWarningRatio = 0.9
+)
+
+// QoS limits, named the way sacctmgr shows them
+const (
+	limitGrpTRES       = "GrpTRES"
+	limitGrpJobs       = "GrpJobs"
+	limitGrpSubmitJobs = "GrpSubmitJobs"
+	limitMaxTRESPU     = "MaxTRESPU"
+	limitMaxJobsPU     = "MaxJobsPU"
+	limitMaxSubmitPU   = "MaxSubmitPU"
+	limitMaxTRES       = "MaxTRES" // per job
+	limitMinTRES       = "MinTRES" // per job
+)
+
+// qosLimitKind describes a QoS limit that usage is enforced against
+type qosLimitKind struct {
+	name    string
+	perUser bool // enforced against each user's jobs rather than the whole QoS
+	submit  bool // counts pending jobs as well as running ones
+}
+
+// qosUsageLimits are the limits reported with the TRES usage, in the order
+// they are reported
+var qosUsageLimits = []qosLimitKind{
+	{name: limitGrpTRES},
+	{name: limitGrpJobs},
+	{name: limitGrpSubmitJobs, submit: true},
+	{name: limitMaxTRESPU, perUser: true},
+	{name: limitMaxJobsPU, perUser: true},
+	{name: limitMaxSubmitPU, perUser: true, submit: true},
+}
+
+// used returns the usage the limit is enforced against
+func (k qosLimitKind) used(running, pending tresCounts, tres string) float64 {
+	if k.submit {
+		return running[tres] + pending[tres]
+	}
+	return running[tres]
+}
+
+// QoSLimitsOptions controls how the QoS limits adapter reads the QoS usage
+type QoSLimitsOptions struct {
+	// SnapshotTTL is how long a fetched snapshot is reused, so that one
+	// collection pass issues a single set of API calls
+	SnapshotTTL time.Duration
+}
+
+// DefaultQoSLimitsOptions returns the default QoS limits options
+func DefaultQoSLimitsOptions() *QoSLimitsOptions {
+	return &QoSLimitsOptions{
+		SnapshotTTL: 15 * time.Second,
+	}
+}
+
+// QoSLimitsClient implements collector.QoSLimitsSLURMClient by joining the
+// QoS definitions with the running and pending jobs. Usage is counted the
+// way slurmctld enforces the limits: GrpTRES, GrpJobs, MaxTRESPU and
+// MaxJobsPU against the TRES allocated to running jobs, GrpSubmitJobs and
+// MaxSubmitPU against running and pending jobs. Limit violations are the
+// pending jobs held by a QoS limit, i.e. with a state reason starting with
+// QOS. Figures slurmrestd has no data for, such as enforcement actions and
+// satisfaction scores, are reported as zero.
+type QoSLimitsClient struct {
+	client slurm.SlurmClient
+	opts   QoSLimitsOptions
+	now    func() time.Time
+
+	mu         sync.Mutex
+	snapshot   *qosLimitsSnapshot
+	versions   map[string]qosVersion      // QoS -> definition version
+	finished   map[string]bool            // finished jobs already counted
+	completed  map[string]*qosCompletions // QoS -> jobs finished since last reported
+	violations map[string]*collector.QoSLimitViolation
+	resolved   []*collector.QoSLimitViolation
+}
+
+// qosVersion identifies a QoS definition and when it was first seen
+type qosVersion struct {
+	version string
+	since   time.Time
+}
+
+// qosCompletions counts the jobs of a QoS that finished since its usage was
+// last reported
+type qosCompletions struct {
+	completed int
+	failed    int
+	walltime  time.Duration
+}
+
+// qosLimitsSnapshot is one consistent view of the QoS and their jobs
+type qosLimitsSnapshot struct {
+	fetchedAt time.Time
+	qos       map[string]*qosDefinition
+	usage     map[string]*qosUsage
+	assocs    []slurm.Association // nil when the associations are unavailable
+}
+
+// qosDefinition is a QoS with its limits by limit name and TRES
+type qosDefinition struct {
+	qos    slurm.QoS
+	limits tresLimits
+}
+
+// qosUsage is what the jobs of one QoS use
+type qosUsage struct {
+	qosUserUsage
+	users map[string]*qosUserUsage
+	jobs  []*tresJob
+}
+
+// qosUserUsage is what the jobs of one user within a QoS use
+type qosUserUsage struct {
+	running tresCounts
+	pending tresCounts
+}
+
+// tresJob is a job with the TRES it holds or requests
+type tresJob struct {
+	*queueJob
+	tres tresCounts
+}
+
+// tresCounts are TRES counts by TRES name, memory in megabytes
+type tresCounts map[string]float64
+
+// tresLimits are limits on TRES counts by limit name
+type tresLimits map[string]tresCounts
+
+// NewQoSLimitsClient creates a QoS limits adapter over a SLURM client
+func NewQoSLimitsClient(client slurm.SlurmClient, opts *QoSLimitsOptions) *QoSLimitsClient {
+	defaults := DefaultQoSLimitsOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	c := &QoSLimitsClient{
+		client:     client,
+		opts:       *opts,
+		now:        time.Now,
+		versions:   make(map[string]qosVersion),
+		finished:   make(map[string]bool),
+		completed:  make(map[string]*qosCompletions),
+		violations: make(map[string]*collector.QoSLimitViolation),
+	}
+	if c.opts.SnapshotTTL <= 0 {
+		c.opts.SnapshotTTL = defaults.SnapshotTTL
+	}
+	return c
+}
+
+// ListQoSNames reports the defined QoS and any other QoS jobs run under
+func (c *QoSLimitsClient) ListQoSNames(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.names(), nil
+}
+
+// GetQoSTRESUsage reports, for every QoS, the use of each TRES that is in
+// use or limited, with the QoS limits on it. Users are reported for the
+// TRES their QoS has a per-user limit on.
+func (c *QoSLimitsClient) GetQoSTRESUsage(ctx context.Context) ([]*collector.QoSTRESUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var usages []*collector.QoSTRESUsage
+	for _, name := range snap.names() {
+		def := snap.qos[name]
+		usage := snap.usageOf(name)
+
+		tres := map[string]bool{jobsTRES: true}
+		for _, counts := range []tresCounts{usage.running, usage.pending} {
+			for t := range counts {
+				tres[t] = true
+			}
+		}
+		for _, kind := range qosUsageLimits {
+			for t := range def.limitsOf(kind.name) {
+				tres[t] = true
+			}
+		}
+		users := usage.userNames()
+
+		for _, t := range sortedKeys(tres) {
+			entry := &collector.QoSTRESUsage{
+				QoSName: name,
+				TRES:    t,
+				Running: usage.running[t],
+				Pending: usage.pending[t],
+			}
+			perUser := false
+			for _, kind := range qosUsageLimits {
+				value, ok := def.limit(kind.name, t)
+				if !ok {
+					continue
+				}
+				used := kind.used(usage.running, usage.pending, t)
+				if kind.perUser {
+					perUser = true
+					used = 0
+					for _, user := range usage.users {
+						used = math.Max(used, kind.used(user.running, user.pending, t))
+					}
+				}
+				entry.Limits = append(entry.Limits, collector.QoSTRESLimit{Name: kind.name, Value: value, Used: used})
+			}
+			usages = append(usages, entry)
+
+			if !perUser {
+				continue
+			}
+			for _, userName := range users {
+				user := usage.users[userName]
+				userEntry := &collector.QoSTRESUsage{
+					QoSName:  name,
+					UserName: userName,
+					TRES:     t,
+					Running:  user.running[t],
+					Pending:  user.pending[t],
+				}
+				for _, kind := range qosUsageLimits {
+					if value, ok := def.limit(kind.name, t); ok && kind.perUser {
+						userEntry.Limits = append(userEntry.Limits, collector.QoSTRESLimit{
+							Name:  kind.name,
+							Value: value,
+							Used:  kind.used(user.running, user.pending, t),
+						})
+					}
+				}
+				usages = append(usages, userEntry)
+			}
+		}
+	}
+	return usages, nil
+}
+
+// GetQoSLimits reports the limits of a QoS. Memory limits are in megabytes;
+// the Grp*Running limits have no slurmrestd equivalent and are zero.
+func (c *QoSLimitsClient) GetQoSLimits(ctx context.Context, qosName string) (*collector.QoSResourceLimits, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	def, err := snap.definition(qosName)
+	if err != nil {
+		return nil, err
+	}
+	usage := snap.usageOf(qosName)
+	qos := def.qos
+
+	limits := &collector.QoSResourceLimits{
+		QoSName:              qosName,
+		Description:          stringValue(qos.Description),
+		GrpCPUs:              int(def.value(limitGrpTRES, "cpu")),
+		GrpMem:               int64(def.value(limitGrpTRES, "mem")),
+		GrpNodes:             int(def.value(limitGrpTRES, "node")),
+		GrpJobs:              int(def.value(limitGrpJobs, jobsTRES)),
+		GrpSubmitJobs:        int(def.value(limitGrpSubmitJobs, jobsTRES)),
+		MaxCPUs:              int(def.value(limitMaxTRES, "cpu")),
+		MaxCPUsPerUser:       int(def.value(limitMaxTRESPU, "cpu")),
+		MaxMem:               int64(def.value(limitMaxTRES, "mem")),
+		MaxMemPerUser:        int64(def.value(limitMaxTRESPU, "mem")),
+		MaxNodes:             int(def.value(limitMaxTRES, "node")),
+		MaxNodesPerUser:      int(def.value(limitMaxTRESPU, "node")),
+		MaxJobsPerUser:       int(def.value(limitMaxJobsPU, jobsTRES)),
+		MaxSubmitJobsPerUser: int(def.value(limitMaxSubmitPU, jobsTRES)),
+		MinCPUs:              int(def.value(limitMinTRES, "cpu")),
+		MinMem:               int64(def.value(limitMinTRES, "mem")),
+		MinNodes:             int(def.value(limitMinTRES, "node")),
+		Flags:                qosFlags(qos),
+		ModifiedAt:           c.versions[qosName].since,
+		RunningJobs:          int(usage.running[jobsTRES]),
+		PendingJobs:          int(usage.pending[jobsTRES]),
+	}
+	limits.ActiveJobs = limits.RunningJobs + limits.PendingJobs
+
+	if qos.Priority != nil {
+		limits.Priority = int(*qos.Priority)
+	}
+	if qos.UsageFactor != nil {
+		limits.UsageFactor = *qos.UsageFactor
+	}
+	if qos.UsageThreshold != nil {
+		limits.UsageThreshold = *qos.UsageThreshold
+	}
+	if qos.Limits != nil {
+		if qos.Limits.GraceTime != nil {
+			limits.GraceTime = time.Duration(*qos.Limits.GraceTime) * time.Second
+		}
+		if max := qos.Limits.Max; max != nil && max.WallClock != nil && max.WallClock.Per != nil {
+			if max.WallClock.Per.QoS != nil {
+				limits.GrpWall = time.Duration(*max.WallClock.Per.QoS) * time.Minute
+			}
+			if max.WallClock.Per.Job != nil {
+				limits.MaxWall = time.Duration(*max.WallClock.Per.Job) * time.Minute
+				limits.MaxWallPerJob = limits.MaxWall
+			}
+		}
+	}
+	if qos.Preempt != nil {
+		limits.Preempt = qos.Preempt.List
+		modes := make([]string, 0, len(qos.Preempt.Mode))
+		for _, mode := range qos.Preempt.Mode {
+			modes = append(modes, string(mode))
+		}
+		limits.PreemptMode = strings.Join(modes, ",")
+	}
+
+	users, accounts := snap.members(qosName)
+	limits.UserCount = len(users)
+	limits.AccountCount = len(accounts)
+	for _, job := range usage.jobs {
+		if job.submit.After(limits.LastUsed) {
+			limits.LastUsed = job.submit
+		}
+	}
+	return limits, nil
+}
+
+// GetQoSViolations reports the pending jobs held by QoS limits, and those
+// released within the time range. A violation starts when the exporter first
+// sees the job held and resolves when the job no longer is.
+func (c *QoSLimitsClient) GetQoSViolations(ctx context.Context, opts *collector.QoSViolationOptions) (*collector.QoSViolations, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &collector.QoSViolationOptions{}
+	}
+	now := snap.fetchedAt
+	window := qosViolationRetention
+	if d, err := time.ParseDuration(opts.TimeRange); err == nil && d > 0 && d < window {
+		window = d
+	}
+
+	result := &collector.QoSViolations{
+		ViolationsByQoS:  make(map[string]int),
+		ViolationsByType: make(map[string]int),
+		ViolationsByUser: make(map[string]int),
+	}
+
+	candidates := make([]*collector.QoSLimitViolation, 0, len(c.violations)+len(c.resolved))
+	for _, id := range sortedViolationIDs(c.violations) {
+		violation := *c.violations[id]
+		violation.Duration = now.Sub(violation.Timestamp)
+		candidates = append(candidates, &violation)
+	}
+	for _, resolved := range c.resolved {
+		if now.Sub(resolved.ResolutionTime) <= window {
+			violation := *resolved
+			candidates = append(candidates, &violation)
+		}
+	}
+
+	var resolutions []float64
+	var autoResolved, recurred int
+	for _, violation := range candidates {
+		if !matchesViolation(violation, opts) {
+			continue
+		}
+		result.Violations = append(result.Violations, *violation)
+		result.TotalViolations++
+		result.ViolationsByQoS[violation.QoSName]++
+		result.ViolationsByType[violation.ViolationType]++
+		result.ViolationsByUser[violation.EntityID]++
+		switch violation.Severity {
+		case "critical":
+			result.CriticalViolations++
+		case "warning":
+			result.WarningViolations++
+		default:
+			result.InfoViolations++
+		}
+		if violation.Status == "active" {
+			result.ActiveViolations++
+			continue
+		}
+
+		result.ResolvedViolations++
+		resolutions = append(resolutions, violation.ResolutionTime.Sub(violation.Timestamp).Seconds())
+		if violation.AutoResolved {
+			autoResolved++
+		}
+		if recurs(violation, candidates) {
+			recurred++
+		}
+	}
+
+	if n := len(resolutions); n > 0 {
+		sort.Float64s(resolutions)
+		mean, _ := meanVariance(resolutions)
+		result.ResolutionStats = collector.ViolationResolutionStats{
+			MeanResolutionTime:   seconds(mean),
+			MedianResolutionTime: seconds(percentile(resolutions, 0.5)),
+			AutoResolutionRate:   float64(autoResolved) / float64(n),
+			ManualResolutionRate: float64(n-autoResolved) / float64(n),
+			RecurrenceRate:       float64(recurred) / float64(n),
+		}
+	}
+	return result, nil
+}
+
+// GetQoSUsage reports what the jobs of a QoS use, as a share of its group
+// limits. Memory is in megabytes. Completed and failed jobs and the walltime
+// they consumed count the jobs that finished since the last call.
+func (c *QoSLimitsClient) GetQoSUsage(ctx context.Context, qosName string) (*collector.QoSUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !snap.has(qosName) {
+		return nil, fmt.Errorf("QoS %s not found", qosName)
+	}
+	def := snap.qos[qosName]
+	usage := snap.usageOf(qosName)
+
+	result := &collector.QoSUsage{
+		QoSName:           qosName,
+		CPUsInUse:         int(usage.running["cpu"]),
+		CPUsAllocated:     int(usage.running["cpu"]),
+		CPUUtilization:    def.utilization(limitGrpTRES, "cpu", usage.running["cpu"]),
+		MemoryInUse:       int64(usage.running["mem"]),
+		MemoryAllocated:   int64(usage.running["mem"]),
+		MemoryUtilization: def.utilization(limitGrpTRES, "mem", usage.running["mem"]),
+		NodesInUse:        int(usage.running["node"]),
+		NodesAllocated:    int(usage.running["node"]),
+		NodeUtilization:   def.utilization(limitGrpTRES, "node", usage.running["node"]),
+		JobsRunning:       int(usage.running[jobsTRES]),
+		JobsPending:       int(usage.pending[jobsTRES]),
+		UsersActive:       len(usage.users),
+		QueueDepth:        int(usage.pending[jobsTRES]),
+		LoadFactor:        def.utilization(limitGrpTRES, "cpu", usage.running["cpu"]+usage.pending["cpu"]),
+	}
+
+	accounts := make(map[string]bool)
+	var waits, turnarounds []float64
+	for _, job := range usage.jobs {
+		switch {
+		case job.state == string(api.JobStateRunning) || job.state == string(api.JobStatePending):
+			if job.account != "" {
+				accounts[job.account] = true
+			}
+			if job.hasStarted(snap.fetchedAt) {
+				waits = append(waits, math.Max(0, job.start.Sub(job.submit).Seconds()))
+			}
+		case isFinishedState(job.state) && !job.end.IsZero() && !job.submit.IsZero():
+			turnarounds = append(turnarounds, math.Max(0, job.end.Sub(job.submit).Seconds()))
+		}
+	}
+	result.AccountsActive = len(accounts)
+	sort.Float64s(waits)
+	mean, _ := meanVariance(waits)
+	result.WaitTimeAverage = seconds(mean)
+	result.WaitTimeMedian = seconds(percentile(waits, 0.5))
+	mean, _ = meanVariance(turnarounds)
+	result.TurnaroundTimeAverage = seconds(mean)
+
+	if done := c.completed[qosName]; done != nil {
+		result.JobsCompleted = done.completed
+		result.JobsFailed = done.failed
+		result.WalltimeConsumed = done.walltime
+		delete(c.completed, qosName)
+	}
+	return result, nil
+}
+
+// GetQoSConfiguration reports how a QoS enforces its limits. The version
+// changes with the definition; LastModified is when the exporter first saw
+// the current one.
+func (c *QoSLimitsClient) GetQoSConfiguration(ctx context.Context, qosName string) (*collector.QoSConfiguration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	def, err := snap.definition(qosName)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]interface{})
+	for _, flag := range def.qos.Flags {
+		overrides[strings.ToLower(string(flag))] = true
+	}
+	version := c.versions[qosName]
+	return &collector.QoSConfiguration{
+		QoSName:           qosName,
+		ConfigVersion:     version.version,
+		LastModified:      version.since,
+		OverrideRules:     overrides,
+		EnforcementPolicy: def.enforcement(),
+		ViolationHandling: def.violationHandling(),
+	}, nil
+}
+
+// GetQoSHierarchy reports the preemption hierarchy of the QoS: each QoS
+// with the QoS it can preempt, rooted at the QoS no other QoS preempts.
+// Slurm QoS do not inherit from each other, so there are no inheritance
+// chains.
+func (c *QoSLimitsClient) GetQoSHierarchy(ctx context.Context) (*collector.QoSHierarchy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := snap.preemptionTree()
+	preempted := make(map[string]bool)
+	for _, targets := range tree {
+		for _, target := range targets {
+			preempted[target] = true
+		}
+	}
+	hierarchy := &collector.QoSHierarchy{
+		QoSTree:          tree,
+		InheritanceChain: make(map[string][]string),
+		PriorityOrder:    snap.priorityOrder(),
+		DefaultQoS:       snap.defaultQoS(),
+		TotalQoS:         len(snap.qos),
+	}
+	for _, name := range sortedDefinitions(snap.qos) {
+		if !preempted[name] {
+			hierarchy.RootQoS = append(hierarchy.RootQoS, name)
+		}
+		hierarchy.MaxDepth = max(hierarchy.MaxDepth, preemptionDepth(tree, name, make(map[string]bool)))
+	}
+	return hierarchy, nil
+}
+
+// GetQoSPriorities reports the QoS priorities, normalized against the
+// highest, and which QoS each QoS can preempt
+func (c *QoSLimitsClient) GetQoSPriorities(ctx context.Context) (*collector.QoSPriorities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	priorities := &collector.QoSPriorities{
+		PriorityMap:          make(map[string]int),
+		NormalizedPriorities: make(map[string]float64),
+		PriorityGroups:       make(map[string][]string),
+		PreemptionMatrix:     snap.preemptionTree(),
+		LastUpdated:          snap.fetchedAt,
+	}
+	var highest float64
+	for _, name := range sortedDefinitions(snap.qos) {
+		priority := snap.qos[name].priority()
+		priorities.PriorityMap[name] = int(priority)
+		group := strconv.Itoa(int(priority))
+		priorities.PriorityGroups[group] = append(priorities.PriorityGroups[group], name)
+		highest = math.Max(highest, priority)
+	}
+	for name, priority := range priorities.PriorityMap {
+		priorities.NormalizedPriorities[name] = ratio(float64(priority), highest)
+	}
+	return priorities, nil
+}
+
+// GetQoSAssignments reports the QoS each user or account association may
+// use, marking its default QoS
+func (c *QoSLimitsClient) GetQoSAssignments(ctx context.Context, entityType string) (*collector.QoSAssignments, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entityType != "user" && entityType != "account" {
+		return nil, fmt.Errorf("unsupported QoS assignment entity type %q", entityType)
+	}
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snap.assocs == nil {
+		return nil, fmt.Errorf("associations not available")
+	}
+
+	assignments := &collector.QoSAssignments{EntityType: entityType}
+	for _, assoc := range snap.assocs {
+		if (entityType == "user") != (assoc.User != "") {
+			continue
+		}
+		entity := stringValue(assoc.Account)
+		if entityType == "user" {
+			entity = associationKey(assoc.User, stringValue(assoc.Account), stringValue(assoc.Partition))
+		}
+		var defaultQoS string
+		if assoc.Default != nil {
+			defaultQoS = stringValue(assoc.Default.QoS)
+		}
+
+		allowed := false
+		for _, name := range assoc.QoS {
+			assignment := collector.QoSAssignment{
+				EntityID:       entity,
+				QoSName:        name,
+				AssignmentType: "explicit",
+				Status:         "active",
+			}
+			if def := snap.qos[name]; def != nil {
+				assignment.Priority = int(def.priority())
+			}
+			if name == defaultQoS {
+				assignment.AssignmentType = "default"
+				allowed = true
+				assignments.DefaultAssigned++
+			} else {
+				assignments.ExplicitAssigned++
+			}
+			assignments.Assignments = append(assignments.Assignments, assignment)
+		}
+		if defaultQoS != "" && !allowed {
+			assignments.ConflictingAssignments = append(assignments.ConflictingAssignments, collector.AssignmentConflict{
+				EntityID:       entity,
+				ConflictingQoS: []string{defaultQoS},
+				ConflictType:   "default_not_allowed",
+				ResolutionRule: "submissions without --qos are rejected",
+			})
+		}
+	}
+	assignments.TotalAssignments = len(assignments.Assignments)
+	return assignments, nil
+}
+
+// GetQoSEnforcement reports how a QoS enforces its limits and how many users
+// currently have jobs held by them. slurmrestd does not record enforcement
+// actions, so the action counts are zero.
+func (c *QoSLimitsClient) GetQoSEnforcement(ctx context.Context, qosName string) (*collector.QoSEnforcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	def, err := snap.definition(qosName)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcement := &collector.QoSEnforcement{
+		QoSName:            qosName,
+		EnforcementEnabled: len(def.limits) > 0,
+		EnforcementLevel:   def.enforcement(),
+		ViolationActions:   make(map[string]string),
+		GracePeriods:       make(map[string]time.Duration),
+		WarningThresholds:  make(map[string]float64),
+		CriticalThresholds: make(map[string]float64),
+	}
+	action := def.violationHandling()
+	for _, kind := range qosUsageLimits {
+		if len(def.limitsOf(kind.name)) > 0 {
+			enforcement.ViolationActions[kind.name] = action
+			enforcement.WarningThresholds[kind.name] = qosWarningRatio
+			enforcement.CriticalThresholds[kind.name] = 1
+		}
+	}
+	if limits := def.qos.Limits; limits != nil && limits.GraceTime != nil {
+		enforcement.GracePeriods["preemption"] = time.Duration(*limits.GraceTime) * time.Second
+	}
+
+	blocked := make(map[string]bool)
+	for _, violation := range c.violations {
+		if violation.QoSName == qosName {
+			blocked[violation.EntityID] = true
+		}
+	}
+	enforcement.EnforcementStats.UsersBlocked = len(blocked)
+	return enforcement, nil
+}
+
+// GetQoSStatistics reports the queue and run times and the throughput of the
+// jobs of a QoS that slurmrestd still lists, over the given period
+func (c *QoSLimitsClient) GetQoSStatistics(ctx context.Context, qosName string, period string) (*collector.QoSStatistics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !snap.has(qosName) {
+		return nil, fmt.Errorf("QoS %s not found", qosName)
+	}
+	window, err := time.ParseDuration(period)
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("invalid period %q", period)
+	}
+	now := snap.fetchedAt
+	since := now.Add(-window)
+	usage := snap.usageOf(qosName)
+
+	stats := &collector.QoSStatistics{
+		QoSName:         qosName,
+		Period:          period,
+		UtilizationRate: snap.qos[qosName].utilization(limitGrpTRES, "cpu", usage.running["cpu"]),
+	}
+	var queueTimes, runTimes, turnarounds []float64
+	for _, job := range usage.jobs {
+		if job.submit.After(since) {
+			stats.JobsSubmitted++
+		}
+		if job.hasStarted(now) && job.start.After(since) {
+			queueTimes = append(queueTimes, math.Max(0, job.start.Sub(job.submit).Seconds()))
+			runTimes = append(runTimes, job.runtime(now).Seconds())
+		}
+		if !isFinishedState(job.state) || job.end.IsZero() || !job.end.After(since) {
+			continue
+		}
+		turnarounds = append(turnarounds, math.Max(0, job.end.Sub(job.submit).Seconds()))
+		switch {
+		case job.state == string(api.JobStateCompleted):
+			stats.JobsCompleted++
+		case job.state == string(api.JobStateCancelled):
+			stats.JobsCanceled++
+		case job.state == string(api.JobStatePreempted):
+			stats.JobsPreempted++
+		case isFailedState(job.state):
+			stats.JobsFailed++
+		}
+	}
+	mean, _ := meanVariance(queueTimes)
+	stats.AverageQueueTime = seconds(mean)
+	mean, _ = meanVariance(runTimes)
+	stats.AverageRunTime = seconds(mean)
+	mean, _ = meanVariance(turnarounds)
+	stats.AverageTurnaroundTime = seconds(mean)
+	stats.Throughput = float64(len(turnarounds)) / window.Hours()
+	return stats, nil
+}
+
+// GetQoSEffectiveness reports the measurable parts of a QoS's effectiveness:
+// PolicyCompliance is the share of its pending jobs not held by its limits
+// and ResourceOptimization the highest share of a group limit in use. The
+// scores slurmrestd has no data for are zero.
+func (c *QoSLimitsClient) GetQoSEffectiveness(ctx context.Context, qosName string) (*collector.QoSEffectiveness, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	def, err := snap.definition(qosName)
+	if err != nil {
+		return nil, err
+	}
+	usage := snap.usageOf(qosName)
+
+	effectiveness := &collector.QoSEffectiveness{
+		QoSName:              qosName,
+		PolicyCompliance:     1,
+		ResourceOptimization: def.peakUtilization(usage),
+		NextReviewDate:       snap.fetchedAt.Add(c.opts.SnapshotTTL),
+	}
+	if pending := usage.pending[jobsTRES]; pending > 0 {
+		effectiveness.PolicyCompliance = 1 - float64(usage.held())/pending
+	}
+	if effectiveness.ResourceOptimization >= qosWarningRatio {
+		effectiveness.RecommendedActions = append(effectiveness.RecommendedActions,
+			fmt.Sprintf("QoS %s is at %.0f%% of a group limit", qosName, 100*effectiveness.ResourceOptimization))
+	}
+	return effectiveness, nil
+}
+
+// GetQoSConflicts reports QoS that preempt each other, per-user limits that
+// can never bind because the group limit is lower, and associations whose
+// default QoS they may not use
+func (c *QoSLimitsClient) GetQoSConflicts(ctx context.Context) (*collector.QoSConflicts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := &collector.QoSConflicts{
+		ConflictsByType:  make(map[string]int),
+		ResolutionMatrix: make(map[string]string),
+		LastAnalyzed:     snap.fetchedAt,
+	}
+	add := func(conflict collector.QoSConflict, critical bool) {
+		conflict.ConflictID = conflict.ConflictType + ":" + strings.Join(append(append([]string{}, conflict.QoSNames...), conflict.AffectedEntities...), ",")
+		conflict.Status = "active"
+		conflict.DetectedAt = snap.fetchedAt
+		conflicts.Conflicts = append(conflicts.Conflicts, conflict)
+		conflicts.ConflictsByType[conflict.ConflictType]++
+		conflicts.ResolutionMatrix[conflict.ConflictType] = conflict.ResolutionRule
+		if critical {
+			conflicts.CriticalConflicts++
+		}
+	}
+
+	tree := snap.preemptionTree()
+	for _, name := range sortedDefinitions(snap.qos) {
+		for _, target := range tree[name] {
+			if name < target && contains(tree[target], name) {
+				add(collector.QoSConflict{
+					ConflictType:   "mutual_preemption",
+					QoSNames:       []string{name, target},
+					Description:    fmt.Sprintf("QoS %s and %s can preempt each other", name, target),
+					Impact:         "jobs of either QoS can be preempted by the other",
+					ResolutionRule: "remove one of the preemptions",
+				}, true)
+			}
+		}
+
+		def := snap.qos[name]
+		for _, pair := range [][2]string{{limitMaxTRESPU, limitGrpTRES}, {limitMaxJobsPU, limitGrpJobs}, {limitMaxSubmitPU, limitGrpSubmitJobs}} {
+			group := def.limitsOf(pair[1])
+			for _, tres := range sortedTRES(def.limitsOf(pair[0])) {
+				if limit, ok := group[tres]; ok && def.limits[pair[0]][tres] > limit {
+					add(collector.QoSConflict{
+						ConflictType:   "per_user_exceeds_group",
+						QoSNames:       []string{name},
+						Description:    fmt.Sprintf("QoS %s %s %s exceeds %s", name, pair[0], tres, pair[1]),
+						Impact:         fmt.Sprintf("%s never binds", pair[0]),
+						ResolutionRule: fmt.Sprintf("lower %s to at most %s", pair[0], pair[1]),
+					}, false)
+				}
+			}
+		}
+	}
+
+	for _, assoc := range snap.assocs {
+		if assoc.Default == nil || stringValue(assoc.Default.QoS) == "" || contains(assoc.QoS, *assoc.Default.QoS) {
+			continue
+		}
+		add(collector.QoSConflict{
+			ConflictType:     "default_not_allowed",
+			QoSNames:         []string{*assoc.Default.QoS},
+			AffectedEntities: []string{associationKey(assoc.User, stringValue(assoc.Account), stringValue(assoc.Partition))},
+			Description:      fmt.Sprintf("default QoS %s is not in the association's QoS list", *assoc.Default.QoS),
+			Impact:           "submissions without --qos are rejected",
+			ResolutionRule:   "add the default QoS to the association's QoS list",
+		}, false)
+	}
+
+	conflicts.TotalConflicts = len(conflicts.Conflicts)
+	return conflicts, nil
+}
+
+// GetSystemQoSOverview summarises the QoS: how close each is to its group
+// limits and how many pending jobs QoS limits hold. SystemLoad and
+// OverallEfficiency have no slurmrestd source and are zero.
+func (c *QoSLimitsClient) GetSystemQoSOverview(ctx context.Context) (*collector.SystemQoSOverview, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &collector.SystemQoSOverview{
+		TotalQoS:       len(snap.qos),
+		DefaultQoS:     snap.defaultQoS(),
+		QoSUtilization: make(map[string]float64),
+		SystemHealth:   "healthy",
+		LastUpdated:    snap.fetchedAt,
+	}
+	if order := snap.priorityOrder(); len(order) > 0 {
+		overview.HighestPriorityQoS = order[0]
+		overview.LowestPriorityQoS = order[len(order)-1]
+	}
+
+	var pending, held float64
+	for _, name := range snap.names() {
+		usage := snap.usageOf(name)
+		if usage.running[jobsTRES]+usage.pending[jobsTRES] > 0 {
+			overview.ActiveQoS++
+		}
+		pending += usage.pending[jobsTRES]
+		held += float64(usage.held())
+
+		def := snap.qos[name]
+		if def == nil || !def.hasGroupLimits() {
+			continue
+		}
+		utilization := def.peakUtilization(usage)
+		overview.QoSUtilization[name] = utilization
+		switch {
+		case utilization >= 1:
+			overview.SystemHealth = "critical"
+		case utilization >= qosWarningRatio && overview.SystemHealth == "healthy":
+			overview.SystemHealth = "warning"
+		}
+		if utilization >= qosWarningRatio {
+			overview.RecommendedOptimizations = append(overview.RecommendedOptimizations,
+				fmt.Sprintf("QoS %s is at %.0f%% of a group limit", name, 100*utilization))
+		}
+	}
+	overview.ViolationRate = ratio(held, pending)
+	overview.ComplianceScore = 1 - overview.ViolationRate
+	return overview, nil
+}
+
+// refresh returns the current snapshot, fetching a new one once the TTL has
+// expired. The caller must hold c.mu.
+func (c *QoSLimitsClient) refresh(ctx context.Context) (*qosLimitsSnapshot, error) {
+	now := c.now()
+	if c.snapshot != nil && now.Sub(c.snapshot.fetchedAt) < c.opts.SnapshotTTL {
+		return c.snapshot, nil
+	}
+
+	manager := c.client.QoS()
+	if manager == nil {
+		return nil, fmt.Errorf("QoS endpoint not available")
+	}
+	qosList, err := manager.List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list QoS: %w", err)
+	}
+	jobList, err := c.client.Jobs().List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	snap := &qosLimitsSnapshot{
+		fetchedAt: now,
+		qos:       make(map[string]*qosDefinition),
+		usage:     make(map[string]*qosUsage),
+	}
+	if qosList != nil {
+		for _, qos := range qosList.QoS {
+			if qos.Name != nil {
+				snap.qos[*qos.Name] = newQoSDefinition(qos)
+			}
+		}
+	}
+	if jobList != nil {
+		for i := range jobList.Jobs {
+			snap.addJob(&jobList.Jobs[i])
+		}
+	}
+
+	// Associations only name who may use each QoS
+	if manager := c.client.Associations(); manager != nil {
+		if assocList, err := manager.List(ctx, nil); err != nil {
+			logrus.WithError(err).Debug("QoS limits continuing without associations")
+		} else if assocList != nil {
+			snap.assocs = assocList.Associations
+		}
+	}
+
+	c.observe(snap)
+	c.snapshot = snap
+	return snap, nil
+}
+
+// observe tracks definition changes, finished jobs and held jobs across
+// snapshots. The caller must hold c.mu.
+func (c *QoSLimitsClient) observe(snap *qosLimitsSnapshot) {
+	now := snap.fetchedAt
+	baseline := c.snapshot == nil
+
+	for name, def := range snap.qos {
+		if version := def.version(); c.versions[name].version != version {
+			c.versions[name] = qosVersion{version: version, since: now}
+		}
+	}
+	for name := range c.versions {
+		if _, ok := snap.qos[name]; !ok {
+			delete(c.versions, name)
+		}
+	}
+
+	// Jobs that finished before the first snapshot are not counted
+	seen := make(map[string]bool)
+	held := make(map[string]*tresJob)
+	for name, usage := range snap.usage {
+		for _, job := range usage.jobs {
+			seen[job.id] = true
+			if job.state == string(api.JobStatePending) && strings.HasPrefix(job.reason, "QOS") {
+				held[job.id+"/"+job.reason] = job
+			}
+			if !isFinishedState(job.state) || c.finished[job.id] {
+				continue
+			}
+			c.finished[job.id] = true
+			if baseline {
+				continue
+			}
+			done := c.completed[name]
+			if done == nil {
+				done = &qosCompletions{}
+				c.completed[name] = done
+			}
+			switch {
+			case job.state == string(api.JobStateCompleted):
+				done.completed++
+			case isFailedState(job.state):
+				done.failed++
+			}
+			done.walltime += job.runtime(now)
+		}
+	}
+	for id := range c.finished {
+		if !seen[id] {
+			delete(c.finished, id)
+		}
+	}
+
+	for id, job := range held {
+		if _, ok := c.violations[id]; ok {
+			continue
+		}
+		c.violations[id] = &collector.QoSLimitViolation{
+			ViolationID:   id,
+			Timestamp:     now,
+			QoSName:       job.qos,
+			ViolationType: job.reason,
+			Severity:      qosViolationSeverity(job.reason),
+			EntityType:    "user",
+			EntityID:      job.user,
+			LimitType:     qosViolationLimitType(job.reason),
+			Status:        "active",
+			Impact:        fmt.Sprintf("job %s pending", job.id),
+		}
+	}
+	for id, violation := range c.violations {
+		if _, ok := held[id]; ok {
+			continue
+		}
+		delete(c.violations, id)
+		resolved := *violation
+		resolved.Status = "resolved"
+		resolved.ResolutionTime = now
+		resolved.Duration = now.Sub(violation.Timestamp)
+		// The limit freed up when the job started or moved on to another
+		// pending reason; anything else took the job out of the queue
+		job := snap.job(strings.SplitN(id, "/", 2)[0])
+		switch {
+		case job == nil:
+			resolved.ResolutionAction = "left_queue"
+		case job.state == string(api.JobStatePending):
+			resolved.ResolutionAction = "released"
+			resolved.AutoResolved = true
+		case job.hasStarted(now):
+			resolved.ResolutionAction = "started"
+			resolved.AutoResolved = true
+		default:
+			resolved.ResolutionAction = strings.ToLower(job.state)
+		}
+		c.resolved = append(c.resolved, &resolved)
+	}
+
+	for len(c.resolved) > 0 && (len(c.resolved) > maxQoSViolations || now.Sub(c.resolved[0].ResolutionTime) > qosViolationRetention) {
+		c.resolved = c.resolved[1:]
+	}
+}
+
+// newQoSDefinition reads the limits of a QoS
+func newQoSDefinition(qos slurm.QoS) *qosDefinition {
+	def := &qosDefinition{qos: qos, limits: make(tresLimits)}
+	if qos.Limits == nil {
+		return def
+	}
+	if max := qos.Limits.Max; max != nil {
+		if max.TRES != nil {
+			def.limits.setTRES(limitGrpTRES, max.TRES.Total)
+			if max.TRES.Per != nil {
+				def.limits.setTRES(limitMaxTRESPU, max.TRES.Per.User)
+				def.limits.setTRES(limitMaxTRES, max.TRES.Per.Job)
+			}
+		}
+		if max.ActiveJobs != nil {
+			def.limits.setJobs(limitGrpJobs, max.ActiveJobs.Count)
+		}
+		if max.Jobs != nil {
+			def.limits.setJobs(limitGrpSubmitJobs, max.Jobs.Count)
+			if max.Jobs.ActiveJobs != nil && max.Jobs.ActiveJobs.Per != nil {
+				def.limits.setJobs(limitMaxJobsPU, max.Jobs.ActiveJobs.Per.User)
+			}
+			if max.Jobs.Per != nil {
+				def.limits.setJobs(limitMaxSubmitPU, max.Jobs.Per.User)
+			}
+		}
+	}
+	if min := qos.Limits.Min; min != nil && min.TRES != nil && min.TRES.Per != nil {
+		def.limits.setTRES(limitMinTRES, min.TRES.Per.Job)
+	}
+	return def
+}
+
+// setTRES records the TRES of a limit; TRES without a count are unlimited
+func (l tresLimits) setTRES(limit string, list []api.TRES) {
+	for _, tres := range list {
+		if tres.Count == nil || *tres.Count < 0 {
+			continue
+		}
+		l.limitsFor(limit)[tresName(tres)] = float64(*tres.Count)
+	}
+}
+
+// setJobs records a job count limit; an unset limit is unlimited
+func (l tresLimits) setJobs(limit string, count *uint32) {
+	if count != nil {
+		l.limitsFor(limit)[jobsTRES] = float64(*count)
+	}
+}
+
+func (l tresLimits) limitsFor(limit string) tresCounts {
+	counts := l[limit]
+	if counts == nil {
+		counts = make(tresCounts)
+		l[limit] = counts
+	}
+	return counts
+}
+
+// limitsOf returns the TRES a limit applies to, or nil for QoS only known
+// from their jobs
+func (d *qosDefinition) limitsOf(limit string) tresCounts {
+	if d == nil {
+		return nil
+	}
+	return d.limits[limit]
+}
+
+func (d *qosDefinition) limit(limit, tres string) (float64, bool) {
+	value, ok := d.limitsOf(limit)[tres]
+	return value, ok
+}
+
+// value returns a limit, zero when unset
+func (d *qosDefinition) value(limit, tres string) float64 {
+	return d.limitsOf(limit)[tres]
+}
+
+// utilization returns used as a share of a limit, zero when unset
+func (d *qosDefinition) utilization(limit, tres string, used float64) float64 {
+	value, ok := d.limit(limit, tres)
+	if !ok || value <= 0 {
+		return 0
+	}
+	return used / value
+}
+
+func (d *qosDefinition) hasGroupLimits() bool {
+	for _, kind := range qosUsageLimits {
+		if !kind.perUser && len(d.limitsOf(kind.name)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// peakUtilization returns the highest share of a group limit in use
+func (d *qosDefinition) peakUtilization(usage *qosUsage) float64 {
+	var peak float64
+	for _, kind := range qosUsageLimits {
+		if kind.perUser {
+			continue
+		}
+		for tres := range d.limitsOf(kind.name) {
+			peak = math.Max(peak, d.utilization(kind.name, tres, kind.used(usage.running, usage.pending, tres)))
+		}
+	}
+	return peak
+}
+
+func (d *qosDefinition) priority() float64 {
+	if d.qos.Priority == nil {
+		return 0
+	}
+	return float64(*d.qos.Priority)
+}
+
+func (d *qosDefinition) hasFlag(flag api.QoSFlagsValue) bool {
+	for _, f := range d.qos.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// enforcement reports whether jobs over a limit are rejected at submission
+// (DenyOnLimit) or left pending
+func (d *qosDefinition) enforcement() string {
+	if d.hasFlag(api.QoSFlagsDenyLimit) {
+		return "deny"
+	}
+	return "pend"
+}
+
+func (d *qosDefinition) violationHandling() string {
+	if d.hasFlag(api.QoSFlagsDenyLimit) {
+		return "reject_submission"
+	}
+	return "hold_pending"
+}
+
+// version identifies the definition in the configuration version
+func (d *qosDefinition) version() string {
+	h := fnv.New32a()
+	fmt.Fprint(h, d.priority(), d.qos.Flags, d.qos.UsageFactor, d.qos.UsageThreshold)
+	names := make([]string, 0, len(d.limits))
+	for name := range d.limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, tres := range sortedTRES(d.limits[name]) {
+			fmt.Fprint(h, name, tres, d.limits[name][tres])
+		}
+	}
+	if d.qos.Preempt != nil {
+		fmt.Fprint(h, d.qos.Preempt.List, d.qos.Preempt.Mode)
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// addJob counts a job against its QoS and user
+func (s *qosLimitsSnapshot) addJob(job *slurm.Job) {
+	qj := &tresJob{queueJob: newQueueJob(job)}
+	if qj.id == "" {
+		return
+	}
+	usage := s.usageFor(qj.qos)
+	usage.jobs = append(usage.jobs, qj)
+
+	running := qj.state == string(api.JobStateRunning)
+	switch {
+	case running:
+		qj.tres = jobTRES(qj.cpus, job.TRESAllocStr, job.TRESReqStr)
+	case qj.state == string(api.JobStatePending):
+		qj.tres = jobTRES(qj.cpus, job.TRESReqStr)
+	default:
+		return
+	}
+
+	usage.add(running, qj.tres)
+	if qj.user != "" {
+		user := usage.users[qj.user]
+		if user == nil {
+			user = &qosUserUsage{running: make(tresCounts), pending: make(tresCounts)}
+			usage.users[qj.user] = user
+		}
+		user.add(running, qj.tres)
+	}
+}
+
+// add counts TRES as running or pending
+func (u *qosUserUsage) add(running bool, tres tresCounts) {
+	counts := u.pending
+	if running {
+		counts = u.running
+	}
+	for name, count := range tres {
+		counts[name] += count
+	}
+}
+
+func (s *qosLimitsSnapshot) usageFor(name string) *qosUsage {
+	usage := s.usage[name]
+	if usage == nil {
+		usage = &qosUsage{
+			qosUserUsage: qosUserUsage{running: make(tresCounts), pending: make(tresCounts)},
+			users:        make(map[string]*qosUserUsage),
+		}
+		s.usage[name] = usage
+	}
+	return usage
+}
+
+// usageOf returns the usage of a QoS, empty when it has no jobs
+func (s *qosLimitsSnapshot) usageOf(name string) *qosUsage {
+	if usage := s.usage[name]; usage != nil {
+		return usage
+	}
+	return &qosUsage{qosUserUsage: qosUserUsage{running: tresCounts{}, pending: tresCounts{}}}
+}
+
+func (s *qosLimitsSnapshot) has(name string) bool {
+	_, defined := s.qos[name]
+	_, used := s.usage[name]
+	return defined || used
+}
+
+func (s *qosLimitsSnapshot) definition(name string) (*qosDefinition, error) {
+	def, ok := s.qos[name]
+	if !ok {
+		return nil, fmt.Errorf("QoS %s not found", name)
+	}
+	return def, nil
+}
+
+// names returns the defined QoS and the QoS of the listed jobs
+func (s *qosLimitsSnapshot) names() []string {
+	names := make(map[string]bool, len(s.qos))
+	for name := range s.qos {
+		names[name] = true
+	}
+	for name := range s.usage {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
+
+func (s *qosLimitsSnapshot) job(id string) *tresJob {
+	for _, usage := range s.usage {
+		for _, job := range usage.jobs {
+			if job.id == id {
+				return job
+			}
+		}
+	}
+	return nil
+}
+
+// members returns the users and accounts whose associations may use a QoS,
+// or that have jobs in it when the associations are unavailable
+func (s *qosLimitsSnapshot) members(name string) (map[string]bool, map[string]bool) {
+	users := make(map[string]bool)
+	accounts := make(map[string]bool)
+	if s.assocs == nil {
+		for _, job := range s.usageOf(name).jobs {
+			if job.user != "" {
+				users[job.user] = true
+			}
+			if job.account != "" {
+				accounts[job.account] = true
+			}
+		}
+		return users, accounts
+	}
+	for _, assoc := range s.assocs {
+		if !contains(assoc.QoS, name) {
+			continue
+		}
+		if assoc.User != "" {
+			users[assoc.User] = true
+		}
+		if account := stringValue(assoc.Account); account != "" {
+			accounts[account] = true
+		}
+	}
+	return users, accounts
+}
+
+// preemptionTree maps each QoS to the QoS it can preempt
+func (s *qosLimitsSnapshot) preemptionTree() map[string][]string {
+	tree := make(map[string][]string)
+	for name, def := range s.qos {
+		if def.qos.Preempt != nil && len(def.qos.Preempt.List) > 0 {
+			targets := append([]string{}, def.qos.Preempt.List...)
+			sort.Strings(targets)
+			tree[name] = targets
+		}
+	}
+	return tree
+}
+
+// priorityOrder returns the QoS by descending priority
+func (s *qosLimitsSnapshot) priorityOrder() []string {
+	order := sortedDefinitions(s.qos)
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.qos[order[i]].priority() > s.qos[order[j]].priority()
+	})
+	return order
+}
+
+// defaultQoS returns the most common default QoS of the associations,
+// falling back to Slurm's default normal QoS
+func (s *qosLimitsSnapshot) defaultQoS() string {
+	counts := make(map[string]int)
+	for _, assoc := range s.assocs {
+		if assoc.Default != nil && stringValue(assoc.Default.QoS) != "" {
+			counts[*assoc.Default.QoS]++
+		}
+	}
+	if common := busiest(counts, 1); len(common) > 0 {
+		return common[0]
+	}
+	if _, ok := s.qos["normal"]; ok {
+		return "normal"
+	}
+	return ""
+}
+
+func (u *qosUsage) userNames() []string {
+	names := make([]string, 0, len(u.users))
+	for name := range u.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// held counts the pending jobs held by a QoS limit
+func (u *qosUsage) held() int {
+	var held int
+	for _, job := range u.jobs {
+		if job.state == string(api.JobStatePending) && strings.HasPrefix(job.reason, "QOS") {
+			held++
+		}
+	}
+	return held
+}
+
+// jobTRES returns the TRES of a job from the first TRES string set, with
+// the job itself counted as the jobs TRES
+func jobTRES(cpus float64, tresStrings ...*string) tresCounts {
+	counts := make(tresCounts)
+	for _, tres := range tresStrings {
+		if tres != nil && *tres != "" {
+			counts = tresCounts(collector.ParseTRES(*tres))
+			break
+		}
+	}
+	if _, ok := counts["cpu"]; !ok && cpus > 0 {
+		counts["cpu"] = cpus
+	}
+	counts[jobsTRES] = 1
+	return counts
+}
+
+// tresName returns the name a TRES goes by in TRES strings, e.g. gres/gpu
+func tresName(tres api.TRES) string {
+	name := strings.ToLower(tres.Type)
+	if tres.Name != nil && *tres.Name != "" {
+		name += "/" + *tres.Name
+	}
+	return name
+}
+
+func qosFlags(qos slurm.QoS) []string {
+	flags := make([]string, 0, len(qos.Flags))
+	for _, flag := range qos.Flags {
+		flags = append(flags, string(flag))
+	}
+	return flags
+}
+
+// qosViolationSeverity rates a QoS state reason: group limits hold every
+// user of the QoS, per-user and per-job limits only some
+func qosViolationSeverity(reason string) string {
+	switch {
+	case strings.HasPrefix(reason, "QOSGrp"):
+		return "critical"
+	case strings.HasPrefix(reason, "QOSMax"), strings.HasPrefix(reason, "QOSMin"):
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func qosViolationLimitType(reason string) string {
+	switch {
+	case strings.HasPrefix(reason, "QOSGrp"):
+		return "group"
+	case strings.Contains(reason, "PerUser"):
+		return "user"
+	case strings.Contains(reason, "PerAccount"):
+		return "account"
+	default:
+		return "job"
+	}
+}
+
+func matchesViolation(violation *collector.QoSLimitViolation, opts *collector.QoSViolationOptions) bool {
+	matches := func(filter, value string) bool {
+		return filter == "" || filter == "all" || filter == value
+	}
+	return matches(opts.QoSName, violation.QoSName) &&
+		matches(opts.Severity, violation.Severity) &&
+		matches(opts.Status, violation.Status) &&
+		matches(opts.EntityType, violation.EntityType) &&
+		matches(opts.EntityID, violation.EntityID)
+}
+
+// recurs reports whether the same user was held by the same QoS limit again
+// after a violation was resolved
+func recurs(resolved *collector.QoSLimitViolation, violations []*collector.QoSLimitViolation) bool {
+	for _, other := range violations {
+		if other != resolved && other.EntityID == resolved.EntityID && other.QoSName == resolved.QoSName &&
+			other.ViolationType == resolved.ViolationType && !other.Timestamp.Before(resolved.ResolutionTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// preemptionDepth returns the length of the longest preemption chain from a
+// QoS, ignoring cycles
+func preemptionDepth(tree map[string][]string, name string, visiting map[string]bool) int {
+	if visiting[name] {
+		return 0
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	depth := 1
+	for _, target := range tree[name] {
+		depth = max(depth, 1+preemptionDepth(tree, target, visiting))
+	}
+	return depth
+}
+
+func sortedDefinitions(defs map[string]*qosDefinition) []string {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTRES(counts tresCounts) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedViolationIDs(violations map[string]*collector.QoSLimitViolation) []string {
+	ids := make([]string, 0, len(violations))
+	for id := range violations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure QoSLimitsClient satisfies the collector interfaces
+var (
+	_ collector.QoSLimitsSLURMClient = (*QoSLimitsClient)(nil)
+	_ collector.QoSTargetLister      = (*QoSLimitsClient)(nil)
+	_ collector.QoSTRESUsageReporter = (*QoSLimitsClient)(nil)
+)
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+// qosTestAssociations lists the associations produced by list; the generated
+// mock predates the current AssociationManager interface
+type qosTestAssociations struct {
+	slurm.AssociationManager
+	list func() []slurm.Association
+}
+
+func (a qosTestAssociations) List(context.Context, *slurm.ListAssociationsOptions) (*slurm.AssociationList, error) {
+	return &slurm.AssociationList{Associations: a.list()}, nil
+}
+
+func qosTestTRES(kind, name string, count int64) api.TRES {
+	tres := api.TRES{Type: kind, Count: &count}
+	if name != "" {
+		tres.Name = &name
+	}
+	return tres
+}
+
+// qosTestQoS returns a gpu QoS limited to 64 GPUs in total, 32 CPUs and two
+// running jobs per user, and a high QoS that can preempt normal
+func qosTestQoS() []slurm.QoS {
+	gpu, normal, high := "gpu", "normal", "high"
+	maxJobs := uint32(2)
+	priority := uint32(100)
+	return []slurm.QoS{
+		{
+			Name: &gpu,
+			Limits: &api.QoSLimits{Max: &api.QoSLimitsMax{
+				TRES: &api.QoSLimitsMaxTRES{
+					Total: []api.TRES{qosTestTRES("gres", "gpu", 64)},
+					Per:   &api.QoSLimitsMaxTRESPer{User: []api.TRES{qosTestTRES("cpu", "", 32)}},
+				},
+				Jobs: &api.QoSLimitsMaxJobs{ActiveJobs: &api.QoSLimitsMaxJobsActiveJobs{
+					Per: &api.QoSLimitsMaxJobsActiveJobsPer{User: &maxJobs},
+				}},
+			}},
+		},
+		{Name: &normal},
+		{Name: &high, Priority: &priority, Preempt: &api.QoSPreempt{List: []string{"normal"}}},
+	}
+}
+
+func qosTestJob(id int32, user, qos, state, tres, reason string) slurm.Job {
+	job := queueTestJob(id, user, state, 100, queueTestNow.Add(-time.Hour), time.Time{})
+	job.QoS = &qos
+	if state == "RUNNING" {
+		job.StartTime = queueTestNow.Add(-30 * time.Minute)
+		job.TRESAllocStr = &tres
+	} else {
+		job.TRESReqStr = &tres
+		job.StateReason = &reason
+	}
+	return job
+}
+
+func qosTestJobs() []slurm.Job {
+	return []slurm.Job{
+		qosTestJob(1, "alice", "gpu", "RUNNING", "cpu=16,mem=64G,node=1,gres/gpu=32", ""),
+		qosTestJob(2, "bob", "gpu", "RUNNING", "cpu=8,mem=32768,node=1,gres/gpu=26", ""),
+		qosTestJob(3, "alice", "gpu", "PENDING", "cpu=16,mem=64G,node=1,gres/gpu=8", "QOSGrpGRES"),
+		qosTestJob(4, "carol", "normal", "RUNNING", "", ""),
+	}
+}
+
+// newQoSLimitsTestClient returns a client whose job list is produced by jobs
+// at the current test time, which advances by setTime
+func newQoSLimitsTestClient(t *testing.T, jobs func(now time.Time) []slurm.Job) (*QoSLimitsClient, func(time.Time)) {
+	t.Helper()
+	clock, setTime := newTestClock()
+	jobManager := mockJobList(jobs, clock)
+
+	qosManager := new(mocks.MockQoSManager)
+	qosManager.On("List", mock.Anything, mock.Anything).Return(&slurm.QoSList{QoS: qosTestQoS()}, nil)
+
+	account, normal := "research", "normal"
+	assocs := []slurm.Association{
+		{User: "alice", Account: &account, QoS: []string{"normal", "gpu"}, Default: &api.AssociationDefault{QoS: &normal}},
+		{User: "bob", Account: &account, QoS: []string{"normal", "gpu"}, Default: &api.AssociationDefault{QoS: &normal}},
+		{User: "carol", Account: &account, QoS: []string{"gpu"}, Default: &api.AssociationDefault{QoS: &normal}},
+	}
+	assocManager := qosTestAssociations{list: func() []slurm.Association { return assocs }}
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(jobManager)
+	client.On("QoS").Return(qosManager)
+	client.On("Associations").Return(assocManager)
+
+	c := NewQoSLimitsClient(client, nil)
+	c.now = clock
+	return c, setTime
+}
+
+func findQoSTRESUsage(usages []*collector.QoSTRESUsage, qos, user, tres string) *collector.QoSTRESUsage {
+	for _, usage := range usages {
+		if usage.QoSName == qos && usage.UserName == user && usage.TRES == tres {
+			return usage
+		}
+	}
+	return nil
+}
+
+func TestQoSLimitsClient_TRESUsage(t *testing.T) {
+	t.Parallel()
+	c, _ := newQoSLimitsTestClient(t, func(time.Time) []slurm.Job { return qosTestJobs() })
+	ctx := context.Background()
+
+	names, err := c.ListQoSNames(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu", "high", "normal"}, names)
+
+	usages, err := c.GetQoSTRESUsage(ctx)
+	require.NoError(t, err)
+
+	gpus := findQoSTRESUsage(usages, "gpu", "", "gres/gpu")
+	require.NotNil(t, gpus)
+	assert.Equal(t, 58.0, gpus.Running)
+	assert.Equal(t, 8.0, gpus.Pending)
+	assert.Equal(t, []collector.QoSTRESLimit{{Name: "GrpTRES", Value: 64, Used: 58}}, gpus.Limits)
+
+	mem := findQoSTRESUsage(usages, "gpu", "", "mem")
+	require.NotNil(t, mem)
+	assert.Equal(t, 96.0*1024, mem.Running)
+	assert.Empty(t, mem.Limits)
+
+	// Per-user limits report the user closest to them on the QoS
+	cpus := findQoSTRESUsage(usages, "gpu", "", "cpu")
+	require.NotNil(t, cpus)
+	assert.Equal(t, []collector.QoSTRESLimit{{Name: "MaxTRESPU", Value: 32, Used: 16}}, cpus.Limits)
+
+	aliceCPUs := findQoSTRESUsage(usages, "gpu", "alice", "cpu")
+	require.NotNil(t, aliceCPUs)
+	assert.Equal(t, 16.0, aliceCPUs.Running)
+	assert.Equal(t, 16.0, aliceCPUs.Pending)
+	aliceJobs := findQoSTRESUsage(usages, "gpu", "alice", "jobs")
+	require.NotNil(t, aliceJobs)
+	assert.Equal(t, []collector.QoSTRESLimit{{Name: "MaxJobsPU", Value: 2, Used: 1}}, aliceJobs.Limits)
+
+	// Users are only reported for TRES with a per-user limit
+	assert.Nil(t, findQoSTRESUsage(usages, "gpu", "alice", "gres/gpu"))
+	assert.Nil(t, findQoSTRESUsage(usages, "normal", "carol", "cpu"))
+
+	// Jobs without a TRES string count their CPUs
+	normalCPUs := findQoSTRESUsage(usages, "normal", "", "cpu")
+	require.NotNil(t, normalCPUs)
+	assert.Equal(t, 4.0, normalCPUs.Running)
+}
+
+func TestQoSLimitsClient_LimitsAndUsage(t *testing.T) {
+	t.Parallel()
+	c, _ := newQoSLimitsTestClient(t, func(time.Time) []slurm.Job { return qosTestJobs() })
+	ctx := context.Background()
+
+	limits, err := c.GetQoSLimits(ctx, "gpu")
+	require.NoError(t, err)
+	assert.Equal(t, 32, limits.MaxCPUsPerUser)
+	assert.Equal(t, 2, limits.MaxJobsPerUser)
+	assert.Zero(t, limits.GrpCPUs)
+	assert.Equal(t, 2, limits.RunningJobs)
+	assert.Equal(t, 1, limits.PendingJobs)
+	assert.Equal(t, 3, limits.UserCount)
+	assert.Equal(t, 1, limits.AccountCount)
+
+	_, err = c.GetQoSLimits(ctx, "missing")
+	assert.Error(t, err)
+
+	usage, err := c.GetQoSUsage(ctx, "gpu")
+	require.NoError(t, err)
+	assert.Equal(t, 24, usage.CPUsInUse)
+	assert.Equal(t, 2, usage.JobsRunning)
+	assert.Equal(t, 1, usage.QueueDepth)
+	assert.Equal(t, 2, usage.UsersActive)
+	assert.Equal(t, 30*time.Minute, usage.WaitTimeAverage)
+
+	hierarchy, err := c.GetQoSHierarchy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu", "high"}, hierarchy.RootQoS)
+	assert.Equal(t, "normal", hierarchy.DefaultQoS)
+	assert.Equal(t, 2, hierarchy.MaxDepth)
+	assert.Equal(t, "high", hierarchy.PriorityOrder[0])
+
+	// carol's association defaults to a QoS it may not use
+	conflicts, err := c.GetQoSConflicts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts.ConflictsByType["default_not_allowed"])
+
+	overview, err := c.GetSystemQoSOverview(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 58.0/64, overview.QoSUtilization["gpu"], 1e-9)
+	assert.Equal(t, "warning", overview.SystemHealth)
+	assert.Equal(t, 1.0, overview.ViolationRate)
+}
+
+func TestQoSLimitsClient_Violations(t *testing.T) {
+	t.Parallel()
+	start := queueTestNow.Add(10 * time.Minute)
+	c, setTime := newQoSLimitsTestClient(t, func(now time.Time) []slurm.Job {
+		jobs := qosTestJobs()
+		if !now.Before(start) {
+			// Job 1 finished and freed the GPUs job 3 was held for
+			jobs[0].JobState = []api.JobState{api.JobStateCompleted}
+			jobs[0].EndTime = start
+			jobs[2] = qosTestJob(3, "alice", "gpu", "RUNNING", "cpu=16,mem=64G,node=1,gres/gpu=8", "")
+			jobs[2].StartTime = start
+		}
+		return jobs
+	})
+	ctx := context.Background()
+
+	violations, err := c.GetQoSViolations(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, violations.ActiveViolations)
+	assert.Equal(t, "QOSGrpGRES", violations.Violations[0].ViolationType)
+	assert.Equal(t, "critical", violations.Violations[0].Severity)
+	assert.Equal(t, "group", violations.Violations[0].LimitType)
+	assert.Equal(t, 1, violations.ViolationsByUser["alice"])
+
+	setTime(start.Add(5 * time.Minute))
+	violations, err = c.GetQoSViolations(ctx, &collector.QoSViolationOptions{QoSName: "gpu"})
+	require.NoError(t, err)
+	require.Equal(t, 1, violations.ResolvedViolations)
+	assert.Zero(t, violations.ActiveViolations)
+	assert.Equal(t, "started", violations.Violations[0].ResolutionAction)
+	assert.Equal(t, 15*time.Minute, violations.ResolutionStats.MeanResolutionTime)
+	assert.Equal(t, 1.0, violations.ResolutionStats.AutoResolutionRate)
+
+	// The finished job is counted once, against the QoS it ran in
+	usage, err := c.GetQoSUsage(ctx, "gpu")
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.JobsCompleted)
+	assert.Equal(t, 40*time.Minute, usage.WalltimeConsumed)
+	usage, err = c.GetQoSUsage(ctx, "gpu")
+	require.NoError(t, err)
+	assert.Zero(t, usage.JobsCompleted)
+}
@@ -0,0 +1,1576 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jontk/slurm-exporter/internal/collector"
+)
+
+const (
+	// queueModelName and queueModelVersion identify the wait-time predictor
+	queueModelName    = "queue_wait"
+	queueModelVersion = "v1"
+
+	// Bounds on the history kept between snapshots
+	maxDepthSamples       = 120
+	maxPositionChanges    = 20
+	maxPredictionOutcomes = 500
+	maxTransitionAge      = 24 * time.Hour
+)
+
+// QueueAnalysisOptions controls how the queue analysis adapter samples slurmrestd
+type QueueAnalysisOptions struct {
+	// SnapshotTTL is how long a fetched job/partition snapshot is reused, so
+	// that one collection pass issues a single set of API calls
+	SnapshotTTL time.Duration
+
+	// MaxTrackedJobs bounds the pending jobs reported for per-job analysis
+	MaxTrackedJobs int
+
+	// MaxTrackedUsers bounds the users reported for per-user analysis
+	MaxTrackedUsers int
+
+	// ThroughputWindow is the look-back used for start rates and wait samples
+	ThroughputWindow time.Duration
+
+	// StarvationThreshold is the pending age after which a job counts as starving
+	StarvationThreshold time.Duration
+}
+
+// DefaultQueueAnalysisOptions returns the default queue analysis options
+func DefaultQueueAnalysisOptions() *QueueAnalysisOptions {
+	return &QueueAnalysisOptions{
+		SnapshotTTL:         15 * time.Second,
+		MaxTrackedJobs:      100,
+		MaxTrackedUsers:     50,
+		ThroughputWindow:    time.Hour,
+		StarvationThreshold: 24 * time.Hour,
+	}
+}
+
+// QueueAnalysisClient implements collector.QueueAnalysisSLURMClient on top of
+// the stock slurmrestd job, partition and cluster statistics endpoints.
+// Movement, transition and prediction accuracy figures are derived from the
+// differences between successive snapshots.
+type QueueAnalysisClient struct {
+	client slurm.SlurmClient
+	opts   QueueAnalysisOptions
+	now    func() time.Time
+
+	mu          sync.Mutex
+	snapshot    *queueSnapshot
+	positions   map[string]*queuePositionHistory
+	depths      map[string][]int
+	peakRates   map[string]float64
+	jobStates   map[string]string
+	stability   float64
+	transitions []queueTransition
+	predictions map[string]queuePrediction
+	outcomes    []queuePredictionOutcome
+}
+
+// queueJob is the subset of a SLURM job the analysis works with
+type queueJob struct {
+	id          string
+	user        string
+	account     string
+	partition   string
+	qos         string
+	state       string
+	reason      string
+	priority    float64
+	cpus        float64
+	gpu         bool
+	backfillTry bool
+	submit      time.Time
+	start       time.Time
+	end         time.Time
+}
+
+// queueSnapshot is one consistent view of the queue
+type queueSnapshot struct {
+	fetchedAt  time.Time
+	jobs       []*queueJob
+	byID       map[string]*queueJob
+	pending    map[string][]*queueJob // partition -> jobs in scheduling order
+	partitions map[string]float64     // partition -> total CPUs
+	stats      *slurm.ClusterStats
+}
+
+type queuePositionHistory struct {
+	initial      int
+	firstSeen    time.Time
+	last         int
+	lastObserved time.Time
+	lastChange   time.Time
+	changes      []int
+	velocity     float64
+	acceleration float64
+}
+
+type queueTransition struct {
+	from string
+	to   string
+	at   time.Time
+	wait time.Duration
+}
+
+type queuePrediction struct {
+	submit         time.Time
+	predictedStart time.Time
+}
+
+type queuePredictionOutcome struct {
+	predicted float64
+	actual    float64
+}
+
+// NewQueueAnalysisClient creates a queue analysis adapter backed by client
+func NewQueueAnalysisClient(client slurm.SlurmClient, opts *QueueAnalysisOptions) *QueueAnalysisClient {
+	defaults := DefaultQueueAnalysisOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	q := &QueueAnalysisClient{
+		client:      client,
+		opts:        *opts,
+		now:         time.Now,
+		positions:   make(map[string]*queuePositionHistory),
+		depths:      make(map[string][]int),
+		peakRates:   make(map[string]float64),
+		jobStates:   make(map[string]string),
+		predictions: make(map[string]queuePrediction),
+	}
+	if q.opts.SnapshotTTL <= 0 {
+		q.opts.SnapshotTTL = defaults.SnapshotTTL
+	}
+	if q.opts.MaxTrackedJobs <= 0 {
+		q.opts.MaxTrackedJobs = defaults.MaxTrackedJobs
+	}
+	if q.opts.MaxTrackedUsers <= 0 {
+		q.opts.MaxTrackedUsers = defaults.MaxTrackedUsers
+	}
+	if q.opts.ThroughputWindow <= 0 {
+		q.opts.ThroughputWindow = defaults.ThroughputWindow
+	}
+	if q.opts.StarvationThreshold <= 0 {
+		q.opts.StarvationThreshold = defaults.StarvationThreshold
+	}
+	return q
+}
+
+// ListQueueTargets reports the partitions, highest-priority pending jobs and
+// users with pending work that the collector should analyse
+func (q *QueueAnalysisClient) ListQueueTargets(ctx context.Context) (*collector.QueueAnalysisTargets, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionSet := make(map[string]bool, len(snap.partitions))
+	for name := range snap.partitions {
+		partitionSet[name] = true
+	}
+	for _, job := range snap.jobs {
+		if job.partition != "" {
+			partitionSet[job.partition] = true
+		}
+	}
+	partitions := make([]string, 0, len(partitionSet))
+	for name := range partitionSet {
+		partitions = append(partitions, name)
+	}
+	sort.Strings(partitions)
+
+	var pending []*queueJob
+	pendingPerUser := make(map[string]int)
+	for _, job := range snap.jobs {
+		if job.state == string(api.JobStatePending) {
+			pending = append(pending, job)
+			pendingPerUser[job.user]++
+		}
+	}
+	sortQueue(pending)
+
+	jobIDs := make([]string, 0, len(pending))
+	for _, job := range pending {
+		if len(jobIDs) >= q.opts.MaxTrackedJobs {
+			break
+		}
+		jobIDs = append(jobIDs, job.id)
+	}
+
+	users := make([]string, 0, len(pendingPerUser))
+	for user := range pendingPerUser {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if pendingPerUser[users[i]] != pendingPerUser[users[j]] {
+			return pendingPerUser[users[i]] > pendingPerUser[users[j]]
+		}
+		return users[i] < users[j]
+	})
+	if len(users) > q.opts.MaxTrackedUsers {
+		users = users[:q.opts.MaxTrackedUsers]
+	}
+
+	return &collector.QueueAnalysisTargets{Partitions: partitions, JobIDs: jobIDs, Users: users}, nil
+}
+
+// GetQueuePositionAnalysis reports where a pending job sits in its partition queue
+func (q *QueueAnalysisClient) GetQueuePositionAnalysis(ctx context.Context, jobID string) (*collector.QueuePositionAnalysis, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, position, err := snap.pendingJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	depth := len(snap.pending[job.partition])
+	analysis := &collector.QueuePositionAnalysis{
+		JobID:              job.id,
+		UserName:           job.user,
+		AccountName:        job.account,
+		PartitionName:      job.partition,
+		CurrentPosition:    position,
+		TotalQueueDepth:    depth,
+		PositionPercentile: 100 * float64(depth-position) / float64(depth),
+		JobsAhead:          position - 1,
+		JobsBehind:         depth - position,
+		InitialPosition:    position,
+		TimeInQueue:        now.Sub(job.submit),
+		MovementDirection:  "stationary",
+		QueueClass:         job.partition,
+		PriorityClass:      job.qos,
+		ResourceClass:      job.resourceClass(),
+		SubmittedAt:        job.submit,
+		LastUpdated:        now,
+	}
+
+	if history, ok := q.positions[job.id]; ok {
+		analysis.InitialPosition = history.initial
+		analysis.PositionChanges = append([]int(nil), history.changes...)
+		analysis.StagnationTime = now.Sub(history.lastChange)
+		analysis.MovementVelocity = history.velocity
+		analysis.MovementAcceleration = history.acceleration
+		if hours := now.Sub(history.firstSeen).Hours(); hours > 0 {
+			analysis.AdvancementRate = float64(history.initial-position) / hours
+		}
+		switch {
+		case history.velocity > 0:
+			analysis.MovementDirection = "advancing"
+		case history.velocity < 0:
+			analysis.MovementDirection = "retreating"
+		}
+	}
+
+	switch {
+	case position == 1:
+		analysis.MovementPrediction = "next"
+	case analysis.AdvancementRate > 0:
+		analysis.MovementPrediction = "advancing"
+	default:
+		analysis.MovementPrediction = "stalled"
+	}
+
+	return analysis, nil
+}
+
+// PredictWaitTime estimates the remaining wait of a pending job. The
+// scheduler's own backfill estimate is preferred; otherwise the partition's
+// recent start rate or observed wait times are used.
+func (q *QueueAnalysisClient) PredictWaitTime(ctx context.Context, jobID string) (*collector.QueueWaitTimePrediction, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, position, err := snap.pendingJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	waited := now.Sub(job.submit).Seconds()
+	started := snap.startedSince(now.Add(-q.opts.ThroughputWindow), job.partition)
+	waits := waitSeconds(started)
+	sort.Float64s(waits)
+
+	prediction := &collector.QueueWaitTimePrediction{
+		JobID:            job.id,
+		UserName:         job.user,
+		PartitionName:    job.partition,
+		ModelVersion:     queueModelVersion,
+		ModelAccuracy:    q.predictionAccuracy(),
+		TrainingDataSize: len(waits),
+		PredictedAt:      now,
+		NextUpdate:       now.Add(q.opts.SnapshotTTL),
+		UpdateFrequency:  q.opts.SnapshotTTL,
+	}
+
+	startRate := float64(len(started)) / q.opts.ThroughputWindow.Hours()
+	var predicted float64
+	switch {
+	case job.start.After(now):
+		predicted = job.start.Sub(now).Seconds()
+		prediction.PredictionMethod = "scheduler_estimate"
+		prediction.ConfidenceLevel = 0.9
+	case startRate > 0:
+		predicted = float64(position) / startRate * 3600
+		prediction.PredictionMethod = "queue_throughput"
+		prediction.ConfidenceLevel = sampleConfidence(len(waits))
+	case len(waits) > 0:
+		predicted = math.Max(0, percentile(waits, 0.5)-waited)
+		prediction.PredictionMethod = "historical_median"
+		prediction.ConfidenceLevel = sampleConfidence(len(waits))
+	default:
+		return nil, fmt.Errorf("not enough queue history to predict wait time for job %s", jobID)
+	}
+
+	prediction.PredictedWaitTime = seconds(predicted)
+	prediction.EstimatedStartTime = now.Add(prediction.PredictedWaitTime)
+
+	if len(waits) > 0 {
+		remaining := func(p float64) time.Duration { return seconds(math.Max(0, percentile(waits, p)-waited)) }
+		prediction.MinWaitTime = remaining(0)
+		prediction.MedianWaitTime = remaining(0.5)
+		prediction.P90WaitTime = remaining(0.9)
+		prediction.MaxWaitTime = remaining(1)
+		prediction.UncertaintyRange = prediction.P90WaitTime - prediction.MinWaitTime
+		prediction.HistoricalFactor = sampleConfidence(len(waits))
+	}
+
+	queue := snap.pending[job.partition]
+	prediction.QueuePositionFactor = float64(position-1) / float64(len(queue))
+	if top := queue[0].priority; top > 0 {
+		prediction.PriorityFactor = job.priority / top
+	}
+	if snap.stats != nil && snap.stats.TotalCPUs > 0 {
+		prediction.SystemLoadFactor = float64(snap.stats.AllocatedCPUs) / float64(snap.stats.TotalCPUs)
+		if snap.stats.IdleCPUs > 0 {
+			prediction.ResourceFactor = math.Min(1, job.cpus/float64(snap.stats.IdleCPUs))
+		} else {
+			prediction.ResourceFactor = 1
+		}
+	}
+
+	q.predictions[job.id] = queuePrediction{submit: job.submit, predictedStart: prediction.EstimatedStartTime}
+	return prediction, nil
+}
+
+// GetQueueMetrics summarises the queue of a partition
+func (q *QueueAnalysisClient) GetQueueMetrics(ctx context.Context, partition string) (*collector.QueueAnalysisMetrics, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := q.partitionStats(snap, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &collector.QueueAnalysisMetrics{
+		PartitionName:         partition,
+		TotalJobs:             stats.total,
+		PendingJobs:           stats.pending,
+		RunningJobs:           stats.running,
+		CompletedJobs:         stats.completed,
+		FailedJobs:            stats.failed,
+		JobsPerHour:           stats.startRate,
+		JobsPerDay:            stats.startRate * 24,
+		PeakThroughput:        q.peakRates[partition],
+		AverageProcessingTime: stats.meanRuntime,
+		ResourceUtilization:   stats.utilization,
+		StarvationRisk:        stats.starvation,
+		OverloadRisk:          stats.overload,
+		BottleneckSeverity:    stats.utilization * stats.overload,
+		LastUpdated:           snap.fetchedAt,
+	}
+
+	if finished := stats.completed + stats.failed; finished > 0 {
+		metrics.ProcessingEfficiency = float64(stats.completed) / float64(finished)
+	}
+	if active := stats.pending + stats.running; active > 0 {
+		metrics.QueueUtilization = float64(stats.pending) / float64(active)
+	}
+	metrics.QueueHealth = 1 - math.Max(metrics.StarvationRisk, metrics.BottleneckSeverity)
+
+	if depths := q.depths[partition]; len(depths) > 0 {
+		values := make([]float64, len(depths))
+		metrics.MinQueueDepth = depths[0]
+		for i, depth := range depths {
+			values[i] = float64(depth)
+			metrics.MaxQueueDepth = max(metrics.MaxQueueDepth, depth)
+			metrics.MinQueueDepth = min(metrics.MinQueueDepth, depth)
+		}
+		metrics.AverageQueueDepth, metrics.QueueDepthVariance = meanVariance(values)
+		metrics.QueueDepthTrend = trend(values)
+	}
+
+	return metrics, nil
+}
+
+// GetHistoricalWaitTimes analyses the wait times of jobs that started within
+// the filter window. slurmrestd only lists recently finished jobs, so the
+// window is effectively bounded by the controller's MinJobAge.
+func (q *QueueAnalysisClient) GetHistoricalWaitTimes(ctx context.Context, filters *collector.WaitTimeFilters) (*collector.HistoricalWaitTimes, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if filters == nil {
+		filters = &collector.WaitTimeFilters{}
+	}
+	start, end := filters.StartTime, filters.EndTime
+	if end.IsZero() {
+		end = snap.fetchedAt
+	}
+	if start.IsZero() {
+		start = end.Add(-q.opts.ThroughputWindow)
+	}
+
+	var jobs []*queueJob
+	for _, job := range snap.jobs {
+		if !job.hasStarted(snap.fetchedAt) || job.start.Before(start) || job.start.After(end) {
+			continue
+		}
+		if (filters.UserName != "" && job.user != filters.UserName) ||
+			(filters.AccountName != "" && job.account != filters.AccountName) ||
+			(filters.PartitionName != "" && job.partition != filters.PartitionName) ||
+			(filters.MinPriority > 0 && job.priority < float64(filters.MinPriority)) ||
+			(filters.MaxPriority > 0 && job.priority > float64(filters.MaxPriority)) ||
+			(filters.JobSizeMin > 0 && job.cpus < float64(filters.JobSizeMin)) ||
+			(filters.JobSizeMax > 0 && job.cpus > float64(filters.JobSizeMax)) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].start.Before(jobs[j].start) })
+
+	result := &collector.HistoricalWaitTimes{
+		AnalysisPeriod:      end.Sub(start).String(),
+		TotalJobs:           len(jobs),
+		WaitTimeTrend:       "stable",
+		DailyPatterns:       make(map[string]float64),
+		WeeklyPatterns:      make(map[string]float64),
+		WaitTimeByPriority:  make(map[string]float64),
+		WaitTimeByPartition: make(map[string]float64),
+		WaitTimeByUser:      make(map[string]float64),
+		LastAnalyzed:        snap.fetchedAt,
+	}
+	if len(jobs) == 0 {
+		return result, nil
+	}
+
+	waits := waitSeconds(jobs)
+	result.WaitTimeTrend = trend(waits)
+
+	byHour := make(map[string][]float64)
+	byDay := make(map[string][]float64)
+	byQoS := make(map[string][]float64)
+	byPartition := make(map[string][]float64)
+	byUser := make(map[string][]float64)
+	for i, job := range jobs {
+		hour := fmt.Sprintf("%02d", job.submit.Hour())
+		day := strings.ToLower(job.submit.Weekday().String())
+		byHour[hour] = append(byHour[hour], waits[i])
+		byDay[day] = append(byDay[day], waits[i])
+		byQoS[job.qos] = append(byQoS[job.qos], waits[i])
+		byPartition[job.partition] = append(byPartition[job.partition], waits[i])
+		byUser[job.user] = append(byUser[job.user], waits[i])
+	}
+	fillMeans(result.DailyPatterns, byHour)
+	fillMeans(result.WeeklyPatterns, byDay)
+	fillMeans(result.WaitTimeByPriority, byQoS)
+	fillMeans(result.WaitTimeByPartition, byPartition)
+	fillMeans(result.WaitTimeByUser, byUser)
+
+	mean, variance := meanVariance(waits)
+	stddev := math.Sqrt(variance)
+	result.MeanWaitTime = mean
+	result.StdDevWaitTime = stddev
+	result.AnomalyThreshold = mean + 3*stddev
+	for i, job := range jobs {
+		if stddev > 0 && waits[i] > result.AnomalyThreshold {
+			result.AnomalousJobs = append(result.AnomalousJobs, job.id)
+		}
+	}
+	result.AnomalyRate = float64(len(result.AnomalousJobs)) / float64(len(jobs))
+
+	sorted := append([]float64(nil), waits...)
+	sort.Float64s(sorted)
+	result.MinWaitTime = sorted[0]
+	result.MedianWaitTime = percentile(sorted, 0.5)
+	result.P90WaitTime = percentile(sorted, 0.9)
+	result.P95WaitTime = percentile(sorted, 0.95)
+	result.P99WaitTime = percentile(sorted, 0.99)
+	result.MaxWaitTime = sorted[len(sorted)-1]
+
+	return result, nil
+}
+
+// GetQueueEfficiencyAnalysis scores how well a partition's queue is draining
+func (q *QueueAnalysisClient) GetQueueEfficiencyAnalysis(ctx context.Context, partition string) (*collector.QueueEfficiencyAnalysis, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := q.partitionStats(snap, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &collector.QueueEfficiencyAnalysis{
+		PartitionName:      partition,
+		ResourceEfficiency: stats.utilization,
+		WaitTimeEfficiency: 1 / (1 + stats.medianWait/3600),
+		BottleneckType:     "none",
+		LastAnalyzed:       snap.fetchedAt,
+	}
+	if active := stats.pending + stats.running; active > 0 {
+		analysis.SchedulingEfficiency = float64(stats.running) / float64(active)
+	}
+	if flow := float64(stats.started) + float64(stats.pending); flow > 0 {
+		analysis.ThroughputEfficiency = float64(stats.started) / flow
+	}
+	analysis.OverallEfficiency = (analysis.SchedulingEfficiency + analysis.ResourceEfficiency +
+		analysis.ThroughputEfficiency + analysis.WaitTimeEfficiency) / 4
+	analysis.OptimizationPotential = 1 - analysis.OverallEfficiency
+
+	if stats.pending > 0 {
+		switch {
+		case stats.utilization >= 0.9:
+			analysis.BottleneckType = "resources"
+		case stats.starvation > 0:
+			analysis.BottleneckType = "priority"
+		default:
+			analysis.BottleneckType = "scheduling"
+		}
+		analysis.BottleneckSeverity = math.Max(stats.utilization*stats.overload, stats.starvation)
+		analysis.BottleneckSources = []string{analysis.BottleneckType}
+	}
+
+	return analysis, nil
+}
+
+// GetResourceQueueAnalysis reports capacity and contention for a resource.
+// Only CPUs are available from the cluster statistics endpoint.
+func (q *QueueAnalysisClient) GetResourceQueueAnalysis(ctx context.Context, resourceType string) (*collector.ResourceQueueAnalysis, error) {
+	if resourceType != "cpu" {
+		return nil, fmt.Errorf("resource type %q is not available from slurmrestd", resourceType)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snap.stats == nil {
+		return nil, fmt.Errorf("cluster statistics unavailable")
+	}
+
+	now := snap.fetchedAt
+	analysis := &collector.ResourceQueueAnalysis{
+		ResourceType:      resourceType,
+		TotalCapacity:     float64(snap.stats.TotalCPUs),
+		AvailableCapacity: float64(snap.stats.IdleCPUs),
+		UtilizedCapacity:  float64(snap.stats.AllocatedCPUs),
+		LastAnalyzed:      now,
+	}
+	if analysis.TotalCapacity > 0 {
+		analysis.CapacityUtilization = analysis.UtilizedCapacity / analysis.TotalCapacity
+		analysis.FutureAvailability = analysis.AvailableCapacity / analysis.TotalCapacity
+	}
+
+	var waiting []float64
+	var requested float64
+	for _, job := range snap.jobs {
+		if job.state == string(api.JobStatePending) && job.reason == "Resources" {
+			waiting = append(waiting, now.Sub(job.submit).Seconds())
+			requested += job.cpus
+		}
+	}
+	analysis.JobsWaitingForResource = len(waiting)
+	analysis.AverageResourceWaitTime, _ = meanVariance(waiting)
+	if analysis.AvailableCapacity > 0 {
+		analysis.ResourceContention = requested / analysis.AvailableCapacity
+	} else if requested > 0 {
+		analysis.ResourceContention = 1
+	}
+	if len(waiting) > 0 && analysis.TotalCapacity > 0 {
+		// Idle CPUs while jobs wait for resources are too fragmented to use
+		analysis.ResourceFragmentation = analysis.AvailableCapacity / analysis.TotalCapacity
+	}
+
+	var startedCPUs float64
+	for _, job := range snap.startedSince(now.Add(-q.opts.ThroughputWindow), "") {
+		startedCPUs += job.cpus
+	}
+	analysis.AllocationRate = startedCPUs / q.opts.ThroughputWindow.Hours()
+
+	if estimate := q.predictionsFor(snap, ""); len(estimate) > 0 {
+		analysis.ExpectedWaitTime, _ = meanVariance(estimate)
+	}
+
+	return analysis, nil
+}
+
+// GetPriorityQueueAnalysis compares queue behaviour across QoS levels
+func (q *QueueAnalysisClient) GetPriorityQueueAnalysis(ctx context.Context) (*collector.PriorityQueueAnalysis, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	since := now.Add(-q.opts.ThroughputWindow)
+	analysis := &collector.PriorityQueueAnalysis{
+		PriorityLevels:       make(map[string]*collector.PriorityLevelMetrics),
+		PriorityDistribution: make(map[string]float64),
+		LastAnalyzed:         now,
+	}
+
+	ages := make(map[string][]float64)
+	positions := make(map[string][]float64)
+	priorities := make(map[string][]float64)
+	var pendingCount, starving int
+	for _, queue := range snap.pending {
+		for i, job := range queue {
+			age := now.Sub(job.submit)
+			ages[job.qos] = append(ages[job.qos], age.Seconds())
+			positions[job.qos] = append(positions[job.qos], float64(i+1))
+			priorities[job.qos] = append(priorities[job.qos], job.priority)
+			pendingCount++
+			if age > q.opts.StarvationThreshold {
+				starving++
+			}
+		}
+	}
+
+	started := snap.startedSince(since, "")
+	startedPerQoS := make(map[string]int)
+	for _, job := range started {
+		startedPerQoS[job.qos]++
+	}
+
+	var highLevel, lowLevel string
+	var highPriority, lowPriority float64
+	for level, levelAges := range ages {
+		sorted := append([]float64(nil), levelAges...)
+		sort.Float64s(sorted)
+		meanAge, _ := meanVariance(levelAges)
+		meanPosition, _ := meanVariance(positions[level])
+		meanPriority, _ := meanVariance(priorities[level])
+
+		var levelStarving int
+		for _, age := range levelAges {
+			if age > q.opts.StarvationThreshold.Seconds() {
+				levelStarving++
+			}
+		}
+
+		analysis.PriorityLevels[level] = &collector.PriorityLevelMetrics{
+			PriorityLevel:   level,
+			JobCount:        len(levelAges),
+			AverageWaitTime: meanAge,
+			MedianWaitTime:  percentile(sorted, 0.5),
+			ThroughputRate:  float64(startedPerQoS[level]) / q.opts.ThroughputWindow.Hours(),
+			QueuePosition:   meanPosition,
+			StarvationRisk:  float64(levelStarving) / float64(len(levelAges)),
+		}
+		analysis.PriorityDistribution[level] = float64(len(levelAges)) / float64(pendingCount)
+
+		if highLevel == "" || meanPriority > highPriority {
+			highLevel, highPriority = level, meanPriority
+		}
+		if lowLevel == "" || meanPriority < lowPriority {
+			lowLevel, lowPriority = level, meanPriority
+		}
+	}
+
+	if pendingCount > 0 {
+		analysis.StarvationRisk = float64(starving) / float64(pendingCount)
+		high := analysis.PriorityLevels[highLevel].AverageWaitTime
+		low := analysis.PriorityLevels[lowLevel].AverageWaitTime
+		if high > 0 {
+			analysis.PriorityAdvantage = low / high
+		}
+		if longest := math.Max(high, low); longest > 0 {
+			analysis.FairnessScore = math.Min(high, low) / longest
+		}
+	}
+	analysis.PrioritySystemHealth = 1 - analysis.StarvationRisk
+
+	if len(started) > 0 {
+		analysis.PriorityInversion = float64(len(snap.jumpedQueue(started))) / float64(len(started))
+	}
+
+	var finished, preempted int
+	for _, job := range snap.jobs {
+		if isFinishedState(job.state) {
+			finished++
+			if job.state == string(api.JobStatePreempted) {
+				preempted++
+			}
+		}
+	}
+	if finished > 0 {
+		analysis.PreemptionRate = float64(preempted) / float64(finished)
+	}
+
+	return analysis, nil
+}
+
+// GetUserQueueExperience summarises the queue experience of a user
+func (q *QueueAnalysisClient) GetUserQueueExperience(ctx context.Context, userName string) (*collector.UserQueueExperience, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	experience := &collector.UserQueueExperience{UserName: userName, LastAnalyzed: now}
+	accounts := make(map[string]int)
+	var waits, positions []float64
+	var completed, finished int
+	for _, job := range snap.jobs {
+		if job.user != userName {
+			continue
+		}
+		experience.TotalSubmissions++
+		accounts[job.account]++
+		if job.hasStarted(now) {
+			waits = append(waits, job.start.Sub(job.submit).Seconds())
+		}
+		if isFinishedState(job.state) {
+			finished++
+			if job.state == string(api.JobStateCompleted) {
+				completed++
+			}
+		}
+	}
+	if experience.TotalSubmissions == 0 {
+		return nil, fmt.Errorf("no jobs found for user %s", userName)
+	}
+
+	for _, queue := range snap.pending {
+		for i, job := range queue {
+			if job.user == userName {
+				positions = append(positions, float64(i+1))
+			}
+		}
+	}
+
+	for account, count := range accounts {
+		if count > accounts[experience.AccountName] || (count == accounts[experience.AccountName] && account < experience.AccountName) {
+			experience.AccountName = account
+		}
+	}
+
+	if len(waits) > 0 {
+		sorted := append([]float64(nil), waits...)
+		sort.Float64s(sorted)
+		mean, variance := meanVariance(waits)
+		experience.AverageWaitTime = mean
+		experience.MedianWaitTime = percentile(sorted, 0.5)
+		experience.WaitTimeVariability = math.Sqrt(variance)
+		experience.QueueExperienceScore = 1 / (1 + mean/3600)
+	}
+	if finished > 0 {
+		experience.JobSuccessRate = float64(completed) / float64(finished)
+	}
+	experience.QueuePosition, _ = meanVariance(positions)
+
+	return experience, nil
+}
+
+// GetBackfillAnalysis estimates backfill activity in a partition. A job that
+// started while an earlier, higher-priority job in the same partition was
+// still pending is counted as backfilled.
+func (q *QueueAnalysisClient) GetBackfillAnalysis(ctx context.Context, partition string) (*collector.BackfillAnalysis, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.partitionStats(snap, partition); err != nil {
+		return nil, err
+	}
+
+	now := snap.fetchedAt
+	started := snap.startedSince(now.Add(-q.opts.ThroughputWindow), partition)
+	backfilled := snap.jumpedQueue(started)
+
+	var attempted int
+	for _, job := range snap.pending[partition] {
+		if job.backfillTry {
+			attempted++
+		}
+	}
+
+	analysis := &collector.BackfillAnalysis{
+		PartitionName:         partition,
+		TotalOpportunities:    attempted + len(backfilled),
+		UtilizedOpportunities: len(backfilled),
+		MissedOpportunities:   attempted,
+		JobsBackfilled:        len(backfilled),
+		BackfillJobSizes:      map[string]int{"small": 0, "medium": 0, "large": 0},
+		LastAnalyzed:          now,
+	}
+	if len(started) > 0 {
+		analysis.BackfillRate = float64(len(backfilled)) / float64(len(started))
+	}
+	if analysis.TotalOpportunities > 0 {
+		analysis.BackfillEfficiency = float64(analysis.UtilizedOpportunities) / float64(analysis.TotalOpportunities)
+		analysis.BackfillSuccess = analysis.BackfillEfficiency
+	}
+
+	var runtimes []float64
+	for _, job := range backfilled {
+		runtimes = append(runtimes, job.runtime(now).Seconds())
+		switch {
+		case job.cpus <= 4:
+			analysis.BackfillJobSizes["small"]++
+		case job.cpus <= 64:
+			analysis.BackfillJobSizes["medium"]++
+		default:
+			analysis.BackfillJobSizes["large"]++
+		}
+	}
+	analysis.AverageBackfillDuration, _ = meanVariance(runtimes)
+
+	return analysis, nil
+}
+
+// GetQueueStateTransitions reports job state changes observed between
+// snapshots within period (a Go duration such as "24h")
+func (q *QueueAnalysisClient) GetQueueStateTransitions(ctx context.Context, period string) (*collector.QueueStateTransitions, error) {
+	window, err := time.ParseDuration(period)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transition period %q: %w", period, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &collector.QueueStateTransitions{
+		AnalysisPeriod:         period,
+		StateTransitions:       make(map[string]map[string]int),
+		TransitionRates:        make(map[string]float64),
+		StateDistribution:      make(map[string]float64),
+		AverageTransitionTime:  make(map[string]float64),
+		TransitionTimeVariance: make(map[string]float64),
+		StateStability:         q.stability,
+		LastAnalyzed:           snap.fetchedAt,
+	}
+
+	for _, job := range snap.jobs {
+		result.StateDistribution[strings.ToLower(job.state)]++
+	}
+	for state := range result.StateDistribution {
+		result.StateDistribution[state] /= float64(len(snap.jobs))
+	}
+
+	since := snap.fetchedAt.Add(-window)
+	var healthy, problematic, total float64
+	waits := make(map[string][]float64)
+	for _, transition := range q.transitions {
+		if transition.at.Before(since) {
+			continue
+		}
+		total++
+		if result.StateTransitions[transition.from] == nil {
+			result.StateTransitions[transition.from] = make(map[string]int)
+		}
+		result.StateTransitions[transition.from][transition.to]++
+
+		key := strings.ToLower(transition.from + "_to_" + transition.to)
+		result.TransitionRates[key] += 1 / window.Hours()
+		if transition.wait > 0 {
+			waits[key] = append(waits[key], transition.wait.Seconds())
+		}
+
+		switch {
+		case transition.to == string(api.JobStateRunning) || transition.to == string(api.JobStateCompleted):
+			healthy++
+		case isFailedState(transition.to):
+			problematic++
+		}
+	}
+	for key, values := range waits {
+		result.AverageTransitionTime[key], result.TransitionTimeVariance[key] = meanVariance(values)
+	}
+
+	if total > 0 {
+		result.HealthyTransitions = healthy / total
+		result.ProblematicTransitions = problematic / total
+	}
+	if healthy+problematic > 0 {
+		result.TransitionEfficiency = healthy / (healthy + problematic)
+	}
+
+	return result, nil
+}
+
+// ValidatePredictionModel compares earlier wait-time predictions with the
+// start times that were subsequently observed
+func (q *QueueAnalysisClient) ValidatePredictionModel(ctx context.Context) (*collector.PredictionModelValidation, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(q.outcomes) == 0 {
+		return nil, fmt.Errorf("no predicted jobs have started yet")
+	}
+
+	var absSum, sqSum, actualSum float64
+	for _, outcome := range q.outcomes {
+		diff := outcome.predicted - outcome.actual
+		absSum += math.Abs(diff)
+		sqSum += diff * diff
+		actualSum += outcome.actual
+	}
+	n := float64(len(q.outcomes))
+	actualMean := actualSum / n
+
+	var totalSq float64
+	for _, outcome := range q.outcomes {
+		totalSq += (outcome.actual - actualMean) * (outcome.actual - actualMean)
+	}
+
+	validation := &collector.PredictionModelValidation{
+		ModelName:           queueModelName,
+		ModelVersion:        queueModelVersion,
+		OverallAccuracy:     q.predictionAccuracy(),
+		MeanAbsoluteError:   absSum / n,
+		RootMeanSquareError: math.Sqrt(sqSum / n),
+		UpdateFrequency:     1 / q.opts.SnapshotTTL.Seconds(),
+		ValidatedAt:         snap.fetchedAt,
+	}
+	if actualMean > 0 {
+		validation.PredictionError = validation.MeanAbsoluteError / actualMean
+	}
+	if totalSq > 0 {
+		validation.R2Score = 1 - sqSum/totalSq
+	}
+	validation.RetrainingNeeded = validation.OverallAccuracy < 0.5
+
+	return validation, nil
+}
+
+// GetSystemLoadImpact reports CPU load overall and per partition
+func (q *QueueAnalysisClient) GetSystemLoadImpact(ctx context.Context) (*collector.SystemLoadImpact, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	snap, err := q.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snap.stats == nil || snap.stats.TotalCPUs == 0 {
+		return nil, fmt.Errorf("cluster statistics unavailable")
+	}
+
+	load := float64(snap.stats.AllocatedCPUs) / float64(snap.stats.TotalCPUs)
+	impact := &collector.SystemLoadImpact{
+		CPULoad:             load,
+		OverallSystemLoad:   load,
+		CapacityUtilization: load,
+		LoadDistribution:    make(map[string]float64),
+		LastMeasured:        snap.fetchedAt,
+	}
+
+	allocated := make(map[string]float64)
+	var resourceWaiters int
+	for _, job := range snap.jobs {
+		switch {
+		case job.state == string(api.JobStateRunning):
+			allocated[job.partition] += job.cpus
+		case job.state == string(api.JobStatePending) && job.reason == "Resources":
+			resourceWaiters++
+		}
+	}
+
+	minLoad, maxLoad := math.Inf(1), math.Inf(-1)
+	for partition, total := range snap.partitions {
+		if total <= 0 {
+			continue
+		}
+		partitionLoad := math.Min(1, allocated[partition]/total)
+		impact.LoadDistribution[partition] = partitionLoad
+		minLoad = math.Min(minLoad, partitionLoad)
+		maxLoad = math.Max(maxLoad, partitionLoad)
+	}
+	if len(impact.LoadDistribution) > 0 {
+		impact.LoadBalanceScore = 1 - (maxLoad - minLoad)
+		impact.LoadBalanceEffectiveness = impact.LoadBalanceScore
+	}
+	if resourceWaiters > 0 {
+		impact.CapacityFragmentation = float64(snap.stats.IdleCPUs) / float64(snap.stats.TotalCPUs)
+	}
+	impact.CapacityOptimization = 1 - impact.CapacityFragmentation
+
+	return impact, nil
+}
+
+// refresh returns the current snapshot, fetching a new one once the TTL has
+// expired. The caller must hold q.mu.
+func (q *QueueAnalysisClient) refresh(ctx context.Context) (*queueSnapshot, error) {
+	now := q.now()
+	if q.snapshot != nil && now.Sub(q.snapshot.fetchedAt) < q.opts.SnapshotTTL {
+		return q.snapshot, nil
+	}
+
+	jobList, err := q.client.Jobs().List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	snap := &queueSnapshot{
+		fetchedAt:  now,
+		byID:       make(map[string]*queueJob),
+		pending:    make(map[string][]*queueJob),
+		partitions: make(map[string]float64),
+	}
+
+	if partitionList, err := q.client.Partitions().List(ctx, nil); err != nil {
+		logrus.WithError(err).Debug("Queue analysis continuing without partition data")
+	} else if partitionList != nil {
+		for _, partition := range partitionList.Partitions {
+			if partition.Name == nil {
+				continue
+			}
+			var cpus float64
+			if partition.CPUs != nil && partition.CPUs.Total != nil {
+				cpus = float64(*partition.CPUs.Total)
+			}
+			snap.partitions[*partition.Name] = cpus
+		}
+	}
+
+	if stats, err := q.client.Info().Stats(ctx); err != nil {
+		logrus.WithError(err).Debug("Queue analysis continuing without cluster statistics")
+	} else {
+		snap.stats = stats
+	}
+
+	if jobList != nil {
+		for i := range jobList.Jobs {
+			job := newQueueJob(&jobList.Jobs[i])
+			if job.id == "" {
+				continue
+			}
+			snap.jobs = append(snap.jobs, job)
+			snap.byID[job.id] = job
+			if job.state == string(api.JobStatePending) {
+				snap.pending[job.partition] = append(snap.pending[job.partition], job)
+			}
+		}
+	}
+	for _, queue := range snap.pending {
+		sortQueue(queue)
+	}
+
+	q.observe(snap)
+	q.snapshot = snap
+	return snap, nil
+}
+
+// observe updates the histories kept between snapshots. The caller must hold q.mu.
+func (q *QueueAnalysisClient) observe(snap *queueSnapshot) {
+	now := snap.fetchedAt
+
+	// Queue positions
+	seen := make(map[string]bool)
+	for _, queue := range snap.pending {
+		for i, job := range queue {
+			position := i + 1
+			seen[job.id] = true
+			history, ok := q.positions[job.id]
+			if !ok {
+				q.positions[job.id] = &queuePositionHistory{
+					initial:      position,
+					firstSeen:    now,
+					last:         position,
+					lastObserved: now,
+					lastChange:   now,
+				}
+				continue
+			}
+			if hours := now.Sub(history.lastObserved).Hours(); hours > 0 {
+				velocity := float64(history.last-position) / hours
+				history.acceleration = (velocity - history.velocity) / hours
+				history.velocity = velocity
+			}
+			if position != history.last {
+				history.lastChange = now
+				history.changes = append(history.changes, position)
+				if len(history.changes) > maxPositionChanges {
+					history.changes = history.changes[len(history.changes)-maxPositionChanges:]
+				}
+			}
+			history.last = position
+			history.lastObserved = now
+		}
+	}
+	for id := range q.positions {
+		if !seen[id] {
+			delete(q.positions, id)
+		}
+	}
+
+	// Queue depth and throughput per partition
+	partitions := make(map[string]bool)
+	for name := range snap.partitions {
+		partitions[name] = true
+	}
+	for name := range snap.pending {
+		partitions[name] = true
+	}
+	for name := range partitions {
+		depths := append(q.depths[name], len(snap.pending[name]))
+		if len(depths) > maxDepthSamples {
+			depths = depths[len(depths)-maxDepthSamples:]
+		}
+		q.depths[name] = depths
+
+		rate := float64(len(snap.startedSince(now.Add(-q.opts.ThroughputWindow), name))) / q.opts.ThroughputWindow.Hours()
+		q.peakRates[name] = math.Max(q.peakRates[name], rate)
+	}
+
+	// State transitions
+	states := make(map[string]string, len(snap.jobs))
+	var unchanged, known int
+	for _, job := range snap.jobs {
+		states[job.id] = job.state
+		previous, ok := q.jobStates[job.id]
+		if !ok {
+			continue
+		}
+		known++
+		if previous == job.state {
+			unchanged++
+			continue
+		}
+		transition := queueTransition{from: previous, to: job.state, at: now}
+		if previous == string(api.JobStatePending) && job.hasStarted(now) {
+			transition.wait = job.start.Sub(job.submit)
+		}
+		q.transitions = append(q.transitions, transition)
+	}
+	q.jobStates = states
+	if known > 0 {
+		q.stability = float64(unchanged) / float64(known)
+	}
+	cutoff := now.Add(-maxTransitionAge)
+	for len(q.transitions) > 0 && q.transitions[0].at.Before(cutoff) {
+		q.transitions = q.transitions[1:]
+	}
+
+	// Prediction outcomes for jobs that have since started
+	for id, prediction := range q.predictions {
+		job, ok := snap.byID[id]
+		if !ok {
+			delete(q.predictions, id)
+			continue
+		}
+		if job.state == string(api.JobStatePending) || !job.hasStarted(now) {
+			continue
+		}
+		q.outcomes = append(q.outcomes, queuePredictionOutcome{
+			predicted: prediction.predictedStart.Sub(prediction.submit).Seconds(),
+			actual:    job.start.Sub(job.submit).Seconds(),
+		})
+		if len(q.outcomes) > maxPredictionOutcomes {
+			q.outcomes = q.outcomes[len(q.outcomes)-maxPredictionOutcomes:]
+		}
+		delete(q.predictions, id)
+	}
+}
+
+// partitionQueueStats aggregates the jobs of one partition
+type partitionQueueStats struct {
+	total, pending, running, completed, failed, started int
+
+	startRate   float64
+	meanRuntime float64
+	medianWait  float64
+	utilization float64
+	starvation  float64
+	overload    float64
+}
+
+// partitionStats aggregates a partition's jobs, failing for unknown partitions
+func (q *QueueAnalysisClient) partitionStats(snap *queueSnapshot, partition string) (*partitionQueueStats, error) {
+	totalCPUs, known := snap.partitions[partition]
+	now := snap.fetchedAt
+	stats := &partitionQueueStats{}
+
+	var runtimes []float64
+	var runningCPUs, pendingCPUs float64
+	var starving int
+	for _, job := range snap.jobs {
+		if job.partition != partition {
+			continue
+		}
+		stats.total++
+		switch {
+		case job.state == string(api.JobStatePending):
+			stats.pending++
+			pendingCPUs += job.cpus
+			if now.Sub(job.submit) > q.opts.StarvationThreshold {
+				starving++
+			}
+		case job.state == string(api.JobStateRunning):
+			stats.running++
+			runningCPUs += job.cpus
+			runtimes = append(runtimes, job.runtime(now).Seconds())
+		case job.state == string(api.JobStateCompleted):
+			stats.completed++
+			runtimes = append(runtimes, job.runtime(now).Seconds())
+		case isFailedState(job.state):
+			stats.failed++
+			runtimes = append(runtimes, job.runtime(now).Seconds())
+		}
+	}
+	if !known && stats.total == 0 {
+		return nil, fmt.Errorf("partition %s not found", partition)
+	}
+
+	started := snap.startedSince(now.Add(-q.opts.ThroughputWindow), partition)
+	stats.started = len(started)
+	stats.startRate = float64(len(started)) / q.opts.ThroughputWindow.Hours()
+	stats.meanRuntime, _ = meanVariance(runtimes)
+
+	if waits := waitSeconds(started); len(waits) > 0 {
+		sort.Float64s(waits)
+		stats.medianWait = percentile(waits, 0.5)
+	}
+	if totalCPUs > 0 {
+		stats.utilization = math.Min(1, runningCPUs/totalCPUs)
+		stats.overload = math.Min(1, pendingCPUs/totalCPUs)
+	}
+	if stats.pending > 0 {
+		stats.starvation = float64(starving) / float64(stats.pending)
+	}
+
+	return stats, nil
+}
+
+// predictionsFor returns the outstanding predicted waits, in seconds, for
+// pending jobs in partition (all partitions when empty)
+func (q *QueueAnalysisClient) predictionsFor(snap *queueSnapshot, partition string) []float64 {
+	var waits []float64
+	for id, prediction := range q.predictions {
+		job, ok := snap.byID[id]
+		if !ok || (partition != "" && job.partition != partition) {
+			continue
+		}
+		waits = append(waits, math.Max(0, prediction.predictedStart.Sub(snap.fetchedAt).Seconds()))
+	}
+	return waits
+}
+
+// predictionAccuracy is the share of validated predictions within 25% (or
+// five minutes) of the observed wait
+func (q *QueueAnalysisClient) predictionAccuracy() float64 {
+	return outcomeAccuracy(q.outcomes)
+}
+
+func outcomeAccuracy(outcomes []queuePredictionOutcome) float64 {
+	if len(outcomes) == 0 {
+		return 0
+	}
+	var accurate int
+	for _, outcome := range outcomes {
+		if math.Abs(outcome.predicted-outcome.actual) <= predictionTolerance(outcome.actual) {
+			accurate++
+		}
+	}
+	return float64(accurate) / float64(len(outcomes))
+}
+
+// predictionTolerance is the error within which a predicted wait counts as accurate
+func predictionTolerance(actual float64) float64 {
+	return math.Max(300, 0.25*actual)
+}
+
+// pendingJob looks up a pending job and its 1-based queue position
+func (s *queueSnapshot) pendingJob(jobID string) (*queueJob, int, error) {
+	job, ok := s.byID[jobID]
+	if !ok {
+		return nil, 0, fmt.Errorf("job %s not found", jobID)
+	}
+	if job.state != string(api.JobStatePending) {
+		return nil, 0, fmt.Errorf("job %s is not pending (state %s)", jobID, job.state)
+	}
+	for i, queued := range s.pending[job.partition] {
+		if queued == job {
+			return job, i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("job %s not found in partition %s queue", jobID, job.partition)
+}
+
+// startedSince returns jobs in partition (any when empty) that started after since
+func (s *queueSnapshot) startedSince(since time.Time, partition string) []*queueJob {
+	var started []*queueJob
+	for _, job := range s.jobs {
+		if (partition == "" || job.partition == partition) && job.hasStarted(s.fetchedAt) && job.start.After(since) {
+			started = append(started, job)
+		}
+	}
+	return started
+}
+
+// jumpedQueue returns the started jobs that began while an earlier submitted,
+// higher-priority job in the same partition was still pending
+func (s *queueSnapshot) jumpedQueue(started []*queueJob) []*queueJob {
+	var jumped []*queueJob
+	for _, job := range started {
+		for _, pending := range s.pending[job.partition] {
+			if pending.priority > job.priority && pending.submit.Before(job.start) {
+				jumped = append(jumped, job)
+				break
+			}
+		}
+	}
+	return jumped
+}
+
+// newQueueJob extracts the fields used by the analysis from a SLURM job
+func newQueueJob(job *slurm.Job) *queueJob {
+	qj := &queueJob{
+		user:    stringValue(job.UserName),
+		account: stringValue(job.Account),
+		qos:     stringValue(job.QoS),
+		reason:  stringValue(job.StateReason),
+		submit:  job.SubmitTime,
+		start:   job.StartTime,
+		end:     job.EndTime,
+	}
+	if job.JobID != nil {
+		qj.id = strconv.Itoa(int(*job.JobID))
+	}
+	if len(job.JobState) > 0 {
+		qj.state = string(job.JobState[0])
+	}
+	if qj.qos == "" {
+		qj.qos = "normal"
+	}
+	// Pending jobs may list several candidate partitions; the first is used
+	if partition := stringValue(job.Partition); partition != "" {
+		qj.partition = strings.Split(partition, ",")[0]
+	}
+	if job.Priority != nil {
+		qj.priority = float64(*job.Priority)
+	}
+	if job.CPUs != nil {
+		qj.cpus = float64(*job.CPUs)
+	}
+	for _, tres := range []*string{job.TRESReqStr, job.TRESPerNode, job.TRESPerJob} {
+		if tres != nil && strings.Contains(*tres, "gpu") {
+			qj.gpu = true
+		}
+	}
+	for _, flag := range job.Flags {
+		if flag == api.FlagsBackfillAttempted {
+			qj.backfillTry = true
+		}
+	}
+	return qj
+}
+
+// hasStarted reports whether the job has actually begun execution; pending
+// jobs carry the scheduler's expected start time instead
+func (j *queueJob) hasStarted(now time.Time) bool {
+	return j.state != string(api.JobStatePending) && !j.start.IsZero() && !j.start.After(now) && !j.submit.IsZero()
+}
+
+// runtime returns how long the job has been (or was) running
+func (j *queueJob) runtime(now time.Time) time.Duration {
+	if j.start.IsZero() || j.start.After(now) {
+		return 0
+	}
+	if !j.end.IsZero() && j.end.Before(now) && j.state != string(api.JobStateRunning) {
+		return j.end.Sub(j.start)
+	}
+	return now.Sub(j.start)
+}
+
+func (j *queueJob) resourceClass() string {
+	if j.gpu {
+		return "gpu"
+	}
+	return "cpu"
+}
+
+// sortQueue orders pending jobs the way the scheduler considers them:
+// highest priority first, then oldest submission
+func sortQueue(jobs []*queueJob) {
+	sort.SliceStable(jobs, func(i, j int) bool { return queuedBefore(jobs[i], jobs[j]) })
+}
+
+// queuedBefore reports whether the scheduler considers a before b
+func queuedBefore(a, b *queueJob) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if !a.submit.Equal(b.submit) {
+		return a.submit.Before(b.submit)
+	}
+	return a.id < b.id
+}
+
+func isFinishedState(state string) bool {
+	return state == string(api.JobStateCompleted) || state == string(api.JobStateCancelled) ||
+		state == string(api.JobStatePreempted) || isFailedState(state)
+}
+
+func isFailedState(state string) bool {
+	switch api.JobState(state) {
+	case api.JobStateFailed, api.JobStateTimeout, api.JobStateNodeFail, api.JobStateOutOfMemory,
+		api.JobStateBootFail, api.JobStateDeadline:
+		return true
+	}
+	return false
+}
+
+func waitSeconds(jobs []*queueJob) []float64 {
+	waits := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		waits = append(waits, math.Max(0, job.start.Sub(job.submit).Seconds()))
+	}
+	return waits
+}
+
+// percentile returns the nearest-rank percentile p (0..1) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, sq / float64(len(values))
+}
+
+// trend compares the first and second half of a series, reporting a change
+// of more than 10% as increasing or decreasing
+func trend(values []float64) string {
+	if len(values) < 2 {
+		return "stable"
+	}
+	half := len(values) / 2
+	first, _ := meanVariance(values[:half])
+	second, _ := meanVariance(values[half:])
+	switch {
+	case second > first*1.1:
+		return "increasing"
+	case second < first*0.9:
+		return "decreasing"
+	default:
+		return "stable"
+	}
+}
+
+func fillMeans(dst map[string]float64, groups map[string][]float64) {
+	for key, values := range groups {
+		dst[key], _ = meanVariance(values)
+	}
+}
+
+// sampleConfidence grows towards 1 as more observations back an estimate
+func sampleConfidence(samples int) float64 {
+	return float64(samples) / float64(samples+10)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Ensure QueueAnalysisClient satisfies the collector interfaces
+var (
+	_ collector.QueueAnalysisSLURMClient  = (*QueueAnalysisClient)(nil)
+	_ collector.QueueAnalysisTargetLister = (*QueueAnalysisClient)(nil)
+)
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/jontk/slurm-client/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jontk/slurm-exporter/internal/testutil/mocks"
+)
+
+var queueTestNow = time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+
+func queueTestJob(id int32, user, state string, priority uint32, submit, start time.Time) slurm.Job {
+	partition := "compute"
+	account := "research"
+	cpus := uint32(4)
+	return slurm.Job{
+		JobID:      &id,
+		UserName:   &user,
+		Account:    &account,
+		Partition:  &partition,
+		JobState:   []api.JobState{api.JobState(state)},
+		Priority:   &priority,
+		CPUs:       &cpus,
+		SubmitTime: submit,
+		StartTime:  start,
+	}
+}
+
+func queueTestJobs() *slurm.JobList {
+	return &slurm.JobList{Jobs: []slurm.Job{
+		queueTestJob(1, "alice", "PENDING", 100, queueTestNow.Add(-2*time.Hour), time.Time{}),
+		queueTestJob(2, "bob", "PENDING", 200, queueTestNow.Add(-time.Hour), queueTestNow.Add(30*time.Minute)),
+		queueTestJob(3, "carol", "RUNNING", 50, queueTestNow.Add(-3*time.Hour), queueTestNow.Add(-30*time.Minute)),
+		queueTestJob(4, "carol", "COMPLETED", 50, queueTestNow.Add(-5*time.Hour), queueTestNow.Add(-4*time.Hour)),
+	}}
+}
+
+// newTestClock returns a clock that starts at queueTestNow and a setter
+// that moves it
+func newTestClock() (func() time.Time, func(time.Time)) {
+	var mu sync.Mutex
+	now := queueTestNow
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return clock, func(at time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = at
+	}
+}
+
+// mockJobList returns a job manager listing the jobs produced at the
+// current time of clock
+func mockJobList(jobs func(now time.Time) []slurm.Job, clock func() time.Time) *mocks.MockJobManager {
+	jobManager := new(mocks.MockJobManager)
+	jobManager.On("List", mock.Anything, mock.Anything).Return(
+		func(context.Context, *slurm.ListJobsOptions) *slurm.JobList {
+			return &slurm.JobList{Jobs: jobs(clock())}
+		}, nil)
+	return jobManager
+}
+
+func newQueueTestClient(t *testing.T, jobs ...*slurm.JobList) (*QueueAnalysisClient, *mocks.MockJobManager) {
+	t.Helper()
+	name := "compute"
+	total := int32(64)
+
+	jobManager := new(mocks.MockJobManager)
+	for _, list := range jobs {
+		jobManager.On("List", mock.Anything, mock.Anything).Return(list, nil).Once()
+	}
+	partitionManager := new(mocks.MockPartitionManager)
+	partitionManager.On("List", mock.Anything, mock.Anything).Return(&slurm.PartitionList{
+		Partitions: []slurm.Partition{{Name: &name, CPUs: &api.PartitionCPUs{Total: &total}}},
+	}, nil)
+	infoManager := new(mocks.MockInfoManager)
+	infoManager.On("Stats", mock.Anything).Return(&slurm.ClusterStats{
+		TotalCPUs: 64, AllocatedCPUs: 4, IdleCPUs: 60,
+	}, nil)
+
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(jobManager)
+	client.On("Partitions").Return(partitionManager)
+	client.On("Info").Return(infoManager)
+
+	qa := NewQueueAnalysisClient(client, nil)
+	qa.now = func() time.Time { return queueTestNow }
+	return qa, jobManager
+}
+
+func TestQueueAnalysisClient_ListQueueTargets(t *testing.T) {
+	t.Parallel()
+	qa, jobManager := newQueueTestClient(t, queueTestJobs())
+	ctx := context.Background()
+
+	targets, err := qa.ListQueueTargets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"compute"}, targets.Partitions)
+	assert.Equal(t, []string{"2", "1"}, targets.JobIDs)
+	assert.Equal(t, []string{"alice", "bob"}, targets.Users)
+
+	// Further calls within the TTL reuse the snapshot
+	_, err = qa.GetQueueMetrics(ctx, "compute")
+	require.NoError(t, err)
+	jobManager.AssertNumberOfCalls(t, "List", 1)
+}
+
+func TestQueueAnalysisClient_QueuePosition(t *testing.T) {
+	t.Parallel()
+	qa, _ := newQueueTestClient(t, queueTestJobs())
+
+	position, err := qa.GetQueuePositionAnalysis(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, position.CurrentPosition)
+	assert.Equal(t, 2, position.TotalQueueDepth)
+	assert.Equal(t, 1, position.JobsAhead)
+	assert.Equal(t, 2*time.Hour, position.TimeInQueue)
+	assert.Equal(t, "cpu", position.ResourceClass)
+
+	_, err = qa.GetQueuePositionAnalysis(context.Background(), "3")
+	assert.Error(t, err, "running jobs have no queue position")
+}
+
+func TestQueueAnalysisClient_PredictWaitTime(t *testing.T) {
+	t.Parallel()
+	qa, _ := newQueueTestClient(t, queueTestJobs())
+	ctx := context.Background()
+
+	estimate, err := qa.PredictWaitTime(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "scheduler_estimate", estimate.PredictionMethod)
+	assert.Equal(t, 30*time.Minute, estimate.PredictedWaitTime)
+
+	// One job started in the last hour, so the second job waits two hours
+	throughput, err := qa.PredictWaitTime(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "queue_throughput", throughput.PredictionMethod)
+	assert.Equal(t, 2*time.Hour, throughput.PredictedWaitTime)
+}
+
+func TestQueueAnalysisClient_Backfill(t *testing.T) {
+	t.Parallel()
+	qa, _ := newQueueTestClient(t, queueTestJobs())
+
+	// Job 3 started while higher-priority job 1 was pending
+	backfill, err := qa.GetBackfillAnalysis(context.Background(), "compute")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backfill.JobsBackfilled)
+	assert.Equal(t, 1.0, backfill.BackfillRate)
+	assert.Equal(t, 1, backfill.BackfillJobSizes["small"])
+
+	_, err = qa.GetBackfillAnalysis(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestQueueAnalysisClient_TransitionsAndValidation(t *testing.T) {
+	t.Parallel()
+	second := queueTestJobs()
+	second.Jobs[0] = queueTestJob(1, "alice", "RUNNING", 100, queueTestNow.Add(-2*time.Hour), queueTestNow.Add(30*time.Second))
+	qa, _ := newQueueTestClient(t, queueTestJobs(), second)
+	ctx := context.Background()
+
+	_, err := qa.ValidatePredictionModel(ctx)
+	assert.Error(t, err, "no outcomes before any predicted job starts")
+
+	_, err = qa.PredictWaitTime(ctx, "1")
+	require.NoError(t, err)
+
+	qa.now = func() time.Time { return queueTestNow.Add(time.Minute) }
+
+	transitions, err := qa.GetQueueStateTransitions(ctx, "1h")
+	require.NoError(t, err)
+	assert.Equal(t, 1, transitions.StateTransitions["PENDING"]["RUNNING"])
+	assert.Equal(t, 1.0, transitions.HealthyTransitions)
+
+	validation, err := qa.ValidatePredictionModel(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, (2*time.Hour - 30*time.Second).Seconds(), validation.MeanAbsoluteError, 1e-6)
+}
+
+func TestQueueAnalysisClient_JobListError(t *testing.T) {
+	t.Parallel()
+	jobManager := new(mocks.MockJobManager)
+	jobManager.On("List", mock.Anything, mock.Anything).Return(nil, errors.New("slurmrestd unavailable"))
+	client := new(mocks.MockSlurmClient)
+	client.On("Jobs").Return(jobManager)
+
+	qa := NewQueueAnalysisClient(client, nil)
+	_, err := qa.GetQueueMetrics(context.Background(), "compute")
+	assert.Error(t, err)
+}
+
+func TestNewQueueAnalysisClient_DefaultsZeroOptions(t *testing.T) {
+	t.Parallel()
+	qa := NewQueueAnalysisClient(new(mocks.MockSlurmClient), &QueueAnalysisOptions{SnapshotTTL: time.Minute})
+
+	defaults := DefaultQueueAnalysisOptions()
+	defaults.SnapshotTTL = time.Minute
+	assert.Equal(t, *defaults, qa.opts)
+}
@@ -0,0 +1,324 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jontk/slurm-exporter/internal/resilience"
+)
+
+// Endpoints shared by SnapshotClient, used as the endpoint label
+const (
+	endpointJobs       = "jobs"
+	endpointNodes      = "nodes"
+	endpointPartitions = "partitions"
+	endpointUsers      = "users"
+	endpointInfo       = "info"
+	endpointStats      = "stats"
+)
+
+// SnapshotClient wraps a SLURM client so that collectors share what they
+// fetch. Unfiltered job, node, partition and user lists and the cluster info
+// and stats are fetched once and handed to every collector asking for them
+// within SnapshotMaxAge; concurrent requests wait for the fetch in flight.
+// Shared results must be treated as read-only.
+type SnapshotClient struct {
+	slurm.SlurmClient
+
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*snapshotEntry
+
+	fetchDuration *prometheus.HistogramVec
+	payloadBytes  *prometheus.GaugeVec
+	sharedFetches *prometheus.CounterVec
+}
+
+// snapshotEntry is the last or in-flight fetch of an endpoint
+type snapshotEntry struct {
+	// done is closed once the fetch completed
+	done      chan struct{}
+	value     any
+	err       error
+	fetchedAt time.Time
+
+	// rejected reports that a circuit breaker rejected the fetch
+	rejected bool
+}
+
+// NewSnapshotClient wraps client, sharing fetches for up to maxAge
+func NewSnapshotClient(client slurm.SlurmClient, maxAge time.Duration) *SnapshotClient {
+	return &SnapshotClient{
+		SlurmClient: client,
+		maxAge:      maxAge,
+		entries:     make(map[string]*snapshotEntry),
+		fetchDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_duration_seconds",
+				Help:      "Time taken to fetch a SLURM endpoint",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"endpoint"},
+		),
+		payloadBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_payload_bytes",
+				Help:      "Size of the response bodies of the last successful fetch of a SLURM endpoint",
+			},
+			[]string{"endpoint"},
+		),
+		sharedFetches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "slurm",
+				Subsystem: "exporter",
+				Name:      "fetch_shared_total",
+				Help:      "Requests for a SLURM endpoint answered from a fetch made for another collector",
+			},
+			[]string{"endpoint"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *SnapshotClient) Describe(ch chan<- *prometheus.Desc) {
+	c.fetchDuration.Describe(ch)
+	c.payloadBytes.Describe(ch)
+	c.sharedFetches.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *SnapshotClient) Collect(ch chan<- prometheus.Metric) {
+	c.fetchDuration.Collect(ch)
+	c.payloadBytes.Collect(ch)
+	c.sharedFetches.Collect(ch)
+}
+
+// fetch returns the shared result of an endpoint, fetching it when there is
+// no fresh one. Failed fetches are handed to the requests that waited for
+// them but are not kept.
+func (c *SnapshotClient) fetch(ctx context.Context, endpoint string, fetchFunc func(context.Context) (any, error)) (any, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[endpoint]; ok {
+		select {
+		case <-entry.done:
+			if entry.err == nil && time.Since(entry.fetchedAt) < c.maxAge {
+				c.mu.Unlock()
+				c.sharedFetches.WithLabelValues(endpoint).Inc()
+				return entry.value, nil
+			}
+		default:
+			c.mu.Unlock()
+			c.sharedFetches.WithLabelValues(endpoint).Inc()
+			select {
+			case <-entry.done:
+				if entry.rejected {
+					resilience.MarkRejected(ctx)
+				}
+				return entry.value, entry.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	entry := &snapshotEntry{done: make(chan struct{})}
+	c.entries[endpoint] = entry
+	c.mu.Unlock()
+
+	var size atomic.Int64
+	fetchCtx, rejected := resilience.TrackRejections(context.WithValue(ctx, payloadSizeKey{}, &size))
+	start := time.Now()
+	entry.value, entry.err = fetchFunc(fetchCtx)
+	entry.fetchedAt = time.Now()
+	entry.rejected = rejected()
+	close(entry.done)
+
+	c.fetchDuration.WithLabelValues(endpoint).Observe(entry.fetchedAt.Sub(start).Seconds())
+	if entry.err == nil {
+		c.payloadBytes.WithLabelValues(endpoint).Set(float64(size.Load()))
+	}
+	return entry.value, entry.err
+}
+
+// Jobs returns the job manager, sharing unfiltered job lists
+func (c *SnapshotClient) Jobs() slurm.JobManager {
+	jobs := c.SlurmClient.Jobs()
+	if jobs == nil {
+		return nil
+	}
+	return &snapshotJobs{JobManager: jobs, client: c}
+}
+
+// Nodes returns the node manager, sharing unfiltered node lists
+func (c *SnapshotClient) Nodes() slurm.NodeManager {
+	nodes := c.SlurmClient.Nodes()
+	if nodes == nil {
+		return nil
+	}
+	return &snapshotNodes{NodeManager: nodes, client: c}
+}
+
+// Partitions returns the partition manager, sharing unfiltered partition lists
+func (c *SnapshotClient) Partitions() slurm.PartitionManager {
+	partitions := c.SlurmClient.Partitions()
+	if partitions == nil {
+		return nil
+	}
+	return &snapshotPartitions{PartitionManager: partitions, client: c}
+}
+
+// Users returns the user manager, sharing unfiltered user lists
+func (c *SnapshotClient) Users() slurm.UserManager {
+	users := c.SlurmClient.Users()
+	if users == nil {
+		return nil
+	}
+	return &snapshotUsers{UserManager: users, client: c}
+}
+
+// Info returns the info manager, sharing cluster info and stats
+func (c *SnapshotClient) Info() slurm.InfoManager {
+	info := c.SlurmClient.Info()
+	if info == nil {
+		return nil
+	}
+	return &snapshotInfo{InfoManager: info, client: c}
+}
+
+type snapshotJobs struct {
+	slurm.JobManager
+	client *SnapshotClient
+}
+
+// List shares unfiltered job lists; filtered lists are fetched as asked
+func (m *snapshotJobs) List(ctx context.Context, opts *slurm.ListJobsOptions) (*slurm.JobList, error) {
+	if opts != nil {
+		return m.JobManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointJobs, func(ctx context.Context) (any, error) {
+		return m.JobManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.JobList)
+	return list, err
+}
+
+type snapshotNodes struct {
+	slurm.NodeManager
+	client *SnapshotClient
+}
+
+// List shares unfiltered node lists; filtered lists are fetched as asked
+func (m *snapshotNodes) List(ctx context.Context, opts *slurm.ListNodesOptions) (*slurm.NodeList, error) {
+	if opts != nil {
+		return m.NodeManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointNodes, func(ctx context.Context) (any, error) {
+		return m.NodeManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.NodeList)
+	return list, err
+}
+
+type snapshotPartitions struct {
+	slurm.PartitionManager
+	client *SnapshotClient
+}
+
+// List shares unfiltered partition lists; filtered lists are fetched as asked
+func (m *snapshotPartitions) List(ctx context.Context, opts *slurm.ListPartitionsOptions) (*slurm.PartitionList, error) {
+	if opts != nil {
+		return m.PartitionManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointPartitions, func(ctx context.Context) (any, error) {
+		return m.PartitionManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.PartitionList)
+	return list, err
+}
+
+type snapshotUsers struct {
+	slurm.UserManager
+	client *SnapshotClient
+}
+
+// List shares unfiltered user lists; filtered lists are fetched as asked
+func (m *snapshotUsers) List(ctx context.Context, opts *slurm.ListUsersOptions) (*slurm.UserList, error) {
+	if opts != nil {
+		return m.UserManager.List(ctx, opts)
+	}
+	value, err := m.client.fetch(ctx, endpointUsers, func(ctx context.Context) (any, error) {
+		return m.UserManager.List(ctx, nil)
+	})
+	list, _ := value.(*slurm.UserList)
+	return list, err
+}
+
+type snapshotInfo struct {
+	slurm.InfoManager
+	client *SnapshotClient
+}
+
+// Get shares the cluster info
+func (m *snapshotInfo) Get(ctx context.Context) (*slurm.ClusterInfo, error) {
+	value, err := m.client.fetch(ctx, endpointInfo, func(ctx context.Context) (any, error) {
+		return m.InfoManager.Get(ctx)
+	})
+	info, _ := value.(*slurm.ClusterInfo)
+	return info, err
+}
+
+// Stats shares the cluster statistics
+func (m *snapshotInfo) Stats(ctx context.Context) (*slurm.ClusterStats, error) {
+	value, err := m.client.fetch(ctx, endpointStats, func(ctx context.Context) (any, error) {
+		return m.InfoManager.Stats(ctx)
+	})
+	stats, _ := value.(*slurm.ClusterStats)
+	return stats, err
+}
+
+// payloadSizeKey carries the byte counter of a fetch to payloadTransport
+type payloadSizeKey struct{}
+
+// payloadTransport counts the response bytes of requests made by a fetch
+type payloadTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *payloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	if size, ok := req.Context().Value(payloadSizeKey{}).(*atomic.Int64); ok {
+		resp.Body = &countingBody{ReadCloser: resp.Body, size: size}
+	}
+	return resp, nil
+}
+
+// countingBody adds the bytes read from a response body to a fetch's size
+type countingBody struct {
+	io.ReadCloser
+	size *atomic.Int64
+}
+
+// Read implements io.Reader
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size.Add(int64(n))
+	return n, err
+}
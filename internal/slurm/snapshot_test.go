@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package slurm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	slurm "github.com/jontk/slurm-client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// countingJobs is a job manager that counts and optionally delays List calls
+type countingJobs struct {
+	slurm.JobManager
+	calls   atomic.Int32
+	delay   time.Duration
+	failing atomic.Bool
+}
+
+func (m *countingJobs) List(ctx context.Context, opts *slurm.ListJobsOptions) (*slurm.JobList, error) {
+	m.calls.Add(1)
+	time.Sleep(m.delay)
+	if m.failing.Load() {
+		return nil, errors.New("slurmrestd unavailable")
+	}
+	return &slurm.JobList{Jobs: []slurm.Job{{}}}, nil
+}
+
+// fakeSlurmClient serves the counting job manager
+type fakeSlurmClient struct {
+	slurm.SlurmClient
+	jobs *countingJobs
+}
+
+func (c *fakeSlurmClient) Jobs() slurm.JobManager {
+	return c.jobs
+}
+
+func TestSnapshotClient_SharesFetches(t *testing.T) {
+	t.Parallel()
+	jobs := &countingJobs{delay: 50 * time.Millisecond}
+	client := NewSnapshotClient(&fakeSlurmClient{jobs: jobs}, time.Minute)
+	ctx := context.Background()
+
+	// Concurrent collectors wait for one fetch
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := client.Jobs().List(ctx, nil)
+			if err != nil || list == nil || len(list.Jobs) != 1 {
+				t.Errorf("List() = %v, %v", list, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := jobs.calls.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+
+	// Later collectors within the max age reuse it, filtered lists do not
+	if _, err := client.Jobs().List(ctx, nil); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if _, err := client.Jobs().List(ctx, &slurm.ListJobsOptions{}); err != nil {
+		t.Fatalf("List(opts) error = %v", err)
+	}
+	if got := jobs.calls.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(client.sharedFetches.WithLabelValues(endpointJobs)); got != 5 {
+		t.Errorf("shared fetches = %v, want 5", got)
+	}
+	if got := testutil.CollectAndCount(client, "slurm_exporter_fetch_duration_seconds"); got != 1 {
+		t.Errorf("fetch duration series = %d, want 1", got)
+	}
+}
+
+func TestSnapshotClient_DoesNotKeepFailures(t *testing.T) {
+	t.Parallel()
+	jobs := &countingJobs{}
+	jobs.failing.Store(true)
+	client := NewSnapshotClient(&fakeSlurmClient{jobs: jobs}, time.Minute)
+	ctx := context.Background()
+
+	if _, err := client.Jobs().List(ctx, nil); err == nil {
+		t.Fatal("List() error = nil, want the fetch error")
+	}
+	jobs.failing.Store(false)
+	if _, err := client.Jobs().List(ctx, nil); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := jobs.calls.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+
+	// Without a max age only concurrent fetches are shared
+	uncached := NewSnapshotClient(&fakeSlurmClient{jobs: jobs}, 0)
+	for range 2 {
+		if _, err := uncached.Jobs().List(ctx, nil); err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+	}
+	if got := jobs.calls.Load(); got != 4 {
+		t.Errorf("fetches = %d, want 4", got)
+	}
+}
+
+func TestPayloadTransport(t *testing.T) {
+	t.Parallel()
+	body := strings.Repeat("x", 1234)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &payloadTransport{next: http.DefaultTransport}}
+	var size atomic.Int64
+	ctx := context.WithValue(context.Background(), payloadSizeKey{}, &size)
+
+	for range 2 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	if got := size.Load(); got != 2*int64(len(body)) {
+		t.Errorf("payload size = %d, want %d", got, 2*len(body))
+	}
+}
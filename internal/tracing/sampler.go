@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package tracing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// collectorAttribute is the span attribute naming the collector a span belongs to
+const collectorAttribute = attribute.Key("collector")
+
+// collectorSampler samples collector spans at their own configured rate and
+// everything else at the base rate.
+//
+// All decisions are ratio based on the trace ID, which is monotonic: a trace
+// sampled at one rate is sampled at every higher rate. Root spans are sampled
+// at the highest configured rate so that a sampled collector span never
+// loses its scrape parent, and spans below a collector follow their parent.
+type collectorSampler struct {
+	base       sdktrace.Sampler
+	root       sdktrace.Sampler
+	collectors map[string]sdktrace.Sampler
+	rates      map[string]float64
+	baseRate   float64
+}
+
+// newCollectorSampler creates a sampler with per-collector rate overrides
+func newCollectorSampler(baseRate float64, rates map[string]float64) sdktrace.Sampler {
+	maxRate := baseRate
+	collectors := make(map[string]sdktrace.Sampler, len(rates))
+	for collector, rate := range rates {
+		collectors[collector] = sdktrace.TraceIDRatioBased(rate)
+		maxRate = max(maxRate, rate)
+	}
+
+	return &collectorSampler{
+		base:       sdktrace.TraceIDRatioBased(baseRate),
+		root:       sdktrace.ParentBased(sdktrace.TraceIDRatioBased(maxRate)),
+		collectors: collectors,
+		rates:      rates,
+		baseRate:   baseRate,
+	}
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *collectorSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key != collectorAttribute {
+			continue
+		}
+		if sampler, ok := s.collectors[attr.Value.AsString()]; ok {
+			return sampler.ShouldSample(p)
+		}
+		return s.base.ShouldSample(p)
+	}
+	return s.root.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler
+func (s *collectorSampler) Description() string {
+	overrides := make([]string, 0, len(s.rates))
+	for collector, rate := range s.rates {
+		overrides = append(overrides, fmt.Sprintf("%s=%g", collector, rate))
+	}
+	sort.Strings(overrides)
+	return fmt.Sprintf("CollectorSampler{base=%g,collectors={%s}}", s.baseRate, strings.Join(overrides, ","))
+}
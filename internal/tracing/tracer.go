@@ -104,23 +104,20 @@ func (ct *CollectionTracer) initializeTracer() error {
 
 // createExporter creates the OTLP HTTP exporter
 func (ct *CollectionTracer) createExporter() (sdktrace.SpanExporter, error) {
+	var opts []otlptracehttp.Option
+
+	// A full URL selects the scheme itself, a bare host:port is combined
+	// with the insecure setting
 	endpoint := ct.config.Endpoint
-	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
 		if ct.config.Insecure {
-			endpoint = fmt.Sprintf("http://%s", endpoint)
-		} else {
-			endpoint = fmt.Sprintf("https://%s", endpoint)
+			opts = append(opts, otlptracehttp.WithInsecure())
 		}
 	}
 
-	opts := []otlptracehttp.Option{
-		otlptracehttp.WithEndpoint(endpoint),
-	}
-
-	if ct.config.Insecure {
-		opts = append(opts, otlptracehttp.WithInsecure())
-	}
-
 	exporter, err := otlptracehttp.New(context.Background(), opts...)
 	if err != nil {
 		return nil, err
@@ -131,15 +128,27 @@ func (ct *CollectionTracer) createExporter() (sdktrace.SpanExporter, error) {
 
 // createSampler creates a sampler based on configuration
 func (ct *CollectionTracer) createSampler() sdktrace.Sampler {
-	if ct.config.SampleRate <= 0 {
-		return sdktrace.NeverSample()
-	}
-	if ct.config.SampleRate >= 1.0 {
-		return sdktrace.AlwaysSample()
+	return newCollectorSampler(ct.config.SampleRate, ct.config.CollectorSampleRates)
+}
+
+// TraceScrape creates the root span for a scrape of the metrics endpoint.
+// Collector spans started on the returned context become its children.
+func (ct *CollectionTracer) TraceScrape(ctx context.Context) (context.Context, func()) {
+	if !ct.enabled || ct.tracer == nil {
+		return ctx, func() {}
 	}
 
-	// Use TraceIDRatioBased for probabilistic sampling
-	return sdktrace.TraceIDRatioBased(ct.config.SampleRate)
+	ctx, span := ct.tracer.Start(ctx, "scrape",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("operation", "scrape")),
+	)
+
+	startTime := time.Now()
+
+	return ctx, func() {
+		span.SetAttributes(attribute.Int64("duration_ms", time.Since(startTime).Milliseconds()))
+		span.End()
+	}
 }
 
 // TraceCollection creates a span for a collection operation
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is an http.RoundTripper that records a span for every request,
+// so slurmrestd calls show up below the collector that made them
+type Transport struct {
+	base   http.RoundTripper
+	tracer *CollectionTracer
+}
+
+// NewTransport wraps base, or http.DefaultTransport when base is nil, with
+// request tracing
+func NewTransport(base http.RoundTripper, tracer *CollectionTracer) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, tracer: tracer}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.tracer == nil || !t.tracer.IsEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, finish := t.tracer.TraceAPICall(req.Context(), req.URL.Path, req.Method)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	recordCall(ctx, time.Since(start))
+	if err != nil {
+		finish(err)
+		return nil, err
+	}
+
+	t.tracer.AddSpanAttribute(ctx, "http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		finish(fmt.Errorf("slurmrestd returned HTTP %d", resp.StatusCode))
+	} else {
+		finish(nil)
+	}
+	return resp, nil
+}
+
+// slowestCallKey carries the slowestCall tracker of a collection
+type slowestCallKey struct{}
+
+// slowestCall is the slowest sampled request made within a context
+type slowestCall struct {
+	mu       sync.Mutex
+	duration time.Duration
+	span     trace.SpanContext
+}
+
+// TrackSlowestCall returns a context in which traced requests record how
+// long they took, and a function returning exemplar labels linking to the
+// trace and span of the slowest of them. It returns nil when no sampled
+// request was made.
+func TrackSlowestCall(ctx context.Context) (context.Context, func() prometheus.Labels) {
+	slowest := &slowestCall{}
+	return context.WithValue(ctx, slowestCallKey{}, slowest), func() prometheus.Labels {
+		slowest.mu.Lock()
+		defer slowest.mu.Unlock()
+		if !slowest.span.IsValid() {
+			return nil
+		}
+		return prometheus.Labels{
+			"trace_id": slowest.span.TraceID().String(),
+			"span_id":  slowest.span.SpanID().String(),
+		}
+	}
+}
+
+// recordCall records a request made with the span in ctx, if it was sampled
+func recordCall(ctx context.Context, duration time.Duration) {
+	slowest, ok := ctx.Value(slowestCallKey{}).(*slowestCall)
+	if !ok {
+		return
+	}
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsSampled() {
+		return
+	}
+
+	slowest.mu.Lock()
+	defer slowest.mu.Unlock()
+	if duration > slowest.duration || !slowest.span.IsValid() {
+		slowest.duration = duration
+		slowest.span = span
+	}
+}
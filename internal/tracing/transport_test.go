@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2024 SLURM Exporter Contributors
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jontk/slurm-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newRecordingTracer returns an enabled tracer whose spans are kept in memory
+func newRecordingTracer(t *testing.T, cfg config.TracingConfig) (*CollectionTracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(newCollectorSampler(cfg.SampleRate, cfg.CollectorSampleRates)),
+	)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	cfg.Enabled = true
+	return &CollectionTracer{
+		tracer:           provider.Tracer("test"),
+		provider:         provider,
+		config:           cfg,
+		logger:           logger,
+		enabled:          true,
+		detailCollectors: make(map[string]bool),
+	}, recorder
+}
+
+func TestTransport_ScrapeToAPICall(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newRecordingTracer(t, config.TracingConfig{SampleRate: 1})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, tracer)}
+
+	scrapeCtx, finishScrape := tracer.TraceScrape(context.Background())
+	ctx, finishCollect := tracer.TraceCollection(scrapeCtx, "nodes")
+	for _, path := range []string{"/slurm/v0.0.44/nodes/", "/missing"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	finishCollect()
+	finishScrape()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 4)
+
+	scrape, collect := spans["scrape"], spans["collect.nodes"]
+	nodes, missing := spans["api./slurm/v0.0.44/nodes/"], spans["api./missing"]
+	assert.Equal(t, scrape.SpanContext().SpanID(), collect.Parent().SpanID())
+	assert.Equal(t, collect.SpanContext().SpanID(), nodes.Parent().SpanID())
+	assert.Equal(t, scrape.SpanContext().TraceID(), nodes.SpanContext().TraceID())
+	assert.Empty(t, nodes.Events())
+	assert.NotEmpty(t, missing.Events(), "HTTP errors are recorded on the span")
+}
+
+func TestCollectorSampler(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newRecordingTracer(t, config.TracingConfig{
+		SampleRate:           0,
+		CollectorSampleRates: map[string]float64{"jobs": 1},
+	})
+
+	for range 10 {
+		scrapeCtx, finishScrape := tracer.TraceScrape(context.Background())
+		for _, collector := range []string{"jobs", "nodes"} {
+			ctx, finish := tracer.TraceCollection(scrapeCtx, collector)
+			_, finishAPI := tracer.TraceAPICall(ctx, "/"+collector, http.MethodGet)
+			finishAPI(nil)
+			finish()
+		}
+		finishScrape()
+	}
+
+	counts := make(map[string]int)
+	for _, span := range recorder.Ended() {
+		counts[span.Name()]++
+	}
+	// Only the jobs collector is sampled, together with its scrape parent
+	assert.Equal(t, map[string]int{"scrape": 10, "collect.jobs": 10, "api./jobs": 10}, counts)
+}
+
+func TestTrackSlowestCall(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newRecordingTracer(t, config.TracingConfig{SampleRate: 1})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, tracer)}
+
+	get := func(ctx context.Context, path string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	ctx, finish := tracer.TraceCollection(context.Background(), "jobs")
+	ctx, slowest := TrackSlowestCall(ctx)
+	assert.Nil(t, slowest(), "no call was made yet")
+	for _, path := range []string{"/fast", "/slow", "/fast"} {
+		get(ctx, path)
+	}
+	finish()
+
+	// Calls outside a tracked context are not recorded
+	get(context.Background(), "/slow")
+
+	var slow sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "api./slow" && span.Parent().IsValid() {
+			slow = span
+		}
+	}
+	require.NotNil(t, slow)
+	assert.Equal(t, prometheus.Labels{
+		"trace_id": slow.SpanContext().TraceID().String(),
+		"span_id":  slow.SpanContext().SpanID().String(),
+	}, slowest())
+}
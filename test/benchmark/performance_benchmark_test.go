@@ -16,8 +16,8 @@ import (
 // BenchmarkRegistryCreation tests registry creation performance
 func BenchmarkRegistryCreation(b *testing.B) {
 	cfg := &config.CollectorsConfig{
-		Jobs:       config.CollectorConfig{Enabled: true},
-		Nodes:      config.CollectorConfig{Enabled: true},
+		Jobs:       config.JobsConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
+		Nodes:      config.NodesConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
 		Partitions: config.CollectorConfig{Enabled: true},
 	}
 
@@ -54,8 +54,8 @@ func BenchmarkMetricCreation(b *testing.B) {
 // BenchmarkMemoryUsage tests memory allocation patterns
 func BenchmarkMemoryUsage(b *testing.B) {
 	cfg := &config.CollectorsConfig{
-		Jobs:       config.CollectorConfig{Enabled: true},
-		Nodes:      config.CollectorConfig{Enabled: true},
+		Jobs:       config.JobsConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
+		Nodes:      config.NodesConfig{CollectorConfig: config.CollectorConfig{Enabled: true}},
 		Partitions: config.CollectorConfig{Enabled: true},
 	}
 	promRegistry := prometheus.NewRegistry()